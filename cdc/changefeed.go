@@ -17,6 +17,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -100,6 +101,8 @@ type changeFeed struct {
 	ddlResolvedTs uint64
 	ddlJobHistory []*timodel.Job
 	ddlExecutedTs uint64
+	// emittedDDLs is nil if the DDL deduplication is disabled
+	emittedDDLs model.EmittedDDLs
 
 	schemas map[model.SchemaID]tableIDMap
 	tables  map[model.TableID]model.TableName
@@ -112,6 +115,22 @@ type changeFeed struct {
 	rebalanceNextTick  bool
 
 	lastRebalanceTime time.Time
+	// tableMovesWindowStart and tableMovesInWindow rate limit the table moves initiated
+	tableMovesWindowStart time.Time
+	tableMovesInWindow    int
+
+	// lagExceededAt is the time its lag exceeds the MaxLagBeforePause.
+	lagExceededAt time.Time
+
+	// flushedCheckpointTs is the checkpoint ts of the changefeed status last written to etcd, which
+	// a new owner resumes the changefeed from
+	flushedCheckpointTs model.Ts
+
+	// targetTsAcked is true if the MQ sinks have acked the checkpoint message of the target ts
+	targetTsAcked bool
+
+	// integrityChecker is nil if the integrity check is disabled
+	integrityChecker *integrityChecker
 
 	etcdCli kv.CDCEtcdClient
 }
@@ -395,13 +414,16 @@ func (c *changeFeed) handleManualMoveTableJobs(ctx context.Context, captures map
 	if len(captures) == 0 {
 		return nil
 	}
-	if len(c.moveTableJobs) > 0 {
-		return nil
-	}
+	// the manual moves don't wait for the rebalance moves held back by the rate limit, only a table
+	// being moved is not moved again until the move is finished
 	for len(c.manualMoveCommands) > 0 {
 		moveJob := c.manualMoveCommands[0]
-		if _, exist := c.moveTableJobs[moveJob.TableID]; exist {
-			break
+		if job, exist := c.moveTableJobs[moveJob.TableID]; exist {
+			if !job.Rebalance || job.Status != model.MoveTableStatusNone {
+				break
+			}
+			log.Info("drop the rebalance move job not initiated in favor of the manual one", zap.Reflect("job", job))
+			delete(c.moveTableJobs, moveJob.TableID)
 		}
 		c.manualMoveCommands = c.manualMoveCommands[1:]
 		moveJob.From = ""
@@ -415,7 +437,10 @@ func (c *changeFeed) handleManualMoveTableJobs(ctx context.Context, captures map
 			log.Warn("invalid manual move job, the table is not found", zap.Reflect("job", moveJob))
 			continue
 		}
-		if moveJob.To == moveJob.From {
+		if moveJob.RescanTs != 0 {
+			// the rescanned table is re-added to the same capture
+			moveJob.To = moveJob.From
+		} else if moveJob.To == moveJob.From {
 			log.Warn("invalid manual move job, the table is already exists in the target capture", zap.Reflect("job", moveJob))
 			continue
 		}
@@ -465,12 +490,16 @@ func (c *changeFeed) rebalanceTables(ctx context.Context, captures map[model.Cap
 	c.scheduler.AlignCapture(captureIDs)
 
 	_, moveTableJobs := c.scheduler.CalRebalanceOperates(0)
+	for _, job := range moveTableJobs {
+		job.Rebalance = true
+	}
 	log.Info("rebalance operations", zap.Reflect("moveTableJobs", moveTableJobs))
 	c.moveTableJobs = moveTableJobs
 	return nil
 }
 
 func (c *changeFeed) handleMoveTableJobs(ctx context.Context, captures map[model.CaptureID]*model.CaptureInfo) error {
+	defer c.updateTableMovesMetric()
 	if len(captures) == 0 {
 		return nil
 	}
@@ -499,9 +528,19 @@ func (c *changeFeed) handleMoveTableJobs(ctx context.Context, captures map[model
 		}
 		return status, true
 	}
-	for tableID, job := range c.moveTableJobs {
+	tableIDs := make([]model.TableID, 0, len(c.moveTableJobs))
+	for tableID := range c.moveTableJobs {
+		tableIDs = append(tableIDs, tableID)
+	}
+	sort.Slice(tableIDs, func(i, j int) bool { return tableIDs[i] < tableIDs[j] })
+	now := time.Now()
+	for _, tableID := range tableIDs {
+		job := c.moveTableJobs[tableID]
 		switch job.Status {
 		case model.MoveTableStatusNone:
+			if job.Rebalance && !c.acquireTableMove(now) {
+				continue
+			}
 			// delete table from original capture
 			status, exist := cloneStatus(job.From)
 			if !exist {
@@ -516,6 +555,9 @@ func (c *changeFeed) handleMoveTableJobs(ctx context.Context, captures map[model
 				continue
 			}
 			replicaInfo.StartTs = c.status.CheckpointTs
+			if job.RescanTs != 0 {
+				replicaInfo.StartTs = job.RescanTs
+			}
 			job.TableReplicaInfo = replicaInfo
 			job.Status = model.MoveTableStatusDeleted
 			log.Info("handle the move job, remove table from the source capture", zap.Reflect("job", job))
@@ -606,15 +648,16 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 	}
 	todoDDLJob := c.ddlJobHistory[0]
 
-	// Check if all the checkpointTs of capture are achieving global resolvedTs(which is equal to todoDDLJob.FinishedTS)
+	// Check if all the checkpointTs of capture are achieving global resolvedTs(which is equal to the barrier ts of todoDDLJob)
 	if len(c.taskStatus) > len(c.taskPositions) {
 		return nil
 	}
 
-	if c.status.CheckpointTs != todoDDLJob.BinlogInfo.FinishedTS {
+	if c.status.CheckpointTs != c.ddlBarrierTs(todoDDLJob) {
 		log.Debug("wait checkpoint ts",
 			zap.Uint64("checkpoint ts", c.status.CheckpointTs),
 			zap.Uint64("finish ts", todoDDLJob.BinlogInfo.FinishedTS),
+			zap.String("ddl order", c.info.Config.DDLOrder),
 			zap.String("ddl query", todoDDLJob.Query))
 		return nil
 	}
@@ -629,6 +672,7 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 	if err != nil {
 		return errors.Trace(err)
 	}
+	droppedTables := c.partiallyDroppedTables(todoDDLJob)
 	err = c.schema.HandleDDL(todoDDLJob)
 	if err != nil {
 		return errors.Trace(err)
@@ -664,23 +708,44 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 	if !c.cyclicEnabled || c.info.Config.Cyclic.SyncDDL {
 		ddlEvent.Query = binloginfo.AddSpecialComment(ddlEvent.Query)
 		log.Debug("DDL processed to make special features mysql-compatible", zap.String("query", ddlEvent.Query))
-		err = c.sink.EmitDDLEvent(ctx, ddlEvent)
-		// If DDL executing failed, pause the changefeed and print log, rather
-		// than return an error and break the running of this owner.
-		if err != nil {
-			if cerror.ErrDDLEventIgnored.NotEqual(err) {
-				c.ddlState = model.ChangeFeedDDLExecuteFailed
-				log.Error("Execute DDL failed",
-					zap.String("ChangeFeedID", c.id),
-					zap.Error(err),
-					zap.Reflect("ddlJob", todoDDLJob))
-				return cerror.ErrExecDDLFailed.GenWithStackByArgs()
-			}
+		if c.isDDLEmitted(todoDDLJob) {
+			log.Info("DDL has been emitted before the owner failed over, skip it",
+				zap.String("changefeed", c.id), zap.Reflect("ddlJob", todoDDLJob))
 		} else {
-			executed = true
+			ddlEvents := []*model.DDLEvent{ddlEvent}
+			if droppedTables != nil {
+				ddlEvents = droppedTables.events(ddlEvent, c.info.Config.Filter.DropTablesBatchSize)
+				log.Info("translate the partially filtered DROP DATABASE into DROP TABLEs",
+					zap.String("changefeed", c.id), zap.String("query", ddlEvent.Query), zap.Int("ddls", len(ddlEvents)))
+			}
+			for _, ddlEvent := range ddlEvents {
+				execution, err := c.emitDDLEvent(ctx, ddlEvent)
+				// If DDL executing failed, pause the changefeed and print log, rather
+				// than return an error and break the running of this owner.
+				if err != nil {
+					if cerror.ErrDDLEventIgnored.NotEqual(err) {
+						c.ddlState = model.ChangeFeedDDLExecuteFailed
+						log.Error("Execute DDL failed",
+							zap.String("ChangeFeedID", c.id),
+							zap.Error(err),
+							zap.Reflect("ddlJob", todoDDLJob))
+						if cerror.ErrDownstreamUnwritable.Equal(err) {
+							return errors.Trace(err)
+						}
+						return cerror.ErrExecDDLFailed.GenWithStackByArgs(
+							time.Duration(execution.DurationMs)*time.Millisecond, execution.Query, err)
+					}
+				} else {
+					executed = true
+				}
+			}
+			if executed {
+				c.recordEmittedDDL(ctx, todoDDLJob)
+			}
 		}
 	}
 	if executed {
+		ddlExecutedCounter.WithLabelValues(c.id).Inc()
 		log.Info("Execute DDL succeeded", zap.String("changefeed", c.id), zap.Reflect("ddlJob", todoDDLJob))
 	} else {
 		log.Info("Execute DDL ignored", zap.String("changefeed", c.id), zap.Reflect("ddlJob", todoDDLJob))
@@ -692,6 +757,75 @@ func (c *changeFeed) handleDDL(ctx context.Context, captures map[string]*model.C
 	return nil
 }
 
+var (
+	ddlExecutingUpdateInterval = time.Second
+	ddlExecutingLogInterval    = time.Minute
+)
+
+// emitDDLEvent executes the DDL in the sink. The owner is blocked during the execution,
+// so the executing DDL is published into the changefeed status before the execution
+// to tell a long-running DDL from a hung changefeed.
+func (c *changeFeed) emitDDLEvent(ctx context.Context, ddlEvent *model.DDLEvent) (*model.DDLExecution, error) {
+	executing := &model.ExecutingDDL{
+		CommitTs:  ddlEvent.CommitTs,
+		Query:     model.TruncateDDLQuery(ddlEvent.Query),
+		StartTime: time.Now(),
+	}
+	c.status.ExecutingDDL = executing
+	err := c.etcdCli.PutChangeFeedStatus(ctx, c.id, c.status)
+	if err != nil {
+		log.Warn("failed to publish the executing DDL", zap.String("changefeed", c.id), zap.Error(err))
+	}
+
+	executingGauge := ddlExecutingDurationGauge.WithLabelValues(c.id)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(ddlExecutingUpdateInterval)
+		defer ticker.Stop()
+		lastLogTime := executing.StartTime
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				executingGauge.Set(now.Sub(executing.StartTime).Seconds())
+				if now.Sub(lastLogTime) >= ddlExecutingLogInterval {
+					log.Info("DDL is still executing", zap.String("changefeed", c.id),
+						zap.Duration("duration", now.Sub(executing.StartTime)), zap.String("query", executing.Query))
+					lastLogTime = now
+				}
+			}
+		}
+	}()
+
+	err = c.sink.EmitDDLEvent(ctx, ddlEvent)
+	close(done)
+	wg.Wait()
+	duration := time.Since(executing.StartTime)
+	executingGauge.Set(0)
+
+	c.status.ExecutingDDL = nil
+	execution := &model.DDLExecution{
+		CommitTs:   executing.CommitTs,
+		Query:      executing.Query,
+		StartTime:  executing.StartTime,
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		if cerror.ErrDDLEventIgnored.Equal(err) {
+			return execution, err
+		}
+		execution.Error = err.Error()
+	} else {
+		ddlExecutionDurationHistogram.WithLabelValues(c.id).Observe(duration.Seconds())
+	}
+	c.status.RecordDDLExecution(execution)
+	return execution, err
+}
+
 // handleSyncPoint record every syncpoint to downstream if the syncpoint feature is enable
 func (c *changeFeed) handleSyncPoint(ctx context.Context) error {
 	//sync-point on
@@ -837,8 +971,8 @@ func (c *changeFeed) calcResolvedTs(ctx context.Context) error {
 	for len(c.ddlJobHistory) > 0 && c.ddlJobHistory[0].BinlogInfo.FinishedTS <= c.ddlExecutedTs {
 		c.ddlJobHistory = c.ddlJobHistory[1:]
 	}
-	if len(c.ddlJobHistory) > 0 && minResolvedTs >= c.ddlJobHistory[0].BinlogInfo.FinishedTS {
-		minResolvedTs = c.ddlJobHistory[0].BinlogInfo.FinishedTS
+	if len(c.ddlJobHistory) > 0 && minResolvedTs >= c.ddlBarrierTs(c.ddlJobHistory[0]) {
+		minResolvedTs = c.ddlBarrierTs(c.ddlJobHistory[0])
 		c.ddlState = model.ChangeFeedWaitToExecDDL
 		c.ddlTs = minResolvedTs
 	}
@@ -949,5 +1083,9 @@ func (c *changeFeed) Close() {
 			log.Warn("failed to close owner sink", zap.Error(err))
 		}
 	}
+	if c.integrityChecker != nil {
+		c.integrityChecker.close()
+	}
+	tableMovesInFlightGauge.DeleteLabelValues(c.id)
 	log.Info("changefeed closed", zap.String("id", c.id))
 }
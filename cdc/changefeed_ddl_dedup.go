@@ -0,0 +1,64 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
+	"go.uber.org/zap"
+)
+
+// loadEmittedDDLs loads the DDLs emitted by the previous owners if the DDL deduplication is enabled.
+// Without them, a DDL could be emitted again after the owner fails over, because the owner may
+// crash after the DDL is emitted but before the checkpoint ts of the changefeed is flushed.
+func (c *changeFeed) loadEmittedDDLs(ctx context.Context) error {
+	if c.info.Config == nil || !c.info.Config.Sink.DeduplicateDDL {
+		return nil
+	}
+	emitted, err := c.etcdCli.GetEmittedDDLs(ctx, c.id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.emittedDDLs = emitted
+	return nil
+}
+
+// isDDLEmitted returns whether the DDL job has been emitted to the sink
+func (c *changeFeed) isDDLEmitted(job *timodel.Job) bool {
+	if c.emittedDDLs == nil {
+		return false
+	}
+	ts, ok := c.emittedDDLs[job.TableID]
+	return ok && ts >= job.BinlogInfo.FinishedTS
+}
+
+// recordEmittedDDL records the DDL job emitted to the sink. The failure is
+// only logged, which makes the DDL emitted again in case of a failover.
+func (c *changeFeed) recordEmittedDDL(ctx context.Context, job *timodel.Job) {
+	if c.emittedDDLs == nil {
+		return
+	}
+	// a new owner resumes the changefeed from the checkpoint ts written to etcd,
+	// so the DDLs before it are not needed any more
+	c.emittedDDLs.Prune(c.flushedCheckpointTs)
+	c.emittedDDLs[job.TableID] = job.BinlogInfo.FinishedTS
+	err := c.etcdCli.PutEmittedDDLs(ctx, c.id, c.emittedDDLs)
+	if err != nil {
+		log.Warn("failed to record the emitted DDL", zap.String("changefeed", c.id),
+			zap.Int64("tableID", job.TableID), zap.Uint64("commitTs", job.BinlogInfo.FinishedTS), zap.Error(err))
+	}
+}
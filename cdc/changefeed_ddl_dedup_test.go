@@ -0,0 +1,170 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/types"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/store/mockstore"
+)
+
+// recordingDDLSink records the DDLs emitted to it
+type recordingDDLSink struct {
+	sink.Sink
+	queries []string
+}
+
+func (s *recordingDDLSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	s.queries = append(s.queries, ddl.Query)
+	return nil
+}
+
+func (s *ownerSuite) TestDeduplicateDDLAcrossFailover(c *check.C) {
+	ctx := s.ctx
+	jobs := []*timodel.Job{
+		{
+			ID:       1,
+			SchemaID: 1,
+			Type:     timodel.ActionCreateSchema,
+			State:    timodel.JobStateSynced,
+			Query:    "create database test",
+			BinlogInfo: &timodel.HistoryInfo{
+				SchemaVersion: 1,
+				FinishedTS:    100,
+				DBInfo:        &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test")},
+			},
+		},
+		{
+			ID:       2,
+			SchemaID: 1,
+			TableID:  47,
+			Type:     timodel.ActionCreateTable,
+			State:    timodel.JobStateSynced,
+			Query:    "create table t1 (id int primary key)",
+			BinlogInfo: &timodel.HistoryInfo{
+				SchemaVersion: 2,
+				FinishedTS:    110,
+				DBInfo:        &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test")},
+				TableInfo: &timodel.TableInfo{
+					ID:         47,
+					Name:       timodel.NewCIStr("t1"),
+					PKIsHandle: true,
+					Columns: []*timodel.ColumnInfo{
+						{ID: 1, FieldType: types.FieldType{Flag: mysql.PriKeyFlag}, State: timodel.StatePublic},
+					},
+				},
+			},
+		},
+	}
+	store, err := mockstore.NewMockTikvStore()
+	c.Assert(err, check.IsNil)
+	defer func() {
+		_ = store.Close()
+	}()
+
+	// newChangefeed simulates the changefeed started by an owner from the flushed checkpoint ts
+	newChangefeed := func(id string, deduplicate bool) (*changeFeed, *recordingDDLSink) {
+		txn, err := store.Begin()
+		c.Assert(err, check.IsNil)
+		defer func() {
+			_ = txn.Rollback()
+		}()
+		schemaSnap, err := entry.NewSingleSchemaSnapshotFromMeta(meta.NewMeta(txn), 0)
+		c.Assert(err, check.IsNil)
+		cfg := config.GetDefaultReplicaConfig()
+		cfg.Sink.DeduplicateDDL = deduplicate
+		f, err := filter.NewFilter(cfg)
+		c.Assert(err, check.IsNil)
+		mockSink := &recordingDDLSink{}
+		cf := &changeFeed{
+			id:            id,
+			info:          &model.ChangeFeedInfo{Config: cfg},
+			status:        &model.ChangeFeedStatus{CheckpointTs: 99},
+			schema:        schemaSnap,
+			schemas:       make(map[model.SchemaID]tableIDMap),
+			tables:        make(map[model.TableID]model.TableName),
+			partitions:    make(map[model.TableID][]int64),
+			orphanTables:  make(map[model.TableID]model.Ts),
+			toCleanTables: make(map[model.TableID]model.Ts),
+			ddlExecutedTs: 99,
+			ddlJobHistory: append([]*timodel.Job(nil), jobs...),
+			filter:        f,
+			sink:          mockSink,
+			etcdCli:       s.client,
+		}
+		c.Assert(cf.loadEmittedDDLs(ctx), check.IsNil)
+		return cf, mockSink
+	}
+	execNextDDL := func(cf *changeFeed) {
+		cf.status.CheckpointTs = cf.ddlJobHistory[0].BinlogInfo.FinishedTS
+		cf.ddlState = model.ChangeFeedWaitToExecDDL
+		c.Assert(cf.handleDDL(ctx, nil), check.IsNil)
+		c.Assert(cf.ddlState, check.Equals, model.ChangeFeedSyncDML)
+	}
+
+	// the owner crashes after emitting the first DDL, before the checkpoint ts is flushed
+	cf, mockSink := newChangefeed("test-dedup", true)
+	execNextDDL(cf)
+	c.Assert(mockSink.queries, check.DeepEquals, []string{"create database test"})
+	emitted, err := s.client.GetEmittedDDLs(ctx, "test-dedup")
+	c.Assert(err, check.IsNil)
+	c.Assert(emitted, check.DeepEquals, model.EmittedDDLs{0: 100})
+
+	// the new owner applies the first DDL to the schema without emitting it again
+	cf, mockSink = newChangefeed("test-dedup", true)
+	execNextDDL(cf)
+	c.Assert(mockSink.queries, check.HasLen, 0)
+	c.Assert(cf.schemas, check.HasKey, int64(1))
+	execNextDDL(cf)
+	c.Assert(mockSink.queries, check.DeepEquals, []string{"create table t1 (id int primary key)"})
+	c.Assert(cf.tables, check.HasKey, int64(47))
+	emitted, err = s.client.GetEmittedDDLs(ctx, "test-dedup")
+	c.Assert(err, check.IsNil)
+	c.Assert(emitted, check.DeepEquals, model.EmittedDDLs{0: 100, 47: 110})
+
+	// the DDLs before the written checkpoint ts are pruned
+	cf, _ = newChangefeed("test-dedup", true)
+	cf.ddlJobHistory = cf.ddlJobHistory[1:]
+	cf.flushedCheckpointTs = 105
+	cf.recordEmittedDDL(ctx, cf.ddlJobHistory[0])
+	emitted, err = s.client.GetEmittedDDLs(ctx, "test-dedup")
+	c.Assert(err, check.IsNil)
+	c.Assert(emitted, check.DeepEquals, model.EmittedDDLs{47: 110})
+
+	// the DDL is emitted again if the deduplication is disabled
+	cf, mockSink = newChangefeed("test-no-dedup", false)
+	execNextDDL(cf)
+	cf, mockSink = newChangefeed("test-no-dedup", false)
+	execNextDDL(cf)
+	c.Assert(mockSink.queries, check.DeepEquals, []string{"create database test"})
+	emitted, err = s.client.GetEmittedDDLs(ctx, "test-no-dedup")
+	c.Assert(err, check.IsNil)
+	c.Assert(emitted, check.HasLen, 0)
+
+	err = s.client.RemoveEmittedDDLs(ctx, "test-dedup")
+	c.Assert(err, check.IsNil)
+	emitted, err = s.client.GetEmittedDDLs(ctx, "test-dedup")
+	c.Assert(err, check.IsNil)
+	c.Assert(emitted, check.HasLen, 0)
+}
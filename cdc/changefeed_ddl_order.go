@@ -0,0 +1,31 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/pkg/config"
+)
+
+// ddlBarrierTs returns the ts the resolved ts of the changefeed stops at before the DDL job is
+// executed, the DDL is executed once the checkpoint ts reaches it. The DMLs committed with the
+// DDL are flushed before the DDL if the barrier is the commit ts of the DDL, and after the DDL
+// if the barrier is the ts right before it.
+func (c *changeFeed) ddlBarrierTs(job *timodel.Job) uint64 {
+	commitTs := job.BinlogInfo.FinishedTS
+	if c.info.Config.DDLOrder == config.DDLOrderDDLFirst && commitTs > 0 {
+		return commitTs - 1
+	}
+	return commitTs
+}
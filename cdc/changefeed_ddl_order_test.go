@@ -0,0 +1,141 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/store/mockstore"
+)
+
+// orderRecordingSink records the order the DDLs and the DMLs are written downstream,
+// the DMLs are written when they are flushed
+type orderRecordingSink struct {
+	sink.Sink
+	rows   []*model.RowChangedEvent
+	events []string
+}
+
+func (s *orderRecordingSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	s.rows = append(s.rows, rows...)
+	return nil
+}
+
+func (s *orderRecordingSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) (uint64, error) {
+	for len(s.rows) > 0 && s.rows[0].CommitTs <= resolvedTs {
+		s.events = append(s.events, fmt.Sprintf("dml %d", s.rows[0].CommitTs))
+		s.rows = s.rows[1:]
+	}
+	return resolvedTs, nil
+}
+
+func (s *orderRecordingSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	s.events = append(s.events, fmt.Sprintf("ddl %d", ddl.CommitTs))
+	return nil
+}
+
+func (s *orderRecordingSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+	return nil
+}
+
+func (s *ownerSuite) TestDDLOrderAtSameCommitTs(c *check.C) {
+	ctx := s.ctx
+	store, err := mockstore.NewMockTikvStore()
+	c.Assert(err, check.IsNil)
+	defer func() {
+		_ = store.Close()
+	}()
+
+	// replicate replicates the DMLs committed at 99, 100 and 101 and a DDL committed at 100
+	// by simulating the owner and a processor, and returns the order written downstream
+	replicate := func(order string) []string {
+		txn, err := store.Begin()
+		c.Assert(err, check.IsNil)
+		defer func() {
+			_ = txn.Rollback()
+		}()
+		schemaSnap, err := entry.NewSingleSchemaSnapshotFromMeta(meta.NewMeta(txn), 0)
+		c.Assert(err, check.IsNil)
+		cfg := config.GetDefaultReplicaConfig()
+		cfg.DDLOrder = order
+		f, err := filter.NewFilter(cfg)
+		c.Assert(err, check.IsNil)
+		mockSink := &orderRecordingSink{}
+		position := &model.TaskPosition{ResolvedTs: 200}
+		cf := &changeFeed{
+			id:            "test-ddl-order",
+			info:          &model.ChangeFeedInfo{Config: cfg},
+			status:        &model.ChangeFeedStatus{ResolvedTs: 98, CheckpointTs: 98},
+			ddlState:      model.ChangeFeedSyncDML,
+			targetTs:      math.MaxUint64,
+			taskStatus:    model.ProcessorsInfos{"capture-1": &model.TaskStatus{}},
+			taskPositions: map[model.CaptureID]*model.TaskPosition{"capture-1": position},
+			schema:        schemaSnap,
+			schemas:       make(map[model.SchemaID]tableIDMap),
+			tables:        make(map[model.TableID]model.TableName),
+			partitions:    make(map[model.TableID][]int64),
+			orphanTables:  make(map[model.TableID]model.Ts),
+			toCleanTables: make(map[model.TableID]model.Ts),
+			ddlResolvedTs: 200,
+			ddlExecutedTs: 98,
+			ddlJobHistory: []*timodel.Job{{
+				ID:       1,
+				SchemaID: 1,
+				Type:     timodel.ActionCreateSchema,
+				State:    timodel.JobStateSynced,
+				Query:    "create database test",
+				BinlogInfo: &timodel.HistoryInfo{
+					SchemaVersion: 1,
+					FinishedTS:    100,
+					DBInfo:        &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test")},
+				},
+			}},
+			filter:  f,
+			sink:    mockSink,
+			etcdCli: s.client,
+		}
+		for _, commitTs := range []uint64{99, 100, 101} {
+			c.Assert(mockSink.EmitRowChangedEvents(ctx, &model.RowChangedEvent{CommitTs: commitTs}), check.IsNil)
+		}
+		for i := 0; i < 10 && cf.status.CheckpointTs < 200; i++ {
+			c.Assert(cf.calcResolvedTs(ctx), check.IsNil)
+			// the processor flushes the sink to the global resolved ts
+			checkpointTs, err := mockSink.FlushRowChangedEvents(ctx, cf.status.ResolvedTs)
+			c.Assert(err, check.IsNil)
+			position.CheckPointTs = checkpointTs
+			c.Assert(cf.calcResolvedTs(ctx), check.IsNil)
+			c.Assert(cf.handleDDL(ctx, nil), check.IsNil)
+		}
+		c.Assert(cf.status.CheckpointTs, check.Equals, uint64(200))
+		c.Assert(cf.ddlJobHistory, check.HasLen, 0)
+		return mockSink.events
+	}
+
+	c.Assert(replicate(""), check.DeepEquals, []string{"dml 99", "dml 100", "ddl 100", "dml 101"})
+	c.Assert(replicate(config.DDLOrderDMLFirst), check.DeepEquals, []string{"dml 99", "dml 100", "ddl 100", "dml 101"})
+	c.Assert(replicate(config.DDLOrderDDLFirst), check.DeepEquals, []string{"dml 99", "ddl 100", "dml 100", "dml 101"})
+
+	c.Assert(config.ValidateDDLOrder(config.DDLOrderDDLFirst), check.IsNil)
+	c.Assert(config.ValidateDDLOrder("ddl-last"), check.ErrorMatches, ".*invalid ddl-order: ddl-last.*")
+}
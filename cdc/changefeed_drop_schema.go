@@ -0,0 +1,95 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"strings"
+
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/quotes"
+)
+
+const defaultDropTablesBatchSize = 64
+
+// droppedSchemaTables are the tables of a dropped schema the filter doesn't exclude
+type droppedSchemaTables struct {
+	tables []model.TableName
+	views  []model.TableName
+}
+
+// partiallyDroppedTables returns the replicated tables of the schema dropped by the job if the filter
+// excludes some other tables of the schema and the changefeed preserves them, otherwise it returns nil.
+// It must be called before the job is applied to the schema snapshot.
+func (c *changeFeed) partiallyDroppedTables(job *timodel.Job) *droppedSchemaTables {
+	if job.Type != timodel.ActionDropSchema ||
+		c.info.Config.Filter.PartialDropDatabase != config.PartialDropDatabaseDropTables {
+		return nil
+	}
+	schema, ok := c.schema.SchemaByID(job.SchemaID)
+	if !ok {
+		return nil
+	}
+	dropped := &droppedSchemaTables{}
+	partial := false
+	for _, table := range schema.Tables {
+		name := model.TableName{Schema: schema.Name.O, Table: table.Name.O}
+		if c.filter.ShouldIgnoreTable(name.Schema, name.Table) {
+			partial = true
+			continue
+		}
+		if table.IsView() {
+			dropped.views = append(dropped.views, name)
+		} else {
+			dropped.tables = append(dropped.tables, name)
+		}
+	}
+	if !partial {
+		return nil
+	}
+	return dropped
+}
+
+// events translates the DROP DATABASE into the DROP TABLEs and DROP VIEWs of the replicated tables,
+// every DDL drops at most batchSize tables.
+func (d *droppedSchemaTables) events(dropSchema *model.DDLEvent, batchSize int) []*model.DDLEvent {
+	if batchSize <= 0 {
+		batchSize = defaultDropTablesBatchSize
+	}
+	var events []*model.DDLEvent
+	translate := func(tp timodel.ActionType, keyword string, names []model.TableName) {
+		for len(names) > 0 {
+			n := batchSize
+			if n > len(names) {
+				n = len(names)
+			}
+			quoted := make([]string, 0, n)
+			for _, name := range names[:n] {
+				quoted = append(quoted, quotes.QuoteSchema(name.Schema, name.Table))
+			}
+			events = append(events, &model.DDLEvent{
+				StartTs:   dropSchema.StartTs,
+				CommitTs:  dropSchema.CommitTs,
+				TableInfo: &model.SimpleTableInfo{Schema: names[0].Schema, Table: names[0].Table},
+				Query:     keyword + " IF EXISTS " + strings.Join(quoted, ","),
+				Type:      tp,
+			})
+			names = names[n:]
+		}
+	}
+	translate(timodel.ActionDropView, "DROP VIEW", d.views)
+	translate(timodel.ActionDropTable, "DROP TABLE", d.tables)
+	return events
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/types"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/store/mockstore"
+)
+
+// filteringDDLSink ignores the DDLs of the filtered tables like the real sinks
+type filteringDDLSink struct {
+	recordingDDLSink
+	filter *filter.Filter
+}
+
+func (s *filteringDDLSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	if s.filter.ShouldIgnoreDDLEvent(ddl.StartTs, ddl.Type, ddl.TableInfo.Schema, ddl.TableInfo.Table) {
+		return cerror.ErrDDLEventIgnored.GenWithStackByArgs()
+	}
+	return s.recordingDDLSink.EmitDDLEvent(ctx, ddl)
+}
+
+func (s *ownerSuite) TestPartialDropDatabase(c *check.C) {
+	ctx := s.ctx
+	dbInfo := &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test")}
+	jobs := []*timodel.Job{{
+		ID:       1,
+		SchemaID: 1,
+		Type:     timodel.ActionCreateSchema,
+		State:    timodel.JobStateSynced,
+		Query:    "create database test",
+		BinlogInfo: &timodel.HistoryInfo{
+			SchemaVersion: 1,
+			FinishedTS:    100,
+			DBInfo:        dbInfo,
+		},
+	}}
+	for i := 1; i <= 3; i++ {
+		jobs = append(jobs, &timodel.Job{
+			ID:       int64(i + 1),
+			SchemaID: 1,
+			TableID:  int64(46 + i),
+			Type:     timodel.ActionCreateTable,
+			State:    timodel.JobStateSynced,
+			Query:    fmt.Sprintf("create table t%d (id int primary key)", i),
+			BinlogInfo: &timodel.HistoryInfo{
+				SchemaVersion: int64(i + 1),
+				FinishedTS:    uint64(100 + i*10),
+				DBInfo:        dbInfo,
+				TableInfo: &timodel.TableInfo{
+					ID:         int64(46 + i),
+					Name:       timodel.NewCIStr(fmt.Sprintf("t%d", i)),
+					PKIsHandle: true,
+					Columns: []*timodel.ColumnInfo{
+						{ID: 1, FieldType: types.FieldType{Flag: mysql.PriKeyFlag}, State: timodel.StatePublic},
+					},
+				},
+			},
+		})
+	}
+	jobs = append(jobs, &timodel.Job{
+		ID:         5,
+		SchemaID:   1,
+		Type:       timodel.ActionDropSchema,
+		State:      timodel.JobStateSynced,
+		Query:      "drop database test",
+		BinlogInfo: &timodel.HistoryInfo{SchemaVersion: 5, FinishedTS: 140, DBInfo: dbInfo},
+	})
+	store, err := mockstore.NewMockTikvStore()
+	c.Assert(err, check.IsNil)
+	defer func() {
+		_ = store.Close()
+	}()
+
+	// execDDLs replicates all the jobs and returns the emitted queries
+	execDDLs := func(cfg *config.ReplicaConfig) []string {
+		txn, err := store.Begin()
+		c.Assert(err, check.IsNil)
+		defer func() {
+			_ = txn.Rollback()
+		}()
+		schemaSnap, err := entry.NewSingleSchemaSnapshotFromMeta(meta.NewMeta(txn), 0)
+		c.Assert(err, check.IsNil)
+		f, err := filter.NewFilter(cfg)
+		c.Assert(err, check.IsNil)
+		mockSink := &filteringDDLSink{filter: f}
+		cf := &changeFeed{
+			id:            "test-partial-drop-database",
+			info:          &model.ChangeFeedInfo{Config: cfg},
+			status:        &model.ChangeFeedStatus{CheckpointTs: 99},
+			schema:        schemaSnap,
+			schemas:       make(map[model.SchemaID]tableIDMap),
+			tables:        make(map[model.TableID]model.TableName),
+			partitions:    make(map[model.TableID][]int64),
+			orphanTables:  make(map[model.TableID]model.Ts),
+			toCleanTables: make(map[model.TableID]model.Ts),
+			ddlExecutedTs: 99,
+			ddlJobHistory: append([]*timodel.Job(nil), jobs...),
+			filter:        f,
+			sink:          mockSink,
+			etcdCli:       s.client,
+		}
+		for len(cf.ddlJobHistory) > 0 {
+			cf.status.CheckpointTs = cf.ddlJobHistory[0].BinlogInfo.FinishedTS
+			cf.ddlState = model.ChangeFeedWaitToExecDDL
+			c.Assert(cf.handleDDL(ctx, nil), check.IsNil)
+			c.Assert(cf.ddlState, check.Equals, model.ChangeFeedSyncDML)
+		}
+		c.Assert(cf.schemas, check.HasLen, 0)
+		c.Assert(cf.tables, check.HasLen, 0)
+		return mockSink.queries
+	}
+	createQueries := []string{"create database test", "create table t1 (id int primary key)", "create table t2 (id int primary key)"}
+
+	// the DROP DATABASE is emitted by default
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.Rules = []string{"test.t1", "test.t2"}
+	c.Assert(execDDLs(cfg), check.DeepEquals, append(createQueries, "drop database test"))
+
+	// only the replicated tables are dropped
+	cfg = config.GetDefaultReplicaConfig()
+	cfg.Filter.Rules = []string{"test.t1", "test.t2"}
+	cfg.Filter.PartialDropDatabase = config.PartialDropDatabaseDropTables
+	c.Assert(execDDLs(cfg), check.DeepEquals, append(createQueries, "DROP TABLE IF EXISTS `test`.`t1`,`test`.`t2`"))
+
+	cfg.Filter.DropTablesBatchSize = 1
+	c.Assert(execDDLs(cfg), check.DeepEquals,
+		append(createQueries, "DROP TABLE IF EXISTS `test`.`t1`", "DROP TABLE IF EXISTS `test`.`t2`"))
+
+	// the DROP DATABASE is emitted if all the tables of the database are replicated
+	cfg = config.GetDefaultReplicaConfig()
+	cfg.Filter.PartialDropDatabase = config.PartialDropDatabaseDropTables
+	c.Assert(execDDLs(cfg), check.DeepEquals, append(createQueries, "create table t3 (id int primary key)", "drop database test"))
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"time"
+
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+// defaultTableMoveInterval is the interval of the rate limit of the table moves if it's not specified
+const defaultTableMoveInterval = 60 * time.Second
+
+// acquireTableMove returns whether a rebalance move can be initiated now, at most MaxTableMoves moves
+// are initiated in every TableMoveInterval. The moves not initiated stay in the move table jobs and
+// are initiated in the later intervals, the next rebalance happens after all of them are finished.
+// The manual moves and the rescans are not rate limited.
+func (c *changeFeed) acquireTableMove(now time.Time) bool {
+	cfg := c.info.Config.Scheduler
+	if cfg == nil || cfg.MaxTableMoves <= 0 {
+		return true
+	}
+	interval := time.Duration(cfg.TableMoveInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultTableMoveInterval
+	}
+	if now.Sub(c.tableMovesWindowStart) >= interval {
+		c.tableMovesWindowStart = now
+		c.tableMovesInWindow = 0
+	}
+	if c.tableMovesInWindow >= cfg.MaxTableMoves {
+		return false
+	}
+	c.tableMovesInWindow++
+	return true
+}
+
+// updateTableMovesMetric sets the number of the table moves which are initiated but not finished
+func (c *changeFeed) updateTableMovesMetric() {
+	inFlight := 0
+	for _, job := range c.moveTableJobs {
+		if job.Status == model.MoveTableStatusDeleted {
+			inFlight++
+		}
+	}
+	tableMovesInFlightGauge.WithLabelValues(c.id).Set(float64(inFlight))
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/scheduler"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func (s *ownerSuite) TestTableMovesRateLimited(c *check.C) {
+	ctx := s.ctx
+	const (
+		tableCount    = 20
+		maxTableMoves = 3
+	)
+	cf := s.newPriorityTestChangefeed(c, "move-limit", config.PriorityClassNormal, 1000)
+	cf.info.Config.Scheduler = &config.SchedulerConfig{
+		Tp: "table-number", PollingTime: -1, MaxTableMoves: maxTableMoves, TableMoveInterval: 60,
+	}
+	cf.scheduler = scheduler.NewScheduler("table-number")
+	cf.orphanTables = make(map[model.TableID]model.Ts)
+	cf.toCleanTables = make(map[model.TableID]model.Ts)
+	cf.rebalanceNextTick = true
+	status := &model.TaskStatus{Tables: make(map[model.TableID]*model.TableReplicaInfo)}
+	workload := make(model.TaskWorkload)
+	for i := 0; i < tableCount; i++ {
+		status.Tables[model.TableID(i)] = &model.TableReplicaInfo{StartTs: 1000}
+		workload[model.TableID(i)] = model.WorkloadInfo{Workload: 1}
+	}
+	c.Assert(s.client.PutTaskStatus(ctx, cf.id, "capture-1", status), check.IsNil)
+	c.Assert(s.client.PutTaskWorkload(ctx, cf.id, "capture-1", &workload), check.IsNil)
+	cf.taskStatus = model.ProcessorsInfos{"capture-1": status.Clone()}
+	captures := map[model.CaptureID]*model.CaptureInfo{
+		"capture-1": {ID: "capture-1"},
+		"capture-2": {ID: "capture-2"},
+	}
+	gauge := tableMovesInFlightGauge.WithLabelValues(cf.id)
+
+	// applyOperations acts as the processors, and returns the number of the tables removed
+	applyOperations := func() int {
+		removed := 0
+		for captureID, status := range cf.taskStatus {
+			for _, op := range status.Operation {
+				if op.Delete && !op.TableApplied() {
+					removed++
+				}
+				op.Status = model.OperFinished
+			}
+			c.Assert(s.client.PutTaskStatus(ctx, cf.id, captureID, status), check.IsNil)
+		}
+		return removed
+	}
+	windows := 0
+	for tick := 0; tick < 100; tick++ {
+		c.Assert(cf.tryBalance(ctx, captures, false, nil), check.IsNil)
+		for _, job := range cf.moveTableJobs {
+			c.Assert(job.Rebalance, check.IsTrue)
+		}
+		removed := applyOperations()
+		c.Assert(removed, check.LessEqual, maxTableMoves)
+		c.Assert(testutil.ToFloat64(gauge), check.Equals, float64(removed))
+		if removed > 0 {
+			windows++
+		}
+		if len(cf.moveTableJobs) == 0 && tick > 0 {
+			break
+		}
+		if removed == 0 {
+			// the moves are not initiated until the next interval
+			cf.tableMovesWindowStart = cf.tableMovesWindowStart.Add(-time.Minute)
+		}
+	}
+	c.Assert(cf.moveTableJobs, check.HasLen, 0)
+	c.Assert(cf.taskStatus["capture-1"].Tables, check.HasLen, tableCount/2)
+	c.Assert(cf.taskStatus["capture-2"].Tables, check.HasLen, tableCount/2)
+	c.Assert(windows, check.Equals, (tableCount/2+maxTableMoves-1)/maxTableMoves)
+	c.Assert(testutil.ToFloat64(gauge), check.Equals, float64(0))
+}
+
+func (s *ownerSuite) TestManualTableMovesNotRateLimited(c *check.C) {
+	ctx := s.ctx
+	cf := s.newPriorityTestChangefeed(c, "manual-move-limit", config.PriorityClassNormal, 1000)
+	cf.info.Config.Scheduler = &config.SchedulerConfig{
+		Tp: "table-number", PollingTime: -1, MaxTableMoves: 1, TableMoveInterval: 60,
+	}
+	status := &model.TaskStatus{Tables: make(map[model.TableID]*model.TableReplicaInfo)}
+	for i := 0; i < 5; i++ {
+		status.Tables[model.TableID(i)] = &model.TableReplicaInfo{StartTs: 1000}
+	}
+	c.Assert(s.client.PutTaskStatus(ctx, cf.id, "capture-1", status), check.IsNil)
+	cf.taskStatus = model.ProcessorsInfos{"capture-1": status.Clone()}
+	captures := map[model.CaptureID]*model.CaptureInfo{
+		"capture-1": {ID: "capture-1"},
+		"capture-2": {ID: "capture-2"},
+	}
+	// the window is used up by the rebalance moves, the moves of tables 3 and 4 are held back
+	cf.tableMovesWindowStart = time.Now()
+	cf.tableMovesInWindow = 1
+	cf.moveTableJobs = map[model.TableID]*model.MoveTableJob{
+		3: {From: "capture-1", To: "capture-2", TableID: 3, Rebalance: true},
+		4: {From: "capture-1", To: "capture-2", TableID: 4, Rebalance: true},
+	}
+
+	// the manual moves don't wait for the rebalance moves, and replace the one of the same table
+	cf.manualMoveCommands = []*model.MoveTableJob{
+		{To: "capture-2", TableID: 0},
+		{To: "capture-2", TableID: 1},
+		{TableID: 2, RescanTs: 1100},
+		{TableID: 3, RescanTs: 1100},
+	}
+	c.Assert(cf.handleManualMoveTableJobs(ctx, captures), check.IsNil)
+	c.Assert(cf.manualMoveCommands, check.HasLen, 0)
+	c.Assert(cf.moveTableJobs, check.HasLen, 5)
+	c.Assert(cf.handleMoveTableJobs(ctx, captures), check.IsNil)
+	for tableID, job := range cf.moveTableJobs {
+		if tableID == 4 {
+			c.Assert(job.Rebalance, check.IsTrue)
+			c.Assert(job.Status, check.Equals, model.MoveTableStatusNone)
+			continue
+		}
+		c.Assert(job.Rebalance, check.IsFalse)
+		c.Assert(job.Status, check.Equals, model.MoveTableStatusDeleted, check.Commentf("table %d", tableID))
+	}
+	c.Assert(cf.moveTableJobs[3].RescanTs, check.Equals, uint64(1100))
+	c.Assert(cf.tableMovesInWindow, check.Equals, 1)
+
+	// the table being moved is not moved again until the move is finished
+	cf.manualMoveCommands = []*model.MoveTableJob{{To: "capture-2", TableID: 0}}
+	c.Assert(cf.handleManualMoveTableJobs(ctx, captures), check.IsNil)
+	c.Assert(cf.manualMoveCommands, check.HasLen, 1)
+}
+
+func (s *ownerSuite) TestSchedulerConfigValidate(c *check.C) {
+	cfg := config.GetDefaultReplicaConfig().Scheduler
+	c.Assert(cfg.Validate(), check.IsNil)
+	cfg.MaxTableMoves = -1
+	c.Assert(cfg.Validate(), check.ErrorMatches, ".*max-table-moves must not be negative.*")
+	cfg.MaxTableMoves = 0
+	cfg.TableMoveInterval = -1
+	c.Assert(cfg.Validate(), check.ErrorMatches, ".*table-move-interval must not be negative.*")
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// slowDDLSink blocks the DDL execution until it is released
+type slowDDLSink struct {
+	sink.Sink
+	started chan *model.DDLEvent
+	release chan error
+}
+
+func (s *slowDDLSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	s.started <- ddl
+	return <-s.release
+}
+
+func (s *ownerSuite) TestEmitDDLEventStatus(c *check.C) {
+	defer func(interval time.Duration) { ddlExecutingUpdateInterval = interval }(ddlExecutingUpdateInterval)
+	ddlExecutingUpdateInterval = 10 * time.Millisecond
+
+	ctx := s.ctx
+	mockSink := &slowDDLSink{started: make(chan *model.DDLEvent), release: make(chan error)}
+	cf := &changeFeed{
+		id:      "test-ddl-status",
+		status:  &model.ChangeFeedStatus{CheckpointTs: 100},
+		sink:    mockSink,
+		etcdCli: s.client,
+	}
+	type result struct {
+		execution *model.DDLExecution
+		err       error
+	}
+	emit := func(query string, commitTs uint64) <-chan result {
+		resultCh := make(chan result, 1)
+		go func() {
+			execution, err := cf.emitDDLEvent(ctx, &model.DDLEvent{CommitTs: commitTs, Query: query})
+			resultCh <- result{execution: execution, err: err}
+		}()
+		<-mockSink.started
+		return resultCh
+	}
+
+	// the executing DDL is published before the sink finishes it
+	resultCh := emit("alter table t add index idx(a)", 101)
+	status, _, err := s.client.GetChangeFeedStatus(ctx, cf.id)
+	c.Assert(err, check.IsNil)
+	c.Assert(status.ExecutingDDL, check.NotNil)
+	c.Assert(status.ExecutingDDL.CommitTs, check.Equals, uint64(101))
+	c.Assert(status.ExecutingDDL.Query, check.Equals, "alter table t add index idx(a)")
+	c.Assert(status.ExecutingDDL.String(), check.Matches, "executing DDL since .*: alter table t add index idx\\(a\\)")
+	gauge := ddlExecutingDurationGauge.WithLabelValues(cf.id)
+	for i := 0; i < 100 && testutil.ToFloat64(gauge) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(testutil.ToFloat64(gauge), check.Greater, float64(0))
+
+	mockSink.release <- nil
+	res := <-resultCh
+	c.Assert(res.err, check.IsNil)
+	c.Assert(testutil.ToFloat64(gauge), check.Equals, float64(0))
+	c.Assert(cf.status.ExecutingDDL, check.IsNil)
+	c.Assert(cf.status.DDLHistory, check.HasLen, 1)
+	c.Assert(cf.status.DDLHistory[0], check.Equals, res.execution)
+	c.Assert(res.execution.CommitTs, check.Equals, uint64(101))
+	c.Assert(res.execution.DurationMs, check.GreaterEqual, int64(10))
+	c.Assert(res.execution.Error, check.Equals, "")
+
+	// the failed DDL keeps the statement and the error
+	resultCh = emit("drop table t", 102)
+	mockSink.release <- errors.New("downstream is gone")
+	res = <-resultCh
+	c.Assert(res.err, check.ErrorMatches, "downstream is gone")
+	c.Assert(cf.status.ExecutingDDL, check.IsNil)
+	c.Assert(cf.status.DDLHistory, check.HasLen, 2)
+	c.Assert(cf.status.DDLHistory[1].Query, check.Equals, "drop table t")
+	c.Assert(cf.status.DDLHistory[1].Error, check.Equals, "downstream is gone")
+
+	// the long query is truncated and only the last DDLs are kept
+	longQuery := "create table t (" + strings.Repeat("a int, ", 100) + "b int)"
+	for i := 0; i < 5; i++ {
+		resultCh = emit(longQuery, uint64(103+i))
+		mockSink.release <- nil
+		res = <-resultCh
+		c.Assert(res.err, check.IsNil)
+	}
+	c.Assert(cf.status.DDLHistory, check.HasLen, 5)
+	c.Assert(cf.status.DDLHistory[0].CommitTs, check.Equals, uint64(103))
+	c.Assert(cf.status.DDLHistory[4].Query, check.HasLen, 259)
+	c.Assert(strings.HasSuffix(cf.status.DDLHistory[4].Query, "..."), check.IsTrue)
+}
@@ -0,0 +1,224 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/codec"
+)
+
+type addIndexSuite struct{}
+
+var _ = check.Suite(&addIndexSuite{})
+
+const (
+	addIndexTableID = 44
+
+	// the unique index on (a, b) exists before the ADD INDEX
+	addIndexUniqueAB = 1
+	// the indexes added by the ADD INDEX
+	addIndexUniqueID    = 2
+	addIndexCompositeCD = 3
+	addIndexPrefixC     = 4
+)
+
+// newAddIndexTableInfo returns the table info of
+// `t(id bigint not null, a bigint not null, b bigint not null, c varchar(32), d bigint, unique key uk_ab(a, b))`,
+// with the indexes added in the given state. No index is added if the state is StateNone.
+func newAddIndexTableInfo(state timodel.SchemaState) *model.TableInfo {
+	notNullType := types.NewFieldType(mysql.TypeLonglong)
+	notNullType.Flag = mysql.NotNullFlag
+	varcharType := types.NewFieldType(mysql.TypeVarchar)
+	varcharType.Flen = 32
+	intType := types.NewFieldType(mysql.TypeLonglong)
+	column := func(id int64, name string, tp *types.FieldType) *timodel.ColumnInfo {
+		return &timodel.ColumnInfo{
+			ID: id, Name: timodel.NewCIStr(name), Offset: int(id - 1), FieldType: *tp, State: timodel.StatePublic,
+		}
+	}
+	index := func(id int64, name string, unique bool, state timodel.SchemaState, cols ...*timodel.IndexColumn) *timodel.IndexInfo {
+		return &timodel.IndexInfo{ID: id, Name: timodel.NewCIStr(name), Unique: unique, Columns: cols, State: state}
+	}
+	info := &timodel.TableInfo{
+		ID:   addIndexTableID,
+		Name: timodel.NewCIStr("t"),
+		Columns: []*timodel.ColumnInfo{
+			column(1, "id", notNullType),
+			column(2, "a", notNullType),
+			column(3, "b", notNullType),
+			column(4, "c", varcharType),
+			column(5, "d", intType),
+		},
+		Indices: []*timodel.IndexInfo{
+			index(addIndexUniqueAB, "uk_ab", true, timodel.StatePublic,
+				&timodel.IndexColumn{Name: timodel.NewCIStr("a"), Offset: 1, Length: types.UnspecifiedLength},
+				&timodel.IndexColumn{Name: timodel.NewCIStr("b"), Offset: 2, Length: types.UnspecifiedLength}),
+		},
+	}
+	if state != timodel.StateNone {
+		info.Indices = append(info.Indices,
+			index(addIndexUniqueID, "uk_id", true, state,
+				&timodel.IndexColumn{Name: timodel.NewCIStr("id"), Offset: 0, Length: types.UnspecifiedLength}),
+			index(addIndexCompositeCD, "idx_cd", false, state,
+				&timodel.IndexColumn{Name: timodel.NewCIStr("c"), Offset: 3, Length: types.UnspecifiedLength},
+				&timodel.IndexColumn{Name: timodel.NewCIStr("d"), Offset: 4, Length: types.UnspecifiedLength}),
+			index(addIndexPrefixC, "idx_c", false, state,
+				&timodel.IndexColumn{Name: timodel.NewCIStr("c"), Offset: 3, Length: 4}))
+	}
+	return model.WrapTableInfo(1, "test", 1, info)
+}
+
+// newAddIndexSchemaStorage returns a schema storage with a snapshot for each table info,
+// the i-th table info takes effect from ts 100*i.
+func newAddIndexSchemaStorage(c *check.C, tableInfos ...*model.TableInfo) *SchemaStorage {
+	storage := &SchemaStorage{resolvedTs: uint64(100 * len(tableInfos))}
+	for i, tableInfo := range tableInfos {
+		snap := newEmptySchemaSnapshot()
+		snap.currentTs = uint64(100 * i)
+		c.Assert(snap.createSchema(&timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test")}), check.IsNil)
+		c.Assert(snap.createTable(tableInfo), check.IsNil)
+		storage.snaps = append(storage.snaps, snap)
+	}
+	return storage
+}
+
+// addIndexRow is a row of the table, _tidb_rowid is the handle
+type addIndexRow struct {
+	handle int64
+	id     int64
+	a, b   int64
+	c      string
+	d      int64
+}
+
+var addIndexRows = []addIndexRow{
+	{handle: 1, id: 10, a: 1, b: 1, c: "alpha", d: 100},
+	{handle: 2, id: 20, a: 1, b: 2, c: "alpine", d: 200},
+	{handle: 3, id: 30, a: 2, b: 1, c: "beta", d: 100},
+}
+
+// indexKV returns the raw KV entry of the row in the index, the index keys written by the backfill
+// and the concurrent DMLs are the same.
+func (r addIndexRow) indexKV(c *check.C, indexID int64, opType model.OpType, crts uint64) *model.RawKVEntry {
+	sc := &stmtctx.StatementContext{TimeZone: time.UTC}
+	var datums []types.Datum
+	unique := false
+	switch indexID {
+	case addIndexUniqueAB:
+		datums, unique = []types.Datum{types.NewIntDatum(r.a), types.NewIntDatum(r.b)}, true
+	case addIndexUniqueID:
+		datums, unique = []types.Datum{types.NewIntDatum(r.id)}, true
+	case addIndexCompositeCD:
+		datums = []types.Datum{types.NewStringDatum(r.c), types.NewIntDatum(r.d)}
+	case addIndexPrefixC:
+		datums = []types.Datum{types.NewStringDatum(r.c[:4])}
+	}
+	value := []byte{'0'}
+	if unique {
+		value = make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(r.handle))
+	} else {
+		// the handle is a part of the key of a non-unique index
+		datums = append(datums, types.NewIntDatum(r.handle))
+	}
+	encoded, err := codec.EncodeKey(sc, nil, datums...)
+	c.Assert(err, check.IsNil)
+	raw := &model.RawKVEntry{
+		OpType:  opType,
+		Key:     tablecodec.EncodeIndexSeekKey(addIndexTableID, indexID, encoded),
+		StartTs: crts - 1,
+		CRTs:    crts,
+	}
+	if opType == model.OpTypePut {
+		raw.Value = value
+	}
+	return raw
+}
+
+func (s *addIndexSuite) TestHandleIndexIgnoresNonPublicIndex(c *check.C) {
+	for _, state := range []timodel.SchemaState{
+		timodel.StateDeleteOnly, timodel.StateWriteOnly, timodel.StateWriteReorganization, timodel.StateDeleteReorganization,
+	} {
+		c.Assert(newAddIndexTableInfo(state).HandleIndexID, check.Equals, int64(addIndexUniqueAB))
+	}
+	// the unique index with fewer columns is the handle once it's public
+	c.Assert(newAddIndexTableInfo(timodel.StatePublic).HandleIndexID, check.Equals, int64(addIndexUniqueID))
+}
+
+func (s *addIndexSuite) TestAddIndexBackfill(c *check.C) {
+	ctx := context.Background()
+	// the ADD INDEX is running from ts 100 to 200
+	storage := newAddIndexSchemaStorage(c,
+		newAddIndexTableInfo(timodel.StateNone),
+		newAddIndexTableInfo(timodel.StateWriteReorganization),
+		newAddIndexTableInfo(timodel.StatePublic),
+	)
+	newIndexes := []int64{addIndexUniqueID, addIndexCompositeCD, addIndexPrefixC}
+	for _, enableOldValue := range []bool{true, false} {
+		m := &mounterImpl{schemaStorage: storage, tz: time.UTC, enableOldValue: enableOldValue}
+		mount := func(raw *model.RawKVEntry) *model.RowChangedEvent {
+			row, err := m.unmarshalAndMountRowChanged(ctx, raw)
+			c.Assert(err, check.IsNil)
+			return row
+		}
+
+		for _, crts := range []uint64{50, 150, 250} {
+			// the backfill writes the index KVs of all the rows
+			for _, row := range addIndexRows {
+				for _, indexID := range newIndexes {
+					c.Assert(mount(row.indexKV(c, indexID, model.OpTypePut, crts)), check.IsNil)
+				}
+			}
+			if crts > 200 {
+				continue
+			}
+			// the index KVs of the added indexes are deleted along with the rows during the ADD INDEX
+			for _, indexID := range newIndexes {
+				c.Assert(mount(addIndexRows[1].indexKV(c, indexID, model.OpTypeDelete, crts)), check.IsNil)
+			}
+		}
+
+		deleted := mount(addIndexRows[1].indexKV(c, addIndexUniqueAB, model.OpTypeDelete, 150))
+		deletedAfterAddIndex := mount(addIndexRows[1].indexKV(c, addIndexUniqueID, model.OpTypeDelete, 250))
+		if enableOldValue {
+			// the row changed events come from the record KVs
+			c.Assert(deleted, check.IsNil)
+			c.Assert(deletedAfterAddIndex, check.IsNil)
+			continue
+		}
+		// the delete is emitted from the handle index if the old value is disabled
+		c.Assert(deleted, check.NotNil)
+		c.Assert(deleted.IsDelete(), check.IsTrue)
+		c.Assert(deleted.PreColumns[1].Value, check.Equals, int64(1))
+		c.Assert(deleted.PreColumns[2].Value, check.Equals, int64(2))
+		// the other columns are unavailable
+		c.Assert(deleted.PreColumns[0], check.IsNil)
+		c.Assert(deleted.PreColumns[3], check.IsNil)
+		// the added unique index is the handle once it's public
+		c.Assert(deletedAfterAddIndex, check.NotNil)
+		c.Assert(deletedAfterAddIndex.IsDelete(), check.IsTrue)
+		c.Assert(deletedAfterAddIndex.PreColumns[0].Value, check.Equals, int64(20))
+		c.Assert(deletedAfterAddIndex.PreColumns[1], check.IsNil)
+	}
+}
@@ -117,15 +117,24 @@ func decodeRecordID(key []byte) (rest []byte, recordID int64, err error) {
 	return
 }
 
-func decodeIndexKey(key []byte) (indexID int64, indexValue []types.Datum, err error) {
+// decodeIndexID decodes the index ID of the index key, the encoded index values are returned
+func decodeIndexID(key []byte) (indexID int64, rest []byte, err error) {
 	if len(key) < prefixIndexLen || !bytes.HasPrefix(key, indexPrefix) {
 		return 0, nil, cerror.ErrInvalidRecordKey.GenWithStackByArgs(key)
 	}
 	key = key[indexPrefixLen:]
-	key, indexID, err = codec.DecodeInt(key)
+	rest, indexID, err = codec.DecodeInt(key)
 	if err != nil {
 		return 0, nil, cerror.WrapError(cerror.ErrCodecDecode, err)
 	}
+	return
+}
+
+func decodeIndexKey(key []byte) (indexID int64, indexValue []types.Datum, err error) {
+	indexID, key, err = decodeIndexID(key)
+	if err != nil {
+		return 0, nil, err
+	}
 	indexValue, err = codec.Decode(key, 2)
 	if err != nil {
 		return 0, nil, cerror.WrapError(cerror.ErrCodecDecode, err)
@@ -228,6 +237,11 @@ func decodeRowV1(b []byte, recordID int64, tableInfo *model.TableInfo, tz *time.
 			// can not find column info, ignore this column because the column should be in WRITE ONLY state
 			continue
 		}
+		// keep the raw encoded value of the unknown type, the mounter decides how to handle it
+		if !isKnownColumnType(colInfo.Tp) {
+			row[id] = types.NewBytesDatum(data)
+			continue
+		}
 		fieldType := &colInfo.FieldType
 		datum, err := unflatten(v, fieldType, tz)
 		if err != nil {
@@ -251,6 +265,19 @@ func decodeRowV1(b []byte, recordID int64, tableInfo *model.TableInfo, tz *time.
 //      https://github.com/pingcap/tidb/blob/master/docs/design/2018-07-19-row-format.md
 func decodeRowV2(data []byte, recordID int64, tableInfo *model.TableInfo, tz *time.Location) (map[int64]types.Datum, error) {
 	handleColID, reqCols := tableInfo.GetRowColInfos()
+	copied := false
+	for i, col := range reqCols {
+		if isKnownColumnType(byte(col.Tp)) {
+			continue
+		}
+		// the column infos are shared by all rows of the table, copy them before modifying
+		if !copied {
+			reqCols = append([]rowcodec.ColInfo(nil), reqCols...)
+			copied = true
+		}
+		// decode the unknown type as a blob to keep the raw bytes, the mounter decides how to handle it
+		reqCols[i].Tp = int32(mysql.TypeBlob)
+	}
 	decoder := rowcodec.NewDatumMapDecoder(reqCols, handleColID, tz)
 	datums, err := decoder.DecodeToDatumMap(data, recordID, nil)
 	if err != nil {
@@ -259,6 +286,20 @@ func decodeRowV2(data []byte, recordID int64, tableInfo *model.TableInfo, tz *ti
 	return datums, nil
 }
 
+// isKnownColumnType returns whether the column type can be decoded by the mounter
+func isKnownColumnType(tp byte) bool {
+	switch tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong,
+		mysql.TypeYear, mysql.TypeFloat, mysql.TypeDouble, mysql.TypeNewDecimal,
+		mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeString,
+		mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeBlob, mysql.TypeLongBlob,
+		mysql.TypeDate, mysql.TypeDatetime, mysql.TypeTimestamp, mysql.TypeDuration,
+		mysql.TypeEnum, mysql.TypeSet, mysql.TypeBit, mysql.TypeJSON:
+		return true
+	}
+	return false
+}
+
 // unflatten converts a raw datum to a column datum.
 func unflatten(datum types.Datum, ft *types.FieldType, loc *time.Location) (types.Datum, error) {
 	if datum.IsNull() {
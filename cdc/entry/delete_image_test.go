@@ -0,0 +1,139 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/store/mockstore"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/rowcodec"
+)
+
+type deleteImageSuite struct{}
+
+var _ = check.Suite(&deleteImageSuite{})
+
+const deleteImageTableID = 42
+
+func newDeleteImageTableInfo() *model.TableInfo {
+	idType := types.NewFieldType(mysql.TypeLonglong)
+	idType.Flag = mysql.PriKeyFlag | mysql.NotNullFlag
+	nameType := types.NewFieldType(mysql.TypeVarchar)
+	info := &timodel.TableInfo{
+		ID:         deleteImageTableID,
+		Name:       timodel.NewCIStr("t"),
+		PKIsHandle: true,
+		Columns: []*timodel.ColumnInfo{
+			{ID: 1, Name: timodel.NewCIStr("id"), Offset: 0, FieldType: *idType, State: timodel.StatePublic},
+			{ID: 2, Name: timodel.NewCIStr("name"), Offset: 1, FieldType: *nameType, State: timodel.StatePublic},
+		},
+	}
+	return model.WrapTableInfo(1, "test", 1, info)
+}
+
+// mountDelete mounts a handle-only deleted row, the old value is missing from the raw KV entry
+func mountDelete(ctx context.Context, m *mounterImpl, tableInfo *model.TableInfo, handle int64, crts uint64) (*model.RowChangedEvent, error) {
+	raw := &model.RawKVEntry{
+		OpType:  model.OpTypeDelete,
+		Key:     tablecodec.EncodeRowKeyWithHandle(deleteImageTableID, handle),
+		StartTs: crts - 1,
+		CRTs:    crts,
+	}
+	key, _, err := decodeTableID(raw.Key)
+	if err != nil {
+		return nil, err
+	}
+	oldValue, err := m.loadDeleteImage(ctx, tableInfo, raw, key)
+	if err != nil {
+		return nil, err
+	}
+	rowKV, err := m.unmarshalRowKVEntry(tableInfo, key, raw.Value, oldValue, baseKVEntry{
+		StartTs:         raw.StartTs,
+		CRTs:            raw.CRTs,
+		PhysicalTableID: deleteImageTableID,
+		Delete:          true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.mountRowKVEntry(tableInfo, rowKV, raw.ApproximateSize())
+}
+
+func (s *deleteImageSuite) TestBestEffort(c *check.C) {
+	ctx := context.Background()
+	m := &mounterImpl{tz: time.UTC, enableOldValue: true, deleteImage: config.DeleteImageBestEffort}
+	row, err := mountDelete(ctx, m, newDeleteImageTableInfo(), 1, 10)
+	c.Assert(err, check.IsNil)
+	c.Assert(row.IsDelete(), check.IsTrue)
+	// only the handle column is available
+	c.Assert(row.PreColumns, check.HasLen, 2)
+	c.Assert(row.PreColumns[0].Name, check.Equals, "id")
+	c.Assert(row.PreColumns[0].Value, check.Equals, int64(1))
+	c.Assert(row.PreColumns[1], check.IsNil)
+
+	// nothing can be emitted if the handle is not the primary key
+	tableInfo := newDeleteImageTableInfo()
+	tableInfo.PKIsHandle = false
+	row, err = mountDelete(ctx, m, tableInfo, 1, 10)
+	c.Assert(err, check.IsNil)
+	c.Assert(row, check.IsNil)
+}
+
+func (s *deleteImageSuite) TestRequire(c *check.C) {
+	m := &mounterImpl{tz: time.UTC, enableOldValue: true, deleteImage: config.DeleteImageRequire}
+	_, err := mountDelete(context.Background(), m, newDeleteImageTableInfo(), 1, 10)
+	c.Assert(cerror.ErrDeleteImageMissing.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*table: test.t, handle: 1.*")
+}
+
+func (s *deleteImageSuite) TestFetch(c *check.C) {
+	ctx := context.Background()
+	store, err := mockstore.NewMockTikvStore()
+	c.Assert(err, check.IsNil)
+	defer store.Close() //nolint:errcheck
+
+	oldValue, err := tablecodec.EncodeRow(&stmtctx.StatementContext{TimeZone: time.UTC},
+		[]types.Datum{types.NewStringDatum("alice")}, []int64{2}, nil, nil, &rowcodec.Encoder{Enable: true})
+	c.Assert(err, check.IsNil)
+	txn, err := store.Begin()
+	c.Assert(err, check.IsNil)
+	err = txn.Set(tablecodec.EncodeRowKeyWithHandle(deleteImageTableID, 1), oldValue)
+	c.Assert(err, check.IsNil)
+	err = txn.Commit(ctx)
+	c.Assert(err, check.IsNil)
+	ver, err := store.CurrentVersion()
+	c.Assert(err, check.IsNil)
+
+	m := &mounterImpl{tz: time.UTC, enableOldValue: true, deleteImage: config.DeleteImageFetch, kvStorage: store}
+	row, err := mountDelete(ctx, m, newDeleteImageTableInfo(), 1, ver.Ver+1)
+	c.Assert(err, check.IsNil)
+	c.Assert(row.IsDelete(), check.IsTrue)
+	c.Assert(row.PreColumns, check.HasLen, 2)
+	c.Assert(row.PreColumns[0].Value, check.Equals, int64(1))
+	c.Assert(row.PreColumns[1].Value, check.DeepEquals, []byte("alice"))
+
+	// the row does not exist before the delete
+	_, err = mountDelete(ctx, m, newDeleteImageTableInfo(), 2, ver.Ver+1)
+	c.Assert(cerror.ErrDeleteImageMissing.Equal(err), check.IsTrue)
+}
@@ -33,10 +33,18 @@ var (
 			Help:      "Bucketed histogram of processing time (s) of unmarshal and mount in mounter.",
 			Buckets:   prometheus.ExponentialBuckets(0.000001, 10, 10),
 		}, []string{"capture", "changefeed"})
+	mounterSkippedRowsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "mounter",
+			Name:      "skipped_rows_count",
+			Help:      "The number of rows failed to mount and skipped by the decode error policy",
+		}, []string{"capture", "changefeed"})
 )
 
 // InitMetrics registers all metrics in this file
 func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(mounterInputChanSizeGauge)
 	registry.MustRegister(mountDuration)
+	registry.MustRegister(mounterSkippedRowsCounter)
 }
@@ -0,0 +1,94 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"time"
+
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/timeutil"
+)
+
+// getMissingColumnValue returns the value of a column missing in a row. A column is missing if the row is
+// written before the column is added, which TiDB doesn't backfill but reads as the origin default value
+// of the column, or if the value is null and the column has no default value when the row is written.
+func (m *mounterImpl) getMissingColumnValue(col *timodel.ColumnInfo) (interface{}, error) {
+	if m.missingColumnDefault == config.MissingColumnCurrentDefault {
+		return getDefaultOrZeroValue(col), nil
+	}
+	defaultValue := col.OriginDefaultValue
+	// the bytes of the default value of a bit column are corrupted by the JSON encoding of the column info
+	if col.Tp == mysql.TypeBit && col.DefaultValueBit != nil && defaultValue != nil {
+		defaultValue = col.DefaultValueBit
+	}
+	if defaultValue == nil {
+		if !mysql.HasNotNullFlag(col.Flag) {
+			return nil, nil
+		}
+		return getZeroValue(col), nil
+	}
+	// the origin default value of a not null enum column added without a default value is empty,
+	// which is read as the index 0 by TiDB
+	if col.Tp == mysql.TypeEnum && defaultValue == "" {
+		return uint64(0), nil
+	}
+
+	sc := &stmtctx.StatementContext{TimeZone: time.UTC}
+	var datum types.Datum
+	if col.Tp == mysql.TypeTimestamp {
+		t, err := m.parseTimestampDefault(sc, col, defaultValue)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		datum = types.NewTimeDatum(t)
+	} else {
+		d := types.NewDatum(defaultValue)
+		var err error
+		datum, err = d.ConvertTo(sc, &col.FieldType)
+		if err != nil {
+			return nil, errors.Annotatef(err, "can not convert the origin default value of column %s", col.Name.O)
+		}
+	}
+	value, _, err := formatColVal(datum, col.Tp)
+	return value, errors.Trace(err)
+}
+
+// parseTimestampDefault parses the default value of a timestamp column to the time zone of the mounter,
+// the default value of a column of version 0 is in the system time zone, and in UTC since version 1
+func (m *mounterImpl) parseTimestampDefault(sc *stmtctx.StatementContext, col *timodel.ColumnInfo, defaultValue interface{}) (types.Time, error) {
+	s, ok := defaultValue.(string)
+	if !ok {
+		return types.ZeroTime, errors.Errorf("unexpected origin default value %v of timestamp column %s", defaultValue, col.Name.O)
+	}
+	t, err := types.ParseTime(sc, s, col.Tp, int8(col.Decimal))
+	if err != nil {
+		return types.ZeroTime, errors.Annotatef(err, "can not parse the origin default value of column %s", col.Name.O)
+	}
+	if t.IsZero() || m.tz == nil {
+		return t, nil
+	}
+	from := time.UTC
+	if col.Version < timodel.ColumnInfoVersion1 {
+		from = timeutil.SystemLocation()
+	}
+	if err := t.ConvertTimeZone(from, m.tz); err != nil {
+		return types.ZeroTime, errors.Trace(err)
+	}
+	return t, nil
+}
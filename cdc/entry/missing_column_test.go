@@ -0,0 +1,115 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"time"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/session"
+	"github.com/pingcap/tidb/store/mockstore"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/testkit"
+)
+
+type missingColumnSuite struct{}
+
+var _ = check.Suite(&missingColumnSuite{})
+
+// mountTableRows mounts the rows of the table stored in TiKV in the order of their handles
+func mountTableRows(c *check.C, m *mounterImpl, store kv.Storage, tableInfo *model.TableInfo) [][]interface{} {
+	txn, err := store.Begin()
+	c.Assert(err, check.IsNil)
+	defer txn.Rollback() //nolint:errcheck
+	prefix := tablecodec.GenTableRecordPrefix(tableInfo.ID)
+	iter, err := txn.Iter(prefix, prefix.PrefixNext())
+	c.Assert(err, check.IsNil)
+	defer iter.Close()
+	var rows [][]interface{}
+	for ; iter.Valid(); c.Assert(iter.Next(), check.IsNil) {
+		key, _, err := decodeTableID(iter.Key())
+		c.Assert(err, check.IsNil)
+		rowKV, err := m.unmarshalRowKVEntry(tableInfo, key, iter.Value(), nil, baseKVEntry{
+			StartTs:         9,
+			CRTs:            10,
+			PhysicalTableID: tableInfo.ID,
+		})
+		c.Assert(err, check.IsNil)
+		row, err := m.mountRowKVEntry(tableInfo, rowKV, int64(len(iter.Value())))
+		c.Assert(err, check.IsNil)
+		rows = append(rows, columnValues(row.Columns))
+	}
+	return rows
+}
+
+func columnValues(cols []*model.Column) []interface{} {
+	values := make([]interface{}, 0, len(cols))
+	for _, col := range cols {
+		values = append(values, col.Value)
+	}
+	return values
+}
+
+func (s *missingColumnSuite) TestInstantAddColumn(c *check.C) {
+	store, err := mockstore.NewMockTikvStore()
+	c.Assert(err, check.IsNil)
+	defer store.Close() //nolint:errcheck
+	session.SetSchemaLease(0)
+	session.DisableStats4Test()
+	domain, err := session.BootstrapSession(store)
+	c.Assert(err, check.IsNil)
+	defer domain.Close()
+	domain.SetStatsUpdating(true)
+
+	tk := testkit.NewTestKit(c, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (id int primary key, a int)")
+	tk.MustExec("insert into t values (1, 1)")
+	// the rows before the columns are added aren't backfilled
+	tk.MustExec("alter table t add column b int default 5, algorithm=instant")
+	tk.MustExec("alter table t add column c varchar(16) not null default 'x', algorithm=instant")
+	tk.MustExec("set @@time_zone = '+08:00'")
+	tk.MustExec("alter table t add column ts timestamp not null default '2020-01-01 08:00:00', algorithm=instant")
+	tk.MustExec("alter table t add column e enum('p', 'q') not null, algorithm=instant")
+	tk.MustExec("alter table t add column s set('p', 'q') not null, algorithm=instant")
+	tk.MustExec("alter table t add column bt bit(4) not null default b'101', algorithm=instant")
+	tk.MustExec("alter table t add column d decimal(5, 2) default 1.5, algorithm=instant")
+	tk.MustExec("alter table t alter column b set default 7")
+	tk.MustExec("insert into t (id, a, s) values (2, 2, 'q')")
+	tk.MustExec("insert into t values (3, 3, null, 'y', '2020-06-01 08:00:00', 'q', 'p,q', b'1', null)")
+
+	tbl, err := domain.InfoSchema().TableByName(timodel.NewCIStr("test"), timodel.NewCIStr("t"))
+	c.Assert(err, check.IsNil)
+	tableInfo := model.WrapTableInfo(1, "test", 1, tbl.Meta())
+
+	for _, mode := range []string{"", config.MissingColumnOriginDefault} {
+		m := &mounterImpl{tz: time.UTC, missingColumnDefault: mode}
+		c.Assert(mountTableRows(c, m, store, tableInfo), check.DeepEquals, [][]interface{}{
+			// the values of the columns added later are the default values when the columns are added
+			{int64(1), int64(1), int64(5), []byte("x"), "2020-01-01 00:00:00", uint64(0), uint64(0), uint64(5), "1.50"},
+			{int64(2), int64(2), int64(7), []byte("x"), "2020-01-01 00:00:00", uint64(1), uint64(2), uint64(5), "1.50"},
+			{int64(3), int64(3), nil, []byte("y"), "2020-06-01 00:00:00", uint64(2), uint64(3), uint64(1), nil},
+		}, check.Commentf("%s", mode))
+	}
+
+	// the current default value of the column is used, or null if the column is nullable
+	m := &mounterImpl{tz: time.UTC, missingColumnDefault: config.MissingColumnCurrentDefault}
+	c.Assert(mountTableRows(c, m, store, tableInfo)[0][:4], check.DeepEquals, []interface{}{
+		int64(1), int64(1), nil, "x",
+	})
+}
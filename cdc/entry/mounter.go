@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -28,10 +29,14 @@ import (
 	timodel "github.com/pingcap/parser/model"
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/util"
+	tidbkv "github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/codec"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -60,6 +65,8 @@ type rowKVEntry struct {
 	// or row data that does not contain any Datum.
 	RowExist    bool
 	PreRowExist bool
+	// PreRowHandleOnly is true if PreRow only contains the handle column of a deleted row
+	PreRowHandleOnly bool
 }
 
 type indexKVEntry struct {
@@ -94,6 +101,15 @@ func (idx *indexKVEntry) unflatten(tableInfo *model.TableInfo, tz *time.Location
 	for i, v := range idx.IndexValue {
 		colOffset := index.Columns[i].Offset
 		fieldType := &tableInfo.Columns[colOffset].FieldType
+		// keep the encoded bytes of the unknown type, the mounter decides how to handle it
+		if !isKnownColumnType(fieldType.Tp) {
+			raw, err := codec.EncodeKey(&stmtctx.StatementContext{TimeZone: tz}, nil, v)
+			if err != nil {
+				return cerror.WrapError(cerror.ErrCodecDecode, err)
+			}
+			idx.IndexValue[i] = types.NewBytesDatum(raw)
+			continue
+		}
 		datum, err := unflatten(v, fieldType, tz)
 		if err != nil {
 			return errors.Trace(err)
@@ -130,10 +146,21 @@ type mounterImpl struct {
 	tz               *time.Location
 	workerNum        int
 	enableOldValue   bool
+	deleteImage      string
+	kvStorage        tidbkv.Storage
+
+	unknownColumnType    string
+	missingColumnDefault string
+	transientErrorRetry  int
+	decodeError          string
+	// snapshotFetcher fetches the schema snapshot of a row, it's schemaStorage.GetSnapshot if nil
+	snapshotFetcher func(ctx context.Context, ts uint64) (*schemaSnapshot, error)
+	// warnedColumns records the columns of unknown types which have been warned, to avoid flooding the log
+	warnedColumns sync.Map
 }
 
 // NewMounter creates a mounter
-func NewMounter(schemaStorage *SchemaStorage, workerNum int, enableOldValue bool) Mounter {
+func NewMounter(schemaStorage *SchemaStorage, kvStorage tidbkv.Storage, workerNum int, enableOldValue bool, deleteImage string, unknownColumnType string, missingColumnDefault string, transientErrorRetry int, decodeError string) Mounter {
 	if workerNum <= 0 {
 		workerNum = defaultMounterWorkerNum
 	}
@@ -146,6 +173,13 @@ func NewMounter(schemaStorage *SchemaStorage, workerNum int, enableOldValue bool
 		rawRowChangedChs: chs,
 		workerNum:        workerNum,
 		enableOldValue:   enableOldValue,
+		deleteImage:      deleteImage,
+		kvStorage:        kvStorage,
+
+		unknownColumnType:    unknownColumnType,
+		missingColumnDefault: missingColumnDefault,
+		transientErrorRetry:  transientErrorRetry,
+		decodeError:          decodeError,
 	}
 }
 
@@ -184,7 +218,7 @@ func (m *mounterImpl) codecWorker(ctx context.Context, index int) error {
 			continue
 		}
 		startTime := time.Now()
-		rowEvent, err := m.unmarshalAndMountRowChanged(ctx, pEvent.RawKV)
+		rowEvent, err := m.mountWithRetry(ctx, pEvent.RawKV)
 		if err != nil {
 			return errors.Trace(err)
 		}
@@ -233,7 +267,7 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(ctx context.Context, raw *mode
 		PhysicalTableID: physicalTableID,
 		Delete:          raw.OpType == model.OpTypeDelete,
 	}
-	snap, err := m.schemaStorage.GetSnapshot(ctx, raw.CRTs)
+	snap, err := m.fetchSnapshot(ctx, raw.CRTs)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -252,7 +286,11 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(ctx context.Context, raw *mode
 		}
 		switch {
 		case bytes.HasPrefix(key, recordPrefix):
-			rowKV, err := m.unmarshalRowKVEntry(tableInfo, key, raw.Value, raw.OldValue, baseInfo)
+			rawOldValue, err := m.loadDeleteImage(ctx, tableInfo, raw, key)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			rowKV, err := m.unmarshalRowKVEntry(tableInfo, key, raw.Value, rawOldValue, baseInfo)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -261,7 +299,7 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(ctx context.Context, raw *mode
 			}
 			return m.mountRowKVEntry(tableInfo, rowKV, raw.ApproximateSize())
 		case bytes.HasPrefix(key, indexPrefix):
-			indexKV, err := m.unmarshalIndexKVEntry(key, raw.Value, raw.OldValue, baseInfo)
+			indexKV, err := m.unmarshalIndexKVEntry(tableInfo, key, raw.Value, raw.OldValue, baseInfo)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -279,6 +317,40 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(ctx context.Context, raw *mode
 	return row, err
 }
 
+// loadDeleteImage returns the old value of the row in the raw KV entry. If the old value is enabled
+// but missing from a delete, it is handled according to the delete image mode.
+func (m *mounterImpl) loadDeleteImage(ctx context.Context, tableInfo *model.TableInfo, raw *model.RawKVEntry, restKey []byte) ([]byte, error) {
+	if !m.enableOldValue || raw.OpType != model.OpTypeDelete || len(raw.OldValue) != 0 {
+		return raw.OldValue, nil
+	}
+	_, recordID, err := decodeRecordID(restKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch m.deleteImage {
+	case config.DeleteImageRequire:
+		return nil, cerror.ErrDeleteImageMissing.GenWithStackByArgs(tableInfo.TableName.String(), recordID)
+	case config.DeleteImageFetch:
+		// the latest version before the delete is committed is the deleted row
+		snap, err := m.kvStorage.GetSnapshot(tidbkv.NewVersion(raw.CRTs - 1))
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrGetStoreSnapshot, err)
+		}
+		value, err := snap.Get(ctx, raw.Key)
+		if err != nil {
+			if tidbkv.IsErrNotFound(err) {
+				return nil, cerror.ErrDeleteImageMissing.GenWithStackByArgs(tableInfo.TableName.String(), recordID)
+			}
+			return nil, cerror.WrapError(cerror.ErrFetchDeleteImage,
+				errors.Annotatef(err, "table: %s, handle: %d", tableInfo.TableName, recordID))
+		}
+		return value, nil
+	}
+	log.Debug("the old value of the deleted row is unavailable, emit the available columns",
+		zap.Stringer("table", tableInfo.TableName), zap.Int64("handle", recordID))
+	return nil, nil
+}
+
 func (m *mounterImpl) unmarshalRowKVEntry(tableInfo *model.TableInfo, restKey []byte, rawValue []byte, rawOldValue []byte, base baseKVEntry) (*rowKVEntry, error) {
 	key, recordID, err := decodeRecordID(restKey)
 	if err != nil {
@@ -307,26 +379,32 @@ func (m *mounterImpl) unmarshalRowKVEntry(tableInfo *model.TableInfo, restKey []
 		return nil, errors.Trace(err)
 	}
 
-	if base.Delete && !m.enableOldValue && tableInfo.PKIsHandle {
+	// fill the handle column if the old value of a deleted row is unavailable
+	preRowHandleOnly := false
+	if base.Delete && !preRowExist && tableInfo.PKIsHandle {
 		id, pkValue, err := fetchHandleValue(tableInfo, recordID)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
 		preRow = map[int64]types.Datum{id: *pkValue}
 		preRowExist = true
+		preRowHandleOnly = true
 	}
 
 	base.RecordID = recordID
 	return &rowKVEntry{
-		baseKVEntry: base,
-		Row:         row,
-		PreRow:      preRow,
-		RowExist:    rowExist,
-		PreRowExist: preRowExist,
+		baseKVEntry:      base,
+		Row:              row,
+		PreRow:           preRow,
+		RowExist:         rowExist,
+		PreRowExist:      preRowExist,
+		PreRowHandleOnly: preRowHandleOnly,
 	}, nil
 }
 
-func (m *mounterImpl) unmarshalIndexKVEntry(restKey []byte, rawValue []byte, rawOldValue []byte, base baseKVEntry) (*indexKVEntry, error) {
+func (m *mounterImpl) unmarshalIndexKVEntry(
+	tableInfo *model.TableInfo, restKey []byte, rawValue []byte, rawOldValue []byte, base baseKVEntry,
+) (*indexKVEntry, error) {
 	// Skip set index KV.
 	// By default we cannot get the old value of a deleted row, then we must get the value of unique key
 	// or primary key for seeking the deleted row through its index key.
@@ -335,7 +413,16 @@ func (m *mounterImpl) unmarshalIndexKVEntry(restKey []byte, rawValue []byte, raw
 		return nil, nil
 	}
 
-	indexID, indexValue, err := decodeIndexKey(restKey)
+	indexID, _, err := decodeIndexID(restKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// Skip the index KVs except the ones of the handle index before decoding the index values,
+	// including the KVs written by the backfill of an index which is not public yet.
+	if indexID != tableInfo.HandleIndexID {
+		return nil, nil
+	}
+	_, indexValue, err := decodeIndexKey(restKey)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -391,7 +478,34 @@ func UnmarshalDDL(raw *model.RawKVEntry) (*timodel.Job, error) {
 	return job, nil
 }
 
-func datum2Column(tableInfo *model.TableInfo, datums map[int64]types.Datum, fillWithDefaultValue bool) ([]*model.Column, error) {
+// formatUnknownColVal formats the value of a column whose type is unknown by the configured policy,
+// skip is true if the column should be excluded from the row.
+func (m *mounterImpl) formatUnknownColVal(tableInfo *model.TableInfo, colInfo *timodel.ColumnInfo, datum types.Datum) (value interface{}, skip bool, err error) {
+	switch m.unknownColumnType {
+	case config.UnknownColumnTypeSkipColumn:
+		m.warnUnknownColumn(tableInfo, colInfo)
+		return nil, true, nil
+	case config.UnknownColumnTypeRawBytes:
+		m.warnUnknownColumn(tableInfo, colInfo)
+		if datum.IsNull() {
+			return nil, false, nil
+		}
+		return datum.GetBytes(), false, nil
+	default:
+		return nil, false, cerror.ErrUnknownColumnType.GenWithStackByArgs(colInfo.Name.O, tableInfo.TableName.String(), colInfo.Tp)
+	}
+}
+
+func (m *mounterImpl) warnUnknownColumn(tableInfo *model.TableInfo, colInfo *timodel.ColumnInfo) {
+	key := fmt.Sprintf("%d.%d", tableInfo.ID, colInfo.ID)
+	if _, loaded := m.warnedColumns.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	log.Warn("column of unknown type found", zap.String("table", tableInfo.TableName.String()),
+		zap.String("column", colInfo.Name.O), zap.Uint8("type", colInfo.Tp), zap.String("policy", m.unknownColumnType))
+}
+
+func (m *mounterImpl) datum2Column(tableInfo *model.TableInfo, datums map[int64]types.Datum, fillWithDefaultValue bool) ([]*model.Column, error) {
 	cols := make([]*model.Column, len(tableInfo.RowColumnsOffset))
 	for _, colInfo := range tableInfo.Columns {
 		if !model.IsColCDCVisible(colInfo) {
@@ -400,7 +514,20 @@ func datum2Column(tableInfo *model.TableInfo, datums map[int64]types.Datum, fill
 		colName := colInfo.Name.O
 		colDatums, exist := datums[colInfo.ID]
 		var colValue interface{}
-		if exist {
+		if !isKnownColumnType(colInfo.Tp) {
+			if !exist && !fillWithDefaultValue {
+				continue
+			}
+			// a missing column is formatted as null, its default value can't be decoded either
+			value, skip, err := m.formatUnknownColVal(tableInfo, colInfo, colDatums)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if skip {
+				continue
+			}
+			colValue = value
+		} else if exist {
 			var err error
 			var warn string
 			colValue, warn, err = formatColVal(colDatums, colInfo.Tp)
@@ -411,7 +538,11 @@ func datum2Column(tableInfo *model.TableInfo, datums map[int64]types.Datum, fill
 				log.Warn(warn, zap.String("table", tableInfo.TableName.String()), zap.String("column", colInfo.Name.String()))
 			}
 		} else if fillWithDefaultValue {
-			colValue = getDefaultOrZeroValue(colInfo)
+			var err error
+			colValue, err = m.getMissingColumnValue(colInfo)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
 		} else {
 			continue
 		}
@@ -420,6 +551,7 @@ func datum2Column(tableInfo *model.TableInfo, datums map[int64]types.Datum, fill
 			Type:  colInfo.Tp,
 			Value: colValue,
 			Flag:  tableInfo.ColumnsFlag[colInfo.ID],
+			Elems: colInfo.Elems,
 		}
 	}
 	return cols, nil
@@ -433,6 +565,12 @@ func (m *mounterImpl) mountRowKVEntry(tableInfo *model.TableInfo, row *rowKVEntr
 	if !m.enableOldValue && row.Delete && !tableInfo.PKIsHandle {
 		return nil, nil
 	}
+	// nothing is available for a deleted row without the old value and the handle column
+	if row.Delete && !row.PreRowExist {
+		log.Warn("skip the deleted row without the old value",
+			zap.Stringer("table", tableInfo.TableName), zap.Int64("handle", row.RecordID))
+		return nil, nil
+	}
 
 	var err error
 	// Decode previous columns.
@@ -440,7 +578,7 @@ func (m *mounterImpl) mountRowKVEntry(tableInfo *model.TableInfo, row *rowKVEntr
 	if row.PreRowExist {
 		// FIXME(leoppro): using pre table info to mounter pre column datum
 		// the pre column and current column in one event may using different table info
-		preCols, err = datum2Column(tableInfo, row.PreRow, m.enableOldValue)
+		preCols, err = m.datum2Column(tableInfo, row.PreRow, m.enableOldValue && !row.PreRowHandleOnly)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -448,7 +586,7 @@ func (m *mounterImpl) mountRowKVEntry(tableInfo *model.TableInfo, row *rowKVEntr
 
 	var cols []*model.Column
 	if row.RowExist {
-		cols, err = datum2Column(tableInfo, row.Row, true)
+		cols, err = m.datum2Column(tableInfo, row.Row, true)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -502,18 +640,32 @@ func (m *mounterImpl) mountIndexKVEntry(tableInfo *model.TableInfo, idx *indexKV
 	preCols := make([]*model.Column, len(tableInfo.RowColumnsOffset))
 	for i, idxCol := range indexInfo.Columns {
 		colInfo := tableInfo.Columns[idxCol.Offset]
-		value, warn, err := formatColVal(idx.IndexValue[i], colInfo.Tp)
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		if warn != "" {
-			log.Warn(warn, zap.String("table", tableInfo.TableName.String()), zap.String("column", colInfo.Name.String()))
+		var value interface{}
+		if !isKnownColumnType(colInfo.Tp) {
+			var skip bool
+			value, skip, err = m.formatUnknownColVal(tableInfo, colInfo, idx.IndexValue[i])
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if skip {
+				continue
+			}
+		} else {
+			var warn string
+			value, warn, err = formatColVal(idx.IndexValue[i], colInfo.Tp)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if warn != "" {
+				log.Warn(warn, zap.String("table", tableInfo.TableName.String()), zap.String("column", colInfo.Name.String()))
+			}
 		}
 		preCols[tableInfo.RowColumnsOffset[colInfo.ID]] = &model.Column{
 			Name:  colInfo.Name.O,
 			Type:  colInfo.Tp,
 			Value: value,
 			Flag:  tableInfo.ColumnsFlag[colInfo.ID],
+			Elems: colInfo.Elems,
 		}
 	}
 	return &model.RowChangedEvent{
@@ -590,6 +742,11 @@ func getDefaultOrZeroValue(col *timodel.ColumnInfo) interface{} {
 		d := types.NewDatum(col.GetDefaultValue())
 		return d.GetValue()
 	}
+	return getZeroValue(col)
+}
+
+// getZeroValue returns the value of a not null column without a default value
+func getZeroValue(col *timodel.ColumnInfo) interface{} {
 	switch col.Tp {
 	case mysql.TypeEnum:
 		// For enum type, if no default value and not null is set,
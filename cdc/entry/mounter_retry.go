@@ -0,0 +1,94 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/retry"
+	"github.com/pingcap/ticdc/pkg/util"
+	"go.uber.org/zap"
+)
+
+// mountRetryInterval is the initial backoff of retrying a row failed by a transient error
+var mountRetryInterval = 100 * time.Millisecond
+
+// transientMountErrors are caused by the dependencies of the mounter momentarily unavailable,
+// the rows failed by them may be mounted after a retry
+var transientMountErrors = []*errors.Error{
+	cerror.ErrSchemaStorageUnresolved,
+	cerror.ErrGetStoreSnapshot,
+	cerror.ErrFetchDeleteImage,
+}
+
+func isTransientMountError(err error) bool {
+	for _, e := range transientMountErrors {
+		if e.Equal(err) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mounterImpl) fetchSnapshot(ctx context.Context, ts uint64) (*schemaSnapshot, error) {
+	if m.snapshotFetcher != nil {
+		return m.snapshotFetcher(ctx, ts)
+	}
+	return m.schemaStorage.GetSnapshot(ctx, ts)
+}
+
+// mountWithRetry mounts the raw KV entry, the transient errors are retried at most transientErrorRetry
+// times with backoff. The row failed by a permanent error, or by a transient one after the retries,
+// is handled by the decode error policy.
+func (m *mounterImpl) mountWithRetry(ctx context.Context, raw *model.RawKVEntry) (*model.RowChangedEvent, error) {
+	var row *model.RowChangedEvent
+	err := retry.Run(mountRetryInterval, uint64(m.transientErrorRetry), func() error {
+		var err error
+		row, err = m.unmarshalAndMountRowChanged(ctx, raw)
+		if err == nil {
+			return nil
+		}
+		if !isTransientMountError(err) {
+			return backoff.Permanent(err)
+		}
+		log.Warn("mount the row failed by a transient error, retry later",
+			zap.Uint64("commitTs", raw.CRTs), zap.Error(err))
+		return err
+	})
+	if e, ok := err.(*backoff.PermanentError); ok {
+		err = e.Err
+	}
+	if err == nil {
+		return row, nil
+	}
+	switch errors.Cause(err) {
+	case context.Canceled, context.DeadlineExceeded:
+		return nil, errors.Trace(err)
+	}
+	if m.decodeError != config.DecodeErrorSkip {
+		return nil, errors.Trace(err)
+	}
+	log.Error("skip the row failed to mount",
+		zap.Uint64("startTs", raw.StartTs), zap.Uint64("commitTs", raw.CRTs),
+		zap.Binary("key", raw.Key), zap.Bool("transient", isTransientMountError(err)), zap.Error(err))
+	mounterSkippedRowsCounter.WithLabelValues(util.CaptureAddrFromCtx(ctx), util.ChangefeedIDFromCtx(ctx)).Inc()
+	return nil, nil
+}
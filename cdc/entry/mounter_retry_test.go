@@ -0,0 +1,108 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/tidb/tablecodec"
+)
+
+type mounterRetrySuite struct{}
+
+var _ = check.Suite(&mounterRetrySuite{})
+
+func (s *mounterRetrySuite) TestTransientSnapshotFailure(c *check.C) {
+	defer func(interval time.Duration) {
+		mountRetryInterval = interval
+	}(mountRetryInterval)
+	mountRetryInterval = time.Millisecond
+	ctx := context.Background()
+	storage := newAddIndexSchemaStorage(c, newAddIndexTableInfo(timodel.StatePublic))
+	failures, fetches := 0, 0
+	m := &mounterImpl{schemaStorage: storage, tz: time.UTC, transientErrorRetry: 3, decodeError: config.DecodeErrorFail}
+	m.snapshotFetcher = func(ctx context.Context, ts uint64) (*schemaSnapshot, error) {
+		fetches++
+		if failures > 0 {
+			failures--
+			// the schema storage is catching up
+			return nil, cerror.ErrSchemaStorageUnresolved.GenWithStackByArgs(ts, 0)
+		}
+		return storage.GetSnapshot(ctx, ts)
+	}
+	raw := addIndexRows[1].indexKV(c, addIndexUniqueID, model.OpTypeDelete, 50)
+
+	// the snapshot is available after the retries
+	failures = 2
+	row, err := m.mountWithRetry(ctx, raw)
+	c.Assert(err, check.IsNil)
+	c.Assert(row, check.NotNil)
+	c.Assert(row.IsDelete(), check.IsTrue)
+	c.Assert(fetches, check.Equals, 3)
+
+	// the snapshot stays unavailable after the retries
+	failures, fetches = 100, 0
+	_, err = m.mountWithRetry(ctx, raw)
+	c.Assert(cerror.ErrSchemaStorageUnresolved.Equal(err), check.IsTrue)
+	c.Assert(fetches, check.Equals, 4)
+	m.decodeError = config.DecodeErrorSkip
+	fetches = 0
+	row, err = m.mountWithRetry(ctx, raw)
+	c.Assert(err, check.IsNil)
+	c.Assert(row, check.IsNil)
+	c.Assert(fetches, check.Equals, 4)
+
+	// the permanent errors are not retried
+	failures, fetches = 0, 0
+	unknownTable := &model.RawKVEntry{
+		OpType:  model.OpTypePut,
+		Key:     tablecodec.EncodeRowKeyWithHandle(addIndexTableID+1, 1),
+		Value:   []byte{0},
+		StartTs: 49,
+		CRTs:    50,
+	}
+	row, err = m.mountWithRetry(ctx, unknownTable)
+	c.Assert(err, check.IsNil)
+	c.Assert(row, check.IsNil)
+	c.Assert(fetches, check.Equals, 1)
+	m.decodeError = config.DecodeErrorFail
+	_, err = m.mountWithRetry(ctx, unknownTable)
+	c.Assert(cerror.ErrSnapshotTableNotFound.Equal(err), check.IsTrue)
+	c.Assert(fetches, check.Equals, 2)
+
+	// the canceled row is never skipped
+	m.decodeError = config.DecodeErrorSkip
+	m.snapshotFetcher = func(ctx context.Context, ts uint64) (*schemaSnapshot, error) {
+		return nil, errors.Trace(context.Canceled)
+	}
+	_, err = m.mountWithRetry(ctx, raw)
+	c.Assert(errors.Cause(err), check.Equals, context.Canceled)
+}
+
+func (s *mounterRetrySuite) TestDecodeErrorConfig(c *check.C) {
+	cfg := config.GetDefaultReplicaConfig().Mounter
+	c.Assert(cfg.Validate(), check.IsNil)
+	cfg.DecodeError = "ignore"
+	c.Assert(cfg.Validate(), check.ErrorMatches, ".*invalid decode-error policy: ignore.*")
+	cfg.DecodeError = config.DecodeErrorSkip
+	cfg.TransientErrorRetry = -1
+	c.Assert(cfg.Validate(), check.ErrorMatches, ".*negative transient-error-retry -1.*")
+}
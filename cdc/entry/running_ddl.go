@@ -0,0 +1,40 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	timeta "github.com/pingcap/tidb/meta"
+)
+
+// RunningDDLJobs returns the DDL jobs which are started but not finished in the snapshot of the meta,
+// the schema objects changed by these jobs may be in the intermediate states in the snapshot.
+// The jobs queueing in the snapshot are not returned, they haven't changed any schema object yet.
+// The add index jobs are not returned either, the indexes being added are invisible to the rows
+// replicated, and waiting for the long running backfill would hold the changefeed for no gain.
+func RunningDDLJobs(meta *timeta.Meta) ([]*timodel.Job, error) {
+	jobs, err := meta.GetAllDDLJobsInQueue(timeta.DefaultJobListKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var running []*timodel.Job
+	for _, job := range jobs {
+		if job.State == timodel.JobStateNone || job.IsFinished() || job.IsSynced() {
+			continue
+		}
+		running = append(running, job)
+	}
+	return running, nil
+}
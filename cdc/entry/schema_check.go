@@ -0,0 +1,69 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+)
+
+// CheckTableSchemas checks whether the rows of the tables matched by the filter can be mounted
+// with the schemas in the snapshot, all the tables that can't are reported in the returned error.
+func CheckTableSchemas(snap *SingleSchemaSnapshot, filter *filter.Filter, unknownColumnType string) error {
+	var failures []string
+	for id, table := range snap.tables {
+		if filter.ShouldIgnoreTable(table.TableName.Schema, table.TableName.Table) || snap.IsIneligibleTableID(id) {
+			continue
+		}
+		if _, ok := snap.SchemaByTableID(id); !ok {
+			failures = append(failures, fmt.Sprintf("%s: the schema is not found", table.TableName))
+			continue
+		}
+		if reason := checkTableSchema(table, unknownColumnType); reason != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", table.TableName, reason))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Strings(failures)
+	return cerror.ErrSchemaCheckFailed.GenWithStackByArgs(snap.currentTs, strings.Join(failures, "; "))
+}
+
+// checkTableSchema returns the reason why the rows of the table can't be mounted, or "" if they can
+func checkTableSchema(table *model.TableInfo, unknownColumnType string) string {
+	for _, col := range table.Columns {
+		if !model.IsColCDCVisible(col) {
+			continue
+		}
+		if !isKnownColumnType(col.Tp) {
+			if unknownColumnType == "" || unknownColumnType == config.UnknownColumnTypeFail {
+				return fmt.Sprintf("column %s has an unknown type %d", col.Name.O, col.Tp)
+			}
+			continue
+		}
+		// the first element is the default value of a not null enum column without a default value
+		if col.Tp == mysql.TypeEnum && mysql.HasNotNullFlag(col.Flag) && col.GetDefaultValue() == nil && len(col.Elems) == 0 {
+			return fmt.Sprintf("enum column %s has no element", col.Name.O)
+		}
+	}
+	return ""
+}
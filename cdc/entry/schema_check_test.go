@@ -0,0 +1,97 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/tidb/types"
+)
+
+type schemaCheckSuite struct{}
+
+var _ = check.Suite(&schemaCheckSuite{})
+
+// newSchemaCheckSnapshot returns a snapshot of the tables whose primary key is the handle,
+// each table has a second column of the given type.
+func newSchemaCheckSnapshot(c *check.C, currentTs uint64, tables map[model.TableName]*types.FieldType) *SingleSchemaSnapshot {
+	snap := newEmptySchemaSnapshot()
+	snap.currentTs = currentTs
+	schemaIDs := make(map[string]int64)
+	tableID := int64(100)
+	for name, tp := range tables {
+		schemaID, ok := schemaIDs[name.Schema]
+		if !ok {
+			schemaID = int64(len(schemaIDs) + 1)
+			schemaIDs[name.Schema] = schemaID
+			c.Assert(snap.createSchema(&timodel.DBInfo{ID: schemaID, Name: timodel.NewCIStr(name.Schema)}), check.IsNil)
+		}
+		tableID++
+		idType := types.NewFieldType(mysql.TypeLonglong)
+		idType.Flag = mysql.PriKeyFlag | mysql.NotNullFlag
+		info := &timodel.TableInfo{
+			ID:         tableID,
+			Name:       timodel.NewCIStr(name.Table),
+			PKIsHandle: true,
+			Columns: []*timodel.ColumnInfo{
+				{ID: 1, Name: timodel.NewCIStr("id"), Offset: 0, FieldType: *idType, State: timodel.StatePublic},
+				{ID: 2, Name: timodel.NewCIStr("v"), Offset: 1, FieldType: *tp, State: timodel.StatePublic},
+			},
+		}
+		c.Assert(snap.createTable(model.WrapTableInfo(schemaID, name.Schema, currentTs, info)), check.IsNil)
+	}
+	return snap
+}
+
+func (s *schemaCheckSuite) TestCheckTableSchemas(c *check.C) {
+	emptyEnumType := types.NewFieldType(mysql.TypeEnum)
+	emptyEnumType.Flag = mysql.NotNullFlag
+	enumType := types.NewFieldType(mysql.TypeEnum)
+	enumType.Flag = mysql.NotNullFlag
+	enumType.Elems = []string{"a", "b"}
+	snap := newSchemaCheckSnapshot(c, 420, map[model.TableName]*types.FieldType{
+		{Schema: "test", Table: "ok"}:         types.NewFieldType(mysql.TypeVarchar),
+		{Schema: "test", Table: "enum"}:       enumType,
+		{Schema: "test", Table: "geo"}:        types.NewFieldType(mysql.TypeGeometry),
+		{Schema: "test", Table: "empty_enum"}: emptyEnumType,
+		{Schema: "ignored", Table: "geo"}:     types.NewFieldType(mysql.TypeGeometry),
+	})
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.Rules = []string{"test.*"}
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+
+	err = CheckTableSchemas(snap, f, config.UnknownColumnTypeFail)
+	c.Assert(cerror.ErrSchemaCheckFailed.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, `.*can't be reconstructed at ts 420: `+
+		"test.empty_enum: enum column v has no element; test.geo: column v has an unknown type 255$")
+	// retrying the changefeed can't fix the schemas, the changefeed is paused until they are fixed
+	c.Assert(filter.ChangefeedPauseError(errors.Trace(err)), check.IsTrue)
+	c.Assert(filter.ChangefeedFastFailError(errors.Trace(err)), check.IsFalse)
+
+	// the columns of unknown types can be replicated with the other policies
+	err = CheckTableSchemas(snap, f, config.UnknownColumnTypeSkipColumn)
+	c.Assert(err, check.ErrorMatches, ".*can't be reconstructed at ts 420: test.empty_enum: enum column v has no element$")
+
+	cfg.Filter.Rules = []string{"test.ok", "test.enum"}
+	f, err = filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(CheckTableSchemas(snap, f, config.UnknownColumnTypeFail), check.IsNil)
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entry
+
+import (
+	"time"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/rowcodec"
+)
+
+type unknownColumnSuite struct{}
+
+var _ = check.Suite(&unknownColumnSuite{})
+
+const unknownColumnTableID = 43
+
+func newUnknownColumnTableInfo() *model.TableInfo {
+	idType := types.NewFieldType(mysql.TypeLonglong)
+	idType.Flag = mysql.PriKeyFlag | mysql.NotNullFlag
+	// the mounter doesn't support the geometry type
+	geoType := types.NewFieldType(mysql.TypeGeometry)
+	info := &timodel.TableInfo{
+		ID:         unknownColumnTableID,
+		Name:       timodel.NewCIStr("t"),
+		PKIsHandle: true,
+		Columns: []*timodel.ColumnInfo{
+			{ID: 1, Name: timodel.NewCIStr("id"), Offset: 0, FieldType: *idType, State: timodel.StatePublic},
+			{ID: 2, Name: timodel.NewCIStr("geo"), Offset: 1, FieldType: *geoType, State: timodel.StatePublic},
+		},
+	}
+	return model.WrapTableInfo(1, "test", 1, info)
+}
+
+func mountUnknownColumnRow(m *mounterImpl, tableInfo *model.TableInfo, newFormat bool) (*model.RowChangedEvent, error) {
+	value, err := tablecodec.EncodeRow(&stmtctx.StatementContext{TimeZone: time.UTC},
+		[]types.Datum{types.NewBytesDatum([]byte("point"))}, []int64{2}, nil, nil, &rowcodec.Encoder{Enable: newFormat})
+	if err != nil {
+		return nil, err
+	}
+	key := tablecodec.EncodeRowKeyWithHandle(unknownColumnTableID, 1)
+	key, _, err = decodeTableID(key)
+	if err != nil {
+		return nil, err
+	}
+	rowKV, err := m.unmarshalRowKVEntry(tableInfo, key, value, nil, baseKVEntry{
+		StartTs:         9,
+		CRTs:            10,
+		PhysicalTableID: unknownColumnTableID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.mountRowKVEntry(tableInfo, rowKV, int64(len(value)))
+}
+
+func (s *unknownColumnSuite) TestFail(c *check.C) {
+	for _, policy := range []string{config.UnknownColumnTypeFail, ""} {
+		m := &mounterImpl{tz: time.UTC, unknownColumnType: policy}
+		for _, newFormat := range []bool{true, false} {
+			_, err := mountUnknownColumnRow(m, newUnknownColumnTableInfo(), newFormat)
+			c.Assert(cerror.ErrUnknownColumnType.Equal(err), check.IsTrue)
+			c.Assert(err, check.ErrorMatches, ".*column geo of table test.t has an unknown type 255.*")
+		}
+	}
+}
+
+func (s *unknownColumnSuite) TestSkipColumn(c *check.C) {
+	m := &mounterImpl{tz: time.UTC, unknownColumnType: config.UnknownColumnTypeSkipColumn}
+	for _, newFormat := range []bool{true, false} {
+		row, err := mountUnknownColumnRow(m, newUnknownColumnTableInfo(), newFormat)
+		c.Assert(err, check.IsNil)
+		c.Assert(row.Columns, check.HasLen, 2)
+		c.Assert(row.Columns[0].Name, check.Equals, "id")
+		c.Assert(row.Columns[0].Value, check.Equals, int64(1))
+		c.Assert(row.Columns[1], check.IsNil)
+	}
+}
+
+func (s *unknownColumnSuite) TestRawBytes(c *check.C) {
+	m := &mounterImpl{tz: time.UTC, unknownColumnType: config.UnknownColumnTypeRawBytes}
+	row, err := mountUnknownColumnRow(m, newUnknownColumnTableInfo(), true)
+	c.Assert(err, check.IsNil)
+	c.Assert(row.Columns, check.HasLen, 2)
+	c.Assert(row.Columns[0].Value, check.Equals, int64(1))
+	c.Assert(row.Columns[1].Name, check.Equals, "geo")
+	c.Assert(row.Columns[1].Type, check.Equals, mysql.TypeGeometry)
+	c.Assert(row.Columns[1].Value, check.DeepEquals, []byte("point"))
+
+	// the old format keeps the value with its encoding flag
+	row, err = mountUnknownColumnRow(m, newUnknownColumnTableInfo(), false)
+	c.Assert(err, check.IsNil)
+	expected, err := codec.EncodeValue(nil, nil, types.NewBytesDatum([]byte("point")))
+	c.Assert(err, check.IsNil)
+	c.Assert(row.Columns[1].Value, check.DeepEquals, expected)
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var gcSafepointRefreshInterval = time.Minute
+
+// gcSpanningTxnGuard detects the transactions of a table which start before the GC safepoint
+// the table starts at and commit after it. The earlier versions of the rows such a transaction
+// needs, for example the old values or the prewrites, may have been collected before the data
+// of the table is scanned, so some of its rows may be missing. The transactions replicated after
+// the table starts are not affected by the later GC.
+type gcSpanningTxnGuard struct {
+	changefeedID string
+	tableID      model.TableID
+	fail         bool
+	// sharedGCSafepoint is shared by the tables of the processor and refreshed by gcSafepointWorker
+	sharedGCSafepoint *uint64
+	// gcSafepoint is the GC safepoint when the table starts, it's zero until the safepoint is known
+	gcSafepoint uint64
+	// reported maps the start ts of the reported transactions to their commit ts,
+	// so that every transaction is reported once
+	reported map[uint64]uint64
+	counter  prometheus.Counter
+}
+
+func newGCSpanningTxnGuard(changefeedID, captureAddr string, tableID model.TableID, gcSafepoint *uint64, policy string) *gcSpanningTxnGuard {
+	return &gcSpanningTxnGuard{
+		changefeedID:      changefeedID,
+		tableID:           tableID,
+		fail:              policy == config.GCSpanningTxnFail,
+		sharedGCSafepoint: gcSafepoint,
+		gcSafepoint:       atomic.LoadUint64(gcSafepoint),
+		reported:          make(map[uint64]uint64),
+		counter:           gcSpanningTxnCounter.WithLabelValues(changefeedID, captureAddr),
+	}
+}
+
+// advance forgets the reported transactions committed before the resolved ts
+func (g *gcSpanningTxnGuard) advance(resolvedTs uint64) {
+	for startTs, commitTs := range g.reported {
+		if commitTs <= resolvedTs {
+			delete(g.reported, startTs)
+		}
+	}
+}
+
+// check returns an error if the transaction of the event starts before the GC safepoint
+// the table starts at and the policy is fail, otherwise it logs a warning for the transaction
+func (g *gcSpanningTxnGuard) check(pEvent *model.PolymorphicEvent) error {
+	if g.gcSafepoint == 0 {
+		// the safepoint isn't fetched when the table starts, the first one fetched is used
+		g.gcSafepoint = atomic.LoadUint64(g.sharedGCSafepoint)
+	}
+	gcSafepoint := g.gcSafepoint
+	if pEvent.StartTs == 0 || pEvent.StartTs >= gcSafepoint {
+		return nil
+	}
+	if _, ok := g.reported[pEvent.StartTs]; ok {
+		return nil
+	}
+	g.reported[pEvent.StartTs] = pEvent.CRTs
+	g.counter.Inc()
+	fields := []zap.Field{
+		zap.String("changefeed", g.changefeedID),
+		zap.Int64("tableID", g.tableID),
+		zap.Uint64("startTs", pEvent.StartTs),
+		zap.Uint64("commitTs", pEvent.CRTs),
+		zap.Uint64("gcSafepoint", gcSafepoint),
+	}
+	if g.fail {
+		log.Error("transaction starts before the GC safepoint, stop the changefeed", fields...)
+		return cerror.ErrTxnSpansGCSafepoint.GenWithStackByArgs(g.tableID, pEvent.StartTs, pEvent.CRTs, gcSafepoint)
+	}
+	log.Warn("transaction starts before the GC safepoint, some of its rows may be missing", fields...)
+	return nil
+}
+
+// gcSafepointWorker refreshes the GC safepoint the gcSpanningTxnGuards of the tables check against
+func (p *processor) gcSafepointWorker(ctx context.Context) error {
+	for {
+		gcSafepoint, err := p.etcdCli.GetGCSafepoint(ctx)
+		if err != nil {
+			log.Warn("failed to get the GC safepoint", zap.String("changefeed", p.changefeedID), zap.Error(err))
+		} else {
+			atomic.StoreUint64(&p.gcSafepoint, gcSafepoint)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(gcSafepointRefreshInterval):
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type gcSpanningTxnGuardSuite struct{}
+
+var _ = check.Suite(&gcSpanningTxnGuardSuite{})
+
+func newTxnTestEvent(startTs, commitTs uint64, key string) *model.PolymorphicEvent {
+	return model.NewPolymorphicEvent(&model.RawKVEntry{
+		OpType:  model.OpTypePut,
+		Key:     []byte(key),
+		StartTs: startTs,
+		CRTs:    commitTs,
+	})
+}
+
+// gcSpanningTestEvents are the events of a table started at ts 100 with the GC safepoint at ts 100,
+// the transaction committed at 105 starts long before the safepoint
+func gcSpanningTestEvents() []*model.PolymorphicEvent {
+	return []*model.PolymorphicEvent{
+		newTxnTestEvent(101, 102, "t_a"),
+		newTxnTestEvent(50, 105, "t_b"),
+		newTxnTestEvent(50, 105, "t_c"),
+		newTxnTestEvent(104, 106, "t_d"),
+	}
+}
+
+func (s *gcSpanningTxnGuardSuite) TestWarnAndContinue(c *check.C) {
+	p, errCh := newLateEventTestProcessor("test-gc-spanning-warn", false)
+	p.gcSafepoint = 100
+	emitted, stop := consumeEvents(p, gcSpanningTestEvents())
+	defer stop()
+	c.Assert(emitted, check.DeepEquals, []uint64{102, 105, 105, 106})
+	// the transaction is reported once
+	c.Assert(testutil.ToFloat64(gcSpanningTxnCounter.WithLabelValues("test-gc-spanning-warn", "")), check.Equals, float64(1))
+	select {
+	case err := <-errCh:
+		c.Fatalf("unexpected error %v", err)
+	default:
+	}
+}
+
+func (s *gcSpanningTxnGuardSuite) TestFail(c *check.C) {
+	p, errCh := newLateEventTestProcessor("test-gc-spanning-fail", false)
+	p.changefeed.Config.GCSpanningTxn = config.GCSpanningTxnFail
+	p.gcSafepoint = 100
+	emitted, stop := consumeEvents(p, gcSpanningTestEvents())
+	defer stop()
+	c.Assert(emitted, check.DeepEquals, []uint64{102})
+	err := <-errCh
+	c.Assert(cerror.ErrTxnSpansGCSafepoint.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches,
+		".*transaction of table 1 with start ts 50 and commit ts 105 starts before the GC safepoint 100.*")
+	c.Assert(testutil.ToFloat64(gcSpanningTxnCounter.WithLabelValues("test-gc-spanning-fail", "")), check.Equals, float64(1))
+}
+
+func (s *gcSpanningTxnGuardSuite) TestForgetReportedTxns(c *check.C) {
+	// the transactions are not checked before the GC safepoint is known
+	var gcSafepoint uint64
+	guard := newGCSpanningTxnGuard("test-gc-spanning-forget", "", 1, &gcSafepoint, "")
+	c.Assert(guard.check(newTxnTestEvent(40, 101, "t_a")), check.IsNil)
+	c.Assert(guard.reported, check.HasLen, 0)
+
+	gcSafepoint = 100
+	c.Assert(guard.check(newTxnTestEvent(50, 105, "t_a")), check.IsNil)
+	c.Assert(guard.check(newTxnTestEvent(60, 110, "t_a")), check.IsNil)
+	c.Assert(guard.reported, check.HasLen, 2)
+	guard.advance(105)
+	c.Assert(guard.reported, check.DeepEquals, map[uint64]uint64{60: 110})
+
+	// the transactions replicated after the table starts aren't affected by the later GC
+	gcSafepoint = 200
+	c.Assert(guard.check(newTxnTestEvent(150, 210, "t_a")), check.IsNil)
+	c.Assert(guard.reported, check.HasLen, 1)
+	// the GC safepoint the table starts at is used
+	guard = newGCSpanningTxnGuard("test-gc-spanning-forget", "", 1, &gcSafepoint, "")
+	c.Assert(guard.gcSafepoint, check.Equals, uint64(200))
+	for _, policy := range []string{"", config.GCSpanningTxnFail, config.GCSpanningTxnWarnAndContinue} {
+		c.Assert(config.ValidateGCSpanningTxn(policy), check.IsNil)
+	}
+	c.Assert(config.ValidateGCSpanningTxn("ignore"), check.ErrorMatches, ".*invalid gc-spanning-txn policy: ignore.*")
+}
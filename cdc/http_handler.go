@@ -18,11 +18,16 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/cdc/sink/producer/memory"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/logutil"
 	"github.com/pingcap/tidb/store/tikv/oracle"
@@ -39,8 +44,30 @@ const (
 	APIOpVarTargetCaptureID = "target-cp-id"
 	// APIOpVarTableID is the key of table ID in HTTP API
 	APIOpVarTableID = "table-id"
+	// APIOpVarRescanTs is the key of the ts to re-scan a table from in HTTP API
+	APIOpVarRescanTs = "from-ts"
+	// APIOpVarDiscardRows is the key of whether to release a quarantined table without re-scanning it in HTTP API
+	APIOpVarDiscardRows = "discard-rows"
 	// APIOpForceRemoveChangefeed is used when remove a changefeed
 	APIOpForceRemoveChangefeed = "force-remove"
+	// APIOpVarQuarantineKey is the key of the original etcd key of a quarantined item in HTTP API
+	APIOpVarQuarantineKey = "key"
+	// APIOpVarMemoryQueue is the key of the name of a memory queue in HTTP API
+	APIOpVarMemoryQueue = "queue"
+	// APIOpVarMemoryQueueLimit is the key of the maximum number of the polled messages in HTTP API
+	APIOpVarMemoryQueueLimit = "limit"
+	// APIOpVarRegionLimit is the key of the maximum number of the reported regions in HTTP API
+	APIOpVarRegionLimit = "limit"
+)
+
+// apiV1ChangefeedsPrefix is the prefix of the RESTful API of the changefeeds
+const apiV1ChangefeedsPrefix = "/api/v1/changefeeds/"
+
+const (
+	defaultMemoryQueuePollLimit = 1024
+
+	defaultLaggingRegionLimit = 100
+	maxLaggingRegionLimit     = 10000
 )
 
 type commonResp struct {
@@ -54,6 +81,13 @@ type ChangefeedResp struct {
 	TSO          uint64              `json:"tso"`
 	Checkpoint   string              `json:"checkpoint"`
 	RunningError *model.RunningError `json:"error"`
+	// Warnings are the problems of the processors which don't stop the changefeed, such as
+	// the sinks paused on the first error
+	Warnings     []*model.RunningError `json:"warnings,omitempty"`
+	ExecutingDDL string                `json:"executing-ddl,omitempty"`
+	// CheckpointInterval is the checkpoint interval of the changefeed, it doesn't take
+	// effect if it is smaller than the flush interval of the processors.
+	CheckpointInterval string `json:"checkpoint-interval,omitempty"`
 }
 
 func handleOwnerResp(w http.ResponseWriter, err error) {
@@ -210,6 +244,73 @@ func (s *Server) handleMoveTable(w http.ResponseWriter, req *http.Request) {
 	handleOwnerResp(w, nil)
 }
 
+func (s *Server) handleRescanTable(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusBadRequest, cerror.ErrSupportPostOnly.GenWithStackByArgs())
+		return
+	}
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, cerror.WrapError(cerror.ErrInternalServerError, err))
+		return
+	}
+	s.rescanTable(w, req, req.Form.Get(APIOpVarChangefeedID), req.Form.Get(APIOpVarTableID))
+}
+
+// handleChangefeedsAPI serves POST /api/v1/changefeeds/{changefeed-id}/tables/{table-id}/rescan
+func (s *Server) handleChangefeedsAPI(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, apiV1ChangefeedsPrefix), "/")
+	if len(parts) != 4 || parts[1] != "tables" || parts[3] != "rescan" {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusBadRequest, cerror.ErrSupportPostOnly.GenWithStackByArgs())
+		return
+	}
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, cerror.WrapError(cerror.ErrInternalServerError, err))
+		return
+	}
+	s.rescanTable(w, req, parts[0], parts[2])
+}
+
+// rescanTable re-scans the table of the changefeed from the from-ts in the form of the request
+func (s *Server) rescanTable(w http.ResponseWriter, req *http.Request, changefeedID, tableIDStr string) {
+	s.ownerLock.RLock()
+	defer s.ownerLock.RUnlock()
+	if s.owner == nil {
+		handleOwnerResp(w, concurrency.ErrElectionNotLeader)
+		return
+	}
+
+	if err := model.ValidateChangefeedID(changefeedID); err != nil {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("invalid changefeed id: %s", changefeedID))
+		return
+	}
+	tableID, err := strconv.ParseInt(tableIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("invalid tableID: %s", tableIDStr))
+		return
+	}
+	fromTsStr := req.Form.Get(APIOpVarRescanTs)
+	fromTs, err := strconv.ParseUint(fromTsStr, 10, 64)
+	if err != nil || fromTs == 0 {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("invalid from-ts: %s", fromTsStr))
+		return
+	}
+	err = s.owner.RescanTable(req.Context(), changefeedID, tableID, fromTs)
+	if cerror.ErrTableRescanInvalid.Equal(err) || cerror.ErrChangeFeedNotExists.Equal(err) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	handleOwnerResp(w, err)
+}
+
 func (s *Server) handleChangefeedQuery(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		writeError(w, http.StatusBadRequest, cerror.ErrSupportPostOnly.GenWithStackByArgs())
@@ -249,17 +350,133 @@ func (s *Server) handleChangefeedQuery(w http.ResponseWriter, req *http.Request)
 	}
 	if cf != nil {
 		resp.RunningError = cf.info.Error
+		resp.CheckpointInterval = cf.info.CheckpointInterval.String()
+		for _, position := range cf.taskPositions {
+			if position.Warning != nil {
+				resp.Warnings = append(resp.Warnings, position.Warning)
+			}
+		}
+		sort.Slice(resp.Warnings, func(i, j int) bool { return resp.Warnings[i].Addr < resp.Warnings[j].Addr })
 	} else if feedInfo != nil {
 		resp.RunningError = feedInfo.Error
+		resp.CheckpointInterval = feedInfo.CheckpointInterval.String()
 	}
 	if status != nil {
 		resp.TSO = status.CheckpointTs
 		tm := oracle.GetTimeFromTS(status.CheckpointTs)
 		resp.Checkpoint = tm.Format("2006-01-02 15:04:05.000")
+		if status.ExecutingDDL != nil {
+			resp.ExecutingDDL = status.ExecutingDDL.String()
+		}
 	}
 	writeData(w, resp)
 }
 
+// handleQuarantine lists the quarantined items with GET, and deletes
+// the quarantined item of the given key with POST.
+func (s *Server) handleQuarantine(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodPost {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("unsupported method: %s", req.Method))
+		return
+	}
+	s.ownerLock.RLock()
+	defer s.ownerLock.RUnlock()
+	if s.owner == nil {
+		handleOwnerResp(w, concurrency.ErrElectionNotLeader)
+		return
+	}
+
+	if req.Method == http.MethodGet {
+		items, err := s.owner.etcdClient.GetQuarantinedItems(req.Context())
+		if err != nil {
+			writeInternalServerError(w, err)
+			return
+		}
+		writeData(w, items)
+		return
+	}
+
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	key := req.Form.Get(APIOpVarQuarantineKey)
+	if key == "" {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("the key of the quarantined item is required"))
+		return
+	}
+	err = s.owner.etcdClient.DeleteQuarantinedItem(req.Context(), key)
+	if cerror.ErrQuarantineNotExists.Equal(err) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	handleOwnerResp(w, err)
+}
+
+// handleQuarantinedTables lists the tables quarantined by a changefeed with GET, and releases
+// the quarantined table of the given table ID with POST. The released table is re-scanned from
+// the first row failed to be written unless discard-rows is true.
+func (s *Server) handleQuarantinedTables(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodPost {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("unsupported method: %s", req.Method))
+		return
+	}
+	s.ownerLock.RLock()
+	defer s.ownerLock.RUnlock()
+	if s.owner == nil {
+		handleOwnerResp(w, concurrency.ErrElectionNotLeader)
+		return
+	}
+
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	changefeedID := req.Form.Get(APIOpVarChangefeedID)
+	if err := model.ValidateChangefeedID(changefeedID); err != nil {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("invalid changefeed id: %s", changefeedID))
+		return
+	}
+	if req.Method == http.MethodGet {
+		tables, err := s.owner.etcdClient.GetQuarantinedTables(req.Context(), changefeedID)
+		if err != nil {
+			writeInternalServerError(w, err)
+			return
+		}
+		writeData(w, tables)
+		return
+	}
+
+	tableIDStr := req.Form.Get(APIOpVarTableID)
+	tableID, err := strconv.ParseInt(tableIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("invalid tableID: %s", tableIDStr))
+		return
+	}
+	discardRows := false
+	if discardRowsStr := req.Form.Get(APIOpVarDiscardRows); discardRowsStr != "" {
+		discardRows, err = strconv.ParseBool(discardRowsStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest,
+				cerror.ErrAPIInvalidParam.GenWithStack("invalid discard-rows: %s", discardRowsStr))
+			return
+		}
+	}
+	err = s.owner.ReleaseQuarantinedTable(req.Context(), changefeedID, tableID, discardRows)
+	if cerror.ErrTableNotQuarantined.Equal(err) || cerror.ErrTableRescanInvalid.Equal(err) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	handleOwnerResp(w, err)
+}
+
 func handleAdminLogLevel(w http.ResponseWriter, r *http.Request) {
 	var level string
 	data, err := ioutil.ReadAll(r.Body)
@@ -285,3 +502,117 @@ func handleAdminLogLevel(w http.ResponseWriter, r *http.Request) {
 
 	writeData(w, struct{}{})
 }
+
+// handleMemoryQueue returns the messages of a memory queue sink, the messages are kept in the queue
+// since the status server isn't authenticated
+func handleMemoryQueue(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("unsupported method: %s", req.Method))
+		return
+	}
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	name := req.Form.Get(APIOpVarMemoryQueue)
+	queue, ok := memory.LookupQueue(name)
+	if !ok {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("memory queue %s not found", name))
+		return
+	}
+	limit := defaultMemoryQueuePollLimit
+	if s := req.Form.Get(APIOpVarMemoryQueueLimit); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest,
+				cerror.ErrAPIInvalidParam.GenWithStack("invalid limit: %s", s))
+			return
+		}
+	}
+	writeData(w, queue.Peek(limit))
+}
+
+// laggingRegionsResp is the regions of a changefeed subscribed by the capture
+// in the ascending order of their resolved ts
+type laggingRegionsResp struct {
+	ChangefeedID string                `json:"changefeed-id"`
+	TotalRegions int                   `json:"total-regions"`
+	Regions      []kv.RegionResolvedTs `json:"regions"`
+}
+
+// handleLaggingRegions reports the regions of a changefeed subscribed by the capture whose
+// resolved ts are the smallest, it helps to find the regions holding the resolved ts back
+func handleLaggingRegions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("unsupported method: %s", req.Method))
+		return
+	}
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	changefeedID := req.Form.Get(APIOpVarChangefeedID)
+	if err := model.ValidateChangefeedID(changefeedID); err != nil {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("invalid changefeed id: %s", changefeedID))
+		return
+	}
+	limit := defaultLaggingRegionLimit
+	if s := req.Form.Get(APIOpVarRegionLimit); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest,
+				cerror.ErrAPIInvalidParam.GenWithStack("invalid limit: %s", s))
+			return
+		}
+	}
+	if limit > maxLaggingRegionLimit {
+		limit = maxLaggingRegionLimit
+	}
+	regions, total := kv.LaggingRegions(changefeedID, limit)
+	writeData(w, laggingRegionsResp{ChangefeedID: changefeedID, TotalRegions: total, Regions: regions})
+}
+
+// pausedSinkResp is the error pausing the sink of a changefeed on the capture
+type pausedSinkResp struct {
+	ChangefeedID string                `json:"changefeed-id"`
+	Paused       bool                  `json:"paused"`
+	Error        *sink.PausedSinkError `json:"error,omitempty"`
+}
+
+// handlePausedSink reports the error and the failing rows pausing the sink of a changefeed
+// on the capture with GET, and resumes the sink with POST, which retries the failing writes.
+func handlePausedSink(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodPost {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("unsupported method: %s", req.Method))
+		return
+	}
+	err := req.ParseForm()
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	changefeedID := req.Form.Get(APIOpVarChangefeedID)
+	if err := model.ValidateChangefeedID(changefeedID); err != nil {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("invalid changefeed id: %s", changefeedID))
+		return
+	}
+	if req.Method == http.MethodGet {
+		pausedErr := sink.GetPausedSinkError(changefeedID)
+		writeData(w, pausedSinkResp{ChangefeedID: changefeedID, Paused: pausedErr != nil, Error: pausedErr})
+		return
+	}
+	if !sink.ResumePausedSink(changefeedID) {
+		writeError(w, http.StatusBadRequest,
+			cerror.ErrAPIInvalidParam.GenWithStack("the sink of changefeed %s is not paused", changefeedID))
+		return
+	}
+	writeData(w, commonResp{Status: true})
+}
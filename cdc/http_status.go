@@ -50,9 +50,16 @@ func (s *Server) startStatusHTTP() error {
 	serverMux.HandleFunc("/capture/owner/admin", s.handleChangefeedAdmin)
 	serverMux.HandleFunc("/capture/owner/rebalance_trigger", s.handleRebalanceTrigger)
 	serverMux.HandleFunc("/capture/owner/move_table", s.handleMoveTable)
+	serverMux.HandleFunc("/capture/owner/rescan_table", s.handleRescanTable)
 	serverMux.HandleFunc("/capture/owner/changefeed/query", s.handleChangefeedQuery)
+	serverMux.HandleFunc("/capture/owner/quarantine", s.handleQuarantine)
+	serverMux.HandleFunc("/capture/owner/quarantined-tables", s.handleQuarantinedTables)
+	serverMux.HandleFunc(apiV1ChangefeedsPrefix, s.handleChangefeedsAPI)
 
 	serverMux.HandleFunc("/admin/log", handleAdminLogLevel)
+	serverMux.HandleFunc("/debug/memory-queue", handleMemoryQueue)
+	serverMux.HandleFunc("/debug/lagging-regions", handleLaggingRegions)
+	serverMux.HandleFunc("/debug/paused-sink", handlePausedSink)
 
 	prometheus.DefaultGatherer = registry
 	serverMux.Handle("/metrics", promhttp.Handler())
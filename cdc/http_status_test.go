@@ -14,6 +14,8 @@
 package cdc
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -21,6 +23,8 @@ import (
 	"time"
 
 	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/sink/producer/memory"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"go.etcd.io/etcd/clientv3/concurrency"
 )
@@ -69,7 +73,13 @@ func (s *httpStatusSuite) TestHTTPStatus(c *check.C) {
 	testHandleChangefeedAdmin(c)
 	testHandleRebalance(c)
 	testHandleMoveTable(c)
+	testHandleRescanTable(c)
 	testHandleChangefeedQuery(c)
+	testHandleQuarantine(c)
+	testHandleQuarantinedTables(c)
+	testHandleMemoryQueue(c)
+	testHandleLaggingRegions(c)
+	testHandlePausedSink(c)
 }
 
 func testPprof(c *check.C) {
@@ -105,12 +115,135 @@ func testHandleMoveTable(c *check.C) {
 	testRequestNonOwnerFailed(c, uri)
 }
 
+func testHandleRescanTable(c *check.C) {
+	uri := fmt.Sprintf("http://%s/capture/owner/rescan_table", testingServerOptions.advertiseAddr)
+	testHTTPPostOnly(c, uri)
+	testRequestNonOwnerFailed(c, uri)
+
+	uri = fmt.Sprintf("http://%s/api/v1/changefeeds/test-cf/tables/1/rescan", testingServerOptions.advertiseAddr)
+	testHTTPPostOnly(c, uri)
+	testRequestNonOwnerFailed(c, uri)
+	resp, err := http.PostForm(fmt.Sprintf("http://%s/api/v1/changefeeds/test-cf/tables/1", testingServerOptions.advertiseAddr), url.Values{})
+	c.Assert(err, check.IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusNotFound)
+}
+
 func testHandleChangefeedQuery(c *check.C) {
 	uri := fmt.Sprintf("http://%s/capture/owner/changefeed/query", testingServerOptions.advertiseAddr)
 	testHTTPPostOnly(c, uri)
 	testRequestNonOwnerFailed(c, uri)
 }
 
+func testHandleQuarantine(c *check.C) {
+	uri := fmt.Sprintf("http://%s/capture/owner/quarantine", testingServerOptions.advertiseAddr)
+	testRequestNonOwnerFailed(c, uri)
+}
+
+func testHandleQuarantinedTables(c *check.C) {
+	uri := fmt.Sprintf("http://%s/capture/owner/quarantined-tables", testingServerOptions.advertiseAddr)
+	testRequestNonOwnerFailed(c, uri)
+}
+
+func testHandleMemoryQueue(c *check.C) {
+	uri := fmt.Sprintf("http://%s/debug/memory-queue", testingServerOptions.advertiseAddr)
+	resp, err := http.Get(uri + "?queue=http-memory-queue")
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusBadRequest)
+
+	queue := memory.GetQueue("http-memory-queue", 4)
+	defer memory.RemoveQueue("http-memory-queue")
+	p, err := memory.NewProducer(&url.URL{Scheme: "memory", Host: "http-memory-queue"})
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 3; i++ {
+		c.Assert(p.SendMessage(context.Background(), []byte("key"), []byte{byte(i)}, nil, 0), check.IsNil)
+	}
+	resp, err = http.Get(uri + "?queue=http-memory-queue&limit=2")
+	c.Assert(err, check.IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+	var msgs []*memory.Message
+	c.Assert(json.NewDecoder(resp.Body).Decode(&msgs), check.IsNil)
+	c.Assert(msgs, check.DeepEquals, []*memory.Message{
+		{Key: []byte("key"), Value: []byte{0}},
+		{Key: []byte("key"), Value: []byte{1}},
+	})
+	// the messages aren't consumed
+	c.Assert(queue.Poll(4), check.HasLen, 3)
+}
+
+func testHandleLaggingRegions(c *check.C) {
+	uri := fmt.Sprintf("http://%s/debug/lagging-regions", testingServerOptions.advertiseAddr)
+	for _, query := range []string{"", "?cf-id=test-lagging&limit=0", "?cf-id=test-lagging&limit=x"} {
+		resp, err := http.Get(uri + query)
+		c.Assert(err, check.IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, check.Equals, http.StatusBadRequest, check.Commentf("%s", query))
+	}
+
+	for i, resolvedTs := range []uint64{500, 100, 400, 200, 300} {
+		untrack := kv.MockTrackedRegion("test-lagging", kv.RegionResolvedTs{
+			RegionID:    uint64(i + 1),
+			StoreID:     1,
+			StoreAddr:   "127.0.0.1:20160",
+			TableID:     53,
+			TableName:   "test.t",
+			ResolvedTs:  resolvedTs,
+			Initialized: true,
+		})
+		defer untrack()
+	}
+	defer kv.MockTrackedRegion("test-other", kv.RegionResolvedTs{RegionID: 10, ResolvedTs: 1})()
+
+	get := func(query string) laggingRegionsResp {
+		resp, err := http.Get(uri + query)
+		c.Assert(err, check.IsNil)
+		defer resp.Body.Close()
+		c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+		var regions laggingRegionsResp
+		c.Assert(json.NewDecoder(resp.Body).Decode(&regions), check.IsNil)
+		return regions
+	}
+	regions := get("?cf-id=test-lagging&limit=2")
+	c.Assert(regions, check.DeepEquals, laggingRegionsResp{
+		ChangefeedID: "test-lagging",
+		TotalRegions: 5,
+		Regions: []kv.RegionResolvedTs{
+			{RegionID: 2, StoreID: 1, StoreAddr: "127.0.0.1:20160", TableID: 53, TableName: "test.t", ResolvedTs: 100, Initialized: true},
+			{RegionID: 4, StoreID: 1, StoreAddr: "127.0.0.1:20160", TableID: 53, TableName: "test.t", ResolvedTs: 200, Initialized: true},
+		},
+	})
+	regions = get("?cf-id=test-lagging")
+	c.Assert(regions.Regions, check.HasLen, 5)
+	c.Assert(regions.Regions[4].ResolvedTs, check.Equals, uint64(500))
+	regions = get("?cf-id=test-none")
+	c.Assert(regions.TotalRegions, check.Equals, 0)
+	c.Assert(regions.Regions, check.HasLen, 0)
+}
+
+func testHandlePausedSink(c *check.C) {
+	uri := fmt.Sprintf("http://%s/debug/paused-sink", testingServerOptions.advertiseAddr)
+	resp, err := http.Get(uri)
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusBadRequest)
+
+	resp, err = http.Get(uri + "?cf-id=test-paused")
+	c.Assert(err, check.IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+	var paused pausedSinkResp
+	c.Assert(json.NewDecoder(resp.Body).Decode(&paused), check.IsNil)
+	c.Assert(paused, check.DeepEquals, pausedSinkResp{ChangefeedID: "test-paused"})
+
+	// the sink not paused can't be resumed
+	resp, err = http.PostForm(uri, url.Values{APIOpVarChangefeedID: {"test-paused"}})
+	c.Assert(err, check.IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusBadRequest)
+}
+
 func testHTTPPostOnly(c *check.C, uri string) {
 	resp, err := http.Get(uri)
 	c.Assert(err, check.IsNil)
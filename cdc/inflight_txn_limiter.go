@@ -0,0 +1,115 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// inflightTxnLimiter bounds the number of the transactions, which are counted by the distinct
+// commit ts, buffered between the sorter outputs of the tables and the sink flush. The sorter
+// outputs wait when the limit is reached, and the transactions are released once the sink is
+// flushed past their commit ts. A nil inflightTxnLimiter doesn't limit anything.
+//
+// The transactions committed after the resolved ts of a table can't be flushed before the table
+// forwards its resolved ts, which needs the sorter output of the table to move on. So a table
+// isn't blocked when all the in-flight transactions are committed after its resolved ts, then
+// the limit is exceeded by the transactions of the lagging table until it forwards its resolved ts.
+type inflightTxnLimiter struct {
+	// limit is not positive if the in-flight transactions are unlimited
+	limit int
+
+	mu sync.Mutex
+	// inflight is the set of the commit ts of the in-flight transactions
+	inflight map[uint64]struct{}
+	// minCommitTs is the min commit ts of the in-flight transactions
+	minCommitTs uint64
+	// releasedCh is closed and replaced when some transactions are released
+	releasedCh chan struct{}
+
+	metricInflightTxns prometheus.Gauge
+}
+
+func newInflightTxnLimiter(changefeedID, captureAddr string, limit int) *inflightTxnLimiter {
+	return &inflightTxnLimiter{
+		limit:              limit,
+		inflight:           make(map[uint64]struct{}),
+		releasedCh:         make(chan struct{}),
+		metricInflightTxns: inflightTxnGauge.WithLabelValues(changefeedID, captureAddr),
+	}
+}
+
+// acquire waits until the transaction of the commit ts is allowed to enter the pipeline,
+// resolvedTs is the resolved ts of the table of the transaction
+func (l *inflightTxnLimiter) acquire(ctx context.Context, commitTs, resolvedTs uint64) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		_, exist := l.inflight[commitTs]
+		if exist || l.limit <= 0 || len(l.inflight) < l.limit || l.minCommitTs > resolvedTs {
+			if !exist {
+				if len(l.inflight) == 0 || commitTs < l.minCommitTs {
+					l.minCommitTs = commitTs
+				}
+				l.inflight[commitTs] = struct{}{}
+				l.metricInflightTxns.Set(float64(len(l.inflight)))
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		releasedCh := l.releasedCh
+		l.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-releasedCh:
+		}
+	}
+}
+
+// release releases the transactions committed before or at the checkpoint ts
+func (l *inflightTxnLimiter) release(checkpointTs uint64) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.inflight) == 0 || l.minCommitTs > checkpointTs {
+		return
+	}
+	l.minCommitTs = 0
+	for commitTs := range l.inflight {
+		if commitTs <= checkpointTs {
+			delete(l.inflight, commitTs)
+		} else if l.minCommitTs == 0 || commitTs < l.minCommitTs {
+			l.minCommitTs = commitTs
+		}
+	}
+	l.metricInflightTxns.Set(float64(len(l.inflight)))
+	close(l.releasedCh)
+	l.releasedCh = make(chan struct{})
+}
+
+// inflightTxns returns the number of the in-flight transactions
+func (l *inflightTxnLimiter) inflightTxns() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.inflight)
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+type inflightTxnLimiterSuite struct{}
+
+var _ = check.Suite(&inflightTxnLimiterSuite{})
+
+// floodTxns sends txnNum small transactions of every table through the limiter, a table forwards
+// its resolved ts after every transaction, and the sink is flushed to the min resolved ts of the
+// tables concurrently. It returns the max number of the in-flight transactions observed.
+func floodTxns(c *check.C, limit, tableNum, txnNum int) int {
+	l := newInflightTxnLimiter("test-inflight", "127.0.0.1:8300", limit)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	resolvedTs := make([]uint64, tableNum)
+	var maxInflight int64
+	var wg sync.WaitGroup
+	errg, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < tableNum; i++ {
+		i := i
+		wg.Add(1)
+		errg.Go(func() error {
+			defer wg.Done()
+			for j := 1; j <= txnNum; j++ {
+				commitTs := uint64(j*tableNum + i)
+				// every transaction has a few rows
+				for row := 0; row < 1+j%3; row++ {
+					if err := l.acquire(ctx, commitTs, atomic.LoadUint64(&resolvedTs[i])); err != nil {
+						return errors.Trace(err)
+					}
+					if n := int64(l.inflightTxns()); n > atomic.LoadInt64(&maxInflight) {
+						atomic.StoreInt64(&maxInflight, n)
+					}
+				}
+				atomic.StoreUint64(&resolvedTs[i], commitTs)
+			}
+			// the finished tables don't hold the resolved ts back
+			atomic.StoreUint64(&resolvedTs[i], math.MaxUint64)
+			return nil
+		})
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	errg.Go(func() error {
+		for {
+			minTs := uint64(math.MaxUint64)
+			for i := range resolvedTs {
+				if ts := atomic.LoadUint64(&resolvedTs[i]); ts < minTs {
+					minTs = ts
+				}
+			}
+			l.release(minTs)
+			select {
+			case <-ctx.Done():
+				return errors.Trace(ctx.Err())
+			case <-done:
+				return nil
+			case <-time.After(time.Millisecond):
+			}
+		}
+	})
+	c.Assert(errg.Wait(), check.IsNil)
+	l.release(math.MaxUint64)
+	c.Assert(l.inflightTxns(), check.Equals, 0)
+	return int(atomic.LoadInt64(&maxInflight))
+}
+
+func (s *inflightTxnLimiterSuite) TestFloodSmallTxns(c *check.C) {
+	c.Assert(floodTxns(c, 16, 1, 5000), check.LessEqual, 16)
+	// a lagging table may exceed the limit by a transaction before it forwards its resolved ts
+	c.Assert(floodTxns(c, 16, 4, 2000), check.LessEqual, 16+4)
+}
+
+func (s *inflightTxnLimiterSuite) TestResolvedTsNotBlocked(c *check.C) {
+	ctx := context.Background()
+	l := newInflightTxnLimiter("test-inflight", "127.0.0.1:8300", 2)
+	c.Assert(l.acquire(ctx, 10, 5), check.IsNil)
+	c.Assert(l.acquire(ctx, 11, 5), check.IsNil)
+	// the rows of an in-flight transaction are always admitted
+	c.Assert(l.acquire(ctx, 11, 5), check.IsNil)
+	// the in-flight transactions can't be flushed before the table forwards its resolved ts,
+	// so the table isn't blocked
+	c.Assert(l.acquire(ctx, 12, 9), check.IsNil)
+	c.Assert(l.inflightTxns(), check.Equals, 3)
+
+	// the transactions before the resolved ts of the table are released by the flush
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- l.acquire(ctx, 13, 12)
+	}()
+	select {
+	case err := <-acquired:
+		c.Fatalf("the transaction is admitted over the limit: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	l.release(10)
+	select {
+	case <-acquired:
+		c.Fatal("the transaction is admitted over the limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+	l.release(12)
+	c.Assert(<-acquired, check.IsNil)
+	c.Assert(l.inflightTxns(), check.Equals, 1)
+
+	cctx, cancel := context.WithCancel(ctx)
+	c.Assert(l.acquire(cctx, 14, 13), check.IsNil)
+	cancel()
+	c.Assert(errors.Cause(l.acquire(cctx, 15, 14)), check.Equals, context.Canceled)
+
+	unlimited := newInflightTxnLimiter("test-inflight", "127.0.0.1:8300", 0)
+	for ts := uint64(1); ts <= 100; ts++ {
+		c.Assert(unlimited.acquire(ctx, ts, 0), check.IsNil)
+	}
+	c.Assert(unlimited.inflightTxns(), check.Equals, 100)
+
+	var nilLimiter *inflightTxnLimiter
+	c.Assert(nilLimiter.acquire(ctx, 1, 0), check.IsNil)
+	nilLimiter.release(1)
+}
@@ -0,0 +1,271 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// integrityReader reads the tables at snapshots, it's implemented by sink.SnapshotReader
+type integrityReader interface {
+	EstimateRowCount(ctx context.Context, schema, table string) (int64, error)
+	Checksum(ctx context.Context, schema, table string, columns []string, ts uint64) (model.TableChecksum, error)
+	Close() error
+}
+
+// integrityDownstreamReader reads the syncpoints recorded in the downstream besides the tables
+type integrityDownstreamReader interface {
+	integrityReader
+	LatestSyncpoint(ctx context.Context, changefeedID model.ChangeFeedID) (primaryTs, secondaryTs uint64, err error)
+}
+
+// checkedTable is a table replicated by the changefeed which could be checked
+type checkedTable struct {
+	name    model.TableName
+	columns []string
+}
+
+// integrityChecker periodically compares the row counts and checksums of the sampled tables
+// between the upstream and the downstream at the latest syncpoint. The checks run in the
+// background, and the mismatches are only reported without failing the changefeed.
+type integrityChecker struct {
+	changefeedID model.ChangeFeedID
+	cfg          *config.IntegrityCheckConfig
+	upstream     integrityReader
+	downstream   integrityDownstreamReader
+	rand         *rand.Rand
+
+	wg sync.WaitGroup
+
+	mu            sync.Mutex
+	lastCheckTime time.Time
+	running       bool
+	cancel        context.CancelFunc
+	result        *model.IntegrityCheckResult
+}
+
+func newIntegrityChecker(ctx context.Context, id model.ChangeFeedID, info *model.ChangeFeedInfo) (*integrityChecker, error) {
+	cfg := info.Config.IntegrityCheck
+	sinkURI, err := url.Parse(info.SinkURI)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	err = cfg.Validate(sinkURI.Scheme, info.SyncPointEnabled)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	upstream, err := sink.NewSnapshotReader(ctx, cfg.UpstreamDSN)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	downstream, err := sink.NewDownstreamSnapshotReader(ctx, id, info.SinkURI)
+	if err != nil {
+		_ = upstream.Close()
+		return nil, errors.Trace(err)
+	}
+	return &integrityChecker{
+		changefeedID:  id,
+		cfg:           cfg,
+		upstream:      upstream,
+		downstream:    downstream,
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		lastCheckTime: time.Now(),
+	}, nil
+}
+
+// tick starts a check in the background if the interval has elapsed since the last check
+// and no check is running, then returns the result of the last finished check. The check
+// is skipped if the latest syncpoint is before schemaTs, the ts of the schema of the tables.
+func (c *integrityChecker) tick(ctx context.Context, now time.Time, schemaTs model.Ts, tables func() []*checkedTable) *model.IntegrityCheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running || now.Sub(c.lastCheckTime) < time.Duration(c.cfg.Interval)*time.Second {
+		return c.result
+	}
+	c.running = true
+	c.lastCheckTime = now
+	candidates := tables()
+	ctx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		result := c.check(ctx, now, schemaTs, candidates)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.running = false
+		c.result = result
+	}()
+	return c.result
+}
+
+// check compares the sampled tables between the upstream and the downstream
+func (c *integrityChecker) check(ctx context.Context, now time.Time, schemaTs model.Ts, candidates []*checkedTable) *model.IntegrityCheckResult {
+	result := &model.IntegrityCheckResult{CheckTime: now}
+	err := c.compareTables(ctx, schemaTs, candidates, result)
+	if err != nil {
+		log.Warn("integrity check failed", zap.String("changefeed", c.changefeedID), zap.Error(err))
+		result.Error = err.Error()
+	}
+	if len(result.Mismatches) > 0 {
+		log.Warn("integrity check found mismatched tables", zap.String("changefeed", c.changefeedID),
+			zap.Uint64("checkpointTs", result.CheckpointTs), zap.Reflect("mismatches", result.Mismatches))
+	}
+	integrityCheckMismatchGauge.WithLabelValues(c.changefeedID).Set(float64(len(result.Mismatches)))
+	return result
+}
+
+func (c *integrityChecker) compareTables(ctx context.Context, schemaTs model.Ts, candidates []*checkedTable, result *model.IntegrityCheckResult) error {
+	primaryTs, secondaryTs, err := c.downstream.LatestSyncpoint(ctx, c.changefeedID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if primaryTs == 0 {
+		return errors.New("no syncpoint is recorded in the downstream")
+	}
+	if primaryTs < schemaTs {
+		return errors.Errorf("the latest syncpoint %d is before the schema of the tables at %d", primaryTs, schemaTs)
+	}
+	result.CheckpointTs, result.SecondaryTs = primaryTs, secondaryTs
+	tables, err := c.sampleTables(ctx, candidates)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, table := range tables {
+		name := table.name.String()
+		result.Tables = append(result.Tables, name)
+		upstream, err := c.upstream.Checksum(ctx, table.name.Schema, table.name.Table, table.columns, primaryTs)
+		if err != nil {
+			return errors.Annotatef(err, "failed to read table %s from upstream", name)
+		}
+		downstream, err := c.downstream.Checksum(ctx, table.name.Schema, table.name.Table, table.columns, secondaryTs)
+		if err != nil {
+			return errors.Annotatef(err, "failed to read table %s from downstream", name)
+		}
+		if upstream != downstream {
+			result.Mismatches = append(result.Mismatches, &model.IntegrityMismatch{
+				Table:      name,
+				Upstream:   upstream,
+				Downstream: downstream,
+			})
+		}
+	}
+	return nil
+}
+
+// sampleTables returns the configured tables replicated by the changefeed, or samples
+// the small tables if no table is configured.
+func (c *integrityChecker) sampleTables(ctx context.Context, candidates []*checkedTable) ([]*checkedTable, error) {
+	if len(c.cfg.Tables) > 0 {
+		byName := make(map[string]*checkedTable, len(candidates))
+		for _, table := range candidates {
+			byName[table.name.String()] = table
+		}
+		tables := make([]*checkedTable, 0, len(c.cfg.Tables))
+		for _, name := range c.cfg.Tables {
+			table, ok := byName[name]
+			if !ok {
+				log.Warn("skip the table not replicated by the changefeed in integrity check",
+					zap.String("changefeed", c.changefeedID), zap.String("table", name))
+				continue
+			}
+			tables = append(tables, table)
+		}
+		return tables, nil
+	}
+
+	tables := make([]*checkedTable, 0, c.cfg.SampleSize)
+	for _, i := range c.rand.Perm(len(candidates)) {
+		if len(tables) >= c.cfg.SampleSize {
+			break
+		}
+		table := candidates[i]
+		rows, err := c.upstream.EstimateRowCount(ctx, table.name.Schema, table.name.Table)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if rows <= c.cfg.MaxRows {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+func (c *integrityChecker) close() {
+	c.mu.Lock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+	if err := c.upstream.Close(); err != nil {
+		log.Warn("failed to close the upstream reader of integrity check", zap.Error(err))
+	}
+	if err := c.downstream.Close(); err != nil {
+		log.Warn("failed to close the downstream reader of integrity check", zap.Error(err))
+	}
+	integrityCheckMismatchGauge.DeleteLabelValues(c.changefeedID)
+}
+
+// integrityCheckTables returns the tables replicated by the changefeed with their columns
+func (c *changeFeed) integrityCheckTables() []*checkedTable {
+	tables := make([]*checkedTable, 0, len(c.tables))
+	for id, name := range c.tables {
+		info, ok := c.schema.TableByID(id)
+		if !ok {
+			continue
+		}
+		columns := make([]string, 0, len(info.Columns))
+		for _, col := range info.Cols() {
+			columns = append(columns, col.Name.O)
+		}
+		tables = append(tables, &checkedTable{name: name, columns: columns})
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].name.String() < tables[j].name.String()
+	})
+	return tables
+}
+
+// handleIntegrityCheck runs the integrity check of the changefeed periodically and records
+// the result into the changefeed status
+func (c *changeFeed) handleIntegrityCheck(ctx context.Context, now time.Time) {
+	if c.integrityChecker == nil {
+		return
+	}
+	result := c.integrityChecker.tick(ctx, now, c.ddlExecutedTs, c.integrityCheckTables)
+	if result != nil {
+		c.status.IntegrityCheck = result
+	}
+}
+
+// handleIntegrityCheck calls handleIntegrityCheck of every changefeed
+func (o *Owner) handleIntegrityCheck(ctx context.Context, now time.Time) {
+	for _, cf := range o.changeFeeds {
+		cf.handleIntegrityCheck(ctx, now)
+	}
+}
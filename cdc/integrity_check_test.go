@@ -0,0 +1,247 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type integrityCheckSuite struct{}
+
+var _ = check.Suite(&integrityCheckSuite{})
+
+// mockIntegrityReader returns the row counts and checksums of the tables by name
+type mockIntegrityReader struct {
+	mu          sync.Mutex
+	rowCounts   map[string]int64
+	checksums   map[string]model.TableChecksum
+	checksumErr error
+	readTs      map[string]uint64
+
+	primaryTs   uint64
+	secondaryTs uint64
+	closed      bool
+}
+
+func newMockIntegrityReader(checksums map[string]model.TableChecksum) *mockIntegrityReader {
+	return &mockIntegrityReader{
+		rowCounts: make(map[string]int64),
+		checksums: checksums,
+		readTs:    make(map[string]uint64),
+	}
+}
+
+func (r *mockIntegrityReader) EstimateRowCount(ctx context.Context, schema, table string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rowCounts[schema+"."+table], nil
+}
+
+func (r *mockIntegrityReader) Checksum(ctx context.Context, schema, table string, columns []string, ts uint64) (model.TableChecksum, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.checksumErr != nil {
+		return model.TableChecksum{}, r.checksumErr
+	}
+	r.readTs[schema+"."+table] = ts
+	return r.checksums[schema+"."+table], nil
+}
+
+func (r *mockIntegrityReader) LatestSyncpoint(ctx context.Context, changefeedID model.ChangeFeedID) (uint64, uint64, error) {
+	return r.primaryTs, r.secondaryTs, nil
+}
+
+func (r *mockIntegrityReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func newTestIntegrityChecker(cfg *config.IntegrityCheckConfig, upstream, downstream *mockIntegrityReader) *integrityChecker {
+	return &integrityChecker{
+		changefeedID: "test-integrity",
+		cfg:          cfg,
+		upstream:     upstream,
+		downstream:   downstream,
+		rand:         rand.New(rand.NewSource(1)),
+	}
+}
+
+func newTestCheckedTables(names ...string) []*checkedTable {
+	tables := make([]*checkedTable, 0, len(names))
+	for _, name := range names {
+		tables = append(tables, &checkedTable{
+			name:    model.TableName{Schema: "test", Table: name},
+			columns: []string{"id", "v"},
+		})
+	}
+	return tables
+}
+
+func (s *integrityCheckSuite) TestCompareTables(c *check.C) {
+	ctx := context.Background()
+	upstream := newMockIntegrityReader(map[string]model.TableChecksum{
+		"test.t1": {RowCount: 10, Checksum: 1234},
+		"test.t2": {RowCount: 20, Checksum: 5678},
+	})
+	downstream := newMockIntegrityReader(map[string]model.TableChecksum{
+		"test.t1": {RowCount: 10, Checksum: 1234},
+		"test.t2": {RowCount: 19, Checksum: 8765},
+	})
+	downstream.primaryTs, downstream.secondaryTs = 100, 200
+	checker := newTestIntegrityChecker(&config.IntegrityCheckConfig{
+		Tables: []string{"test.t1", "test.t2", "test.not_replicated"},
+	}, upstream, downstream)
+	candidates := newTestCheckedTables("t1", "t2", "t3")
+	now := time.Now()
+
+	result := checker.check(ctx, now, 90, candidates)
+	c.Assert(result.Error, check.Equals, "")
+	c.Assert(result.CheckTime, check.Equals, now)
+	c.Assert(result.CheckpointTs, check.Equals, uint64(100))
+	c.Assert(result.SecondaryTs, check.Equals, uint64(200))
+	c.Assert(result.Tables, check.DeepEquals, []string{"test.t1", "test.t2"})
+	c.Assert(result.Mismatches, check.DeepEquals, []*model.IntegrityMismatch{{
+		Table:      "test.t2",
+		Upstream:   model.TableChecksum{RowCount: 20, Checksum: 5678},
+		Downstream: model.TableChecksum{RowCount: 19, Checksum: 8765},
+	}})
+	// the upstream is read at the syncpoint and the downstream is read at the corresponding ts
+	c.Assert(upstream.readTs, check.DeepEquals, map[string]uint64{"test.t1": 100, "test.t2": 100})
+	c.Assert(downstream.readTs, check.DeepEquals, map[string]uint64{"test.t1": 200, "test.t2": 200})
+	gauge := integrityCheckMismatchGauge.WithLabelValues("test-integrity")
+	c.Assert(testutil.ToFloat64(gauge), check.Equals, float64(1))
+
+	// the schema of the tables are changed after the latest syncpoint
+	result = checker.check(ctx, now, 101, candidates)
+	c.Assert(result.Error, check.Matches, "the latest syncpoint 100 is before the schema of the tables at 101")
+	c.Assert(result.Tables, check.HasLen, 0)
+	c.Assert(testutil.ToFloat64(gauge), check.Equals, float64(0))
+
+	downstream.checksumErr = errors.New("downstream is gone")
+	result = checker.check(ctx, now, 90, candidates)
+	c.Assert(result.Error, check.Matches, "failed to read table test.t1 from downstream: downstream is gone")
+
+	downstream.primaryTs, downstream.secondaryTs = 0, 0
+	result = checker.check(ctx, now, 90, candidates)
+	c.Assert(result.Error, check.Equals, "no syncpoint is recorded in the downstream")
+	c.Assert(result.CheckpointTs, check.Equals, uint64(0))
+}
+
+func (s *integrityCheckSuite) TestSampleTables(c *check.C) {
+	ctx := context.Background()
+	upstream := newMockIntegrityReader(nil)
+	upstream.rowCounts = map[string]int64{
+		"test.t1": 10, "test.t2": 1000, "test.t3": 50, "test.t4": 5000, "test.t5": 100,
+	}
+	checker := newTestIntegrityChecker(&config.IntegrityCheckConfig{SampleSize: 2, MaxRows: 100}, upstream, nil)
+	candidates := newTestCheckedTables("t1", "t2", "t3", "t4", "t5")
+
+	small := map[string]bool{"test.t1": true, "test.t3": true, "test.t5": true}
+	sampled := make(map[string]int)
+	for i := 0; i < 50; i++ {
+		tables, err := checker.sampleTables(ctx, candidates)
+		c.Assert(err, check.IsNil)
+		c.Assert(tables, check.HasLen, 2)
+		c.Assert(tables[0], check.Not(check.Equals), tables[1])
+		for _, table := range tables {
+			c.Assert(small[table.name.String()], check.IsTrue)
+			sampled[table.name.String()]++
+		}
+	}
+	// every small table has a chance to be sampled
+	c.Assert(sampled, check.HasLen, 3)
+
+	checker.cfg.SampleSize = 5
+	tables, err := checker.sampleTables(ctx, candidates)
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.HasLen, 3)
+
+	tables, err = checker.sampleTables(ctx, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.HasLen, 0)
+}
+
+func (s *integrityCheckSuite) TestReportIntegrityCheck(c *check.C) {
+	ctx := context.Background()
+	upstream := newMockIntegrityReader(map[string]model.TableChecksum{"test.t1": {RowCount: 1, Checksum: 1}})
+	downstream := newMockIntegrityReader(map[string]model.TableChecksum{"test.t1": {RowCount: 1, Checksum: 1}})
+	downstream.primaryTs, downstream.secondaryTs = 100, 200
+	checker := newTestIntegrityChecker(&config.IntegrityCheckConfig{Interval: 60, SampleSize: 1, MaxRows: 10}, upstream, downstream)
+	start := time.Now()
+	checker.lastCheckTime = start
+	listed := 0
+	tables := func() []*checkedTable {
+		listed++
+		return newTestCheckedTables("t1")
+	}
+
+	// no check is started before the interval elapses
+	c.Assert(checker.tick(ctx, start.Add(30*time.Second), 0, tables), check.IsNil)
+	c.Assert(listed, check.Equals, 0)
+	c.Assert(checker.tick(ctx, start.Add(time.Minute), 0, tables), check.IsNil)
+	checker.wg.Wait()
+	c.Assert(listed, check.Equals, 1)
+	result := checker.tick(ctx, start.Add(90*time.Second), 0, tables)
+	c.Assert(result, check.NotNil)
+	c.Assert(result.Tables, check.DeepEquals, []string{"test.t1"})
+	c.Assert(result.Mismatches, check.HasLen, 0)
+	c.Assert(listed, check.Equals, 1)
+
+	// the result is recorded into the changefeed status
+	cf := &changeFeed{
+		status:           &model.ChangeFeedStatus{},
+		tables:           make(map[model.TableID]model.TableName),
+		integrityChecker: checker,
+	}
+	cf.handleIntegrityCheck(ctx, start.Add(90*time.Second))
+	c.Assert(cf.status.IntegrityCheck, check.Equals, result)
+	cf.handleIntegrityCheck(ctx, start.Add(2*time.Minute))
+	checker.wg.Wait()
+	cf.handleIntegrityCheck(ctx, start.Add(2*time.Minute))
+	c.Assert(cf.status.IntegrityCheck, check.Not(check.Equals), result)
+	c.Assert(cf.status.IntegrityCheck.CheckTime, check.Equals, start.Add(2*time.Minute))
+	// there is no table to check in the changefeed
+	c.Assert(cf.status.IntegrityCheck.Tables, check.HasLen, 0)
+
+	checker.close()
+	c.Assert(upstream.closed, check.IsTrue)
+	c.Assert(downstream.closed, check.IsTrue)
+}
+
+func (s *integrityCheckSuite) TestRefuseInvalidIntegrityCheck(c *check.C) {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.IntegrityCheck.Enable = true
+	cfg.IntegrityCheck.UpstreamDSN = "root@tcp(127.0.0.1:4000)/"
+	_, err := newIntegrityChecker(context.Background(), "test-integrity", &model.ChangeFeedInfo{
+		SinkURI:          "kafka://127.0.0.1:9092/topic",
+		SyncPointEnabled: true,
+		Config:           cfg,
+	})
+	c.Assert(cerror.ErrIntegrityCheckInvalid.Equal(err), check.IsTrue)
+	_, err = newIntegrityChecker(context.Background(), "test-integrity", &model.ChangeFeedInfo{
+		SinkURI: "tidb://127.0.0.1:4000/",
+		Config:  cfg,
+	})
+	c.Assert(err, check.ErrorMatches, ".*the sync point must be enabled.*")
+}
@@ -833,7 +833,9 @@ func (s *eventFeedSession) partialRegionFeed(
 	}()
 
 	ts := state.sri.ts
-	maxTs, err := s.singleEventFeed(ctx, state.sri.verID.GetID(), state.sri.span, state.sri.ts, receiver)
+	tracked := trackRegion(ctx, state.sri)
+	maxTs, err := s.singleEventFeed(ctx, state.sri.verID.GetID(), state.sri.span, state.sri.ts, tracked, receiver)
+	tracked.untrack()
 	log.Debug("singleEventFeed quit")
 
 	if err == nil || errors.Cause(err) == context.Canceled {
@@ -1223,6 +1225,7 @@ func (s *eventFeedSession) singleEventFeed(
 	regionID uint64,
 	span regionspan.ComparableSpan,
 	startTs uint64,
+	tracked *trackedRegion,
 	receiverCh <-chan *regionEvent,
 ) (uint64, error) {
 	captureAddr := util.CaptureAddrFromCtx(ctx)
@@ -1266,6 +1269,7 @@ func (s *eventFeedSession) singleEventFeed(
 			},
 		}
 		lastResolvedTs = resolvedTs
+		tracked.setResolvedTs(resolvedTs)
 
 		select {
 		case s.eventCh <- revent:
@@ -1352,6 +1356,7 @@ func (s *eventFeedSession) singleEventFeed(
 						}
 						metricPullEventInitializedCounter.Inc()
 						initialized = true
+						tracked.setInitialized()
 						for _, cacheEntry := range matcher.cachedCommit {
 							value, ok := matcher.matchRow(cacheEntry)
 							if !ok {
@@ -16,6 +16,8 @@ package kv
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -25,6 +27,7 @@ import (
 	"github.com/pingcap/ticdc/pkg/etcd"
 	"github.com/pingcap/ticdc/pkg/retry"
 	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/tikv"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/clientv3/concurrency"
@@ -55,6 +58,9 @@ const (
 
 	// JobKeyPrefix is the prefix of job keys
 	JobKeyPrefix = EtcdKeyBase + "/job"
+
+	// QuarantineKeyPrefix is the prefix of the keys moved aside by the owner because they are inconsistent
+	QuarantineKeyPrefix = EtcdKeyBase + "/quarantine"
 )
 
 // GetEtcdKeyChangeFeedList returns the prefix key of all changefeed config
@@ -82,6 +88,26 @@ func GetEtcdKeyTaskPositionList(changefeedID string) string {
 	return fmt.Sprintf("%s/changefeed/task/position/%s", EtcdKeyBase, changefeedID)
 }
 
+// GetEtcdKeyEmittedDDLs returns the key of the DDLs emitted by a changefeed
+func GetEtcdKeyEmittedDDLs(changefeedID string) string {
+	return fmt.Sprintf("%s/changefeed/ddl/emitted/%s", EtcdKeyBase, changefeedID)
+}
+
+// GetEtcdKeyQuarantinedTableList returns the prefix key of the tables quarantined by a changefeed
+func GetEtcdKeyQuarantinedTableList(changefeedID string) string {
+	return fmt.Sprintf("%s/changefeed/quarantined-table/%s", EtcdKeyBase, changefeedID)
+}
+
+// GetEtcdKeyQuarantinedTable returns the key of a table quarantined by a changefeed
+func GetEtcdKeyQuarantinedTable(changefeedID string, tableID model.TableID) string {
+	return fmt.Sprintf("%s/%d", GetEtcdKeyQuarantinedTableList(changefeedID), tableID)
+}
+
+// GetEtcdKeyChangeFeedHistory returns the key of the tombstones of the garbage collected changefeeds
+func GetEtcdKeyChangeFeedHistory() string {
+	return fmt.Sprintf("%s/changefeed/history", EtcdKeyBase)
+}
+
 // GetEtcdKeyTaskPosition returns the key of a task position
 func GetEtcdKeyTaskPosition(changefeedID, captureID string) string {
 	return TaskPositionKeyPrefix + "/" + captureID + "/" + changefeedID
@@ -107,6 +133,11 @@ func GetEtcdKeyJob(changeFeedID string) string {
 	return JobKeyPrefix + "/" + changeFeedID
 }
 
+// GetEtcdKeyQuarantine returns the quarantine key of a CDC key
+func GetEtcdKeyQuarantine(key string) string {
+	return QuarantineKeyPrefix + strings.TrimPrefix(key, EtcdKeyBase)
+}
+
 // CDCEtcdClient is a wrap of etcd client
 type CDCEtcdClient struct {
 	Client *etcd.Client
@@ -223,6 +254,19 @@ func (c CDCEtcdClient) GetChangeFeedStatus(ctx context.Context, id string) (*mod
 	return info, resp.Kvs[0].ModRevision, errors.Trace(err)
 }
 
+// GetGCSafepoint returns the GC safepoint saved by TiDB, 0 is returned if it has never been saved
+func (c CDCEtcdClient) GetGCSafepoint(ctx context.Context) (uint64, error) {
+	resp, err := c.Client.Get(ctx, tikv.GcSavedSafePoint)
+	if err != nil {
+		return 0, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	if resp.Count == 0 {
+		return 0, nil
+	}
+	safepoint, err := strconv.ParseUint(string(resp.Kvs[0].Value), 10, 64)
+	return safepoint, errors.Trace(err)
+}
+
 // GetCaptures returns kv revision and CaptureInfo list
 func (c CDCEtcdClient) GetCaptures(ctx context.Context) (int64, []*model.CaptureInfo, error) {
 	key := CaptureInfoKeyPrefix
@@ -711,6 +755,204 @@ func (c CDCEtcdClient) RemoveChangeFeedStatus(
 	return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
 }
 
+// GetEmittedDDLs queries the DDLs emitted by a changefeed, an empty EmittedDDLs
+// is returned if no DDL is recorded
+func (c CDCEtcdClient) GetEmittedDDLs(ctx context.Context, changefeedID string) (model.EmittedDDLs, error) {
+	key := GetEtcdKeyEmittedDDLs(changefeedID)
+	resp, err := c.Client.Get(ctx, key)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	emitted := make(model.EmittedDDLs)
+	if resp.Count == 0 {
+		return emitted, nil
+	}
+	err = emitted.Unmarshal(resp.Kvs[0].Value)
+	return emitted, errors.Trace(err)
+}
+
+// PutEmittedDDLs puts the DDLs emitted by a changefeed into etcd
+func (c CDCEtcdClient) PutEmittedDDLs(ctx context.Context, changefeedID string, emitted model.EmittedDDLs) error {
+	value, err := emitted.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = c.Client.Put(ctx, GetEtcdKeyEmittedDDLs(changefeedID), value)
+	return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+}
+
+// RemoveEmittedDDLs removes the DDLs emitted by a changefeed from etcd
+func (c CDCEtcdClient) RemoveEmittedDDLs(ctx context.Context, changefeedID string) error {
+	_, err := c.Client.Delete(ctx, GetEtcdKeyEmittedDDLs(changefeedID))
+	return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+}
+
+// GetQuarantinedTables queries the tables quarantined by a changefeed
+func (c CDCEtcdClient) GetQuarantinedTables(ctx context.Context, changefeedID string) (map[model.TableID]*model.QuarantinedTable, error) {
+	resp, err := c.Client.Get(ctx, GetEtcdKeyQuarantinedTableList(changefeedID)+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	tables := make(map[model.TableID]*model.QuarantinedTable, resp.Count)
+	for _, rawKv := range resp.Kvs {
+		table := &model.QuarantinedTable{}
+		if err := table.Unmarshal(rawKv.Value); err != nil {
+			return nil, errors.Trace(err)
+		}
+		tables[table.TableID] = table
+	}
+	return tables, nil
+}
+
+// PutQuarantinedTable puts a table quarantined by a changefeed into etcd
+func (c CDCEtcdClient) PutQuarantinedTable(ctx context.Context, changefeedID string, table *model.QuarantinedTable) error {
+	value, err := table.Marshal()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = c.Client.Put(ctx, GetEtcdKeyQuarantinedTable(changefeedID, table.TableID), value)
+	return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+}
+
+// DeleteQuarantinedTable releases a table quarantined by a changefeed
+func (c CDCEtcdClient) DeleteQuarantinedTable(ctx context.Context, changefeedID string, tableID model.TableID) error {
+	resp, err := c.Client.Delete(ctx, GetEtcdKeyQuarantinedTable(changefeedID, tableID))
+	if err != nil {
+		return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	if resp.Deleted == 0 {
+		return cerror.ErrTableNotQuarantined.GenWithStackByArgs(tableID, changefeedID)
+	}
+	return nil
+}
+
+// RemoveQuarantinedTables removes all the tables quarantined by a changefeed from etcd
+func (c CDCEtcdClient) RemoveQuarantinedTables(ctx context.Context, changefeedID string) error {
+	_, err := c.Client.Delete(ctx, GetEtcdKeyQuarantinedTableList(changefeedID)+"/", clientv3.WithPrefix())
+	return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+}
+
+// GetChangeFeedHistory queries the tombstones of the garbage collected changefeeds,
+// the revision of the history key is returned, which is 0 if the key doesn't exist
+func (c CDCEtcdClient) GetChangeFeedHistory(ctx context.Context) (*model.ChangeFeedHistory, int64, error) {
+	resp, err := c.Client.Get(ctx, GetEtcdKeyChangeFeedHistory())
+	if err != nil {
+		return nil, 0, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	history := &model.ChangeFeedHistory{}
+	if resp.Count == 0 {
+		return history, 0, nil
+	}
+	err = history.Unmarshal(resp.Kvs[0].Value)
+	return history, resp.Kvs[0].ModRevision, errors.Trace(err)
+}
+
+// DeleteChangeFeedMeta appends the tombstone of a changefeed to the history and deletes all
+// the keys of the changefeed in a transaction. Nothing is changed and false is returned if the
+// history, the changefeed info or status is modified since the given revisions, a zero revision
+// means the key doesn't exist.
+func (c CDCEtcdClient) DeleteChangeFeedMeta(
+	ctx context.Context, tombstone *model.ChangeFeedTombstone, infoRevision, statusRevision int64,
+) (bool, error) {
+	id := tombstone.ID
+	history, historyRevision, err := c.GetChangeFeedHistory(ctx)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	history.Append(tombstone)
+	value, err := history.Marshal()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	cmps := []clientv3.Cmp{
+		clientv3.Compare(clientv3.ModRevision(GetEtcdKeyChangeFeedHistory()), "=", historyRevision),
+		clientv3.Compare(clientv3.ModRevision(GetEtcdKeyChangeFeedInfo(id)), "=", infoRevision),
+		clientv3.Compare(clientv3.ModRevision(GetEtcdKeyChangeFeedStatus(id)), "=", statusRevision),
+	}
+	ops := []clientv3.Op{
+		clientv3.OpPut(GetEtcdKeyChangeFeedHistory(), value),
+		clientv3.OpDelete(GetEtcdKeyChangeFeedInfo(id)),
+		clientv3.OpDelete(GetEtcdKeyChangeFeedStatus(id)),
+		clientv3.OpDelete(GetEtcdKeyEmittedDDLs(id)),
+		clientv3.OpDelete(GetEtcdKeyQuarantinedTableList(id)+"/", clientv3.WithPrefix()),
+	}
+	resp, err := c.Client.Get(ctx, TaskKeyPrefix+"/", clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return false, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	for _, rawKv := range resp.Kvs {
+		suffix, err := model.ExtractKeySuffix(string(rawKv.Key))
+		if err != nil {
+			return false, err
+		}
+		if suffix == id {
+			ops = append(ops, clientv3.OpDelete(string(rawKv.Key)))
+		}
+	}
+	txnResp, err := c.Client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return false, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	return txnResp.Succeeded, nil
+}
+
+// QuarantineKey moves a key to the quarantine prefix with the reason. The key is kept
+// untouched and false is returned if it is modified since the revision of rawKv.
+func (c CDCEtcdClient) QuarantineKey(ctx context.Context, rawKv *mvccpb.KeyValue, reason string) (bool, error) {
+	key := string(rawKv.Key)
+	item := &model.QuarantinedItem{
+		Key:            key,
+		Value:          string(rawKv.Value),
+		Reason:         reason,
+		QuarantineTime: time.Now(),
+	}
+	value, err := item.Marshal()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	resp, err := c.Client.Txn(ctx).If(
+		clientv3.Compare(clientv3.ModRevision(key), "=", rawKv.ModRevision),
+	).Then(
+		clientv3.OpPut(GetEtcdKeyQuarantine(key), value),
+		clientv3.OpDelete(key),
+	).Commit()
+	if err != nil {
+		return false, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	return resp.Succeeded, nil
+}
+
+// GetQuarantinedItems returns all quarantined items
+func (c CDCEtcdClient) GetQuarantinedItems(ctx context.Context) ([]*model.QuarantinedItem, error) {
+	resp, err := c.Client.Get(ctx, QuarantineKeyPrefix+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	items := make([]*model.QuarantinedItem, 0, resp.Count)
+	for _, rawKv := range resp.Kvs {
+		item := &model.QuarantinedItem{}
+		err := item.Unmarshal(rawKv.Value)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// DeleteQuarantinedItem deletes a quarantined item by its original key
+func (c CDCEtcdClient) DeleteQuarantinedItem(ctx context.Context, key string) error {
+	quarantineKey := GetEtcdKeyQuarantine(key)
+	resp, err := c.Client.Delete(ctx, quarantineKey)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	if resp.Deleted == 0 {
+		return cerror.ErrQuarantineNotExists.GenWithStackByArgs(quarantineKey)
+	}
+	return nil
+}
+
 // PutChangeFeedStatus puts changefeed synchronization status into etcd
 func (c CDCEtcdClient) PutChangeFeedStatus(
 	ctx context.Context,
@@ -0,0 +1,462 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+	"go.etcd.io/etcd/mvcc"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultTaskCachePageSize  = 1024
+	defaultTaskCacheWorkerNum = 8
+	// the max number of the task keys read in a single txn, which is the default limit of etcd
+	taskCacheMaxTxnOps = 128
+	// the interval to request a progress notification of the watch when waiting for a revision
+	taskCacheProgressInterval = 100 * time.Millisecond
+)
+
+// TaskCache keeps the task status and the task positions of all changefeeds in memory.
+// It is bootstrapped from a snapshot of the task keys listed page by page at a single revision,
+// the values of every changefeed are then read at the revision and decoded in parallel, and
+// a changefeed can be read as soon as its own values are loaded, see IsLoaded. The cache is
+// kept up to date by a watch started at the revision of the snapshot. It saves the owner from
+// listing all task keys for every changefeed.
+type TaskCache struct {
+	client    CDCEtcdClient
+	pageSize  int64
+	workerNum int
+
+	mu        sync.RWMutex
+	revision  int64
+	status    map[model.ChangeFeedID]model.ProcessorsInfos
+	positions map[model.ChangeFeedID]map[model.CaptureID]*model.TaskPosition
+	// snapshotRevision is the revision the task keys are listed at
+	snapshotRevision int64
+	// unloaded are the task keys of the changefeeds whose values aren't loaded yet
+	unloaded map[model.ChangeFeedID][]string
+	// pending are the watched changes of the unloaded changefeeds, which are applied
+	// after the values of the changefeeds are loaded
+	pending map[model.ChangeFeedID][]*taskEntry
+	// advanced is closed and replaced when the revision of the cache advances
+	advanced chan struct{}
+}
+
+// NewTaskCache creates a TaskCache, Load must be called before reading from it
+func NewTaskCache(client CDCEtcdClient) *TaskCache {
+	return &TaskCache{
+		client:    client,
+		pageSize:  defaultTaskCachePageSize,
+		workerNum: defaultTaskCacheWorkerNum,
+		advanced:  make(chan struct{}),
+	}
+}
+
+// Revision returns the etcd revision the cache is up to date with
+func (c *TaskCache) Revision() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.revision
+}
+
+// IsLoaded returns whether the task status and the task positions of the changefeed are loaded
+func (c *TaskCache) IsLoaded(changefeedID model.ChangeFeedID) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.unloaded[changefeedID]
+	return !ok
+}
+
+// Load replaces the content of the cache with a snapshot of the task keys, the values of
+// the changefeeds are loaded by Run
+func (c *TaskCache) Load(ctx context.Context) error {
+	start := time.Now()
+	unloaded := make(map[model.ChangeFeedID][]string)
+	revision, keyCount, err := c.listKeys(ctx, func(key string) {
+		entry, err := parseTaskKey(key)
+		if err != nil || entry == nil {
+			// the keys written by others than CDC are skipped as the watch does
+			return
+		}
+		unloaded[entry.changefeedID] = append(unloaded[entry.changefeedID], key)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.status = make(map[model.ChangeFeedID]model.ProcessorsInfos)
+	c.positions = make(map[model.ChangeFeedID]map[model.CaptureID]*model.TaskPosition)
+	c.snapshotRevision = revision
+	c.unloaded = unloaded
+	c.pending = make(map[model.ChangeFeedID][]*taskEntry)
+	c.setRevisionLocked(revision)
+	c.mu.Unlock()
+	log.Info("task cache keys listed", zap.Int64("revision", revision), zap.Int("keys", keyCount),
+		zap.Int("changefeeds", len(unloaded)), zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// listKeys lists the task keys without values page by page at the revision of the first page
+func (c *TaskCache) listKeys(ctx context.Context, fn func(key string)) (int64, int, error) {
+	var revision int64
+	keyCount := 0
+	key := TaskKeyPrefix + "/"
+	end := clientv3.GetPrefixRangeEnd(key)
+	for {
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(c.pageSize), clientv3.WithKeysOnly()}
+		if revision != 0 {
+			opts = append(opts, clientv3.WithRev(revision))
+		}
+		resp, err := c.client.Client.Get(ctx, key, opts...)
+		if err != nil {
+			return 0, 0, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+		}
+		if revision == 0 {
+			revision = resp.Header.Revision
+		}
+		keyCount += len(resp.Kvs)
+		for _, rawKv := range resp.Kvs {
+			fn(string(rawKv.Key))
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			return revision, keyCount, nil
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// loadChangefeeds loads the values of the unloaded changefeeds in parallel at the revision of
+// the snapshot, each changefeed can be read once its values are loaded
+func (c *TaskCache) loadChangefeeds(ctx context.Context) error {
+	start := time.Now()
+	c.mu.RLock()
+	revision := c.snapshotRevision
+	feeds := make(map[model.ChangeFeedID][]string, len(c.unloaded))
+	for changefeedID, keys := range c.unloaded {
+		feeds[changefeedID] = keys
+	}
+	c.mu.RUnlock()
+	if len(feeds) == 0 {
+		return nil
+	}
+
+	feedCh := make(chan model.ChangeFeedID, len(feeds))
+	for changefeedID := range feeds {
+		feedCh <- changefeedID
+	}
+	close(feedCh)
+	errg, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < c.workerNum; i++ {
+		errg.Go(func() error {
+			for changefeedID := range feedCh {
+				entries, err := c.readEntries(ctx, feeds[changefeedID], revision)
+				if err != nil {
+					return err
+				}
+				c.mu.Lock()
+				// the cache is reloaded at another revision, the values are stale
+				if c.snapshotRevision == revision {
+					for _, entry := range entries {
+						entry.applyTo(c.status, c.positions)
+					}
+					for _, entry := range c.pending[changefeedID] {
+						entry.applyTo(c.status, c.positions)
+					}
+					delete(c.pending, changefeedID)
+					delete(c.unloaded, changefeedID)
+				}
+				c.mu.Unlock()
+			}
+			return nil
+		})
+	}
+	if err := errg.Wait(); err != nil {
+		return err
+	}
+	log.Info("task cache loaded", zap.Int64("revision", revision),
+		zap.Int("changefeeds", len(feeds)), zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// readEntries reads and decodes the task keys at the revision
+func (c *TaskCache) readEntries(ctx context.Context, keys []string, revision int64) ([]*taskEntry, error) {
+	entries := make([]*taskEntry, 0, len(keys))
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > taskCacheMaxTxnOps {
+			n = taskCacheMaxTxnOps
+		}
+		ops := make([]clientv3.Op, 0, n)
+		for _, key := range keys[:n] {
+			ops = append(ops, clientv3.OpGet(key, clientv3.WithRev(revision)))
+		}
+		keys = keys[n:]
+		resp, err := c.client.Client.Txn(ctx).Then(ops...).Commit()
+		if err != nil {
+			if errors.Cause(err) == rpctypes.ErrCompacted {
+				return nil, errors.Trace(mvcc.ErrCompacted)
+			}
+			return nil, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+		}
+		for _, op := range resp.Responses {
+			for _, rawKv := range op.GetResponseRange().Kvs {
+				entry, err := decodeTaskEntry(rawKv)
+				if err != nil {
+					return nil, err
+				}
+				if entry != nil {
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Run keeps the cache up to date by watching the task keys from the revision of the
+// cache, the cache is reloaded if the revision is compacted.
+func (c *TaskCache) Run(ctx context.Context) error {
+	for {
+		err := c.loadAndWatch(ctx)
+		if errors.Cause(err) == context.Canceled {
+			return errors.Trace(err)
+		}
+		if errors.Cause(err) == mvcc.ErrCompacted {
+			log.Warn("task cache is compacted, reload it", zap.Int64("revision", c.Revision()))
+			err = c.Load(ctx)
+		}
+		if err != nil {
+			log.Warn("task cache watch failed, retry later", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return errors.Trace(ctx.Err())
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+// loadAndWatch loads the values of the unloaded changefeeds while watching the changes
+func (c *TaskCache) loadAndWatch(ctx context.Context) error {
+	errg, ctx := errgroup.WithContext(ctx)
+	errg.Go(func() error {
+		return c.loadChangefeeds(ctx)
+	})
+	errg.Go(func() error {
+		return c.watch(ctx)
+	})
+	return errg.Wait()
+}
+
+func (c *TaskCache) watch(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wch := c.client.Client.Watch(ctx, TaskKeyPrefix+"/", clientv3.WithPrefix(), clientv3.WithRev(c.Revision()+1))
+	for resp := range wch {
+		if resp.CompactRevision != 0 {
+			return errors.Trace(mvcc.ErrCompacted)
+		}
+		if resp.Err() != nil {
+			return errors.Trace(resp.Err())
+		}
+		if resp.Created {
+			continue
+		}
+		entries := make([]*taskEntry, 0, len(resp.Events))
+		for _, ev := range resp.Events {
+			var entry *taskEntry
+			var err error
+			if ev.Type == clientv3.EventTypeDelete {
+				entry, err = parseTaskKey(string(ev.Kv.Key))
+				if entry != nil {
+					entry.deleted = true
+				}
+			} else {
+				entry, err = decodeTaskEntry(ev.Kv)
+			}
+			if err != nil {
+				// skip the key written by others than CDC
+				log.Warn("skip unexpected task key", zap.ByteString("key", ev.Kv.Key), zap.Error(err))
+				continue
+			}
+			if entry != nil {
+				entries = append(entries, entry)
+			}
+		}
+		c.mu.Lock()
+		for _, entry := range entries {
+			if _, ok := c.unloaded[entry.changefeedID]; ok {
+				c.pending[entry.changefeedID] = append(c.pending[entry.changefeedID], entry)
+				continue
+			}
+			entry.applyTo(c.status, c.positions)
+		}
+		c.setRevisionLocked(resp.Header.Revision)
+		c.mu.Unlock()
+	}
+	if ctx.Err() != nil {
+		return errors.Trace(ctx.Err())
+	}
+	return cerror.ErrOwnerEtcdWatch.GenWithStackByArgs()
+}
+
+func (c *TaskCache) setRevisionLocked(revision int64) {
+	if revision <= c.revision {
+		return
+	}
+	c.revision = revision
+	close(c.advanced)
+	c.advanced = make(chan struct{})
+}
+
+// WaitRevision waits until the cache is up to date with the revision, which makes the
+// changes written before the revision visible
+func (c *TaskCache) WaitRevision(ctx context.Context, revision int64) error {
+	for {
+		c.mu.RLock()
+		current, advanced := c.revision, c.advanced
+		c.mu.RUnlock()
+		if current >= revision {
+			return nil
+		}
+		// the watch only advances its revision on changes of the task keys, ask etcd for
+		// a progress notification in case the task keys are not changed
+		if err := c.client.Client.Unwrap().RequestProgress(ctx); err != nil {
+			return cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-advanced:
+		case <-time.After(taskCacheProgressInterval):
+		}
+	}
+}
+
+// GetAllTaskStatus returns a copy of the task status of a changefeed, mapping from captureID to TaskStatus
+func (c *TaskCache) GetAllTaskStatus(changefeedID model.ChangeFeedID) model.ProcessorsInfos {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status := make(model.ProcessorsInfos, len(c.status[changefeedID]))
+	for captureID, info := range c.status[changefeedID] {
+		status[captureID] = info.Clone()
+	}
+	return status
+}
+
+// GetAllTaskPositions returns a copy of the task positions of a changefeed, mapping from captureID to TaskPosition
+func (c *TaskCache) GetAllTaskPositions(changefeedID model.ChangeFeedID) map[model.CaptureID]*model.TaskPosition {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	positions := make(map[model.CaptureID]*model.TaskPosition, len(c.positions[changefeedID]))
+	for captureID, pos := range c.positions[changefeedID] {
+		clone := *pos
+		positions[captureID] = &clone
+	}
+	return positions
+}
+
+// taskEntry is a decoded task status or task position key
+type taskEntry struct {
+	changefeedID model.ChangeFeedID
+	captureID    model.CaptureID
+	status       *model.TaskStatus
+	position     *model.TaskPosition
+	deleted      bool
+}
+
+// parseTaskKey parses the task status or task position key, nil is returned for other task keys
+func parseTaskKey(key string) (*taskEntry, error) {
+	var suffix string
+	entry := &taskEntry{}
+	switch {
+	case strings.HasPrefix(key, TaskStatusKeyPrefix+"/"):
+		suffix = key[len(TaskStatusKeyPrefix)+1:]
+		entry.status = &model.TaskStatus{}
+	case strings.HasPrefix(key, TaskPositionKeyPrefix+"/"):
+		suffix = key[len(TaskPositionKeyPrefix)+1:]
+		entry.position = &model.TaskPosition{}
+	default:
+		return nil, nil
+	}
+	parts := strings.SplitN(suffix, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, cerror.ErrInvalidTaskKey.GenWithStackByArgs(key)
+	}
+	entry.captureID, entry.changefeedID = parts[0], parts[1]
+	return entry, nil
+}
+
+func decodeTaskEntry(rawKv *mvccpb.KeyValue) (*taskEntry, error) {
+	entry, err := parseTaskKey(string(rawKv.Key))
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	if entry.status != nil {
+		if err := entry.status.Unmarshal(rawKv.Value); err != nil {
+			return nil, cerror.ErrDecodeFailed.GenWithStackByArgs("failed to unmarshal task status: %s", err)
+		}
+		entry.status.ModRevision = rawKv.ModRevision
+	} else {
+		if err := entry.position.Unmarshal(rawKv.Value); err != nil {
+			return nil, cerror.ErrDecodeFailed.GenWithStackByArgs("failed to unmarshal task position: %s", err)
+		}
+	}
+	return entry, nil
+}
+
+func (e *taskEntry) applyTo(
+	status map[model.ChangeFeedID]model.ProcessorsInfos,
+	positions map[model.ChangeFeedID]map[model.CaptureID]*model.TaskPosition,
+) {
+	if e.status != nil {
+		if e.deleted {
+			delete(status[e.changefeedID], e.captureID)
+			if len(status[e.changefeedID]) == 0 {
+				delete(status, e.changefeedID)
+			}
+			return
+		}
+		if _, ok := status[e.changefeedID]; !ok {
+			status[e.changefeedID] = make(model.ProcessorsInfos)
+		}
+		status[e.changefeedID][e.captureID] = e.status
+		return
+	}
+	if e.deleted {
+		delete(positions[e.changefeedID], e.captureID)
+		if len(positions[e.changefeedID]) == 0 {
+			delete(positions, e.changefeedID)
+		}
+		return
+	}
+	if _, ok := positions[e.changefeedID]; !ok {
+		positions[e.changefeedID] = make(map[model.CaptureID]*model.TaskPosition)
+	}
+	positions[e.changefeedID][e.captureID] = e.position
+}
@@ -0,0 +1,250 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"go.etcd.io/etcd/clientv3"
+)
+
+func (s *etcdSuite) putTaskKeys(c *check.C, changefeedNum, captureNum int) {
+	ops := make([]clientv3.Op, 0, 128)
+	flush := func() {
+		_, err := s.client.Client.Txn(s.ctx).Then(ops...).Commit()
+		c.Assert(err, check.IsNil)
+		ops = ops[:0]
+	}
+	for i := 0; i < changefeedNum; i++ {
+		changefeedID := fmt.Sprintf("changefeed-%d", i)
+		for j := 0; j < captureNum; j++ {
+			captureID := fmt.Sprintf("capture-%d", j)
+			status := &model.TaskStatus{Tables: map[model.TableID]*model.TableReplicaInfo{int64(j): {StartTs: uint64(i)}}}
+			statusValue, err := status.Marshal()
+			c.Assert(err, check.IsNil)
+			position := &model.TaskPosition{CheckPointTs: uint64(i), ResolvedTs: uint64(j)}
+			positionValue, err := position.Marshal()
+			c.Assert(err, check.IsNil)
+			ops = append(ops,
+				clientv3.OpPut(GetEtcdKeyTaskStatus(changefeedID, captureID), statusValue),
+				clientv3.OpPut(GetEtcdKeyTaskPosition(changefeedID, captureID), positionValue),
+				clientv3.OpPut(GetEtcdKeyTaskWorkload(changefeedID, captureID), "{}"))
+			if len(ops) >= 120 {
+				flush()
+			}
+		}
+	}
+	if len(ops) != 0 {
+		flush()
+	}
+}
+
+func (s *etcdSuite) checkTaskCache(c *check.C, cache *TaskCache, changefeedID string) {
+	status, err := s.client.GetAllTaskStatus(s.ctx, changefeedID)
+	c.Assert(err, check.IsNil)
+	// the cache returns clones, which never have nil maps
+	for captureID, info := range status {
+		status[captureID] = info.Clone()
+	}
+	c.Assert(cache.GetAllTaskStatus(changefeedID), check.DeepEquals, status)
+	positions, err := s.client.GetAllTaskPositions(s.ctx, changefeedID)
+	c.Assert(err, check.IsNil)
+	c.Assert(cache.GetAllTaskPositions(changefeedID), check.DeepEquals, positions)
+}
+
+func (s *etcdSuite) TestTaskCache(c *check.C) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	s.putTaskKeys(c, 3, 4)
+	cache := NewTaskCache(s.client)
+	cache.pageSize = 5
+	cache.workerNum = 2
+	err := cache.Load(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(cache.IsLoaded("changefeed-0"), check.IsFalse)
+	c.Assert(cache.loadChangefeeds(ctx), check.IsNil)
+	for i := 0; i < 3; i++ {
+		c.Assert(cache.IsLoaded(fmt.Sprintf("changefeed-%d", i)), check.IsTrue)
+		s.checkTaskCache(c, cache, fmt.Sprintf("changefeed-%d", i))
+	}
+	c.Assert(cache.GetAllTaskStatus("changefeed-0"), check.HasLen, 4)
+	c.Assert(cache.GetAllTaskStatus("not-exist"), check.HasLen, 0)
+
+	// the caller can't modify the cache
+	cache.GetAllTaskStatus("changefeed-0")["capture-0"].AdminJobType = model.AdminStop
+	cache.GetAllTaskPositions("changefeed-0")["capture-0"].CheckPointTs = 100
+	s.checkTaskCache(c, cache, "changefeed-0")
+
+	// the changes after the snapshot are watched
+	go func() {
+		_ = cache.Run(ctx)
+	}()
+	err = s.client.PutTaskStatus(ctx, "changefeed-0", "capture-new", &model.TaskStatus{AdminJobType: model.AdminStop})
+	c.Assert(err, check.IsNil)
+	err = s.client.DeleteTaskPosition(ctx, "changefeed-1", "capture-0")
+	c.Assert(err, check.IsNil)
+	err = s.client.DeleteTaskStatus(ctx, "changefeed-2", "capture-0")
+	c.Assert(err, check.IsNil)
+	resp, err := s.client.Client.Get(ctx, "/not-exist")
+	c.Assert(err, check.IsNil)
+	err = cache.WaitRevision(ctx, resp.Header.Revision)
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 3; i++ {
+		s.checkTaskCache(c, cache, fmt.Sprintf("changefeed-%d", i))
+	}
+	c.Assert(cache.GetAllTaskStatus("changefeed-0")["capture-new"].AdminJobType, check.Equals, model.AdminStop)
+	c.Assert(cache.GetAllTaskPositions("changefeed-1"), check.Not(check.HasKey), "capture-0")
+
+	// the revision advances without changes of the task keys
+	_, err = s.client.Client.Put(ctx, "/other", "value")
+	c.Assert(err, check.IsNil)
+	resp, err = s.client.Client.Get(ctx, "/other")
+	c.Assert(err, check.IsNil)
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	err = cache.WaitRevision(waitCtx, resp.Header.Revision)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *etcdSuite) TestTaskCacheReloadAfterCompaction(c *check.C) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	s.putTaskKeys(c, 2, 2)
+	cache := NewTaskCache(s.client)
+	err := cache.Load(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(cache.loadChangefeeds(ctx), check.IsNil)
+
+	_, err = s.client.PutTaskPositionOnChange(ctx, "changefeed-1", "capture-1", &model.TaskPosition{CheckPointTs: 1000})
+	c.Assert(err, check.IsNil)
+	resp, err := s.client.Client.Put(ctx, "/other", "value")
+	c.Assert(err, check.IsNil)
+	_, err = s.client.Client.Unwrap().Compact(ctx, resp.Header.Revision)
+	c.Assert(err, check.IsNil)
+
+	go func() {
+		_ = cache.Run(ctx)
+	}()
+	err = cache.WaitRevision(ctx, resp.Header.Revision)
+	c.Assert(err, check.IsNil)
+	// the changefeeds are loaded again after the reload
+	for i := 0; i < 100 && !cache.IsLoaded("changefeed-1"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(cache.IsLoaded("changefeed-1"), check.IsTrue)
+	c.Assert(cache.GetAllTaskPositions("changefeed-1")["capture-1"].CheckPointTs, check.Equals, uint64(1000))
+	s.checkTaskCache(c, cache, "changefeed-1")
+}
+
+func (s *etcdSuite) TestTaskCacheWatchBeforeLoaded(c *check.C) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	s.putTaskKeys(c, 2, 2)
+	cache := NewTaskCache(s.client)
+	err := cache.Load(ctx)
+	c.Assert(err, check.IsNil)
+
+	// the changes watched before the changefeed is loaded are applied on top of the snapshot
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	watchDone := make(chan error, 1)
+	go func() {
+		watchDone <- cache.watch(watchCtx)
+	}()
+	_, err = s.client.PutTaskPositionOnChange(ctx, "changefeed-1", "capture-1", &model.TaskPosition{CheckPointTs: 1000})
+	c.Assert(err, check.IsNil)
+	err = s.client.DeleteTaskStatus(ctx, "changefeed-1", "capture-0")
+	c.Assert(err, check.IsNil)
+	err = s.client.PutTaskStatus(ctx, "changefeed-new", "capture-0", &model.TaskStatus{})
+	c.Assert(err, check.IsNil)
+	resp, err := s.client.Client.Get(ctx, "/not-exist")
+	c.Assert(err, check.IsNil)
+	c.Assert(cache.WaitRevision(ctx, resp.Header.Revision), check.IsNil)
+	watchCancel()
+	<-watchDone
+
+	// the changefeed created after the snapshot is read from the watch
+	c.Assert(cache.IsLoaded("changefeed-new"), check.IsTrue)
+	s.checkTaskCache(c, cache, "changefeed-new")
+	c.Assert(cache.IsLoaded("changefeed-1"), check.IsFalse)
+	c.Assert(cache.GetAllTaskStatus("changefeed-1"), check.HasLen, 0)
+	c.Assert(cache.loadChangefeeds(ctx), check.IsNil)
+	c.Assert(cache.IsLoaded("changefeed-1"), check.IsTrue)
+	c.Assert(cache.GetAllTaskPositions("changefeed-1")["capture-1"].CheckPointTs, check.Equals, uint64(1000))
+	c.Assert(cache.GetAllTaskStatus("changefeed-1"), check.Not(check.HasKey), "capture-0")
+	for _, changefeedID := range []string{"changefeed-0", "changefeed-1"} {
+		s.checkTaskCache(c, cache, changefeedID)
+	}
+}
+
+// TestTaskCacheBootstrap measures the time for a new owner to start the first changefeed and all
+// changefeeds on a large deployment, with the task cache and with the task keys read from etcd
+func (s *etcdSuite) TestTaskCacheBootstrap(c *check.C) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	const changefeedNum, captureNum = 300, 20
+	s.putTaskKeys(c, changefeedNum, captureNum)
+
+	start := time.Now()
+	cache := NewTaskCache(s.client)
+	err := cache.Load(ctx)
+	c.Assert(err, check.IsNil)
+	go func() {
+		_ = cache.Run(ctx)
+	}()
+	var firstLoaded time.Duration
+	loaded := make(map[string]struct{}, changefeedNum)
+	for len(loaded) < changefeedNum {
+		for i := 0; i < changefeedNum; i++ {
+			changefeedID := fmt.Sprintf("changefeed-%d", i)
+			if _, ok := loaded[changefeedID]; ok || !cache.IsLoaded(changefeedID) {
+				continue
+			}
+			if len(loaded) == 0 {
+				firstLoaded = time.Since(start)
+			}
+			loaded[changefeedID] = struct{}{}
+			c.Assert(cache.GetAllTaskStatus(changefeedID), check.HasLen, captureNum)
+			c.Assert(cache.GetAllTaskPositions(changefeedID), check.HasLen, captureNum)
+		}
+		c.Assert(time.Since(start) < 10*time.Second, check.IsTrue)
+		time.Sleep(time.Millisecond)
+	}
+	allLoaded := time.Since(start)
+
+	// the owner reading etcd directly lists all task keys for every changefeed
+	start = time.Now()
+	var directFirst time.Duration
+	for i := 0; i < changefeedNum; i++ {
+		changefeedID := fmt.Sprintf("changefeed-%d", i)
+		status, err := s.client.GetAllTaskStatus(ctx, changefeedID)
+		c.Assert(err, check.IsNil)
+		c.Assert(status, check.HasLen, captureNum)
+		positions, err := s.client.GetAllTaskPositions(ctx, changefeedID)
+		c.Assert(err, check.IsNil)
+		c.Assert(positions, check.HasLen, captureNum)
+		if i == 0 {
+			directFirst = time.Since(start)
+		}
+	}
+	direct := time.Since(start)
+	c.Logf("task keys: %d, with task cache: first changefeed %s, all changefeeds %s; "+
+		"from etcd: first changefeed %s, all changefeeds %s",
+		changefeedNum*captureNum*3, firstLoaded, allLoaded, directFirst, direct)
+	c.Assert(allLoaded < 5*time.Second, check.IsTrue)
+	c.Assert(allLoaded < direct, check.IsTrue)
+}
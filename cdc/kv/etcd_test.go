@@ -449,3 +449,43 @@ func (s *etcdSuite) TestGetAllCaptureLeases(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Check(queryLeases, check.DeepEquals, map[string]int64{})
 }
+
+func (s *etcdSuite) TestQuarantinedTables(c *check.C) {
+	ctx := context.Background()
+	feedID := "feedid"
+	tables, err := s.client.GetQuarantinedTables(ctx, feedID)
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.HasLen, 0)
+
+	for _, tableID := range []model.TableID{1, 11} {
+		err = s.client.PutQuarantinedTable(ctx, feedID, &model.QuarantinedTable{
+			TableID: tableID, Schema: "test", Table: "t", CommitTs: 100, Error: "duplicate entry",
+		})
+		c.Assert(err, check.IsNil)
+	}
+	// the prefix of table 1 must not match table 11
+	err = s.client.PutQuarantinedTable(ctx, feedID+"1", &model.QuarantinedTable{TableID: 2})
+	c.Assert(err, check.IsNil)
+	tables, err = s.client.GetQuarantinedTables(ctx, feedID)
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.HasLen, 2)
+	c.Assert(tables[11].CommitTs, check.Equals, uint64(100))
+	c.Assert(tables[11].Error, check.Equals, "duplicate entry")
+
+	err = s.client.DeleteQuarantinedTable(ctx, feedID, 1)
+	c.Assert(err, check.IsNil)
+	err = s.client.DeleteQuarantinedTable(ctx, feedID, 1)
+	c.Assert(cerror.ErrTableNotQuarantined.Equal(err), check.IsTrue)
+	tables, err = s.client.GetQuarantinedTables(ctx, feedID)
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.HasLen, 1)
+
+	err = s.client.RemoveQuarantinedTables(ctx, feedID)
+	c.Assert(err, check.IsNil)
+	tables, err = s.client.GetQuarantinedTables(ctx, feedID)
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.HasLen, 0)
+	tables, err = s.client.GetQuarantinedTables(ctx, feedID+"1")
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.HasLen, 1)
+}
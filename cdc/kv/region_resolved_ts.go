@@ -0,0 +1,132 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/ticdc/pkg/util"
+)
+
+// RegionResolvedTs is the resolved ts of a region subscribed by the capture
+type RegionResolvedTs struct {
+	RegionID  uint64 `json:"region-id"`
+	StoreID   uint64 `json:"store-id"`
+	StoreAddr string `json:"store-addr"`
+	TableID   int64  `json:"table-id"`
+	TableName string `json:"table-name"`
+	// ResolvedTs is the start ts of the request before the region is initialized
+	ResolvedTs  uint64 `json:"resolved-ts"`
+	Initialized bool   `json:"initialized"`
+}
+
+// trackedRegion is a region tracked from the request is sent until the region feed quits,
+// a nil trackedRegion tracks nothing
+type trackedRegion struct {
+	changefeedID string
+	info         RegionResolvedTs
+	resolvedTs   uint64
+	initialized  int32
+}
+
+// trackedRegions is the tracked regions of every changefeed on the capture
+var trackedRegions = struct {
+	sync.Mutex
+	feeds map[string]map[*trackedRegion]struct{}
+}{feeds: make(map[string]map[*trackedRegion]struct{})}
+
+func trackRegion(ctx context.Context, sri singleRegionInfo) *trackedRegion {
+	tableID, tableName := util.TableIDFromCtx(ctx)
+	info := RegionResolvedTs{
+		RegionID:   sri.verID.GetID(),
+		TableID:    tableID,
+		TableName:  tableName,
+		StoreID:    getStoreID(sri.rpcCtx),
+		ResolvedTs: sri.ts,
+	}
+	if sri.rpcCtx != nil {
+		info.StoreAddr = sri.rpcCtx.Addr
+	}
+	return addTrackedRegion(util.ChangefeedIDFromCtx(ctx), info)
+}
+
+func addTrackedRegion(changefeedID string, info RegionResolvedTs) *trackedRegion {
+	r := &trackedRegion{changefeedID: changefeedID, info: info, resolvedTs: info.ResolvedTs}
+	if info.Initialized {
+		r.initialized = 1
+	}
+	trackedRegions.Lock()
+	defer trackedRegions.Unlock()
+	regions, ok := trackedRegions.feeds[changefeedID]
+	if !ok {
+		regions = make(map[*trackedRegion]struct{})
+		trackedRegions.feeds[changefeedID] = regions
+	}
+	regions[r] = struct{}{}
+	return r
+}
+
+func (r *trackedRegion) untrack() {
+	if r == nil {
+		return
+	}
+	trackedRegions.Lock()
+	defer trackedRegions.Unlock()
+	regions := trackedRegions.feeds[r.changefeedID]
+	delete(regions, r)
+	if len(regions) == 0 {
+		delete(trackedRegions.feeds, r.changefeedID)
+	}
+}
+
+func (r *trackedRegion) setResolvedTs(ts uint64) {
+	if r != nil {
+		atomic.StoreUint64(&r.resolvedTs, ts)
+	}
+}
+
+func (r *trackedRegion) setInitialized() {
+	if r != nil {
+		atomic.StoreInt32(&r.initialized, 1)
+	}
+}
+
+// LaggingRegions returns at most limit regions of the changefeed subscribed by the capture
+// in the ascending order of their resolved ts, and the total number of the subscribed regions
+func LaggingRegions(changefeedID string, limit int) ([]RegionResolvedTs, int) {
+	trackedRegions.Lock()
+	regions := make([]RegionResolvedTs, 0, len(trackedRegions.feeds[changefeedID]))
+	for r := range trackedRegions.feeds[changefeedID] {
+		info := r.info
+		info.ResolvedTs = atomic.LoadUint64(&r.resolvedTs)
+		info.Initialized = atomic.LoadInt32(&r.initialized) == 1
+		regions = append(regions, info)
+	}
+	trackedRegions.Unlock()
+
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].ResolvedTs != regions[j].ResolvedTs {
+			return regions[i].ResolvedTs < regions[j].ResolvedTs
+		}
+		return regions[i].RegionID < regions[j].RegionID
+	})
+	total := len(regions)
+	if limit > 0 && total > limit {
+		regions = regions[:limit]
+	}
+	return regions, total
+}
@@ -142,6 +142,12 @@ func (*mockPullerInit) IsInitialized() bool {
 	return true
 }
 
+// MockTrackedRegion tracks a region of the changefeed as if it's subscribed,
+// the returned function untracks the region.
+func MockTrackedRegion(changefeedID string, region RegionResolvedTs) (untrack func()) {
+	return addTrackedRegion(changefeedID, region).untrack
+}
+
 // TestSplit try split on every region, and test can get value event from
 // every region after split.
 func TestSplit(t require.TestingT, pdCli pd.Client, storage kv.Storage) {
@@ -0,0 +1,89 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// lateEventGuard detects the events of a table whose commit ts is behind the checkpoint of
+// the table when they come out of the sorter, which are dropped or fail the changefeed.
+//
+// The checkpoint of the table is the start ts of the table, and then the resolved ts emitted
+// by the sorter. The checkpoint of the processor is not used, since a table handed off from
+// another capture replays the events after its start ts, which may be behind the checkpoint
+// the processor has reached with other tables.
+type lateEventGuard struct {
+	changefeedID string
+	tableID      model.TableID
+	startTs      uint64
+	resolvedTs   uint64
+	strict       bool
+	counter      prometheus.Counter
+}
+
+func newLateEventGuard(changefeedID, captureAddr string, tableID model.TableID, startTs uint64, strict bool) *lateEventGuard {
+	return &lateEventGuard{
+		changefeedID: changefeedID,
+		tableID:      tableID,
+		startTs:      startTs,
+		strict:       strict,
+		counter:      lateEventCounter.WithLabelValues(changefeedID, captureAddr),
+	}
+}
+
+// advance moves the checkpoint of the table forward to the resolved ts emitted by the sorter
+func (g *lateEventGuard) advance(resolvedTs uint64) {
+	if resolvedTs > g.resolvedTs {
+		g.resolvedTs = resolvedTs
+	}
+}
+
+// check returns whether the event should be sent to the sink. A late event is dropped,
+// or an error is returned if the strict consistency is enabled.
+func (g *lateEventGuard) check(pEvent *model.PolymorphicEvent) (bool, error) {
+	if pEvent.CRTs >= g.startTs && pEvent.CRTs > g.resolvedTs {
+		return true, nil
+	}
+	g.counter.Inc()
+	checkpointTs := g.startTs
+	if g.resolvedTs > checkpointTs {
+		checkpointTs = g.resolvedTs
+	}
+	var key []byte
+	var regionID uint64
+	if pEvent.RawKV != nil {
+		key, regionID = pEvent.RawKV.Key, pEvent.RawKV.RegionID
+	}
+	fields := []zap.Field{
+		zap.String("changefeed", g.changefeedID),
+		zap.Int64("tableID", g.tableID),
+		zap.ByteString("key", key),
+		zap.Uint64("startTs", pEvent.StartTs),
+		zap.Uint64("commitTs", pEvent.CRTs),
+		zap.Uint64("regionID", regionID),
+		zap.Uint64("tableStartTs", g.startTs),
+		zap.Uint64("tableResolvedTs", g.resolvedTs),
+	}
+	if g.strict {
+		log.Error("event arrives after the table checkpoint, stop the changefeed", fields...)
+		return false, cerror.ErrProcessorLateEvent.GenWithStackByArgs(g.tableID, pEvent.CRTs, checkpointTs, regionID)
+	}
+	log.Warn("event arrives after the table checkpoint, drop it", fields...)
+	return false, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/puller"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/notify"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// passThroughSorter outputs the events in the order they are added
+type passThroughSorter struct {
+	outputCh chan *model.PolymorphicEvent
+}
+
+func (s *passThroughSorter) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *passThroughSorter) AddEntry(ctx context.Context, entry *model.PolymorphicEvent) {
+	s.outputCh <- entry
+}
+
+func (s *passThroughSorter) Output() <-chan *model.PolymorphicEvent {
+	return s.outputCh
+}
+
+type lateEventGuardSuite struct{}
+
+var _ = check.Suite(&lateEventGuardSuite{})
+
+func newLateEventTestProcessor(changefeedID string, strict bool) (*processor, chan error) {
+	replicaConfig := config.GetDefaultReplicaConfig()
+	replicaConfig.StrictConsistency = strict
+	errCh := make(chan error, 1)
+	p := &processor{
+		changefeedID:          changefeedID,
+		changefeed:            model.ChangeFeedInfo{Config: replicaConfig},
+		localResolvedNotifier: new(notify.Notifier),
+		output:                make(chan *model.PolymorphicEvent, 16),
+		errCh:                 errCh,
+		opDoneCh:              make(chan int64, 1),
+	}
+	return p, errCh
+}
+
+// consumeEvents runs sorterConsume on the events of a table started at ts 100 and returns
+// the events sent to the output of the processor
+func consumeEvents(p *processor, events []*model.PolymorphicEvent) ([]uint64, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sorter := puller.NewRectifier(&passThroughSorter{outputCh: make(chan *model.PolymorphicEvent, 16)}, math.MaxUint64)
+	go func() {
+		_ = sorter.Run(ctx)
+	}()
+	done := make(chan struct{})
+	go func() {
+		p.sorterConsume(ctx, 1, "test.t", sorter, new(uint64), &model.TableReplicaInfo{StartTs: 100})
+		close(done)
+	}()
+	for _, event := range events {
+		sorter.AddEntry(ctx, event)
+	}
+	// the resolved event flushes the events before it through sorterConsume
+	sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, 1000))
+	var emitted []uint64
+loop:
+	for {
+		select {
+		case pEvent := <-p.output:
+			emitted = append(emitted, pEvent.CRTs)
+		case <-done:
+			break loop
+		case <-time.After(100 * time.Millisecond):
+			break loop
+		}
+	}
+	return emitted, func() {
+		cancel()
+		<-done
+	}
+}
+
+func newLateTestEvent(commitTs uint64) *model.PolymorphicEvent {
+	return model.NewPolymorphicEvent(&model.RawKVEntry{
+		OpType:   model.OpTypePut,
+		Key:      []byte("t_key"),
+		StartTs:  commitTs - 1,
+		CRTs:     commitTs,
+		RegionID: 7,
+	})
+}
+
+func (s *lateEventGuardSuite) TestDropLateEvents(c *check.C) {
+	p, errCh := newLateEventTestProcessor("test-late-drop", false)
+	// the processor has moved past the start ts of the table with other tables,
+	// the events replayed after the start ts of the table are not late
+	p.checkpointTs = 200
+	p.sinkEmittedResolvedTs = 200
+
+	emitted, stop := consumeEvents(p, []*model.PolymorphicEvent{
+		newLateTestEvent(105),
+		newLateTestEvent(99),
+		model.NewResolvedPolymorphicEvent(0, 110),
+		newLateTestEvent(110),
+		newLateTestEvent(108),
+		newLateTestEvent(120),
+	})
+	defer stop()
+	c.Assert(emitted, check.DeepEquals, []uint64{105, 120})
+	c.Assert(testutil.ToFloat64(lateEventCounter.WithLabelValues("test-late-drop", "")), check.Equals, float64(3))
+	select {
+	case err := <-errCh:
+		c.Fatalf("unexpected error %v", err)
+	default:
+	}
+}
+
+func (s *lateEventGuardSuite) TestStrictConsistency(c *check.C) {
+	p, errCh := newLateEventTestProcessor("test-late-strict", true)
+	emitted, stop := consumeEvents(p, []*model.PolymorphicEvent{
+		newLateTestEvent(105),
+		model.NewResolvedPolymorphicEvent(0, 110),
+		newLateTestEvent(108),
+		newLateTestEvent(120),
+	})
+	defer stop()
+	c.Assert(emitted, check.DeepEquals, []uint64{105})
+	err := <-errCh
+	c.Assert(cerror.ErrProcessorLateEvent.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*event of table 1 with commit ts 108 arrives after the table checkpoint 110, region: 7.*")
+	c.Assert(testutil.ToFloat64(lateEventCounter.WithLabelValues("test-late-strict", "")), check.Equals, float64(1))
+}
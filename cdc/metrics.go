@@ -20,6 +20,7 @@ import (
 	"github.com/pingcap/ticdc/cdc/kv"
 	"github.com/pingcap/ticdc/cdc/puller"
 	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/cdc/sink/producer/kafka"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -36,6 +37,8 @@ func init() {
 	kv.InitMetrics(registry)
 	puller.InitMetrics(registry)
 	sink.InitMetrics(registry)
+	kafka.InitMetrics(registry)
 	entry.InitMetrics(registry)
 	initProcessorMetrics(registry)
+	initOwnerMetrics(registry)
 }
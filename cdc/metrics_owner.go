@@ -0,0 +1,98 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	reconciledKeysCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "reconciled_keys_count",
+			Help:      "The number of inconsistent etcd keys repaired or quarantined by the owner",
+		}, []string{"class", "action"})
+	ddlExecutedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "ddl_executed_count",
+			Help:      "The number of DDL executed by the sink of a changefeed",
+		}, []string{"changefeed"})
+	ddlExecutingDurationGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "ddl_executing_duration_seconds",
+			Help:      "The time the DDL being executed by the sink has taken, it is 0 if no DDL is executing",
+		}, []string{"changefeed"})
+	ddlExecutionDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "ddl_execution_duration_seconds",
+			Help:      "Bucketed histogram of the time (s) taken by the sink to execute a DDL",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 20),
+		}, []string{"changefeed"})
+	integrityCheckMismatchGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "integrity_check_mismatched_tables",
+			Help:      "The number of tables whose data differs between upstream and downstream in the last integrity check",
+		}, []string{"changefeed"})
+	tableMovesInFlightGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "table_moves_in_flight",
+			Help:      "The number of table moves removed from the source capture but not added to the target capture yet",
+		}, []string{"changefeed"})
+	sharedTopicChangefeedsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "shared_topic_changefeeds",
+			Help:      "The number of other changefeeds writing the same tables to the same MQ topic as the changefeed",
+		}, []string{"changefeed"})
+	pausedByDownstreamGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "paused_by_downstream",
+			Help:      "Whether the changefeed is paused since its downstream refuses the writes, e.g. read-only or disk full",
+		}, []string{"changefeed"})
+	startTsDDLWaitGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "owner",
+			Name:      "start_ts_ddl_wait_duration",
+			Help:      "The seconds the changefeed has waited for the DDL jobs running at its start ts to finish before bootstrapped",
+		}, []string{"changefeed"})
+)
+
+// initOwnerMetrics registers all metrics used in owner
+func initOwnerMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(reconciledKeysCounter)
+	registry.MustRegister(ddlExecutedCounter)
+	registry.MustRegister(ddlExecutingDurationGauge)
+	registry.MustRegister(ddlExecutionDurationHistogram)
+	registry.MustRegister(integrityCheckMismatchGauge)
+	registry.MustRegister(tableMovesInFlightGauge)
+	registry.MustRegister(sharedTopicChangefeedsGauge)
+	registry.MustRegister(pausedByDownstreamGauge)
+	registry.MustRegister(startTsDDLWaitGauge)
+}
@@ -14,6 +14,7 @@
 package cdc
 
 import (
+	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -97,6 +98,27 @@ var (
 			Name:      "exit_with_error_count",
 			Help:      "counter for processor exits with error",
 		}, []string{"changefeed", "capture"})
+	rowChangedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "processor",
+			Name:      "row_changed_count",
+			Help:      "counter for row changed events sent to the sink by operation, updates are counted as inserts if the old value is disabled",
+		}, []string{"changefeed", "capture", "operation"})
+	lateEventCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "processor",
+			Name:      "late_event_count",
+			Help:      "counter for events arriving with a commit ts behind the table checkpoint",
+		}, []string{"changefeed", "capture"})
+	gcSpanningTxnCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "processor",
+			Name:      "gc_spanning_txn_count",
+			Help:      "counter for transactions starting before the GC safepoint",
+		}, []string{"changefeed", "capture"})
 	sinkFlushRowChangedDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: "ticdc",
@@ -105,6 +127,13 @@ var (
 			Help:      "Bucketed histogram of processing time (s) of flushing events in processor",
 			Buckets:   prometheus.ExponentialBuckets(0.002 /* 2ms */, 2, 20),
 		}, []string{"changefeed", "capture"})
+	inflightTxnGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "processor",
+			Name:      "inflight_txn_count",
+			Help:      "number of transactions buffered between the sorter output and the sink flush",
+		}, []string{"changefeed", "capture"})
 )
 
 // initProcessorMetrics registers all metrics used in processor
@@ -121,4 +150,47 @@ func initProcessorMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(waitEventPrepareDuration)
 	registry.MustRegister(processorErrorCounter)
 	registry.MustRegister(sinkFlushRowChangedDuration)
+	registry.MustRegister(rowChangedCounter)
+	registry.MustRegister(lateEventCounter)
+	registry.MustRegister(gcSpanningTxnCounter)
+	registry.MustRegister(inflightTxnGauge)
+}
+
+// The operation labels of the row changed counter
+const (
+	rowOperationInsert = "insert"
+	rowOperationUpdate = "update"
+	rowOperationDelete = "delete"
+)
+
+// rowOperationCounter counts the row changed events of a changefeed by operation
+type rowOperationCounter struct {
+	insert prometheus.Counter
+	update prometheus.Counter
+	delete prometheus.Counter
+}
+
+func newRowOperationCounter(changefeedID, captureAddr string) *rowOperationCounter {
+	return &rowOperationCounter{
+		insert: rowChangedCounter.WithLabelValues(changefeedID, captureAddr, rowOperationInsert),
+		update: rowChangedCounter.WithLabelValues(changefeedID, captureAddr, rowOperationUpdate),
+		delete: rowChangedCounter.WithLabelValues(changefeedID, captureAddr, rowOperationDelete),
+	}
+}
+
+func (c *rowOperationCounter) record(rows []*model.RowChangedEvent) {
+	var inserts, updates, deletes int
+	for _, row := range rows {
+		switch {
+		case row.IsDelete():
+			deletes++
+		case len(row.PreColumns) != 0:
+			updates++
+		default:
+			inserts++
+		}
+	}
+	c.insert.Add(float64(inserts))
+	c.update.Add(float64(updates))
+	c.delete.Add(float64(deletes))
 }
@@ -0,0 +1,43 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type processorMetricsSuite struct{}
+
+var _ = check.Suite(&processorMetricsSuite{})
+
+func (s *processorMetricsSuite) TestRowOperationCounter(c *check.C) {
+	col := &model.Column{Name: "id", Value: 1}
+	insert := &model.RowChangedEvent{Columns: []*model.Column{col}}
+	update := &model.RowChangedEvent{Columns: []*model.Column{col}, PreColumns: []*model.Column{col}}
+	del := &model.RowChangedEvent{PreColumns: []*model.Column{col}}
+
+	counter := newRowOperationCounter("test-metrics", "127.0.0.1:8300")
+	counter.record([]*model.RowChangedEvent{insert, update, insert, del, insert})
+	counter.record([]*model.RowChangedEvent{update, del})
+	counter.record(nil)
+
+	get := func(operation string) float64 {
+		return testutil.ToFloat64(rowChangedCounter.WithLabelValues("test-metrics", "127.0.0.1:8300", operation))
+	}
+	c.Assert(get(rowOperationInsert), check.Equals, float64(3))
+	c.Assert(get(rowOperationUpdate), check.Equals, float64(2))
+	c.Assert(get(rowOperationDelete), check.Equals, float64(2))
+}
@@ -48,6 +48,9 @@ const (
 	StateStopped  FeedState = "stopped"
 	StateRemoved  FeedState = "removed"
 	StateFinished FeedState = "finished"
+	// StatePausedByDownstream is the state of a changefeed paused since its downstream refuses the writes
+	// for a while, e.g. read-only or disk full, the changefeed is resumed once the downstream is writable
+	StatePausedByDownstream FeedState = "paused-by-downstream"
 )
 
 const (
@@ -84,6 +87,17 @@ type ChangeFeedInfo struct {
 
 	SyncPointEnabled  bool          `json:"sync-point-enabled"`
 	SyncPointInterval time.Duration `json:"sync-point-interval"`
+
+	// CheckpointInterval is the min interval the processors flush the sink and the checkpoint
+	// in, a larger interval reduces the downstream and etcd writes at the cost of latency.
+	CheckpointInterval time.Duration `json:"checkpoint-interval"`
+	// MaxLagBeforePause is the max checkpoint lag of the changefeed, the owner pauses the
+	// changefeed once the lag exceeds it for a sustained period, the paused changefeed doesn't
+	// hold the GC safepoint of upstream any more. Zero means never pause.
+	MaxLagBeforePause time.Duration `json:"max-lag-before-pause"`
+	// ResumePending is set when the changefeed is resumed and cleared once the owner loads it,
+	// the downstream schemas are reconciled when it's loaded, see SinkConfig.ReconcileSchema
+	ResumePending bool `json:"resume-pending,omitempty"`
 }
 
 var changeFeedIDRe *regexp.Regexp = regexp.MustCompile(`^[a-zA-Z0-9]+(\-[a-zA-Z0-9]+)*$`)
@@ -97,6 +111,16 @@ func ValidateChangefeedID(changefeedID string) error {
 	return nil
 }
 
+// WithRedactedConfig returns a shallow copy of the info whose config is without the
+// password of the upstream dsn, it's used when the info is printed or exported.
+func (info *ChangeFeedInfo) WithRedactedConfig() *ChangeFeedInfo {
+	clone := *info
+	if info.Config != nil {
+		clone.Config = info.Config.Redacted()
+	}
+	return &clone
+}
+
 // String implements fmt.Stringer interface, but hide some sensitive information
 func (info *ChangeFeedInfo) String() (str string) {
 	var err error
@@ -112,6 +136,7 @@ func (info *ChangeFeedInfo) String() (str string) {
 		return
 	}
 	clone.SinkURI = "***"
+	clone = clone.WithRedactedConfig()
 	str, err = clone.Marshal()
 	if err != nil {
 		log.Error("failed to marshal changefeed info", zap.Error(err))
@@ -177,6 +202,18 @@ func (info *ChangeFeedInfo) VerifyAndFix() error {
 	if info.Engine == "" {
 		info.Engine = SortInMemory
 	}
+	if info.Config.PriorityClass == "" {
+		info.Config.PriorityClass = defaultConfig.PriorityClass
+	}
+	if info.Config.DDLOrder == "" {
+		info.Config.DDLOrder = defaultConfig.DDLOrder
+	}
+	if info.Config.StartTsInDDL == "" {
+		info.Config.StartTsInDDL = defaultConfig.StartTsInDDL
+	}
+	if info.Config.FinishVerification == "" {
+		info.Config.FinishVerification = defaultConfig.FinishVerification
+	}
 	if info.Config.Filter == nil {
 		info.Config.Filter = defaultConfig.Filter
 	}
@@ -186,12 +223,21 @@ func (info *ChangeFeedInfo) VerifyAndFix() error {
 	if info.Config.Sink == nil {
 		info.Config.Sink = defaultConfig.Sink
 	}
+	if info.Config.Sorter == nil {
+		info.Config.Sorter = defaultConfig.Sorter
+	}
 	if info.Config.Cyclic == nil {
 		info.Config.Cyclic = defaultConfig.Cyclic
 	}
 	if info.Config.Scheduler == nil {
 		info.Config.Scheduler = defaultConfig.Scheduler
 	}
+	if info.Config.IntegrityCheck == nil {
+		info.Config.IntegrityCheck = defaultConfig.IntegrityCheck
+	}
+	if info.Config.Debug == nil {
+		info.Config.Debug = defaultConfig.Debug
+	}
 	return nil
 }
 
@@ -0,0 +1,166 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// ChangefeedsExportVersion is the version of the changefeeds export document
+const ChangefeedsExportVersion = 1
+
+// RedactedCredential replaces the credentials of sink URIs in the export document
+const RedactedCredential = "redacted"
+
+// sensitiveSinkQueryKeys are the sink URI options holding credentials
+var sensitiveSinkQueryKeys = map[string]struct{}{
+	"access-key":        {},
+	"secret-access-key": {},
+	"sasl-password":     {},
+}
+
+// ChangefeedsExport is a portable document of changefeed definitions,
+// which is used to back up changefeeds or migrate them to another cluster.
+type ChangefeedsExport struct {
+	Version int `json:"version"`
+	// ClusterID is the ID of the upstream PD cluster
+	ClusterID   uint64                `json:"cluster-id"`
+	ExportTime  time.Time             `json:"export-time"`
+	Changefeeds []*ExportedChangefeed `json:"changefeeds"`
+}
+
+// ExportedChangefeed is the definition of a changefeed in the export document
+type ExportedChangefeed struct {
+	ID           ChangeFeedID    `json:"id"`
+	Info         *ChangeFeedInfo `json:"info"`
+	CheckpointTs uint64          `json:"checkpoint-ts"`
+}
+
+// Marshal returns the json encoded export document
+func (e *ChangefeedsExport) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(e, "", "  ")
+	return data, cerror.WrapError(cerror.ErrMarshalFailed, err)
+}
+
+// Unmarshal unmarshals the export document from json and checks its version
+func (e *ChangefeedsExport) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, e)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrUnmarshalFailed, err)
+	}
+	if e.Version != ChangefeedsExportVersion {
+		return cerror.ErrChangefeedImportInvalid.GenWithStackByArgs("", "unsupported document version")
+	}
+	return nil
+}
+
+// NewExportedChangefeed returns the portable definition of a changefeed,
+// the credentials in the sink URI and the upstream dsn are redacted and the runtime states are dropped.
+func NewExportedChangefeed(id ChangeFeedID, info *ChangeFeedInfo, status *ChangeFeedStatus) (*ExportedChangefeed, error) {
+	str, err := info.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	clone := new(ChangeFeedInfo)
+	err = clone.Unmarshal([]byte(str))
+	if err != nil {
+		return nil, err
+	}
+	clone.SinkURI, err = RedactSinkURI(info.SinkURI)
+	if err != nil {
+		return nil, err
+	}
+	clone = clone.WithRedactedConfig()
+	clone.AdminJobType = AdminNone
+	clone.State = StateNormal
+	clone.ErrorHis = nil
+	clone.Error = nil
+	return &ExportedChangefeed{
+		ID:           id,
+		Info:         clone,
+		CheckpointTs: info.GetCheckpointTs(status),
+	}, nil
+}
+
+// RedactSinkURI replaces the password and the credential options of a sink URI with RedactedCredential
+func RedactSinkURI(sinkURIStr string) (string, error) {
+	sinkURI, err := url.Parse(sinkURIStr)
+	if err != nil {
+		return "", cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	redacted := false
+	if sinkURI.User != nil {
+		scheme := strings.ToLower(sinkURI.Scheme)
+		if scheme == "pulsar" || scheme == "pulsar+ssl" {
+			// the user name of a pulsar sink is the token
+			sinkURI.User = url.User(RedactedCredential)
+			redacted = true
+		} else if _, ok := sinkURI.User.Password(); ok {
+			sinkURI.User = url.UserPassword(sinkURI.User.Username(), RedactedCredential)
+			redacted = true
+		}
+	}
+	query := sinkURI.Query()
+	for key := range query {
+		if isSensitiveSinkQueryKey(key) {
+			query.Set(key, RedactedCredential)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return sinkURIStr, nil
+	}
+	sinkURI.RawQuery = query.Encode()
+	return sinkURI.String(), nil
+}
+
+// IsSinkURIRedacted returns true if any credential of the sink URI is redacted
+func IsSinkURIRedacted(sinkURIStr string) (bool, error) {
+	sinkURI, err := url.Parse(sinkURIStr)
+	if err != nil {
+		return false, cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	if sinkURI.User != nil {
+		if sinkURI.User.Username() == RedactedCredential {
+			return true, nil
+		}
+		if password, ok := sinkURI.User.Password(); ok && password == RedactedCredential {
+			return true, nil
+		}
+	}
+	for key, values := range sinkURI.Query() {
+		if !isSensitiveSinkQueryKey(key) {
+			continue
+		}
+		for _, value := range values {
+			if value == RedactedCredential {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func isSensitiveSinkQueryKey(key string) bool {
+	if _, ok := sensitiveSinkQueryKeys[key]; ok {
+		return true
+	}
+	// the authentication parameters of pulsar
+	return strings.HasPrefix(key, "auth.")
+}
@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/pingcap/errors"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
@@ -83,6 +84,9 @@ type TaskPosition struct {
 	Count uint64 `json:"count"`
 	// Error code when error happens
 	Error *RunningError `json:"error"`
+	// Warning is the problem of the sink which doesn't stop the changefeed, such as a failed
+	// delivery probe of the Kafka sink
+	Warning *RunningError `json:"warning,omitempty"`
 }
 
 // Marshal returns the json marshal format of a TaskStatus
@@ -121,6 +125,10 @@ type MoveTableJob struct {
 	TableID          TableID
 	TableReplicaInfo *TableReplicaInfo
 	Status           MoveTableStatus
+	// RescanTs is non-zero if the table is re-added to the same capture to re-scan from the ts
+	RescanTs Ts
+	// Rebalance is true if the job is created by the rebalance, only these moves are rate limited
+	Rebalance bool
 }
 
 // All TableOperation status
@@ -395,6 +403,90 @@ type ChangeFeedStatus struct {
 	ResolvedTs   uint64       `json:"resolved-ts"`
 	CheckpointTs uint64       `json:"checkpoint-ts"`
 	AdminJobType AdminJobType `json:"admin-job-type"`
+	// Conflicts records the changefeeds replicating the same tables to the same sink target
+	Conflicts []ChangeFeedID `json:"conflicts,omitempty"`
+	// ExecutingDDL is the DDL being executed by the sink, it is nil if no DDL is executing
+	ExecutingDDL *ExecutingDDL `json:"executing-ddl,omitempty"`
+	// DDLHistory records the last executed DDLs, the latest one is at the end
+	DDLHistory []*DDLExecution `json:"ddl-history,omitempty"`
+	// IntegrityCheck is the result of the last integrity check, it is nil if the check is disabled
+	IntegrityCheck *IntegrityCheckResult `json:"integrity-check,omitempty"`
+	// RemovedAt is the unix time in milliseconds when the changefeed is removed or finished
+	RemovedAt int64 `json:"removed-at,omitempty"`
+	// StartedAt is the unix time in milliseconds when the changefeed starts or resumes replicating
+	// from StartCheckpointTs, they tell whether the changefeed is still in the initial scan
+	StartedAt         int64  `json:"started-at,omitempty"`
+	StartCheckpointTs uint64 `json:"start-checkpoint-ts,omitempty"`
+}
+
+const (
+	// maxDDLQueryLengthInStatus is the max length of a DDL query recorded in the changefeed status
+	maxDDLQueryLengthInStatus = 256
+	// maxDDLHistoryInStatus is the max number of executed DDLs recorded in the changefeed status
+	maxDDLHistoryInStatus = 5
+)
+
+// TruncateDDLQuery truncates the DDL query to be recorded in the changefeed status
+func TruncateDDLQuery(query string) string {
+	if len(query) <= maxDDLQueryLengthInStatus {
+		return query
+	}
+	return query[:maxDDLQueryLengthInStatus] + "..."
+}
+
+// ExecutingDDL is a DDL being executed by the sink of a changefeed
+type ExecutingDDL struct {
+	CommitTs  uint64    `json:"commit-ts"`
+	Query     string    `json:"query"`
+	StartTime time.Time `json:"start-time"`
+}
+
+// String implements fmt.Stringer interface.
+func (d *ExecutingDDL) String() string {
+	return fmt.Sprintf("executing DDL since %s: %s", d.StartTime.Format("2006-01-02 15:04:05.000"), d.Query)
+}
+
+// DDLExecution records a DDL executed by the sink of a changefeed
+type DDLExecution struct {
+	CommitTs   uint64    `json:"commit-ts"`
+	Query      string    `json:"query"`
+	StartTime  time.Time `json:"start-time"`
+	DurationMs int64     `json:"duration-ms"`
+	// Error is the error message if the DDL fails
+	Error string `json:"error,omitempty"`
+}
+
+// RecordDDLExecution appends the DDL execution to the DDL history, only the last executions are kept
+func (status *ChangeFeedStatus) RecordDDLExecution(execution *DDLExecution) {
+	status.DDLHistory = append(status.DDLHistory, execution)
+	if len(status.DDLHistory) > maxDDLHistoryInStatus {
+		status.DDLHistory = status.DDLHistory[len(status.DDLHistory)-maxDDLHistoryInStatus:]
+	}
+}
+
+// TableChecksum is the row count and checksum of a table at a snapshot
+type TableChecksum struct {
+	RowCount int64  `json:"row-count"`
+	Checksum uint64 `json:"checksum"`
+}
+
+// IntegrityMismatch is a table whose data differs between the upstream and the downstream
+type IntegrityMismatch struct {
+	Table      string        `json:"table"`
+	Upstream   TableChecksum `json:"upstream"`
+	Downstream TableChecksum `json:"downstream"`
+}
+
+// IntegrityCheckResult is the result of an integrity check of a changefeed, the upstream is read
+// at the CheckpointTs of a syncpoint and the downstream is read at the corresponding SecondaryTs.
+type IntegrityCheckResult struct {
+	CheckTime    time.Time            `json:"check-time"`
+	CheckpointTs uint64               `json:"checkpoint-ts"`
+	SecondaryTs  uint64               `json:"secondary-ts"`
+	Tables       []string             `json:"tables"`
+	Mismatches   []*IntegrityMismatch `json:"mismatches,omitempty"`
+	// Error is the error message if the check fails
+	Error string `json:"error,omitempty"`
 }
 
 // Marshal returns json encoded string of ChangeFeedStatus, only contains necessary fields stored in storage
@@ -410,6 +502,119 @@ func (status *ChangeFeedStatus) Unmarshal(data []byte) error {
 		cerror.WrapError(cerror.ErrUnmarshalFailed, err), "Unmarshal data: %v", data)
 }
 
+// QuarantinedItem is a key moved aside by the owner because it is inconsistent
+type QuarantinedItem struct {
+	// Key is the original key of the item
+	Key            string    `json:"key"`
+	Value          string    `json:"value"`
+	Reason         string    `json:"reason"`
+	QuarantineTime time.Time `json:"quarantine-time"`
+}
+
+// Marshal returns json encoded string of QuarantinedItem
+func (item *QuarantinedItem) Marshal() (string, error) {
+	data, err := json.Marshal(item)
+	return string(data), cerror.WrapError(cerror.ErrMarshalFailed, err)
+}
+
+// Unmarshal unmarshals into *QuarantinedItem from json marshal byte slice
+func (item *QuarantinedItem) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, item)
+	return errors.Annotatef(
+		cerror.WrapError(cerror.ErrUnmarshalFailed, err), "Unmarshal data: %v", data)
+}
+
+// QuarantinedTable is a table quarantined by the sink because its rows still fail to be
+// written after the retries, the rows of the table are discarded until it is released
+type QuarantinedTable struct {
+	TableID TableID `json:"table-id"`
+	Schema  string  `json:"schema"`
+	Table   string  `json:"table"`
+	// CommitTs is the commit ts of the first row failed to be written
+	CommitTs       Ts        `json:"commit-ts"`
+	Error          string    `json:"error"`
+	CaptureID      string    `json:"capture-id"`
+	QuarantineTime time.Time `json:"quarantine-time"`
+}
+
+// Marshal returns json encoded string of QuarantinedTable
+func (t *QuarantinedTable) Marshal() (string, error) {
+	data, err := json.Marshal(t)
+	return string(data), cerror.WrapError(cerror.ErrMarshalFailed, err)
+}
+
+// Unmarshal unmarshals into *QuarantinedTable from json marshal byte slice
+func (t *QuarantinedTable) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, t)
+	return errors.Annotatef(
+		cerror.WrapError(cerror.ErrUnmarshalFailed, err), "Unmarshal data: %v", data)
+}
+
+// EmittedDDLs records the commit ts of the last DDL emitted to the sink of every table,
+// the schema level DDLs are recorded with table ID 0
+type EmittedDDLs map[TableID]Ts
+
+// Marshal returns the json marshal format of EmittedDDLs
+func (e EmittedDDLs) Marshal() (string, error) {
+	data, err := json.Marshal(e)
+	return string(data), cerror.WrapError(cerror.ErrMarshalFailed, err)
+}
+
+// Unmarshal unmarshals into *EmittedDDLs from json marshal byte slice
+func (e *EmittedDDLs) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, e)
+	return errors.Annotatef(
+		cerror.WrapError(cerror.ErrUnmarshalFailed, err), "Unmarshal data: %v", data)
+}
+
+// Prune removes the DDLs committed before the checkpoint ts, they are never replicated again
+func (e EmittedDDLs) Prune(checkpointTs Ts) {
+	for tableID, ts := range e {
+		if ts < checkpointTs {
+			delete(e, tableID)
+		}
+	}
+}
+
+// maxChangeFeedTombstones is the max number of tombstones kept in the ChangeFeedHistory
+const maxChangeFeedTombstones = 1000
+
+// ChangeFeedTombstone is the compact record of a changefeed whose metadata is garbage
+// collected by the owner after the retention period
+type ChangeFeedTombstone struct {
+	ID           ChangeFeedID `json:"id"`
+	CheckpointTs uint64       `json:"checkpoint-ts"`
+	State        FeedState    `json:"state"`
+	RemovedTime  time.Time    `json:"removed-time"`
+}
+
+// ChangeFeedHistory keeps the tombstones of the latest garbage collected changefeeds,
+// the oldest tombstone is dropped once there are more than maxChangeFeedTombstones
+type ChangeFeedHistory struct {
+	Tombstones []*ChangeFeedTombstone `json:"tombstones"`
+}
+
+// Append appends a tombstone to the history
+func (h *ChangeFeedHistory) Append(tombstone *ChangeFeedTombstone) {
+	h.Tombstones = append(h.Tombstones, tombstone)
+	if len(h.Tombstones) > maxChangeFeedTombstones {
+		h.Tombstones = append(h.Tombstones[:0:0], h.Tombstones[len(h.Tombstones)-maxChangeFeedTombstones:]...)
+	}
+}
+
+// Marshal returns json encoded string of ChangeFeedHistory
+func (h *ChangeFeedHistory) Marshal() (string, error) {
+	data, err := json.Marshal(h)
+	return string(data), cerror.WrapError(cerror.ErrMarshalFailed, err)
+}
+
+// Unmarshal unmarshals into *ChangeFeedHistory from json marshal byte slice
+func (h *ChangeFeedHistory) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, h)
+	return errors.Annotatef(
+		cerror.WrapError(cerror.ErrUnmarshalFailed, err), "Unmarshal data: %v", data)
+}
+
 // ProcInfoSnap holds most important replication information of a processor
 type ProcInfoSnap struct {
 	CfID      string                        `json:"changefeed-id"`
@@ -14,6 +14,7 @@
 package model
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/pingcap/check"
@@ -114,3 +115,23 @@ func (s *removeTableSuite) TestShouldHandleTableNotFoundCorrectly(c *check.C) {
 	_, found := info.RemoveTable(404, 666)
 	c.Assert(found, check.IsFalse)
 }
+
+type changeFeedHistorySuite struct{}
+
+var _ = check.Suite(&changeFeedHistorySuite{})
+
+func (s *changeFeedHistorySuite) TestAppendAndMarshal(c *check.C) {
+	history := &ChangeFeedHistory{}
+	for i := 0; i < maxChangeFeedTombstones+10; i++ {
+		history.Append(&ChangeFeedTombstone{ID: fmt.Sprintf("cf-%d", i), CheckpointTs: uint64(i), State: StateRemoved})
+	}
+	c.Assert(history.Tombstones, check.HasLen, maxChangeFeedTombstones)
+	c.Assert(history.Tombstones[0].ID, check.Equals, "cf-10")
+	c.Assert(history.Tombstones[maxChangeFeedTombstones-1].ID, check.Equals, fmt.Sprintf("cf-%d", maxChangeFeedTombstones+9))
+
+	data, err := history.Marshal()
+	c.Assert(err, check.IsNil)
+	decoded := &ChangeFeedHistory{}
+	c.Assert(decoded.Unmarshal([]byte(data)), check.IsNil)
+	c.Assert(decoded, check.DeepEquals, history)
+}
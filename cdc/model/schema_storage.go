@@ -140,7 +140,8 @@ func (ti *TableInfo) findHandleIndex() {
 	}
 	handleIndexOffset := -1
 	for i, idx := range ti.Indices {
-		if !ti.IsIndexUnique(idx) {
+		// the index being added is not usable until it's public
+		if idx.State != model.StatePublic || !ti.IsIndexUnique(idx) {
 			continue
 		}
 		if idx.Primary {
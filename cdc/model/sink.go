@@ -267,6 +267,9 @@ type Column struct {
 	Type  byte           `json:"type"`
 	Flag  ColumnFlagType `json:"flag"`
 	Value interface{}    `json:"value"`
+	// Elems is the elements of an ENUM or SET column, which is used by the encoders
+	// to encode the value as the names of the elements
+	Elems []string `json:"-"`
 }
 
 // ColumnValueString returns the string representation of the column value
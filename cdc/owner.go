@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -82,6 +84,33 @@ type Owner struct {
 	// record last time that flushes all changefeeds' replication status
 	lastFlushChangefeeds    time.Time
 	flushChangefeedInterval time.Duration
+	// rejectOverlappingChangefeeds marks a new changefeed as failed if it replicates
+	// the same tables to the same sink target as a running changefeed
+	rejectOverlappingChangefeeds bool
+	// gcGuardLag is the checkpoint lag beyond which a changefeed is paused for blocking
+	// the GC of upstream, zero disables the GC guard
+	gcGuardLag time.Duration
+	// gcShedFeeds record stopped changefeeds paused by the GC guard or for lagging behind,
+	// which don't hold the gc safepoint
+	gcShedFeeds map[model.ChangeFeedID]struct{}
+	// pausedByDownstream record stopped changefeeds paused since their downstreams refuse the writes,
+	// the changefeeds are resumed once the probes find the downstreams writable
+	pausedByDownstream map[model.ChangeFeedID]*downstreamPause
+	// ddlWaitingFeeds record when the changefeeds start to wait for the DDL jobs running at their
+	// start ts, see ReplicaConfig.StartTsInDDL
+	ddlWaitingFeeds map[model.ChangeFeedID]*ddlWait
+	// downstreamProbes tracks the running probes of the downstreams of pausedByDownstream
+	downstreamProbes sync.WaitGroup
+	// taskCache keeps the task status and positions of all changefeeds after the owner is
+	// elected, the task keys are read from etcd directly if it is nil
+	taskCache *kv.TaskCache
+	// changefeedMetaRetention is how long the keys of the removed, finished and failed
+	// changefeeds are kept, zero disables the garbage collection of them
+	changefeedMetaRetention time.Duration
+	lastChangefeedMetaGC    time.Time
+	// deadFeedsSeen records when the owner first sees the dead changefeeds whose removed
+	// time is unknown, the retention of them starts from then
+	deadFeedsSeen map[model.ChangeFeedID]time.Time
 }
 
 const (
@@ -89,6 +118,9 @@ const (
 	CDCServiceSafePointID = "ticdc"
 	// GCSafepointUpdateInterval is the minimual interval that CDC can update gc safepoint
 	GCSafepointUpdateInterval = time.Duration(2 * time.Second)
+
+	// taskCacheWaitTimeout is the max duration to wait for the task cache to catch up with etcd
+	taskCacheWaitTimeout = 3 * time.Second
 )
 
 // NewOwner creates a new Owner instance
@@ -99,6 +131,9 @@ func NewOwner(
 	sess *concurrency.Session,
 	gcTTL int64,
 	flushChangefeedInterval time.Duration,
+	rejectOverlappingChangefeeds bool,
+	gcGuardLag time.Duration,
+	changefeedMetaRetention time.Duration,
 ) (*Owner, error) {
 	cli := kv.NewCDCEtcdClient(ctx, sess.Client())
 	endpoints := sess.Client().Endpoints()
@@ -111,6 +146,7 @@ func NewOwner(
 		changeFeeds:             make(map[model.ChangeFeedID]*changeFeed),
 		failInitFeeds:           make(map[model.ChangeFeedID]struct{}),
 		stoppedFeeds:            make(map[model.ChangeFeedID]*model.ChangeFeedStatus),
+		gcShedFeeds:             make(map[model.ChangeFeedID]struct{}),
 		captures:                make(map[model.CaptureID]*model.CaptureInfo),
 		rebalanceTigger:         make(map[model.ChangeFeedID]bool),
 		manualScheduleCommand:   make(map[model.ChangeFeedID][]*model.MoveTableJob),
@@ -119,6 +155,11 @@ func NewOwner(
 		etcdClient:              cli,
 		gcTTL:                   gcTTL,
 		flushChangefeedInterval: flushChangefeedInterval,
+
+		rejectOverlappingChangefeeds: rejectOverlappingChangefeeds,
+		gcGuardLag:                   gcGuardLag,
+		changefeedMetaRetention:      changefeedMetaRetention,
+		deadFeedsSeen:                make(map[model.ChangeFeedID]time.Time),
 	}
 
 	return owner, nil
@@ -193,7 +234,8 @@ func (o *Owner) newChangeFeed(
 	processorsInfos model.ProcessorsInfos,
 	taskPositions map[string]*model.TaskPosition,
 	info *model.ChangeFeedInfo,
-	checkpointTs uint64) (cf *changeFeed, resultErr error) {
+	checkpointTs uint64,
+	reconcileSchema bool) (cf *changeFeed, resultErr error) {
 	log.Info("Find new changefeed", zap.Stringer("info", info),
 		zap.String("id", id), zap.Uint64("checkpoint ts", checkpointTs))
 
@@ -205,6 +247,14 @@ func (o *Owner) newChangeFeed(
 		failpoint.Return(nil, errors.New("failpoint injected retriable error"))
 	})
 
+	sinkURI, err := url.Parse(info.SinkURI)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	if err := info.Config.Sink.ValidateReconcileSchema(sinkURI.Scheme); err != nil {
+		return nil, err
+	}
+
 	// TODO here we create another pb client,we should reuse them
 	kvStore, err := kv.CreateTiStore(strings.Join(o.pdEndpoints, ","), o.credential)
 	if err != nil {
@@ -214,6 +264,9 @@ func (o *Owner) newChangeFeed(
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if err := checkDDLJobsAtStartTs(kvStore, meta, id, info, checkpointTs); err != nil {
+		return nil, errors.Trace(err)
+	}
 	schemaSnap, err := entry.NewSingleSchemaSnapshotFromMeta(meta, checkpointTs)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -223,6 +276,11 @@ func (o *Owner) newChangeFeed(
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if info.Config.Mounter.CheckSchemaAtStart {
+		if err := entry.CheckTableSchemas(schemaSnap, filter, info.Config.Mounter.UnknownColumnType); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 
 	if info.Engine == model.SortInFile {
 		err = os.MkdirAll(info.SortDir, 0755)
@@ -267,6 +325,7 @@ func (o *Owner) newChangeFeed(
 	partitions := make(map[model.TableID][]int64)
 	orphanTables := make(map[model.TableID]model.Ts)
 	sinkTableInfo := make([]*model.SimpleTableInfo, len(schemaSnap.CloneTables()))
+	var reconcileTables []*model.TableInfo
 	j := 0
 	for tid, table := range schemaSnap.CloneTables() {
 		j++
@@ -298,6 +357,7 @@ func (o *Owner) newChangeFeed(
 			log.Warn("skip ineligible table", zap.Int64("tid", tid), zap.Stringer("table", table))
 			continue
 		}
+		reconcileTables = append(reconcileTables, tblInfo)
 		// `existingTables` are tables dispatched to a processor, however the
 		// capture that this processor belongs to could have crashed or exited.
 		// So we check this before task dispatching, but after the update of
@@ -357,6 +417,17 @@ func (o *Owner) newChangeFeed(
 		log.Error("error on running owner", zap.Error(err))
 	}
 
+	if reconcileSchema && info.Config.Sink.ReconcileSchema {
+		// the downstream schemas are reconciled before any table is dispatched to the processors
+		reconciler, ok := primarySink.(sink.SchemaReconciler)
+		if !ok {
+			return nil, cerror.ErrReconcileUnsupported.GenWithStackByArgs(fmt.Sprintf("%T", primarySink))
+		}
+		if err := reconciler.ReconcileSchemas(ctx, reconcileTables); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
 	var syncpointStore sink.SyncpointStore
 	if info.SyncPointEnabled {
 		syncpointStore, err = sink.NewSyncpointStore(ctx, id, info.SinkURI)
@@ -376,25 +447,40 @@ func (o *Owner) newChangeFeed(
 		orphanTables:  orphanTables,
 		toCleanTables: make(map[model.TableID]model.Ts),
 		status: &model.ChangeFeedStatus{
-			ResolvedTs:   0,
-			CheckpointTs: checkpointTs,
+			ResolvedTs:        0,
+			CheckpointTs:      checkpointTs,
+			StartedAt:         time.Now().UnixNano() / 1e6,
+			StartCheckpointTs: checkpointTs,
 		},
-		scheduler:         scheduler.NewScheduler(info.Config.Scheduler.Tp),
-		ddlState:          model.ChangeFeedSyncDML,
-		ddlExecutedTs:     checkpointTs,
-		targetTs:          info.GetTargetTs(),
-		ddlTs:             0,
-		updateResolvedTs:  true,
-		startTimer:        make(chan bool),
-		syncpointStore:    syncpointStore,
-		syncCancel:        nil,
-		taskStatus:        processorsInfos,
-		taskPositions:     taskPositions,
-		etcdCli:           o.etcdClient,
-		filter:            filter,
-		sink:              primarySink,
-		cyclicEnabled:     info.Config.Cyclic.IsEnabled(),
-		lastRebalanceTime: time.Now(),
+		scheduler:           scheduler.NewScheduler(info.Config.Scheduler.Tp),
+		ddlState:            model.ChangeFeedSyncDML,
+		ddlExecutedTs:       checkpointTs,
+		targetTs:            info.GetTargetTs(),
+		ddlTs:               0,
+		updateResolvedTs:    true,
+		startTimer:          make(chan bool),
+		syncpointStore:      syncpointStore,
+		syncCancel:          nil,
+		taskStatus:          processorsInfos,
+		taskPositions:       taskPositions,
+		etcdCli:             o.etcdClient,
+		filter:              filter,
+		sink:                primarySink,
+		cyclicEnabled:       info.Config.Cyclic.IsEnabled(),
+		lastRebalanceTime:   time.Now(),
+		flushedCheckpointTs: checkpointTs,
+	}
+	err = cf.loadEmittedDDLs(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info.Config.IntegrityCheck != nil && info.Config.IntegrityCheck.Enable {
+		checker, err := newIntegrityChecker(ctx, id, info)
+		if err != nil {
+			log.Warn("integrity check is disabled", zap.String("changefeed", id), zap.Error(err))
+		} else {
+			cf.integrityChecker = checker
+		}
 	}
 	return cf, nil
 }
@@ -427,18 +513,60 @@ func (o *Owner) checkAndCleanTasksInfo(ctx context.Context) error {
 	return nil
 }
 
+// cachedTaskReader reads the task status and positions from the task cache
+type cachedTaskReader struct {
+	ChangeFeedRWriter
+	cache *kv.TaskCache
+}
+
+func (r *cachedTaskReader) GetAllTaskStatus(ctx context.Context, changefeedID string) (model.ProcessorsInfos, error) {
+	return r.cache.GetAllTaskStatus(changefeedID), nil
+}
+
+func (r *cachedTaskReader) GetAllTaskPositions(ctx context.Context, changefeedID string) (map[string]*model.TaskPosition, error) {
+	return r.cache.GetAllTaskPositions(changefeedID), nil
+}
+
+// isTaskLoaded returns whether the task status and positions of the changefeed can be read from the reader,
+// the task cache loads the changefeeds one by one after the owner is elected
+func isTaskLoaded(reader ChangeFeedRWriter, changefeedID model.ChangeFeedID) bool {
+	r, ok := reader.(*cachedTaskReader)
+	return !ok || r.cache.IsLoaded(changefeedID)
+}
+
+// taskReader returns the reader of the task status and positions up to date with the revision,
+// it falls back to read etcd directly if the task cache can't catch up in time.
+func (o *Owner) taskReader(ctx context.Context, revision int64) ChangeFeedRWriter {
+	if o.taskCache == nil {
+		return o.cfRWriter
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, taskCacheWaitTimeout)
+	defer cancel()
+	if err := o.taskCache.WaitRevision(waitCtx, revision); err != nil {
+		log.Warn("task cache falls behind etcd, read the task keys from etcd",
+			zap.Int64("revision", revision), zap.Int64("cacheRevision", o.taskCache.Revision()), zap.Error(err))
+		return o.cfRWriter
+	}
+	return &cachedTaskReader{ChangeFeedRWriter: o.cfRWriter, cache: o.taskCache}
+}
+
 func (o *Owner) loadChangeFeeds(ctx context.Context) error {
-	_, details, err := o.cfRWriter.GetChangeFeeds(ctx)
+	revision, details, err := o.cfRWriter.GetChangeFeeds(ctx)
 	if err != nil {
 		return err
 	}
+	taskReader := o.taskReader(ctx, revision)
 	errorFeeds := make(map[model.ChangeFeedID]*model.RunningError)
 	for changeFeedID, cfInfoRawValue := range details {
-		taskStatus, err := o.cfRWriter.GetAllTaskStatus(ctx, changeFeedID)
+		// the changefeed is handled once its own task keys are loaded
+		if !isTaskLoaded(taskReader, changeFeedID) {
+			continue
+		}
+		taskStatus, err := taskReader.GetAllTaskStatus(ctx, changeFeedID)
 		if err != nil {
 			return err
 		}
-		taskPositions, err := o.cfRWriter.GetAllTaskPositions(ctx, changeFeedID)
+		taskPositions, err := taskReader.GetAllTaskPositions(ctx, changeFeedID)
 		if err != nil {
 			return err
 		}
@@ -500,13 +628,31 @@ func (o *Owner) loadChangeFeeds(ctx context.Context) error {
 			if status.AdminJobType == model.AdminStop {
 				if _, ok := o.stoppedFeeds[changeFeedID]; !ok {
 					o.stoppedFeeds[changeFeedID] = status
+					if releasesGCSafepoint(cfInfo.Error) {
+						o.gcShedFeeds[changeFeedID] = struct{}{}
+					}
+					if isPausedByDownstream(cfInfo.Error) {
+						o.markPausedByDownstream(changeFeedID, cfInfo, time.Now())
+					}
 				}
 			}
 			continue
 		}
 		checkpointTs := cfInfo.GetCheckpointTs(status)
+		if !o.isDDLJobsRecheckDue(changeFeedID, time.Now()) {
+			continue
+		}
 
-		newCf, err := o.newChangeFeed(ctx, changeFeedID, taskStatus, taskPositions, cfInfo, checkpointTs)
+		// the downstream schemas are only reconciled when the changefeed starts or resumes,
+		// not when it's loaded again by a new owner or after an error
+		resumed := cfInfo.ResumePending
+		reconcileSchema := status == nil || resumed
+		newCf, err := o.newChangeFeed(ctx, changeFeedID, taskStatus, taskPositions, cfInfo, checkpointTs, reconcileSchema)
+		if err != nil && waitForDDLJobsAtStartTs(cfInfo, err) {
+			o.markWaitingForDDLJobs(changeFeedID, err, time.Now())
+			continue
+		}
+		o.forgetWaitingForDDLJobs(changeFeedID)
 		if err != nil {
 			cfInfo.Error = &model.RunningError{
 				Addr:    util.CaptureAddrFromCtx(ctx),
@@ -525,6 +671,15 @@ func (o *Owner) loadChangeFeeds(ctx context.Context) error {
 				}
 				continue
 			}
+			if filter.ChangefeedPauseError(err) {
+				log.Error("create changefeed with an error to be fixed, pause the changefeed",
+					zap.Error(err), zap.String("changefeedid", changeFeedID))
+				err := o.pauseChangefeedNotCreated(ctx, changeFeedID, cfInfo, status, checkpointTs)
+				if err != nil {
+					return err
+				}
+				continue
+			}
 
 			err2 := o.etcdClient.SaveChangeFeedInfo(ctx, cfInfo, changeFeedID)
 			if err2 != nil {
@@ -537,6 +692,63 @@ func (o *Owner) loadChangeFeeds(ctx context.Context) error {
 			continue
 		}
 
+		if status != nil {
+			newCf.status.DDLHistory = status.DDLHistory
+			if !resumed {
+				newCf.restoreInitialScan(status)
+			}
+		}
+
+		if overlapped := o.findOverlappingChangefeeds(newCf); len(overlapped) != 0 {
+			err := cerror.ErrOwnerChangefeedOverlapped.GenWithStackByArgs(changeFeedID, overlapped)
+			later := o.createdAfter(newCf, overlapped)
+			switch {
+			case o.rejectOverlappingChangefeeds && len(later) == len(overlapped):
+				// the new changefeed is created before all the overlapped ones, which are stopped
+				// instead, so the result doesn't depend on the order the changefeeds are loaded
+				for _, id := range later {
+					err := cerror.ErrOwnerChangefeedOverlapped.GenWithStackByArgs(id, []model.ChangeFeedID{changeFeedID})
+					log.Error("stop the changefeed overlapping with an earlier created one",
+						zap.Error(err), zap.String("changefeedid", id))
+					err = o.EnqueueJob(model.AdminJob{
+						CfID: id,
+						Type: model.AdminStop,
+						Error: &model.RunningError{
+							Addr:    util.CaptureAddrFromCtx(ctx),
+							Code:    string(cerror.ErrOwnerChangefeedOverlapped.RFCCode()),
+							Message: err.Error(),
+						},
+					})
+					if err != nil {
+						return errors.Trace(err)
+					}
+				}
+			case o.rejectOverlappingChangefeeds:
+				log.Error("reject the changefeed overlapping with others, mark changefeed as failed",
+					zap.Error(err), zap.String("changefeedid", changeFeedID))
+				newCf.Close()
+				cfInfo.Error = &model.RunningError{
+					Addr:    util.CaptureAddrFromCtx(ctx),
+					Code:    "CDC-owner-1001",
+					Message: err.Error(),
+				}
+				cfInfo.State = model.StateFailed
+				err := o.etcdClient.SaveChangeFeedInfo(ctx, cfInfo, changeFeedID)
+				if err != nil {
+					return err
+				}
+				continue
+			default:
+				log.Warn("changefeed overlaps with others", zap.Error(err), zap.String("changefeed", changeFeedID))
+				newCf.status.Conflicts = overlapped
+				for _, id := range overlapped {
+					status := o.changeFeeds[id].status
+					status.Conflicts = append(status.Conflicts, changeFeedID)
+					sort.Strings(status.Conflicts)
+				}
+			}
+		}
+
 		if newCf.info.SyncPointEnabled {
 			log.Info("syncpoint is on, creating the sync table")
 			//create the sync table
@@ -549,8 +761,24 @@ func (o *Owner) loadChangeFeeds(ctx context.Context) error {
 			log.Info("syncpoint is off")
 		}
 
+		if resumed {
+			cfInfo.ResumePending = false
+			if err := o.etcdClient.SaveChangeFeedInfo(ctx, cfInfo, changeFeedID); err != nil {
+				// the downstream schemas are reconciled again when the changefeed is loaded next time
+				log.Warn("failed to clear the pending resume of changefeed",
+					zap.String("changefeed", changeFeedID), zap.Error(err))
+			}
+		}
 		o.changeFeeds[changeFeedID] = newCf
 		delete(o.stoppedFeeds, changeFeedID)
+		delete(o.gcShedFeeds, changeFeedID)
+		o.forgetPausedByDownstream(changeFeedID)
+		o.updateSharedTopicMetrics()
+	}
+	for id := range o.ddlWaitingFeeds {
+		if _, ok := details[id]; !ok {
+			o.forgetWaitingForDDLJobs(id)
+		}
 	}
 	o.adminJobsLock.Lock()
 	for cfID, err := range errorFeeds {
@@ -573,7 +801,8 @@ func (o *Owner) balanceTables(ctx context.Context) error {
 		o.rebalanceForAllChangefeed = false
 	}
 	o.rebalanceMu.Unlock()
-	for id, changefeed := range o.changeFeeds {
+	for _, id := range o.changefeedIDsByPriority() {
+		changefeed := o.changeFeeds[id]
 		rebalanceNow := false
 		var scheduleCommands []*model.MoveTableJob
 		o.rebalanceMu.Lock()
@@ -598,8 +827,8 @@ func (o *Owner) balanceTables(ctx context.Context) error {
 }
 
 func (o *Owner) flushChangeFeedInfos(ctx context.Context) error {
-	// no running or stopped changefeed, clear gc safepoint.
-	if len(o.changeFeeds) == 0 && len(o.stoppedFeeds) == 0 {
+	// no running or stopped changefeed holding the gc safepoint, clear gc safepoint.
+	if len(o.changeFeeds) == 0 && len(o.stoppedFeeds) == len(o.gcShedFeeds) {
 		if !o.gcSafepointLastUpdate.IsZero() {
 			_, err := o.pdClient.UpdateServiceGCSafePoint(ctx, CDCServiceSafePointID, 0, 0)
 			if err != nil {
@@ -613,22 +842,31 @@ func (o *Owner) flushChangeFeedInfos(ctx context.Context) error {
 
 	minCheckpointTs := uint64(math.MaxUint64)
 	if len(o.changeFeeds) > 0 {
-		snapshot := make(map[model.ChangeFeedID]*model.ChangeFeedStatus, len(o.changeFeeds))
-		for id, changefeed := range o.changeFeeds {
-			snapshot[id] = changefeed.status
-			if changefeed.status.CheckpointTs < minCheckpointTs {
-				minCheckpointTs = changefeed.status.CheckpointTs
-			}
-		}
 		if time.Since(o.lastFlushChangefeeds) > o.flushChangefeedInterval {
+			snapshot := make(map[model.ChangeFeedID]*model.ChangeFeedStatus, len(o.changeFeeds))
+			for id, changefeed := range o.changeFeeds {
+				snapshot[id] = changefeed.status
+			}
 			err := o.cfRWriter.PutAllChangeFeedStatus(ctx, snapshot)
 			if err != nil {
 				return errors.Trace(err)
 			}
+			for _, changefeed := range o.changeFeeds {
+				changefeed.flushedCheckpointTs = changefeed.status.CheckpointTs
+			}
 			o.lastFlushChangefeeds = time.Now()
 		}
+		for _, changefeed := range o.changeFeeds {
+			if ts := changefeed.gcSafepointTs(); ts < minCheckpointTs {
+				minCheckpointTs = ts
+			}
+		}
 	}
-	for _, status := range o.stoppedFeeds {
+	for id, status := range o.stoppedFeeds {
+		// the changefeeds paused by the GC guard don't block the GC of upstream any more
+		if _, ok := o.gcShedFeeds[id]; ok {
+			continue
+		}
 		if status.CheckpointTs < minCheckpointTs {
 			minCheckpointTs = status.CheckpointTs
 		}
@@ -659,12 +897,20 @@ func (o *Owner) handleDDL(ctx context.Context) error {
 	for _, cf := range o.changeFeeds {
 		err := cf.handleDDL(ctx, o.captures)
 		if err != nil {
-			if cerror.ErrExecDDLFailed.NotEqual(err) {
+			code := cerror.ErrExecDDLFailed.RFCCode()
+			if cerror.ErrDownstreamUnwritable.Equal(err) {
+				code = cerror.ErrDownstreamUnwritable.RFCCode()
+			} else if cerror.ErrExecDDLFailed.NotEqual(err) {
 				return errors.Trace(err)
 			}
 			err = o.EnqueueJob(model.AdminJob{
 				CfID: cf.id,
 				Type: model.AdminStop,
+				Error: &model.RunningError{
+					Addr:    util.CaptureAddrFromCtx(ctx),
+					Code:    string(code),
+					Message: err.Error(),
+				},
 			})
 			if err != nil {
 				return errors.Trace(err)
@@ -713,11 +959,77 @@ func (o *Owner) dispatchJob(ctx context.Context, job model.AdminJob) error {
 	// For `AdminRemove`, we need to update stoppedFeeds when removing a stopped changefeed.
 	if job.Type == model.AdminStop {
 		o.stoppedFeeds[job.CfID] = cf.status
+		if releasesGCSafepoint(job.Error) {
+			o.gcShedFeeds[job.CfID] = struct{}{}
+		}
+		if isPausedByDownstream(job.Error) {
+			o.markPausedByDownstream(job.CfID, cf.info, time.Now())
+		}
 	}
 	delete(o.changeFeeds, job.CfID)
+	o.clearConflicts(job.CfID)
 	return nil
 }
 
+// findOverlappingChangefeeds returns the running changefeeds which replicate
+// any table of the given changefeed to the same sink target.
+func (o *Owner) findOverlappingChangefeeds(cf *changeFeed) []model.ChangeFeedID {
+	target, err := sink.NormalizeSinkTarget(cf.info.SinkURI)
+	if err != nil || target == "" {
+		return nil
+	}
+	var overlapped []model.ChangeFeedID
+	for id, other := range o.changeFeeds {
+		if id == cf.id {
+			continue
+		}
+		otherTarget, err := sink.NormalizeSinkTarget(other.info.SinkURI)
+		if err != nil || otherTarget != target {
+			continue
+		}
+		for tid := range cf.tables {
+			if _, ok := other.tables[tid]; ok {
+				overlapped = append(overlapped, id)
+				break
+			}
+		}
+	}
+	sort.Strings(overlapped)
+	return overlapped
+}
+
+// createdAfter returns the overlapped changefeeds which are created after the given
+// changefeed, the changefeed IDs break the ties of the create times.
+func (o *Owner) createdAfter(cf *changeFeed, overlapped []model.ChangeFeedID) []model.ChangeFeedID {
+	var later []model.ChangeFeedID
+	for _, id := range overlapped {
+		other := o.changeFeeds[id].info
+		if other.CreateTime.After(cf.info.CreateTime) ||
+			(other.CreateTime.Equal(cf.info.CreateTime) && id > cf.id) {
+			later = append(later, id)
+		}
+	}
+	return later
+}
+
+// clearConflicts removes the changefeed from the conflicts of running changefeeds
+func (o *Owner) clearConflicts(cfID model.ChangeFeedID) {
+	for _, cf := range o.changeFeeds {
+		conflicts := cf.status.Conflicts[:0]
+		for _, id := range cf.status.Conflicts {
+			if id != cfID {
+				conflicts = append(conflicts, id)
+			}
+		}
+		if len(conflicts) == 0 {
+			conflicts = nil
+		}
+		cf.status.Conflicts = conflicts
+	}
+	sharedTopicChangefeedsGauge.DeleteLabelValues(cfID)
+	o.updateSharedTopicMetrics()
+}
+
 func (o *Owner) collectChangefeedInfo(ctx context.Context, cid model.ChangeFeedID) (
 	cf *changeFeed,
 	status *model.ChangeFeedStatus,
@@ -755,6 +1067,9 @@ func (o *Owner) collectChangefeedInfo(ctx context.Context, cid model.ChangeFeedI
 		}
 	case model.AdminStop:
 		feedState = model.StateStopped
+		if cfInfo != nil && isPausedByDownstream(cfInfo.Error) {
+			feedState = model.StatePausedByDownstream
+		}
 	case model.AdminRemove:
 		feedState = model.StateRemoved
 	case model.AdminFinish:
@@ -763,10 +1078,10 @@ func (o *Owner) collectChangefeedInfo(ctx context.Context, cid model.ChangeFeedI
 	return
 }
 
-func (o *Owner) checkClusterHealth(_ context.Context) error {
+func (o *Owner) checkClusterHealth(ctx context.Context) error {
 	// check whether a changefeed has finished by comparing checkpoint-ts and target-ts
 	for _, cf := range o.changeFeeds {
-		if cf.status.CheckpointTs == cf.info.GetTargetTs() {
+		if cf.status.CheckpointTs == cf.info.GetTargetTs() && cf.downstreamCaughtUp(ctx) {
 			log.Info("changefeed replication finished", zap.String("changefeed", cf.id), zap.Uint64("checkpointTs", cf.status.CheckpointTs))
 			err := o.EnqueueJob(model.AdminJob{
 				CfID: cf.id,
@@ -781,6 +1096,28 @@ func (o *Owner) checkClusterHealth(_ context.Context) error {
 	return nil
 }
 
+// pauseChangefeedNotCreated pauses the changefeed failed to be created, the checkpoint is kept
+// in the status so the changefeed can be resumed from it
+func (o *Owner) pauseChangefeedNotCreated(
+	ctx context.Context,
+	id model.ChangeFeedID,
+	info *model.ChangeFeedInfo,
+	status *model.ChangeFeedStatus,
+	checkpointTs uint64,
+) error {
+	if status == nil {
+		status = &model.ChangeFeedStatus{ResolvedTs: checkpointTs, CheckpointTs: checkpointTs}
+	}
+	status.AdminJobType = model.AdminStop
+	err := o.etcdClient.PutChangeFeedStatus(ctx, id, status)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	info.AdminJobType = model.AdminStop
+	info.State = model.StateStopped
+	return errors.Trace(o.etcdClient.SaveChangeFeedInfo(ctx, info, id))
+}
+
 func (o *Owner) handleAdminJob(ctx context.Context) error {
 	removeIdx := 0
 	o.adminJobsLock.Lock()
@@ -821,6 +1158,14 @@ func (o *Owner) handleAdminJob(ctx context.Context) error {
 			case model.StateFinished:
 				log.Info("changefeed has finished, pause command will do nothing")
 				continue
+			case model.StatePausedByDownstream:
+				if job.Error == nil {
+					err := o.holdPausedByDownstream(ctx, job.CfID)
+					if err != nil {
+						return errors.Trace(err)
+					}
+				}
+				continue
 			}
 			if cf == nil {
 				log.Warn("invalid admin job, changefeed not found", zap.String("changefeed", job.CfID))
@@ -829,7 +1174,8 @@ func (o *Owner) handleAdminJob(ctx context.Context) error {
 
 			cf.info.AdminJobType = model.AdminStop
 			cf.info.Error = job.Error
-			if job.Error != nil {
+			// the pauses by the downstream are resumed by the probes, they don't count as failures
+			if job.Error != nil && !isPausedByDownstream(job.Error) {
 				cf.info.ErrorHis = append(cf.info.ErrorHis, time.Now().UnixNano()/1e6)
 			}
 			err := o.etcdClient.SaveChangeFeedInfo(ctx, cf.info, job.CfID)
@@ -843,8 +1189,10 @@ func (o *Owner) handleAdminJob(ctx context.Context) error {
 			}
 			cf.stopSyncPointTicker()
 		case model.AdminRemove, model.AdminFinish:
+			removedAt := time.Now().UnixNano() / 1e6
 			if cf != nil {
 				cf.stopSyncPointTicker()
+				cf.status.RemovedAt = removedAt
 				err := o.dispatchJob(ctx, job)
 				if err != nil {
 					return errors.Trace(err)
@@ -862,14 +1210,17 @@ func (o *Owner) handleAdminJob(ctx context.Context) error {
 						log.Info("changefeed has been removed or finished, remove command will do nothing")
 					}
 					continue
-				case model.StateStopped, model.StateFailed:
+				case model.StateStopped, model.StateFailed, model.StatePausedByDownstream:
 					// remove a paused or failed changefeed
 					status.AdminJobType = model.AdminRemove
+					status.RemovedAt = removedAt
 					err = o.etcdClient.PutChangeFeedStatus(ctx, job.CfID, status)
 					if err != nil {
 						return errors.Trace(err)
 					}
 					delete(o.stoppedFeeds, job.CfID)
+					delete(o.gcShedFeeds, job.CfID)
+					o.forgetPausedByDownstream(job.CfID)
 				default:
 					return cerror.ErrChangefeedAbnormalState.GenWithStackByArgs(feedState, status)
 				}
@@ -879,14 +1230,23 @@ func (o *Owner) handleAdminJob(ctx context.Context) error {
 			if err != nil {
 				return errors.Trace(err)
 			}
+			err = o.etcdClient.RemoveEmittedDDLs(ctx, job.CfID)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			err = o.etcdClient.RemoveQuarantinedTables(ctx, job.CfID)
+			if err != nil {
+				return errors.Trace(err)
+			}
 			if job.Opts != nil && job.Opts.ForceRemove {
 				// if `ForceRemove` is enabled, remove all information related to this changefeed
 				err := o.etcdClient.RemoveChangeFeedStatus(ctx, job.CfID)
 				if err != nil {
 					return errors.Trace(err)
 				}
-			} else {
-				// set ttl to changefeed status
+			} else if o.changefeedMetaRetention <= 0 {
+				// set ttl to changefeed status, it is garbage collected by the owner
+				// after the retention period otherwise
 				err = o.etcdClient.SetChangeFeedStatusTTL(ctx, job.CfID, 24*3600 /*24 hours*/)
 				if err != nil {
 					return errors.Trace(err)
@@ -919,6 +1279,8 @@ func (o *Owner) handleAdminJob(ctx context.Context) error {
 			// clear last running error
 			cfInfo.State = model.StateNormal
 			cfInfo.Error = nil
+			// the resume is persisted so that a new owner loading the changefeed still knows it
+			cfInfo.ResumePending = true
 			err = o.etcdClient.SaveChangeFeedInfo(ctx, cfInfo, job.CfID)
 			if err != nil {
 				return errors.Trace(err)
@@ -974,7 +1336,15 @@ func (o *Owner) Run(ctx context.Context, tickTime time.Duration) error {
 	})
 
 	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	defer func() {
+		cancel()
+		o.downstreamProbes.Wait()
+	}()
+
+	// repair the states written by older versions before loading changefeeds
+	if _, err := o.reconcileEtcdState(ctx); err != nil {
+		return err
+	}
 
 	if err := o.throne(ctx); err != nil {
 		return err
@@ -982,6 +1352,17 @@ func (o *Owner) Run(ctx context.Context, tickTime time.Duration) error {
 
 	ctx1, cancel := context.WithCancel(ctx)
 	defer cancel()
+	// the task keys are listed once, the values are loaded in the background and then kept up to date by watch
+	o.taskCache = kv.NewTaskCache(o.etcdClient)
+	if err := o.taskCache.Load(ctx1); err != nil {
+		return err
+	}
+	go func() {
+		err := o.taskCache.Run(ctx1)
+		if errors.Cause(err) != context.Canceled {
+			log.Warn("task cache exited", zap.Error(err))
+		}
+	}()
 	changedFeeds := o.watchFeedChange(ctx1)
 
 	ticker := time.NewTicker(tickTime)
@@ -992,6 +1373,8 @@ loop:
 	for {
 		select {
 		case <-o.done:
+			// the probes of the downstreams end within the probe timeout
+			o.downstreamProbes.Wait()
 			close(o.done)
 			break loop
 		case <-ctx.Done():
@@ -1073,6 +1456,23 @@ func (o *Owner) run(ctx context.Context) error {
 		return errors.Trace(err)
 	}
 
+	o.handleIntegrityCheck(ctx, time.Now())
+
+	err = o.shedChangefeedOnGCDanger(ctx, time.Now())
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = o.pauseLaggingChangefeeds(ctx, time.Now())
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	err = o.resumeWritableDownstreams(ctx, time.Now())
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	err = o.handleAdminJob(ctx)
 	if err != nil {
 		return errors.Trace(err)
@@ -1088,6 +1488,11 @@ func (o *Owner) run(ctx context.Context) error {
 		return errors.Trace(err)
 	}
 
+	err = o.gcChangefeedMeta(ctx, time.Now())
+	if err != nil {
+		log.Warn("garbage-collect the changefeed metadata failed", zap.Error(err))
+	}
+
 	return nil
 }
 
@@ -0,0 +1,133 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultDownstreamProbeInterval is the interval between the probes of the downstream of a
+	// changefeed paused by the downstream, if the sink config doesn't set it
+	defaultDownstreamProbeInterval = 30 * time.Second
+	// downstreamProbeTimeout bounds a probe
+	downstreamProbeTimeout = 10 * time.Second
+)
+
+// probeDownstreamWritable checks whether the downstream of the sink uri accepts the writes
+var probeDownstreamWritable = sink.ProbeDownstreamWritable
+
+// downstreamPause records a changefeed paused since its downstream refuses the writes
+type downstreamPause struct {
+	sinkURI   string
+	interval  time.Duration
+	nextProbe time.Time
+	// probing is 1 while a probe runs, and writable is 1 once a probe finds the downstream writable,
+	// they are accessed atomically since the probes run off the owner loop
+	probing  int32
+	writable int32
+}
+
+// isPausedByDownstream returns whether the changefeed is paused since its downstream refuses the writes
+func isPausedByDownstream(runningErr *model.RunningError) bool {
+	return runningErr != nil && runningErr.Code == string(cerror.ErrDownstreamUnwritable.RFCCode())
+}
+
+// markPausedByDownstream records the changefeed paused by its downstream, the downstream is probed
+// after the probe interval
+func (o *Owner) markPausedByDownstream(id model.ChangeFeedID, info *model.ChangeFeedInfo, now time.Time) {
+	if o.pausedByDownstream == nil {
+		o.pausedByDownstream = make(map[model.ChangeFeedID]*downstreamPause)
+	}
+	interval := defaultDownstreamProbeInterval
+	if info.Config != nil && info.Config.Sink != nil && info.Config.Sink.DownstreamProbeInterval > 0 {
+		interval = time.Duration(info.Config.Sink.DownstreamProbeInterval) * time.Second
+	}
+	o.pausedByDownstream[id] = &downstreamPause{
+		sinkURI:   info.SinkURI,
+		interval:  interval,
+		nextProbe: now.Add(interval),
+	}
+	pausedByDownstreamGauge.WithLabelValues(id).Set(1)
+	log.Warn("changefeed is paused since the downstream refuses the writes, it's resumed once the downstream is writable",
+		zap.String("changefeed", id), zap.Duration("probe-interval", interval))
+}
+
+// forgetPausedByDownstream forgets the changefeed after it's resumed, removed or paused by the user
+func (o *Owner) forgetPausedByDownstream(id model.ChangeFeedID) {
+	delete(o.pausedByDownstream, id)
+	pausedByDownstreamGauge.DeleteLabelValues(id)
+}
+
+// holdPausedByDownstream keeps the changefeed paused by its downstream paused after the user pauses
+// it, the changefeed is in the stopped state and not resumed by the probes anymore
+func (o *Owner) holdPausedByDownstream(ctx context.Context, id model.ChangeFeedID) error {
+	info, err := o.etcdClient.GetChangeFeedInfo(ctx, id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	info.Error = nil
+	err = o.etcdClient.SaveChangeFeedInfo(ctx, info, id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	o.forgetPausedByDownstream(id)
+	log.Info("changefeed paused by the downstream is paused by the user", zap.String("changefeed", id))
+	return nil
+}
+
+// resumeWritableDownstreams probes the downstreams of the changefeeds paused by them once the probe
+// interval elapses, the changefeeds are resumed once the downstreams accept the writes again. The probes
+// run in their own goroutines so that a slow downstream doesn't block the owner, while the changefeeds
+// are resumed by the owner, which skips the changefeeds paused by the user during the probes.
+func (o *Owner) resumeWritableDownstreams(ctx context.Context, now time.Time) error {
+	for id, pause := range o.pausedByDownstream {
+		if atomic.LoadInt32(&pause.writable) == 1 {
+			log.Info("the downstream accepts the writes again, resume the changefeed", zap.String("changefeed", id))
+			err := o.EnqueueJob(model.AdminJob{CfID: id, Type: model.AdminResume})
+			if err != nil {
+				return err
+			}
+			o.forgetPausedByDownstream(id)
+			continue
+		}
+		if now.Before(pause.nextProbe) || !atomic.CompareAndSwapInt32(&pause.probing, 0, 1) {
+			continue
+		}
+		pause.nextProbe = now.Add(pause.interval)
+		o.downstreamProbes.Add(1)
+		go func(id model.ChangeFeedID, pause *downstreamPause) {
+			defer o.downstreamProbes.Done()
+			defer atomic.StoreInt32(&pause.probing, 0)
+			probeCtx, cancel := context.WithTimeout(ctx, downstreamProbeTimeout)
+			defer cancel()
+			if err := probeDownstreamWritable(probeCtx, id, pause.sinkURI); err != nil {
+				log.Info("the downstream still refuses the writes",
+					zap.String("changefeed", id), zap.Duration("probe-interval", pause.interval), zap.Error(err))
+				return
+			}
+			atomic.StoreInt32(&pause.writable, 1)
+		}(id, pause)
+	}
+	return nil
+}
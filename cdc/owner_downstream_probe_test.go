@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"time"
+
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func (s *ownerSuite) TestPausedByDownstream(c *check.C) {
+	ctx := s.ctx
+	pausedByDownstreamGauge.Reset()
+	defer pausedByDownstreamGauge.Reset()
+	defer func(probe func(context.Context, model.ChangeFeedID, string) error) {
+		probeDownstreamWritable = probe
+	}(probeDownstreamWritable)
+	readOnly := true
+	var probed []string
+	probeDownstreamWritable = func(ctx context.Context, id model.ChangeFeedID, sinkURI string) error {
+		probed = append(probed, sinkURI)
+		if readOnly {
+			return &dmysql.MySQLError{Number: mysql.ErrOptionPreventsStatement, Message: "--read-only"}
+		}
+		return nil
+	}
+
+	const sinkURI = "mysql://127.0.0.1:3306/"
+	cf := s.newPriorityTestChangefeed(c, "paused", config.PriorityClassNormal, 1000)
+	cf.info.SinkURI = sinkURI
+	cf.info.Config.Sink.DownstreamProbeInterval = 5
+	owner := &Owner{
+		changeFeeds:   map[model.ChangeFeedID]*changeFeed{"paused": cf},
+		failInitFeeds: make(map[model.ChangeFeedID]struct{}),
+		stoppedFeeds:  make(map[model.ChangeFeedID]*model.ChangeFeedStatus),
+		gcShedFeeds:   make(map[model.ChangeFeedID]struct{}),
+		pdClient:      &safepointPDClient{},
+		cfRWriter:     s.client,
+		etcdClient:    s.client,
+	}
+	c.Assert(s.client.SaveChangeFeedInfo(ctx, cf.info, "paused"), check.IsNil)
+	c.Assert(s.client.PutChangeFeedStatus(ctx, "paused", cf.status), check.IsNil)
+	position := &model.TaskPosition{
+		CheckPointTs: 1000,
+		Error: &model.RunningError{
+			Addr:    "127.0.0.1:8300",
+			Code:    string(cerror.ErrDownstreamUnwritable.RFCCode()),
+			Message: "the downstream refuses to write for a while, Error 1290: --read-only",
+		},
+	}
+	_, err := s.client.PutTaskPositionOnChange(ctx, "paused", "capture-1", position)
+	c.Assert(err, check.IsNil)
+
+	// the changefeed whose processor meets the read-only downstream is paused by the downstream
+	c.Assert(owner.loadChangeFeeds(ctx), check.IsNil)
+	c.Assert(owner.handleAdminJob(ctx), check.IsNil)
+	c.Assert(owner.changeFeeds, check.HasLen, 0)
+	c.Assert(owner.pausedByDownstream, check.HasKey, "paused")
+	_, _, state, err := owner.collectChangefeedInfo(ctx, "paused")
+	c.Assert(err, check.IsNil)
+	c.Assert(state, check.Equals, model.StatePausedByDownstream)
+	c.Assert(testutil.ToFloat64(pausedByDownstreamGauge.WithLabelValues("paused")), check.Equals, float64(1))
+	// the pause doesn't count as a failure of the changefeed
+	info, err := s.client.GetChangeFeedInfo(ctx, "paused")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.ErrorHis, check.HasLen, 0)
+
+	// the downstream is probed off the owner loop once the probe interval elapses, until it accepts
+	// the writes, and then the changefeed is resumed by the owner loop
+	now := time.Now()
+	probe := func(now time.Time) {
+		c.Assert(owner.resumeWritableDownstreams(ctx, now), check.IsNil)
+		owner.downstreamProbes.Wait()
+	}
+	probe(now)
+	c.Assert(probed, check.HasLen, 0)
+	probe(now.Add(5 * time.Second))
+	c.Assert(probed, check.DeepEquals, []string{sinkURI})
+	c.Assert(owner.adminJobs, check.HasLen, 0)
+	probe(now.Add(8 * time.Second))
+	c.Assert(probed, check.HasLen, 1)
+	readOnly = false
+	probe(now.Add(10 * time.Second))
+	c.Assert(probed, check.HasLen, 2)
+	c.Assert(owner.adminJobs, check.HasLen, 0)
+	probe(now.Add(11 * time.Second))
+	c.Assert(probed, check.HasLen, 2)
+	c.Assert(owner.adminJobs, check.DeepEquals, []model.AdminJob{{CfID: "paused", Type: model.AdminResume}})
+	c.Assert(owner.pausedByDownstream, check.HasLen, 0)
+	c.Assert(testutil.CollectAndCount(pausedByDownstreamGauge), check.Equals, 0)
+
+	// the changefeed is resumed with the error cleared
+	c.Assert(owner.handleAdminJob(ctx), check.IsNil)
+	info, err = s.client.GetChangeFeedInfo(ctx, "paused")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.AdminJobType, check.Equals, model.AdminResume)
+	c.Assert(info.Error, check.IsNil)
+	_, _, state, err = owner.collectChangefeedInfo(ctx, "paused")
+	c.Assert(err, check.IsNil)
+	c.Assert(state, check.Equals, model.StateNormal)
+
+	// the changefeed paused by the user isn't resumed by the probes
+	info.AdminJobType = model.AdminStop
+	info.Error = position.Error
+	c.Assert(s.client.SaveChangeFeedInfo(ctx, info, "paused"), check.IsNil)
+	cf.status.AdminJobType = model.AdminStop
+	c.Assert(s.client.PutChangeFeedStatus(ctx, "paused", cf.status), check.IsNil)
+	owner.markPausedByDownstream("paused", info, now)
+	probe(now.Add(5 * time.Second))
+	c.Assert(owner.EnqueueJob(model.AdminJob{CfID: "paused", Type: model.AdminStop}), check.IsNil)
+	c.Assert(owner.handleAdminJob(ctx), check.IsNil)
+	c.Assert(owner.pausedByDownstream, check.HasLen, 0)
+	probe(now.Add(10 * time.Second))
+	c.Assert(owner.adminJobs, check.HasLen, 0)
+	_, _, state, err = owner.collectChangefeedInfo(ctx, "paused")
+	c.Assert(err, check.IsNil)
+	c.Assert(state, check.Equals, model.StateStopped)
+}
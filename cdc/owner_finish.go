@@ -0,0 +1,62 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"math"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/pkg/config"
+	"go.uber.org/zap"
+)
+
+// downstreamCaughtUp returns whether the downstream is verified to have applied all the events
+// before the target ts, the changefeed whose checkpoint ts reaches the target ts is finished only
+// after that. The checkpoint ts isn't enough alone since it jumps to the resolved ts if no table
+// is scheduled and no processor reports its position yet.
+func (c *changeFeed) downstreamCaughtUp(ctx context.Context) bool {
+	verification := c.info.Config.FinishVerification
+	if verification == config.FinishVerificationNone {
+		return true
+	}
+	if len(c.orphanTables) > 0 || len(c.toCleanTables) > 0 || len(c.moveTableJobs) > 0 {
+		return false
+	}
+	for captureID, status := range c.taskStatus {
+		if status.AppliedTs() != math.MaxUint64 {
+			// some table operations are still unapplied
+			return false
+		}
+		if len(status.Tables) == 0 {
+			continue
+		}
+		position, ok := c.taskPositions[captureID]
+		if !ok || position.CheckPointTs < c.targetTs {
+			log.Debug("wait for the sink of the processor to flush the target ts",
+				zap.String("changefeed", c.id), zap.String("captureID", captureID))
+			return false
+		}
+	}
+	if verification == config.FinishVerificationAcked && !c.targetTsAcked {
+		// the checkpoint message is written synchronously to all the partitions by the MQ sinks
+		if err := c.sink.EmitCheckpointTs(ctx, c.targetTs); err != nil {
+			log.Warn("emit the target ts to the sink failed, retry later",
+				zap.String("changefeed", c.id), zap.Uint64("targetTs", c.targetTs), zap.Error(err))
+			return false
+		}
+		c.targetTsAcked = true
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer/memory"
+	"github.com/pingcap/ticdc/pkg/config"
+)
+
+func (s *ownerSuite) TestFinishWaitsForSinkFlushed(c *check.C) {
+	const targetTs = 1000
+	cf := s.newPriorityTestChangefeed(c, "bounded", config.PriorityClassNormal, targetTs)
+	cf.info.TargetTs = targetTs
+	cf.targetTs = targetTs
+	cf.taskStatus = model.ProcessorsInfos{
+		"capture-1": {Tables: map[model.TableID]*model.TableReplicaInfo{1: {StartTs: 500}}},
+		"capture-2": {Tables: map[model.TableID]*model.TableReplicaInfo{}},
+	}
+	owner := &Owner{changeFeeds: map[model.ChangeFeedID]*changeFeed{"bounded": cf}}
+	finishJobs := func() int {
+		owner.adminJobs = nil
+		c.Assert(owner.checkClusterHealth(s.ctx), check.IsNil)
+		count := 0
+		for _, job := range owner.adminJobs {
+			c.Assert(job.Type, check.Equals, model.AdminFinish)
+			count++
+		}
+		return count
+	}
+
+	// the checkpoint ts reaches the target ts before any processor reports its flushed ts
+	c.Assert(finishJobs(), check.Equals, 0)
+	cf.taskPositions = map[model.CaptureID]*model.TaskPosition{"capture-1": {CheckPointTs: 900, ResolvedTs: targetTs}}
+	c.Assert(finishJobs(), check.Equals, 0)
+
+	// the table to be moved isn't flushed by the new processor yet
+	cf.taskPositions["capture-1"].CheckPointTs = targetTs
+	cf.taskStatus["capture-2"].Operation = map[model.TableID]*model.TableOperation{
+		2: {BoundaryTs: 800, Status: model.OperDispatched},
+	}
+	c.Assert(finishJobs(), check.Equals, 0)
+	cf.taskStatus["capture-2"].Operation = nil
+	c.Assert(finishJobs(), check.Equals, 1)
+
+	// the verification is disabled
+	cf.taskPositions["capture-1"].CheckPointTs = 900
+	cf.info.Config.FinishVerification = config.FinishVerificationNone
+	c.Assert(finishJobs(), check.Equals, 1)
+}
+
+func (s *ownerSuite) TestFinishWaitsForTargetTsAcked(c *check.C) {
+	const targetTs = 1000
+	defer memory.RemoveQueue("finish-test")
+	cf := s.newPriorityTestChangefeed(c, "bounded", config.PriorityClassNormal, targetTs)
+	cf.info.TargetTs = targetTs
+	cf.info.Config.FinishVerification = config.FinishVerificationAcked
+	cf.targetTs = targetTs
+	var err error
+	cf.sink, err = sink.NewSink(s.ctx, "bounded", "memory://finish-test?protocol=default&partition-num=2",
+		nil, cf.info.Config, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+	defer cf.sink.Close()
+	owner := &Owner{changeFeeds: map[model.ChangeFeedID]*changeFeed{"bounded": cf}}
+
+	c.Assert(owner.checkClusterHealth(s.ctx), check.IsNil)
+	c.Assert(owner.adminJobs, check.HasLen, 1)
+	c.Assert(cf.targetTsAcked, check.IsTrue)
+	queue, ok := memory.LookupQueue("finish-test")
+	c.Assert(ok, check.IsTrue)
+	msgs := queue.Poll(16)
+	c.Assert(msgs, check.HasLen, 2)
+	for _, msg := range msgs {
+		decoder, err := codec.NewJSONEventBatchDecoder(msg.Key, msg.Value)
+		c.Assert(err, check.IsNil)
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeResolved)
+		ts, err := decoder.NextResolvedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(ts, check.Equals, uint64(targetTs))
+	}
+
+	// the target ts is emitted only once
+	c.Assert(owner.checkClusterHealth(s.ctx), check.IsNil)
+	c.Assert(queue.Poll(16), check.HasLen, 0)
+}
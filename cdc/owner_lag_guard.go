@@ -0,0 +1,99 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"go.uber.org/zap"
+)
+
+var (
+	// lagPauseSustainedDuration is how long the lag of a changefeed must keep exceeding
+	// the MaxLagBeforePause before the changefeed is paused
+	lagPauseSustainedDuration = 5 * time.Minute
+	// initialScanGracePeriod is how long the lag of a changefeed is ignored if its checkpoint
+	// hasn't advanced since it started, a large initial scan holds the checkpoint for a while.
+	initialScanGracePeriod = 30 * time.Minute
+)
+
+// inInitialScan returns whether the changefeed may be still scanning the initial data
+func (c *changeFeed) inInitialScan(now time.Time) bool {
+	startedAt := time.Unix(0, c.status.StartedAt*1e6)
+	return c.status.CheckpointTs <= c.status.StartCheckpointTs && now.Sub(startedAt) < initialScanGracePeriod
+}
+
+// restoreInitialScan restores the start of the initial scan recorded in the status, so the
+// initial scan isn't started over if the changefeed is loaded again by a new owner
+func (c *changeFeed) restoreInitialScan(status *model.ChangeFeedStatus) {
+	// the status written by the old versions doesn't record it
+	if status.StartedAt == 0 {
+		return
+	}
+	c.status.StartedAt = status.StartedAt
+	c.status.StartCheckpointTs = status.StartCheckpointTs
+}
+
+// isPausedForLag returns whether the changefeed is paused since its checkpoint lags behind the MaxLagBeforePause
+func isPausedForLag(runningErr *model.RunningError) bool {
+	return runningErr != nil && runningErr.Code == string(cerror.ErrChangefeedLagTooLong.RFCCode())
+}
+
+// pauseLaggingChangefeeds pauses the changefeeds whose checkpoint lag exceeds the
+// MaxLagBeforePause of them for lagPauseSustainedDuration.
+func (o *Owner) pauseLaggingChangefeeds(ctx context.Context, now time.Time) error {
+	for _, cf := range o.changeFeeds {
+		maxLag := cf.info.MaxLagBeforePause
+		if maxLag <= 0 {
+			continue
+		}
+		lag := now.Sub(oracle.GetTimeFromTS(cf.status.CheckpointTs))
+		if lag <= maxLag || cf.inInitialScan(now) {
+			cf.lagExceededAt = time.Time{}
+			continue
+		}
+		if cf.lagExceededAt.IsZero() {
+			cf.lagExceededAt = now
+			log.Info("the checkpoint lag of changefeed exceeds the max lag",
+				zap.String("changefeed", cf.id), zap.Duration("lag", lag), zap.Duration("max-lag", maxLag))
+			continue
+		}
+		if now.Sub(cf.lagExceededAt) < lagPauseSustainedDuration {
+			continue
+		}
+		cf.lagExceededAt = time.Time{}
+		err := cerror.ErrChangefeedLagTooLong.GenWithStackByArgs(cf.id, maxLag, lagPauseSustainedDuration)
+		log.Warn("pause the changefeed lagging behind for a long time",
+			zap.String("changefeed", cf.id), zap.Duration("lag", lag), zap.Duration("max-lag", maxLag))
+		err = o.EnqueueJob(model.AdminJob{
+			CfID: cf.id,
+			Type: model.AdminStop,
+			Error: &model.RunningError{
+				Addr:    util.CaptureAddrFromCtx(ctx),
+				Code:    string(cerror.ErrChangefeedLagTooLong.RFCCode()),
+				Message: err.Error(),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
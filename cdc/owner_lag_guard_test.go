@@ -0,0 +1,130 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+)
+
+func (s *ownerSuite) TestPauseLaggingChangefeeds(c *check.C) {
+	ctx := s.ctx
+	start := time.Now()
+	tsAt := func(t time.Time) uint64 {
+		return oracle.ComposeTS(oracle.GetPhysical(t), 0)
+	}
+	newChangefeed := func(id string, maxLag time.Duration, checkpoint time.Time) *changeFeed {
+		cf := s.newPriorityTestChangefeed(c, id, config.PriorityClassNormal, tsAt(checkpoint))
+		cf.info.MaxLagBeforePause = maxLag
+		cf.status.StartedAt = start.UnixNano() / 1e6
+		cf.status.StartCheckpointTs = cf.status.CheckpointTs
+		return cf
+	}
+	owner := &Owner{
+		changeFeeds: map[model.ChangeFeedID]*changeFeed{
+			// the checkpoint of it advances but keeps lagging behind
+			"runaway": newChangefeed("runaway", time.Hour, start.Add(-2*time.Hour)),
+			// the checkpoint of it stays at the start ts during the initial scan
+			"scanning": newChangefeed("scanning", time.Hour, start.Add(-48*time.Hour)),
+			"disabled": newChangefeed("disabled", 0, start.Add(-48*time.Hour)),
+			"healthy":  newChangefeed("healthy", time.Hour, start),
+		},
+		stoppedFeeds: make(map[model.ChangeFeedID]*model.ChangeFeedStatus),
+		gcShedFeeds:  make(map[model.ChangeFeedID]struct{}),
+		pdClient:     &safepointPDClient{},
+		cfRWriter:    s.client,
+		etcdClient:   s.client,
+	}
+	tick := func(now time.Time) []model.AdminJob {
+		// the checkpoint of the healthy and the runaway changefeeds advance with the time
+		owner.changeFeeds["healthy"].status.CheckpointTs = tsAt(now)
+		if cf, ok := owner.changeFeeds["runaway"]; ok {
+			cf.status.CheckpointTs = tsAt(now.Add(-2 * time.Hour))
+		}
+		c.Assert(owner.pauseLaggingChangefeeds(ctx, now), check.IsNil)
+		owner.adminJobsLock.Lock()
+		jobs := append([]model.AdminJob(nil), owner.adminJobs...)
+		owner.adminJobsLock.Unlock()
+		c.Assert(owner.handleAdminJob(ctx), check.IsNil)
+		return jobs
+	}
+
+	// the lag must be sustained before the changefeed is paused
+	c.Assert(tick(start.Add(time.Minute)), check.HasLen, 0)
+	c.Assert(tick(start.Add(lagPauseSustainedDuration)), check.HasLen, 0)
+	jobs := tick(start.Add(time.Minute + lagPauseSustainedDuration))
+	c.Assert(jobs, check.HasLen, 1)
+	c.Assert(jobs[0].CfID, check.Equals, "runaway")
+	c.Assert(jobs[0].Type, check.Equals, model.AdminStop)
+	c.Assert(owner.changeFeeds, check.Not(check.HasKey), "runaway")
+	c.Assert(owner.stoppedFeeds, check.HasKey, "runaway")
+	// the paused changefeed doesn't hold the GC safepoint
+	c.Assert(owner.holdsGCSafepoint("runaway"), check.IsFalse)
+	info, err := s.client.GetChangeFeedInfo(ctx, "runaway")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.AdminJobType, check.Equals, model.AdminStop)
+	c.Assert(info.Error.Code, check.Equals, string(cerror.ErrChangefeedLagTooLong.RFCCode()))
+	c.Assert(info.Error.Message, check.Matches, ".*changefeed runaway is paused since its checkpoint lags behind 1h0m0s.*")
+
+	// the changefeed in the initial scan isn't paused until the grace period ends
+	c.Assert(tick(start.Add(initialScanGracePeriod-time.Minute)), check.HasLen, 0)
+	c.Assert(owner.changeFeeds["scanning"].lagExceededAt.IsZero(), check.IsTrue)
+	c.Assert(tick(start.Add(initialScanGracePeriod)), check.HasLen, 0)
+	jobs = tick(start.Add(initialScanGracePeriod + lagPauseSustainedDuration))
+	c.Assert(jobs, check.HasLen, 1)
+	c.Assert(jobs[0].CfID, check.Equals, "scanning")
+
+	// the lag recovered within the sustained period doesn't pause the changefeed
+	cf := owner.changeFeeds["healthy"]
+	now := start.Add(time.Hour)
+	cf.status.CheckpointTs = tsAt(now.Add(-2 * time.Hour))
+	c.Assert(owner.pauseLaggingChangefeeds(ctx, now), check.IsNil)
+	c.Assert(cf.lagExceededAt, check.Equals, now)
+	c.Assert(tick(now.Add(time.Minute)), check.HasLen, 0)
+	c.Assert(cf.lagExceededAt.IsZero(), check.IsTrue)
+	c.Assert(tick(now.Add(time.Minute+lagPauseSustainedDuration)), check.HasLen, 0)
+	c.Assert(owner.changeFeeds, check.HasLen, 2)
+	c.Assert(owner.changeFeeds, check.HasKey, "disabled")
+}
+
+func (s *ownerSuite) TestRestoreInitialScan(c *check.C) {
+	start := time.Now()
+	checkpointTs := oracle.ComposeTS(oracle.GetPhysical(start.Add(-48*time.Hour)), 0)
+	status := &model.ChangeFeedStatus{
+		CheckpointTs:      checkpointTs,
+		StartedAt:         start.Add(-initialScanGracePeriod).UnixNano() / 1e6,
+		StartCheckpointTs: checkpointTs,
+	}
+
+	// the changefeed loaded again by a new owner doesn't start over the grace period
+	cf := s.newPriorityTestChangefeed(c, "scanning", config.PriorityClassNormal, checkpointTs)
+	cf.status.StartedAt = start.UnixNano() / 1e6
+	cf.status.StartCheckpointTs = checkpointTs
+	c.Assert(cf.inInitialScan(start), check.IsTrue)
+	cf.restoreInitialScan(status)
+	c.Assert(cf.status.StartedAt, check.Equals, status.StartedAt)
+	c.Assert(cf.inInitialScan(start), check.IsFalse)
+
+	// the status written by the old versions doesn't record the start
+	cf = s.newPriorityTestChangefeed(c, "upgraded", config.PriorityClassNormal, checkpointTs)
+	cf.status.StartedAt = start.UnixNano() / 1e6
+	cf.status.StartCheckpointTs = checkpointTs
+	cf.restoreInitialScan(&model.ChangeFeedStatus{CheckpointTs: checkpointTs})
+	c.Assert(cf.inInitialScan(start), check.IsTrue)
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// changefeedMetaGCInterval is the interval the owner looks for the expired changefeed metadata
+var changefeedMetaGCInterval = 10 * time.Minute
+
+// holdsGCSafepoint returns whether the checkpoint of the changefeed is taken into
+// account when the owner updates the GC safepoint of upstream
+func (o *Owner) holdsGCSafepoint(id model.ChangeFeedID) bool {
+	if _, ok := o.changeFeeds[id]; ok {
+		return true
+	}
+	if _, ok := o.stoppedFeeds[id]; ok {
+		_, shed := o.gcShedFeeds[id]
+		return !shed
+	}
+	return false
+}
+
+// deadChangefeedTombstone returns the tombstone of a removed, finished or failed changefeed,
+// nil is returned for the other changefeeds. The removed time is zero if it is unknown.
+func deadChangefeedTombstone(
+	id model.ChangeFeedID, info *model.ChangeFeedInfo, status *model.ChangeFeedStatus,
+) *model.ChangeFeedTombstone {
+	tombstone := &model.ChangeFeedTombstone{ID: id}
+	switch {
+	case status != nil && (status.AdminJobType == model.AdminRemove || status.AdminJobType == model.AdminFinish):
+		tombstone.State = model.StateRemoved
+		if status.AdminJobType == model.AdminFinish {
+			tombstone.State = model.StateFinished
+		}
+		tombstone.CheckpointTs = status.CheckpointTs
+		if status.RemovedAt > 0 {
+			tombstone.RemovedTime = time.Unix(0, status.RemovedAt*1e6)
+		}
+	case info != nil && info.State == model.StateFailed:
+		tombstone.State = model.StateFailed
+		tombstone.CheckpointTs = info.GetCheckpointTs(status)
+		if len(info.ErrorHis) > 0 {
+			tombstone.RemovedTime = time.Unix(0, info.ErrorHis[len(info.ErrorHis)-1]*1e6)
+		}
+	default:
+		return nil
+	}
+	return tombstone
+}
+
+// gcChangefeedMeta deletes the keys of the removed, finished and failed changefeeds once they
+// are dead for longer than changefeedMetaRetention. A tombstone of each changefeed is written
+// into the changefeed history, and the changefeeds holding the GC safepoint are never touched.
+func (o *Owner) gcChangefeedMeta(ctx context.Context, now time.Time) error {
+	if o.changefeedMetaRetention <= 0 || now.Sub(o.lastChangefeedMetaGC) < changefeedMetaGCInterval {
+		return nil
+	}
+	o.lastChangefeedMetaGC = now
+
+	_, rawInfos, err := o.etcdClient.GetChangeFeeds(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	statuses, err := o.etcdClient.GetAllChangeFeedStatus(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ids := make(map[model.ChangeFeedID]struct{}, len(rawInfos)+len(statuses))
+	for id := range rawInfos {
+		ids[id] = struct{}{}
+	}
+	for id := range statuses {
+		ids[id] = struct{}{}
+	}
+
+	deadFeeds := make(map[model.ChangeFeedID]struct{})
+	for id := range ids {
+		if o.holdsGCSafepoint(id) {
+			continue
+		}
+		var info *model.ChangeFeedInfo
+		var infoRevision int64
+		if rawKv, ok := rawInfos[id]; ok {
+			info = &model.ChangeFeedInfo{}
+			if err := info.Unmarshal(rawKv.Value); err != nil {
+				// the unparseable keys are quarantined by the reconciliation
+				continue
+			}
+			infoRevision = rawKv.ModRevision
+		}
+		tombstone := deadChangefeedTombstone(id, info, statuses[id])
+		if tombstone == nil {
+			continue
+		}
+		deadFeeds[id] = struct{}{}
+		if tombstone.RemovedTime.IsZero() {
+			// the removed time is not recorded by older versions, start the retention from now
+			if _, ok := o.deadFeedsSeen[id]; !ok {
+				o.deadFeedsSeen[id] = now
+			}
+			tombstone.RemovedTime = o.deadFeedsSeen[id]
+		}
+		if now.Sub(tombstone.RemovedTime) < o.changefeedMetaRetention {
+			continue
+		}
+
+		var statusRevision int64
+		if statuses[id] != nil {
+			_, statusRevision, err = o.etcdClient.GetChangeFeedStatus(ctx, id)
+			if err != nil && cerror.ErrChangeFeedNotExists.NotEqual(err) {
+				return errors.Trace(err)
+			}
+		}
+		ok, err := o.etcdClient.DeleteChangeFeedMeta(ctx, tombstone, infoRevision, statusRevision)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !ok {
+			log.Info("changefeed metadata modified during garbage collection, skip it", zap.String("changefeed", id))
+			continue
+		}
+		log.Info("garbage-collect the metadata of the dead changefeed",
+			zap.String("changefeed", id), zap.String("state", string(tombstone.State)),
+			zap.Uint64("checkpoint-ts", tombstone.CheckpointTs), zap.Time("removed-time", tombstone.RemovedTime))
+		delete(deadFeeds, id)
+	}
+	for id := range o.deadFeedsSeen {
+		if _, ok := deadFeeds[id]; !ok {
+			delete(o.deadFeedsSeen, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.etcd.io/etcd/clientv3"
+)
+
+func (s *ownerSuite) TestGCChangefeedMeta(c *check.C) {
+	ctx := s.ctx
+	now := time.Now()
+	retention := 7 * 24 * time.Hour
+	msBefore := func(d time.Duration) int64 {
+		return now.Add(-d).UnixNano() / 1e6
+	}
+	putInfo := func(id model.ChangeFeedID, info *model.ChangeFeedInfo) {
+		info.Config = config.GetDefaultReplicaConfig()
+		c.Assert(s.client.SaveChangeFeedInfo(ctx, info, id), check.IsNil)
+	}
+	putStatus := func(id model.ChangeFeedID, status *model.ChangeFeedStatus) {
+		c.Assert(s.client.PutChangeFeedStatus(ctx, id, status), check.IsNil)
+	}
+	putStatus("removed-expired", &model.ChangeFeedStatus{
+		CheckpointTs: 100, AdminJobType: model.AdminRemove, RemovedAt: msBefore(8 * 24 * time.Hour),
+	})
+	_, err := s.client.Client.Put(ctx, kv.GetEtcdKeyTaskPosition("removed-expired", "capture-1"), "{}")
+	c.Assert(err, check.IsNil)
+	c.Assert(s.client.PutEmittedDDLs(ctx, "removed-expired", model.EmittedDDLs{1: 90}), check.IsNil)
+	putStatus("finished-expired", &model.ChangeFeedStatus{
+		CheckpointTs: 200, AdminJobType: model.AdminFinish, RemovedAt: msBefore(7*24*time.Hour + time.Minute),
+	})
+	putStatus("removed-recently", &model.ChangeFeedStatus{
+		CheckpointTs: 300, AdminJobType: model.AdminRemove, RemovedAt: msBefore(24 * time.Hour),
+	})
+	putInfo("failed-expired", &model.ChangeFeedInfo{
+		StartTs: 400, State: model.StateFailed, ErrorHis: []int64{msBefore(10 * 24 * time.Hour)},
+	})
+	// the changefeed holds the gc safepoint
+	putInfo("failed-stopped", &model.ChangeFeedInfo{
+		StartTs: 500, State: model.StateFailed, ErrorHis: []int64{msBefore(10 * 24 * time.Hour)},
+	})
+	putStatus("failed-stopped", &model.ChangeFeedStatus{CheckpointTs: 500, AdminJobType: model.AdminStop})
+	putInfo("normal", &model.ChangeFeedInfo{StartTs: 600})
+	putStatus("normal", &model.ChangeFeedStatus{CheckpointTs: 600})
+	// the removed time is unknown
+	putStatus("removed-legacy", &model.ChangeFeedStatus{CheckpointTs: 700, AdminJobType: model.AdminRemove})
+
+	owner := &Owner{
+		changeFeeds:             make(map[model.ChangeFeedID]*changeFeed),
+		stoppedFeeds:            map[model.ChangeFeedID]*model.ChangeFeedStatus{"failed-stopped": {CheckpointTs: 500}},
+		gcShedFeeds:             make(map[model.ChangeFeedID]struct{}),
+		deadFeedsSeen:           make(map[model.ChangeFeedID]time.Time),
+		etcdClient:              s.client,
+		changefeedMetaRetention: retention,
+	}
+	c.Assert(owner.gcChangefeedMeta(ctx, now), check.IsNil)
+
+	exists := func(id model.ChangeFeedID) bool {
+		_, err := s.client.GetChangeFeedInfo(ctx, id)
+		if err == nil {
+			return true
+		}
+		c.Assert(cerror.ErrChangeFeedNotExists.Equal(err), check.IsTrue)
+		_, _, err = s.client.GetChangeFeedStatus(ctx, id)
+		if err == nil {
+			return true
+		}
+		c.Assert(cerror.ErrChangeFeedNotExists.Equal(err), check.IsTrue)
+		return false
+	}
+	c.Assert(exists("removed-expired"), check.IsFalse)
+	c.Assert(exists("finished-expired"), check.IsFalse)
+	c.Assert(exists("failed-expired"), check.IsFalse)
+	c.Assert(exists("removed-recently"), check.IsTrue)
+	c.Assert(exists("failed-stopped"), check.IsTrue)
+	c.Assert(exists("normal"), check.IsTrue)
+	c.Assert(exists("removed-legacy"), check.IsTrue)
+	resp, err := s.client.Client.Get(ctx, kv.TaskKeyPrefix+"/", clientv3.WithPrefix())
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Kvs, check.HasLen, 0)
+	emitted, err := s.client.GetEmittedDDLs(ctx, "removed-expired")
+	c.Assert(err, check.IsNil)
+	c.Assert(emitted, check.HasLen, 0)
+
+	history, _, err := s.client.GetChangeFeedHistory(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(history.Tombstones, check.HasLen, 3)
+	tombstones := make(map[model.ChangeFeedID]*model.ChangeFeedTombstone)
+	for _, tombstone := range history.Tombstones {
+		tombstones[tombstone.ID] = tombstone
+	}
+	c.Assert(tombstones["removed-expired"].State, check.Equals, model.StateRemoved)
+	c.Assert(tombstones["removed-expired"].CheckpointTs, check.Equals, uint64(100))
+	c.Assert(tombstones["removed-expired"].RemovedTime.UnixNano()/1e6, check.Equals, msBefore(8*24*time.Hour))
+	c.Assert(tombstones["finished-expired"].State, check.Equals, model.StateFinished)
+	c.Assert(tombstones["finished-expired"].CheckpointTs, check.Equals, uint64(200))
+	c.Assert(tombstones["failed-expired"].State, check.Equals, model.StateFailed)
+	c.Assert(tombstones["failed-expired"].CheckpointTs, check.Equals, uint64(400))
+
+	// the garbage collection runs every changefeedMetaGCInterval
+	later := now.Add(retention - time.Second)
+	owner.lastChangefeedMetaGC = later.Add(-time.Minute)
+	c.Assert(owner.gcChangefeedMeta(ctx, later), check.IsNil)
+	c.Assert(exists("removed-recently"), check.IsTrue)
+	owner.lastChangefeedMetaGC = time.Time{}
+	c.Assert(owner.gcChangefeedMeta(ctx, later), check.IsNil)
+	c.Assert(exists("removed-recently"), check.IsFalse)
+	// the retention of the changefeed whose removed time is unknown starts from the first check
+	c.Assert(exists("removed-legacy"), check.IsTrue)
+	c.Assert(owner.deadFeedsSeen, check.DeepEquals, map[model.ChangeFeedID]time.Time{"removed-legacy": now})
+	owner.lastChangefeedMetaGC = time.Time{}
+	c.Assert(owner.gcChangefeedMeta(ctx, now.Add(retention+time.Minute)), check.IsNil)
+	c.Assert(exists("removed-legacy"), check.IsFalse)
+	c.Assert(owner.deadFeedsSeen, check.HasLen, 0)
+
+	// the changefeed holding the gc safepoint is deleted after it is shed by the gc guard
+	c.Assert(exists("failed-stopped"), check.IsTrue)
+	owner.gcShedFeeds["failed-stopped"] = struct{}{}
+	owner.lastChangefeedMetaGC = time.Time{}
+	c.Assert(owner.gcChangefeedMeta(ctx, now), check.IsNil)
+	c.Assert(exists("failed-stopped"), check.IsFalse)
+	c.Assert(exists("normal"), check.IsTrue)
+
+	history, _, err = s.client.GetChangeFeedHistory(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(history.Tombstones, check.HasLen, 6)
+	c.Assert(history.Tombstones[5].ID, check.Equals, "failed-stopped")
+}
+
+func (s *ownerSuite) TestDeleteChangeFeedMetaConflict(c *check.C) {
+	ctx := s.ctx
+	status := &model.ChangeFeedStatus{CheckpointTs: 100, AdminJobType: model.AdminRemove}
+	c.Assert(s.client.PutChangeFeedStatus(ctx, "removed", status), check.IsNil)
+	_, revision, err := s.client.GetChangeFeedStatus(ctx, "removed")
+	c.Assert(err, check.IsNil)
+	// the changefeed is created again with the same ID
+	c.Assert(s.client.SaveChangeFeedInfo(ctx, &model.ChangeFeedInfo{Config: config.GetDefaultReplicaConfig()}, "removed"), check.IsNil)
+	tombstone := &model.ChangeFeedTombstone{ID: "removed", CheckpointTs: 100, State: model.StateRemoved}
+	ok, err := s.client.DeleteChangeFeedMeta(ctx, tombstone, 0, revision)
+	c.Assert(err, check.IsNil)
+	c.Assert(ok, check.IsFalse)
+	_, err = s.client.GetChangeFeedInfo(ctx, "removed")
+	c.Assert(err, check.IsNil)
+	history, _, err := s.client.GetChangeFeedHistory(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(history.Tombstones, check.HasLen, 0)
+}
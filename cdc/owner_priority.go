@@ -0,0 +1,140 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"go.uber.org/zap"
+)
+
+// priorityClass returns the priority class of the changefeed
+func (c *changeFeed) priorityClass() string {
+	if c.info.Config == nil || c.info.Config.PriorityClass == "" {
+		return config.PriorityClassNormal
+	}
+	return c.info.Config.PriorityClass
+}
+
+// changefeedIDsByPriority returns the IDs of the running changefeeds, the changefeeds of
+// higher priority come first so they are scheduled before the others.
+func (o *Owner) changefeedIDsByPriority() []model.ChangeFeedID {
+	ids := make([]model.ChangeFeedID, 0, len(o.changeFeeds))
+	for id := range o.changeFeeds {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ri := config.PriorityRank(o.changeFeeds[ids[i]].priorityClass())
+		rj := config.PriorityRank(o.changeFeeds[ids[j]].priorityClass())
+		if ri != rj {
+			return ri > rj
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// shedChangefeedOnGCDanger pauses a changefeed blocking the GC of upstream if the GC safepoint
+// held by the owner lags behind gcGuardLag. The changefeeds of the lowest priority class are paused
+// first, the most lagging one first among the ones of the same class, and at most one changefeed
+// is paused in a round, so no more changefeed is paused once the safepoint is no longer in danger.
+// The critical changefeeds are never paused, nor the ones which don't move the safepoint forward
+// since a changefeed which can't be paused holds it behind them.
+func (o *Owner) shedChangefeedOnGCDanger(ctx context.Context, now time.Time) error {
+	if o.gcGuardLag <= 0 {
+		return nil
+	}
+	dangerTs := oracle.ComposeTS(oracle.GetPhysical(now.Add(-o.gcGuardLag)), 0)
+	// floorTs is the lowest ts held by the changefeeds which can't be paused
+	floorTs := uint64(math.MaxUint64)
+	for id, status := range o.stoppedFeeds {
+		if o.holdsGCSafepoint(id) && status.CheckpointTs < floorTs {
+			floorTs = status.CheckpointTs
+		}
+	}
+	type candidate struct {
+		cf   *changeFeed
+		ts   model.Ts
+		rank int
+	}
+	candidates := make([]candidate, 0, len(o.changeFeeds))
+	for _, cf := range o.changeFeeds {
+		ts := cf.gcSafepointTs()
+		class := cf.priorityClass()
+		if class == config.PriorityClassCritical {
+			if ts < floorTs {
+				floorTs = ts
+			}
+			continue
+		}
+		candidates = append(candidates, candidate{cf: cf, ts: ts, rank: config.PriorityRank(class)})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].rank != candidates[j].rank {
+			return candidates[i].rank < candidates[j].rank
+		}
+		if candidates[i].ts != candidates[j].ts {
+			return candidates[i].ts < candidates[j].ts
+		}
+		return candidates[i].cf.id < candidates[j].cf.id
+	})
+	// the safepoint is in danger only if it lags behind, pausing the changefeeds holding the
+	// safepoint at or after the floor doesn't move it forward
+	var victim *changeFeed
+	var victimTs model.Ts
+	for _, c := range candidates {
+		if c.ts < dangerTs && c.ts < floorTs {
+			victim, victimTs = c.cf, c.ts
+			break
+		}
+	}
+	if victim == nil {
+		return nil
+	}
+	lag := now.Sub(oracle.GetTimeFromTS(victimTs))
+	err := cerror.ErrChangefeedShedByGCGuard.GenWithStackByArgs(victim.id, lag)
+	log.Warn("pause the changefeed blocking the GC of upstream",
+		zap.String("changefeed", victim.id),
+		zap.String("priority-class", victim.priorityClass()),
+		zap.Duration("lag", lag))
+	return o.EnqueueJob(model.AdminJob{
+		CfID: victim.id,
+		Type: model.AdminStop,
+		Error: &model.RunningError{
+			Addr:    util.CaptureAddrFromCtx(ctx),
+			Code:    string(cerror.ErrChangefeedShedByGCGuard.RFCCode()),
+			Message: err.Error(),
+		},
+	})
+}
+
+// isShedByGCGuard returns whether the changefeed is paused by the GC guard
+func isShedByGCGuard(runningErr *model.RunningError) bool {
+	return runningErr != nil && runningErr.Code == string(cerror.ErrChangefeedShedByGCGuard.RFCCode())
+}
+
+// releasesGCSafepoint returns whether the stopped changefeed doesn't hold the GC safepoint of upstream,
+// the changefeeds paused for blocking the GC or lagging behind would block it again otherwise
+func releasesGCSafepoint(runningErr *model.RunningError) bool {
+	return isShedByGCGuard(runningErr) || isPausedForLag(runningErr)
+}
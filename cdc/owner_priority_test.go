@@ -0,0 +1,142 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	pd "github.com/tikv/pd/client"
+	"golang.org/x/sync/errgroup"
+)
+
+// safepointPDClient records the last gc safepoint updated by the owner
+type safepointPDClient struct {
+	pd.Client
+	safepoint uint64
+}
+
+func (m *safepointPDClient) UpdateServiceGCSafePoint(ctx context.Context, serviceID string, ttl int64, safePoint uint64) (uint64, error) {
+	m.safepoint = safePoint
+	return safePoint, nil
+}
+
+func (s *ownerSuite) newPriorityTestChangefeed(c *check.C, id, class string, checkpointTs uint64) *changeFeed {
+	replicaConfig := config.GetDefaultReplicaConfig()
+	replicaConfig.PriorityClass = class
+	cancelCtx, cancel := context.WithCancel(s.ctx)
+	errg, _ := errgroup.WithContext(cancelCtx)
+	cfSink, err := sink.NewSink(s.ctx, id, "blackhole://", nil, replicaConfig, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+	return &changeFeed{
+		id:         id,
+		info:       &model.ChangeFeedInfo{SinkURI: "blackhole://", Config: replicaConfig},
+		status:     &model.ChangeFeedStatus{CheckpointTs: checkpointTs},
+		taskStatus: model.ProcessorsInfos{},
+		ddlHandler: &ddlHandler{cancel: cancel, wg: errg},
+		sink:       cfSink,
+		etcdCli:    s.client,
+	}
+}
+
+func (s *ownerSuite) TestShedChangefeedOnGCDanger(c *check.C) {
+	ctx := s.ctx
+	now := time.Now()
+	tsBefore := func(lag time.Duration) uint64 {
+		return oracle.ComposeTS(oracle.GetPhysical(now.Add(-lag)), 0)
+	}
+	pdCli := &safepointPDClient{}
+	owner := &Owner{
+		changeFeeds: map[model.ChangeFeedID]*changeFeed{
+			"critical":    s.newPriorityTestChangefeed(c, "critical", config.PriorityClassCritical, tsBefore(3*time.Hour)),
+			"normal":      s.newPriorityTestChangefeed(c, "normal", config.PriorityClassNormal, tsBefore(2*time.Hour)),
+			"best-effort": s.newPriorityTestChangefeed(c, "best-effort", config.PriorityClassBestEffort, tsBefore(4*time.Hour)),
+			"healthy":     s.newPriorityTestChangefeed(c, "healthy", config.PriorityClassBestEffort, tsBefore(time.Minute)),
+		},
+		stoppedFeeds: make(map[model.ChangeFeedID]*model.ChangeFeedStatus),
+		gcShedFeeds:  make(map[model.ChangeFeedID]struct{}),
+		pdClient:     pdCli,
+		cfRWriter:    s.client,
+		etcdClient:   s.client,
+		gcGuardLag:   time.Hour,
+	}
+	c.Assert(owner.changefeedIDsByPriority(), check.DeepEquals,
+		[]model.ChangeFeedID{"critical", "normal", "best-effort", "healthy"})
+
+	shed := func() []model.AdminJob {
+		owner.gcSafepointLastUpdate = time.Time{}
+		c.Assert(owner.shedChangefeedOnGCDanger(ctx, now), check.IsNil)
+		owner.adminJobsLock.Lock()
+		jobs := append([]model.AdminJob(nil), owner.adminJobs...)
+		owner.adminJobsLock.Unlock()
+		c.Assert(owner.handleAdminJob(ctx), check.IsNil)
+		c.Assert(owner.flushChangeFeedInfos(ctx), check.IsNil)
+		return jobs
+	}
+
+	// the lagging changefeed of the lowest priority is shed first, and the gc safepoint moves forward
+	jobs := shed()
+	c.Assert(jobs, check.HasLen, 1)
+	c.Assert(jobs[0].CfID, check.Equals, "best-effort")
+	c.Assert(jobs[0].Type, check.Equals, model.AdminStop)
+	c.Assert(isShedByGCGuard(jobs[0].Error), check.IsTrue)
+	c.Assert(owner.changeFeeds, check.Not(check.HasKey), "best-effort")
+	c.Assert(owner.stoppedFeeds, check.HasKey, "best-effort")
+	c.Assert(owner.gcShedFeeds, check.HasKey, "best-effort")
+	c.Assert(pdCli.safepoint, check.Equals, tsBefore(3*time.Hour))
+	info, err := s.client.GetChangeFeedInfo(ctx, "best-effort")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Error.Code, check.Equals, string(cerror.ErrChangefeedShedByGCGuard.RFCCode()))
+
+	// the normal one isn't shed since the critical one holds the gc safepoint behind it
+	c.Assert(shed(), check.HasLen, 0)
+	c.Assert(owner.changeFeeds, check.HasKey, "normal")
+	c.Assert(pdCli.safepoint, check.Equals, tsBefore(3*time.Hour))
+
+	// it's shed once the critical one catches up
+	owner.changeFeeds["critical"].status.CheckpointTs = tsBefore(time.Minute)
+	// the checkpoint holds the gc safepoint once it's written
+	c.Assert(owner.flushChangeFeedInfos(ctx), check.IsNil)
+	jobs = shed()
+	c.Assert(jobs, check.HasLen, 1)
+	c.Assert(jobs[0].CfID, check.Equals, "normal")
+	c.Assert(owner.stoppedFeeds, check.HasKey, "normal")
+	c.Assert(pdCli.safepoint, check.Equals, tsBefore(time.Minute))
+
+	// nothing is shed once the gc safepoint is no longer in danger
+	c.Assert(shed(), check.HasLen, 0)
+	c.Assert(owner.changeFeeds, check.HasLen, 2)
+	c.Assert(owner.changeFeeds, check.HasKey, "critical")
+	c.Assert(owner.changeFeeds, check.HasKey, "healthy")
+
+	// the changefeed paused by the user still holds the gc safepoint, and the lagging ones
+	// after it aren't shed
+	owner.stoppedFeeds["paused"] = &model.ChangeFeedStatus{CheckpointTs: tsBefore(5 * time.Hour)}
+	owner.changeFeeds["healthy"].status.CheckpointTs = tsBefore(2 * time.Hour)
+	c.Assert(shed(), check.HasLen, 0)
+	c.Assert(owner.changeFeeds, check.HasKey, "healthy")
+	c.Assert(pdCli.safepoint, check.Equals, tsBefore(5*time.Hour))
+
+	// nothing is shed if the gc guard is disabled
+	owner.gcGuardLag = 0
+	critical := owner.changeFeeds["critical"]
+	critical.info.Config.PriorityClass = config.PriorityClassBestEffort
+	c.Assert(shed(), check.HasLen, 0)
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ReleaseQuarantinedTable releases a table quarantined by the sink of the changefeed. The rows discarded
+// while the table is quarantined are not kept anywhere, so the table is re-scanned from the commit ts of
+// the first row failed to be written, which fails if the table can't be re-scanned from it. The discarded
+// rows are lost for good if discardRows is true, the table is released without being re-scanned.
+func (o *Owner) ReleaseQuarantinedTable(
+	ctx context.Context, changefeedID model.ChangeFeedID, tableID model.TableID, discardRows bool,
+) error {
+	tables, err := o.etcdClient.GetQuarantinedTables(ctx, changefeedID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	table, ok := tables[tableID]
+	if !ok {
+		return cerror.ErrTableNotQuarantined.GenWithStackByArgs(tableID, changefeedID)
+	}
+	if discardRows {
+		log.Warn("release the quarantined table without re-scanning it, the discarded rows are lost",
+			zap.String("changefeed", changefeedID), zap.Int64("tableID", tableID), zap.Uint64("commitTs", table.CommitTs))
+		return errors.Trace(o.etcdClient.DeleteQuarantinedTable(ctx, changefeedID, tableID))
+	}
+	fromTs := table.CommitTs - 1
+	if err := o.checkRescanTable(ctx, changefeedID, tableID, fromTs); err != nil {
+		return errors.Trace(err)
+	}
+	// the table is released before it's re-scanned, the processors sync the quarantined tables
+	// before adding the tables, so that the rows re-scanned are not discarded again
+	if err := o.etcdClient.DeleteQuarantinedTable(ctx, changefeedID, tableID); err != nil {
+		return errors.Trace(err)
+	}
+	o.queueRescanTable(changefeedID, tableID, fromTs)
+	return nil
+}
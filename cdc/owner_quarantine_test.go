@@ -0,0 +1,67 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/tidb/store/tikv"
+)
+
+func (s *ownerSuite) TestReleaseQuarantinedTable(c *check.C) {
+	ctx := s.ctx
+	const checkpointTs = 1000
+	cf := s.newPriorityTestChangefeed(c, "release-quarantined", config.PriorityClassNormal, checkpointTs)
+	owner := &Owner{
+		changeFeeds:           map[model.ChangeFeedID]*changeFeed{cf.id: cf},
+		manualScheduleCommand: make(map[model.ChangeFeedID][]*model.MoveTableJob),
+		etcdClient:            s.client,
+	}
+	info := &model.ChangeFeedInfo{SinkURI: "mysql://127.0.0.1:3306/", Config: config.GetDefaultReplicaConfig()}
+	c.Assert(s.client.SaveChangeFeedInfo(ctx, info, cf.id), check.IsNil)
+	c.Assert(s.client.PutChangeFeedStatus(ctx, cf.id, cf.status), check.IsNil)
+	_, err := s.client.Client.Put(ctx, tikv.GcSavedSafePoint, "200")
+	c.Assert(err, check.IsNil)
+	defer func(f func(*Owner, model.TableID, model.Ts) (model.Ts, bool, error)) {
+		rescanTableLastDDLTs = f
+	}(rescanTableLastDDLTs)
+	rescanTableLastDDLTs = func(_ *Owner, tableID model.TableID, ts model.Ts) (model.Ts, bool, error) {
+		return 300, true, nil
+	}
+	for _, table := range []*model.QuarantinedTable{{TableID: 1, CommitTs: 600}, {TableID: 2, CommitTs: 100}} {
+		c.Assert(s.client.PutQuarantinedTable(ctx, cf.id, table), check.IsNil)
+	}
+
+	err = owner.ReleaseQuarantinedTable(ctx, cf.id, 3, false)
+	c.Assert(cerror.ErrTableNotQuarantined.Equal(err), check.IsTrue)
+
+	// the released table is re-scanned from the first row failed to be written
+	c.Assert(owner.ReleaseQuarantinedTable(ctx, cf.id, 1, false), check.IsNil)
+	c.Assert(owner.manualScheduleCommand[cf.id], check.DeepEquals, []*model.MoveTableJob{{TableID: 1, RescanTs: 599}})
+
+	// the table which can't be re-scanned stays quarantined unless its rows are discarded
+	err = owner.ReleaseQuarantinedTable(ctx, cf.id, 2, false)
+	c.Assert(cerror.ErrTableRescanInvalid.Equal(err), check.IsTrue)
+	tables, err := s.client.GetQuarantinedTables(ctx, cf.id)
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.HasLen, 1)
+	c.Assert(tables[2], check.NotNil)
+	c.Assert(owner.ReleaseQuarantinedTable(ctx, cf.id, 2, true), check.IsNil)
+	tables, err = s.client.GetQuarantinedTables(ctx, cf.id)
+	c.Assert(err, check.IsNil)
+	c.Assert(tables, check.HasLen, 0)
+	c.Assert(owner.manualScheduleCommand[cf.id], check.HasLen, 1)
+}
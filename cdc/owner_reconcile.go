@@ -0,0 +1,267 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+	"go.uber.org/zap"
+)
+
+// The classes of inconsistent keys found by the reconciliation
+const (
+	reconcileInfoWithoutStatus = "info-without-status"
+	reconcileStatusWithoutInfo = "status-without-info"
+	reconcileOrphanTask        = "orphan-task"
+	reconcileUnparseable       = "unparseable"
+	reconcileUnknownAdminJob   = "unknown-admin-job"
+)
+
+// The actions taken on inconsistent keys
+const (
+	reconcileRepaired    = "repaired"
+	reconcileQuarantined = "quarantined"
+)
+
+// reconcileSummary counts the inconsistent keys handled by the reconciliation by class
+type reconcileSummary struct {
+	repaired    map[string]int
+	quarantined map[string]int
+}
+
+func newReconcileSummary() *reconcileSummary {
+	return &reconcileSummary{
+		repaired:    make(map[string]int),
+		quarantined: make(map[string]int),
+	}
+}
+
+func (s *reconcileSummary) record(class, action string) {
+	if action == reconcileRepaired {
+		s.repaired[class]++
+	} else {
+		s.quarantined[class]++
+	}
+	reconciledKeysCounter.WithLabelValues(class, action).Inc()
+}
+
+func isKnownAdminJobType(tp model.AdminJobType) bool {
+	return tp >= model.AdminNone && tp <= model.AdminFinish
+}
+
+// adminJobTypeOfState returns the admin job type recorded in the status of a changefeed in the given state
+func adminJobTypeOfState(state model.FeedState) model.AdminJobType {
+	switch state {
+	case model.StateStopped, model.StateFailed:
+		return model.AdminStop
+	case model.StateRemoved:
+		return model.AdminRemove
+	case model.StateFinished:
+		return model.AdminFinish
+	}
+	return model.AdminNone
+}
+
+// reconcileEtcdState repairs the inconsistent changefeed states in etcd, which may be
+// written by older versions, or moves them to the quarantine prefix if they can't be
+// repaired safely. It is called once after the owner is elected.
+func (o *Owner) reconcileEtcdState(ctx context.Context) (*reconcileSummary, error) {
+	summary := newReconcileSummary()
+	quarantine := func(rawKv *mvccpb.KeyValue, class, reason string) error {
+		ok, err := o.etcdClient.QuarantineKey(ctx, rawKv, reason)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !ok {
+			// the key is updated by others, check it in the next reconciliation
+			log.Warn("key modified during reconciliation, skip it", zap.ByteString("key", rawKv.Key))
+			return nil
+		}
+		log.Warn("quarantine inconsistent key",
+			zap.ByteString("key", rawKv.Key), zap.String("class", class), zap.String("reason", reason))
+		summary.record(class, reconcileQuarantined)
+		return nil
+	}
+
+	_, rawInfos, err := o.etcdClient.GetChangeFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[model.ChangeFeedID]*model.ChangeFeedInfo, len(rawInfos))
+	stopped := make(map[model.ChangeFeedID]struct{})
+	for id, rawKv := range rawInfos {
+		info := &model.ChangeFeedInfo{}
+		err := info.Unmarshal(rawKv.Value)
+		if err != nil {
+			err = quarantine(rawKv, reconcileUnparseable, fmt.Sprintf("unparseable changefeed info: %s", errors.Cause(err)))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !isKnownAdminJobType(info.AdminJobType) {
+			log.Warn("changefeed info has an unknown admin job type, stop the changefeed",
+				zap.String("changefeed", id), zap.Int("admin-job-type", int(info.AdminJobType)))
+			info.AdminJobType = model.AdminStop
+			info.State = model.StateStopped
+			err := o.etcdClient.SaveChangeFeedInfo(ctx, info, id)
+			if err != nil {
+				return nil, err
+			}
+			stopped[id] = struct{}{}
+			summary.record(reconcileUnknownAdminJob, reconcileRepaired)
+		}
+		infos[id] = info
+	}
+
+	resp, err := o.etcdClient.Client.Get(ctx, kv.JobKeyPrefix+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	statuses := make(map[model.ChangeFeedID]*model.ChangeFeedStatus, len(resp.Kvs))
+	for _, rawKv := range resp.Kvs {
+		id, err := model.ExtractKeySuffix(string(rawKv.Key))
+		if err != nil {
+			return nil, err
+		}
+		status := &model.ChangeFeedStatus{}
+		err = status.Unmarshal(rawKv.Value)
+		if err != nil {
+			err = quarantine(rawKv, reconcileUnparseable, fmt.Sprintf("unparseable changefeed status: %s", errors.Cause(err)))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, ok := infos[id]; !ok {
+			// the status of a removed or finished changefeed is kept unless it is removed
+			// forcibly, it is garbage collected after the retention period
+			if status.AdminJobType == model.AdminRemove || status.AdminJobType == model.AdminFinish {
+				continue
+			}
+			err = quarantine(rawKv, reconcileStatusWithoutInfo, "the changefeed status has no changefeed info")
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		unknown := !isKnownAdminJobType(status.AdminJobType)
+		if unknown {
+			log.Warn("changefeed status has an unknown admin job type, stop the changefeed",
+				zap.String("changefeed", id), zap.Int("admin-job-type", int(status.AdminJobType)))
+			summary.record(reconcileUnknownAdminJob, reconcileRepaired)
+		}
+		// keep the status consistent with the repaired changefeed info
+		_, infoStopped := stopped[id]
+		if unknown || (infoStopped && status.AdminJobType != model.AdminStop) {
+			status.AdminJobType = model.AdminStop
+			err := o.etcdClient.PutChangeFeedStatus(ctx, id, status)
+			if err != nil {
+				return nil, err
+			}
+		}
+		statuses[id] = status
+	}
+
+	resp, err = o.etcdClient.Client.Get(ctx, kv.TaskKeyPrefix+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrPDEtcdAPIError, err)
+	}
+	positions := make(map[model.ChangeFeedID][]*model.TaskPosition)
+	for _, rawKv := range resp.Kvs {
+		key := string(rawKv.Key)
+		var (
+			kind  string
+			value interface{ Unmarshal([]byte) error }
+		)
+		position := &model.TaskPosition{}
+		switch {
+		case strings.HasPrefix(key, kv.TaskStatusKeyPrefix+"/"):
+			kind, value = "task status", &model.TaskStatus{}
+		case strings.HasPrefix(key, kv.TaskPositionKeyPrefix+"/"):
+			kind, value = "task position", position
+		case strings.HasPrefix(key, kv.TaskWorkloadKeyPrefix+"/"):
+			kind, value = "task workload", &model.TaskWorkload{}
+		default:
+			log.Warn("unknown task key, skip it", zap.String("key", key))
+			continue
+		}
+		id, err := model.ExtractKeySuffix(key)
+		if err != nil {
+			return nil, err
+		}
+		err = value.Unmarshal(rawKv.Value)
+		if err != nil {
+			err = quarantine(rawKv, reconcileUnparseable, fmt.Sprintf("unparseable %s: %s", kind, errors.Cause(err)))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, ok := infos[id]; !ok {
+			err = quarantine(rawKv, reconcileOrphanTask, fmt.Sprintf("the %s has no changefeed info", kind))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if value == position {
+			positions[id] = append(positions[id], position)
+		}
+	}
+
+	for id, info := range infos {
+		if _, ok := statuses[id]; ok {
+			continue
+		}
+		// start from the minimum checkpoint of the processors to avoid losing data
+		checkpointTs := info.GetCheckpointTs(nil)
+		if len(positions[id]) > 0 {
+			minCheckpointTs := positions[id][0].CheckPointTs
+			for _, pos := range positions[id] {
+				if pos.CheckPointTs < minCheckpointTs {
+					minCheckpointTs = pos.CheckPointTs
+				}
+			}
+			if minCheckpointTs > checkpointTs {
+				checkpointTs = minCheckpointTs
+			}
+		}
+		status := &model.ChangeFeedStatus{
+			ResolvedTs:   checkpointTs,
+			CheckpointTs: checkpointTs,
+			AdminJobType: adminJobTypeOfState(info.State),
+		}
+		log.Warn("changefeed status is missing, initialize it",
+			zap.String("changefeed", id), zap.Reflect("status", status))
+		err := o.etcdClient.PutChangeFeedStatus(ctx, id, status)
+		if err != nil {
+			return nil, err
+		}
+		summary.record(reconcileInfoWithoutStatus, reconcileRepaired)
+	}
+
+	log.Info("reconcile changefeed states in etcd finished",
+		zap.Any("repaired", summary.repaired), zap.Any("quarantined", summary.quarantined))
+	return summary, nil
+}
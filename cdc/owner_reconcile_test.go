@@ -0,0 +1,151 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+func (s *ownerSuite) TestReconcileEtcdState(c *check.C) {
+	ctx := s.ctx
+	put := func(key, value string) {
+		_, err := s.client.Client.Put(ctx, key, value)
+		c.Assert(err, check.IsNil)
+	}
+	putInfo := func(id model.ChangeFeedID, info *model.ChangeFeedInfo) {
+		info.SinkURI = "blackhole://"
+		info.Config = config.GetDefaultReplicaConfig()
+		err := s.client.SaveChangeFeedInfo(ctx, info, id)
+		c.Assert(err, check.IsNil)
+	}
+	putStatus := func(id model.ChangeFeedID, status *model.ChangeFeedStatus) {
+		err := s.client.PutChangeFeedStatus(ctx, id, status)
+		c.Assert(err, check.IsNil)
+	}
+	putPosition := func(id model.ChangeFeedID, captureID string, checkpointTs uint64) {
+		pos := &model.TaskPosition{CheckPointTs: checkpointTs, ResolvedTs: checkpointTs}
+		value, err := pos.Marshal()
+		c.Assert(err, check.IsNil)
+		put(kv.GetEtcdKeyTaskPosition(id, captureID), value)
+	}
+
+	// a consistent changefeed
+	putInfo("normal", &model.ChangeFeedInfo{StartTs: 100})
+	putStatus("normal", &model.ChangeFeedStatus{CheckpointTs: 1000})
+	put(kv.GetEtcdKeyTaskStatus("normal", "capture-1"), "{}")
+	putPosition("normal", "capture-1", 1000)
+	// info without status
+	putInfo("no-status", &model.ChangeFeedInfo{StartTs: 100})
+	putPosition("no-status", "capture-1", 500)
+	putPosition("no-status", "capture-2", 300)
+	putInfo("stopped-no-status", &model.ChangeFeedInfo{StartTs: 200, State: model.StateStopped})
+	// status without info
+	putStatus("removed", &model.ChangeFeedStatus{CheckpointTs: 1000, AdminJobType: model.AdminRemove})
+	putStatus("finished", &model.ChangeFeedStatus{CheckpointTs: 1000, AdminJobType: model.AdminFinish})
+	putStatus("dangling", &model.ChangeFeedStatus{CheckpointTs: 1000})
+	// orphan task keys
+	put(kv.GetEtcdKeyTaskStatus("gone", "capture-1"), "{}")
+	put(kv.GetEtcdKeyTaskWorkload("gone", "capture-1"), "{}")
+	// unparseable json
+	put(kv.GetEtcdKeyChangeFeedInfo("broken-info"), "{broken")
+	putStatus("broken-info", &model.ChangeFeedStatus{CheckpointTs: 1000})
+	putInfo("broken-status", &model.ChangeFeedInfo{StartTs: 100})
+	put(kv.GetEtcdKeyJob("broken-status"), "broken")
+	put(kv.GetEtcdKeyTaskPosition("normal", "capture-2"), "broken")
+	// unknown admin job types
+	putInfo("unknown-info-job", &model.ChangeFeedInfo{StartTs: 100, AdminJobType: 10})
+	putStatus("unknown-info-job", &model.ChangeFeedStatus{CheckpointTs: 1000})
+	putInfo("unknown-status-job", &model.ChangeFeedInfo{StartTs: 100})
+	putStatus("unknown-status-job", &model.ChangeFeedStatus{CheckpointTs: 1000, AdminJobType: 7})
+
+	owner := &Owner{etcdClient: s.client}
+	summary, err := owner.reconcileEtcdState(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(summary.repaired, check.DeepEquals, map[string]int{
+		reconcileInfoWithoutStatus: 3,
+		reconcileUnknownAdminJob:   2,
+	})
+	c.Assert(summary.quarantined, check.DeepEquals, map[string]int{
+		reconcileStatusWithoutInfo: 2,
+		reconcileOrphanTask:        2,
+		reconcileUnparseable:       3,
+	})
+
+	getStatus := func(id model.ChangeFeedID) *model.ChangeFeedStatus {
+		status, _, err := s.client.GetChangeFeedStatus(ctx, id)
+		c.Assert(err, check.IsNil)
+		return status
+	}
+	c.Assert(getStatus("normal"), check.DeepEquals, &model.ChangeFeedStatus{CheckpointTs: 1000})
+	c.Assert(getStatus("no-status"), check.DeepEquals, &model.ChangeFeedStatus{ResolvedTs: 300, CheckpointTs: 300})
+	c.Assert(getStatus("stopped-no-status"), check.DeepEquals,
+		&model.ChangeFeedStatus{ResolvedTs: 200, CheckpointTs: 200, AdminJobType: model.AdminStop})
+	c.Assert(getStatus("broken-status"), check.DeepEquals, &model.ChangeFeedStatus{ResolvedTs: 100, CheckpointTs: 100})
+	c.Assert(getStatus("removed").AdminJobType, check.Equals, model.AdminRemove)
+	c.Assert(getStatus("finished").AdminJobType, check.Equals, model.AdminFinish)
+	c.Assert(getStatus("unknown-info-job").AdminJobType, check.Equals, model.AdminStop)
+	c.Assert(getStatus("unknown-status-job").AdminJobType, check.Equals, model.AdminStop)
+	info, err := s.client.GetChangeFeedInfo(ctx, "unknown-info-job")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.AdminJobType, check.Equals, model.AdminStop)
+	c.Assert(info.State, check.Equals, model.StateStopped)
+	_, _, err = s.client.GetChangeFeedStatus(ctx, "dangling")
+	c.Assert(cerror.ErrChangeFeedNotExists.Equal(err), check.IsTrue)
+	_, err = s.client.GetChangeFeedInfo(ctx, "broken-info")
+	c.Assert(cerror.ErrChangeFeedNotExists.Equal(err), check.IsTrue)
+	statuses, err := s.client.GetAllTaskStatus(ctx, "gone")
+	c.Assert(err, check.IsNil)
+	c.Assert(statuses, check.HasLen, 0)
+	positions, err := s.client.GetAllTaskPositions(ctx, "normal")
+	c.Assert(err, check.IsNil)
+	c.Assert(positions, check.HasLen, 1)
+
+	items, err := s.client.GetQuarantinedItems(ctx)
+	c.Assert(err, check.IsNil)
+	reasons := make(map[string]string, len(items))
+	for _, item := range items {
+		reasons[item.Key] = item.Reason
+	}
+	c.Assert(reasons, check.HasLen, 7)
+	c.Assert(reasons[kv.GetEtcdKeyJob("dangling")], check.Equals, "the changefeed status has no changefeed info")
+	c.Assert(reasons[kv.GetEtcdKeyJob("broken-info")], check.Equals, "the changefeed status has no changefeed info")
+	c.Assert(reasons[kv.GetEtcdKeyTaskStatus("gone", "capture-1")], check.Equals, "the task status has no changefeed info")
+	c.Assert(reasons[kv.GetEtcdKeyTaskWorkload("gone", "capture-1")], check.Equals, "the task workload has no changefeed info")
+	c.Assert(reasons[kv.GetEtcdKeyChangeFeedInfo("broken-info")], check.Matches, "unparseable changefeed info: .*")
+	c.Assert(reasons[kv.GetEtcdKeyJob("broken-status")], check.Matches, "unparseable changefeed status: .*")
+	c.Assert(reasons[kv.GetEtcdKeyTaskPosition("normal", "capture-2")], check.Matches, "unparseable task position: .*")
+	for _, item := range items {
+		if item.Key == kv.GetEtcdKeyChangeFeedInfo("broken-info") {
+			c.Assert(item.Value, check.Equals, "{broken")
+		}
+	}
+
+	// the states are consistent after the reconciliation
+	summary, err = owner.reconcileEtcdState(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(summary.repaired, check.HasLen, 0)
+	c.Assert(summary.quarantined, check.HasLen, 0)
+
+	err = s.client.DeleteQuarantinedItem(ctx, kv.GetEtcdKeyJob("dangling"))
+	c.Assert(err, check.IsNil)
+	err = s.client.DeleteQuarantinedItem(ctx, kv.GetEtcdKeyJob("dangling"))
+	c.Assert(cerror.ErrQuarantineNotExists.Equal(err), check.IsTrue)
+	items, err = s.client.GetQuarantinedItems(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(items, check.HasLen, 6)
+}
@@ -0,0 +1,178 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// RescanTable re-scans the table of the changefeed from fromTs. The table is removed from its capture
+// and added back with fromTs as the start ts, the rows committed after fromTs are replicated again
+// and upserted by the MySQL sink in safe mode, while the other tables of the changefeed are untouched.
+// The changefeeds of the other sinks are rejected.
+// The table rejoins the live stream once it catches up with the changefeed.
+func (o *Owner) RescanTable(ctx context.Context, changefeedID model.ChangeFeedID, tableID model.TableID, fromTs model.Ts) error {
+	if err := o.checkRescanTable(ctx, changefeedID, tableID, fromTs); err != nil {
+		return errors.Trace(err)
+	}
+	o.queueRescanTable(changefeedID, tableID, fromTs)
+	return nil
+}
+
+// checkRescanTable checks whether the table of the changefeed can be re-scanned from fromTs
+func (o *Owner) checkRescanTable(ctx context.Context, changefeedID model.ChangeFeedID, tableID model.TableID, fromTs model.Ts) error {
+	info, err := o.etcdClient.GetChangeFeedInfo(ctx, changefeedID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if info.AdminJobType.IsStopState() || (info.State != "" && info.State != model.StateNormal) {
+		return cerror.ErrTableRescanInvalid.GenWithStackByArgs(tableID, changefeedID, "the changefeed is not running")
+	}
+	if err := verifyRescanSink(info.SinkURI); err != nil {
+		return cerror.ErrTableRescanInvalid.GenWithStackByArgs(tableID, changefeedID, err.Error())
+	}
+	status, _, err := o.etcdClient.GetChangeFeedStatus(ctx, changefeedID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if fromTs > status.CheckpointTs {
+		return cerror.ErrTableRescanInvalid.GenWithStackByArgs(tableID, changefeedID,
+			fmt.Sprintf("from-ts %d is ahead of the checkpoint %d", fromTs, status.CheckpointTs))
+	}
+	safepoint, err := o.etcdClient.GetGCSafepoint(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if fromTs < safepoint {
+		return cerror.ErrTableRescanInvalid.GenWithStackByArgs(tableID, changefeedID,
+			fmt.Sprintf("from-ts %d is less than the GC safepoint %d", fromTs, safepoint))
+	}
+	// the rows before the last DDL of the table can't be mounted with the current schema
+	lastDDLTs, exist, err := rescanTableLastDDLTs(o, tableID, status.CheckpointTs)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !exist {
+		return cerror.ErrTableRescanInvalid.GenWithStackByArgs(tableID, changefeedID, "the table doesn't exist")
+	}
+	if fromTs < lastDDLTs {
+		return cerror.ErrTableRescanInvalid.GenWithStackByArgs(tableID, changefeedID,
+			fmt.Sprintf("from-ts %d is before the last DDL of the table at %d", fromTs, lastDDLTs))
+	}
+	return nil
+}
+
+func (o *Owner) queueRescanTable(changefeedID model.ChangeFeedID, tableID model.TableID, fromTs model.Ts) {
+	o.rebalanceMu.Lock()
+	defer o.rebalanceMu.Unlock()
+	o.manualScheduleCommand[changefeedID] = append(o.manualScheduleCommand[changefeedID], &model.MoveTableJob{
+		TableID:  tableID,
+		RescanTs: fromTs,
+	})
+	log.Info("rescan the table", zap.String("changefeed", changefeedID),
+		zap.Int64("tableID", tableID), zap.Uint64("fromTs", fromTs))
+}
+
+// rescanTableLastDDLTs returns the commit ts of the last DDL of the table in the schema at ts,
+// and whether the table exists. It loads the schema from TiKV, since the schema of the changefeed
+// is only accessed by the owner loop.
+var rescanTableLastDDLTs = func(o *Owner, tableID model.TableID, ts model.Ts) (model.Ts, bool, error) {
+	kvStore, err := kv.CreateTiStore(strings.Join(o.pdEndpoints, ","), o.credential)
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	meta, err := kv.GetSnapshotMeta(kvStore, ts)
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	schemaSnap, err := entry.NewSingleSchemaSnapshotFromMeta(meta, ts)
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	tableInfo, exist := schemaSnap.TableByID(tableID)
+	if !exist {
+		return 0, false, nil
+	}
+	return tableInfo.UpdateTS, true, nil
+}
+
+// verifyRescanSink checks whether the sink upserts the rows replicated again, only the MySQL sink
+// in safe mode does so. The MQ sinks emit the rows behind the resolved events already sent, and drop
+// them if the deduplication is enabled, the blackhole sink drops the rows before the checkpoint.
+func verifyRescanSink(sinkURIStr string) error {
+	sinkURI, err := url.Parse(sinkURIStr)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	switch strings.ToLower(sinkURI.Scheme) {
+	case "mysql", "tidb", "mysql+ssl", "tidb+ssl":
+	default:
+		return errors.Errorf("the rows replicated again can't be upserted by the %s sink, only the MySQL sink in safe mode supports it",
+			sinkURI.Scheme)
+	}
+	s := sinkURI.Query().Get("safe-mode")
+	if s == "" {
+		return nil
+	}
+	safeMode, err := strconv.ParseBool(s)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+	}
+	if !safeMode {
+		return errors.New("the rows can't be upserted with the safe mode of the MySQL sink disabled")
+	}
+	return nil
+}
+
+// gcSafepointTs returns the ts the changefeed holds the GC safepoint at. It's the checkpoint ts,
+// or the from-ts of a rescanned table until the table catches up with the checkpoint. The checkpoint
+// ts not written to etcd yet isn't used, which is flushed less often with a checkpoint interval.
+func (c *changeFeed) gcSafepointTs() model.Ts {
+	ts := c.status.CheckpointTs
+	hold := func(t model.Ts) {
+		if t != 0 && t < ts {
+			ts = t
+		}
+	}
+	hold(c.flushedCheckpointTs)
+	for _, job := range c.manualMoveCommands {
+		hold(job.RescanTs)
+	}
+	for _, job := range c.moveTableJobs {
+		hold(job.RescanTs)
+	}
+	for _, startTs := range c.orphanTables {
+		hold(startTs)
+	}
+	// the rescanned table added to the capture lowers the checkpoint of the processor
+	for _, status := range c.taskStatus {
+		hold(status.AppliedTs())
+	}
+	for _, position := range c.taskPositions {
+		hold(position.CheckPointTs)
+	}
+	return ts
+}
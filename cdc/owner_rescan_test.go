@@ -0,0 +1,164 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/scheduler"
+	"github.com/pingcap/tidb/store/tikv"
+)
+
+func (s *ownerSuite) TestRescanTable(c *check.C) {
+	ctx := s.ctx
+	const checkpointTs = 1000
+	pdCli := &safepointPDClient{}
+	cf := s.newPriorityTestChangefeed(c, "rescan", config.PriorityClassNormal, checkpointTs)
+	cf.info.Config.Scheduler = &config.SchedulerConfig{Tp: "table-number", PollingTime: -1}
+	cf.scheduler = scheduler.NewScheduler("table-number")
+	cf.orphanTables = make(map[model.TableID]model.Ts)
+	cf.toCleanTables = make(map[model.TableID]model.Ts)
+	owner := &Owner{
+		changeFeeds:           map[model.ChangeFeedID]*changeFeed{cf.id: cf},
+		stoppedFeeds:          make(map[model.ChangeFeedID]*model.ChangeFeedStatus),
+		gcShedFeeds:           make(map[model.ChangeFeedID]struct{}),
+		manualScheduleCommand: make(map[model.ChangeFeedID][]*model.MoveTableJob),
+		pdClient:              pdCli,
+		cfRWriter:             s.client,
+		etcdClient:            s.client,
+	}
+	info := &model.ChangeFeedInfo{SinkURI: "mysql://127.0.0.1:3306/", Config: config.GetDefaultReplicaConfig()}
+	c.Assert(s.client.SaveChangeFeedInfo(ctx, info, cf.id), check.IsNil)
+	c.Assert(s.client.PutChangeFeedStatus(ctx, cf.id, cf.status), check.IsNil)
+	_, err := s.client.Client.Put(ctx, tikv.GcSavedSafePoint, "200")
+	c.Assert(err, check.IsNil)
+	lastDDLTs := map[model.TableID]model.Ts{1: 300, 2: 300}
+	defer func(f func(*Owner, model.TableID, model.Ts) (model.Ts, bool, error)) {
+		rescanTableLastDDLTs = f
+	}(rescanTableLastDDLTs)
+	rescanTableLastDDLTs = func(_ *Owner, tableID model.TableID, ts model.Ts) (model.Ts, bool, error) {
+		c.Assert(ts, check.Equals, uint64(checkpointTs))
+		lastDDLTs, exist := lastDDLTs[tableID]
+		return lastDDLTs, exist, nil
+	}
+
+	// the from-ts must be between the GC safepoint and the checkpoint
+	err = owner.RescanTable(ctx, cf.id, 1, 100)
+	c.Assert(cerror.ErrTableRescanInvalid.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*from-ts 100 is less than the GC safepoint 200.*")
+	err = owner.RescanTable(ctx, cf.id, 1, 2000)
+	c.Assert(err, check.ErrorMatches, ".*from-ts 2000 is ahead of the checkpoint 1000.*")
+	err = owner.RescanTable(ctx, "unknown", 1, 500)
+	c.Assert(cerror.ErrChangeFeedNotExists.Equal(err), check.IsTrue)
+	// the rows before the last DDL of the table can't be rescanned
+	lastDDLTs[1] = 600
+	err = owner.RescanTable(ctx, cf.id, 1, 500)
+	c.Assert(cerror.ErrTableRescanInvalid.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*from-ts 500 is before the last DDL of the table at 600.*")
+	lastDDLTs[1] = 300
+	err = owner.RescanTable(ctx, cf.id, 3, 500)
+	c.Assert(err, check.ErrorMatches, ".*the table doesn't exist.*")
+	// the rows can't be re-applied without safe mode
+	info.SinkURI = "mysql://127.0.0.1:3306/?safe-mode=false"
+	c.Assert(s.client.SaveChangeFeedInfo(ctx, info, cf.id), check.IsNil)
+	err = owner.RescanTable(ctx, cf.id, 1, 500)
+	c.Assert(err, check.ErrorMatches, ".*with the safe mode of the MySQL sink disabled.*")
+	// the other sinks don't upsert the rows replicated again
+	for _, sinkURI := range []string{"kafka://127.0.0.1:9092/topic", "pulsar://127.0.0.1:6650/topic", "blackhole://", "memory://queue"} {
+		info.SinkURI = sinkURI
+		c.Assert(s.client.SaveChangeFeedInfo(ctx, info, cf.id), check.IsNil)
+		err = owner.RescanTable(ctx, cf.id, 1, 500)
+		c.Assert(cerror.ErrTableRescanInvalid.Equal(err), check.IsTrue)
+		c.Assert(err, check.ErrorMatches, ".*only the MySQL sink in safe mode supports it.*")
+	}
+	info.SinkURI, info.AdminJobType = "mysql://127.0.0.1:3306/", model.AdminStop
+	c.Assert(s.client.SaveChangeFeedInfo(ctx, info, cf.id), check.IsNil)
+	err = owner.RescanTable(ctx, cf.id, 1, 500)
+	c.Assert(err, check.ErrorMatches, ".*the changefeed is not running.*")
+	info.AdminJobType = model.AdminNone
+	c.Assert(s.client.SaveChangeFeedInfo(ctx, info, cf.id), check.IsNil)
+	c.Assert(owner.RescanTable(ctx, cf.id, 1, 500), check.IsNil)
+
+	status := &model.TaskStatus{Tables: map[model.TableID]*model.TableReplicaInfo{
+		1: {StartTs: 800},
+		2: {StartTs: 800},
+	}}
+	c.Assert(s.client.PutTaskStatus(ctx, cf.id, "capture-1", status), check.IsNil)
+	cf.taskStatus = model.ProcessorsInfos{"capture-1": status.Clone()}
+	cf.taskPositions = map[model.CaptureID]*model.TaskPosition{"capture-1": {CheckPointTs: checkpointTs, ResolvedTs: checkpointTs}}
+	owner.captures = map[model.CaptureID]*model.CaptureInfo{"capture-1": {ID: "capture-1"}}
+	// balanceTables acts as the owner, and applyOperations acts as the processor
+	balanceTables := func() {
+		c.Assert(owner.balanceTables(ctx), check.IsNil)
+		c.Assert(owner.flushChangeFeedInfos(ctx), check.IsNil)
+		owner.gcSafepointLastUpdate = time.Time{}
+	}
+	applyOperations := func() map[model.TableID]*model.TableOperation {
+		status := cf.taskStatus["capture-1"]
+		operations := status.Operation
+		for _, op := range operations {
+			op.Status = model.OperFinished
+		}
+		c.Assert(s.client.PutTaskStatus(ctx, cf.id, "capture-1", status), check.IsNil)
+		status.Operation = nil
+		return operations
+	}
+
+	// the table is removed from the capture, the GC safepoint is held at the from-ts
+	balanceTables()
+	c.Assert(pdCli.safepoint, check.Equals, uint64(500))
+	ops := applyOperations()
+	c.Assert(ops, check.HasLen, 1)
+	c.Assert(ops[1].Delete, check.IsTrue)
+	c.Assert(ops[1].BoundaryTs, check.Equals, uint64(checkpointTs))
+
+	// the table is added back to the same capture from the from-ts, the other table is untouched
+	balanceTables()
+	c.Assert(pdCli.safepoint, check.Equals, uint64(500))
+	c.Assert(cf.moveTableJobs, check.HasLen, 0)
+	c.Assert(cf.taskStatus["capture-1"].Tables, check.DeepEquals, map[model.TableID]*model.TableReplicaInfo{
+		1: {StartTs: 500},
+		2: {StartTs: 800},
+	})
+	ops = applyOperations()
+	c.Assert(ops, check.HasLen, 1)
+	c.Assert(ops[1].Delete, check.IsFalse)
+	c.Assert(ops[1].BoundaryTs, check.Equals, uint64(500))
+
+	// the rows after the from-ts are replayed by the rescanned table only
+	strict := cf.info.Config.StrictConsistency
+	guards := map[model.TableID]*lateEventGuard{
+		1: newLateEventGuard(cf.id, "", 1, 500, strict),
+		2: newLateEventGuard(cf.id, "", 2, 800, strict),
+	}
+	for tableID, guard := range guards {
+		event := model.NewPolymorphicEvent(&model.RawKVEntry{OpType: model.OpTypePut, StartTs: 599, CRTs: 600})
+		pass, err := guard.check(event)
+		c.Assert(err, check.IsNil)
+		c.Assert(pass, check.Equals, tableID == 1)
+	}
+
+	// the GC safepoint is held until the rescanned table catches up
+	cf.taskPositions["capture-1"].CheckPointTs = 700
+	balanceTables()
+	c.Assert(pdCli.safepoint, check.Equals, uint64(700))
+	c.Assert(cf.status.CheckpointTs, check.Equals, uint64(checkpointTs))
+	cf.taskPositions["capture-1"].CheckPointTs = 1200
+	balanceTables()
+	c.Assert(pdCli.safepoint, check.Equals, uint64(checkpointTs))
+}
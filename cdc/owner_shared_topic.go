@@ -0,0 +1,32 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/ticdc/cdc/sink"
+)
+
+// updateSharedTopicMetrics sets the number of other running changefeeds writing the same tables to
+// the same MQ topic for every running changefeed of an MQ sink. The messages of such changefeeds
+// are interleaved in the topic, which breaks the order of the rows of the same key.
+func (o *Owner) updateSharedTopicMetrics() {
+	for id, cf := range o.changeFeeds {
+		target, err := sink.NormalizeSinkTarget(cf.info.SinkURI)
+		if err != nil || !sink.IsMQSinkTarget(target) {
+			continue
+		}
+		// the conflicts of a changefeed are the changefeeds replicating the same tables to the same target
+		sharedTopicChangefeedsGauge.WithLabelValues(id).Set(float64(len(cf.status.Conflicts)))
+	}
+}
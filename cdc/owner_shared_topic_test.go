@@ -0,0 +1,51 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func (s *ownerSuite) TestSharedTopicMetrics(c *check.C) {
+	sharedTopicChangefeedsGauge.Reset()
+	defer sharedTopicChangefeedsGauge.Reset()
+	newCf := func(id string, sinkURI string, conflicts ...model.ChangeFeedID) *changeFeed {
+		return &changeFeed{
+			id:     id,
+			info:   &model.ChangeFeedInfo{SinkURI: sinkURI},
+			status: &model.ChangeFeedStatus{Conflicts: conflicts},
+		}
+	}
+	owner := &Owner{changeFeeds: map[model.ChangeFeedID]*changeFeed{
+		"topic-1": newCf("topic-1", "kafka://127.0.0.1:9092/topic", "topic-2"),
+		"topic-2": newCf("topic-2", "kafka://127.0.0.1/topic?protocol=canal", "topic-1"),
+		"topic-3": newCf("topic-3", "kafka://127.0.0.1/topic-3"),
+		"mysql-1": newCf("mysql-1", "mysql://127.0.0.1:3306/", "mysql-2"),
+		"mysql-2": newCf("mysql-2", "mysql://127.0.0.1:3306/", "mysql-1"),
+	}}
+	owner.updateSharedTopicMetrics()
+	c.Assert(testutil.ToFloat64(sharedTopicChangefeedsGauge.WithLabelValues("topic-1")), check.Equals, float64(1))
+	c.Assert(testutil.ToFloat64(sharedTopicChangefeedsGauge.WithLabelValues("topic-2")), check.Equals, float64(1))
+	c.Assert(testutil.ToFloat64(sharedTopicChangefeedsGauge.WithLabelValues("topic-3")), check.Equals, float64(0))
+	// the changefeeds of the other sinks are not flagged
+	c.Assert(testutil.CollectAndCount(sharedTopicChangefeedsGauge), check.Equals, 3)
+
+	// the changefeed sharing the topic is removed
+	delete(owner.changeFeeds, "topic-2")
+	owner.clearConflicts("topic-2")
+	c.Assert(testutil.ToFloat64(sharedTopicChangefeedsGauge.WithLabelValues("topic-1")), check.Equals, float64(0))
+	c.Assert(testutil.CollectAndCount(sharedTopicChangefeedsGauge), check.Equals, 2)
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	tidbkv "github.com/pingcap/tidb/kv"
+	timeta "github.com/pingcap/tidb/meta"
+	"go.uber.org/zap"
+)
+
+// startTsDDLRecheckInterval is the interval to check again whether the DDL jobs running at the start ts
+// of a waiting changefeed have finished, the changefeed isn't created again on every tick of the owner
+const startTsDDLRecheckInterval = 10 * time.Second
+
+// ddlWait is the wait of a changefeed for the DDL jobs running at its start ts
+type ddlWait struct {
+	since     time.Time
+	checkedAt time.Time
+}
+
+// checkDDLJobsAtStartTs checks the DDL jobs running at the start ts of a changefeed, the schema objects
+// changed by them are in the intermediate states in the schema snapshot at the start ts. The changefeed
+// is rejected by default. With the wait policy, the changefeed is started after the jobs finish, which
+// doesn't change the schemas it's bootstrapped with, the snapshot is still read at the start ts.
+func checkDDLJobsAtStartTs(
+	kvStore tidbkv.Storage, meta *timeta.Meta, id model.ChangeFeedID, info *model.ChangeFeedInfo, checkpointTs uint64,
+) error {
+	// the changefeed has been bootstrapped and replicated since the start ts
+	if checkpointTs != info.StartTs {
+		return nil
+	}
+	jobs, err := entry.RunningDDLJobs(meta)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+	if info.Config.StartTsInDDL == config.StartTsInDDLReject {
+		job := jobs[0]
+		return cerror.ErrStartTsInRunningDDL.GenWithStackByArgs(info.StartTs, id, job.ID, job.Query, job.StartTS)
+	}
+
+	ver, err := kvStore.CurrentVersion()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	current, err := kv.GetSnapshotMeta(kvStore, ver.Ver)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, job := range jobs {
+		finished, err := current.GetHistoryDDLJob(job.ID)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if finished == nil {
+			return cerror.ErrStartTsInRunningDDL.GenWithStackByArgs(info.StartTs, id, job.ID, job.Query, job.StartTS)
+		}
+		log.Info("the DDL job running at the start ts of the changefeed has finished",
+			zap.String("changefeed", id), zap.Int64("jobID", job.ID), zap.String("query", job.Query),
+			zap.Uint64("startTs", info.StartTs), zap.Stringer("state", finished.State))
+	}
+	return nil
+}
+
+// waitForDDLJobsAtStartTs returns whether the changefeed waits for the DDL jobs running at its start ts
+func waitForDDLJobsAtStartTs(info *model.ChangeFeedInfo, err error) bool {
+	return cerror.ErrStartTsInRunningDDL.Equal(err) && info.Config.StartTsInDDL != config.StartTsInDDLReject
+}
+
+// markWaitingForDDLJobs records the changefeed waiting for the DDL jobs running at its start ts,
+// the wait is logged when it starts and its duration is exposed by the metric
+func (o *Owner) markWaitingForDDLJobs(id model.ChangeFeedID, err error, now time.Time) {
+	if o.ddlWaitingFeeds == nil {
+		o.ddlWaitingFeeds = make(map[model.ChangeFeedID]*ddlWait)
+	}
+	wait, ok := o.ddlWaitingFeeds[id]
+	if !ok {
+		log.Info("the changefeed waits for the DDL jobs running at its start ts to finish",
+			zap.String("changefeed", id), zap.Error(err))
+		wait = &ddlWait{since: now}
+		o.ddlWaitingFeeds[id] = wait
+	}
+	wait.checkedAt = now
+	startTsDDLWaitGauge.WithLabelValues(id).Set(now.Sub(wait.since).Seconds())
+}
+
+// isDDLJobsRecheckDue returns whether the DDL jobs the changefeed waits for should be checked again,
+// it's true if the changefeed isn't waiting
+func (o *Owner) isDDLJobsRecheckDue(id model.ChangeFeedID, now time.Time) bool {
+	wait, ok := o.ddlWaitingFeeds[id]
+	return !ok || now.Sub(wait.checkedAt) >= startTsDDLRecheckInterval
+}
+
+// forgetWaitingForDDLJobs forgets the changefeed after it's bootstrapped, failed or removed
+func (o *Owner) forgetWaitingForDDLJobs(id model.ChangeFeedID) {
+	if _, ok := o.ddlWaitingFeeds[id]; !ok {
+		return
+	}
+	delete(o.ddlWaitingFeeds, id)
+	startTsDDLWaitGauge.DeleteLabelValues(id)
+}
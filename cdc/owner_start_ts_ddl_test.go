@@ -0,0 +1,186 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/entry"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	tidbkv "github.com/pingcap/tidb/kv"
+	timeta "github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/store/mockstore"
+	"github.com/pingcap/tidb/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type startTsInDDLSuite struct{}
+
+var _ = check.Suite(&startTsInDDLSuite{})
+
+func runMetaTxn(c *check.C, store tidbkv.Storage, fn func(txn tidbkv.Transaction, m *timeta.Meta)) uint64 {
+	txn, err := store.Begin()
+	c.Assert(err, check.IsNil)
+	fn(txn, timeta.NewMeta(txn))
+	c.Assert(txn.Commit(context.Background()), check.IsNil)
+	ver, err := store.CurrentVersion()
+	c.Assert(err, check.IsNil)
+	return ver.Ver
+}
+
+func newTestColumn(id int64, name string, state timodel.SchemaState) *timodel.ColumnInfo {
+	return &timodel.ColumnInfo{
+		ID:        id,
+		Name:      timodel.NewCIStr(name),
+		Offset:    int(id - 1),
+		State:     state,
+		FieldType: *types.NewFieldType(mysql.TypeLong),
+	}
+}
+
+func visibleColumns(c *check.C, storage *entry.SchemaStorage, tableID int64) int {
+	table, ok := storage.GetLastSnapshot().TableByID(tableID)
+	c.Assert(ok, check.IsTrue)
+	return len(table.RowColumnsOffset)
+}
+
+// TestStartTsInRunningDDL creates a changefeed whose start ts falls in the execution of an ADD COLUMN job,
+// the added column is in the write only state in the schema snapshot at the start ts
+func (s *startTsInDDLSuite) TestStartTsInRunningDDL(c *check.C) {
+	store, err := mockstore.NewMockTikvStore()
+	c.Assert(err, check.IsNil)
+	defer store.Close() //nolint:errcheck
+
+	dbInfo := &timodel.DBInfo{ID: 1, Name: timodel.NewCIStr("test"), State: timodel.StatePublic}
+	tblInfo := &timodel.TableInfo{
+		ID:          2,
+		Name:        timodel.NewCIStr("t"),
+		Columns:     []*timodel.ColumnInfo{newTestColumn(1, "id", timodel.StatePublic)},
+		MaxColumnID: 1,
+		State:       timodel.StatePublic,
+	}
+	runMetaTxn(c, store, func(_ tidbkv.Transaction, m *timeta.Meta) {
+		c.Assert(m.CreateDatabase(dbInfo), check.IsNil)
+		c.Assert(m.CreateTableOrView(dbInfo.ID, tblInfo), check.IsNil)
+	})
+	job := &timodel.Job{
+		ID:         3,
+		Type:       timodel.ActionAddColumn,
+		SchemaID:   dbInfo.ID,
+		TableID:    tblInfo.ID,
+		Query:      "alter table t add column a int",
+		BinlogInfo: &timodel.HistoryInfo{},
+	}
+	startTs := runMetaTxn(c, store, func(txn tidbkv.Transaction, m *timeta.Meta) {
+		tblInfo.Columns = append(tblInfo.Columns, newTestColumn(2, "a", timodel.StateWriteOnly))
+		tblInfo.MaxColumnID = 2
+		c.Assert(m.UpdateTable(dbInfo.ID, tblInfo), check.IsNil)
+		job.State = timodel.JobStateRunning
+		job.SchemaState = timodel.StateWriteOnly
+		job.StartTS = txn.StartTS()
+		c.Assert(m.EnQueueDDLJob(job), check.IsNil)
+		addIndexJob := &timodel.Job{
+			ID:          4,
+			Type:        timodel.ActionAddIndex,
+			SchemaID:    dbInfo.ID,
+			TableID:     tblInfo.ID,
+			Query:       "alter table t add index idx(id)",
+			State:       timodel.JobStateRunning,
+			SchemaState: timodel.StateWriteReorganization,
+			StartTS:     txn.StartTS(),
+		}
+		c.Assert(m.EnQueueDDLJob(addIndexJob, timeta.AddIndexJobListKey), check.IsNil)
+	})
+
+	info := &model.ChangeFeedInfo{StartTs: startTs, Config: config.GetDefaultReplicaConfig()}
+	meta, err := kv.GetSnapshotMeta(store, startTs)
+	c.Assert(err, check.IsNil)
+	// the add index job running at the start ts is ignored
+	jobs, err := entry.RunningDDLJobs(meta)
+	c.Assert(err, check.IsNil)
+	c.Assert(jobs, check.HasLen, 1)
+	c.Assert(jobs[0].ID, check.Equals, job.ID)
+
+	// the changefeed is rejected by default, or waits for the job to finish
+	err = checkDDLJobsAtStartTs(store, meta, "test-cf", info, startTs)
+	c.Assert(cerror.ErrStartTsInRunningDDL.Equal(err), check.IsTrue)
+	c.Assert(waitForDDLJobsAtStartTs(info, err), check.IsFalse)
+	info.Config.StartTsInDDL = config.StartTsInDDLWait
+	err = checkDDLJobsAtStartTs(store, meta, "test-cf", info, startTs)
+	c.Assert(cerror.ErrStartTsInRunningDDL.Equal(err), check.IsTrue)
+	c.Assert(waitForDDLJobsAtStartTs(info, err), check.IsTrue)
+	// the changefeed restarted from its checkpoint isn't checked
+	c.Assert(checkDDLJobsAtStartTs(store, meta, "test-cf", info, startTs+1), check.IsNil)
+
+	finishedTs := runMetaTxn(c, store, func(txn tidbkv.Transaction, m *timeta.Meta) {
+		tblInfo.Columns[1].State = timodel.StatePublic
+		c.Assert(m.UpdateTable(dbInfo.ID, tblInfo), check.IsNil)
+		_, err := m.DeQueueDDLJob()
+		c.Assert(err, check.IsNil)
+		job.State = timodel.JobStateSynced
+		job.SchemaState = timodel.StatePublic
+		job.BinlogInfo.AddTableInfo(2, tblInfo)
+		job.BinlogInfo.FinishedTS = txn.StartTS()
+		c.Assert(m.AddHistoryDDLJob(job, true), check.IsNil)
+	})
+	finishedMeta, err := kv.GetSnapshotMeta(store, finishedTs)
+	c.Assert(err, check.IsNil)
+	jobs, err = entry.RunningDDLJobs(finishedMeta)
+	c.Assert(err, check.IsNil)
+	c.Assert(jobs, check.HasLen, 0)
+
+	// the job is still running in the snapshot at the start ts, but the changefeed can be started
+	// after the job finishes with the wait policy
+	info.Config.StartTsInDDL = config.StartTsInDDLWait
+	c.Assert(checkDDLJobsAtStartTs(store, meta, "test-cf", info, startTs), check.IsNil)
+	info.Config.StartTsInDDL = config.StartTsInDDLReject
+	err = checkDDLJobsAtStartTs(store, meta, "test-cf", info, startTs)
+	c.Assert(cerror.ErrStartTsInRunningDDL.Equal(err), check.IsTrue)
+
+	// the schema at the start ts is the one before the job, and the job finishing after the start ts is
+	// replicated on top of it
+	storage, err := entry.NewSchemaStorage(meta, startTs, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(visibleColumns(c, storage, tblInfo.ID), check.Equals, 1)
+	history, err := finishedMeta.GetHistoryDDLJob(job.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(history.BinlogInfo.FinishedTS, check.Greater, startTs)
+	c.Assert(storage.HandleDDLJob(history), check.IsNil)
+	c.Assert(visibleColumns(c, storage, tblInfo.ID), check.Equals, 2)
+}
+
+func (s *startTsInDDLSuite) TestWaitingForDDLJobsMetric(c *check.C) {
+	startTsDDLWaitGauge.Reset()
+	defer startTsDDLWaitGauge.Reset()
+	owner := &Owner{}
+	err := cerror.ErrStartTsInRunningDDL.GenWithStackByArgs(100, "test-cf", 3, "alter table t add column a int", 90)
+	now := time.Now()
+	c.Assert(owner.isDDLJobsRecheckDue("test-cf", now), check.IsTrue)
+	owner.markWaitingForDDLJobs("test-cf", err, now)
+	owner.markWaitingForDDLJobs("test-cf", err, now.Add(5*time.Second))
+	c.Assert(testutil.ToFloat64(startTsDDLWaitGauge.WithLabelValues("test-cf")), check.Equals, float64(5))
+	// the jobs are checked again after the interval since the last check
+	c.Assert(owner.isDDLJobsRecheckDue("test-cf", now.Add(5*time.Second+startTsDDLRecheckInterval/2)), check.IsFalse)
+	c.Assert(owner.isDDLJobsRecheckDue("test-cf", now.Add(5*time.Second+startTsDDLRecheckInterval)), check.IsTrue)
+	owner.forgetWaitingForDDLJobs("test-cf")
+	c.Assert(owner.ddlWaitingFeeds, check.HasLen, 0)
+	c.Assert(testutil.CollectAndCount(startTsDDLWaitGauge), check.Equals, 0)
+}
@@ -74,6 +74,8 @@ type processor struct {
 	changefeedID string
 	changefeed   model.ChangeFeedInfo
 	limitter     *puller.BlurResourceLimitter
+	sortLimiter  *puller.SortLimiter
+	txnLimiter   *inflightTxnLimiter
 	stopped      int32
 
 	pdCli      pd.Client
@@ -83,12 +85,18 @@ type processor struct {
 	session    *concurrency.Session
 
 	sink sink.Sink
+	// quarantineSyncer is nil unless the tables failing to be written are quarantined
+	quarantineSyncer *quarantinedTablesSyncer
 
 	sinkEmittedResolvedTs   uint64
 	globalResolvedTs        uint64
 	localResolvedTs         uint64
 	checkpointTs            uint64
+	gcSafepoint             uint64
 	flushCheckpointInterval time.Duration
+	// sinkFlushMu avoids flushing the sink concurrently when the processor is stopped
+	sinkFlushMu       sync.Mutex
+	lastSinkFlushTime time.Time
 
 	ddlPuller       puller.Puller
 	ddlPullerCancel context.CancelFunc
@@ -198,6 +206,8 @@ func newProcessor(
 	p := &processor{
 		id:            uuid.New().String(),
 		limitter:      limitter,
+		sortLimiter:   puller.NewSortLimiter(captureInfo.AdvertiseAddr, changefeedID, changefeed.Config.Sorter.Concurrency),
+		txnLimiter:    newInflightTxnLimiter(changefeedID, captureInfo.AdvertiseAddr, changefeed.Config.MaxInflightTxns),
 		captureInfo:   captureInfo,
 		changefeedID:  changefeedID,
 		changefeed:    changefeed,
@@ -208,7 +218,7 @@ func newProcessor(
 		session:       session,
 		sink:          sink,
 		ddlPuller:     ddlPuller,
-		mounter:       entry.NewMounter(schemaStorage, changefeed.Config.Mounter.WorkerNum, changefeed.Config.EnableOldValue),
+		mounter:       entry.NewMounter(schemaStorage, kvStorage, changefeed.Config.Mounter.WorkerNum, changefeed.Config.EnableOldValue, changefeed.Config.Mounter.DeleteImage, changefeed.Config.Mounter.UnknownColumnType, changefeed.Config.Mounter.MissingColumnDefault, changefeed.Config.Mounter.TransientErrorRetry, changefeed.Config.Mounter.DecodeError),
 		schemaStorage: schemaStorage,
 		errCh:         errCh,
 
@@ -222,6 +232,7 @@ func newProcessor(
 		localResolvedReceiver: localResolvedNotifier.NewReceiver(50 * time.Millisecond),
 
 		checkpointTs:              checkpointTs,
+		flushCheckpointInterval:   effectiveCheckpointInterval(flushCheckpointInterval, changefeed.CheckpointInterval),
 		localCheckpointTsNotifier: localCheckpointTsNotifier,
 		localCheckpointTsReceiver: localCheckpointTsNotifier.NewReceiver(50 * time.Millisecond),
 
@@ -236,6 +247,7 @@ func newProcessor(
 	}
 	p.status = status
 	p.statusModRevision = modRevision
+	p.initQuarantinedTablesSyncer()
 
 	for tableID, replicaInfo := range p.status.Tables {
 		p.addTable(ctx, tableID, replicaInfo)
@@ -270,6 +282,14 @@ func (p *processor) Run(ctx context.Context) {
 		return p.collectMetrics(cctx)
 	})
 
+	wg.Go(func() error {
+		return p.gcSafepointWorker(cctx)
+	})
+
+	wg.Go(func() error {
+		return p.quarantinedTablesWorker(cctx)
+	})
+
 	wg.Go(func() error {
 		return p.ddlPuller.Run(ddlPullerCtx)
 	})
@@ -328,6 +348,8 @@ func (p *processor) writeDebugInfo(w io.Writer) {
 // 4, check admin command in TaskStatus and apply corresponding command
 func (p *processor) positionWorker(ctx context.Context) error {
 	lastFlushTime := time.Now()
+	lastResolvedFlushTime := time.Now()
+	flushedResolvedTs := p.position.ResolvedTs
 	retryFlushTaskStatusAndPosition := func() error {
 		t0Update := time.Now()
 		err := retry.Run(500*time.Millisecond, 3, func() error {
@@ -401,10 +423,18 @@ func (p *processor) positionWorker(ctx context.Context) error {
 
 			if p.position.ResolvedTs < minResolvedTs {
 				p.position.ResolvedTs = minResolvedTs
-				if err := retryFlushTaskStatusAndPosition(); err != nil {
-					return errors.Trace(err)
-				}
 			}
+			// the task position is written in the checkpoint interval of the changefeed,
+			// which delays the table operations as well
+			if !resolvedTsFlushDue(p.position.ResolvedTs, flushedResolvedTs,
+				time.Since(lastResolvedFlushTime), p.changefeed.CheckpointInterval) {
+				continue
+			}
+			if err := retryFlushTaskStatusAndPosition(); err != nil {
+				return errors.Trace(err)
+			}
+			flushedResolvedTs = p.position.ResolvedTs
+			lastResolvedFlushTime = time.Now()
 		case <-p.localCheckpointTsReceiver.C:
 			checkpointTs := atomic.LoadUint64(&p.checkpointTs)
 			if checkpointTs == 0 {
@@ -425,11 +455,19 @@ func (p *processor) positionWorker(ctx context.Context) error {
 			if err := retryFlushTaskStatusAndPosition(); err != nil {
 				return errors.Trace(err)
 			}
+			flushedResolvedTs = p.position.ResolvedTs
 			lastFlushTime = time.Now()
 		}
 	}
 }
 
+// resolvedTsFlushDue returns whether the resolved ts of the task position should be written, it's
+// written at most once in the checkpoint interval, and the resolved ts held back by the interval is
+// written once the interval passes even if it stops advancing.
+func resolvedTsFlushDue(resolvedTs, flushedResolvedTs model.Ts, sinceLastFlush, interval time.Duration) bool {
+	return resolvedTs != flushedResolvedTs && sinceLastFlush >= interval
+}
+
 func (p *processor) ddlPullWorker(ctx context.Context) error {
 	ddlRawKVCh := puller.SortOutput(ctx, p.ddlPuller.Output())
 	var ddlRawKV *model.RawKVEntry
@@ -487,6 +525,21 @@ func (p *processor) workloadWorker(ctx context.Context) error {
 	}
 }
 
+// newRunningError creates the RunningError of the error reported by the processor
+func newRunningError(addr string, err error) *model.RunningError {
+	var code string
+	if terror, ok := errors.Cause(err).(*errors.Error); ok {
+		code = string(terror.RFCCode())
+	} else {
+		code = string(cerror.ErrProcessorUnknown.RFCCode())
+	}
+	return &model.RunningError{
+		Addr:    addr,
+		Code:    code,
+		Message: err.Error(),
+	}
+}
+
 func (p *processor) flushTaskPosition(ctx context.Context) error {
 	failpoint.Inject("ProcessorUpdatePositionDelaying", func() {
 		time.Sleep(1 * time.Second)
@@ -495,6 +548,10 @@ func (p *processor) flushTaskPosition(ctx context.Context) error {
 		return cerror.ErrAdminStopProcessor.GenWithStackByArgs()
 	}
 	//p.position.Count = p.sink.Count()
+	p.position.Warning = nil
+	if warning := sink.Warning(p.sink); warning != nil {
+		p.position.Warning = newRunningError(p.captureInfo.AdvertiseAddr, warning)
+	}
 	updated, err := p.etcdCli.PutTaskPositionOnChange(ctx, p.changefeedID, p.captureInfo.ID, p.position)
 	if err != nil {
 		if errors.Cause(err) != context.Canceled {
@@ -531,6 +588,9 @@ func (p *processor) flushTaskStatusAndPosition(ctx context.Context) error {
 				}
 				return false, backoff.Permanent(cerror.ErrAdminStopProcessor.GenWithStackByArgs())
 			}
+			if err := p.syncQuarantinedTablesBeforeAdding(ctx, taskStatus); err != nil {
+				return false, errors.Trace(err)
+			}
 			toRemove, err := p.handleTables(ctx, taskStatus)
 			tablesToRemove = append(tablesToRemove, toRemove...)
 			if err != nil {
@@ -753,42 +813,80 @@ func (p *processor) globalStatusWorker(ctx context.Context) error {
 	}
 }
 
+// effectiveCheckpointInterval returns the interval the processor of a changefeed flushes the
+// checkpoint in, the interval of the changefeed takes effect if it is larger than the capture's.
+func effectiveCheckpointInterval(captureInterval, changefeedInterval time.Duration) time.Duration {
+	if changefeedInterval > captureInterval {
+		return changefeedInterval
+	}
+	return captureInterval
+}
+
 func (p *processor) sinkDriver(ctx context.Context) error {
 	metricFlushDuration := sinkFlushRowChangedDuration.WithLabelValues(p.changefeedID, p.captureInfo.AdvertiseAddr)
+	// the resolved ts is still forwarded in time, while the sink is flushed in the checkpoint
+	// interval of the changefeed, the ticker flushes the events pending for the interval.
+	var tickerCh <-chan time.Time
+	if p.changefeed.CheckpointInterval > 0 {
+		ticker := time.NewTicker(p.changefeed.CheckpointInterval)
+		defer ticker.Stop()
+		tickerCh = ticker.C
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-p.sinkEmittedResolvedReceiver.C:
-			sinkEmittedResolvedTs := atomic.LoadUint64(&p.sinkEmittedResolvedTs)
-			globalResolvedTs := atomic.LoadUint64(&p.globalResolvedTs)
-			var minTs uint64
-			if sinkEmittedResolvedTs < globalResolvedTs {
-				minTs = sinkEmittedResolvedTs
-			} else {
-				minTs = globalResolvedTs
-			}
-			if minTs == 0 || atomic.LoadUint64(&p.checkpointTs) == minTs {
-				continue
-			}
-			start := time.Now()
+		case <-tickerCh:
+		}
+		start := time.Now()
+		flushed, err := p.flushSink(ctx, false /* force */)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !flushed {
+			continue
+		}
+		dur := time.Since(start)
+		metricFlushDuration.Observe(dur.Seconds())
+		if dur > 3*time.Second {
+			log.Warn("flush row changed events too slow", zap.Duration("duration", dur))
+		}
+	}
+}
 
-			checkpointTs, err := p.sink.FlushRowChangedEvents(ctx, minTs)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			if checkpointTs != 0 {
-				atomic.StoreUint64(&p.checkpointTs, checkpointTs)
-				p.localCheckpointTsNotifier.Notify()
-			}
+// flushSink flushes the events before the min of the sink emitted resolved ts and the global
+// resolved ts into the sink, and returns whether the sink is flushed. The sink is flushed at
+// most once in the checkpoint interval of the changefeed unless force is true.
+func (p *processor) flushSink(ctx context.Context, force bool) (bool, error) {
+	p.sinkFlushMu.Lock()
+	defer p.sinkFlushMu.Unlock()
+	if !force && time.Since(p.lastSinkFlushTime) < p.changefeed.CheckpointInterval {
+		return false, nil
+	}
+	sinkEmittedResolvedTs := atomic.LoadUint64(&p.sinkEmittedResolvedTs)
+	globalResolvedTs := atomic.LoadUint64(&p.globalResolvedTs)
+	var minTs uint64
+	if sinkEmittedResolvedTs < globalResolvedTs {
+		minTs = sinkEmittedResolvedTs
+	} else {
+		minTs = globalResolvedTs
+	}
+	if minTs == 0 || atomic.LoadUint64(&p.checkpointTs) == minTs {
+		return false, nil
+	}
+	p.lastSinkFlushTime = time.Now()
 
-			dur := time.Since(start)
-			metricFlushDuration.Observe(dur.Seconds())
-			if dur > 3*time.Second {
-				log.Warn("flush row changed events too slow", zap.Duration("duration", dur))
-			}
-		}
+	checkpointTs, err := p.sink.FlushRowChangedEvents(ctx, minTs)
+	if err != nil {
+		return false, errors.Trace(err)
 	}
+	if checkpointTs != 0 {
+		atomic.StoreUint64(&p.checkpointTs, checkpointTs)
+		p.txnLimiter.release(checkpointTs)
+		p.localCheckpointTsNotifier.Notify()
+	}
+	return true, nil
 }
 
 // syncResolved handle `p.ddlJobsCh` and `p.resolvedTxns`
@@ -800,6 +898,7 @@ func (p *processor) syncResolved(ctx context.Context) error {
 
 	events := make([]*model.PolymorphicEvent, 0, defaultSyncResolvedBatch)
 	rows := make([]*model.RowChangedEvent, 0, defaultSyncResolvedBatch)
+	rowCounter := newRowOperationCounter(p.changefeedID, p.captureInfo.AdvertiseAddr)
 
 	flushRowChangedEvents := func() error {
 		for _, ev := range events {
@@ -821,6 +920,7 @@ func (p *processor) syncResolved(ctx context.Context) error {
 		if err != nil {
 			return errors.Trace(err)
 		}
+		rowCounter.record(rows)
 		events = events[:0]
 		rows = rows[:0]
 		return nil
@@ -971,7 +1071,9 @@ func (p *processor) addTable(ctx context.Context, tableID int64, replicaInfo *mo
 		var sorterImpl puller.EventSorter
 		switch p.changefeed.Engine {
 		case model.SortInMemory:
-			sorterImpl = puller.NewEntrySorter()
+			entrySorter := puller.NewEntrySorter()
+			entrySorter.SetSortLimiter(p.sortLimiter)
+			sorterImpl = entrySorter
 		case model.SortInFile:
 			err := util.IsDirAndWritable(p.changefeed.SortDir)
 			if err != nil {
@@ -986,7 +1088,9 @@ func (p *processor) addTable(ctx context.Context, tableID int64, replicaInfo *mo
 					return nil
 				}
 			}
-			sorterImpl = puller.NewFileSorter(p.changefeed.SortDir)
+			fileSorter := puller.NewFileSorter(p.changefeed.SortDir)
+			fileSorter.SetSortLimiter(p.sortLimiter)
+			sorterImpl = fileSorter
 		default:
 			p.errCh <- cerror.ErrUnknownSortEngine.GenWithStackByArgs(p.changefeed.Engine)
 			return nil
@@ -1050,6 +1154,8 @@ func (p *processor) sorterConsume(
 	var lastResolvedTs uint64
 	opDone := false
 	resolvedTsGauge := tableResolvedTsGauge.WithLabelValues(p.changefeedID, p.captureInfo.AdvertiseAddr, tableName)
+	guard := newLateEventGuard(p.changefeedID, p.captureInfo.AdvertiseAddr, tableID, replicaInfo.StartTs, p.changefeed.Config.StrictConsistency)
+	gcGuard := newGCSpanningTxnGuard(p.changefeedID, p.captureInfo.AdvertiseAddr, tableID, &p.gcSafepoint, p.changefeed.Config.GCSpanningTxn)
 	checkDoneTicker := time.NewTicker(1 * time.Second)
 	checkDone := func() {
 		localResolvedTs := atomic.LoadUint64(&p.localResolvedTs)
@@ -1093,6 +1199,8 @@ func (p *processor) sorterConsume(
 			if pEvent.RawKV != nil && pEvent.RawKV.OpType == model.OpTypeResolved {
 				atomic.StoreUint64(pResolvedTs, pEvent.CRTs)
 				lastResolvedTs = pEvent.CRTs
+				guard.advance(pEvent.CRTs)
+				gcGuard.advance(pEvent.CRTs)
 				p.localResolvedNotifier.Notify()
 				resolvedTsGauge.Set(float64(oracle.ExtractPhysical(pEvent.CRTs)))
 				if !opDone {
@@ -1100,15 +1208,23 @@ func (p *processor) sorterConsume(
 				}
 				continue
 			}
-			sinkResolvedTs := atomic.LoadUint64(&p.sinkEmittedResolvedTs)
-			if pEvent.CRTs <= lastResolvedTs || pEvent.CRTs < replicaInfo.StartTs {
-				log.Fatal("The CRTs of event is not expected, please report a bug",
-					zap.String("model", "sorter"),
-					zap.Uint64("globalResolvedTs", sinkResolvedTs),
-					zap.Uint64("resolvedTs", lastResolvedTs),
-					zap.Int64("tableID", tableID),
-					zap.Any("replicaInfo", replicaInfo),
-					zap.Any("row", pEvent))
+			emit, err := guard.check(pEvent)
+			if err != nil {
+				p.errCh <- err
+				return
+			}
+			if !emit {
+				continue
+			}
+			if err := gcGuard.check(pEvent); err != nil {
+				p.errCh <- err
+				return
+			}
+			if err := p.txnLimiter.acquire(ctx, pEvent.CRTs, atomic.LoadUint64(pResolvedTs)); err != nil {
+				if errors.Cause(err) != context.Canceled {
+					p.errCh <- err
+				}
+				return
 			}
 			select {
 			case <-ctx.Done():
@@ -1168,6 +1284,11 @@ func (p *processor) stop(ctx context.Context) error {
 	// mark tables share the same context with its original table, don't need to cancel
 	p.stateMu.Unlock()
 	atomic.StoreInt32(&p.stopped, 1)
+	// flush the pending events regardless of the checkpoint interval
+	if _, err := p.flushSink(ctx, true /* force */); err != nil {
+		log.Warn("failed to flush sink before stopping processor",
+			zap.String("changefeed", p.changefeedID), zap.Error(err))
+	}
 	if err := p.etcdCli.DeleteTaskPosition(ctx, p.changefeedID, p.captureInfo.ID); err != nil {
 		return err
 	}
@@ -1195,12 +1316,13 @@ func runProcessor(
 	checkpointTs uint64,
 	flushCheckpointInterval time.Duration,
 ) (*processor, error) {
-	opts := make(map[string]string, len(info.Opts)+2)
+	opts := make(map[string]string, len(info.Opts)+3)
 	for k, v := range info.Opts {
 		opts[k] = v
 	}
 	opts[sink.OptChangefeedID] = changefeedID
 	opts[sink.OptCaptureAddr] = captureInfo.AdvertiseAddr
+	opts[sink.OptCaptureID] = captureInfo.ID
 	ctx = util.PutChangefeedIDInCtx(ctx, changefeedID)
 	filter, err := filter.NewFilter(info.Config)
 	if err != nil {
@@ -1236,17 +1358,7 @@ func runProcessor(
 				zap.String("processorid", processor.id),
 				zap.Error(err))
 			// record error information in etcd
-			var code string
-			if terror, ok := err.(*errors.Error); ok {
-				code = string(terror.RFCCode())
-			} else {
-				code = string(cerror.ErrProcessorUnknown.RFCCode())
-			}
-			processor.position.Error = &model.RunningError{
-				Addr:    captureInfo.AdvertiseAddr,
-				Code:    code,
-				Message: err.Error(),
-			}
+			processor.position.Error = newRunningError(captureInfo.AdvertiseAddr, err)
 			_, err = processor.etcdCli.PutTaskPositionOnChange(ctx, processor.changefeedID, processor.captureInfo.ID, processor.position)
 			if err != nil {
 				log.Warn("upload processor error failed", zap.Error(err))
@@ -0,0 +1,174 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/notify"
+)
+
+// flushCountingSink counts the flushes of the underlying sink
+type flushCountingSink struct {
+	sink.Sink
+	flushCount int64
+}
+
+func (s *flushCountingSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) (uint64, error) {
+	atomic.AddInt64(&s.flushCount, 1)
+	return s.Sink.FlushRowChangedEvents(ctx, resolvedTs)
+}
+
+type processorFlushSuite struct{}
+
+var _ = check.Suite(&processorFlushSuite{})
+
+func newFlushTestProcessor(c *check.C, interval time.Duration) (*processor, *flushCountingSink) {
+	s, err := sink.NewSink(context.Background(), "test-flush", "blackhole://", nil, config.GetDefaultReplicaConfig(), map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+	countingSink := &flushCountingSink{Sink: s}
+	sinkEmittedResolvedNotifier := new(notify.Notifier)
+	localCheckpointTsNotifier := new(notify.Notifier)
+	p := &processor{
+		changefeedID:                "test-flush",
+		changefeed:                  model.ChangeFeedInfo{CheckpointInterval: interval},
+		sink:                        countingSink,
+		sinkEmittedResolvedNotifier: sinkEmittedResolvedNotifier,
+		sinkEmittedResolvedReceiver: sinkEmittedResolvedNotifier.NewReceiver(10 * time.Millisecond),
+		localCheckpointTsNotifier:   localCheckpointTsNotifier,
+	}
+	return p, countingSink
+}
+
+// countFlushes drives the sink of the processor for the duration and returns the flush count
+func countFlushes(c *check.C, interval, duration time.Duration) int64 {
+	p, countingSink := newFlushTestProcessor(c, interval)
+	defer p.sinkEmittedResolvedNotifier.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.sinkDriver(ctx)
+	}()
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for ts := uint64(1); ; ts++ {
+		select {
+		case <-ctx.Done():
+			c.Assert(<-errCh, check.Equals, context.DeadlineExceeded)
+			// the last resolved ts is flushed eventually
+			c.Assert(atomic.LoadUint64(&p.checkpointTs) > 0, check.IsTrue)
+			return atomic.LoadInt64(&countingSink.flushCount)
+		case <-ticker.C:
+			atomic.StoreUint64(&p.globalResolvedTs, ts)
+			atomic.StoreUint64(&p.sinkEmittedResolvedTs, ts)
+			p.sinkEmittedResolvedNotifier.Notify()
+		}
+	}
+}
+
+func (s *processorFlushSuite) TestFlushCountWithCheckpointInterval(c *check.C) {
+	duration := 500 * time.Millisecond
+	noInterval := countFlushes(c, 0, duration)
+	withInterval := countFlushes(c, 200*time.Millisecond, duration)
+	c.Assert(noInterval, check.Greater, int64(10))
+	c.Assert(withInterval, check.GreaterEqual, int64(1))
+	c.Assert(withInterval, check.LessEqual, int64(3))
+}
+
+func (s *processorFlushSuite) TestForceFlush(c *check.C) {
+	ctx := context.Background()
+	p, countingSink := newFlushTestProcessor(c, time.Hour)
+	atomic.StoreUint64(&p.globalResolvedTs, 100)
+	atomic.StoreUint64(&p.sinkEmittedResolvedTs, 100)
+	flushed, err := p.flushSink(ctx, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(flushed, check.IsTrue)
+	c.Assert(atomic.LoadUint64(&p.checkpointTs), check.Equals, uint64(100))
+
+	// the events are pending in the checkpoint interval
+	atomic.StoreUint64(&p.globalResolvedTs, 200)
+	atomic.StoreUint64(&p.sinkEmittedResolvedTs, 200)
+	flushed, err = p.flushSink(ctx, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(flushed, check.IsFalse)
+	c.Assert(atomic.LoadInt64(&countingSink.flushCount), check.Equals, int64(1))
+
+	// the processor flushes the sink immediately when it is stopped
+	flushed, err = p.flushSink(ctx, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(flushed, check.IsTrue)
+	c.Assert(atomic.LoadUint64(&p.checkpointTs), check.Equals, uint64(200))
+	c.Assert(atomic.LoadInt64(&countingSink.flushCount), check.Equals, int64(2))
+}
+
+func (s *processorFlushSuite) TestEffectiveCheckpointInterval(c *check.C) {
+	c.Assert(effectiveCheckpointInterval(100*time.Millisecond, 0), check.Equals, 100*time.Millisecond)
+	c.Assert(effectiveCheckpointInterval(100*time.Millisecond, time.Minute), check.Equals, time.Minute)
+	c.Assert(effectiveCheckpointInterval(time.Second, 10*time.Millisecond), check.Equals, time.Second)
+}
+
+func (s *processorFlushSuite) TestResolvedTsFlushDue(c *check.C) {
+	c.Assert(resolvedTsFlushDue(100, 90, 0, 0), check.IsTrue)
+	c.Assert(resolvedTsFlushDue(100, 100, 0, 0), check.IsFalse)
+	// the resolved ts is held back in the interval
+	c.Assert(resolvedTsFlushDue(100, 90, 10*time.Second, time.Minute), check.IsFalse)
+	// and written once the interval passes though it stops advancing
+	c.Assert(resolvedTsFlushDue(100, 90, time.Minute, time.Minute), check.IsTrue)
+	c.Assert(resolvedTsFlushDue(100, 100, time.Hour, time.Minute), check.IsFalse)
+}
+
+func (s *ownerSuite) TestGCSafepointHoldsFlushedCheckpoint(c *check.C) {
+	ctx := s.ctx
+	pdCli := &safepointPDClient{}
+	cf := s.newPriorityTestChangefeed(c, "flush-interval", config.PriorityClassNormal, 1000)
+	cf.flushedCheckpointTs = 1000
+	owner := &Owner{
+		changeFeeds:             map[model.ChangeFeedID]*changeFeed{cf.id: cf},
+		stoppedFeeds:            make(map[model.ChangeFeedID]*model.ChangeFeedStatus),
+		gcShedFeeds:             make(map[model.ChangeFeedID]struct{}),
+		pdClient:                pdCli,
+		cfRWriter:               s.client,
+		etcdClient:              s.client,
+		flushChangefeedInterval: time.Hour,
+	}
+	flush := func() {
+		owner.gcSafepointLastUpdate = time.Time{}
+		c.Assert(owner.flushChangeFeedInfos(ctx), check.IsNil)
+	}
+	flush()
+	c.Assert(pdCli.safepoint, check.Equals, uint64(1000))
+
+	// the checkpoint not written to etcd yet doesn't move the safepoint forward,
+	// a new owner would resume the changefeed from the written one
+	cf.status.CheckpointTs = 2000
+	flush()
+	c.Assert(pdCli.safepoint, check.Equals, uint64(1000))
+	status, _, err := s.client.GetChangeFeedStatus(ctx, cf.id)
+	c.Assert(err, check.IsNil)
+	c.Assert(status.CheckpointTs, check.Equals, uint64(1000))
+
+	owner.lastFlushChangefeeds = time.Time{}
+	flush()
+	c.Assert(pdCli.safepoint, check.Equals, uint64(2000))
+	status, _, err = s.client.GetChangeFeedStatus(ctx, cf.id)
+	c.Assert(err, check.IsNil)
+	c.Assert(status.CheckpointTs, check.Equals, uint64(2000))
+}
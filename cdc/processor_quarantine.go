@@ -0,0 +1,132 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	"go.uber.org/zap"
+)
+
+const quarantinedTablesSyncInterval = 5 * time.Second
+
+// quarantinedTablesSyncer keeps the tables quarantined by the sink in sync with etcd. The tables
+// quarantined by the sink are persisted, the tables persisted by the other processors are
+// quarantined, and the tables whose keys are deleted, i.e. released by the API, are released.
+type quarantinedTablesSyncer struct {
+	changefeedID string
+	etcdCli      kv.CDCEtcdClient
+	quarantiner  sink.TableQuarantiner
+
+	mu sync.Mutex
+	// persisted are the tables quarantined by the sink which are known to be persisted
+	persisted map[model.TableID]struct{}
+}
+
+func newQuarantinedTablesSyncer(
+	changefeedID string, etcdCli kv.CDCEtcdClient, quarantiner sink.TableQuarantiner,
+) *quarantinedTablesSyncer {
+	return &quarantinedTablesSyncer{
+		changefeedID: changefeedID,
+		etcdCli:      etcdCli,
+		quarantiner:  quarantiner,
+		persisted:    make(map[model.TableID]struct{}),
+	}
+}
+
+func (s *quarantinedTablesSyncer) sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	persisted, err := s.etcdCli.GetQuarantinedTables(ctx, s.changefeedID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	quarantined := make(map[model.TableID]struct{})
+	for _, table := range s.quarantiner.QuarantinedTables() {
+		quarantined[table.TableID] = struct{}{}
+		if _, ok := persisted[table.TableID]; ok {
+			s.persisted[table.TableID] = struct{}{}
+			continue
+		}
+		if _, ok := s.persisted[table.TableID]; ok {
+			s.quarantiner.ReleaseTable(table.TableID)
+			delete(s.persisted, table.TableID)
+			continue
+		}
+		if err := s.etcdCli.PutQuarantinedTable(ctx, s.changefeedID, table); err != nil {
+			return errors.Trace(err)
+		}
+		s.persisted[table.TableID] = struct{}{}
+	}
+	for tableID, table := range persisted {
+		if _, ok := quarantined[tableID]; !ok {
+			s.quarantiner.QuarantineTable(table)
+			s.persisted[tableID] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// initQuarantinedTablesSyncer creates the syncer of the quarantined tables if the tables failing to be
+// written are quarantined
+func (p *processor) initQuarantinedTablesSyncer() {
+	if p.changefeed.Config.Sink.TableErrorPolicy != config.TableErrorPolicyQuarantine {
+		return
+	}
+	quarantiner, ok := p.sink.(sink.TableQuarantiner)
+	if !ok {
+		log.Warn("the sink doesn't support quarantining the tables", zap.String("changefeed", p.changefeedID))
+		return
+	}
+	p.quarantineSyncer = newQuarantinedTablesSyncer(p.changefeedID, p.etcdCli, quarantiner)
+}
+
+// syncQuarantinedTablesBeforeAdding syncs the quarantined tables if the task status adds any table,
+// so that a table released and re-scanned is released by the sink before its rows are re-scanned
+func (p *processor) syncQuarantinedTablesBeforeAdding(ctx context.Context, status *model.TaskStatus) error {
+	if p.quarantineSyncer == nil {
+		return nil
+	}
+	for _, opt := range status.Operation {
+		if !opt.Delete && !opt.TableProcessed() {
+			return errors.Trace(p.quarantineSyncer.sync(ctx))
+		}
+	}
+	return nil
+}
+
+// quarantinedTablesWorker syncs the tables quarantined by the sink with etcd periodically
+func (p *processor) quarantinedTablesWorker(ctx context.Context) error {
+	if p.quarantineSyncer == nil {
+		return nil
+	}
+	for {
+		if err := p.quarantineSyncer.sync(ctx); err != nil {
+			log.Warn("failed to sync the quarantined tables", zap.String("changefeed", p.changefeedID), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(quarantinedTablesSyncInterval):
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type mockTableQuarantiner struct {
+	tables map[model.TableID]*model.QuarantinedTable
+}
+
+func (q *mockTableQuarantiner) QuarantinedTables() []*model.QuarantinedTable {
+	tables := make([]*model.QuarantinedTable, 0, len(q.tables))
+	for _, table := range q.tables {
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+func (q *mockTableQuarantiner) QuarantineTable(table *model.QuarantinedTable) {
+	q.tables[table.TableID] = table
+}
+
+func (q *mockTableQuarantiner) ReleaseTable(tableID model.TableID) {
+	delete(q.tables, tableID)
+}
+
+func (s *ownerSuite) TestSyncQuarantinedTables(c *check.C) {
+	ctx := s.ctx
+	changefeedID := "quarantine-test"
+	// table 3 is quarantined by another processor
+	err := s.client.PutQuarantinedTable(ctx, changefeedID, &model.QuarantinedTable{TableID: 3, CaptureID: "another"})
+	c.Assert(err, check.IsNil)
+
+	quarantiner := &mockTableQuarantiner{tables: map[model.TableID]*model.QuarantinedTable{
+		1: {TableID: 1, CaptureID: "capture"},
+	}}
+	syncer := newQuarantinedTablesSyncer(changefeedID, s.client, quarantiner)
+	c.Assert(syncer.sync(ctx), check.IsNil)
+	persisted, err := s.client.GetQuarantinedTables(ctx, changefeedID)
+	c.Assert(err, check.IsNil)
+	c.Assert(persisted, check.HasLen, 2)
+	c.Assert(persisted[1].CaptureID, check.Equals, "capture")
+	c.Assert(quarantiner.tables, check.HasLen, 2)
+	c.Assert(quarantiner.tables[3].CaptureID, check.Equals, "another")
+
+	// the tables released by the API are released by the sink
+	quarantiner.QuarantineTable(&model.QuarantinedTable{TableID: 2})
+	c.Assert(s.client.DeleteQuarantinedTable(ctx, changefeedID, 1), check.IsNil)
+	c.Assert(s.client.DeleteQuarantinedTable(ctx, changefeedID, 3), check.IsNil)
+	c.Assert(syncer.sync(ctx), check.IsNil)
+	c.Assert(quarantiner.tables, check.HasLen, 1)
+	c.Assert(quarantiner.tables[2], check.NotNil)
+	persisted, err = s.client.GetQuarantinedTables(ctx, changefeedID)
+	c.Assert(err, check.IsNil)
+	c.Assert(persisted, check.HasLen, 1)
+	c.Assert(persisted[2], check.NotNil)
+}
+
+func (s *ownerSuite) TestSyncQuarantinedTablesBeforeAdding(c *check.C) {
+	ctx := s.ctx
+	changefeedID := "quarantine-add-test"
+	quarantiner := &mockTableQuarantiner{tables: make(map[model.TableID]*model.QuarantinedTable)}
+	p := &processor{quarantineSyncer: newQuarantinedTablesSyncer(changefeedID, s.client, quarantiner)}
+	quarantiner.QuarantineTable(&model.QuarantinedTable{TableID: 1})
+	c.Assert(p.quarantineSyncer.sync(ctx), check.IsNil)
+
+	// the table is released and re-scanned, it's released before it's added back
+	c.Assert(s.client.DeleteQuarantinedTable(ctx, changefeedID, 1), check.IsNil)
+	status := &model.TaskStatus{Operation: map[model.TableID]*model.TableOperation{1: {Delete: true}}}
+	c.Assert(p.syncQuarantinedTablesBeforeAdding(ctx, status), check.IsNil)
+	c.Assert(quarantiner.tables, check.HasLen, 1)
+	status.Operation[1] = &model.TableOperation{BoundaryTs: 599}
+	c.Assert(p.syncQuarantinedTablesBeforeAdding(ctx, status), check.IsNil)
+	c.Assert(quarantiner.tables, check.HasLen, 0)
+}
@@ -38,6 +38,7 @@ type EntrySorter struct {
 
 	outputCh         chan *model.PolymorphicEvent
 	resolvedNotifier *notify.Notifier
+	sortLimiter      *SortLimiter
 }
 
 // NewEntrySorter creates a new EntrySorter
@@ -48,6 +49,11 @@ func NewEntrySorter() *EntrySorter {
 	}
 }
 
+// SetSortLimiter makes the sorter share the limit of the concurrent sort operations, it must be called before Run
+func (es *EntrySorter) SetSortLimiter(l *SortLimiter) {
+	es.sortLimiter = l
+}
+
 // Run runs EntrySorter
 func (es *EntrySorter) Run(ctx context.Context) error {
 	captureAddr := util.CaptureAddrFromCtx(ctx)
@@ -139,14 +145,21 @@ func (es *EntrySorter) Run(ctx context.Context) error {
 					resEvents[i] = model.NewResolvedPolymorphicEvent(0, rts)
 				}
 				toSort = append(toSort, resEvents...)
-				startTime := time.Now()
-				sort.Slice(toSort, func(i, j int) bool {
-					return lessFunc(toSort[i], toSort[j])
+				err := es.sortLimiter.run(ctx, func() error {
+					startTime := time.Now()
+					sort.Slice(toSort, func(i, j int) bool {
+						return lessFunc(toSort[i], toSort[j])
+					})
+					metricEntrySorterSortDuration.Observe(time.Since(startTime).Seconds())
+					return nil
 				})
-				metricEntrySorterSortDuration.Observe(time.Since(startTime).Seconds())
+				if err != nil {
+					// the context is canceled while waiting for the turn
+					continue
+				}
 				maxResolvedTs := resolvedTsGroup[len(resolvedTsGroup)-1]
 
-				startTime = time.Now()
+				startTime := time.Now()
 				var merged []*model.PolymorphicEvent
 				mergeFunc(toSort, sorted, func(entry *model.PolymorphicEvent) {
 					if entry.CRTs <= maxResolvedTs {
@@ -178,10 +178,11 @@ func (cache *fileCache) flush(ctx context.Context, entries []*model.PolymorphicE
 // FileSorter accepts out-of-order raw kv entries, sort in local file system
 // and output sorted entries
 type FileSorter struct {
-	dir      string
-	outputCh chan *model.PolymorphicEvent
-	inputCh  chan *model.PolymorphicEvent
-	cache    *fileCache
+	dir         string
+	outputCh    chan *model.PolymorphicEvent
+	inputCh     chan *model.PolymorphicEvent
+	cache       *fileCache
+	sortLimiter *SortLimiter
 }
 
 // flushEventsToFile writes a slice of model.PolymorphicEvent to a given file in sequence
@@ -239,6 +240,11 @@ func NewFileSorter(dir string) *FileSorter {
 	return fs
 }
 
+// SetSortLimiter makes the sorter share the limit of the concurrent sort operations, it must be called before Run
+func (fs *FileSorter) SetSortLimiter(l *SortLimiter) {
+	fs.sortLimiter = l
+}
+
 // sortItem is used in PolymorphicEvent merge procedure from sorted files
 type sortItem struct {
 	entry     *model.PolymorphicEvent
@@ -371,21 +377,27 @@ func (fs *FileSorter) rotate(ctx context.Context, resolvedTs uint64) error {
 	// prepare buffer reader of all sorted files
 	readers := make([]*bufio.Reader, 0, len(files)+1)
 	toRemoveFiles := make([]string, 0, len(files)+1)
-	for _, f := range files {
-		sortedFile, err := sortSingleFile(ctx, f)
-		if err != nil {
-			return errors.Trace(err)
-		}
-		if sortedFile == "" {
-			continue
-		}
-		toRemoveFiles = append(toRemoveFiles, sortedFile)
-		fd, err := os.Open(filepath.Join(fs.dir, sortedFile))
-		if err != nil {
-			return errors.Trace(err)
+	err := fs.sortLimiter.run(ctx, func() error {
+		for _, f := range files {
+			sortedFile, err := sortSingleFile(ctx, f)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if sortedFile == "" {
+				continue
+			}
+			toRemoveFiles = append(toRemoveFiles, sortedFile)
+			fd, err := os.Open(filepath.Join(fs.dir, sortedFile))
+			if err != nil {
+				return errors.Trace(err)
+			}
+			rd := bufio.NewReader(fd)
+			readers = append(readers, rd)
 		}
-		rd := bufio.NewReader(fd)
-		readers = append(readers, rd)
+		return nil
+	})
+	if err != nil {
+		return errors.Trace(err)
 	}
 	if fs.cache.lastSortedFile != "" {
 		toRemoveFiles = append(toRemoveFiles, fs.cache.lastSortedFile)
@@ -103,6 +103,13 @@ var (
 			Help:      "Bucketed histogram of processing time (s) of merge in entry sorter.",
 			Buckets:   prometheus.ExponentialBuckets(0.000001, 10, 10),
 		}, []string{"capture", "changefeed", "table"})
+	sorterActiveSortsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "puller",
+			Name:      "sorter_active_sort_count",
+			Help:      "The number of the running sort operations of the sorters of a changefeed",
+		}, []string{"capture", "changefeed"})
 )
 
 // InitMetrics registers all metrics in this file
@@ -118,4 +125,5 @@ func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(entrySorterUnsortedSizeGauge)
 	registry.MustRegister(entrySorterSortDuration)
 	registry.MustRegister(entrySorterMergeDuration)
+	registry.MustRegister(sorterActiveSortsGauge)
 }
@@ -0,0 +1,57 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/semaphore"
+)
+
+// SortLimiter bounds the number of the concurrent sort operations of the sorters sharing it,
+// which are usually the sorters of all the tables of a changefeed. The sort operations
+// exceeding the limit wait for their turns. A nil SortLimiter doesn't limit anything.
+type SortLimiter struct {
+	// sem is nil if the concurrent sort operations are unlimited
+	sem *semaphore.Weighted
+	// metricActiveSorts is the number of the running sort operations
+	metricActiveSorts prometheus.Gauge
+}
+
+// NewSortLimiter creates a SortLimiter, the sort operations are unlimited if the concurrency is not positive
+func NewSortLimiter(captureAddr, changefeedID string, concurrency int) *SortLimiter {
+	l := &SortLimiter{metricActiveSorts: sorterActiveSortsGauge.WithLabelValues(captureAddr, changefeedID)}
+	if concurrency > 0 {
+		l.sem = semaphore.NewWeighted(int64(concurrency))
+	}
+	return l
+}
+
+// run runs the sort operation once it's allowed to
+func (l *SortLimiter) run(ctx context.Context, sort func() error) error {
+	if l == nil {
+		return sort()
+	}
+	if l.sem != nil {
+		if err := l.sem.Acquire(ctx, 1); err != nil {
+			return errors.Trace(err)
+		}
+		defer l.sem.Release(1)
+	}
+	l.metricActiveSorts.Inc()
+	defer l.metricActiveSorts.Dec()
+	return sort()
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package puller
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/sync/errgroup"
+)
+
+type sortLimiterSuite struct{}
+
+var _ = check.Suite(&sortLimiterSuite{})
+
+func (s *sortLimiterSuite) TestSortLimiter(c *check.C) {
+	const (
+		tableCount  = 16
+		concurrency = 2
+	)
+	ctx := context.Background()
+	limiter := NewSortLimiter("capture", "sort-limiter-test", concurrency)
+	var running, maxRunning int64
+	var wg sync.WaitGroup
+	for i := 0; i < tableCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := limiter.run(ctx, func() error {
+				n := atomic.AddInt64(&running, 1)
+				for {
+					max := atomic.LoadInt64(&maxRunning)
+					if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+						break
+					}
+				}
+				c.Assert(testutil.ToFloat64(limiter.metricActiveSorts), check.LessEqual, float64(concurrency))
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt64(&running, -1)
+				return nil
+			})
+			c.Assert(err, check.IsNil)
+		}()
+	}
+	wg.Wait()
+	c.Assert(atomic.LoadInt64(&maxRunning), check.Equals, int64(concurrency))
+	c.Assert(testutil.ToFloat64(limiter.metricActiveSorts), check.Equals, float64(0))
+
+	// the waiting sort operation is canceled with the context
+	limiter = NewSortLimiter("capture", "sort-limiter-test", 1)
+	c.Assert(limiter.sem.TryAcquire(1), check.IsTrue)
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err := limiter.run(cctx, func() error {
+		c.Fatal("the sort operation is not canceled")
+		return nil
+	})
+	c.Assert(err, check.ErrorMatches, ".*context canceled.*")
+
+	// the nil and unlimited limiters never block
+	var nilLimiter *SortLimiter
+	c.Assert(nilLimiter.run(cctx, func() error { return nil }), check.IsNil)
+	limiter = NewSortLimiter("capture", "sort-limiter-test", 0)
+	c.Assert(limiter.sem, check.IsNil)
+	c.Assert(limiter.run(cctx, func() error { return nil }), check.IsNil)
+}
+
+func (s *sortLimiterSuite) TestEntrySortersShareSortLimiter(c *check.C) {
+	const (
+		tableCount  = 64
+		concurrency = 2
+		entryCount  = 2000
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	limiter := NewSortLimiter("capture", "sort-limiter-many-tables", concurrency)
+
+	// sample the active sort operations while the tables are sorted
+	var maxActive int64
+	sampleDone := make(chan struct{})
+	sampleCtx, stopSample := context.WithCancel(ctx)
+	go func() {
+		defer close(sampleDone)
+		for {
+			select {
+			case <-sampleCtx.Done():
+				return
+			default:
+			}
+			if n := int64(testutil.ToFloat64(limiter.metricActiveSorts)); n > atomic.LoadInt64(&maxActive) {
+				atomic.StoreInt64(&maxActive, n)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	sortCtx, stopSort := context.WithCancel(ctx)
+	sorters := make([]*EntrySorter, tableCount)
+	runErrg := new(errgroup.Group)
+	for i := range sorters {
+		sorter := NewEntrySorter()
+		sorter.SetSortLimiter(limiter)
+		sorters[i] = sorter
+		runErrg.Go(func() error {
+			return sorter.Run(sortCtx)
+		})
+	}
+	const resolvedTs = uint64(entryCount + 1)
+	for _, sorter := range sorters {
+		for _, ts := range rand.Perm(entryCount) {
+			sorter.AddEntry(ctx, model.NewPolymorphicEvent(&model.RawKVEntry{OpType: model.OpTypePut, CRTs: uint64(ts + 1)}))
+		}
+		sorter.AddEntry(ctx, model.NewResolvedPolymorphicEvent(0, resolvedTs))
+	}
+	checkErrg := new(errgroup.Group)
+	for _, sorter := range sorters {
+		sorter := sorter
+		checkErrg.Go(func() error {
+			lastTs := uint64(0)
+			for i := 0; i <= entryCount; i++ {
+				ev := <-sorter.Output()
+				c.Assert(ev.CRTs, check.Greater, lastTs)
+				lastTs = ev.CRTs
+			}
+			c.Assert(lastTs, check.Equals, resolvedTs)
+			return nil
+		})
+	}
+	c.Assert(checkErrg.Wait(), check.IsNil)
+	stopSample()
+	<-sampleDone
+	stopSort()
+	_ = runErrg.Wait()
+
+	c.Assert(atomic.LoadInt64(&maxActive), check.LessEqual, int64(concurrency))
+	c.Assert(testutil.ToFloat64(limiter.metricActiveSorts), check.Equals, float64(0))
+}
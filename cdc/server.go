@@ -42,6 +42,10 @@ const (
 
 	// DefaultCDCGCSafePointTTL is the default value of cdc gc safe-point ttl, specified in seconds.
 	DefaultCDCGCSafePointTTL = 24 * 60 * 60
+
+	// DefaultChangefeedMetaRetention is the default retention of the keys of the removed,
+	// finished and failed changefeeds
+	DefaultChangefeedMetaRetention = 7 * 24 * time.Hour
 )
 
 type options struct {
@@ -53,6 +57,10 @@ type options struct {
 	timezone               *time.Location
 	ownerFlushInterval     time.Duration
 	processorFlushInterval time.Duration
+
+	rejectOverlappingChangefeeds bool
+	gcGuardLag                   time.Duration
+	changefeedMetaRetention      time.Duration
 }
 
 func (o *options) validateAndAdjust() error {
@@ -78,6 +86,12 @@ func (o *options) validateAndAdjust() error {
 	if o.gcTTL == 0 {
 		return cerror.ErrInvalidServerOption.GenWithStack("empty GC TTL is not allowed")
 	}
+	if o.gcGuardLag < 0 {
+		return cerror.ErrInvalidServerOption.GenWithStack("negative GC guard lag is not allowed")
+	}
+	if o.changefeedMetaRetention < 0 {
+		return cerror.ErrInvalidServerOption.GenWithStack("negative changefeed meta retention is not allowed")
+	}
 	var tlsConfig *tls.Config
 	if o.credential != nil {
 		var err error
@@ -152,6 +166,30 @@ func ProcessorFlushInterval(dur time.Duration) ServerOption {
 	}
 }
 
+// RejectOverlappingChangefeeds returns a ServerOption that sets whether to reject changefeeds
+// replicating the same tables to the same sink target as a running changefeed
+func RejectOverlappingChangefeeds(reject bool) ServerOption {
+	return func(o *options) {
+		o.rejectOverlappingChangefeeds = reject
+	}
+}
+
+// GCGuardLag returns a ServerOption that sets the checkpoint lag of changefeeds, beyond which
+// the owner pauses the changefeeds blocking the GC of upstream, zero disables the GC guard
+func GCGuardLag(lag time.Duration) ServerOption {
+	return func(o *options) {
+		o.gcGuardLag = lag
+	}
+}
+
+// ChangefeedMetaRetention returns a ServerOption that sets how long the keys of the removed,
+// finished and failed changefeeds are kept, zero disables the garbage collection of them
+func ChangefeedMetaRetention(retention time.Duration) ServerOption {
+	return func(o *options) {
+		o.changefeedMetaRetention = retention
+	}
+}
+
 // Credential returns a ServerOption that sets the TLS
 func Credential(credential *security.Credential) ServerOption {
 	return func(o *options) {
@@ -190,6 +228,9 @@ func NewServer(opt ...ServerOption) (*Server, error) {
 		zap.Any("timezone", opts.timezone),
 		zap.Duration("owner-flush-interval", opts.ownerFlushInterval),
 		zap.Duration("processor-flush-interval", opts.processorFlushInterval),
+		zap.Bool("reject-overlapping-changefeeds", opts.rejectOverlappingChangefeeds),
+		zap.Duration("gc-guard-lag", opts.gcGuardLag),
+		zap.Duration("changefeed-meta-retention", opts.changefeedMetaRetention),
 	)
 
 	s := &Server{
@@ -277,7 +318,9 @@ func (s *Server) campaignOwnerLoop(ctx context.Context) error {
 			continue
 		}
 		log.Info("campaign owner successfully", zap.String("capture", s.capture.info.ID))
-		owner, err := NewOwner(ctx, s.pdClient, s.opts.credential, s.capture.session, s.opts.gcTTL, s.opts.ownerFlushInterval)
+		owner, err := NewOwner(ctx, s.pdClient, s.opts.credential, s.capture.session,
+			s.opts.gcTTL, s.opts.ownerFlushInterval, s.opts.rejectOverlappingChangefeeds, s.opts.gcGuardLag,
+			s.opts.changefeedMetaRetention)
 		if err != nil {
 			log.Warn("create new owner failed", zap.Error(err))
 			continue
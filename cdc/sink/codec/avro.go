@@ -38,6 +38,7 @@ import (
 type AvroEventBatchEncoder struct {
 	keySchemaManager   *AvroSchemaManager
 	valueSchemaManager *AvroSchemaManager
+	valueFormat        *ValueFormat
 	resultBuf          []*MQMessage
 }
 
@@ -75,13 +76,19 @@ func (a *AvroEventBatchEncoder) GetKeySchemaManager() *AvroSchemaManager {
 	return a.keySchemaManager
 }
 
+// SetValueFormat sets the representations of the ENUM, SET and BIT values for an Avro encoder,
+// the Avro types of the columns depend on them
+func (a *AvroEventBatchEncoder) SetValueFormat(format *ValueFormat) {
+	a.valueFormat = format
+}
+
 // AppendRowChangedEvent appends a row change event to the encoder
 // NOTE: the encoder can only store one RowChangedEvent!
 func (a *AvroEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
 	mqMessage := NewMQMessage(nil, nil, e.CommitTs)
 
 	if !e.IsDelete() {
-		res, err := avroEncode(e.Table, a.valueSchemaManager, e.TableInfoVersion, e.Columns)
+		res, err := avroEncode(e.Table, a.valueSchemaManager, e.TableInfoVersion, e.Columns, a.valueFormat)
 		if err != nil {
 			log.Warn("AppendRowChangedEvent: avro encoding failed", zap.String("table", e.Table.String()))
 			return EncoderNoOperation, errors.Annotate(err, "AppendRowChangedEvent could not encode to Avro")
@@ -100,7 +107,7 @@ func (a *AvroEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent)
 
 	pkeyCols := e.HandleKeyColumns()
 
-	res, err := avroEncode(e.Table, a.keySchemaManager, e.TableInfoVersion, pkeyCols)
+	res, err := avroEncode(e.Table, a.keySchemaManager, e.TableInfoVersion, pkeyCols, a.valueFormat)
 	if err != nil {
 		log.Warn("AppendRowChangedEvent: avro encoding failed", zap.String("table", e.Table.String()))
 		return EncoderNoOperation, errors.Annotate(err, "AppendRowChangedEvent could not encode to Avro")
@@ -163,9 +170,11 @@ func (a *AvroEventBatchEncoder) Size() int {
 	return sum
 }
 
-func avroEncode(table *model.TableName, manager *AvroSchemaManager, tableVersion uint64, cols []*model.Column) (*avroEncodeResult, error) {
+func avroEncode(
+	table *model.TableName, manager *AvroSchemaManager, tableVersion uint64, cols []*model.Column, format *ValueFormat,
+) (*avroEncodeResult, error) {
 	schemaGen := func() (string, error) {
-		schema, err := ColumnInfoToAvroSchema(table.Table, cols)
+		schema, err := ColumnInfoToAvroSchema(table.Table, cols, format)
 		if err != nil {
 			return "", errors.Annotate(err, "AvroEventBatchEncoder: generating schema failed")
 		}
@@ -217,7 +226,7 @@ const (
 )
 
 // ColumnInfoToAvroSchema generates the Avro schema JSON for the corresponding columns
-func ColumnInfoToAvroSchema(name string, columnInfo []*model.Column) (string, error) {
+func ColumnInfoToAvroSchema(name string, columnInfo []*model.Column, format *ValueFormat) (string, error) {
 	top := avroSchemaTop{
 		Tp:     "record",
 		Name:   name,
@@ -225,7 +234,7 @@ func ColumnInfoToAvroSchema(name string, columnInfo []*model.Column) (string, er
 	}
 
 	for _, col := range columnInfo {
-		avroType, err := getAvroDataTypeFromColumn(col)
+		avroType, err := getAvroDataTypeFromColumn(col, format)
 		if err != nil {
 			return "", err
 		}
@@ -302,8 +311,11 @@ var unsignedLongAvroType = avroLogicalType{
 	Scale:       0,
 }
 
-func getAvroDataTypeFromColumn(col *model.Column) (interface{}, error) {
+func getAvroDataTypeFromColumn(col *model.Column, format *ValueFormat) (interface{}, error) {
 	log.Info("DEBUG: getAvroDataTypeFromColumn", zap.Reflect("col", col))
+	if format.isString(col.Type) {
+		return "string", nil
+	}
 	switch col.Type {
 	case mysql.TypeFloat:
 		return "float", nil
@@ -371,6 +383,14 @@ func columnToAvroNativeData(col *model.Column) (interface{}, string, error) {
 		return retVal, string("bytes." + decimalType), nil
 	}
 
+	// the ENUM, SET and BIT values rewritten by the ValueFormat
+	if str, ok := col.Value.(string); ok {
+		switch col.Type {
+		case mysql.TypeEnum, mysql.TypeSet, mysql.TypeBit:
+			return str, "string", nil
+		}
+	}
+
 	switch col.Type {
 	case mysql.TypeDate, mysql.TypeDatetime, mysql.TypeNewDate, mysql.TypeTimestamp:
 		str := col.Value.(string)
@@ -411,7 +431,7 @@ func columnToAvroNativeData(col *model.Column) (interface{}, string, error) {
 		fsp := len(frac)
 		fracInt, err := strconv.ParseInt(frac, 10, 32)
 		if err != nil {
-			return nil, "", err
+			return nil, "", cerror.WrapError(cerror.ErrAvroEncodeFailed, err)
 		}
 		fracInt = int64(float64(fracInt) * math.Pow10(6-fsp))
 
@@ -107,6 +107,7 @@ func isCanalDdl(t canal.EventType) bool {
 
 type canalEntryBuilder struct {
 	bytesDecoder *encoding.Decoder // default charset is ISO-8859-1
+	commitTime   *CommitTime
 }
 
 // build the header of a canal entry
@@ -128,6 +129,12 @@ func (b *canalEntryBuilder) buildHeader(commitTs uint64, schema string, table st
 		}
 		h.Props = append(h.Props, p)
 	}
+	if b.commitTime != nil {
+		h.Props = append(h.Props, &canal.Pair{
+			Key:   "commitTime",
+			Value: b.commitTime.Format(commitTs),
+		})
+	}
 	return h
 }
 
@@ -440,6 +447,11 @@ func (d *CanalEventBatchEncoder) resetPacket() {
 	}
 }
 
+// SetCommitTime adds the commit time to the properties of the entry headers
+func (d *CanalEventBatchEncoder) SetCommitTime(commitTime *CommitTime) {
+	d.entryBuilder.commitTime = commitTime
+}
+
 // NewCanalEventBatchEncoder creates a new CanalEventBatchEncoder.
 func NewCanalEventBatchEncoder() EventBatchEncoder {
 	encoder := &CanalEventBatchEncoder{
@@ -0,0 +1,74 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+)
+
+// commitTimeLayout is ISO-8601 with milliseconds, which is the precision of the physical part of a TSO
+const commitTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// CommitTime derives the wall-clock commit time of the events from their commit ts
+type CommitTime struct {
+	loc *time.Location
+}
+
+// NewCommitTime creates a CommitTime, it returns nil if the commit time is disabled
+func NewCommitTime(cfg *config.SinkConfig) (*CommitTime, error) {
+	if !cfg.CommitTime {
+		return nil, nil
+	}
+	if err := cfg.ValidateCommitTimeZone(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	loc := time.UTC
+	if cfg.CommitTimeZone != "" {
+		loc, _ = time.LoadLocation(cfg.CommitTimeZone)
+	}
+	return &CommitTime{loc: loc}, nil
+}
+
+// Format returns the commit time of the commit ts, the physical part of a TSO is
+// the milliseconds since the Unix epoch, the logical part is ignored.
+func (t *CommitTime) Format(commitTs uint64) string {
+	if t == nil {
+		return ""
+	}
+	return oracle.GetTimeFromTS(commitTs).In(t.loc).Format(commitTimeLayout)
+}
+
+// commitTimeEncoder is implemented by the encoders which support the commit time
+type commitTimeEncoder interface {
+	SetCommitTime(commitTime *CommitTime)
+}
+
+// WithCommitTime wraps the encoder constructor to add the commit time to the encoded messages,
+// the encoders which don't support the commit time are returned untouched.
+func WithCommitTime(newEncoder func() EventBatchEncoder, commitTime *CommitTime) func() EventBatchEncoder {
+	if commitTime == nil {
+		return newEncoder
+	}
+	return func() EventBatchEncoder {
+		encoder := newEncoder()
+		if e, ok := encoder.(commitTimeEncoder); ok {
+			e.SetCommitTime(commitTime)
+		}
+		return encoder
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+)
+
+type commitTimeSuite struct{}
+
+var _ = check.Suite(&commitTimeSuite{})
+
+// the physical part of knownTs is 1591943372224, which is 2020-06-12 06:29:32.224 UTC
+const knownTs = 417318403368288260
+
+func (s *commitTimeSuite) TestFormat(c *check.C) {
+	t, err := NewCommitTime(&config.SinkConfig{})
+	c.Assert(err, check.IsNil)
+	c.Assert(t, check.IsNil)
+	c.Assert(t.Format(knownTs), check.Equals, "")
+
+	t, err = NewCommitTime(&config.SinkConfig{CommitTime: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Format(knownTs), check.Equals, "2020-06-12T06:29:32.224Z")
+	// the logical part doesn't change the commit time
+	c.Assert(t.Format(oracle.ComposeTS(1591943372224, 0)), check.Equals, "2020-06-12T06:29:32.224Z")
+
+	t, err = NewCommitTime(&config.SinkConfig{CommitTime: true, CommitTimeZone: "Asia/Shanghai"})
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Format(knownTs), check.Equals, "2020-06-12T14:29:32.224+08:00")
+
+	_, err = NewCommitTime(&config.SinkConfig{CommitTime: true, CommitTimeZone: "Nowhere/Nothing"})
+	c.Assert(err, check.ErrorMatches, ".*ErrCommitTimeZoneInvalid.*Nowhere.*")
+}
+
+func (s *commitTimeSuite) TestEncoders(c *check.C) {
+	t, err := NewCommitTime(&config.SinkConfig{CommitTime: true})
+	c.Assert(err, check.IsNil)
+	row := &model.RowChangedEvent{
+		CommitTs: knownTs,
+		Table:    &model.TableName{Schema: "test", Table: "t"},
+		Columns:  []*model.Column{{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(1)}},
+	}
+
+	encoder := WithCommitTime(NewJSONEventBatchEncoder, t)()
+	_, err = encoder.AppendRowChangedEvent(row)
+	c.Assert(err, check.IsNil)
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+	decoder, err := NewJSONEventBatchDecoder(msgs[0].Key, msgs[0].Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+	key := decoder.(*JSONEventBatchDecoder).nextKey
+	c.Assert(key.Ts, check.Equals, uint64(knownTs))
+	c.Assert(key.CommitTime, check.Equals, "2020-06-12T06:29:32.224Z")
+
+	canalEncoder := WithCommitTime(NewCanalEventBatchEncoder, t)().(*CanalEventBatchEncoder)
+	entry, err := canalEncoder.entryBuilder.FromRowEvent(row)
+	c.Assert(err, check.IsNil)
+	var commitTime string
+	for _, p := range entry.GetHeader().GetProps() {
+		if p.GetKey() == "commitTime" {
+			commitTime = p.GetValue()
+		}
+	}
+	c.Assert(commitTime, check.Equals, "2020-06-12T06:29:32.224Z")
+	c.Assert(entry.GetHeader().GetExecuteTime(), check.Equals, int64(1591943372224))
+}
@@ -16,11 +16,42 @@ package codec
 import (
 	"strings"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"go.uber.org/zap"
 )
 
+// unencodableErrors are the errors the encoders return if the values of the row can't be encoded by the protocol
+var unencodableErrors = []*errors.Error{
+	cerror.ErrAvroEncodeFailed,
+	cerror.ErrAvroEncodeToBinary,
+	cerror.ErrAvroUnknownType,
+	cerror.ErrAvroMarshalFailed,
+	cerror.ErrCanalEncodeFailed,
+	cerror.ErrMaxwellEncodeFailed,
+	cerror.ErrProtobufSchemaMismatch,
+}
+
+// IsUnencodableError returns whether the encoder fails since the values of the row can't be
+// encoded by the protocol, retrying can't help but another protocol may encode them
+func IsUnencodableError(err error) bool {
+	// the errors wrapping a cause are not matched by Equal, so the chain is searched instead
+	return errors.Find(err, func(e error) bool {
+		rfcErr, ok := e.(*errors.Error)
+		if !ok {
+			return false
+		}
+		for _, unencodable := range unencodableErrors {
+			if rfcErr.RFCCode() == unencodable.RFCCode() {
+				return true
+			}
+		}
+		return false
+	}) != nil
+}
+
 // EventBatchEncoder is an abstraction for events encoder
 type EventBatchEncoder interface {
 	// EncodeCheckpointEvent appends a checkpoint event into the batch.
@@ -111,6 +142,7 @@ const (
 	ProtocolCanal
 	ProtocolAvro
 	ProtocolMaxwell
+	ProtocolProtobuf
 )
 
 // FromString converts the protocol from string to Protocol enum type
@@ -124,6 +156,8 @@ func (p *Protocol) FromString(protocol string) {
 		*p = ProtocolAvro
 	case "maxwell":
 		*p = ProtocolMaxwell
+	case "protobuf":
+		*p = ProtocolProtobuf
 	default:
 		*p = ProtocolDefault
 		log.Warn("can't support codec protocol, using default protocol", zap.String("protocol", protocol))
@@ -141,6 +175,8 @@ func NewEventBatchEncoder(p Protocol) func() EventBatchEncoder {
 		return NewAvroEventBatchEncoder
 	case ProtocolMaxwell:
 		return NewMaxwellEventBatchEncoder
+	case ProtocolProtobuf:
+		return NewProtobufEventBatchEncoder
 	default:
 		log.Warn("unknown codec protocol value of EventBatchEncoder", zap.Int("protocol_value", int(p)))
 		return NewJSONEventBatchEncoder
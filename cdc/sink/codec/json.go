@@ -109,12 +109,20 @@ func formatColumnVal(c column) column {
 			}
 		}
 	case mysql.TypeBit:
-		if s, ok := c.Value.(json.Number); ok {
+		switch s := c.Value.(type) {
+		case json.Number:
 			intNum, err := s.Int64()
 			if err != nil {
 				log.Fatal("invalid column value, please report a bug", zap.Any("col", c), zap.Error(err))
 			}
 			c.Value = uint64(intNum)
+		case string:
+			// the BIT value is encoded as base64 by the ValueFormat
+			v, err := bitFromBase64(s)
+			if err != nil {
+				log.Fatal("invalid column value, please report a bug", zap.Any("col", c), zap.Error(err))
+			}
+			c.Value = v
 		}
 	}
 	return c
@@ -128,6 +136,8 @@ type mqMessageKey struct {
 	RowID     int64               `json:"rid,omitempty"`
 	Partition *int64              `json:"ptn,omitempty"`
 	Type      model.MqMessageType `json:"t"`
+	// CommitTime is the wall-clock time of Ts, it's set only if the commit time is enabled
+	CommitTime string `json:"commit-time,omitempty"`
 }
 
 func (m *mqMessageKey) Encode() ([]byte, error) {
@@ -143,6 +153,11 @@ type mqMessageRow struct {
 	Update     map[string]column `json:"u,omitempty"`
 	PreColumns map[string]column `json:"p,omitempty"`
 	Delete     map[string]column `json:"d,omitempty"`
+	// Data, Types and Binary are the column values, the column types and the binary columns
+	// of an insert event encoded in the compact-insert mode
+	Data   map[string]interface{} `json:"data,omitempty"`
+	Types  map[string]byte        `json:"types,omitempty"`
+	Binary []string               `json:"binary,omitempty"`
 }
 
 func (m *mqMessageRow) Encode() ([]byte, error) {
@@ -261,6 +276,8 @@ func mqMessageToRowEvent(key *mqMessageKey, value *mqMessageRow) *model.RowChang
 
 	if len(value.Delete) != 0 {
 		e.PreColumns = jsonColumns2SinkColumns(value.Delete)
+	} else if len(value.Data) != 0 {
+		e.Columns = compactColumns2SinkColumns(value.Data, value.Types, value.Binary)
 	} else {
 		e.Columns = jsonColumns2SinkColumns(value.Update)
 		e.PreColumns = jsonColumns2SinkColumns(value.PreColumns)
@@ -300,6 +317,19 @@ type JSONEventBatchEncoder struct {
 	keyBuf            *bytes.Buffer
 	valueBuf          *bytes.Buffer
 	supportMixedBuild bool // TODO decouple this out
+	commitTime        *CommitTime
+	compactInsert     bool
+}
+
+// SetCommitTime adds the commit time to the keys of the row changed and DDL events
+func (d *JSONEventBatchEncoder) SetCommitTime(commitTime *CommitTime) {
+	d.commitTime = commitTime
+}
+
+// SetCompactInsert encodes the values of the insert events without the column types, the flags
+// and the operation wrapper, the update and delete events are encoded as usual
+func (d *JSONEventBatchEncoder) SetCompactInsert(enabled bool) {
+	d.compactInsert = enabled
 }
 
 // SetMixedBuildSupport is used by CDC Log
@@ -349,11 +379,17 @@ func (d *JSONEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, er
 // AppendRowChangedEvent implements the EventBatchEncoder interface
 func (d *JSONEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
 	keyMsg, valueMsg := rowEventToMqMessage(e)
+	keyMsg.CommitTime = d.commitTime.Format(e.CommitTs)
 	key, err := keyMsg.Encode()
 	if err != nil {
 		return EncoderNoOperation, errors.Trace(err)
 	}
-	value, err := valueMsg.Encode()
+	var value []byte
+	if d.compactInsert && isCompactInsert(e) {
+		value, err = rowEventToCompactInsert(e).Encode()
+	} else {
+		value, err = valueMsg.Encode()
+	}
 	if err != nil {
 		return EncoderNoOperation, errors.Trace(err)
 	}
@@ -374,6 +410,7 @@ func (d *JSONEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent)
 // EncodeDDLEvent implements the EventBatchEncoder interface
 func (d *JSONEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
 	keyMsg, valueMsg := ddlEventtoMqMessage(e)
+	keyMsg.CommitTime = d.commitTime.Format(e.CommitTs)
 	key, err := keyMsg.Encode()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -0,0 +1,112 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// compactInsertMessage is the value of an insert event in the compact-insert mode, it carries the
+// column values with their types only, without the other flags and the operation wrapper. The binary
+// string columns are listed since their values are escaped like the full form.
+type compactInsertMessage struct {
+	Table  string                 `json:"table"`
+	Data   map[string]interface{} `json:"data"`
+	Types  map[string]byte        `json:"types"`
+	Binary []string               `json:"binary,omitempty"`
+}
+
+func (m *compactInsertMessage) Encode() ([]byte, error) {
+	data, err := json.Marshal(m)
+	return data, cerror.WrapError(cerror.ErrMarshalFailed, err)
+}
+
+// isCompactInsert returns true if the row is an insert event that can be encoded compactly. The row
+// without the pre columns is an insert since the compact-insert mode requires the old values, with
+// which the updates carry the pre columns.
+func isCompactInsert(e *model.RowChangedEvent) bool {
+	return !e.IsDelete() && len(e.PreColumns) == 0
+}
+
+func rowEventToCompactInsert(e *model.RowChangedEvent) *compactInsertMessage {
+	msg := &compactInsertMessage{
+		Table: e.Table.Table,
+		Data:  make(map[string]interface{}, len(e.Columns)),
+		Types: make(map[string]byte, len(e.Columns)),
+	}
+	for _, col := range e.Columns {
+		if col == nil {
+			continue
+		}
+		c := column{}
+		c.FromSinkColumn(col)
+		msg.Data[col.Name] = c.Value
+		msg.Types[col.Name] = c.Type
+		if c.Flag.IsBinary() {
+			msg.Binary = append(msg.Binary, col.Name)
+		}
+	}
+	sort.Strings(msg.Binary)
+	return msg
+}
+
+// compactColumns2SinkColumns converts the column values of a compact insert to the sink columns as
+// the full form does, the flags of the columns other than the binary flag are unknown.
+func compactColumns2SinkColumns(data map[string]interface{}, types map[string]byte, binary []string) []*model.Column {
+	binaryCols := make(map[string]struct{}, len(binary))
+	for _, name := range binary {
+		binaryCols[name] = struct{}{}
+	}
+	sinkCols := make([]*model.Column, 0, len(data))
+	for name, value := range data {
+		c := column{Type: types[name], Value: value}
+		if _, ok := binaryCols[name]; ok {
+			c.Flag.SetIsBinary()
+		}
+		c = formatColumnVal(c)
+		sinkCols = append(sinkCols, c.ToSinkColumn(name))
+	}
+	if len(sinkCols) == 0 {
+		return nil
+	}
+	sort.Slice(sinkCols, func(i, j int) bool {
+		return strings.Compare(sinkCols[i].Name, sinkCols[j].Name) > 0
+	})
+	return sinkCols
+}
+
+// compactInsertEncoder is implemented by the encoders which support the compact-insert mode
+type compactInsertEncoder interface {
+	SetCompactInsert(enabled bool)
+}
+
+// WithCompactInsert wraps the encoder constructor to encode the insert events compactly,
+// the encoders which don't support the compact-insert mode are returned untouched.
+func WithCompactInsert(newEncoder func() EventBatchEncoder, enabled bool) func() EventBatchEncoder {
+	if !enabled {
+		return newEncoder
+	}
+	return func() EventBatchEncoder {
+		encoder := newEncoder()
+		if e, ok := encoder.(compactInsertEncoder); ok {
+			e.SetCompactInsert(true)
+		}
+		return encoder
+	}
+}
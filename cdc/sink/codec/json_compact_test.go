@@ -0,0 +1,111 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/json"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+type compactInsertSuite struct{}
+
+var _ = check.Suite(&compactInsertSuite{})
+
+// encodeRowValue encodes the row in a batch of its own and returns the JSON value of the row
+func encodeRowValue(c *check.C, newEncoder func() EventBatchEncoder, row *model.RowChangedEvent) []byte {
+	encoder := newEncoder()
+	_, err := encoder.AppendRowChangedEvent(row)
+	c.Assert(err, check.IsNil)
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+	// the value is prefixed by its length
+	return msgs[0].Value[8:]
+}
+
+func (s *compactInsertSuite) TestCompactInsert(c *check.C) {
+	table := &model.TableName{Schema: "test", Table: "events"}
+	cols := []*model.Column{
+		{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(1)},
+		{Name: "name", Type: mysql.TypeVarchar, Value: []byte("click")},
+		{Name: "note", Type: mysql.TypeVarchar, Value: nil},
+		{Name: "tag", Type: mysql.TypeVarString, Flag: model.BinaryFlag, Value: []byte("a\x00\"b")},
+	}
+	insert := &model.RowChangedEvent{CommitTs: 417318403368288260, Table: table, Columns: cols}
+	update := &model.RowChangedEvent{CommitTs: 417318403368288260, Table: table, Columns: cols, PreColumns: cols}
+	del := &model.RowChangedEvent{CommitTs: 417318403368288260, Table: table, PreColumns: cols}
+
+	newEncoder := NewJSONEventBatchEncoder
+	newCompactEncoder := WithCompactInsert(newEncoder, true)
+	c.Assert(WithCompactInsert(newEncoder, false)().(*JSONEventBatchEncoder).compactInsert, check.IsFalse)
+
+	// the insert is encoded as the column values and types only
+	full := encodeRowValue(c, newEncoder, insert)
+	compact := encodeRowValue(c, newCompactEncoder, insert)
+	c.Assert(len(compact), check.Less, len(full))
+	c.Assert(string(compact), check.Equals, `{"table":"events","data":{"id":1,"name":"click","note":null,"tag":"a\\x00\\\"b"},`+
+		`"types":{"id":8,"name":15,"note":15,"tag":253},"binary":["tag"]}`)
+	var shape map[string]json.RawMessage
+	c.Assert(json.Unmarshal(compact, &shape), check.IsNil)
+	c.Assert(shape, check.HasLen, 4)
+
+	// the updates and the deletes keep the full form
+	for _, row := range []*model.RowChangedEvent{update, del} {
+		c.Assert(encodeRowValue(c, newCompactEncoder, row), check.DeepEquals, encodeRowValue(c, newEncoder, row))
+	}
+
+	// the compact inserts are decoded with the column types and the unescaped binary strings
+	encoder := newCompactEncoder()
+	for _, row := range []*model.RowChangedEvent{insert, update, del} {
+		_, err := encoder.AppendRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+	}
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+	decoder, err := NewJSONEventBatchDecoder(msgs[0].Key, msgs[0].Value)
+	c.Assert(err, check.IsNil)
+	decoded := make([]*model.RowChangedEvent, 0, 3)
+	for {
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		if !hasNext {
+			break
+		}
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		row, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		decoded = append(decoded, row)
+	}
+	c.Assert(decoded, check.HasLen, 3)
+	c.Assert(decoded[0].Table, check.DeepEquals, table)
+	c.Assert(decoded[0].PreColumns, check.IsNil)
+	c.Assert(decoded[0].Columns, check.DeepEquals, []*model.Column{
+		{Name: "tag", Type: mysql.TypeVarString, Flag: model.BinaryFlag, Value: []byte("a\x00\"b")},
+		{Name: "note", Type: mysql.TypeVarchar, Value: nil},
+		{Name: "name", Type: mysql.TypeVarchar, Value: []byte("click")},
+		{Name: "id", Type: mysql.TypeLonglong, Value: json.Number("1")},
+	})
+	c.Assert(decoded[0].Columns, check.DeepEquals, jsonColumns2SinkColumns(map[string]column{
+		"tag":  {Type: mysql.TypeVarString, Flag: model.BinaryFlag, Value: "a\\x00\\\"b"},
+		"note": {Type: mysql.TypeVarchar},
+		"name": {Type: mysql.TypeVarchar, Value: "click"},
+		"id":   {Type: mysql.TypeLonglong, Value: json.Number("1")},
+	}))
+	c.Assert(decoded[1].Columns, check.HasLen, 4)
+	c.Assert(decoded[1].PreColumns, check.HasLen, 4)
+	c.Assert(decoded[1].Columns[3].Type, check.Equals, mysql.TypeLonglong)
+	c.Assert(decoded[2].IsDelete(), check.IsTrue)
+}
@@ -0,0 +1,559 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"go.uber.org/zap"
+)
+
+// ProtobufSchema maps tables to the user-defined protobuf messages
+// loaded from a serialized FileDescriptorSet.
+type ProtobufSchema struct {
+	messages map[string]*descriptor.DescriptorProto
+	rules    []struct {
+		filter.Filter
+		message string
+	}
+
+	// validated caches the tables whose columns have been checked against
+	// their message, keyed by table name and table info version.
+	validated sync.Map
+}
+
+// NewProtobufSchema creates a ProtobufSchema from a serialized FileDescriptorSet
+func NewProtobufSchema(descriptorSet []byte, cfg *config.ReplicaConfig) (*ProtobufSchema, error) {
+	fds := new(descriptor.FileDescriptorSet)
+	if err := proto.Unmarshal(descriptorSet, fds); err != nil {
+		return nil, cerror.WrapError(cerror.ErrProtobufInvalidDescriptor, err)
+	}
+	s := &ProtobufSchema{
+		messages: make(map[string]*descriptor.DescriptorProto),
+	}
+	for _, file := range fds.GetFile() {
+		prefix := file.GetPackage()
+		if prefix != "" {
+			prefix += "."
+		}
+		for _, msg := range file.GetMessageType() {
+			if err := s.addMessage(prefix, msg); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	if len(s.messages) == 0 {
+		return nil, cerror.ErrProtobufInvalidDescriptor.GenWithStackByArgs("no message is defined")
+	}
+
+	var ruleConfigs []*config.ProtobufMessageRule
+	if cfg.Sink.Protobuf != nil {
+		ruleConfigs = cfg.Sink.Protobuf.MessageRules
+	}
+	for _, ruleConfig := range ruleConfigs {
+		if _, ok := s.messages[ruleConfig.Message]; !ok {
+			return nil, cerror.ErrProtobufInvalidDescriptor.GenWithStackByArgs(
+				fmt.Sprintf("message %s not found in descriptor", ruleConfig.Message))
+		}
+		f, err := filter.Parse(ruleConfig.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filter.CaseInsensitive(f)
+		}
+		s.rules = append(s.rules, struct {
+			filter.Filter
+			message string
+		}{Filter: f, message: ruleConfig.Message})
+	}
+	return s, nil
+}
+
+func (s *ProtobufSchema) addMessage(prefix string, msg *descriptor.DescriptorProto) error {
+	name := prefix + msg.GetName()
+	for _, field := range msg.GetField() {
+		if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			return cerror.ErrProtobufInvalidDescriptor.GenWithStackByArgs(
+				fmt.Sprintf("repeated field %s.%s is not supported", name, field.GetName()))
+		}
+		switch field.GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+			return cerror.ErrProtobufInvalidDescriptor.GenWithStackByArgs(
+				fmt.Sprintf("nested message field %s.%s is not supported", name, field.GetName()))
+		}
+	}
+	s.messages[name] = msg
+	for _, nested := range msg.GetNestedType() {
+		if nested.GetOptions().GetMapEntry() {
+			continue
+		}
+		if err := s.addMessage(name+".", nested); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// matchMessage returns the message of the table. The first matched rule wins,
+// if no rule matches, the message with the same name as the table is used.
+func (s *ProtobufSchema) matchMessage(table *model.TableName) (string, *descriptor.DescriptorProto, error) {
+	for _, rule := range s.rules {
+		if rule.MatchTable(table.Schema, table.Table) {
+			return rule.message, s.messages[rule.message], nil
+		}
+	}
+	for name, msg := range s.messages {
+		if msg.GetName() == table.Table {
+			return name, msg, nil
+		}
+	}
+	return "", nil, cerror.ErrProtobufSchemaMismatch.GenWithStackByArgs(
+		table.String(), "", "no message is mapped to the table")
+}
+
+// validate checks that every required field of the message has a source column
+func (s *ProtobufSchema) validate(table *model.TableName, version uint64,
+	msgName string, msg *descriptor.DescriptorProto, cols []*model.Column) error {
+	cacheKey := fmt.Sprintf("%s/%d", table.String(), version)
+	if _, ok := s.validated.Load(cacheKey); ok {
+		return nil
+	}
+	colNames := make(map[string]struct{}, len(cols))
+	for _, col := range cols {
+		if col != nil {
+			colNames[col.Name] = struct{}{}
+		}
+	}
+	for _, field := range msg.GetField() {
+		if field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REQUIRED {
+			continue
+		}
+		if _, ok := colNames[field.GetName()]; !ok {
+			return cerror.ErrProtobufSchemaMismatch.GenWithStackByArgs(table.String(), msgName,
+				fmt.Sprintf("required field %s has no source column", field.GetName()))
+		}
+	}
+	s.validated.Store(cacheKey, struct{}{})
+	return nil
+}
+
+// encodeRow serializes the columns into the message mapped to the table
+func (s *ProtobufSchema) encodeRow(table *model.TableName, version uint64, cols []*model.Column) ([]byte, error) {
+	msgName, msg, err := s.matchMessage(table)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := s.validate(table, version, msgName, msg, cols); err != nil {
+		return nil, errors.Trace(err)
+	}
+	values := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		if col != nil {
+			values[col.Name] = col.Value
+		}
+	}
+	buf := proto.NewBuffer(nil)
+	for _, field := range msg.GetField() {
+		value, ok := values[field.GetName()]
+		if !ok {
+			continue
+		}
+		if value == nil {
+			if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REQUIRED {
+				return nil, cerror.ErrProtobufSchemaMismatch.GenWithStackByArgs(table.String(), msgName,
+					fmt.Sprintf("required field %s got a NULL value", field.GetName()))
+			}
+			continue
+		}
+		if err := encodeProtobufField(buf, field, value); err != nil {
+			return nil, cerror.ErrProtobufSchemaMismatch.GenWithStackByArgs(table.String(), msgName, err.Error())
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeRow decodes a message encoded by the protobuf protocol back into columns.
+// The columns only carry the name and value, because the MySQL type is not
+// recorded in the user-defined message.
+func (s *ProtobufSchema) DecodeRow(table *model.TableName, data []byte) ([]*model.Column, error) {
+	msgName, msg, err := s.matchMessage(table)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	fields := make(map[int32]*descriptor.FieldDescriptorProto, len(msg.GetField()))
+	for _, field := range msg.GetField() {
+		fields[field.GetNumber()] = field
+	}
+	var cols []*model.Column
+	for len(data) > 0 {
+		tag, n := proto.DecodeVarint(data)
+		if n == 0 {
+			return nil, cerror.ErrProtobufDecodeFailed.GenWithStackByArgs()
+		}
+		data = data[n:]
+		field, ok := fields[int32(tag>>3)]
+		if !ok {
+			return nil, cerror.ErrProtobufSchemaMismatch.GenWithStackByArgs(table.String(), msgName,
+				fmt.Sprintf("unknown field number %d", tag>>3))
+		}
+		var value interface{}
+		value, data, err = decodeProtobufField(field, int(tag&0x7), data)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		cols = append(cols, &model.Column{Name: field.GetName(), Value: value})
+	}
+	return cols, nil
+}
+
+func encodeProtobufField(buf *proto.Buffer, field *descriptor.FieldDescriptorProto, value interface{}) error {
+	number := uint64(field.GetNumber())
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_ENUM:
+		v, err := protobufInt(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireVarint)
+		return buf.EncodeVarint(uint64(v))
+	case descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SINT32:
+		v, err := protobufInt(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireVarint)
+		return buf.EncodeZigzag64(uint64(v))
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_UINT32:
+		v, err := protobufUint(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireVarint)
+		return buf.EncodeVarint(v)
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		v, err := protobufInt(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireVarint)
+		if v != 0 {
+			return buf.EncodeVarint(1)
+		}
+		return buf.EncodeVarint(0)
+	case descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		v, err := protobufUint(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireFixed64)
+		return buf.EncodeFixed64(v)
+	case descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		v, err := protobufInt(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireFixed64)
+		return buf.EncodeFixed64(uint64(v))
+	case descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		v, err := protobufUint(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireFixed32)
+		return buf.EncodeFixed32(v)
+	case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		v, err := protobufInt(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireFixed32)
+		return buf.EncodeFixed32(uint64(uint32(int32(v))))
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		v, err := protobufFloat(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireFixed64)
+		return buf.EncodeFixed64(math.Float64bits(v))
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		v, err := protobufFloat(field, value)
+		if err != nil {
+			return err
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireFixed32)
+		return buf.EncodeFixed32(uint64(math.Float32bits(float32(v))))
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		var v []byte
+		switch value := value.(type) {
+		case []byte:
+			v = value
+		case string:
+			v = []byte(value)
+		default:
+			if field.GetType() == descriptor.FieldDescriptorProto_TYPE_BYTES {
+				return protobufTypeMismatch(field, value)
+			}
+			v = []byte(model.ColumnValueString(value))
+		}
+		_ = buf.EncodeVarint(number<<3 | proto.WireBytes)
+		return buf.EncodeRawBytes(v)
+	}
+	return protobufTypeMismatch(field, value)
+}
+
+func decodeProtobufField(field *descriptor.FieldDescriptorProto, wireType int, data []byte) (interface{}, []byte, error) {
+	switch wireType {
+	case proto.WireVarint:
+		x, n := proto.DecodeVarint(data)
+		if n == 0 {
+			return nil, nil, cerror.ErrProtobufDecodeFailed.GenWithStackByArgs()
+		}
+		data = data[n:]
+		switch field.GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SINT32:
+			return int64(x>>1) ^ -int64(x&1), data, nil
+		case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_UINT32:
+			return x, data, nil
+		case descriptor.FieldDescriptorProto_TYPE_BOOL:
+			return x != 0, data, nil
+		}
+		return int64(x), data, nil
+	case proto.WireFixed64:
+		if len(data) < 8 {
+			return nil, nil, cerror.ErrProtobufDecodeFailed.GenWithStackByArgs()
+		}
+		x := binary.LittleEndian.Uint64(data)
+		data = data[8:]
+		switch field.GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+			return math.Float64frombits(x), data, nil
+		case descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+			return int64(x), data, nil
+		}
+		return x, data, nil
+	case proto.WireFixed32:
+		if len(data) < 4 {
+			return nil, nil, cerror.ErrProtobufDecodeFailed.GenWithStackByArgs()
+		}
+		x := binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		switch field.GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+			return float64(math.Float32frombits(x)), data, nil
+		case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+			return int64(int32(x)), data, nil
+		}
+		return uint64(x), data, nil
+	case proto.WireBytes:
+		l, n := proto.DecodeVarint(data)
+		if n == 0 || uint64(len(data)-n) < l {
+			return nil, nil, cerror.ErrProtobufDecodeFailed.GenWithStackByArgs()
+		}
+		v := data[n : n+int(l)]
+		data = data[n+int(l):]
+		if field.GetType() == descriptor.FieldDescriptorProto_TYPE_STRING {
+			return string(v), data, nil
+		}
+		return append([]byte(nil), v...), data, nil
+	}
+	return nil, nil, cerror.ErrProtobufDecodeFailed.GenWithStackByArgs()
+}
+
+func protobufTypeMismatch(field *descriptor.FieldDescriptorProto, value interface{}) error {
+	return errors.Errorf("field %s of type %s can not hold the column value %v(%T)",
+		field.GetName(), strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_")), value, value)
+}
+
+func protobufInt(field *descriptor.FieldDescriptorProto, value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, protobufTypeMismatch(field, value)
+		}
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, protobufTypeMismatch(field, value)
+		}
+		return i, nil
+	}
+	return 0, protobufTypeMismatch(field, value)
+}
+
+func protobufUint(field *descriptor.FieldDescriptorProto, value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case uint64:
+		return v, nil
+	case int64:
+		if v < 0 {
+			return 0, protobufTypeMismatch(field, value)
+		}
+		return uint64(v), nil
+	case string:
+		i, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, protobufTypeMismatch(field, value)
+		}
+		return i, nil
+	}
+	return 0, protobufTypeMismatch(field, value)
+}
+
+func protobufFloat(field *descriptor.FieldDescriptorProto, value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case string:
+		// decimal column
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, protobufTypeMismatch(field, value)
+		}
+		return f, nil
+	}
+	return 0, protobufTypeMismatch(field, value)
+}
+
+// ProtobufEventBatchEncoder encodes row changed events into user-defined protobuf messages
+type ProtobufEventBatchEncoder struct {
+	schema    *ProtobufSchema
+	resultBuf []*MQMessage
+}
+
+// NewProtobufEventBatchEncoder creates a ProtobufEventBatchEncoder
+func NewProtobufEventBatchEncoder() EventBatchEncoder {
+	return &ProtobufEventBatchEncoder{
+		resultBuf: make([]*MQMessage, 0, 4096),
+	}
+}
+
+// SetSchema sets the protobuf schema for a protobuf encoder
+func (d *ProtobufEventBatchEncoder) SetSchema(schema *ProtobufSchema) {
+	d.schema = schema
+}
+
+// AppendRowChangedEvent implements the EventBatchEncoder interface.
+// The key is the message with handle key columns only, and the value is the
+// message with all columns, or nil for a delete event.
+func (d *ProtobufEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
+	mqMessage := NewMQMessage(nil, nil, e.CommitTs)
+	if !e.IsDelete() {
+		value, err := d.schema.encodeRow(e.Table, e.TableInfoVersion, e.Columns)
+		if err != nil {
+			log.Warn("AppendRowChangedEvent: protobuf encoding failed", zap.String("table", e.Table.String()), zap.Error(err))
+			return EncoderNoOperation, errors.Trace(err)
+		}
+		mqMessage.Value = value
+	}
+	key, err := d.schema.encodeKey(e)
+	if err != nil {
+		return EncoderNoOperation, errors.Trace(err)
+	}
+	mqMessage.Key = key
+	d.resultBuf = append(d.resultBuf, mqMessage)
+	return EncoderNeedAsyncWrite, nil
+}
+
+// encodeKey encodes the handle key columns of the row, the required fields
+// are not validated because a key only carries part of the message.
+func (s *ProtobufSchema) encodeKey(e *model.RowChangedEvent) ([]byte, error) {
+	msgName, msg, err := s.matchMessage(e.Table)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buf := proto.NewBuffer(nil)
+	handles := e.HandleKeyColumns()
+	for _, field := range msg.GetField() {
+		for _, col := range handles {
+			if col.Name != field.GetName() || col.Value == nil {
+				continue
+			}
+			if err := encodeProtobufField(buf, field, col.Value); err != nil {
+				return nil, cerror.ErrProtobufSchemaMismatch.GenWithStackByArgs(e.Table.String(), msgName, err.Error())
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// AppendResolvedEvent is no-op for protobuf
+func (d *ProtobufEventBatchEncoder) AppendResolvedEvent(ts uint64) (EncoderResult, error) {
+	return EncoderNoOperation, nil
+}
+
+// EncodeCheckpointEvent is no-op for protobuf
+func (d *ProtobufEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
+// EncodeDDLEvent is no-op for protobuf, user-defined messages can not describe DDLs
+func (d *ProtobufEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
+	return nil, nil
+}
+
+// Build implements the EventBatchEncoder interface
+func (d *ProtobufEventBatchEncoder) Build() []*MQMessage {
+	old := d.resultBuf
+	d.resultBuf = nil
+	return old
+}
+
+// MixedBuild implements the EventBatchEncoder interface
+func (d *ProtobufEventBatchEncoder) MixedBuild(withVersion bool) []byte {
+	panic("Mixed Build only use for JsonEncoder")
+}
+
+// Size implements the EventBatchEncoder interface
+func (d *ProtobufEventBatchEncoder) Size() int {
+	sum := 0
+	for _, msg := range d.resultBuf {
+		sum += len(msg.Key)
+		sum += len(msg.Value)
+	}
+	return sum
+}
+
+// Reset implements the EventBatchEncoder interface
+func (d *ProtobufEventBatchEncoder) Reset() {
+	panic("Reset only used for JsonEncoder")
+}
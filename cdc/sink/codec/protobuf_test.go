@@ -0,0 +1,177 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+type protobufBatchEncoderSuite struct{}
+
+var _ = check.Suite(&protobufBatchEncoderSuite{})
+
+func newProtobufField(name string, number int32, tp descriptor.FieldDescriptorProto_Type,
+	label descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto {
+	return &descriptor.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   tp.Enum(),
+		Label:  label.Enum(),
+	}
+}
+
+func newTestDescriptorSet(c *check.C) []byte {
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+	required := descriptor.FieldDescriptorProto_LABEL_REQUIRED
+	fds := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("order.proto"),
+			Package: proto.String("shop"),
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Order"),
+				Field: []*descriptor.FieldDescriptorProto{
+					newProtobufField("id", 1, descriptor.FieldDescriptorProto_TYPE_INT64, required),
+					newProtobufField("amount", 2, descriptor.FieldDescriptorProto_TYPE_DOUBLE, optional),
+					newProtobufField("note", 3, descriptor.FieldDescriptorProto_TYPE_STRING, optional),
+					newProtobufField("flags", 4, descriptor.FieldDescriptorProto_TYPE_UINT64, optional),
+					newProtobufField("delta", 5, descriptor.FieldDescriptorProto_TYPE_SINT32, optional),
+					newProtobufField("paid", 6, descriptor.FieldDescriptorProto_TYPE_BOOL, optional),
+					newProtobufField("raw", 7, descriptor.FieldDescriptorProto_TYPE_BYTES, optional),
+				},
+			}},
+		}},
+	}
+	data, err := proto.Marshal(fds)
+	c.Assert(err, check.IsNil)
+	return data
+}
+
+func newTestProtobufConfig() *config.ReplicaConfig {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.Protobuf = &config.ProtobufConfig{
+		MessageRules: []*config.ProtobufMessageRule{
+			{Matcher: []string{"shop.orders_*"}, Message: "shop.Order"},
+		},
+	}
+	return cfg
+}
+
+func (s *protobufBatchEncoderSuite) TestEncodeAndDecode(c *check.C) {
+	schema, err := NewProtobufSchema(newTestDescriptorSet(c), newTestProtobufConfig())
+	c.Assert(err, check.IsNil)
+	encoder := NewProtobufEventBatchEncoder().(*ProtobufEventBatchEncoder)
+	encoder.SetSchema(schema)
+
+	table := &model.TableName{Schema: "shop", Table: "orders_2020"}
+	row := &model.RowChangedEvent{
+		CommitTs: 417318403368288260,
+		Table:    table,
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(-42)},
+			{Name: "amount", Type: mysql.TypeNewDecimal, Value: "12.5"},
+			{Name: "note", Type: mysql.TypeVarchar, Value: []byte("hello")},
+			{Name: "flags", Type: mysql.TypeBit, Value: uint64(5)},
+			{Name: "delta", Type: mysql.TypeLong, Value: int64(-3)},
+			{Name: "paid", Type: mysql.TypeTiny, Value: int64(1)},
+			{Name: "raw", Type: mysql.TypeBlob, Value: []byte{0x0, 0x1}},
+			{Name: "unmapped", Type: mysql.TypeLong, Value: int64(7)},
+		},
+	}
+	res, err := encoder.AppendRowChangedEvent(row)
+	c.Assert(err, check.IsNil)
+	c.Assert(res, check.Equals, EncoderNeedAsyncWrite)
+	c.Assert(encoder.Size(), check.Greater, 0)
+
+	messages := encoder.Build()
+	c.Assert(messages, check.HasLen, 1)
+	c.Assert(messages[0].Ts, check.Equals, row.CommitTs)
+
+	cols, err := schema.DecodeRow(table, messages[0].Value)
+	c.Assert(err, check.IsNil)
+	c.Assert(cols, check.DeepEquals, []*model.Column{
+		{Name: "id", Value: int64(-42)},
+		{Name: "amount", Value: float64(12.5)},
+		{Name: "note", Value: "hello"},
+		{Name: "flags", Value: uint64(5)},
+		{Name: "delta", Value: int64(-3)},
+		{Name: "paid", Value: true},
+		{Name: "raw", Value: []byte{0x0, 0x1}},
+	})
+	keyCols, err := schema.DecodeRow(table, messages[0].Key)
+	c.Assert(err, check.IsNil)
+	c.Assert(keyCols, check.DeepEquals, []*model.Column{{Name: "id", Value: int64(-42)}})
+
+	// a delete event only carries the key
+	_, err = encoder.AppendRowChangedEvent(&model.RowChangedEvent{
+		CommitTs:   417318403368288261,
+		Table:      table,
+		PreColumns: row.Columns,
+	})
+	c.Assert(err, check.IsNil)
+	messages = encoder.Build()
+	c.Assert(messages, check.HasLen, 1)
+	c.Assert(messages[0].Value, check.IsNil)
+	keyCols, err = schema.DecodeRow(table, messages[0].Key)
+	c.Assert(err, check.IsNil)
+	c.Assert(keyCols, check.DeepEquals, []*model.Column{{Name: "id", Value: int64(-42)}})
+}
+
+func (s *protobufBatchEncoderSuite) TestSchemaMismatch(c *check.C) {
+	schema, err := NewProtobufSchema(newTestDescriptorSet(c), newTestProtobufConfig())
+	c.Assert(err, check.IsNil)
+	encoder := NewProtobufEventBatchEncoder().(*ProtobufEventBatchEncoder)
+	encoder.SetSchema(schema)
+
+	// the required field `id` has no source column
+	_, err = encoder.AppendRowChangedEvent(&model.RowChangedEvent{
+		Table: &model.TableName{Schema: "shop", Table: "orders_2021"},
+		Columns: []*model.Column{
+			{Name: "order_id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag, Value: int64(1)},
+		},
+	})
+	c.Assert(cerror.ErrProtobufSchemaMismatch.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*required field id has no source column.*")
+
+	// the column value can not be stored in the field
+	_, err = encoder.AppendRowChangedEvent(&model.RowChangedEvent{
+		Table: &model.TableName{Schema: "shop", Table: "orders_2022"},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag, Value: int64(1)},
+			{Name: "flags", Type: mysql.TypeLonglong, Value: int64(-1)},
+		},
+	})
+	c.Assert(cerror.ErrProtobufSchemaMismatch.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*field flags of type uint64 can not hold.*")
+
+	// no message is mapped to the table
+	_, err = encoder.AppendRowChangedEvent(&model.RowChangedEvent{
+		Table: &model.TableName{Schema: "shop", Table: "customers"},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag, Value: int64(1)},
+		},
+	})
+	c.Assert(cerror.ErrProtobufSchemaMismatch.Equal(err), check.IsTrue)
+
+	// the message in rules must exist
+	cfg := newTestProtobufConfig()
+	cfg.Sink.Protobuf.MessageRules[0].Message = "shop.Customer"
+	_, err = NewProtobufSchema(newTestDescriptorSet(c), cfg)
+	c.Assert(cerror.ErrProtobufInvalidDescriptor.Equal(err), check.IsTrue)
+}
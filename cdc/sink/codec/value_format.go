@@ -0,0 +1,162 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// ValueFormat rewrites the ENUM, SET and BIT values of the row changed events into the
+// representations chosen in the sink config before the events are encoded.
+// The mounter always emits an ENUM as the index, a SET as the bitmask and a BIT as an integer.
+type ValueFormat struct {
+	enum string
+	set  string
+	bit  string
+}
+
+// NewValueFormat creates a ValueFormat, it returns nil if all the values keep the default representations
+func NewValueFormat(cfg *config.SinkConfig) *ValueFormat {
+	f := &ValueFormat{enum: cfg.EnumFormat, set: cfg.SetFormat, bit: cfg.BitFormat}
+	if !f.isString(mysql.TypeEnum) && !f.isString(mysql.TypeSet) && !f.isString(mysql.TypeBit) {
+		return nil
+	}
+	return f
+}
+
+// isString returns whether the values of the type are rewritten into strings
+func (f *ValueFormat) isString(tp byte) bool {
+	if f == nil {
+		return false
+	}
+	switch tp {
+	case mysql.TypeEnum:
+		return f.enum == config.EnumFormatName
+	case mysql.TypeSet:
+		return f.set == config.SetFormatString
+	case mysql.TypeBit:
+		return f.bit == config.BitFormatBase64
+	}
+	return false
+}
+
+// FormatRow returns the row changed event with the values in the configured representations.
+// The row is copied if any value is rewritten, so that the row passed in is left untouched.
+func (f *ValueFormat) FormatRow(row *model.RowChangedEvent) (*model.RowChangedEvent, error) {
+	if f == nil {
+		return row, nil
+	}
+	cols, colsChanged, err := f.formatColumns(row.Columns)
+	if err != nil {
+		return nil, err
+	}
+	preCols, preColsChanged, err := f.formatColumns(row.PreColumns)
+	if err != nil {
+		return nil, err
+	}
+	if !colsChanged && !preColsChanged {
+		return row, nil
+	}
+	newRow := *row
+	newRow.Columns = cols
+	newRow.PreColumns = preCols
+	return &newRow, nil
+}
+
+func (f *ValueFormat) formatColumns(cols []*model.Column) ([]*model.Column, bool, error) {
+	var newCols []*model.Column
+	for i, col := range cols {
+		if col == nil || col.Value == nil || !f.isString(col.Type) {
+			continue
+		}
+		value, err := f.formatValue(col)
+		if err != nil {
+			return nil, false, err
+		}
+		if newCols == nil {
+			newCols = make([]*model.Column, len(cols))
+			copy(newCols, cols)
+		}
+		newCol := *col
+		newCol.Value = value
+		newCols[i] = &newCol
+	}
+	if newCols == nil {
+		return cols, false, nil
+	}
+	return newCols, true, nil
+}
+
+func (f *ValueFormat) formatValue(col *model.Column) (string, error) {
+	v, ok := col.Value.(uint64)
+	if !ok {
+		return "", cerror.ErrValueFormatFailed.GenWithStackByArgs(col.Name, col.Value)
+	}
+	switch col.Type {
+	case mysql.TypeEnum:
+		// the index 0 is reserved for the empty string of an invalid value
+		if v == 0 {
+			return "", nil
+		}
+		if v > uint64(len(col.Elems)) {
+			return "", cerror.ErrValueFormatFailed.GenWithStackByArgs(col.Name, v)
+		}
+		return col.Elems[v-1], nil
+	case mysql.TypeSet:
+		var names []string
+		for i, elem := range col.Elems {
+			if v&(1<<uint(i)) != 0 {
+				names = append(names, elem)
+				v &^= 1 << uint(i)
+			}
+		}
+		if v != 0 {
+			return "", cerror.ErrValueFormatFailed.GenWithStackByArgs(col.Name, col.Value)
+		}
+		return strings.Join(names, ","), nil
+	default:
+		return base64.StdEncoding.EncodeToString(bitBytes(v)), nil
+	}
+}
+
+// bitBytes returns the big-endian bytes of a BIT value without the leading zero bytes
+func bitBytes(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	i := 0
+	for i < len(buf)-1 && buf[i] == 0 {
+		i++
+	}
+	return buf[i:]
+}
+
+// bitFromBase64 decodes a BIT value encoded as the base64 of its big-endian bytes
+func bitFromBase64(s string) (uint64, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) > 8 {
+		return 0, cerror.ErrValueFormatFailed.GenWithStackByArgs("bit", s)
+	}
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v, nil
+}
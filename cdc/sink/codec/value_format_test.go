@@ -0,0 +1,209 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/json"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+)
+
+type valueFormatSuite struct{}
+
+var _ = check.Suite(&valueFormatSuite{})
+
+func newValueFormatRow() *model.RowChangedEvent {
+	elems := []string{"a", "b", "c"}
+	return &model.RowChangedEvent{
+		CommitTs: 1,
+		Table:    &model.TableName{Schema: "test", Table: "t"},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(1)},
+			{Name: "e", Type: mysql.TypeEnum, Value: uint64(2), Elems: elems},
+			{Name: "s", Type: mysql.TypeSet, Value: uint64(5), Elems: elems},
+			{Name: "b", Type: mysql.TypeBit, Value: uint64(0x0102)},
+			{Name: "null_e", Type: mysql.TypeEnum, Value: nil, Elems: elems},
+		},
+		PreColumns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(1)},
+			{Name: "e", Type: mysql.TypeEnum, Value: uint64(0), Elems: elems},
+			{Name: "s", Type: mysql.TypeSet, Value: uint64(0), Elems: elems},
+			{Name: "b", Type: mysql.TypeBit, Value: uint64(0)},
+			{Name: "null_e", Type: mysql.TypeEnum, Value: nil, Elems: elems},
+		},
+	}
+}
+
+func columnValues(cols []*model.Column) []interface{} {
+	values := make([]interface{}, 0, len(cols))
+	for _, col := range cols {
+		values = append(values, col.Value)
+	}
+	return values
+}
+
+// columnValuesByName returns the values of the decoded columns, whose order is not kept
+func columnValuesByName(cols []*model.Column) map[string]interface{} {
+	values := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		values[col.Name] = col.Value
+	}
+	return values
+}
+
+func (s *valueFormatSuite) TestFormatRow(c *check.C) {
+	row := newValueFormatRow()
+	c.Assert(NewValueFormat(&config.SinkConfig{}), check.IsNil)
+	f := NewValueFormat(&config.SinkConfig{
+		EnumFormat: config.EnumFormatIndex,
+		SetFormat:  config.SetFormatBitmask,
+		BitFormat:  config.BitFormatInteger,
+	})
+	c.Assert(f, check.IsNil)
+	formatted, err := f.FormatRow(row)
+	c.Assert(err, check.IsNil)
+	c.Assert(formatted, check.Equals, row)
+
+	f = NewValueFormat(&config.SinkConfig{
+		EnumFormat: config.EnumFormatName,
+		SetFormat:  config.SetFormatString,
+		BitFormat:  config.BitFormatBase64,
+	})
+	formatted, err = f.FormatRow(row)
+	c.Assert(err, check.IsNil)
+	c.Assert(columnValues(formatted.Columns), check.DeepEquals, []interface{}{int64(1), "b", "a,c", "AQI=", nil})
+	c.Assert(columnValues(formatted.PreColumns), check.DeepEquals, []interface{}{int64(1), "", "", "AA==", nil})
+	// the row passed in is left untouched
+	c.Assert(row, check.DeepEquals, newValueFormatRow())
+
+	// only the configured types are rewritten
+	f = NewValueFormat(&config.SinkConfig{EnumFormat: config.EnumFormatName})
+	formatted, err = f.FormatRow(row)
+	c.Assert(err, check.IsNil)
+	c.Assert(columnValues(formatted.Columns), check.DeepEquals, []interface{}{int64(1), "b", uint64(5), uint64(0x0102), nil})
+	c.Assert(formatted.Columns[0], check.Equals, row.Columns[0])
+
+	row.Columns[1].Value = uint64(4)
+	_, err = f.FormatRow(row)
+	c.Assert(err, check.ErrorMatches, ".*can not format the value of column e: 4.*")
+	row.Columns[1].Value = uint64(1)
+	row.Columns[2].Value = uint64(8)
+	f = NewValueFormat(&config.SinkConfig{SetFormat: config.SetFormatString})
+	_, err = f.FormatRow(row)
+	c.Assert(err, check.ErrorMatches, ".*can not format the value of column s: 8.*")
+}
+
+func (s *valueFormatSuite) TestValidateValueFormat(c *check.C) {
+	cfg := &config.SinkConfig{}
+	c.Assert(cfg.ValidateValueFormat(), check.IsNil)
+	cfg.EnumFormat = config.EnumFormatName
+	cfg.SetFormat = config.SetFormatString
+	cfg.BitFormat = config.BitFormatBase64
+	c.Assert(cfg.ValidateValueFormat(), check.IsNil)
+	cfg.BitFormat = "hex"
+	c.Assert(cfg.ValidateValueFormat(), check.ErrorMatches, ".*invalid bit format: hex.*")
+}
+
+// roundTripJSON encodes the row with the default protocol and decodes it back
+func (s *valueFormatSuite) roundTripJSON(c *check.C, cfg *config.SinkConfig) *model.RowChangedEvent {
+	row, err := NewValueFormat(cfg).FormatRow(newValueFormatRow())
+	c.Assert(err, check.IsNil)
+	encoder := NewJSONEventBatchEncoder()
+	_, err = encoder.AppendRowChangedEvent(row)
+	c.Assert(err, check.IsNil)
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+	decoder, err := NewJSONEventBatchDecoder(msgs[0].Key, msgs[0].Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+	decoded, err := decoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	return decoded
+}
+
+func (s *valueFormatSuite) TestJSONRoundTrip(c *check.C) {
+	decoded := s.roundTripJSON(c, &config.SinkConfig{})
+	c.Assert(columnValuesByName(decoded.Columns), check.DeepEquals, map[string]interface{}{
+		"id": json.Number("1"), "e": json.Number("2"), "s": json.Number("5"), "b": uint64(0x0102), "null_e": nil,
+	})
+
+	decoded = s.roundTripJSON(c, &config.SinkConfig{
+		EnumFormat: config.EnumFormatName,
+		SetFormat:  config.SetFormatString,
+		BitFormat:  config.BitFormatBase64,
+	})
+	// the base64 BIT value is decoded back to the integer the MySQL sink writes
+	c.Assert(columnValuesByName(decoded.Columns), check.DeepEquals, map[string]interface{}{
+		"id": json.Number("1"), "e": "b", "s": "a,c", "b": uint64(0x0102), "null_e": nil,
+	})
+	c.Assert(columnValuesByName(decoded.PreColumns), check.DeepEquals, map[string]interface{}{
+		"id": json.Number("1"), "e": "", "s": "", "b": uint64(0), "null_e": nil,
+	})
+}
+
+func (s *valueFormatSuite) TestCanalColumn(c *check.C) {
+	builder := NewCanalEntryBuilder()
+	buildValues := func(cfg *config.SinkConfig) []string {
+		row, err := NewValueFormat(cfg).FormatRow(newValueFormatRow())
+		c.Assert(err, check.IsNil)
+		values := make([]string, 0, 3)
+		for _, col := range row.Columns[1:4] {
+			canalCol, err := builder.buildColumn(col, col.Name, false)
+			c.Assert(err, check.IsNil)
+			values = append(values, canalCol.GetValue())
+		}
+		return values
+	}
+	c.Assert(buildValues(&config.SinkConfig{}), check.DeepEquals, []string{"2", "5", "258"})
+	c.Assert(buildValues(&config.SinkConfig{
+		EnumFormat: config.EnumFormatName,
+		SetFormat:  config.SetFormatString,
+		BitFormat:  config.BitFormatBase64,
+	}), check.DeepEquals, []string{"b", "a,c", "AQI="})
+}
+
+func (s *valueFormatSuite) TestAvroColumn(c *check.C) {
+	f := NewValueFormat(&config.SinkConfig{
+		EnumFormat: config.EnumFormatName,
+		SetFormat:  config.SetFormatString,
+	})
+	row, err := f.FormatRow(newValueFormatRow())
+	c.Assert(err, check.IsNil)
+	for _, col := range row.Columns[1:] {
+		tp, err := getAvroDataTypeFromColumn(col, f)
+		c.Assert(err, check.IsNil)
+		if col.Type == mysql.TypeBit {
+			c.Assert(tp, check.DeepEquals, unsignedLongAvroType)
+		} else {
+			c.Assert(tp, check.Equals, "string")
+		}
+	}
+	data, tp, err := columnToAvroNativeData(row.Columns[1])
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.Equals, "b")
+	c.Assert(tp, check.Equals, "string")
+	data, tp, err = columnToAvroNativeData(row.Columns[2])
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.Equals, "a,c")
+	c.Assert(tp, check.Equals, "string")
+	_, tp, err = columnToAvroNativeData(row.Columns[3])
+	c.Assert(err, check.IsNil)
+	c.Assert(tp, check.Equals, "bytes."+string(decimalType))
+}
@@ -80,11 +80,23 @@ func (s *dispatcherSwitcher) matchDispatcher(row *model.RowChangedEvent) Dispatc
 	return nil
 }
 
-// NewDispatcher creates a new dispatcher
+// NewDispatcher creates a new dispatcher, the rows of a table are dispatched by the first
+// dispatch rule matching the table, or the dispatcher of the changefeed if no rule matches
 func NewDispatcher(cfg *config.ReplicaConfig, partitionNum int32) (Dispatcher, error) {
-	ruleConfigs := append(cfg.Sink.DispatchRules, &config.DispatchRule{
+	// the dispatchers are validated when the changefeed is created or updated, the changefeeds
+	// created by the old versions may still use the unsupported ones, which fall back to the default
+	if err := cfg.Sink.ValidateDispatchers(); err != nil {
+		log.Warn("the dispatchers are invalid", zap.Error(err))
+	}
+	defaultDispatcher := cfg.Sink.Dispatcher
+	if defaultDispatcher == "" {
+		defaultDispatcher = "default"
+	}
+	ruleConfigs := make([]*config.DispatchRule, 0, len(cfg.Sink.DispatchRules)+1)
+	ruleConfigs = append(ruleConfigs, cfg.Sink.DispatchRules...)
+	ruleConfigs = append(ruleConfigs, &config.DispatchRule{
 		Matcher:    []string{"*.*"},
-		Dispatcher: "default",
+		Dispatcher: defaultDispatcher,
 	})
 	rules := make([]struct {
 		Dispatcher
@@ -75,3 +75,70 @@ func (s SwitcherSuite) TestSwitcher(c *check.C) {
 		},
 	}), check.FitsTypeOf, &indexValueDispatcher{})
 }
+
+func (s SwitcherSuite) TestPerTableDispatchers(c *check.C) {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.DispatchRules = []*config.DispatchRule{
+		{Matcher: []string{"test.orders"}, Dispatcher: "index-value"},
+		{Matcher: []string{"test.ledger"}, Dispatcher: "table"},
+	}
+	cfg.Sink.Dispatcher = "ts"
+	d, err := NewDispatcher(cfg, 16)
+	c.Assert(err, check.IsNil)
+	// the config is not modified by the dispatcher
+	c.Assert(cfg.Sink.DispatchRules, check.HasLen, 2)
+
+	row := func(table string, id int64, commitTs uint64) *model.RowChangedEvent {
+		return &model.RowChangedEvent{
+			CommitTs: commitTs,
+			Table:    &model.TableName{Schema: "test", Table: table},
+			Columns:  []*model.Column{{Name: "id", Flag: model.HandleKeyFlag, Value: id}},
+		}
+	}
+	orderPartitions := make(map[int32]struct{})
+	ledgerPartitions := make(map[int32]struct{})
+	for i := int64(0); i < 64; i++ {
+		// the rows of a key are always dispatched to the same partition
+		partition := d.Dispatch(row("orders", i, uint64(i)))
+		c.Assert(d.Dispatch(row("orders", i, uint64(i+100))), check.Equals, partition)
+		orderPartitions[partition] = struct{}{}
+		ledgerPartitions[d.Dispatch(row("ledger", i, uint64(i)))] = struct{}{}
+	}
+	// the rows of orders are spread by the key, and the rows of ledger go to a single partition
+	c.Assert(len(orderPartitions), check.Greater, 1)
+	c.Assert(ledgerPartitions, check.HasLen, 1)
+
+	// the dispatcher of the changefeed dispatches the rows of the other tables
+	c.Assert(d.(*dispatcherSwitcher).matchDispatcher(row("users", 1, 1)), check.FitsTypeOf, &tsDispatcher{})
+	c.Assert(d.Dispatch(row("users", 1, 1)), check.Not(check.Equals), d.Dispatch(row("users", 1, 2)))
+}
+
+func (s SwitcherSuite) TestInvalidDispatchers(c *check.C) {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.DispatchRules = []*config.DispatchRule{
+		{Matcher: []string{"test.*"}, Dispatcher: "Table"},
+	}
+	_, err := NewDispatcher(cfg, 4)
+	c.Assert(err, check.IsNil)
+
+	cfg.Sink.DispatchRules = []*config.DispatchRule{
+		{Matcher: []string{"test.*"}, Dispatcher: "key"},
+	}
+	c.Assert(cfg.Sink.ValidateDispatchers(), check.ErrorMatches, ".*invalid dispatcher key: unsupported dispatcher.*")
+	// the changefeeds created by the old versions may use the unsupported dispatchers,
+	// which fall back to the default dispatcher
+	d, err := NewDispatcher(cfg, 4)
+	c.Assert(err, check.IsNil)
+	row := &model.RowChangedEvent{Table: &model.TableName{Schema: "test", Table: "t"}}
+	c.Assert(d.(*dispatcherSwitcher).matchDispatcher(row), check.FitsTypeOf, &defaultDispatcher{})
+
+	cfg.Sink.DispatchRules = []*config.DispatchRule{{Dispatcher: "ts"}}
+	c.Assert(cfg.Sink.ValidateDispatchers(), check.ErrorMatches, ".*invalid dispatcher ts: the matcher is empty.*")
+
+	cfg.Sink.DispatchRules = nil
+	cfg.Sink.Dispatcher = "random"
+	c.Assert(cfg.Sink.ValidateDispatchers(), check.ErrorMatches, ".*invalid dispatcher random: unsupported dispatcher.*")
+	d, err = NewDispatcher(cfg, 4)
+	c.Assert(err, check.IsNil)
+	c.Assert(d.(*dispatcherSwitcher).matchDispatcher(row), check.FitsTypeOf, &defaultDispatcher{})
+}
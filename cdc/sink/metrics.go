@@ -70,6 +70,48 @@ var (
 			Name:      "total_flushed_rows_count",
 			Help:      "totla count of flushed rows",
 		}, []string{"capture", "changefeed"})
+	filteredRowsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "sink",
+			Name:      "filtered_rows_count",
+			Help:      "total count of rows dropped by the filter",
+		}, []string{"capture", "changefeed"})
+	concurrentFlushesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "sink",
+			Name:      "concurrent_flushes",
+			Help:      "number of the flushes the MySQL sink workers are running",
+		}, []string{"capture", "changefeed"})
+	concurrentTableFlushesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "ticdc",
+			Subsystem: "sink",
+			Name:      "concurrent_table_flushes",
+			Help:      "number of the tables the MySQL sink workers are flushing in the table atomicity mode",
+		}, []string{"capture", "changefeed"})
+	fallbackEncodedRowsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "sink",
+			Name:      "fallback_encoded_rows_count",
+			Help:      "total count of rows encoded by the fallback protocol",
+		}, []string{"capture", "changefeed"})
+	quarantinedRowsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "sink",
+			Name:      "quarantined_rows_count",
+			Help:      "total count of rows discarded because their tables are quarantined",
+		}, []string{"capture", "changefeed"})
+	dedupHitsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "sink",
+			Name:      "dedup_hits_count",
+			Help:      "total count of duplicate rows dropped by the MQ sink",
+		}, []string{"capture", "changefeed"})
 )
 
 // InitMetrics registers all metrics in this file
@@ -81,4 +123,10 @@ func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(bucketSizeCounter)
 	registry.MustRegister(totalRowsCountGauge)
 	registry.MustRegister(totalFlushedRowsCountGauge)
+	registry.MustRegister(filteredRowsCounter)
+	registry.MustRegister(fallbackEncodedRowsCounter)
+	registry.MustRegister(concurrentFlushesGauge)
+	registry.MustRegister(concurrentTableFlushesGauge)
+	registry.MustRegister(quarantinedRowsCounter)
+	registry.MustRegister(dedupHitsCounter)
 }
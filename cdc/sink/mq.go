@@ -15,6 +15,7 @@ package sink
 
 import (
 	"context"
+	"io/ioutil"
 	"net/url"
 	"strconv"
 	"strings"
@@ -28,6 +29,7 @@ import (
 	"github.com/pingcap/ticdc/cdc/sink/dispatcher"
 	"github.com/pingcap/ticdc/cdc/sink/producer"
 	"github.com/pingcap/ticdc/cdc/sink/producer/kafka"
+	"github.com/pingcap/ticdc/cdc/sink/producer/memory"
 	"github.com/pingcap/ticdc/cdc/sink/producer/pulsar"
 	"github.com/pingcap/ticdc/pkg/config"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
@@ -44,6 +46,22 @@ type mqSink struct {
 	newEncoder func() codec.EventBatchEncoder
 	filter     *filter.Filter
 	protocol   codec.Protocol
+	// recordHeaders are the names of the headers attached to the messages of rows,
+	// every row is sent in a separate message if it's not empty.
+	recordHeaders []string
+	// valueFormat rewrites the ENUM, SET and BIT values before the rows are encoded
+	valueFormat *codec.ValueFormat
+	// newFallbackEncoder creates the encoders for the rows newEncoder fails to encode if it's not nil
+	newFallbackEncoder func() codec.EventBatchEncoder
+	fallbackProtocol   string
+	// deduplicator drops the duplicate rows, it's nil if the deduplication is disabled
+	deduplicator *rowDeduplicator
+	// watermarkProducer sends the resolved ts to a separate topic instead of the data topic if it's not nil
+	watermarkProducer producer.Producer
+	// prober checks the end-to-end delivery of the Kafka cluster if it's not nil
+	prober *kafka.Prober
+	// deleteKeyOnly trims the old values of the deletes to the handle key columns
+	deleteKeyOnly bool
 
 	partitionNum   int32
 	partitionInput []chan struct {
@@ -81,6 +99,11 @@ func newMqSink(
 	var protocol codec.Protocol
 	protocol.FromString(config.Sink.Protocol)
 
+	if err := config.Sink.ValidateValueFormat(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	valueFormat := codec.NewValueFormat(config.Sink)
+
 	newEncoder := codec.NewEventBatchEncoder(protocol)
 	if protocol == codec.ProtocolAvro {
 		registryURI, ok := opts["registry"]
@@ -104,12 +127,59 @@ func newMqSink(
 			avroEncoder := newEncoder1().(*codec.AvroEventBatchEncoder)
 			avroEncoder.SetKeySchemaManager(keySchemaManager)
 			avroEncoder.SetValueSchemaManager(valueSchemaManager)
+			avroEncoder.SetValueFormat(valueFormat)
 			return avroEncoder
 		}
+	} else if protocol == codec.ProtocolProtobuf {
+		if config.Sink.Protobuf == nil || config.Sink.Protobuf.DescriptorFile == "" {
+			return nil, cerror.ErrProtobufInvalidDescriptor.GenWithStackByArgs(
+				`protobuf protocol requires "sink.protobuf.descriptor-file" to be set`)
+		}
+		descriptorSet, err := ioutil.ReadFile(config.Sink.Protobuf.DescriptorFile)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrProtobufInvalidDescriptor, err)
+		}
+		schema, err := codec.NewProtobufSchema(descriptorSet, config)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		newEncoder1 := newEncoder
+		newEncoder = func() codec.EventBatchEncoder {
+			protobufEncoder := newEncoder1().(*codec.ProtobufEventBatchEncoder)
+			protobufEncoder.SetSchema(schema)
+			return protobufEncoder
+		}
 	} else if protocol == codec.ProtocolCanal && !config.EnableOldValue {
 		log.Error("Old value is not enabled when using Canal protocol. Please update changefeed config")
 		return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, errors.New("Canal requires old value to be enabled"))
 	}
+	newFallbackEncoder, err := newFallbackEncoder(config)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	commitTime, err := codec.NewCommitTime(config.Sink)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	newEncoder = codec.WithCommitTime(newEncoder, commitTime)
+	if config.Sink.CompactInsert && protocol != codec.ProtocolDefault {
+		return nil, cerror.ErrKafkaInvalidConfig.GenWithStack("compact-insert is only supported by the default protocol")
+	}
+	// without the old values the updates have no pre columns either, and can't be told from the inserts
+	if config.Sink.CompactInsert && !config.EnableOldValue {
+		return nil, cerror.ErrKafkaInvalidConfig.GenWithStack("compact-insert requires enable-old-value")
+	}
+	newEncoder = codec.WithCompactInsert(newEncoder, config.Sink.CompactInsert)
+	if newFallbackEncoder != nil {
+		newFallbackEncoder = codec.WithCommitTime(newFallbackEncoder, commitTime)
+	}
+	recordHeaders, err := parseRecordHeaders(opts["record-headers"])
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := config.Sink.Dedup.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
 
 	k := &mqSink{
 		mqProducer: mqProducer,
@@ -118,6 +188,16 @@ func newMqSink(
 		filter:     filter,
 		protocol:   protocol,
 
+		recordHeaders: recordHeaders,
+		valueFormat:   valueFormat,
+
+		newFallbackEncoder: newFallbackEncoder,
+		fallbackProtocol:   strings.ToLower(config.Sink.FallbackProtocol),
+
+		deduplicator: newRowDeduplicator(config.Sink.Dedup,
+			dedupHitsCounter.WithLabelValues(opts[OptCaptureAddr], opts[OptChangefeedID])),
+		deleteKeyOnly: config.Sink.DeleteKeyOnly,
+
 		partitionNum:        partitionNum,
 		partitionInput:      partitionInput,
 		partitionResolvedTs: make([]uint64, partitionNum),
@@ -142,10 +222,15 @@ func newMqSink(
 func (k *mqSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
 	rowsCount := 0
 	for _, row := range rows {
-		if k.filter.ShouldIgnoreDMLEvent(row.StartTs, row.Table.Schema, row.Table.Table) {
+		if k.filter.ShouldIgnoreRowChangedEvent(row) {
 			log.Info("Row changed event ignored", zap.Uint64("start-ts", row.StartTs))
 			continue
 		}
+		if k.deduplicator != nil && k.deduplicator.isDuplicate(row) {
+			log.Debug("duplicate row changed event dropped",
+				zap.Int64("table-id", row.Table.TableID), zap.Int64("row-id", row.RowID), zap.Uint64("commit-ts", row.CommitTs))
+			continue
+		}
 		partition := k.dispatcher.Dispatch(row)
 		select {
 		case <-ctx.Done():
@@ -158,6 +243,7 @@ func (k *mqSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowCha
 		rowsCount++
 	}
 	k.statistics.AddRowsCount(rowsCount)
+	k.statistics.AddFilteredRowsCount(len(rows) - rowsCount)
 	return nil
 }
 
@@ -210,7 +296,10 @@ func (k *mqSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
 	if msg == nil {
 		return nil
 	}
-	err = k.writeToProducer(ctx, msg.Key, msg.Value, codec.EncoderNeedSyncWrite, -1)
+	if k.watermarkProducer != nil {
+		return k.emitWatermarks(ctx, msg)
+	}
+	err = k.writeToProducer(ctx, msg.Key, msg.Value, nil, codec.EncoderNeedSyncWrite, -1)
 	return errors.Trace(err)
 }
 
@@ -234,7 +323,7 @@ func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
 		return nil
 	}
 	log.Debug("emit ddl event", zap.String("query", ddl.Query), zap.Uint64("commit-ts", ddl.CommitTs))
-	err = k.writeToProducer(ctx, msg.Key, msg.Value, codec.EncoderNeedSyncWrite, -1)
+	err = k.writeToProducer(ctx, msg.Key, msg.Value, nil, codec.EncoderNeedSyncWrite, -1)
 	return errors.Trace(err)
 }
 
@@ -244,8 +333,26 @@ func (k *mqSink) Initialize(ctx context.Context, tableInfo []*model.SimpleTableI
 	return nil
 }
 
+// Warning returns the error of the last delivery probe
+func (k *mqSink) Warning() error {
+	if k.prober != nil {
+		return k.prober.Err()
+	}
+	return nil
+}
+
 func (k *mqSink) Close() error {
 	err := k.mqProducer.Close()
+	if k.watermarkProducer != nil {
+		if err1 := k.watermarkProducer.Close(); err == nil {
+			err = err1
+		}
+	}
+	if k.prober != nil {
+		if err1 := k.prober.Close(); err == nil {
+			err = err1
+		}
+	}
 	return errors.Trace(err)
 }
 
@@ -269,7 +376,7 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 	tick := time.NewTicker(500 * time.Millisecond)
 	defer tick.Stop()
 
-	flushToProducer := func(op codec.EncoderResult) error {
+	flushToProducer := func(op codec.EncoderResult, headers []producer.MessageHeader) error {
 		return k.statistics.RecordBatchExecution(func() (int, error) {
 			messages := encoder.Build()
 			thisBatchSize := len(messages)
@@ -278,7 +385,7 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 			}
 
 			for _, msg := range messages {
-				err := k.writeToProducer(ctx, msg.Key, msg.Value, codec.EncoderNeedAsyncWrite, partition)
+				err := k.writeToProducer(ctx, msg.Key, msg.Value, headers, codec.EncoderNeedAsyncWrite, partition)
 				if err != nil {
 					return 0, err
 				}
@@ -303,7 +410,7 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-tick.C:
-			if err := flushToProducer(codec.EncoderNeedAsyncWrite); err != nil {
+			if err := flushToProducer(codec.EncoderNeedAsyncWrite, nil); err != nil {
 				return errors.Trace(err)
 			}
 			continue
@@ -311,7 +418,7 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 		}
 		if e.row == nil {
 			if e.resolvedTs != 0 {
-				if err := flushToProducer(codec.EncoderNeedAsyncWrite); err != nil {
+				if err := flushToProducer(codec.EncoderNeedAsyncWrite, nil); err != nil {
 					return errors.Trace(err)
 				}
 				atomic.StoreUint64(&k.partitionResolvedTs[partition], e.resolvedTs)
@@ -319,33 +426,65 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 			}
 			continue
 		}
-		op, err := encoder.AppendRowChangedEvent(e.row)
+		row, err := k.valueFormat.FormatRow(e.row)
 		if err != nil {
 			return errors.Trace(err)
 		}
+		if k.deleteKeyOnly {
+			row = trimDeleteToHandleKey(row)
+		}
+		op, err := encoder.AppendRowChangedEvent(row)
+		if err != nil {
+			// the other errors, e.g. of the schema registry, fail the changefeed and are retried by its restart
+			if k.newFallbackEncoder == nil || !codec.IsUnencodableError(err) {
+				return errors.Trace(err)
+			}
+			// the pending rows are sent first to keep the order of the rows in the partition
+			if err := flushToProducer(codec.EncoderNeedAsyncWrite, nil); err != nil {
+				return errors.Trace(err)
+			}
+			var headers []producer.MessageHeader
+			if len(k.recordHeaders) != 0 {
+				headers = rowRecordHeaders(k.recordHeaders, e.row)
+			}
+			if err := k.sendWithFallback(ctx, row, err, headers, partition); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+
+		if len(k.recordHeaders) != 0 {
+			if op == codec.EncoderNoOperation {
+				op = codec.EncoderNeedAsyncWrite
+			}
+			if err := flushToProducer(op, rowRecordHeaders(k.recordHeaders, e.row)); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
 
 		if encoder.Size() >= batchSizeLimit {
 			op = codec.EncoderNeedAsyncWrite
 		}
 
 		if encoder.Size() >= batchSizeLimit || op != codec.EncoderNoOperation {
-			if err := flushToProducer(op); err != nil {
+			if err := flushToProducer(op, nil); err != nil {
 				return errors.Trace(err)
 			}
 		}
 	}
 }
 
-func (k *mqSink) writeToProducer(ctx context.Context, key []byte, value []byte, op codec.EncoderResult, partition int32) error {
+func (k *mqSink) writeToProducer(ctx context.Context, key []byte, value []byte, headers []producer.MessageHeader, op codec.EncoderResult, partition int32) error {
 	switch op {
 	case codec.EncoderNeedAsyncWrite:
 		if partition >= 0 {
-			return k.mqProducer.SendMessage(ctx, key, value, partition)
+			return k.mqProducer.SendMessage(ctx, key, value, headers, partition)
 		}
 		return cerror.ErrAsyncBroadcaseNotSupport.GenWithStackByArgs()
 	case codec.EncoderNeedSyncWrite:
 		if partition >= 0 {
-			err := k.mqProducer.SendMessage(ctx, key, value, partition)
+			err := k.mqProducer.SendMessage(ctx, key, value, headers, partition)
 			if err != nil {
 				return err
 			}
@@ -361,6 +500,9 @@ func (k *mqSink) writeToProducer(ctx context.Context, key []byte, value []byte,
 	return nil
 }
 
+// defaultProbeInterval is the interval of the Kafka delivery probes if probe-topic is set
+const defaultProbeInterval = time.Minute
+
 func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL, filter *filter.Filter, replicaConfig *config.ReplicaConfig, opts map[string]string, errCh chan error) (*mqSink, error) {
 	config := kafka.NewKafkaConfig()
 
@@ -427,9 +569,40 @@ func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL, filter *filter.Fi
 		config.Credential.KeyPath = s
 	}
 
+	s = sinkURI.Query().Get("record-headers")
+	if s != "" {
+		// copy the opts since they may belong to the changefeed info
+		sinkOpts := make(map[string]string, len(opts)+1)
+		for k, v := range opts {
+			sinkOpts[k] = v
+		}
+		sinkOpts["record-headers"] = s
+		opts = sinkOpts
+	}
+
 	topic := strings.TrimFunc(sinkURI.Path, func(r rune) bool {
 		return r == '/'
 	})
+	watermarkTopic := sinkURI.Query().Get("watermark-topic")
+	if watermarkTopic != "" && watermarkTopic == topic {
+		return nil, cerror.ErrKafkaInvalidConfig.GenWithStack("watermark-topic must differ from the data topic %s", topic)
+	}
+	probeTopic := sinkURI.Query().Get("probe-topic")
+	if probeTopic != "" && (probeTopic == topic || probeTopic == watermarkTopic) {
+		return nil, cerror.ErrKafkaInvalidConfig.GenWithStack("probe-topic must differ from the data topic and the watermark topic")
+	}
+	probeInterval := defaultProbeInterval
+	s = sinkURI.Query().Get("probe-interval")
+	if s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+		}
+		if d <= 0 {
+			return nil, cerror.ErrKafkaInvalidConfig.GenWithStack("probe-interval must be positive, got %s", s)
+		}
+		probeInterval = d
+	}
 	producer, err := kafka.NewKafkaSaramaProducer(ctx, sinkURI.Host, topic, config, errCh)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -438,6 +611,24 @@ func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL, filter *filter.Fi
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if watermarkTopic != "" {
+		// all the watermarks are sent to a single partition of the watermark topic
+		watermarkConfig := config
+		watermarkConfig.PartitionNum = 1
+		sink.watermarkProducer, err = kafka.NewKafkaSaramaProducer(ctx, sinkURI.Host, watermarkTopic, watermarkConfig, errCh)
+		if err != nil {
+			_ = sink.Close()
+			return nil, errors.Trace(err)
+		}
+	}
+	if probeTopic != "" {
+		sink.prober, err = kafka.NewProber(ctx, sinkURI.Host, probeTopic, config, probeInterval)
+		if err != nil {
+			_ = sink.Close()
+			return nil, errors.Trace(err)
+		}
+		go sink.prober.Run(ctx)
+	}
 	return sink, nil
 }
 
@@ -459,3 +650,21 @@ func newPulsarSink(ctx context.Context, sinkURI *url.URL, filter *filter.Filter,
 	}
 	return sink, nil
 }
+
+func newMemorySink(ctx context.Context, sinkURI *url.URL, filter *filter.Filter, replicaConfig *config.ReplicaConfig, opts map[string]string, errCh chan error) (*mqSink, error) {
+	producer, err := memory.NewProducer(sinkURI)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s := sinkURI.Query().Get("protocol")
+	if s != "" {
+		replicaConfig.Sink.Protocol = s
+	}
+	sink, err := newMqSink(ctx, &security.Credential{}, producer, filter, replicaConfig, opts, errCh)
+	if err != nil {
+		// the queue is removed once no producer sends to it
+		_ = producer.Close()
+		return nil, errors.Trace(err)
+	}
+	return sink, nil
+}
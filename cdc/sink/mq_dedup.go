@@ -0,0 +1,100 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultDedupWindow  = time.Minute
+	defaultDedupMaxRows = 100000
+)
+
+type dedupKey struct {
+	tableID  model.TableID
+	handle   int64
+	commitTs uint64
+}
+
+type dedupEntry struct {
+	key    dedupKey
+	sentAt time.Time
+}
+
+// rowDeduplicator remembers the rows sent in the recent window, and drops the rows sent again
+// within the window, e.g. the rows replayed by a table moved back to the capture. The rows are
+// remembered in memory only, the rows replayed after the processor or the capture restarts are
+// sent again. It's not thread-safe.
+type rowDeduplicator struct {
+	window  time.Duration
+	maxRows int
+	// entries are the remembered rows in the order they are sent
+	entries *list.List
+	sent    map[dedupKey]struct{}
+	now     func() time.Time
+
+	metricHits prometheus.Counter
+}
+
+// newRowDeduplicator creates a rowDeduplicator, it returns nil if the deduplication is disabled
+func newRowDeduplicator(cfg *config.DedupConfig, metricHits prometheus.Counter) *rowDeduplicator {
+	if cfg == nil || !cfg.Enable {
+		return nil
+	}
+	d := &rowDeduplicator{
+		window:     defaultDedupWindow,
+		maxRows:    defaultDedupMaxRows,
+		entries:    list.New(),
+		sent:       make(map[dedupKey]struct{}),
+		now:        time.Now,
+		metricHits: metricHits,
+	}
+	if cfg.Window > 0 {
+		d.window = time.Duration(cfg.Window) * time.Second
+	}
+	if cfg.MaxRows > 0 {
+		d.maxRows = cfg.MaxRows
+	}
+	return d
+}
+
+// isDuplicate returns true if the same row is sent within the window, otherwise the row is remembered
+func (d *rowDeduplicator) isDuplicate(row *model.RowChangedEvent) bool {
+	now := d.now()
+	for front := d.entries.Front(); front != nil && now.Sub(front.Value.(dedupEntry).sentAt) >= d.window; front = d.entries.Front() {
+		d.forget(front)
+	}
+	key := dedupKey{tableID: row.Table.TableID, handle: row.RowID, commitTs: row.CommitTs}
+	if _, ok := d.sent[key]; ok {
+		d.metricHits.Inc()
+		return true
+	}
+	if d.entries.Len() >= d.maxRows {
+		d.forget(d.entries.Front())
+	}
+	d.sent[key] = struct{}{}
+	d.entries.PushBack(dedupEntry{key: key, sentAt: now})
+	return false
+}
+
+func (d *rowDeduplicator) forget(e *list.Element) {
+	d.entries.Remove(e)
+	delete(d.sent, e.Value.(dedupEntry).key)
+}
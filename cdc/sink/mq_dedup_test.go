@@ -0,0 +1,117 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer/memory"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type dedupSuite struct{}
+
+var _ = check.Suite(&dedupSuite{})
+
+func newDedupTestRow(tableID model.TableID, rowID int64, commitTs uint64) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		CommitTs: commitTs,
+		RowID:    rowID,
+		Table:    &model.TableName{Schema: "test", Table: "t", TableID: tableID},
+	}
+}
+
+func (s *dedupSuite) TestDropDuplicatesInWindow(c *check.C) {
+	c.Assert(newRowDeduplicator(nil, nil), check.IsNil)
+	c.Assert(newRowDeduplicator(&config.DedupConfig{Window: 10}, nil), check.IsNil)
+
+	counter := dedupHitsCounter.WithLabelValues("capture", "dedup-test")
+	d := newRowDeduplicator(&config.DedupConfig{Enable: true, Window: 10, MaxRows: 3}, counter)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 1, 100)), check.IsFalse)
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 1, 100)), check.IsTrue)
+	// another table, handle or commit ts is not a duplicate
+	c.Assert(d.isDuplicate(newDedupTestRow(2, 1, 100)), check.IsFalse)
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 2, 100)), check.IsFalse)
+	c.Assert(testutil.ToFloat64(counter), check.Equals, float64(1))
+
+	// the row is passed after the window expires
+	now = now.Add(9 * time.Second)
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 1, 100)), check.IsTrue)
+	now = now.Add(time.Second)
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 1, 100)), check.IsFalse)
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 1, 100)), check.IsTrue)
+	c.Assert(testutil.ToFloat64(counter), check.Equals, float64(3))
+
+	// the oldest rows are forgotten if there are too many rows
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 3, 100)), check.IsFalse)
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 4, 100)), check.IsFalse)
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 5, 100)), check.IsFalse)
+	c.Assert(d.entries.Len(), check.Equals, 3)
+	c.Assert(d.sent, check.HasLen, 3)
+	c.Assert(d.isDuplicate(newDedupTestRow(1, 1, 100)), check.IsFalse)
+}
+
+func (s *dedupSuite) TestMQSinkDropsReplayedRows(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer memory.RemoveQueue("dedup-sink-test")
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.Dedup = &config.DedupConfig{Enable: true}
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	sink, err := NewSink(ctx, "dedup-sink-test", "memory://dedup-sink-test?protocol=default",
+		f, cfg, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+	defer sink.Close()
+
+	rows := []*model.RowChangedEvent{newDedupTestRow(1, 1, 101), newDedupTestRow(1, 2, 102)}
+	c.Assert(sink.EmitRowChangedEvents(ctx, rows...), check.IsNil)
+	// the first row is replayed
+	c.Assert(sink.EmitRowChangedEvents(ctx, newDedupTestRow(1, 1, 101), newDedupTestRow(1, 3, 103)), check.IsNil)
+	_, err = sink.FlushRowChangedEvents(ctx, 103)
+	c.Assert(err, check.IsNil)
+
+	queue, ok := memory.LookupQueue("dedup-sink-test")
+	c.Assert(ok, check.IsTrue)
+	var rowIDs []int64
+	for _, msg := range queue.Poll(16) {
+		decoder, err := codec.NewJSONEventBatchDecoder(msg.Key, msg.Value)
+		c.Assert(err, check.IsNil)
+		for {
+			tp, hasNext, err := decoder.HasNext()
+			c.Assert(err, check.IsNil)
+			if !hasNext {
+				break
+			}
+			if tp != model.MqMessageTypeRow {
+				_, err = decoder.NextResolvedEvent()
+				c.Assert(err, check.IsNil)
+				continue
+			}
+			row, err := decoder.NextRowChangedEvent()
+			c.Assert(err, check.IsNil)
+			rowIDs = append(rowIDs, int64(row.CommitTs-100))
+		}
+	}
+	c.Assert(rowIDs, check.DeepEquals, []int64{1, 2, 3})
+}
@@ -0,0 +1,36 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import "github.com/pingcap/ticdc/cdc/model"
+
+// trimDeleteToHandleKey returns the delete with the handle key columns of the old value only,
+// the other rows and the deletes of the tables without a handle key are returned as they are
+func trimDeleteToHandleKey(row *model.RowChangedEvent) *model.RowChangedEvent {
+	if !row.IsDelete() {
+		return row
+	}
+	keys := make([]*model.Column, 0, 1)
+	for _, col := range row.PreColumns {
+		if col != nil && col.Flag.IsHandleKey() {
+			keys = append(keys, col)
+		}
+	}
+	if len(keys) == 0 || len(keys) == len(row.PreColumns) {
+		return row
+	}
+	trimmed := *row
+	trimmed.PreColumns = keys
+	return &trimmed
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer/memory"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+)
+
+type deleteKeySuite struct{}
+
+var _ = check.Suite(&deleteKeySuite{})
+
+func newDeleteKeyTestColumns(id int64, name string) []*model.Column {
+	return []*model.Column{
+		{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: id},
+		{Name: "name", Type: mysql.TypeVarchar, Value: []byte(name)},
+		{Name: "age", Type: mysql.TypeLonglong, Value: int64(18)},
+	}
+}
+
+func (s *deleteKeySuite) TestMQSinkDeleteKeyOnly(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	emit := func(deleteKeyOnly bool) map[model.OpType][]*model.RowChangedEvent {
+		defer memory.RemoveQueue("delete-key-test")
+		cfg := config.GetDefaultReplicaConfig()
+		cfg.Sink.DeleteKeyOnly = deleteKeyOnly
+		f, err := filter.NewFilter(cfg)
+		c.Assert(err, check.IsNil)
+		sink, err := NewSink(ctx, "delete-key-test", "memory://delete-key-test?protocol=default",
+			f, cfg, map[string]string{}, make(chan error, 1))
+		c.Assert(err, check.IsNil)
+		defer sink.Close()
+
+		table := &model.TableName{Schema: "test", Table: "t", TableID: 1}
+		rows := []*model.RowChangedEvent{
+			{CommitTs: 101, Table: table, Columns: newDeleteKeyTestColumns(1, "alice")},
+			{CommitTs: 102, Table: table, PreColumns: newDeleteKeyTestColumns(1, "alice")},
+		}
+		c.Assert(sink.EmitRowChangedEvents(ctx, rows...), check.IsNil)
+		_, err = sink.FlushRowChangedEvents(ctx, 102)
+		c.Assert(err, check.IsNil)
+
+		queue, ok := memory.LookupQueue("delete-key-test")
+		c.Assert(ok, check.IsTrue)
+		received := make(map[model.OpType][]*model.RowChangedEvent)
+		for _, msg := range queue.Poll(16) {
+			decoder, err := codec.NewJSONEventBatchDecoder(msg.Key, msg.Value)
+			c.Assert(err, check.IsNil)
+			for {
+				tp, hasNext, err := decoder.HasNext()
+				c.Assert(err, check.IsNil)
+				if !hasNext {
+					break
+				}
+				if tp != model.MqMessageTypeRow {
+					_, err = decoder.NextResolvedEvent()
+					c.Assert(err, check.IsNil)
+					continue
+				}
+				row, err := decoder.NextRowChangedEvent()
+				c.Assert(err, check.IsNil)
+				opType := model.OpTypePut
+				if row.IsDelete() {
+					opType = model.OpTypeDelete
+				}
+				received[opType] = append(received[opType], row)
+			}
+		}
+		return received
+	}
+
+	// the delete carries the handle key only, the insert is unaffected
+	received := emit(true)
+	c.Assert(received[model.OpTypePut], check.HasLen, 1)
+	c.Assert(received[model.OpTypePut][0].Columns, check.HasLen, 3)
+	c.Assert(received[model.OpTypeDelete], check.HasLen, 1)
+	deleted := received[model.OpTypeDelete][0]
+	c.Assert(deleted.PreColumns, check.HasLen, 1)
+	c.Assert(deleted.PreColumns[0].Name, check.Equals, "id")
+	c.Assert(fmt.Sprint(deleted.PreColumns[0].Value), check.Equals, "1")
+
+	// the delete carries the full old value by default
+	received = emit(false)
+	c.Assert(received[model.OpTypeDelete], check.HasLen, 1)
+	c.Assert(received[model.OpTypeDelete][0].PreColumns, check.HasLen, 3)
+}
+
+func (s *deleteKeySuite) TestTrimDeleteWithoutHandleKey(c *check.C) {
+	cols := newDeleteKeyTestColumns(1, "alice")
+	cols[0].Flag = 0
+	row := &model.RowChangedEvent{CommitTs: 100, PreColumns: cols}
+	c.Assert(trimDeleteToHandleKey(row), check.Equals, row)
+
+	// the invisible columns are dropped along with the other columns
+	cols = append(newDeleteKeyTestColumns(1, "alice"), nil)
+	row = &model.RowChangedEvent{CommitTs: 100, PreColumns: cols}
+	trimmed := trimDeleteToHandleKey(row)
+	c.Assert(trimmed.PreColumns, check.DeepEquals, cols[:1])
+	c.Assert(row.PreColumns, check.HasLen, 4)
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// fallbackProtocolHeader is the header attached to the messages encoded by the fallback protocol,
+// its value is the name of the fallback protocol
+const fallbackProtocolHeader = "fallback-protocol"
+
+// newFallbackEncoder returns the constructor of the encoders of the fallback protocol,
+// it returns nil if the fallback protocol is not configured.
+func newFallbackEncoder(config *config.ReplicaConfig) (func() codec.EventBatchEncoder, error) {
+	if err := config.Sink.ValidateFallbackProtocol(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if config.Sink.FallbackProtocol == "" {
+		return nil, nil
+	}
+	var protocol codec.Protocol
+	protocol.FromString(config.Sink.FallbackProtocol)
+	if protocol == codec.ProtocolCanal && !config.EnableOldValue {
+		return nil, cerror.ErrFallbackProtocolInvalid.GenWithStackByArgs(
+			config.Sink.FallbackProtocol, "canal requires old value to be enabled")
+	}
+	return codec.NewEventBatchEncoder(protocol), nil
+}
+
+// sendWithFallback sends the row the encoder failed to encode in a message of the fallback protocol.
+func (k *mqSink) sendWithFallback(
+	ctx context.Context, row *model.RowChangedEvent, cause error, headers []producer.MessageHeader, partition int32,
+) error {
+	encoder := k.newFallbackEncoder()
+	if _, err := encoder.AppendRowChangedEvent(row); err != nil {
+		return errors.Annotatef(err, "the fallback protocol failed to encode the row, the protocol failed with: %v", cause)
+	}
+	log.Warn("the row is encoded by the fallback protocol",
+		zap.String("table", row.Table.String()),
+		zap.Uint64("commit-ts", row.CommitTs),
+		zap.String("fallback-protocol", k.fallbackProtocol),
+		zap.Error(cause))
+	headers = append(headers, producer.MessageHeader{Key: fallbackProtocolHeader, Value: []byte(k.fallbackProtocol)})
+	err := k.statistics.RecordBatchExecution(func() (int, error) {
+		messages := encoder.Build()
+		for _, msg := range messages {
+			err := k.writeToProducer(ctx, msg.Key, msg.Value, headers, codec.EncoderNeedAsyncWrite, partition)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return len(messages), nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	k.statistics.AddFallbackRowsCount(1)
+	return nil
+}
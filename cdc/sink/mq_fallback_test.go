@@ -0,0 +1,193 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type mqFallbackSuite struct{}
+
+var _ = check.Suite(&mqFallbackSuite{})
+
+func (s *mqFallbackSuite) TestValidateFallbackProtocol(c *check.C) {
+	cfg := &config.SinkConfig{Protocol: "avro"}
+	c.Assert(cfg.ValidateFallbackProtocol(), check.IsNil)
+	for _, protocol := range []string{"default", "canal", "Maxwell"} {
+		cfg.FallbackProtocol = protocol
+		c.Assert(cfg.ValidateFallbackProtocol(), check.IsNil)
+	}
+	cfg.FallbackProtocol = "protobuf"
+	c.Assert(cfg.ValidateFallbackProtocol(), check.ErrorMatches, ".*unsupported fallback protocol.*")
+	cfg.Protocol, cfg.FallbackProtocol = "canal", "canal"
+	c.Assert(cfg.ValidateFallbackProtocol(), check.ErrorMatches, ".*the same as the protocol.*")
+
+	replicaConfig := config.GetDefaultReplicaConfig()
+	replicaConfig.Sink.FallbackProtocol = "canal"
+	replicaConfig.EnableOldValue = false
+	_, err := newFallbackEncoder(replicaConfig)
+	c.Assert(err, check.ErrorMatches, ".*canal requires old value to be enabled.*")
+	replicaConfig.Sink.FallbackProtocol = ""
+	newEncoder, err := newFallbackEncoder(replicaConfig)
+	c.Assert(err, check.IsNil)
+	c.Assert(newEncoder, check.IsNil)
+}
+
+// startMockRegistry mocks a schema registry which assigns ID 1 to all the schemas
+func startMockRegistry() {
+	httpmock.Activate()
+	httpmock.RegisterResponder("GET", "http://127.0.0.1:8081", httpmock.NewStringResponder(200, "{}"))
+	httpmock.RegisterResponder("POST", `=~^http://127.0.0.1:8081/subjects/(.+)/versions`,
+		httpmock.NewStringResponder(200, `{"id":1}`))
+}
+
+func (s *mqFallbackSuite) TestAvroFallbackToJSON(c *check.C) {
+	startMockRegistry()
+	defer httpmock.DeactivateAndReset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.Protocol = "avro"
+	cfg.Sink.FallbackProtocol = "default"
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	p := &recordingProducer{}
+	opts := map[string]string{"registry": "http://127.0.0.1:8081", OptChangefeedID: "fallback-test"}
+	sink, err := newMqSink(ctx, nil, p, f, cfg, opts, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+
+	table := &model.TableName{Schema: "test", Table: "t"}
+	row := func(commitTs uint64, id int64, createdAt string) *model.RowChangedEvent {
+		return &model.RowChangedEvent{CommitTs: commitTs, Table: table, Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag, Value: id},
+			{Name: "created_at", Type: mysql.TypeDatetime, Value: createdAt},
+		}}
+	}
+	// Avro can't encode the zero datetime
+	err = sink.EmitRowChangedEvents(ctx,
+		row(101, 1, "2020-01-01 00:00:00"),
+		row(102, 2, "0000-00-00 00:00:00"),
+		row(103, 3, "2020-01-03 00:00:00"),
+	)
+	c.Assert(err, check.IsNil)
+	checkpoint, err := sink.FlushRowChangedEvents(ctx, 103)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpoint, check.Equals, uint64(103))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c.Assert(p.messages, check.HasLen, 3)
+	c.Assert(p.messages[0].headers, check.HasLen, 0)
+	c.Assert(p.messages[2].headers, check.HasLen, 0)
+	// the rows are sent in order, the row Avro fails to encode is sent in JSON
+	fallback := p.messages[1]
+	c.Assert(fallback.headers, check.DeepEquals, []producer.MessageHeader{
+		{Key: fallbackProtocolHeader, Value: []byte("default")},
+	})
+	decoder, err := codec.NewJSONEventBatchDecoder(fallback.key, fallback.value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+	decoded, err := decoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded.CommitTs, check.Equals, uint64(102))
+	c.Assert(decoded.Columns, check.HasLen, 2)
+	c.Assert(decoded.Columns[1].Value, check.Equals, "0000-00-00 00:00:00")
+	_, hasNext, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+
+	counter := fallbackEncodedRowsCounter.WithLabelValues("", "fallback-test")
+	c.Assert(testutil.ToFloat64(counter), check.Equals, float64(1))
+}
+
+func (s *mqFallbackSuite) TestNoFallback(c *check.C) {
+	startMockRegistry()
+	defer httpmock.DeactivateAndReset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.Protocol = "avro"
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	errCh := make(chan error, 1)
+	sink, err := newMqSink(ctx, nil, &recordingProducer{}, f, cfg, map[string]string{"registry": "http://127.0.0.1:8081"}, errCh)
+	c.Assert(err, check.IsNil)
+	err = sink.EmitRowChangedEvents(ctx, &model.RowChangedEvent{
+		CommitTs: 101, Table: &model.TableName{Schema: "test", Table: "t"}, Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag, Value: int64(1)},
+			{Name: "created_at", Type: mysql.TypeDatetime, Value: "0000-00-00 00:00:00"},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	// the sink fails if no fallback protocol is configured
+	c.Assert(<-errCh, check.ErrorMatches, ".*could not encode to Avro.*")
+}
+
+func (s *mqFallbackSuite) TestNoFallbackOnRegistryError(c *check.C) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "http://127.0.0.1:8081", httpmock.NewStringResponder(200, "{}"))
+	// the registry assigns an illegal ID to the schemas
+	httpmock.RegisterResponder("POST", `=~^http://127.0.0.1:8081/subjects/(.+)/versions`,
+		httpmock.NewStringResponder(200, `{"id":0}`))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.Protocol = "avro"
+	cfg.Sink.FallbackProtocol = "default"
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	p := &recordingProducer{}
+	errCh := make(chan error, 1)
+	sink, err := newMqSink(ctx, nil, p, f, cfg, map[string]string{"registry": "http://127.0.0.1:8081"}, errCh)
+	c.Assert(err, check.IsNil)
+	err = sink.EmitRowChangedEvents(ctx, &model.RowChangedEvent{
+		CommitTs: 101, Table: &model.TableName{Schema: "test", Table: "t"}, Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag, Value: int64(1)},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	// the row is not sent with the fallback protocol since Avro can encode it
+	c.Assert(<-errCh, check.ErrorMatches, ".*Illegal schema ID.*")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c.Assert(p.messages, check.HasLen, 0)
+}
+
+func (s *mqFallbackSuite) TestIsUnencodableError(c *check.C) {
+	c.Assert(codec.IsUnencodableError(cerror.ErrAvroEncodeFailed.GenWithStackByArgs()), check.IsTrue)
+	c.Assert(codec.IsUnencodableError(errors.Annotate(cerror.ErrCanalEncodeFailed.GenWithStackByArgs(), "test")), check.IsTrue)
+	c.Assert(codec.IsUnencodableError(errors.Annotate(cerror.WrapError(cerror.ErrAvroEncodeToBinary, errors.New("test")), "test")), check.IsTrue)
+	c.Assert(codec.IsUnencodableError(cerror.ErrAvroSchemaAPIError.GenWithStackByArgs()), check.IsFalse)
+	c.Assert(codec.IsUnencodableError(cerror.WrapError(cerror.ErrAvroSchemaAPIError, errors.New("test"))), check.IsFalse)
+	c.Assert(codec.IsUnencodableError(errors.New("test")), check.IsFalse)
+}
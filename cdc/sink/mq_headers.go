@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// The record headers could be attached to the messages of the row changed events
+const (
+	recordHeaderSchema   = "schema"
+	recordHeaderTable    = "table"
+	recordHeaderOp       = "op"
+	recordHeaderCommitTs = "commit-ts"
+)
+
+// parseRecordHeaders parses the comma separated names of the record headers
+func parseRecordHeaders(s string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case recordHeaderSchema, recordHeaderTable, recordHeaderOp, recordHeaderCommitTs:
+			names = append(names, name)
+		default:
+			return nil, cerror.ErrKafkaInvalidConfig.GenWithStack(
+				"unknown record header %s, the supported headers are schema, table, op and commit-ts", name)
+		}
+	}
+	return names, nil
+}
+
+// rowOp returns the operation of the row changed event
+func rowOp(row *model.RowChangedEvent) string {
+	switch {
+	case row.IsDelete():
+		return "delete"
+	case len(row.PreColumns) != 0:
+		return "update"
+	default:
+		return "insert"
+	}
+}
+
+// rowRecordHeaders returns the record headers of the row changed event
+func rowRecordHeaders(names []string, row *model.RowChangedEvent) []producer.MessageHeader {
+	headers := make([]producer.MessageHeader, 0, len(names))
+	for _, name := range names {
+		var value string
+		switch name {
+		case recordHeaderSchema:
+			value = row.Table.Schema
+		case recordHeaderTable:
+			value = row.Table.Table
+		case recordHeaderOp:
+			value = rowOp(row)
+		case recordHeaderCommitTs:
+			value = strconv.FormatUint(row.CommitTs, 10)
+		}
+		headers = append(headers, producer.MessageHeader{Key: name, Value: []byte(value)})
+	}
+	return headers
+}
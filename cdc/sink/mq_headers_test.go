@@ -0,0 +1,130 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+)
+
+type mqHeadersSuite struct{}
+
+var _ = check.Suite(&mqHeadersSuite{})
+
+type recordedMessage struct {
+	key       []byte
+	value     []byte
+	headers   []producer.MessageHeader
+	partition int32
+}
+
+// recordingProducer records the messages sent to it, the broadcast messages are recorded with partition -1
+type recordingProducer struct {
+	mu           sync.Mutex
+	messages     []recordedMessage
+	partitionNum int32
+}
+
+func (p *recordingProducer) SendMessage(ctx context.Context, key []byte, value []byte, headers []producer.MessageHeader, partition int32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, recordedMessage{key: key, value: value, headers: headers, partition: partition})
+	return nil
+}
+
+func (p *recordingProducer) SyncBroadcastMessage(ctx context.Context, key []byte, value []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, recordedMessage{key: key, value: value, partition: -1})
+	return nil
+}
+
+func (p *recordingProducer) Flush(ctx context.Context) error { return nil }
+
+func (p *recordingProducer) GetPartitionNum() int32 {
+	if p.partitionNum == 0 {
+		return 1
+	}
+	return p.partitionNum
+}
+
+func (p *recordingProducer) Close() error { return nil }
+
+func (s *mqHeadersSuite) emitRows(c *check.C, opts map[string]string) []recordedMessage {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.GetDefaultReplicaConfig()
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	p := &recordingProducer{}
+	sink, err := newMqSink(ctx, nil, p, f, cfg, opts, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+	table := &model.TableName{Schema: "test", Table: "t"}
+	col := &model.Column{Name: "id", Type: 3, Flag: model.HandleKeyFlag, Value: 1}
+	err = sink.EmitRowChangedEvents(ctx,
+		&model.RowChangedEvent{CommitTs: 101, Table: table, Columns: []*model.Column{col}},
+		&model.RowChangedEvent{CommitTs: 102, Table: table, Columns: []*model.Column{col}, PreColumns: []*model.Column{col}},
+		&model.RowChangedEvent{CommitTs: 103, Table: &model.TableName{Schema: "test", Table: "t2"}, PreColumns: []*model.Column{col}},
+	)
+	c.Assert(err, check.IsNil)
+	checkpoint, err := sink.FlushRowChangedEvents(ctx, 103)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpoint, check.Equals, uint64(103))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.messages
+}
+
+func (s *mqHeadersSuite) TestRecordHeaders(c *check.C) {
+	messages := s.emitRows(c, map[string]string{"record-headers": "schema, table,op,commit-ts"})
+	c.Assert(messages, check.HasLen, 3)
+	header := func(schema, table, op, commitTs string) []producer.MessageHeader {
+		return []producer.MessageHeader{
+			{Key: "schema", Value: []byte(schema)},
+			{Key: "table", Value: []byte(table)},
+			{Key: "op", Value: []byte(op)},
+			{Key: "commit-ts", Value: []byte(commitTs)},
+		}
+	}
+	c.Assert(messages[0].headers, check.DeepEquals, header("test", "t", "insert", "101"))
+	c.Assert(messages[1].headers, check.DeepEquals, header("test", "t", "update", "102"))
+	c.Assert(messages[2].headers, check.DeepEquals, header("test", "t2", "delete", "103"))
+
+	// only the configured headers are attached
+	messages = s.emitRows(c, map[string]string{"record-headers": "op"})
+	c.Assert(messages, check.HasLen, 3)
+	c.Assert(messages[2].headers, check.DeepEquals, []producer.MessageHeader{{Key: "op", Value: []byte("delete")}})
+
+	// the rows are batched without headers
+	messages = s.emitRows(c, map[string]string{})
+	c.Assert(messages, check.HasLen, 1)
+	c.Assert(messages[0].headers, check.HasLen, 0)
+}
+
+func (s *mqHeadersSuite) TestParseRecordHeaders(c *check.C) {
+	names, err := parseRecordHeaders("")
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.HasLen, 0)
+	names, err = parseRecordHeaders("Commit-TS,schema")
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"commit-ts", "schema"})
+	_, err = parseRecordHeaders("schema,region")
+	c.Assert(err, check.ErrorMatches, ".*unknown record header region.*")
+}
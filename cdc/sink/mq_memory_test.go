@@ -0,0 +1,192 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer/memory"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+)
+
+type memorySinkSuite struct{}
+
+var _ = check.Suite(&memorySinkSuite{})
+
+func (s *memorySinkSuite) TestProduceAndConsume(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer memory.RemoveQueue("memory-sink-test")
+	cfg := config.GetDefaultReplicaConfig()
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	sink, err := NewSink(ctx, "memory-sink-test", "memory://memory-sink-test?partition-num=2&protocol=default",
+		f, cfg, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+
+	table := &model.TableName{Schema: "test", Table: "t"}
+	var rows []*model.RowChangedEvent
+	for i := 1; i <= 3; i++ {
+		rows = append(rows, &model.RowChangedEvent{CommitTs: uint64(100 + i), Table: table, Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag, Value: int64(i)},
+		}})
+	}
+	c.Assert(sink.EmitRowChangedEvents(ctx, rows...), check.IsNil)
+	checkpoint, err := sink.FlushRowChangedEvents(ctx, 103)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpoint, check.Equals, uint64(103))
+	c.Assert(sink.EmitCheckpointTs(ctx, 103), check.IsNil)
+
+	queue, ok := memory.LookupQueue("memory-sink-test")
+	c.Assert(ok, check.IsTrue)
+	// the queue is removed once the sink is closed, the messages are kept for the consumers holding it
+	c.Assert(sink.Close(), check.IsNil)
+	_, ok = memory.LookupQueue("memory-sink-test")
+	c.Assert(ok, check.IsFalse)
+	var commitTs []uint64
+	resolvedPartitions := make(map[int32]struct{})
+	// the messages can be peeked without consuming them
+	c.Assert(queue.Peek(16), check.DeepEquals, queue.Peek(16))
+	for _, msg := range queue.Poll(16) {
+		c.Assert(msg.Partition, check.Less, int32(2))
+		decoder, err := codec.NewJSONEventBatchDecoder(msg.Key, msg.Value)
+		c.Assert(err, check.IsNil)
+		for {
+			tp, hasNext, err := decoder.HasNext()
+			c.Assert(err, check.IsNil)
+			if !hasNext {
+				break
+			}
+			switch tp {
+			case model.MqMessageTypeRow:
+				row, err := decoder.NextRowChangedEvent()
+				c.Assert(err, check.IsNil)
+				commitTs = append(commitTs, row.CommitTs)
+			case model.MqMessageTypeResolved:
+				ts, err := decoder.NextResolvedEvent()
+				c.Assert(err, check.IsNil)
+				c.Assert(ts, check.Equals, uint64(103))
+				resolvedPartitions[msg.Partition] = struct{}{}
+			}
+		}
+	}
+	c.Assert(resolvedPartitions, check.HasLen, 2)
+	// all the rows of a table are dispatched to the same partition
+	c.Assert(commitTs, check.DeepEquals, []uint64{101, 102, 103})
+	c.Assert(queue.Poll(16), check.HasLen, 0)
+	c.Assert(queue.Dropped(), check.Equals, uint64(0))
+}
+
+func mustParseURL(c *check.C, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	c.Assert(err, check.IsNil)
+	return u
+}
+
+func (s *memorySinkSuite) TestDropWhenFull(c *check.C) {
+	ctx := context.Background()
+	defer memory.RemoveQueue("memory-full-test")
+	_, err := memory.NewProducer(mustParseURL(c, "memory://?capacity=2"))
+	c.Assert(err, check.ErrorMatches, ".*the name of the memory queue is required.*")
+	_, err = memory.NewProducer(mustParseURL(c, "memory://memory-full-test?capacity=-1"))
+	c.Assert(err, check.ErrorMatches, ".*invalid capacity -1.*")
+
+	p, err := memory.NewProducer(mustParseURL(c, "memory://memory-full-test?capacity=2"))
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 5; i++ {
+		c.Assert(p.SendMessage(ctx, nil, []byte{byte(i)}, nil, 0), check.IsNil)
+	}
+	queue, ok := memory.LookupQueue("memory-full-test")
+	c.Assert(ok, check.IsTrue)
+	c.Assert(queue.Dropped(), check.Equals, uint64(3))
+	msgs := queue.Poll(16)
+	c.Assert(msgs, check.HasLen, 2)
+	c.Assert(msgs[0].Value, check.DeepEquals, []byte{0})
+	c.Assert(msgs[1].Value, check.DeepEquals, []byte{1})
+
+	// the messages sent after closing are discarded
+	c.Assert(p.Close(), check.IsNil)
+	c.Assert(p.SendMessage(ctx, nil, []byte{5}, nil, 0), check.IsNil)
+	c.Assert(queue.Poll(16), check.HasLen, 0)
+}
+
+func (s *memorySinkSuite) TestCompactInsertRequiresOldValue(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer memory.RemoveQueue("compact-insert-test")
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.CompactInsert = true
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	const sinkURI = "memory://compact-insert-test?protocol=default"
+
+	// without the old value an update has no pre columns, and would be encoded as an insert
+	_, err = NewSink(ctx, "compact-insert-test", sinkURI, f, cfg, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.ErrorMatches, ".*compact-insert requires enable-old-value.*")
+
+	cfg.EnableOldValue = true
+	sink, err := NewSink(ctx, "compact-insert-test", sinkURI, f, cfg, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+	defer sink.Close() //nolint:errcheck
+	table := &model.TableName{Schema: "test", Table: "t"}
+	newCols := func(v int64) []*model.Column {
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(1)},
+			{Name: "v", Type: mysql.TypeLonglong, Value: v},
+		}
+	}
+	rows := []*model.RowChangedEvent{
+		{CommitTs: 101, Table: table, Columns: newCols(1)},
+		{CommitTs: 102, Table: table, Columns: newCols(2), PreColumns: newCols(1)},
+	}
+	c.Assert(sink.EmitRowChangedEvents(ctx, rows...), check.IsNil)
+	_, err = sink.FlushRowChangedEvents(ctx, 102)
+	c.Assert(err, check.IsNil)
+
+	queue, ok := memory.LookupQueue("compact-insert-test")
+	c.Assert(ok, check.IsTrue)
+	var received []*model.RowChangedEvent
+	for _, msg := range queue.Poll(16) {
+		decoder, err := codec.NewJSONEventBatchDecoder(msg.Key, msg.Value)
+		c.Assert(err, check.IsNil)
+		for {
+			tp, hasNext, err := decoder.HasNext()
+			c.Assert(err, check.IsNil)
+			if !hasNext {
+				break
+			}
+			if tp != model.MqMessageTypeRow {
+				_, err = decoder.NextResolvedEvent()
+				c.Assert(err, check.IsNil)
+				continue
+			}
+			row, err := decoder.NextRowChangedEvent()
+			c.Assert(err, check.IsNil)
+			received = append(received, row)
+		}
+	}
+	c.Assert(received, check.HasLen, 2)
+	// the insert carries the column values and types only, and the update keeps the full form
+	c.Assert(received[0].PreColumns, check.IsNil)
+	c.Assert(received[0].Columns[1].Type, check.Equals, mysql.TypeLonglong)
+	c.Assert(received[0].Columns[1].Flag, check.Equals, model.ColumnFlagType(0))
+	c.Assert(received[1].PreColumns, check.HasLen, 2)
+	c.Assert(received[1].Columns[1].Flag.IsHandleKey(), check.IsTrue)
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer/memory"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+)
+
+type mqProtobufSuite struct{}
+
+var _ = check.Suite(&mqProtobufSuite{})
+
+// writeTestDescriptorSet writes the descriptor set of message shop.Order { int64 id = 1; string note = 2; }
+func writeTestDescriptorSet(c *check.C) string {
+	newField := func(name string, number int32, tp descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto {
+		return &descriptor.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   tp.Enum(),
+			Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+	}
+	fds := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("order.proto"),
+			Package: proto.String("shop"),
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Order"),
+				Field: []*descriptor.FieldDescriptorProto{
+					newField("id", 1, descriptor.FieldDescriptorProto_TYPE_INT64),
+					newField("note", 2, descriptor.FieldDescriptorProto_TYPE_STRING),
+				},
+			}},
+		}},
+	}
+	data, err := proto.Marshal(fds)
+	c.Assert(err, check.IsNil)
+	path := filepath.Join(c.MkDir(), "order.desc")
+	c.Assert(ioutil.WriteFile(path, data, 0644), check.IsNil)
+	return path
+}
+
+func (s *mqProtobufSuite) TestMQSinkProtobuf(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer memory.RemoveQueue("protobuf-test")
+	const sinkURI = "memory://protobuf-test?protocol=protobuf"
+	cfg := config.GetDefaultReplicaConfig()
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+
+	// the protocol requires the descriptor file
+	_, err = NewSink(ctx, "protobuf-test", sinkURI, f, cfg, map[string]string{}, make(chan error, 1))
+	c.Assert(cerror.ErrProtobufInvalidDescriptor.Equal(err), check.IsTrue)
+
+	cfg.Sink.Protobuf = &config.ProtobufConfig{
+		DescriptorFile: writeTestDescriptorSet(c),
+		MessageRules:   []*config.ProtobufMessageRule{{Matcher: []string{"shop.*"}, Message: "shop.Order"}},
+	}
+	sink, err := NewSink(ctx, "protobuf-test", sinkURI, f, cfg, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+	defer sink.Close() //nolint:errcheck
+
+	table := &model.TableName{Schema: "shop", Table: "orders"}
+	row := &model.RowChangedEvent{CommitTs: 101, Table: table, Columns: []*model.Column{
+		{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(1)},
+		{Name: "note", Type: mysql.TypeVarchar, Value: []byte("paid")},
+	}}
+	c.Assert(sink.EmitRowChangedEvents(ctx, row), check.IsNil)
+	_, err = sink.FlushRowChangedEvents(ctx, 101)
+	c.Assert(err, check.IsNil)
+
+	// the row is sent as the protobuf message mapped to its table
+	queue, ok := memory.LookupQueue("protobuf-test")
+	c.Assert(ok, check.IsTrue)
+	msgs := queue.Poll(16)
+	c.Assert(msgs, check.HasLen, 1)
+	descriptorSet, err := ioutil.ReadFile(cfg.Sink.Protobuf.DescriptorFile)
+	c.Assert(err, check.IsNil)
+	schema, err := codec.NewProtobufSchema(descriptorSet, cfg)
+	c.Assert(err, check.IsNil)
+	cols, err := schema.DecodeRow(table, msgs[0].Value)
+	c.Assert(err, check.IsNil)
+	c.Assert(cols, check.DeepEquals, []*model.Column{{Name: "id", Value: int64(1)}, {Name: "note", Value: "paid"}})
+	keyCols, err := schema.DecodeRow(table, msgs[0].Key)
+	c.Assert(err, check.IsNil)
+	c.Assert(keyCols, check.DeepEquals, []*model.Column{{Name: "id", Value: int64(1)}})
+}
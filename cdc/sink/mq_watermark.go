@@ -0,0 +1,50 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
+)
+
+// The headers attached to the watermarks sent to the watermark topic, so that a consumer
+// is able to tell which partition of the data topic a watermark belongs to.
+const (
+	watermarkHeaderPartition    = "partition"
+	watermarkHeaderPartitionNum = "partition-num"
+)
+
+// watermarkPartition is the partition of the watermark topic all the watermarks are sent to,
+// which keeps the watermarks of the data partitions in order.
+const watermarkPartition = 0
+
+// emitWatermarks sends a watermark for each partition of the data topic to the watermark topic
+func (k *mqSink) emitWatermarks(ctx context.Context, msg *codec.MQMessage) error {
+	partitionNum := []byte(strconv.Itoa(int(k.partitionNum)))
+	for i := int32(0); i < k.partitionNum; i++ {
+		headers := []producer.MessageHeader{
+			{Key: watermarkHeaderPartition, Value: []byte(strconv.Itoa(int(i)))},
+			{Key: watermarkHeaderPartitionNum, Value: partitionNum},
+		}
+		err := k.watermarkProducer.SendMessage(ctx, msg.Key, msg.Value, headers, watermarkPartition)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(k.watermarkProducer.Flush(ctx))
+}
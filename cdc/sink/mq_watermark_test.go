@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/sink/codec"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/filter"
+)
+
+type mqWatermarkSuite struct{}
+
+var _ = check.Suite(&mqWatermarkSuite{})
+
+func (s *mqWatermarkSuite) TestEmitWatermarks(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.GetDefaultReplicaConfig()
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	dataProducer := &recordingProducer{partitionNum: 3}
+	sink, err := newMqSink(ctx, nil, dataProducer, f, cfg, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+
+	// the watermarks are broadcast to the data partitions by default
+	c.Assert(sink.EmitCheckpointTs(ctx, 100), check.IsNil)
+	c.Assert(dataProducer.messages, check.HasLen, 1)
+	c.Assert(dataProducer.messages[0].partition, check.Equals, int32(-1))
+
+	dataProducer.messages = nil
+	watermarkProducer := &recordingProducer{}
+	sink.watermarkProducer = watermarkProducer
+	c.Assert(sink.EmitCheckpointTs(ctx, 200), check.IsNil)
+	c.Assert(dataProducer.messages, check.HasLen, 0)
+	c.Assert(watermarkProducer.messages, check.HasLen, 3)
+	expected, err := codec.NewJSONEventBatchEncoder().EncodeCheckpointEvent(200)
+	c.Assert(err, check.IsNil)
+	for i, msg := range watermarkProducer.messages {
+		c.Assert(msg.partition, check.Equals, int32(watermarkPartition))
+		c.Assert(msg.value, check.DeepEquals, expected.Value)
+		c.Assert(msg.headers, check.DeepEquals, []producer.MessageHeader{
+			{Key: watermarkHeaderPartition, Value: []byte{byte('0' + i)}},
+			{Key: watermarkHeaderPartitionNum, Value: []byte("3")},
+		})
+	}
+}
+
+func (s *mqWatermarkSuite) TestWatermarkTopicConflict(c *check.C) {
+	sinkURI, err := url.Parse("kafka://127.0.0.1:9092/cdc-test?watermark-topic=cdc-test")
+	c.Assert(err, check.IsNil)
+	cfg := config.GetDefaultReplicaConfig()
+	_, err = newKafkaSaramaSink(context.Background(), sinkURI, nil, cfg, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.ErrorMatches, ".*watermark-topic must differ from the data topic cdc-test.*")
+}
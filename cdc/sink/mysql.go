@@ -62,7 +62,13 @@ const (
 	defaultBatchReplaceSize    = 20
 	defaultReadTimeout         = "2m"
 	defaultWriteTimeout        = "2m"
+	defaultDialTimeout         = "2m"
 	defaultSafeMode            = true
+	defaultHeartbeatInterval   = 10 * time.Second
+	defaultSlowLogRedact       = true
+	// the concurrent flushes are only bounded by the worker count by default
+	defaultMaxConcurrentFlushes = 0
+	defaultTxnAtomicity         = txnAtomicityNone
 )
 
 // SyncpointTableName is the name of table where all syncpoint maps sit
@@ -97,6 +103,26 @@ type mysqlSink struct {
 
 	statistics *Statistics
 
+	// lastHeartbeatTime is only accessed in the flushing goroutine
+	lastHeartbeatTime time.Time
+	// flushWindow is only accessed in the flushing goroutine
+	flushWindow flushWindow
+
+	// flushLimiter bounds the concurrent flushes of the workers, it's nil in some tests
+	flushLimiter *flushLimiter
+	// tableFlushLimiter bounds the tables flushed at the same time, it's nil unless in the table atomicity mode
+	tableFlushLimiter *flushLimiter
+	// quarantine is nil unless the tables failing to be written are quarantined
+	quarantine *tableQuarantine
+	// pauser is nil unless the writes are paused on the first error
+	pauser *errorPauser
+	// softDeleter is nil unless the deletes are translated to the soft deletes
+	softDeleter *softDeleter
+
+	// commitTsTables are the quoted downstream tables known to have the commit ts column
+	commitTsTablesMu sync.Mutex
+	commitTsTables   map[string]struct{}
+
 	// metrics used by mysql sink only
 	metricConflictDetectDurationHis prometheus.Observer
 	metricBucketSizeCounters        []prometheus.Counter
@@ -105,6 +131,7 @@ type mysqlSink struct {
 func (s *mysqlSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
 	count := s.txnCache.Append(s.filter, rows...)
 	s.statistics.AddRowsCount(count)
+	s.statistics.AddFilteredRowsCount(len(rows) - count)
 	return nil
 }
 
@@ -137,6 +164,9 @@ func (s *mysqlSink) flushRowChangedEvents(ctx context.Context) {
 			return
 		case <-receiver.C:
 		}
+		if err := s.flushWindow.wait(ctx); err != nil {
+			return
+		}
 		resolvedTs := atomic.LoadUint64(&s.resolvedTs)
 		resolvedTxnsMap := s.txnCache.Resolved(resolvedTs)
 		if len(resolvedTxnsMap) == 0 {
@@ -144,6 +174,7 @@ func (s *mysqlSink) flushRowChangedEvents(ctx context.Context) {
 				atomic.StoreUint64(&worker.checkpointTs, resolvedTs)
 			}
 			s.txnCache.UpdateCheckpoint(resolvedTs)
+			s.writeHeartbeat(ctx, resolvedTs, time.Now())
 			continue
 		}
 
@@ -158,6 +189,7 @@ func (s *mysqlSink) flushRowChangedEvents(ctx context.Context) {
 			atomic.StoreUint64(&worker.checkpointTs, resolvedTs)
 		}
 		s.txnCache.UpdateCheckpoint(resolvedTs)
+		s.writeHeartbeat(ctx, resolvedTs, time.Now())
 	}
 }
 
@@ -177,7 +209,16 @@ func (s *mysqlSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error
 		return cerror.ErrDDLEventIgnored.GenWithStackByArgs()
 	}
 	err := s.execDDLWithMaxRetries(ctx, ddl, defaultDDLMaxRetryTime)
-	return errors.Trace(err)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if s.softDeleter != nil && ddl.Type == timodel.ActionCreateTable {
+		s.softDeleter.forgetTable(ddl.TableInfo.Schema, ddl.TableInfo.Table)
+	}
+	if s.params.lastWriterWins && ddl.Type == timodel.ActionCreateTable {
+		return errors.Trace(s.ensureCreatedTableCommitTsColumn(ctx, ddl.TableInfo.Schema, ddl.TableInfo.Table))
+	}
+	return nil
 }
 
 // Initialize is no-op for Mysql sink
@@ -196,6 +237,10 @@ func (s *mysqlSink) execDDLWithMaxRetries(ctx context.Context, ddl *model.DDLEve
 			if errors.Cause(err) == context.Canceled {
 				return backoff.Permanent(err)
 			}
+			if s.params.pauseOnUnwritable && isDownstreamUnwritableError(err) {
+				log.Warn("the downstream refuses to execute DDL, stop retrying", zap.String("query", ddl.Query), zap.Error(err))
+				return backoff.Permanent(newDownstreamUnwritableError(err))
+			}
 			if err != nil {
 				log.Warn("execute DDL with error, retry later", zap.String("query", ddl.Query), zap.Error(err))
 			}
@@ -276,12 +321,34 @@ type sinkParams struct {
 	tidbTxnMode         string
 	changefeedID        string
 	captureAddr         string
+	captureID           string
 	batchReplaceEnabled bool
 	batchReplaceSize    int
 	readTimeout         string
 	writeTimeout        string
+	dialTimeout         string
 	enableOldValue      bool
 	safeMode            bool
+	enableHeartbeat     bool
+	heartbeatInterval   time.Duration
+	// slowLogThreshold is the execution time over which a statement is logged, 0 disables the slow log
+	slowLogThreshold time.Duration
+	slowLogRedact    bool
+	// maxConcurrentFlushes bounds the flushes running at the same time, 0 means unlimited
+	maxConcurrentFlushes int
+	// resolvedTsFlushWindow is the min interval between the flushes triggered by the resolved ts, 0 means no limit
+	resolvedTsFlushWindow time.Duration
+	// lastWriterWins skips the writes of the rows whose stored commit ts are larger
+	lastWriterWins bool
+	txnAtomicity   string
+	// tableFlushConcurrency bounds the tables flushed at the same time in the table atomicity mode,
+	// 0 means the tables flushed at the same time are only bounded by the worker count
+	tableFlushConcurrency int
+	// onlyUpdateChangedColumns writes the changed columns only in the SET clause of the updates,
+	// the updates changing no column are skipped
+	onlyUpdateChangedColumns bool
+	// pauseOnUnwritable stops retrying the writes the downstream refuses for a while, e.g. read-only or disk full
+	pauseOnUnwritable bool
 }
 
 func (s *sinkParams) Clone() *sinkParams {
@@ -290,14 +357,20 @@ func (s *sinkParams) Clone() *sinkParams {
 }
 
 var defaultParams = &sinkParams{
-	workerCount:         defaultWorkerCount,
-	maxTxnRow:           defaultMaxTxnRow,
-	tidbTxnMode:         defaultTiDBTxnMode,
-	batchReplaceEnabled: defaultBatchReplaceEnabled,
-	batchReplaceSize:    defaultBatchReplaceSize,
-	readTimeout:         defaultReadTimeout,
-	writeTimeout:        defaultWriteTimeout,
-	safeMode:            defaultSafeMode,
+	workerCount:          defaultWorkerCount,
+	maxTxnRow:            defaultMaxTxnRow,
+	tidbTxnMode:          defaultTiDBTxnMode,
+	batchReplaceEnabled:  defaultBatchReplaceEnabled,
+	batchReplaceSize:     defaultBatchReplaceSize,
+	readTimeout:          defaultReadTimeout,
+	writeTimeout:         defaultWriteTimeout,
+	dialTimeout:          defaultDialTimeout,
+	safeMode:             defaultSafeMode,
+	heartbeatInterval:    defaultHeartbeatInterval,
+	slowLogRedact:        defaultSlowLogRedact,
+	maxConcurrentFlushes: defaultMaxConcurrentFlushes,
+	txnAtomicity:         defaultTxnAtomicity,
+	pauseOnUnwritable:    true,
 }
 
 func checkTiDBVariable(ctx context.Context, db *sql.DB, variableName, defaultValue string) (string, error) {
@@ -333,6 +406,7 @@ func configureSinkURI(
 	dsnCfg.Params["time_zone"] = fmt.Sprintf(`"%s"`, tz.String())
 	dsnCfg.Params["readTimeout"] = params.readTimeout
 	dsnCfg.Params["writeTimeout"] = params.writeTimeout
+	dsnCfg.Params["timeout"] = params.dialTimeout
 
 	autoRandom, err := checkTiDBVariable(ctx, testDB, "allow_auto_random_explicit_insert", "1")
 	if err != nil {
@@ -375,6 +449,9 @@ func newMySQLSink(
 	if caddr, ok := opts[OptCaptureAddr]; ok {
 		params.captureAddr = caddr
 	}
+	if captureID, ok := opts[OptCaptureID]; ok {
+		params.captureID = captureID
+	}
 	tz := util.TimezoneFromCtx(ctx)
 
 	if sinkURI == nil {
@@ -394,6 +471,36 @@ func newMySQLSink(
 			params.workerCount = c
 		}
 	}
+	s = sinkURI.Query().Get("max-concurrent-flushes")
+	if s != "" {
+		c, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		if c < 0 {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("max-concurrent-flushes must not be negative, got %s", s)
+		}
+		params.maxConcurrentFlushes = c
+	}
+	s = sinkURI.Query().Get("transaction-atomicity")
+	if s != "" {
+		if s != txnAtomicityNone && s != txnAtomicityTable {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("transaction-atomicity must be %s or %s, got %s",
+				txnAtomicityNone, txnAtomicityTable, s)
+		}
+		params.txnAtomicity = s
+	}
+	s = sinkURI.Query().Get("table-flush-concurrency")
+	if s != "" {
+		c, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		if c < 0 {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("table-flush-concurrency must not be negative, got %s", s)
+		}
+		params.tableFlushConcurrency = c
+	}
 	s = sinkURI.Query().Get("max-txn-row")
 	if s != "" {
 		c, err := strconv.Atoi(s)
@@ -455,8 +562,109 @@ func newMySQLSink(
 		}
 		params.safeMode = safeModeEnabled
 	}
+	s = sinkURI.Query().Get("only-update-changed-columns")
+	if s != "" {
+		enable, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		params.onlyUpdateChangedColumns = enable
+	}
+
+	s = sinkURI.Query().Get("enable-heartbeat")
+	if s != "" {
+		enableHeartbeat, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		params.enableHeartbeat = enableHeartbeat
+	}
+	s = sinkURI.Query().Get("heartbeat-interval")
+	if s != "" {
+		interval, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		if interval <= 0 {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("heartbeat-interval must be positive, got %s", s)
+		}
+		params.heartbeatInterval = interval
+	}
+	s = sinkURI.Query().Get("slow-log-threshold")
+	if s != "" {
+		threshold, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		if threshold < 0 {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("slow-log-threshold must not be negative, got %s", s)
+		}
+		params.slowLogThreshold = threshold
+	}
+	s = sinkURI.Query().Get("resolved-ts-flush-window")
+	if s != "" {
+		window, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		if window < 0 {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("resolved-ts-flush-window must not be negative, got %s", s)
+		}
+		params.resolvedTsFlushWindow = window
+	}
+	s = sinkURI.Query().Get("slow-log-redact")
+	if s != "" {
+		redact, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		params.slowLogRedact = redact
+	}
+	for _, timeout := range []struct {
+		name  string
+		value *string
+	}{
+		{"read-timeout", &params.readTimeout},
+		{"write-timeout", &params.writeTimeout},
+		{"dial-timeout", &params.dialTimeout},
+	} {
+		s = sinkURI.Query().Get(timeout.name)
+		if s == "" {
+			continue
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		if d <= 0 {
+			return nil, cerror.ErrMySQLInvalidConfig.GenWithStack("%s must be positive, got %s", timeout.name, s)
+		}
+		*timeout.value = s
+	}
 
 	params.enableOldValue = replicaConfig.EnableOldValue
+	if err := replicaConfig.Sink.ValidateTableErrorPolicy(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := replicaConfig.Debug.Validate(replicaConfig.Sink); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := replicaConfig.Sink.ValidateConflictResolution(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	params.lastWriterWins = replicaConfig.Sink.ConflictResolution == config.ConflictResolutionLastWriterWins
+	if err := replicaConfig.Sink.ValidateDownstreamUnwritable(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	params.pauseOnUnwritable = replicaConfig.Sink.DownstreamUnwritable != config.DownstreamUnwritableRetry
+	softDelete := replicaConfig.Sink.SoftDelete
+	if err := softDelete.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if softDelete != nil && softDelete.Enable && params.lastWriterWins {
+		return nil, cerror.ErrSoftDeleteInvalidConfig.GenWithStack("soft delete can't be enabled with the conflict resolution %s",
+			config.ConflictResolutionLastWriterWins)
+	}
 
 	// dsn format of the driver:
 	// [username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]
@@ -481,6 +689,10 @@ func newMySQLSink(
 		dsn.Params = make(map[string]string, 1)
 	}
 	dsn.Params["time_zone"] = fmt.Sprintf(`"%s"`, tz.String())
+	// a downstream which accepts the connection but never responds must not stall the parameter detection
+	dsn.Params["readTimeout"] = params.readTimeout
+	dsn.Params["writeTimeout"] = params.writeTimeout
+	dsn.Params["timeout"] = params.dialTimeout
 	testDB, err := sql.Open("mysql", dsn.FormatDSN())
 	if err != nil {
 		return nil, errors.Annotate(
@@ -516,6 +728,9 @@ func newMySQLSink(
 			params.captureAddr, params.changefeedID, strconv.Itoa(i))
 	}
 
+	flushLimiter := newFlushLimiter(params.maxConcurrentFlushes,
+		concurrentFlushesGauge.WithLabelValues(params.captureAddr, params.changefeedID))
+
 	sink := &mysqlSink{
 		db:                              db,
 		params:                          params,
@@ -524,8 +739,24 @@ func newMySQLSink(
 		statistics:                      NewStatistics(ctx, "mysql", opts),
 		metricConflictDetectDurationHis: metricConflictDetectDurationHis,
 		metricBucketSizeCounters:        metricBucketSizeCounters,
+		flushLimiter:                    flushLimiter,
+		flushWindow:                     flushWindow{window: params.resolvedTsFlushWindow},
 		errCh:                           make(chan error, 1),
 	}
+	if replicaConfig.Sink.TableErrorPolicy == config.TableErrorPolicyQuarantine {
+		sink.quarantine = newTableQuarantine(params.captureID,
+			quarantinedRowsCounter.WithLabelValues(params.captureAddr, params.changefeedID))
+	}
+	if replicaConfig.Debug != nil && replicaConfig.Debug.PauseOnFirstError {
+		sink.pauser = newErrorPauser(params.changefeedID)
+	}
+	if softDelete != nil && softDelete.Enable {
+		sink.softDeleter = newSoftDeleter(softDelete, tz)
+	}
+	if params.txnAtomicity == txnAtomicityTable {
+		sink.tableFlushLimiter = newFlushLimiter(params.tableFlushConcurrency,
+			concurrentTableFlushesGauge.WithLabelValues(params.captureAddr, params.changefeedID))
+	}
 
 	if val, ok := opts[mark.OptCyclicConfig]; ok {
 		cfg := new(config.CyclicConfig)
@@ -541,6 +772,13 @@ func newMySQLSink(
 		}
 	}
 
+	if params.enableHeartbeat {
+		err = sink.initHeartbeat(ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
 	sink.execWaitNotifier = new(notify.Notifier)
 	sink.resolvedNotifier = new(notify.Notifier)
 	sink.createSinkWorkers(ctx)
@@ -552,10 +790,21 @@ func newMySQLSink(
 
 func (s *mysqlSink) createSinkWorkers(ctx context.Context) {
 	s.workers = make([]*mysqlSinkWorker, s.params.workerCount)
+	execDMLs := s.execDMLs
+	if s.quarantine != nil {
+		execDMLs = s.quarantine.wrap(execDMLs)
+	}
+	if s.flushLimiter != nil {
+		execDMLs = s.flushLimiter.wrap(execDMLs)
+	}
+	if s.tableFlushLimiter != nil {
+		execDMLs = s.tableFlushLimiter.wrap(execDMLs)
+	}
 	for i := range s.workers {
 		receiver := s.execWaitNotifier.NewReceiver(defaultFlushInterval)
 		worker := newMySQLSinkWorker(
-			s.params.maxTxnRow, i, s.metricBucketSizeCounters[i], receiver, s.execDMLs)
+			s.params.maxTxnRow, i, s.metricBucketSizeCounters[i], receiver, execDMLs)
+		worker.splitByTable = s.params.txnAtomicity == txnAtomicityTable
 		s.workers[i] = worker
 		go func() {
 			err := worker.run(ctx)
@@ -589,6 +838,10 @@ func (s *mysqlSink) notifyAndWaitExec(ctx context.Context) {
 }
 
 func (s *mysqlSink) dispatchAndExecTxns(ctx context.Context, txnsGroup map[model.TableID][]*model.SingleTableTxn) {
+	if s.params.txnAtomicity == txnAtomicityTable {
+		s.dispatchTxnsByTable(ctx, txnsGroup)
+		return
+	}
 	nWorkers := s.params.workerCount
 	causality := newCausality()
 	rowsChIdx := 0
@@ -629,6 +882,8 @@ type mysqlSinkWorker struct {
 	metricBucketSize prometheus.Counter
 	receiver         *notify.Receiver
 	checkpointTs     uint64
+	// splitByTable flushes the rows of different tables separately
+	splitByTable bool
 }
 
 func newMySQLSinkWorker(
@@ -711,7 +966,8 @@ func (w *mysqlSinkWorker) run(ctx context.Context) (err error) {
 			if txn == nil {
 				return errors.Trace(flushRows())
 			}
-			if txn.ReplicaID != replicaID || len(toExecRows)+len(txn.Rows) > w.maxTxnRow {
+			if txn.ReplicaID != replicaID || len(toExecRows)+len(txn.Rows) > w.maxTxnRow ||
+				(w.splitByTable && len(toExecRows) != 0 && toExecRows[0].Table.TableID != txn.Table.TableID) {
 				if err := flushRows(); err != nil {
 					return errors.Trace(err)
 				}
@@ -728,7 +984,32 @@ func (w *mysqlSinkWorker) run(ctx context.Context) (err error) {
 	}
 }
 
+// QuarantinedTables implements the TableQuarantiner interface
+func (s *mysqlSink) QuarantinedTables() []*model.QuarantinedTable {
+	if s.quarantine == nil {
+		return nil
+	}
+	return s.quarantine.QuarantinedTables()
+}
+
+// QuarantineTable implements the TableQuarantiner interface
+func (s *mysqlSink) QuarantineTable(table *model.QuarantinedTable) {
+	if s.quarantine != nil {
+		s.quarantine.QuarantineTable(table)
+	}
+}
+
+// ReleaseTable implements the TableQuarantiner interface
+func (s *mysqlSink) ReleaseTable(tableID model.TableID) {
+	if s.quarantine != nil {
+		s.quarantine.ReleaseTable(tableID)
+	}
+}
+
 func (s *mysqlSink) Close() error {
+	if s.pauser != nil {
+		s.pauser.close()
+	}
 	s.execWaitNotifier.Close()
 	s.resolvedNotifier.Close()
 	err := s.db.Close()
@@ -736,7 +1017,7 @@ func (s *mysqlSink) Close() error {
 }
 
 func (s *mysqlSink) execDMLWithMaxRetries(
-	ctx context.Context, dmls *preparedDMLs, maxRetries uint64, bucket int,
+	ctx context.Context, rows []*model.RowChangedEvent, dmls *preparedDMLs, maxRetries uint64, bucket int,
 ) error {
 	if len(dmls.sqls) != len(dmls.values) {
 		log.Fatal("unexpected number of sqls and values",
@@ -747,50 +1028,69 @@ func (s *mysqlSink) execDMLWithMaxRetries(
 		if errors.Cause(err) == context.Canceled {
 			return backoff.Permanent(err)
 		}
+		if s.params.pauseOnUnwritable && isDownstreamUnwritableError(err) {
+			log.Warn("the downstream refuses to execute DMLs, stop retrying", zap.Error(err))
+			return backoff.Permanent(newDownstreamUnwritableError(err))
+		}
 		log.Warn("execute DMLs with error, retry later", zap.Error(err))
 		return err
 	}
-	return retry.Run(500*time.Millisecond, maxRetries,
-		func() error {
-			failpoint.Inject("MySQLSinkTxnRandomError", func() {
-				failpoint.Return(checkTxnErr(errors.Trace(dmysql.ErrInvalidConn)))
-			})
-			failpoint.Inject("MySQLSinkHangLongTime", func() {
-				time.Sleep(time.Hour)
-			})
-			err := s.statistics.RecordBatchExecution(func() (int, error) {
-				tx, err := s.db.BeginTx(ctx, nil)
+	execTxn := func() error {
+		failpoint.Inject("MySQLSinkTxnRandomError", func() {
+			failpoint.Return(checkTxnErr(errors.Trace(dmysql.ErrInvalidConn)))
+		})
+		failpoint.Inject("MySQLSinkHangLongTime", func() {
+			time.Sleep(time.Hour)
+		})
+		err := s.statistics.RecordBatchExecution(func() (int, error) {
+			tx, err := s.db.BeginTx(ctx, nil)
+			if err != nil {
+				return 0, checkTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
+			}
+			for i, query := range dmls.sqls {
+				args := dmls.values[i]
+				log.Debug("exec row", zap.String("sql", query), zap.Any("args", args))
+				start := time.Now()
+				_, err := tx.ExecContext(ctx, query, args...)
+				s.logSlowStatement(query, args, time.Since(start), err)
 				if err != nil {
 					return 0, checkTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
 				}
-				for i, query := range dmls.sqls {
-					args := dmls.values[i]
-					log.Debug("exec row", zap.String("sql", query), zap.Any("args", args))
-					if _, err := tx.ExecContext(ctx, query, args...); err != nil {
-						return 0, checkTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
-					}
-				}
-				if len(dmls.markSQL) != 0 {
-					log.Debug("exec row", zap.String("sql", dmls.markSQL))
-					if _, err := tx.ExecContext(ctx, dmls.markSQL); err != nil {
-						return 0, checkTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
-					}
-				}
-				if err = tx.Commit(); err != nil {
+			}
+			if len(dmls.markSQL) != 0 {
+				log.Debug("exec row", zap.String("sql", dmls.markSQL))
+				if _, err := tx.ExecContext(ctx, dmls.markSQL); err != nil {
 					return 0, checkTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
 				}
-				return dmls.rowCount, nil
-			})
+			}
+			start := time.Now()
+			err = tx.Commit()
+			s.logSlowStatement("COMMIT", nil, time.Since(start), err)
 			if err != nil {
-				return errors.Trace(err)
+				return 0, checkTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
 			}
-			log.Debug("Exec Rows succeeded",
-				zap.String("changefeed", s.params.changefeedID),
-				zap.Int("num of Rows", dmls.rowCount),
-				zap.Int("bucket", bucket))
-			return nil
-		},
-	)
+			return dmls.rowCount, nil
+		})
+		if err != nil {
+			if _, ok := err.(*backoff.PermanentError); ok {
+				return err
+			}
+			return errors.Trace(err)
+		}
+		log.Debug("Exec Rows succeeded",
+			zap.String("changefeed", s.params.changefeedID),
+			zap.Int("num of Rows", dmls.rowCount),
+			zap.Int("bucket", bucket))
+		return nil
+	}
+	return retry.Run(500*time.Millisecond, maxRetries, func() error {
+		if s.pauser == nil {
+			return execTxn()
+		}
+		// pause on every failure of the transaction, which is retried once the writes are
+		// resumed, so the retries are never used up while paused
+		return s.pauser.retry(ctx, pausedOperationExecDMLs, 0, rows, execTxn)
+	})
 }
 
 type preparedDMLs struct {
@@ -822,11 +1122,26 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 		var query string
 		var args []interface{}
 		quoteTable := quotes.QuoteSchema(row.Table.Schema, row.Table.Table)
+		cols := row.Columns
+		if s.softDeleter != nil && len(cols) != 0 {
+			cols = s.softDeleter.withMarker(cols)
+		}
+
+		if s.params.lastWriterWins {
+			querys, args := prepareLastWriterWins(quoteTable, row)
+			sqls = append(sqls, querys...)
+			values = append(values, args...)
+			rowCount += len(querys)
+			continue
+		}
 
 		// Translate to UPDATE if old value is enabled, not in safe mode and is update event
 		if translateToInsert && len(row.PreColumns) != 0 && len(row.Columns) != 0 {
 			flushCacheDMLs()
-			query, args = prepareUpdate(quoteTable, row.PreColumns, row.Columns)
+			if s.params.onlyUpdateChangedColumns {
+				cols = changedColumns(row.PreColumns, cols)
+			}
+			query, args = prepareUpdate(quoteTable, row.PreColumns, cols)
 			if query != "" {
 				sqls = append(sqls, query)
 				values = append(values, args)
@@ -840,7 +1155,11 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 		// update will be translated to DELETE + INSERT(or REPLACE) SQL.
 		if len(row.PreColumns) != 0 {
 			flushCacheDMLs()
-			query, args = prepareDelete(quoteTable, row.PreColumns)
+			if s.softDeleter != nil && len(row.Columns) == 0 {
+				query, args = s.softDeleter.prepareDelete(quoteTable, row.PreColumns, row.CommitTs)
+			} else {
+				query, args = prepareDelete(quoteTable, row.PreColumns)
+			}
 			if query != "" {
 				sqls = append(sqls, query)
 				values = append(values, args)
@@ -850,8 +1169,10 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 
 		// Case for insert event or update event
 		if len(row.Columns) != 0 {
+			// the insert of a row soft deleted before replaces the deleted row
+			translateToInsert := translateToInsert && s.softDeleter == nil
 			if s.params.batchReplaceEnabled {
-				query, args = prepareReplace(quoteTable, row.Columns, false /* appendPlaceHolder */, translateToInsert)
+				query, args = prepareReplace(quoteTable, cols, false /* appendPlaceHolder */, translateToInsert)
 				if query != "" {
 					if _, ok := replaces[query]; !ok {
 						replaces[query] = make([][]interface{}, 0)
@@ -860,7 +1181,7 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 					rowCount++
 				}
 			} else {
-				query, args = prepareReplace(quoteTable, row.Columns, true /* appendPlaceHolder */, translateToInsert)
+				query, args = prepareReplace(quoteTable, cols, true /* appendPlaceHolder */, translateToInsert)
 				sqls = append(sqls, query)
 				values = append(values, args)
 				if query != "" {
@@ -901,9 +1222,19 @@ func (s *mysqlSink) execDMLs(ctx context.Context, rows []*model.RowChangedEvent,
 		time.Sleep(time.Second * 2)
 		failpoint.Return(errors.Trace(dmysql.ErrInvalidConn))
 	})
+	if s.params.lastWriterWins {
+		if err := s.ensureCommitTsColumns(ctx, rows); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if s.softDeleter != nil {
+		if err := s.softDeleter.checkColumns(ctx, s, rows); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	dmls := s.prepareDMLs(rows, replicaID, bucket)
 	log.Debug("prepare DMLs", zap.Any("rows", rows), zap.Strings("sqls", dmls.sqls), zap.Any("values", dmls.values))
-	if err := s.execDMLWithMaxRetries(ctx, dmls, defaultDMLMaxRetryTime, bucket); err != nil {
+	if err := s.execDMLWithMaxRetries(ctx, rows, dmls, defaultDMLMaxRetryTime, bucket); err != nil {
 		ts := make([]uint64, 0, len(rows))
 		for _, row := range rows {
 			if len(ts) == 0 || ts[len(ts)-1] != row.CommitTs {
@@ -1114,6 +1445,21 @@ func buildColumnList(names []string) string {
 
 // newSyncpointStore create a sink to record the syncpoint map in downstream DB for every changefeed
 func newMySQLSyncpointStore(ctx context.Context, id string, sinkURI *url.URL) (SyncpointStore, error) {
+	syncDB, err := openSyncpointDB(ctx, id, sinkURI)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	log.Info("Start mysql syncpoint sink")
+	syncpointStore := &mysqlSyncpointStore{
+		db: syncDB,
+	}
+
+	return syncpointStore, nil
+}
+
+// openSyncpointDB opens the downstream DB where the syncpoints are recorded
+func openSyncpointDB(ctx context.Context, id string, sinkURI *url.URL) (*sql.DB, error) {
 	var syncDB *sql.DB
 
 	//todo If is neither mysql nor tidb, such as kafka, just ignore this feature.
@@ -1191,13 +1537,7 @@ func newMySQLSyncpointStore(ctx context.Context, id string, sinkURI *url.URL) (S
 	if err != nil {
 		return nil, errors.Annotate(err, "fail to open MySQL connection")
 	}
-
-	log.Info("Start mysql syncpoint sink")
-	syncpointStore := &mysqlSyncpointStore{
-		db: syncDB,
-	}
-
-	return syncpointStore, nil
+	return syncDB, nil
 }
 
 func (s *mysqlSyncpointStore) CreateSynctable(ctx context.Context) error {
@@ -0,0 +1,59 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+// changedColumns returns the columns of an update whose values differ from the old values, they're
+// the only columns in the SET clause if only-update-changed-columns is enabled. A column without
+// the old value is always changed.
+func changedColumns(preCols, cols []*model.Column) []*model.Column {
+	preValues := make(map[string]*model.Column, len(preCols))
+	for _, col := range preCols {
+		if col != nil {
+			preValues[col.Name] = col
+		}
+	}
+	changed := make([]*model.Column, 0, len(cols))
+	for _, col := range cols {
+		if col == nil {
+			continue
+		}
+		preCol, ok := preValues[col.Name]
+		if ok && columnValueEqual(preCol.Value, col.Value) {
+			continue
+		}
+		changed = append(changed, col)
+	}
+	return changed
+}
+
+// columnValueEqual compares the column values with NULL treated as a value, a NULL equals
+// NULL only, so that the updates from or to NULL are never taken as unchanged
+func columnValueEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if ab, ok := a.([]byte); ok {
+		if bb, ok := b.([]byte); ok {
+			return bytes.Equal(ab, bb)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
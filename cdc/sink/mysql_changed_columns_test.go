@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+func newChangedColumnsTestRow(pre, post [2]interface{}) *model.RowChangedEvent {
+	newCols := func(values [2]interface{}) []*model.Column {
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+			{Name: "a", Type: mysql.TypeLong, Flag: model.NullableFlag, Value: values[0]},
+			{Name: "b", Type: mysql.TypeVarchar, Flag: model.NullableFlag, Value: values[1]},
+		}
+	}
+	return &model.RowChangedEvent{
+		StartTs:    100,
+		CommitTs:   101,
+		Table:      &model.TableName{Schema: "test", Table: "t"},
+		PreColumns: newCols(pre),
+		Columns:    newCols(post),
+	}
+}
+
+func (s MySQLSinkSuite) TestOnlyUpdateChangedColumns(c *check.C) {
+	ms := newMySQLSink4Test(c)
+	ms.params.enableOldValue = true
+	ms.params.safeMode = false
+	ms.params.onlyUpdateChangedColumns = true
+	testCases := []struct {
+		pre, post [2]interface{}
+		sqls      []string
+		values    [][]interface{}
+	}{{
+		// value to NULL
+		pre:    [2]interface{}{int64(1), []byte("x")},
+		post:   [2]interface{}{nil, []byte("x")},
+		sqls:   []string{"UPDATE `test`.`t` SET `a`=? WHERE `id`=? LIMIT 1;"},
+		values: [][]interface{}{{nil, 1}},
+	}, {
+		// NULL to value
+		pre:    [2]interface{}{int64(1), nil},
+		post:   [2]interface{}{int64(1), []byte("")},
+		sqls:   []string{"UPDATE `test`.`t` SET `b`=? WHERE `id`=? LIMIT 1;"},
+		values: [][]interface{}{{[]byte(""), 1}},
+	}, {
+		// NULL to NULL is unchanged
+		pre:    [2]interface{}{nil, nil},
+		post:   [2]interface{}{int64(0), nil},
+		sqls:   []string{"UPDATE `test`.`t` SET `a`=? WHERE `id`=? LIMIT 1;"},
+		values: [][]interface{}{{int64(0), 1}},
+	}, {
+		// no column is changed
+		pre:    [2]interface{}{nil, []byte("x")},
+		post:   [2]interface{}{nil, []byte("x")},
+		sqls:   []string{},
+		values: [][]interface{}{},
+	}}
+	for i, tc := range testCases {
+		dmls := ms.prepareDMLs([]*model.RowChangedEvent{newChangedColumnsTestRow(tc.pre, tc.post)}, 0, 0)
+		c.Assert(dmls.sqls, check.DeepEquals, tc.sqls, check.Commentf("%d", i))
+		c.Assert(dmls.values, check.DeepEquals, tc.values, check.Commentf("%d", i))
+	}
+
+	// all the columns are written by default
+	ms.params.onlyUpdateChangedColumns = false
+	dmls := ms.prepareDMLs([]*model.RowChangedEvent{newChangedColumnsTestRow([2]interface{}{nil, nil}, [2]interface{}{nil, nil})}, 0, 0)
+	c.Assert(dmls.sqls, check.DeepEquals, []string{"UPDATE `test`.`t` SET `id`=?,`a`=?,`b`=? WHERE `id`=? LIMIT 1;"})
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/quotes"
+	"go.uber.org/zap"
+)
+
+// commitTsColumn is the column of the downstream tables storing the commit ts of the last write
+// of every row, it's added and maintained by the sink if the last writer wins
+const commitTsColumn = "_cdc_commit_ts"
+
+// lastWriterWinsCond is true if the stored row is not newer than the row being written
+var lastWriterWinsCond = fmt.Sprintf("%[1]s IS NULL OR %[1]s <= VALUES(%[1]s)", quotes.QuoteName(commitTsColumn))
+
+// ensureCommitTsColumns adds the commit ts column to the downstream tables of the rows which lack it
+func (s *mysqlSink) ensureCommitTsColumns(ctx context.Context, rows []*model.RowChangedEvent) error {
+	for _, row := range rows {
+		if err := s.ensureCommitTsColumn(ctx, row.Table.Schema, row.Table.Table); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (s *mysqlSink) ensureCommitTsColumn(ctx context.Context, schema, table string) error {
+	quoteTable := quotes.QuoteSchema(schema, table)
+	s.commitTsTablesMu.Lock()
+	_, ok := s.commitTsTables[quoteTable]
+	s.commitTsTablesMu.Unlock()
+	if ok {
+		return nil
+	}
+
+	columns, err := s.downstreamColumns(ctx, schema, table)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(columns) == 0 {
+		// the writes fail since the table doesn't exist
+		log.Warn("the table doesn't exist in the downstream, skip adding the commit ts column",
+			zap.String("table", quoteTable))
+		return nil
+	}
+	if _, ok := columns[commitTsColumn]; !ok {
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s BIGINT UNSIGNED NULL DEFAULT NULL",
+			quoteTable, quotes.QuoteName(commitTsColumn))
+		log.Info("add the commit ts column to the downstream table", zap.String("query", query))
+		ddl := &model.DDLEvent{
+			TableInfo: &model.SimpleTableInfo{Schema: schema, Table: table},
+			Query:     query,
+			Type:      timodel.ActionAddColumn,
+		}
+		// the error of the column added by another sink at the same time is ignored
+		if err := s.execDDLWithMaxRetries(ctx, ddl, defaultDDLMaxRetryTime); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	s.commitTsTablesMu.Lock()
+	if s.commitTsTables == nil {
+		s.commitTsTables = make(map[string]struct{})
+	}
+	s.commitTsTables[quoteTable] = struct{}{}
+	s.commitTsTablesMu.Unlock()
+	return nil
+}
+
+// ensureCreatedTableCommitTsColumn adds the commit ts column to a table just created, the rows of the
+// table are written after the column is added since they're written after the DDL is executed
+func (s *mysqlSink) ensureCreatedTableCommitTsColumn(ctx context.Context, schema, table string) error {
+	s.commitTsTablesMu.Lock()
+	// the table may be dropped and created again
+	delete(s.commitTsTables, quotes.QuoteSchema(schema, table))
+	s.commitTsTablesMu.Unlock()
+	return errors.Trace(s.ensureCommitTsColumn(ctx, schema, table))
+}
+
+// prepareLastWriterWins returns the DMLs writing the row unless the stored row has a larger commit ts.
+// An update changing the handle deletes the old row and writes the new row. A delete removes the
+// commit ts along with the row, so an older write arriving after the delete writes the row again.
+func prepareLastWriterWins(quoteTable string, row *model.RowChangedEvent) ([]string, [][]interface{}) {
+	var sqls []string
+	var values [][]interface{}
+	if len(row.PreColumns) != 0 {
+		_, preHandle := whereSlice(row.PreColumns)
+		_, handle := whereSlice(row.Columns)
+		if len(row.Columns) == 0 || !reflect.DeepEqual(preHandle, handle) {
+			query, args := prepareLastWriterWinsDelete(quoteTable, row.PreColumns, row.CommitTs)
+			if query != "" {
+				sqls = append(sqls, query)
+				values = append(values, args)
+			}
+		}
+	}
+	if len(row.Columns) != 0 {
+		query, args := prepareLastWriterWinsUpsert(quoteTable, row.Columns, row.CommitTs)
+		if query != "" {
+			sqls = append(sqls, query)
+			values = append(values, args)
+		}
+	}
+	return sqls, values
+}
+
+// prepareLastWriterWinsUpsert returns an upsert keeping the stored row if its commit ts is larger,
+// the commit ts column is assigned last since the assignments are evaluated from left to right
+func prepareLastWriterWinsUpsert(quoteTable string, cols []*model.Column, commitTs uint64) (string, []interface{}) {
+	columnNames := make([]string, 0, len(cols)+1)
+	args := make([]interface{}, 0, len(cols)+1)
+	for _, col := range cols {
+		if col == nil || col.Flag.IsGeneratedColumn() {
+			continue
+		}
+		columnNames = append(columnNames, col.Name)
+		args = append(args, col.Value)
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+	columnNames = append(columnNames, commitTsColumn)
+	args = append(args, commitTs)
+
+	var builder strings.Builder
+	builder.WriteString("INSERT INTO " + quoteTable + "(" + buildColumnList(columnNames) + ") VALUES (" +
+		model.HolderString(len(columnNames)) + ") ON DUPLICATE KEY UPDATE ")
+	for i, name := range columnNames {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		column := quotes.QuoteName(name)
+		builder.WriteString(fmt.Sprintf("%[1]s=IF(%[2]s, VALUES(%[1]s), %[1]s)", column, lastWriterWinsCond))
+	}
+	builder.WriteString(";")
+	return builder.String(), args
+}
+
+// prepareLastWriterWinsDelete returns a delete skipping the stored row if its commit ts is larger
+func prepareLastWriterWinsDelete(quoteTable string, cols []*model.Column, commitTs uint64) (string, []interface{}) {
+	var builder strings.Builder
+	builder.WriteString("DELETE FROM " + quoteTable + " WHERE ")
+
+	colNames, wargs := whereSlice(cols)
+	if len(wargs) == 0 {
+		return "", nil
+	}
+	args := make([]interface{}, 0, len(wargs)+1)
+	for i := 0; i < len(colNames); i++ {
+		if wargs[i] == nil {
+			builder.WriteString(quotes.QuoteName(colNames[i]) + " IS NULL AND ")
+		} else {
+			builder.WriteString(quotes.QuoteName(colNames[i]) + " = ? AND ")
+			args = append(args, wargs[i])
+		}
+	}
+	column := quotes.QuoteName(commitTsColumn)
+	builder.WriteString("(" + column + " IS NULL OR " + column + " <= ?) LIMIT 1;")
+	args = append(args, commitTs)
+	return builder.String(), args
+}
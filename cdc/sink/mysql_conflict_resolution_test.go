@@ -0,0 +1,123 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/tidb/session"
+	"github.com/pingcap/tidb/store/mockstore"
+	"github.com/pingcap/tidb/util/testkit"
+)
+
+func newConflictTestRow(commitTs uint64, preValue, value interface{}) *model.RowChangedEvent {
+	newColumns := func(v interface{}) []*model.Column {
+		if v == nil {
+			return nil
+		}
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+			{Name: "v", Type: mysql.TypeLong, Value: v},
+		}
+	}
+	return &model.RowChangedEvent{
+		StartTs:    commitTs - 1,
+		CommitTs:   commitTs,
+		Table:      &model.TableName{Schema: "test", Table: "t", TableID: 42},
+		PreColumns: newColumns(preValue),
+		Columns:    newColumns(value),
+	}
+}
+
+func (s MySQLSinkSuite) TestLastWriterWinsAddsCommitTsColumn(c *check.C) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	ms := newMySQLSink4Test(c)
+	ms.db = db
+	ms.params.lastWriterWins = true
+
+	// the commit ts column is added before the first write of the table
+	mock.ExpectQuery("SELECT COLUMN_NAME FROM information_schema.columns WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?").
+		WithArgs("test", "t").WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("v"))
+	mock.ExpectBegin()
+	mock.ExpectExec("USE `test`;").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE `test`.`t` ADD COLUMN `_cdc_commit_ts` BIGINT UNSIGNED NULL DEFAULT NULL").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	cond := "`_cdc_commit_ts` IS NULL OR `_cdc_commit_ts` <= VALUES(`_cdc_commit_ts`)"
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `test`.`t`(`id`,`v`,`_cdc_commit_ts`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE "+
+		"`id`=IF("+cond+", VALUES(`id`), `id`),"+
+		"`v`=IF("+cond+", VALUES(`v`), `v`),"+
+		"`_cdc_commit_ts`=IF("+cond+", VALUES(`_cdc_commit_ts`), `_cdc_commit_ts`);").
+		WithArgs(1, 2, uint64(11)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	c.Assert(ms.execDMLs(ctx, []*model.RowChangedEvent{newConflictTestRow(11, nil, 2)}, 0, 0), check.IsNil)
+
+	// the table is known to have the column
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `test`.`t` WHERE `id` = ? AND (`_cdc_commit_ts` IS NULL OR `_cdc_commit_ts` <= ?) LIMIT 1;").
+		WithArgs(1, uint64(12)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	c.Assert(ms.execDMLs(ctx, []*model.RowChangedEvent{newConflictTestRow(12, 2, nil)}, 0, 0), check.IsNil)
+
+	mock.ExpectClose()
+	c.Assert(db.Close(), check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s MySQLSinkSuite) TestLastWriterWinsSkipsOlderWrites(c *check.C) {
+	store, err := mockstore.NewMockTikvStore()
+	c.Assert(err, check.IsNil)
+	defer store.Close() //nolint:errcheck
+	session.SetSchemaLease(0)
+	session.DisableStats4Test()
+	domain, err := session.BootstrapSession(store)
+	c.Assert(err, check.IsNil)
+	defer domain.Close()
+	domain.SetStatsUpdating(true)
+
+	tk := testkit.NewTestKit(c, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (id int primary key, v int, _cdc_commit_ts bigint unsigned)")
+	ms := newMySQLSink4Test(c)
+	ms.params.lastWriterWins = true
+	write := func(row *model.RowChangedEvent) {
+		dmls := ms.prepareDMLs([]*model.RowChangedEvent{row}, 0, 0)
+		for i, query := range dmls.sqls {
+			tk.MustExec(query, dmls.values[i]...)
+		}
+	}
+
+	// the writes of another changefeed with the older commit ts are skipped
+	write(newConflictTestRow(20, nil, 10))
+	write(newConflictTestRow(15, nil, 5))
+	tk.MustQuery("select id, v, _cdc_commit_ts from t").Check(testkit.Rows("1 10 20"))
+	write(newConflictTestRow(18, 5, 6))
+	tk.MustQuery("select id, v, _cdc_commit_ts from t").Check(testkit.Rows("1 10 20"))
+	write(newConflictTestRow(25, 10, 30))
+	tk.MustQuery("select id, v, _cdc_commit_ts from t").Check(testkit.Rows("1 30 25"))
+	write(newConflictTestRow(22, 6, nil))
+	tk.MustQuery("select id, v, _cdc_commit_ts from t").Check(testkit.Rows("1 30 25"))
+	// the write of the same commit ts is applied again, e.g. replayed after the changefeed restarts
+	write(newConflictTestRow(25, 10, 30))
+	tk.MustQuery("select id, v, _cdc_commit_ts from t").Check(testkit.Rows("1 30 25"))
+	write(newConflictTestRow(30, 30, nil))
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("0"))
+}
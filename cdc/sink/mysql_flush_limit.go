@@ -0,0 +1,57 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/semaphore"
+)
+
+// flushLimiter bounds the number of the flushes the sink workers run at the same time,
+// independently of the worker count which is also the size of the connection pool.
+type flushLimiter struct {
+	// sem is nil if the concurrent flushes are unlimited
+	sem *semaphore.Weighted
+	// metricConcurrentFlushes is the number of the running flushes
+	metricConcurrentFlushes prometheus.Gauge
+}
+
+func newFlushLimiter(maxConcurrentFlushes int, metricConcurrentFlushes prometheus.Gauge) *flushLimiter {
+	l := &flushLimiter{metricConcurrentFlushes: metricConcurrentFlushes}
+	if maxConcurrentFlushes > 0 {
+		l.sem = semaphore.NewWeighted(int64(maxConcurrentFlushes))
+	}
+	return l
+}
+
+// wrap returns an execDMLs which waits for its turn before flushing the rows
+func (l *flushLimiter) wrap(
+	execDMLs func(context.Context, []*model.RowChangedEvent, uint64, int) error,
+) func(context.Context, []*model.RowChangedEvent, uint64, int) error {
+	return func(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+		if l.sem != nil {
+			if err := l.sem.Acquire(ctx, 1); err != nil {
+				return errors.Trace(err)
+			}
+			defer l.sem.Release(1)
+		}
+		l.metricConcurrentFlushes.Inc()
+		defer l.metricConcurrentFlushes.Dec()
+		return execDMLs(ctx, rows, replicaID, bucket)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/notify"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/sync/errgroup"
+)
+
+func (s MySQLSinkSuite) TestMaxConcurrentFlushes(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const (
+		workerCount          = 4
+		maxConcurrentFlushes = 2
+	)
+	gauge := concurrentFlushesGauge.WithLabelValues("capture", "flush-limit-test")
+	limiter := newFlushLimiter(maxConcurrentFlushes, gauge)
+
+	var running, maxRunning, flushed int64
+	// the slow downstream takes 100ms to flush the rows
+	slowExecDMLs := func(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+		n := atomic.AddInt64(&running, 1)
+		for {
+			max := atomic.LoadInt64(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+				break
+			}
+		}
+		c.Assert(testutil.ToFloat64(gauge), check.LessEqual, float64(maxConcurrentFlushes))
+		time.Sleep(100 * time.Millisecond)
+		atomic.AddInt64(&running, -1)
+		atomic.AddInt64(&flushed, 1)
+		return nil
+	}
+
+	notifier := new(notify.Notifier)
+	errg, cctx := errgroup.WithContext(ctx)
+	workers := make([]*mysqlSinkWorker, workerCount)
+	for i := range workers {
+		w := newMySQLSinkWorker(256, i,
+			bucketSizeCounter.WithLabelValues("capture", "flush-limit-test", strconv.Itoa(i)),
+			notifier.NewReceiver(-1), limiter.wrap(slowExecDMLs))
+		workers[i] = w
+		errg.Go(func() error {
+			return w.run(cctx)
+		})
+	}
+	for i, w := range workers {
+		w.appendTxn(cctx, &model.SingleTableTxn{CommitTs: uint64(i + 1), Rows: []*model.RowChangedEvent{{CommitTs: uint64(i + 1)}}})
+	}
+	// ensure all txns are fetched from txn channel in sink workers
+	time.Sleep(100 * time.Millisecond)
+	notifier.Notify()
+	for _, w := range workers {
+		w.waitAllTxnsExecuted()
+	}
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+
+	c.Assert(atomic.LoadInt64(&flushed), check.Equals, int64(workerCount))
+	c.Assert(atomic.LoadInt64(&maxRunning), check.Equals, int64(maxConcurrentFlushes))
+	c.Assert(testutil.ToFloat64(gauge), check.Equals, float64(0))
+}
+
+func (s MySQLSinkSuite) TestUnlimitedFlushes(c *check.C) {
+	ctx := context.Background()
+	gauge := concurrentFlushesGauge.WithLabelValues("capture", "flush-unlimited-test")
+	limiter := newFlushLimiter(0, gauge)
+	c.Assert(limiter.sem, check.IsNil)
+	execDMLs := limiter.wrap(func(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+		c.Assert(testutil.ToFloat64(gauge), check.Equals, float64(1))
+		return nil
+	})
+	c.Assert(execDMLs(ctx, nil, 0, 0), check.IsNil)
+	c.Assert(testutil.ToFloat64(gauge), check.Equals, float64(0))
+
+	// the waiting flush is canceled with the context
+	limiter = newFlushLimiter(1, gauge)
+	c.Assert(limiter.sem.TryAcquire(1), check.IsTrue)
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err := limiter.wrap(func(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+		c.Fatal("the flush is not canceled")
+		return nil
+	})(cctx, nil, 0, 0)
+	c.Assert(err, check.ErrorMatches, ".*context canceled.*")
+}
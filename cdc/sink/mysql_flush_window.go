@@ -0,0 +1,45 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"time"
+)
+
+// flushWindow coalesces the flushes triggered by the advancing resolved ts, so that the
+// sink flushes at most once per window. The window bounds the delay of a flush, a flush
+// after the sink has been idle for the window is not delayed.
+type flushWindow struct {
+	// window is the min interval between two flushes, 0 disables the coalescing
+	window    time.Duration
+	lastFlush time.Time
+}
+
+// wait blocks until the window since the last flush passes, the caller should read the
+// latest resolved ts after wait returns. It returns the error of ctx if ctx is done.
+func (w *flushWindow) wait(ctx context.Context) error {
+	if w.window <= 0 {
+		return nil
+	}
+	if remaining := w.window - time.Since(w.lastFlush); remaining > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(remaining):
+		}
+	}
+	w.lastFlush = time.Now()
+	return nil
+}
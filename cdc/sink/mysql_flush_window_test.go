@@ -0,0 +1,98 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/notify"
+)
+
+func (s MySQLSinkSuite) TestFlushWindowBoundsFlushes(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const window = 100 * time.Millisecond
+	w := &flushWindow{window: window}
+
+	// the resolved ts advances every millisecond for 550ms
+	var resolvedTs uint64
+	notifier := new(notify.Notifier)
+	receiver := notifier.NewReceiver(-1)
+	advanceDone := make(chan struct{})
+	go func() {
+		defer close(advanceDone)
+		deadline := time.Now().Add(550 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			atomic.AddUint64(&resolvedTs, 1)
+			notifier.Notify()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	var flushTimes []time.Time
+	var flushedTs uint64
+	flush := func() {
+		c.Assert(w.wait(ctx), check.IsNil)
+		flushTimes = append(flushTimes, w.lastFlush)
+		flushedTs = atomic.LoadUint64(&resolvedTs)
+	}
+	for {
+		select {
+		case <-advanceDone:
+		case <-receiver.C:
+			flush()
+			continue
+		}
+		break
+	}
+	// flush the resolved ts advanced during the last window
+	flush()
+	c.Assert(flushedTs, check.Equals, atomic.LoadUint64(&resolvedTs))
+	// about one flush per window instead of one flush per advance
+	c.Assert(len(flushTimes), check.GreaterEqual, 3)
+	c.Assert(len(flushTimes), check.Less, int(atomic.LoadUint64(&resolvedTs)/10))
+	for i := 1; i < len(flushTimes); i++ {
+		c.Assert(flushTimes[i].Sub(flushTimes[i-1]), check.GreaterEqual, window)
+	}
+
+	// the flush after the sink has been idle for the window is not delayed
+	time.Sleep(window)
+	start := time.Now()
+	c.Assert(w.wait(ctx), check.IsNil)
+	c.Assert(time.Since(start), check.Less, window/2)
+
+	// the waiting flush is canceled with the context
+	cancel()
+	c.Assert(w.wait(ctx), check.Equals, context.Canceled)
+
+	// no window, no wait
+	w = &flushWindow{}
+	start = time.Now()
+	for i := 0; i < 10; i++ {
+		c.Assert(w.wait(ctx), check.IsNil)
+	}
+	c.Assert(time.Since(start), check.Less, window)
+}
+
+func (s MySQLSinkSuite) TestParseFlushWindowParams(c *check.C) {
+	ctx := context.Background()
+	sinkURI, err := url.Parse("mysql://127.0.0.1:3306/?resolved-ts-flush-window=-1s")
+	c.Assert(err, check.IsNil)
+	_, err = newMySQLSink(ctx, "test-cf", sinkURI, nil, nil, map[string]string{})
+	c.Assert(err, check.ErrorMatches, ".*resolved-ts-flush-window must not be negative.*")
+}
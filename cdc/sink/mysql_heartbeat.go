@@ -0,0 +1,105 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/pkg/cyclic/mark"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/quotes"
+	"go.uber.org/zap"
+)
+
+// heartbeatTableName is the name of the table where the mysql sink writes heartbeats
+const heartbeatTableName = "heartbeat"
+
+var (
+	quotedHeartbeatTable = quotes.QuoteSchema(mark.SchemaName, heartbeatTableName)
+
+	createHeartbeatSQLs = []string{
+		"CREATE DATABASE IF NOT EXISTS " + quotes.QuoteName(mark.SchemaName),
+		"CREATE TABLE IF NOT EXISTS " + quotedHeartbeatTable + " (" +
+			"`changefeed_id` VARCHAR(255) NOT NULL, " +
+			"`capture_id` VARCHAR(255) NOT NULL, " +
+			"`checkpoint_ts` BIGINT UNSIGNED NOT NULL, " +
+			"`update_time` DATETIME(6) NOT NULL, " +
+			"PRIMARY KEY (`changefeed_id`, `capture_id`))",
+	}
+
+	upsertHeartbeatSQL = "INSERT INTO " + quotedHeartbeatTable +
+		" (`changefeed_id`, `capture_id`, `checkpoint_ts`, `update_time`) VALUES (?, ?, ?, NOW(6))" +
+		" ON DUPLICATE KEY UPDATE `checkpoint_ts` = VALUES(`checkpoint_ts`), `update_time` = VALUES(`update_time`)"
+)
+
+// initHeartbeat creates the heartbeat table and checks whether the downstream user
+// is able to write heartbeats, the check is done in a transaction rolled back at last.
+func (s *mysqlSink) initHeartbeat(ctx context.Context) error {
+	for _, query := range createHeartbeatSQLs {
+		_, err := s.db.ExecContext(ctx, query)
+		if err != nil {
+			return wrapHeartbeatError(err)
+		}
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMySQLTxnError, err)
+	}
+	_, err = tx.ExecContext(ctx, upsertHeartbeatSQL, s.params.changefeedID, s.params.captureID, 0)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Warn("failed to rollback txn", zap.Error(rbErr))
+		}
+		return wrapHeartbeatError(err)
+	}
+	err = tx.Rollback()
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMySQLTxnError, err)
+	}
+	log.Info("heartbeat of mysql sink is enabled",
+		zap.String("table", quotedHeartbeatTable), zap.Duration("interval", s.params.heartbeatInterval))
+	return nil
+}
+
+// writeHeartbeat upserts the heartbeat row if the heartbeat interval elapses. It is called
+// after the transactions before checkpointTs are flushed, so the heartbeat reflects the
+// replication progress. The heartbeats are not counted in the statistics of the sink.
+func (s *mysqlSink) writeHeartbeat(ctx context.Context, checkpointTs uint64, now time.Time) {
+	if !s.params.enableHeartbeat || now.Sub(s.lastHeartbeatTime) < s.params.heartbeatInterval {
+		return
+	}
+	_, err := s.db.ExecContext(ctx, upsertHeartbeatSQL, s.params.changefeedID, s.params.captureID, checkpointTs)
+	if err != nil {
+		// a failing heartbeat is exposed by the stale heartbeat row, it does not stop the replication
+		log.Warn("failed to write heartbeat", zap.String("changefeed", s.params.changefeedID),
+			zap.Uint64("checkpoint-ts", checkpointTs), zap.Error(err))
+		return
+	}
+	s.lastHeartbeatTime = now
+}
+
+func wrapHeartbeatError(err error) error {
+	if code, ok := getSQLErrCode(err); ok {
+		switch code {
+		case mysql.ErrDBaccessDenied, mysql.ErrAccessDenied, mysql.ErrTableaccessDenied,
+			mysql.ErrColumnaccessDenied, mysql.ErrSpecificAccessDenied:
+			return cerror.ErrMySQLHeartbeatPrivilege.GenWithStackByArgs(quotedHeartbeatTable, err)
+		}
+	}
+	return errors.Annotate(cerror.WrapError(cerror.ErrMySQLQueryError, err), "fail to init heartbeat")
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/check"
+	"github.com/pingcap/parser/mysql"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/notify"
+)
+
+func newHeartbeatSink4Test(c *check.C) (*mysqlSink, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	ms := newMySQLSink4Test(c)
+	ms.db = db
+	ms.params.changefeedID = "test-cf"
+	ms.params.captureID = "capture-1"
+	ms.params.enableHeartbeat = true
+	return ms, mock
+}
+
+func (s MySQLSinkSuite) TestInitHeartbeat(c *check.C) {
+	ctx := context.Background()
+	ms, mock := newHeartbeatSink4Test(c)
+	for _, query := range createHeartbeatSQLs {
+		mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", "capture-1", 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectRollback()
+	err := ms.initHeartbeat(ctx)
+	c.Assert(err, check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// the user can't create the table
+	ms, mock = newHeartbeatSink4Test(c)
+	mock.ExpectExec(createHeartbeatSQLs[0]).WillReturnError(&dmysql.MySQLError{Number: mysql.ErrDBaccessDenied})
+	err = ms.initHeartbeat(ctx)
+	c.Assert(cerror.ErrMySQLHeartbeatPrivilege.Equal(err), check.IsTrue)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// the user can't write the table
+	ms, mock = newHeartbeatSink4Test(c)
+	for _, query := range createHeartbeatSQLs {
+		mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", "capture-1", 0).
+		WillReturnError(&dmysql.MySQLError{Number: mysql.ErrTableaccessDenied})
+	mock.ExpectRollback()
+	err = ms.initHeartbeat(ctx)
+	c.Assert(cerror.ErrMySQLHeartbeatPrivilege.Equal(err), check.IsTrue)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// other errors are not reported as privilege errors
+	ms, mock = newHeartbeatSink4Test(c)
+	mock.ExpectExec(createHeartbeatSQLs[0]).WillReturnError(&dmysql.MySQLError{Number: mysql.ErrUnknown})
+	err = ms.initHeartbeat(ctx)
+	c.Assert(cerror.ErrMySQLHeartbeatPrivilege.Equal(err), check.IsFalse)
+	c.Assert(err, check.ErrorMatches, "fail to init heartbeat.*")
+}
+
+func (s MySQLSinkSuite) TestWriteHeartbeat(c *check.C) {
+	ctx := context.Background()
+	ms, mock := newHeartbeatSink4Test(c)
+	ms.params.heartbeatInterval = 10 * time.Second
+	start := time.Now()
+
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", "capture-1", 100).WillReturnResult(sqlmock.NewResult(1, 1))
+	ms.writeHeartbeat(ctx, 100, start)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// the interval doesn't elapse
+	ms.writeHeartbeat(ctx, 200, start.Add(5*time.Second))
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", "capture-1", 300).WillReturnResult(sqlmock.NewResult(1, 1))
+	ms.writeHeartbeat(ctx, 300, start.Add(10*time.Second))
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// a failed heartbeat is retried in the next flush
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", "capture-1", 400).WillReturnError(dmysql.ErrInvalidConn)
+	ms.writeHeartbeat(ctx, 400, start.Add(20*time.Second))
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", "capture-1", 500).WillReturnResult(sqlmock.NewResult(1, 1))
+	ms.writeHeartbeat(ctx, 500, start.Add(21*time.Second))
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// heartbeats are excluded from the row counters
+	c.Assert(atomic.LoadUint64(&ms.statistics.totalRows), check.Equals, uint64(0))
+	c.Assert(atomic.LoadUint64(&ms.statistics.totalFlushedRows), check.Equals, uint64(0))
+
+	// nothing is written if the heartbeat is disabled
+	ms.params.enableHeartbeat = false
+	ms.writeHeartbeat(ctx, 600, start.Add(time.Hour))
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s MySQLSinkSuite) TestHeartbeatInFlushPath(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms, mock := newHeartbeatSink4Test(c)
+	ms.params.heartbeatInterval = time.Hour
+	ms.resolvedNotifier = new(notify.Notifier)
+	defer ms.resolvedNotifier.Close()
+
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", "capture-1", 100).WillReturnResult(sqlmock.NewResult(1, 1))
+	go ms.flushRowChangedEvents(ctx)
+	checkpointTs, err := ms.FlushRowChangedEvents(ctx, 100)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(100))
+	for i := 0; i < 100; i++ {
+		if mock.ExpectationsWereMet() == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/types"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/quotes"
+	"go.uber.org/zap"
+)
+
+// SchemaReconciler is implemented by the sinks which can bring the downstream schemas
+// up to date with the upstream schemas before the rows are written.
+type SchemaReconciler interface {
+	// ReconcileSchemas adds the columns of the tables the downstream lacks,
+	// the tables the downstream doesn't have are skipped.
+	ReconcileSchemas(ctx context.Context, tables []*model.TableInfo) error
+}
+
+// ReconcileSchemas implements SchemaReconciler
+func (s *mysqlSink) ReconcileSchemas(ctx context.Context, tables []*model.TableInfo) error {
+	for _, table := range tables {
+		downstream, err := s.downstreamColumns(ctx, table.TableName.Schema, table.TableName.Table)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if len(downstream) == 0 {
+			log.Warn("the table doesn't exist in the downstream, skip reconciling its schema",
+				zap.Stringer("table", table.TableName))
+			continue
+		}
+		for _, query := range missingColumnDDLs(table, downstream) {
+			log.Info("reconcile the downstream schema", zap.Stringer("table", table.TableName), zap.String("query", query))
+			ddl := &model.DDLEvent{
+				TableInfo: &model.SimpleTableInfo{Schema: table.TableName.Schema, Table: table.TableName.Table},
+				Query:     query,
+				Type:      timodel.ActionAddColumn,
+			}
+			if err := s.execDDLWithMaxRetries(ctx, ddl, defaultDDLMaxRetryTime); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// downstreamColumns returns the lower-case names of the columns of the downstream table,
+// it's empty if the table doesn't exist
+func (s *mysqlSink) downstreamColumns(ctx context.Context, schema, table string) (map[string]struct{}, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT COLUMN_NAME FROM information_schema.columns WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+		schema, table)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	defer rows.Close()
+	columns := make(map[string]struct{})
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+		}
+		columns[strings.ToLower(name)] = struct{}{}
+	}
+	return columns, cerror.WrapError(cerror.ErrMySQLQueryError, rows.Err())
+}
+
+// missingColumnDDLs returns the DDLs adding the replicated columns of the table the downstream lacks,
+// every column is placed after the column preceding it in the upstream.
+func missingColumnDDLs(table *model.TableInfo, downstream map[string]struct{}) []string {
+	var ddls []string
+	prev := ""
+	for _, col := range table.Columns {
+		if !model.IsColCDCVisible(col) {
+			continue
+		}
+		if _, ok := downstream[col.Name.L]; !ok {
+			if col.IsGenerated() {
+				// the expression of a stored generated column is left to the user
+				log.Warn("skip reconciling the generated column", zap.Stringer("table", table.TableName), zap.String("column", col.Name.O))
+				prev = col.Name.O
+				continue
+			}
+			position := "FIRST"
+			if prev != "" {
+				position = "AFTER " + quotes.QuoteName(prev)
+			}
+			ddls = append(ddls, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s %s",
+				quotes.QuoteSchema(table.TableName.Schema, table.TableName.Table),
+				quotes.QuoteName(col.Name.O), columnDefinition(col), position))
+		}
+		prev = col.Name.O
+	}
+	return ddls
+}
+
+// columnDefinition returns the type, the charset and collation, the nullability, the default value,
+// the ON UPDATE clause and the comment of the column
+func columnDefinition(col *timodel.ColumnInfo) string {
+	var b strings.Builder
+	b.WriteString(col.GetTypeDesc())
+	if types.HasCharset(&col.FieldType) && col.Charset != "" {
+		b.WriteString(" CHARACTER SET " + col.Charset)
+		if col.Collate != "" {
+			b.WriteString(" COLLATE " + col.Collate)
+		}
+	}
+	if mysql.HasNotNullFlag(col.Flag) {
+		b.WriteString(" NOT NULL")
+	}
+	switch v := col.GetDefaultValue().(type) {
+	case nil:
+	case string:
+		if col.Tp == mysql.TypeBit {
+			// the default value of a bit column is its big-endian bytes
+			var n uint64
+			for _, c := range []byte(v) {
+				n = n<<8 | uint64(c)
+			}
+			b.WriteString(fmt.Sprintf(" DEFAULT %d", n))
+			break
+		}
+		if strings.HasPrefix(strings.ToUpper(v), "CURRENT_TIMESTAMP") {
+			b.WriteString(" DEFAULT " + v)
+		} else {
+			b.WriteString(" DEFAULT " + quoteString(v))
+		}
+	default:
+		b.WriteString(fmt.Sprintf(" DEFAULT '%v'", v))
+	}
+	if mysql.HasOnUpdateNowFlag(col.Flag) {
+		b.WriteString(" ON UPDATE CURRENT_TIMESTAMP")
+		if col.Decimal > 0 {
+			b.WriteString(fmt.Sprintf("(%d)", col.Decimal))
+		}
+	}
+	if col.Comment != "" {
+		b.WriteString(" COMMENT " + quoteString(col.Comment))
+	}
+	return b.String()
+}
+
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(s) + "'"
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/types"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+func newReconcileTestTable() *model.TableInfo {
+	newColumn := func(id int64, name string, tp byte, flag uint, defaultValue interface{}) *timodel.ColumnInfo {
+		ft := types.NewFieldType(tp)
+		ft.Flag = flag
+		if tp == mysql.TypeVarchar {
+			ft.Flen = 16
+			ft.Charset = "utf8mb4"
+			ft.Collate = "utf8mb4_bin"
+		}
+		return &timodel.ColumnInfo{
+			ID: id, Name: timodel.NewCIStr(name), Offset: int(id - 1), FieldType: *ft,
+			DefaultValue: defaultValue, State: timodel.StatePublic,
+		}
+	}
+	updated := newColumn(4, "updated", mysql.TypeTimestamp, mysql.OnUpdateNowFlag, "CURRENT_TIMESTAMP(3)")
+	updated.Decimal = 3
+	updated.Comment = "it's updated"
+	return model.WrapTableInfo(1, "test", 1, &timodel.TableInfo{
+		ID:   42,
+		Name: timodel.NewCIStr("t"),
+		Columns: []*timodel.ColumnInfo{
+			newColumn(1, "id", mysql.TypeLong, mysql.PriKeyFlag|mysql.NotNullFlag, nil),
+			newColumn(2, "name", mysql.TypeVarchar, mysql.NotNullFlag, "it's"),
+			newColumn(3, "v", mysql.TypeLong, mysql.UnsignedFlag, nil),
+			updated,
+		},
+		PKIsHandle: true,
+	})
+}
+
+func (s MySQLSinkSuite) TestReconcileSchemas(c *check.C) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	ms := newMySQLSink4Test(c)
+	ms.db = db
+	ms.params.batchReplaceEnabled = true
+	query := "SELECT COLUMN_NAME FROM information_schema.columns WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+
+	// the downstream table lacks the column name, v and updated
+	mock.ExpectQuery(query).WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("ID"))
+	for _, ddl := range []string{
+		"ALTER TABLE `test`.`t` ADD COLUMN `name` varchar(16) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT 'it''s' AFTER `id`",
+		"ALTER TABLE `test`.`t` ADD COLUMN `v` int(11) unsigned AFTER `name`",
+		"ALTER TABLE `test`.`t` ADD COLUMN `updated` timestamp(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT 'it''s updated' AFTER `v`",
+	} {
+		mock.ExpectBegin()
+		mock.ExpectExec("USE `test`;").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(ddl).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+	}
+	// the rows written after the reconciliation carry the added columns
+	mock.ExpectBegin()
+	mock.ExpectExec("REPLACE INTO `test`.`t`(`id`,`name`,`v`) VALUES (?,?,?)").
+		WithArgs(1, "a", 2).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	c.Assert(ms.ReconcileSchemas(ctx, []*model.TableInfo{newReconcileTestTable()}), check.IsNil)
+	err = ms.execDMLs(ctx, []*model.RowChangedEvent{{
+		StartTs:  10,
+		CommitTs: 11,
+		Table:    &model.TableName{Schema: "test", Table: "t", TableID: 42},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: 1},
+			{Name: "name", Type: mysql.TypeVarchar, Value: "a"},
+			{Name: "v", Type: mysql.TypeLong, Value: 2},
+		},
+	}}, 0, 0)
+	c.Assert(err, check.IsNil)
+
+	// the tables the downstream doesn't have and the up-to-date tables are left alone
+	mock.ExpectQuery(query).WithArgs("test", "t").WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}))
+	mock.ExpectQuery(query).WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("name").AddRow("v").AddRow("updated"))
+	c.Assert(ms.ReconcileSchemas(ctx, []*model.TableInfo{newReconcileTestTable(), newReconcileTestTable()}), check.IsNil)
+
+	mock.ExpectClose()
+	c.Assert(db.Close(), check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"time"
+
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// redactedValue replaces the non-null values of a statement in the slow log
+const redactedValue = "?"
+
+// logSlowStatement logs the statement executed in the downstream if it takes longer than
+// the slow log threshold. The values are redacted unless slow-log-redact is disabled, the
+// null values are kept since they don't leak the data.
+func (s *mysqlSink) logSlowStatement(query string, args []interface{}, duration time.Duration, err error) {
+	if s.params.slowLogThreshold <= 0 || duration < s.params.slowLogThreshold {
+		return
+	}
+	fields := []zap.Field{
+		zap.String("changefeed", s.params.changefeedID),
+		zap.Duration("duration", duration),
+		zap.String("sql", query),
+	}
+	if s.params.slowLogRedact {
+		fields = append(fields, zap.Strings("args", redactArgs(args)))
+	} else {
+		fields = append(fields, zap.Any("args", args))
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	log.Warn("slow downstream statement", fields...)
+}
+
+func redactArgs(args []interface{}) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			redacted[i] = "NULL"
+		} else {
+			redacted[i] = redactedValue
+		}
+	}
+	return redacted
+}
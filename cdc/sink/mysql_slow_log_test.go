@@ -0,0 +1,91 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeLogs redirects the global logger to an observer until the returned function is called
+func observeLogs(c *check.C) (*observer.ObservedLogs, func()) {
+	core, logs := observer.New(zap.WarnLevel)
+	logger, props, err := log.InitLogger(&log.Config{Level: "info"})
+	c.Assert(err, check.IsNil)
+	log.ReplaceGlobals(zap.New(core), props)
+	return logs, func() { log.ReplaceGlobals(logger, props) }
+}
+
+func (s MySQLSinkSuite) TestSlowLog(c *check.C) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	ms := newMySQLSink4Test(c)
+	ms.db = db
+	ms.params.changefeedID = "test-cf"
+	ms.params.slowLogThreshold = 50 * time.Millisecond
+	logs, restore := observeLogs(c)
+	defer restore()
+
+	fastSQL := "INSERT INTO `s`.`t`(`id`,`name`) VALUES (?,?);"
+	slowSQL := "UPDATE `s`.`t` SET `id`=?,`name`=? WHERE `id`=? LIMIT 1;"
+	mock.ExpectBegin()
+	mock.ExpectExec(fastSQL).WithArgs(1, "alice").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(slowSQL).WithArgs(2, nil, 1).WillDelayFor(100 * time.Millisecond).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	dmls := &preparedDMLs{
+		sqls:     []string{fastSQL, slowSQL},
+		values:   [][]interface{}{{1, "alice"}, {2, nil, 1}},
+		rowCount: 2,
+	}
+	err = ms.execDMLWithMaxRetries(ctx, nil, dmls, 1, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	slowLogs := logs.FilterMessage("slow downstream statement").All()
+	c.Assert(slowLogs, check.HasLen, 1)
+	fields := slowLogs[0].ContextMap()
+	c.Assert(fields["changefeed"], check.Equals, "test-cf")
+	c.Assert(fields["sql"], check.Equals, slowSQL)
+	c.Assert(fields["args"], check.DeepEquals, []interface{}{"?", "NULL", "?"})
+	c.Assert(fields["duration"], check.FitsTypeOf, time.Duration(0))
+	c.Assert(fields["duration"].(time.Duration) >= 100*time.Millisecond, check.IsTrue)
+
+	// the values are logged if the redaction is disabled
+	ms.params.slowLogRedact = false
+	ms.logSlowStatement(slowSQL, []interface{}{2, "bob", 1}, time.Second, nil)
+	slowLogs = logs.FilterMessage("slow downstream statement").All()
+	c.Assert(slowLogs, check.HasLen, 2)
+	c.Assert(slowLogs[1].ContextMap()["args"], check.DeepEquals, []interface{}{2, "bob", 1})
+
+	// nothing is logged if the slow log is disabled
+	ms.params.slowLogThreshold = 0
+	ms.logSlowStatement(slowSQL, nil, time.Hour, nil)
+	c.Assert(logs.FilterMessage("slow downstream statement").Len(), check.Equals, 2)
+}
+
+func (s MySQLSinkSuite) TestParseSlowLogParams(c *check.C) {
+	ctx := context.Background()
+	sinkURI, err := url.Parse("mysql://127.0.0.1:3306/?slow-log-threshold=-1s")
+	c.Assert(err, check.IsNil)
+	_, err = newMySQLSink(ctx, "test-cf", sinkURI, nil, nil, map[string]string{})
+	c.Assert(err, check.ErrorMatches, ".*slow-log-threshold must not be negative.*")
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/cyclic/mark"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/quotes"
+)
+
+// SnapshotReader reads the tables of a TiDB cluster at snapshots, it's used to check
+// the integrity of the data replicated by a changefeed.
+type SnapshotReader struct {
+	db *sql.DB
+}
+
+// NewSnapshotReader creates a SnapshotReader of the TiDB with the dsn
+func NewSnapshotReader(ctx context.Context, dsn string) (*SnapshotReader, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrMySQLConnectionError, err)
+	}
+	err = db.PingContext(ctx)
+	if err != nil {
+		_ = db.Close()
+		return nil, cerror.WrapError(cerror.ErrMySQLConnectionError, err)
+	}
+	return newSnapshotReader(db), nil
+}
+
+// NewDownstreamSnapshotReader creates a SnapshotReader of the downstream TiDB of the sink uri
+func NewDownstreamSnapshotReader(ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr string) (*SnapshotReader, error) {
+	sinkURI, err := url.Parse(sinkURIStr)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	db, err := openSyncpointDB(ctx, changefeedID, sinkURI)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newSnapshotReader(db), nil
+}
+
+func newSnapshotReader(db *sql.DB) *SnapshotReader {
+	// the snapshot of a connection is not reset, so the connections are never reused
+	db.SetMaxIdleConns(0)
+	return &SnapshotReader{db: db}
+}
+
+// EstimateRowCount returns the estimated row count of the table
+func (r *SnapshotReader) EstimateRowCount(ctx context.Context, schema, table string) (int64, error) {
+	var count sql.NullInt64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT TABLE_ROWS FROM information_schema.tables WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+		schema, table).Scan(&count)
+	if err != nil {
+		return 0, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	return count.Int64, nil
+}
+
+// Checksum returns the row count and the checksum of the columns of the table at the snapshot ts
+func (r *SnapshotReader) Checksum(ctx context.Context, schema, table string, columns []string, ts uint64) (model.TableChecksum, error) {
+	var checksum model.TableChecksum
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return checksum, cerror.WrapError(cerror.ErrMySQLConnectionError, err)
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("SET @@tidb_snapshot = '%d'", ts))
+	if err != nil {
+		return checksum, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	err = conn.QueryRowContext(ctx, checksumQuery(schema, table, columns)).Scan(&checksum.RowCount, &checksum.Checksum)
+	return checksum, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+}
+
+// checksumQuery returns the query of the row count and the xor of the crc32 of every row
+func checksumQuery(schema, table string, columns []string) string {
+	fields := make([]string, 0, 2*len(columns))
+	for _, column := range columns {
+		fields = append(fields, quotes.QuoteName(column))
+	}
+	// CONCAT_WS skips the NULL values, so NULL and empty values are told apart by ISNULL
+	for _, column := range columns {
+		fields = append(fields, "ISNULL("+quotes.QuoteName(column)+")")
+	}
+	return fmt.Sprintf("SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS('#', %s))), 0) FROM %s",
+		strings.Join(fields, ", "), quotes.QuoteSchema(schema, table))
+}
+
+// LatestSyncpoint returns the latest syncpoint of the changefeed recorded in the downstream,
+// the ts are 0 if no syncpoint is recorded.
+func (r *SnapshotReader) LatestSyncpoint(ctx context.Context, changefeedID model.ChangeFeedID) (primaryTs, secondaryTs uint64, err error) {
+	query := "SELECT primary_ts, secondary_ts FROM " + mark.SchemaName + "." + syncpointTableName +
+		" WHERE cf = ? ORDER BY CAST(primary_ts AS UNSIGNED) DESC LIMIT 1"
+	err = r.db.QueryRowContext(ctx, query, changefeedID).Scan(&primaryTs, &secondaryTs)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	return primaryTs, secondaryTs, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+}
+
+// Close closes the SnapshotReader
+func (r *SnapshotReader) Close() error {
+	return cerror.WrapError(cerror.ErrMySQLConnectionError, r.db.Close())
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+func newSnapshotReader4Test(c *check.C) (*SnapshotReader, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	// the mocked db has only one connection, which must be reused
+	return &SnapshotReader{db: db}, mock
+}
+
+func (s MySQLSinkSuite) TestSnapshotReaderChecksum(c *check.C) {
+	ctx := context.Background()
+	reader, mock := newSnapshotReader4Test(c)
+	query := "SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS('#', `id`, `v`, ISNULL(`id`), ISNULL(`v`)))), 0) FROM `test`.`t1`"
+	c.Assert(checksumQuery("test", "t1", []string{"id", "v"}), check.Equals, query)
+	mock.ExpectExec("SET @@tidb_snapshot = '417257993615179777'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"count", "checksum"}).AddRow(10, 1234))
+	checksum, err := reader.Checksum(ctx, "test", "t1", []string{"id", "v"}, 417257993615179777)
+	c.Assert(err, check.IsNil)
+	c.Assert(checksum, check.Equals, model.TableChecksum{RowCount: 10, Checksum: 1234})
+
+	mock.ExpectQuery("SELECT TABLE_ROWS FROM information_schema.tables WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?").
+		WithArgs("test", "t1").WillReturnRows(sqlmock.NewRows([]string{"TABLE_ROWS"}).AddRow(nil))
+	count, err := reader.EstimateRowCount(ctx, "test", "t1")
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, int64(0))
+
+	mock.ExpectClose()
+	c.Assert(reader.Close(), check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s MySQLSinkSuite) TestSnapshotReaderLatestSyncpoint(c *check.C) {
+	ctx := context.Background()
+	reader, mock := newSnapshotReader4Test(c)
+	query := "SELECT primary_ts, secondary_ts FROM tidb_cdc.syncpoint_v1 WHERE cf = ? ORDER BY CAST(primary_ts AS UNSIGNED) DESC LIMIT 1"
+	mock.ExpectQuery(query).WithArgs("test-cf").
+		WillReturnRows(sqlmock.NewRows([]string{"primary_ts", "secondary_ts"}).AddRow("417257993615179777", "417257993615179999"))
+	primaryTs, secondaryTs, err := reader.LatestSyncpoint(ctx, "test-cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(primaryTs, check.Equals, uint64(417257993615179777))
+	c.Assert(secondaryTs, check.Equals, uint64(417257993615179999))
+
+	mock.ExpectQuery(query).WithArgs("test-cf").WillReturnRows(sqlmock.NewRows([]string{"primary_ts", "secondary_ts"}))
+	primaryTs, secondaryTs, err = reader.LatestSyncpoint(ctx, "test-cf")
+	c.Assert(err, check.IsNil)
+	c.Assert(primaryTs, check.Equals, uint64(0))
+	c.Assert(secondaryTs, check.Equals, uint64(0))
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
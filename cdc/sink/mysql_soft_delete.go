@@ -0,0 +1,127 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/quotes"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"go.uber.org/zap"
+)
+
+// softDeleter translates the deletes to the updates setting the marker column of the rows
+type softDeleter struct {
+	cfg *config.SoftDeleteConfig
+	// tz is the time zone of the downstream session, the time column is written in it
+	tz *time.Location
+
+	// checkedTables are the quoted downstream tables known to have the soft delete columns
+	checkedTablesMu sync.Mutex
+	checkedTables   map[string]struct{}
+}
+
+func newSoftDeleter(cfg *config.SoftDeleteConfig, tz *time.Location) *softDeleter {
+	if tz == nil {
+		tz = time.UTC
+	}
+	return &softDeleter{cfg: cfg, tz: tz, checkedTables: make(map[string]struct{})}
+}
+
+// checkColumns checks the soft delete columns exist in the downstream tables of the rows
+func (d *softDeleter) checkColumns(ctx context.Context, s *mysqlSink, rows []*model.RowChangedEvent) error {
+	for _, row := range rows {
+		quoteTable := quotes.QuoteSchema(row.Table.Schema, row.Table.Table)
+		d.checkedTablesMu.Lock()
+		_, ok := d.checkedTables[quoteTable]
+		d.checkedTablesMu.Unlock()
+		if ok {
+			continue
+		}
+		columns, err := s.downstreamColumns(ctx, row.Table.Schema, row.Table.Table)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if len(columns) == 0 {
+			// the writes fail since the table doesn't exist
+			log.Warn("the table doesn't exist in the downstream, skip checking the soft delete columns",
+				zap.String("table", quoteTable))
+			continue
+		}
+		for _, name := range []string{d.cfg.MarkerColumn, d.cfg.TimeColumn} {
+			if _, ok := columns[strings.ToLower(name)]; name != "" && !ok {
+				return cerror.ErrSoftDeleteColumnNotFound.GenWithStackByArgs(name, quoteTable)
+			}
+		}
+		d.checkedTablesMu.Lock()
+		d.checkedTables[quoteTable] = struct{}{}
+		d.checkedTablesMu.Unlock()
+	}
+	return nil
+}
+
+// forgetTable forgets the checked table, it's called after the table is created again
+func (d *softDeleter) forgetTable(schema, table string) {
+	d.checkedTablesMu.Lock()
+	defer d.checkedTablesMu.Unlock()
+	delete(d.checkedTables, quotes.QuoteSchema(schema, table))
+}
+
+// withMarker returns the columns of a written row along with the soft delete columns resetting the marker
+func (d *softDeleter) withMarker(cols []*model.Column) []*model.Column {
+	marked := make([]*model.Column, 0, len(cols)+2)
+	marked = append(marked, cols...)
+	marked = append(marked, &model.Column{Name: d.cfg.MarkerColumn, Value: 0})
+	if d.cfg.TimeColumn != "" {
+		marked = append(marked, &model.Column{Name: d.cfg.TimeColumn, Value: nil})
+	}
+	return marked
+}
+
+// prepareDelete returns the update setting the marker column of the deleted row
+func (d *softDeleter) prepareDelete(quoteTable string, cols []*model.Column, commitTs uint64) (string, []interface{}) {
+	colNames, wargs := whereSlice(cols)
+	if len(wargs) == 0 {
+		return "", nil
+	}
+	var builder strings.Builder
+	builder.WriteString("UPDATE " + quoteTable + " SET " + quotes.QuoteName(d.cfg.MarkerColumn) + " = 1")
+	args := make([]interface{}, 0, len(wargs)+1)
+	if d.cfg.TimeColumn != "" {
+		builder.WriteString(", " + quotes.QuoteName(d.cfg.TimeColumn) + " = ?")
+		args = append(args, oracle.GetTimeFromTS(commitTs).In(d.tz).Format("2006-01-02 15:04:05.000"))
+	}
+	builder.WriteString(" WHERE ")
+	for i := 0; i < len(colNames); i++ {
+		if i > 0 {
+			builder.WriteString(" AND ")
+		}
+		if wargs[i] == nil {
+			builder.WriteString(quotes.QuoteName(colNames[i]) + " IS NULL")
+		} else {
+			builder.WriteString(quotes.QuoteName(colNames[i]) + " = ?")
+			args = append(args, wargs[i])
+		}
+	}
+	builder.WriteString(" LIMIT 1;")
+	return builder.String(), args
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/tidb/session"
+	"github.com/pingcap/tidb/store/mockstore"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"github.com/pingcap/tidb/util/testkit"
+)
+
+func (s MySQLSinkSuite) TestSoftDelete(c *check.C) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	ms := newMySQLSink4Test(c)
+	ms.db = db
+	ms.params.batchReplaceEnabled = true
+	ms.softDeleter = newSoftDeleter(&config.SoftDeleteConfig{
+		Enable:       true,
+		MarkerColumn: "is_deleted",
+		TimeColumn:   "deleted_at",
+	}, time.UTC)
+	commitTs := oracle.ComposeTS(oracle.GetPhysical(time.Date(2020, 1, 2, 3, 4, 5, 6e6, time.UTC)), 0)
+
+	// the columns are checked before the first write of the table, and the delete becomes an update
+	mock.ExpectQuery("SELECT COLUMN_NAME FROM information_schema.columns WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?").
+		WithArgs("test", "t").WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).
+		AddRow("id").AddRow("v").AddRow("IS_DELETED").AddRow("deleted_at"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `test`.`t` SET `is_deleted` = 1, `deleted_at` = ? WHERE `id` = ? LIMIT 1;").
+		WithArgs("2020-01-02 03:04:05.006", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	c.Assert(ms.execDMLs(ctx, []*model.RowChangedEvent{newConflictTestRow(commitTs, 2, nil)}, 0, 0), check.IsNil)
+
+	// the row inserted again resets the marker
+	mock.ExpectBegin()
+	mock.ExpectExec("REPLACE INTO `test`.`t`(`id`,`v`,`is_deleted`,`deleted_at`) VALUES (?,?,?,?)").
+		WithArgs(1, 3, 0, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	c.Assert(ms.execDMLs(ctx, []*model.RowChangedEvent{newConflictTestRow(commitTs+1, nil, 3)}, 0, 0), check.IsNil)
+
+	// the downstream table lacking the marker column is rejected
+	row := newConflictTestRow(commitTs+2, 3, nil)
+	row.Table = &model.TableName{Schema: "test", Table: "t2", TableID: 43}
+	mock.ExpectQuery("SELECT COLUMN_NAME FROM information_schema.columns WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?").
+		WithArgs("test", "t2").WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("v"))
+	err = ms.execDMLs(ctx, []*model.RowChangedEvent{row}, 0, 0)
+	c.Assert(cerror.ErrSoftDeleteColumnNotFound.Equal(err), check.IsTrue)
+
+	mock.ExpectClose()
+	c.Assert(db.Close(), check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s MySQLSinkSuite) TestSoftDeleteKeepsDeletedRows(c *check.C) {
+	store, err := mockstore.NewMockTikvStore()
+	c.Assert(err, check.IsNil)
+	defer store.Close() //nolint:errcheck
+	session.SetSchemaLease(0)
+	session.DisableStats4Test()
+	domain, err := session.BootstrapSession(store)
+	c.Assert(err, check.IsNil)
+	defer domain.Close()
+	domain.SetStatsUpdating(true)
+
+	tk := testkit.NewTestKit(c, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (id int primary key, v int, is_deleted tinyint not null default 0)")
+	ms := newMySQLSink4Test(c)
+	ms.softDeleter = newSoftDeleter(&config.SoftDeleteConfig{Enable: true, MarkerColumn: "is_deleted"}, nil)
+	write := func(row *model.RowChangedEvent) {
+		dmls := ms.prepareDMLs([]*model.RowChangedEvent{row}, 0, 0)
+		for i, query := range dmls.sqls {
+			tk.MustExec(query, dmls.values[i]...)
+		}
+	}
+
+	write(newConflictTestRow(10, nil, 1))
+	write(newConflictTestRow(11, 1, 2))
+	tk.MustQuery("select id, v, is_deleted from t").Check(testkit.Rows("1 2 0"))
+	write(newConflictTestRow(12, 2, nil))
+	tk.MustQuery("select id, v, is_deleted from t").Check(testkit.Rows("1 2 1"))
+	write(newConflictTestRow(13, nil, 3))
+	tk.MustQuery("select id, v, is_deleted from t").Check(testkit.Rows("1 3 0"))
+}
+
+func (s MySQLSinkSuite) TestSoftDeleteConfig(c *check.C) {
+	c.Assert((*config.SoftDeleteConfig)(nil).Validate(), check.IsNil)
+	c.Assert((&config.SoftDeleteConfig{}).Validate(), check.IsNil)
+	err := (&config.SoftDeleteConfig{Enable: true}).Validate()
+	c.Assert(err, check.ErrorMatches, ".*marker-column must not be empty.*")
+	err = (&config.SoftDeleteConfig{Enable: true, MarkerColumn: "d", TimeColumn: "D"}).Validate()
+	c.Assert(err, check.ErrorMatches, ".*time-column must differ from marker-column d.*")
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/pingcap/ticdc/cdc/model"
+)
+
+const (
+	// txnAtomicityNone dispatches the transactions to the workers by the causality of their keys,
+	// the transactions of a table may be written by different workers
+	txnAtomicityNone = "none"
+	// txnAtomicityTable writes the transactions of a table by the same worker in the order of their
+	// commit ts, and a flush only writes the rows of a table. The tables are flushed in parallel,
+	// the order across the tables is only constrained by the resolved ts.
+	txnAtomicityTable = "table"
+)
+
+// tableWorkerIndex returns the index of the worker writing the table in the table atomicity mode
+func tableWorkerIndex(tableID model.TableID, workerCount int) int {
+	return int(uint64(tableID) % uint64(workerCount))
+}
+
+// dispatchTxnsByTable dispatches the transactions to the workers of their tables and waits them executed
+func (s *mysqlSink) dispatchTxnsByTable(ctx context.Context, txnsGroup map[model.TableID][]*model.SingleTableTxn) {
+	h := newTxnsHeap(txnsGroup)
+	h.iter(func(txn *model.SingleTableTxn) {
+		s.workers[tableWorkerIndex(txn.Table.TableID, len(s.workers))].appendTxn(ctx, txn)
+	})
+	s.notifyAndWaitExec(ctx)
+}
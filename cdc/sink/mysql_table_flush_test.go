@@ -0,0 +1,131 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/notify"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/sync/errgroup"
+)
+
+func (s MySQLSinkSuite) TestTableFlushConcurrency(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const (
+		workerCount           = 4
+		tableFlushConcurrency = 2
+		tableCount            = 6
+		txnsPerTable          = 3
+	)
+	gauge := concurrentTableFlushesGauge.WithLabelValues("capture", "table-flush-test")
+	limiter := newFlushLimiter(tableFlushConcurrency, gauge)
+
+	var running, maxRunning int64
+	var mu sync.Mutex
+	flushed := make(map[model.TableID][]uint64)
+	// the slow downstream takes 50ms to flush the rows
+	slowExecDMLs := func(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+		n := atomic.AddInt64(&running, 1)
+		for {
+			max := atomic.LoadInt64(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+				break
+			}
+		}
+		c.Assert(testutil.ToFloat64(gauge), check.LessEqual, float64(tableFlushConcurrency))
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		for _, row := range rows {
+			// a flush only writes the rows of a table, which are written by the worker of the table
+			c.Assert(row.Table.TableID, check.Equals, rows[0].Table.TableID)
+			c.Assert(bucket, check.Equals, tableWorkerIndex(row.Table.TableID, workerCount))
+			flushed[row.Table.TableID] = append(flushed[row.Table.TableID], row.CommitTs)
+		}
+		mu.Unlock()
+		atomic.AddInt64(&running, -1)
+		return nil
+	}
+
+	sink := &mysqlSink{
+		params:                          &sinkParams{workerCount: workerCount, txnAtomicity: txnAtomicityTable},
+		execWaitNotifier:                new(notify.Notifier),
+		metricConflictDetectDurationHis: conflictDetectDurationHis.WithLabelValues("capture", "table-flush-test"),
+	}
+	errg, cctx := errgroup.WithContext(ctx)
+	sink.workers = make([]*mysqlSinkWorker, workerCount)
+	for i := range sink.workers {
+		w := newMySQLSinkWorker(256, i,
+			bucketSizeCounter.WithLabelValues("capture", "table-flush-test", strconv.Itoa(i)),
+			sink.execWaitNotifier.NewReceiver(defaultFlushInterval), limiter.wrap(slowExecDMLs))
+		w.splitByTable = true
+		sink.workers[i] = w
+		errg.Go(func() error {
+			return w.run(cctx)
+		})
+	}
+
+	// the transactions of the tables interleave in the commit ts
+	txnsGroup := make(map[model.TableID][]*model.SingleTableTxn)
+	for i := 0; i < txnsPerTable; i++ {
+		for tableID := model.TableID(1); tableID <= tableCount; tableID++ {
+			table := &model.TableName{Schema: "test", Table: "t" + strconv.Itoa(int(tableID)), TableID: tableID}
+			commitTs := uint64(i*tableCount) + uint64(tableID)
+			txnsGroup[tableID] = append(txnsGroup[tableID], &model.SingleTableTxn{
+				Table:    table,
+				CommitTs: commitTs,
+				Rows:     []*model.RowChangedEvent{{Table: table, CommitTs: commitTs}},
+			})
+		}
+	}
+	sink.dispatchAndExecTxns(cctx, txnsGroup)
+	cancel()
+	c.Assert(errg.Wait(), check.IsNil)
+
+	c.Assert(atomic.LoadInt64(&maxRunning), check.Equals, int64(tableFlushConcurrency))
+	c.Assert(testutil.ToFloat64(gauge), check.Equals, float64(0))
+	c.Assert(flushed, check.HasLen, tableCount)
+	for tableID, commitTs := range flushed {
+		expected := make([]uint64, 0, txnsPerTable)
+		for i := 0; i < txnsPerTable; i++ {
+			expected = append(expected, uint64(i*tableCount)+uint64(tableID))
+		}
+		c.Assert(commitTs, check.DeepEquals, expected, check.Commentf("table %d", tableID))
+	}
+}
+
+func (s MySQLSinkSuite) TestTxnAtomicityParams(c *check.C) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		query string
+		err   string
+	}{
+		{"transaction-atomicity=all", ".*transaction-atomicity must be none or table, got all.*"},
+		{"transaction-atomicity=table&table-flush-concurrency=-1", ".*table-flush-concurrency must not be negative.*"},
+		{"transaction-atomicity=table&table-flush-concurrency=x", ".*invalid syntax.*"},
+	} {
+		sinkURI, err := url.Parse("mysql://127.0.0.1:3306/?" + tc.query)
+		c.Assert(err, check.IsNil)
+		_, err = newMySQLSink(ctx, "test-cf", sinkURI, nil, nil, map[string]string{})
+		c.Assert(err, check.ErrorMatches, tc.err)
+	}
+}
@@ -256,6 +256,96 @@ func (s MySQLSinkSuite) TestEmitRowChangedEvents(c *check.C) {
 	}
 }
 
+// eventFilterTestCase describes the rows that should be left after applying the event filter
+type eventFilterTestCase struct {
+	ignoreEvent []string
+	inserts     int
+	updates     int
+	deletes     int
+}
+
+var eventFilterTestCases = []eventFilterTestCase{
+	{ignoreEvent: nil, inserts: 2, updates: 1, deletes: 1},
+	{ignoreEvent: []string{"insert"}, inserts: 0, updates: 1, deletes: 1},
+	{ignoreEvent: []string{"update"}, inserts: 2, updates: 0, deletes: 1},
+	{ignoreEvent: []string{"delete"}, inserts: 2, updates: 1, deletes: 0},
+	{ignoreEvent: []string{"insert", "update"}, inserts: 0, updates: 0, deletes: 1},
+	{ignoreEvent: []string{"insert", "delete"}, inserts: 0, updates: 1, deletes: 0},
+	{ignoreEvent: []string{"update", "delete"}, inserts: 2, updates: 0, deletes: 0},
+	{ignoreEvent: []string{"insert", "update", "delete"}, inserts: 0, updates: 0, deletes: 0},
+}
+
+func newEventFilter4Test(c *check.C, ignoreEvent []string) *filter.Filter {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.EnableOldValue = true
+	cfg.Filter.EventFilters = []*config.EventFilterRule{
+		{Matcher: []string{"db1.audit_*"}, IgnoreEvent: ignoreEvent},
+	}
+	f, err := filter.NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	return f
+}
+
+// newEventFilterTestRows returns two inserts, an update and a delete of the filtered
+// table, and an insert of a table which is not matched by the event filter
+func newEventFilterTestRows() []*model.RowChangedEvent {
+	table := &model.TableName{Schema: "db1", Table: "audit_log", TableID: 1}
+	newCols := func(id int64, val string) []*model.Column {
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: id},
+			{Name: "val", Type: mysql.TypeVarchar, Value: val},
+		}
+	}
+	return []*model.RowChangedEvent{
+		{StartTs: 1, CommitTs: 2, Table: table, Columns: newCols(1, "a")},
+		{StartTs: 1, CommitTs: 2, Table: table, Columns: newCols(2, "b")},
+		{StartTs: 3, CommitTs: 4, Table: table, PreColumns: newCols(1, "a"), Columns: newCols(1, "c")},
+		{StartTs: 5, CommitTs: 6, Table: table, PreColumns: newCols(2, "b")},
+		{StartTs: 5, CommitTs: 6, Table: &model.TableName{Schema: "db1", Table: "orders", TableID: 2}, Columns: newCols(1, "d")},
+	}
+}
+
+func (s MySQLSinkSuite) TestEmitRowChangedEventsWithEventFilter(c *check.C) {
+	ctx := context.Background()
+	for _, tc := range eventFilterTestCases {
+		ms := newMySQLSink4Test(c)
+		ms.params.enableOldValue = true
+		ms.params.safeMode = false
+		ms.filter = newEventFilter4Test(c, tc.ignoreEvent)
+		ms.resolvedNotifier = new(notify.Notifier)
+		err := ms.EmitRowChangedEvents(ctx, newEventFilterTestRows()...)
+		c.Assert(err, check.IsNil)
+
+		var inserts, updates, deletes int
+		for _, txns := range ms.txnCache.Resolved(6) {
+			for _, txn := range txns {
+				if txn.Table.Table != "audit_log" {
+					continue
+				}
+				for _, row := range txn.Rows {
+					switch {
+					case row.IsDelete():
+						deletes++
+					case len(row.PreColumns) != 0:
+						updates++
+					default:
+						inserts++
+					}
+				}
+			}
+		}
+		comment := check.Commentf("ignore events: %v", tc.ignoreEvent)
+		c.Assert(inserts, check.Equals, tc.inserts, comment)
+		c.Assert(updates, check.Equals, tc.updates, comment)
+		c.Assert(deletes, check.Equals, tc.deletes, comment)
+
+		// ignoring events must not block the checkpoint
+		checkpointTs, err := ms.FlushRowChangedEvents(ctx, 6)
+		c.Assert(err, check.IsNil)
+		c.Assert(checkpointTs, check.Equals, uint64(6))
+	}
+}
+
 func (s MySQLSinkSuite) TestMysqlSinkWorker(c *check.C) {
 	testCases := []struct {
 		txns                     []*model.SingleTableTxn
@@ -665,7 +755,12 @@ func (s MySQLSinkSuite) TestSinkParamsClone(c *check.C) {
 		batchReplaceSize:    defaultBatchReplaceSize,
 		readTimeout:         defaultReadTimeout,
 		writeTimeout:        defaultWriteTimeout,
+		dialTimeout:         defaultDialTimeout,
 		safeMode:            defaultSafeMode,
+		heartbeatInterval:   defaultHeartbeatInterval,
+		slowLogRedact:       defaultSlowLogRedact,
+		txnAtomicity:        defaultTxnAtomicity,
+		pauseOnUnwritable:   true,
 	})
 	c.Assert(param2, check.DeepEquals, &sinkParams{
 		changefeedID:        "123",
@@ -676,7 +771,12 @@ func (s MySQLSinkSuite) TestSinkParamsClone(c *check.C) {
 		batchReplaceSize:    defaultBatchReplaceSize,
 		readTimeout:         defaultReadTimeout,
 		writeTimeout:        defaultWriteTimeout,
+		dialTimeout:         defaultDialTimeout,
 		safeMode:            defaultSafeMode,
+		heartbeatInterval:   defaultHeartbeatInterval,
+		slowLogRedact:       defaultSlowLogRedact,
+		txnAtomicity:        defaultTxnAtomicity,
+		pauseOnUnwritable:   true,
 	})
 }
 
@@ -699,6 +799,7 @@ func (s MySQLSinkSuite) TestConfigureSinkURI(c *check.C) {
 		"tidb_txn_mode=optimistic",
 		"readTimeout=2m",
 		"writeTimeout=2m",
+		"timeout=2m",
 		"allow_auto_random_explicit_insert=1",
 	}
 	for _, param := range expectedParams {
@@ -0,0 +1,106 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/config"
+)
+
+func (s MySQLSinkSuite) TestConfigureSinkURITimeouts(c *check.C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, check.IsNil)
+	columns := []string{"Variable_name", "Value"}
+	mock.ExpectQuery("show session variables like 'allow_auto_random_explicit_insert';").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("show session variables like 'tidb_txn_mode';").WillReturnRows(
+		sqlmock.NewRows(columns).AddRow("tidb_txn_mode", "pessimistic"),
+	)
+
+	dsn, err := dmysql.ParseDSN("root:123456@tcp(127.0.0.1:4000)/")
+	c.Assert(err, check.IsNil)
+	params := defaultParams.Clone()
+	params.readTimeout = "10s"
+	params.writeTimeout = "20s"
+	params.dialTimeout = "3s"
+	dsnStr, err := configureSinkURI(context.TODO(), dsn, time.Local, params, db)
+	c.Assert(err, check.IsNil)
+	for _, param := range []string{"readTimeout=10s", "writeTimeout=20s", "timeout=3s"} {
+		c.Assert(strings.Contains(dsnStr, param), check.IsTrue, check.Commentf("dsn: %s", dsnStr))
+	}
+	cfg, err := dmysql.ParseDSN(dsnStr)
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.ReadTimeout, check.Equals, 10*time.Second)
+	c.Assert(cfg.WriteTimeout, check.Equals, 20*time.Second)
+	c.Assert(cfg.Timeout, check.Equals, 3*time.Second)
+}
+
+func (s MySQLSinkSuite) TestParseTimeoutParams(c *check.C) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		query string
+		err   string
+	}{
+		{"read-timeout=abc", ".*invalid duration.*"},
+		{"write-timeout=0s", ".*write-timeout must be positive.*"},
+		{"dial-timeout=-1s", ".*dial-timeout must be positive.*"},
+	} {
+		sinkURI, err := url.Parse("mysql://127.0.0.1:3306/?" + tc.query)
+		c.Assert(err, check.IsNil)
+		_, err = newMySQLSink(ctx, "test-cf", sinkURI, nil, nil, map[string]string{})
+		c.Assert(err, check.ErrorMatches, tc.err)
+	}
+}
+
+func (s MySQLSinkSuite) TestHungDownstreamTimeout(c *check.C) {
+	// the downstream accepts the connections but never sends the handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer l.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sinkURI, err := url.Parse(fmt.Sprintf("mysql://%s/?read-timeout=100ms&write-timeout=100ms&dial-timeout=1s", l.Addr()))
+	c.Assert(err, check.IsNil)
+	start := time.Now()
+	_, err = newMySQLSink(ctx, "test-cf", sinkURI, nil, config.GetDefaultReplicaConfig(), map[string]string{})
+	// the driver reports the read timeout of the handshake as a bad connection
+	c.Assert(err, check.ErrorMatches, ".*ErrMySQLQueryError.*bad connection.*")
+	c.Assert(ctx.Err(), check.IsNil)
+	c.Assert(time.Since(start) < 5*time.Second, check.IsTrue)
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	readOnlySQL = "SELECT @@read_only"
+	// downstreamProbeCaptureID is the capture id of the heartbeat row written by the downstream probes
+	downstreamProbeCaptureID = "downstream-probe"
+)
+
+// isDownstreamUnwritableError returns whether the downstream refuses the writes for a while,
+// e.g. it's read-only or its disk is full, the writes are unlikely to succeed by retrying soon
+func isDownstreamUnwritableError(err error) bool {
+	code, ok := getSQLErrCode(err)
+	if !ok {
+		return false
+	}
+	switch code {
+	case mysql.ErrOptionPreventsStatement, mysql.ErrReadOnlyMode, mysql.ErrDiskFull, mysql.ErrRecordFileFull:
+		return true
+	case mysql.ErrErrorOnWrite:
+		// the errno of the failed write is only in the message
+		return strings.Contains(errors.Cause(err).Error(), "No space left on device")
+	}
+	return false
+}
+
+func newDownstreamUnwritableError(err error) error {
+	return cerror.ErrDownstreamUnwritable.GenWithStackByArgs(errors.Cause(err).Error())
+}
+
+// ProbeDownstreamWritable checks whether the MySQL downstream of the sink uri accepts the writes,
+// the other downstreams are always writable
+func ProbeDownstreamWritable(ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr string) error {
+	sinkURI, err := url.Parse(sinkURIStr)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	if _, ok := validSchemes[strings.ToLower(sinkURI.Scheme)]; !ok {
+		return nil
+	}
+	db, err := openSyncpointDB(ctx, changefeedID, sinkURI)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer db.Close()
+	return probeDownstreamWritable(ctx, db, changefeedID)
+}
+
+// probeDownstreamWritable checks the read_only variable of the downstream and then writes a heartbeat
+// row in a transaction rolled back at last, which reveals the downstream refusing the writes for the
+// other reasons, e.g. super_read_only or disk full. No DDL is executed, so the heartbeat row is only
+// written if the heartbeat table is created by a mysql sink enabling the heartbeat.
+func probeDownstreamWritable(ctx context.Context, db *sql.DB, changefeedID model.ChangeFeedID) error {
+	var readOnly bool
+	if err := db.QueryRowContext(ctx, readOnlySQL).Scan(&readOnly); err != nil {
+		return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	if readOnly {
+		return cerror.ErrDownstreamUnwritable.GenWithStackByArgs("read_only is enabled")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMySQLTxnError, err)
+	}
+	_, err = tx.ExecContext(ctx, upsertHeartbeatSQL, changefeedID, downstreamProbeCaptureID, 0)
+	if rbErr := tx.Rollback(); rbErr != nil {
+		log.Warn("failed to rollback txn", zap.Error(rbErr))
+	}
+	if err != nil {
+		if code, ok := getSQLErrCode(err); ok && code == mysql.ErrNoSuchTable {
+			return nil
+		}
+		return cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	return nil
+}
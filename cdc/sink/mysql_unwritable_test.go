@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+var errReadOnly = &dmysql.MySQLError{
+	Number:  mysql.ErrOptionPreventsStatement,
+	Message: "The MySQL server is running with the --read-only option so it cannot execute this statement",
+}
+
+func (s MySQLSinkSuite) TestDownstreamUnwritableError(c *check.C) {
+	cases := []struct {
+		err        error
+		unwritable bool
+	}{
+		{errReadOnly, true},
+		{cerror.WrapError(cerror.ErrMySQLTxnError, errReadOnly), true},
+		{&dmysql.MySQLError{Number: mysql.ErrReadOnlyMode, Message: "Running in read-only mode"}, true},
+		{&dmysql.MySQLError{Number: mysql.ErrDiskFull, Message: "Disk full (/tmp/t); waiting for someone to free some space..."}, true},
+		{&dmysql.MySQLError{Number: mysql.ErrRecordFileFull, Message: "The table 't' is full"}, true},
+		{&dmysql.MySQLError{Number: mysql.ErrErrorOnWrite, Message: "Error writing file './t.ibd' (errno: 28 - No space left on device)"}, true},
+		{&dmysql.MySQLError{Number: mysql.ErrErrorOnWrite, Message: "Error writing file './t.ibd' (errno: 5 - Input/output error)"}, false},
+		{&dmysql.MySQLError{Number: mysql.ErrDupEntry, Message: "Duplicate entry '1' for key 'PRIMARY'"}, false},
+		{dmysql.ErrInvalidConn, false},
+		{errors.New("read-only"), false},
+	}
+	for _, cs := range cases {
+		c.Assert(isDownstreamUnwritableError(cs.err), check.Equals, cs.unwritable, check.Commentf("%v", cs.err))
+	}
+}
+
+func (s MySQLSinkSuite) TestExecDMLsOnReadOnlyDownstream(c *check.C) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	ms := newMySQLSink4Test(c)
+	ms.db = db
+	insertSQL := "INSERT INTO `s`.`t`(`id`) VALUES (?);"
+	dmls := &preparedDMLs{
+		sqls:     []string{insertSQL},
+		values:   [][]interface{}{{1}},
+		rowCount: 1,
+	}
+
+	// the writes the read-only downstream refuses are not retried
+	mock.ExpectBegin()
+	mock.ExpectExec(insertSQL).WithArgs(1).WillReturnError(errReadOnly)
+	err = ms.execDMLWithMaxRetries(ctx, nil, dmls, 3, 0)
+	c.Assert(cerror.ErrDownstreamUnwritable.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*the downstream refuses to write for a while.*--read-only.*")
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// the writes are retried as the other errors if the downstream refusing the writes isn't paused
+	ms.params.pauseOnUnwritable = false
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec(insertSQL).WithArgs(1).WillReturnError(errReadOnly)
+	}
+	err = ms.execDMLWithMaxRetries(ctx, nil, dmls, 1, 0)
+	c.Assert(cerror.ErrDownstreamUnwritable.Equal(err), check.IsFalse)
+	c.Assert(err, check.ErrorMatches, ".*--read-only.*")
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s MySQLSinkSuite) TestProbeDownstreamWritable(c *check.C) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	defer db.Close()
+
+	// the read-only downstream refuses the writes
+	mock.ExpectQuery(readOnlySQL).WillReturnRows(sqlmock.NewRows([]string{"@@read_only"}).AddRow(1))
+	err = probeDownstreamWritable(ctx, db, "test-cf")
+	c.Assert(cerror.ErrDownstreamUnwritable.Equal(err), check.IsTrue)
+	c.Assert(err, check.ErrorMatches, ".*read_only is enabled.*")
+
+	// the heartbeat is written in a transaction rolled back at last
+	mock.ExpectQuery(readOnlySQL).WillReturnRows(sqlmock.NewRows([]string{"@@read_only"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", downstreamProbeCaptureID, 0).WillReturnError(errReadOnly)
+	mock.ExpectRollback()
+	err = probeDownstreamWritable(ctx, db, "test-cf")
+	c.Assert(err, check.ErrorMatches, ".*--read-only.*")
+
+	mock.ExpectQuery(readOnlySQL).WillReturnRows(sqlmock.NewRows([]string{"@@read_only"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", downstreamProbeCaptureID, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectRollback()
+	c.Assert(probeDownstreamWritable(ctx, db, "test-cf"), check.IsNil)
+
+	// the heartbeat isn't written without the heartbeat table
+	mock.ExpectQuery(readOnlySQL).WillReturnRows(sqlmock.NewRows([]string{"@@read_only"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(upsertHeartbeatSQL).WithArgs("test-cf", downstreamProbeCaptureID, 0).
+		WillReturnError(&dmysql.MySQLError{Number: mysql.ErrNoSuchTable, Message: "Table 'tidb_cdc.heartbeat' doesn't exist"})
+	mock.ExpectRollback()
+	c.Assert(probeDownstreamWritable(ctx, db, "test-cf"), check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// the downstreams other than MySQL are always writable
+	c.Assert(ProbeDownstreamWritable(ctx, "test-cf", "kafka://127.0.0.1:9092/topic"), check.IsNil)
+}
+
+func (s MySQLSinkSuite) TestDownstreamUnwritableConfig(c *check.C) {
+	cfg := config.GetDefaultReplicaConfig().Sink
+	c.Assert(cfg.ValidateDownstreamUnwritable(), check.IsNil)
+	cfg.DownstreamUnwritable = config.DownstreamUnwritableRetry
+	c.Assert(cfg.ValidateDownstreamUnwritable(), check.IsNil)
+	cfg.DownstreamUnwritable = "ignore"
+	c.Assert(cfg.ValidateDownstreamUnwritable(), check.ErrorMatches, ".*unsupported downstream-unwritable ignore.*")
+	cfg.DownstreamUnwritable = ""
+	cfg.DownstreamProbeInterval = -1
+	c.Assert(cfg.ValidateDownstreamUnwritable(), check.ErrorMatches, ".*negative downstream-probe-interval.*")
+}
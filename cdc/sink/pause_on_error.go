@@ -0,0 +1,320 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// maxPausedRows is the max number of the failing rows kept by a paused sink
+const maxPausedRows = 1024
+
+// The operations of the sink which pause on an error
+const (
+	pausedOperationEmit     = "emit"
+	pausedOperationFlush    = "flush"
+	pausedOperationExecDMLs = "exec-dmls"
+	// pausedOperationAsync is an asynchronous write failing after it's emitted, such as a message of a MQ producer
+	pausedOperationAsync = "async"
+)
+
+// PausedSinkError is the first error of the paused writes of a sink, see DebugConfig.PauseOnFirstError
+type PausedSinkError struct {
+	ChangefeedID string `json:"changefeed-id"`
+	Operation    string `json:"operation"`
+	// ResolvedTs is the resolved ts of a failing flush
+	ResolvedTs uint64 `json:"resolved-ts,omitempty"`
+	// Rows are at most maxPausedRows of the failing rows, the rows of a failing flush
+	// are the rows emitted but not flushed yet
+	Rows      []*model.RowChangedEvent `json:"rows"`
+	TotalRows int                      `json:"total-rows"`
+	Error     string                   `json:"error"`
+	PauseTime time.Time                `json:"pause-time"`
+}
+
+// errorPauser pauses the writes failing with an error until they are resumed, and then retries them
+type errorPauser struct {
+	changefeedID string
+
+	mu       sync.Mutex
+	paused   *PausedSinkError
+	resumeCh chan struct{}
+}
+
+// pausedSinks is the paused sinks of every changefeed on the capture, a changefeed may have several
+// paused sinks, such as the sink of the processor, the DDL sink of the owner and the MySQL sink
+// pausing inside the retries of the transactions
+var pausedSinks = struct {
+	sync.Mutex
+	feeds map[string]map[*errorPauser]struct{}
+}{feeds: make(map[string]map[*errorPauser]struct{})}
+
+func newErrorPauser(changefeedID string) *errorPauser {
+	return &errorPauser{changefeedID: changefeedID}
+}
+
+// retry calls write until it succeeds, and pauses after every failure until the writes are resumed.
+// The permanent errors and the downstream refusing the writes are returned without pausing.
+func (p *errorPauser) retry(
+	ctx context.Context, operation string, resolvedTs uint64, rows []*model.RowChangedEvent, write func() error,
+) error {
+	for {
+		err := write()
+		if !p.shouldPause(err) {
+			return err
+		}
+		if err := p.pause(ctx, operation, resolvedTs, rows, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *errorPauser) shouldPause(err error) bool {
+	if err == nil || errors.Cause(err) == context.Canceled {
+		return false
+	}
+	if _, ok := err.(*backoff.PermanentError); ok {
+		return false
+	}
+	// the downstream is probed and the changefeed is resumed by the owner
+	return cerror.ErrDownstreamUnwritable.NotEqual(err)
+}
+
+// pause blocks until the writes are resumed, only the first error is kept if several writes fail
+func (p *errorPauser) pause(
+	ctx context.Context, operation string, resolvedTs uint64, rows []*model.RowChangedEvent, cause error,
+) error {
+	p.mu.Lock()
+	if p.paused == nil {
+		p.paused = &PausedSinkError{
+			ChangefeedID: p.changefeedID,
+			Operation:    operation,
+			ResolvedTs:   resolvedTs,
+			Rows:         rows,
+			TotalRows:    len(rows),
+			Error:        cause.Error(),
+			PauseTime:    time.Now(),
+		}
+		if len(rows) > maxPausedRows {
+			p.paused.Rows = rows[:maxPausedRows]
+		}
+		p.resumeCh = make(chan struct{})
+		log.Warn("pause the writes of the sink on the error",
+			zap.String("changefeed", p.changefeedID), zap.String("operation", operation),
+			zap.Uint64("resolvedTs", resolvedTs), zap.Int("rows", len(rows)), zap.Error(cause))
+	}
+	resumeCh := p.resumeCh
+	p.mu.Unlock()
+
+	pausedSinks.Lock()
+	if _, ok := pausedSinks.feeds[p.changefeedID]; !ok {
+		pausedSinks.feeds[p.changefeedID] = make(map[*errorPauser]struct{})
+	}
+	pausedSinks.feeds[p.changefeedID][p] = struct{}{}
+	pausedSinks.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case <-resumeCh:
+	}
+	return nil
+}
+
+// wait blocks until the writes are resumed if they are paused
+func (p *errorPauser) wait(ctx context.Context) error {
+	p.mu.Lock()
+	if p.paused == nil {
+		p.mu.Unlock()
+		return nil
+	}
+	resumeCh := p.resumeCh
+	p.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case <-resumeCh:
+	}
+	return nil
+}
+
+// forwardErrors pauses on the asynchronous errors of the sink before forwarding them to errCh.
+// The failed writes are already dropped, so they can't be retried, the error fails the changefeed
+// once resumed and the changefeed restarts from its checkpoint.
+func (p *errorPauser) forwardErrors(ctx context.Context, sinkErrCh <-chan error, errCh chan<- error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-sinkErrCh:
+			if p.shouldPause(err) {
+				if p.pause(ctx, pausedOperationAsync, 0, nil, err) != nil {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case errCh <- err:
+			}
+		}
+	}
+}
+
+func (p *errorPauser) resume() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused == nil {
+		return false
+	}
+	log.Info("resume the paused writes of the sink", zap.String("changefeed", p.changefeedID))
+	close(p.resumeCh)
+	p.paused = nil
+	return true
+}
+
+func (p *errorPauser) close() {
+	pausedSinks.Lock()
+	defer pausedSinks.Unlock()
+	if pausers, ok := pausedSinks.feeds[p.changefeedID]; ok {
+		delete(pausers, p)
+		if len(pausers) == 0 {
+			delete(pausedSinks.feeds, p.changefeedID)
+		}
+	}
+}
+
+// GetPausedSinkError returns the earliest error pausing the sinks of the changefeed on the capture,
+// or nil if no sink is paused
+func GetPausedSinkError(changefeedID string) *PausedSinkError {
+	pausedSinks.Lock()
+	defer pausedSinks.Unlock()
+	var first *PausedSinkError
+	for p := range pausedSinks.feeds[changefeedID] {
+		p.mu.Lock()
+		paused := p.paused
+		p.mu.Unlock()
+		if paused != nil && (first == nil || paused.PauseTime.Before(first.PauseTime)) {
+			first = paused
+		}
+	}
+	return first
+}
+
+// ResumePausedSink resumes all the paused sinks of the changefeed on the capture, which retry
+// the failing writes. It returns false if no sink is paused.
+func ResumePausedSink(changefeedID string) bool {
+	pausedSinks.Lock()
+	pausers := pausedSinks.feeds[changefeedID]
+	delete(pausedSinks.feeds, changefeedID)
+	pausedSinks.Unlock()
+	resumed := false
+	for p := range pausers {
+		if p.resume() {
+			resumed = true
+		}
+	}
+	return resumed
+}
+
+// pauseOnErrorSink pauses the row writes of the sink failing with an error
+type pauseOnErrorSink struct {
+	Sink
+	pauser *errorPauser
+
+	mu sync.Mutex
+	// pending are the rows emitted but not flushed, which are kept for the inspection of a failing flush
+	pending []*model.RowChangedEvent
+}
+
+func newPauseOnErrorSink(changefeedID string, s Sink) *pauseOnErrorSink {
+	return &pauseOnErrorSink{Sink: s, pauser: newErrorPauser(changefeedID)}
+}
+
+// EmitRowChangedEvents implements the Sink interface
+func (s *pauseOnErrorSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	// the sink may be paused by an asynchronous error
+	if err := s.pauser.wait(ctx); err != nil {
+		return err
+	}
+	err := s.pauser.retry(ctx, pausedOperationEmit, 0, rows, func() error {
+		return s.Sink.EmitRowChangedEvents(ctx, rows...)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.mu.Lock()
+	s.pending = append(s.pending, rows...)
+	s.mu.Unlock()
+	return nil
+}
+
+// FlushRowChangedEvents implements the Sink interface
+func (s *pauseOnErrorSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) (uint64, error) {
+	if err := s.pauser.wait(ctx); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	pending := make([]*model.RowChangedEvent, 0, len(s.pending))
+	for _, row := range s.pending {
+		if row.CommitTs <= resolvedTs {
+			pending = append(pending, row)
+		}
+	}
+	s.mu.Unlock()
+
+	var checkpointTs uint64
+	err := s.pauser.retry(ctx, pausedOperationFlush, resolvedTs, pending, func() error {
+		var err error
+		checkpointTs, err = s.Sink.FlushRowChangedEvents(ctx, resolvedTs)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	s.mu.Lock()
+	unflushed := s.pending[:0]
+	for _, row := range s.pending {
+		if row.CommitTs > checkpointTs {
+			unflushed = append(unflushed, row)
+		}
+	}
+	s.pending = unflushed
+	s.mu.Unlock()
+	return checkpointTs, nil
+}
+
+// Warning returns the error pausing the sinks of the changefeed, so the pause is reported in the
+// task position, or the warning of the sink if no sink is paused
+func (s *pauseOnErrorSink) Warning() error {
+	if paused := GetPausedSinkError(s.pauser.changefeedID); paused != nil {
+		return cerror.ErrSinkPausedOnError.GenWithStackByArgs(paused.Operation, paused.Error)
+	}
+	return Warning(s.Sink)
+}
+
+// Close implements the Sink interface
+func (s *pauseOnErrorSink) Close() error {
+	s.pauser.close()
+	return s.Sink.Close()
+}
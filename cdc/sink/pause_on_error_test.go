@@ -0,0 +1,288 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+type pauseOnErrorSuite struct{}
+
+var _ = check.Suite(&pauseOnErrorSuite{})
+
+// failingSink fails the first calls of EmitRowChangedEvents and FlushRowChangedEvents
+type failingSink struct {
+	Sink
+	emitFailures  int
+	flushFailures int
+	emitted       []*model.RowChangedEvent
+	warning       error
+}
+
+func (s *failingSink) Warning() error {
+	return s.warning
+}
+
+func (s *failingSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	if s.emitFailures > 0 {
+		s.emitFailures--
+		return errors.New("injected emit error")
+	}
+	s.emitted = append(s.emitted, rows...)
+	return nil
+}
+
+func (s *failingSink) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) (uint64, error) {
+	if s.flushFailures > 0 {
+		s.flushFailures--
+		return 0, errors.New("injected flush error")
+	}
+	return resolvedTs, nil
+}
+
+func (s *failingSink) Close() error {
+	return nil
+}
+
+func waitPausedSinkError(c *check.C, changefeedID string) *PausedSinkError {
+	for i := 0; i < 100; i++ {
+		if paused := GetPausedSinkError(changefeedID); paused != nil {
+			return paused
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("the sink of %s is not paused", changefeedID)
+	return nil
+}
+
+func assertNotReturned(c *check.C, done chan error) {
+	select {
+	case err := <-done:
+		c.Fatalf("the paused write returns %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (s *pauseOnErrorSuite) TestPauseOnFirstError(c *check.C) {
+	ctx := context.Background()
+	inner := &failingSink{emitFailures: 2, flushFailures: 1}
+	sink := newPauseOnErrorSink("pause-test", inner)
+	defer sink.Close() //nolint:errcheck
+	rows := []*model.RowChangedEvent{newQuarantineTestRow(1, 10), newQuarantineTestRow(1, 20)}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sink.EmitRowChangedEvents(ctx, rows...)
+	}()
+	paused := waitPausedSinkError(c, "pause-test")
+	c.Assert(paused.Operation, check.Equals, pausedOperationEmit)
+	c.Assert(paused.Rows, check.DeepEquals, rows)
+	c.Assert(paused.TotalRows, check.Equals, 2)
+	c.Assert(paused.Error, check.Equals, "injected emit error")
+	assertNotReturned(c, done)
+
+	// the emit fails again after resumed, the sink keeps paused
+	c.Assert(ResumePausedSink("pause-test"), check.IsTrue)
+	paused = waitPausedSinkError(c, "pause-test")
+	c.Assert(paused.Rows, check.DeepEquals, rows)
+	assertNotReturned(c, done)
+	c.Assert(ResumePausedSink("pause-test"), check.IsTrue)
+	c.Assert(<-done, check.IsNil)
+	c.Assert(inner.emitted, check.DeepEquals, rows)
+	c.Assert(GetPausedSinkError("pause-test"), check.IsNil)
+	c.Assert(ResumePausedSink("pause-test"), check.IsFalse)
+
+	// a failing flush keeps the rows not flushed before the resolved ts
+	go func() {
+		_, err := sink.FlushRowChangedEvents(ctx, 15)
+		done <- err
+	}()
+	paused = waitPausedSinkError(c, "pause-test")
+	c.Assert(paused.Operation, check.Equals, pausedOperationFlush)
+	c.Assert(paused.ResolvedTs, check.Equals, uint64(15))
+	c.Assert(paused.Rows, check.DeepEquals, rows[:1])
+	c.Assert(paused.Error, check.Equals, "injected flush error")
+	c.Assert(ResumePausedSink("pause-test"), check.IsTrue)
+	c.Assert(<-done, check.IsNil)
+	c.Assert(sink.pending, check.DeepEquals, rows[1:])
+
+	checkpointTs, err := sink.FlushRowChangedEvents(ctx, 20)
+	c.Assert(err, check.IsNil)
+	c.Assert(checkpointTs, check.Equals, uint64(20))
+	c.Assert(sink.pending, check.HasLen, 0)
+}
+
+func (s *pauseOnErrorSuite) TestPauseExecDMLs(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, check.IsNil)
+	ms := newMySQLSink4Test(c)
+	ms.db = db
+	ms.pauser = newErrorPauser("pause-exec-test")
+	defer ms.pauser.close()
+	insertSQL := "INSERT INTO `s`.`t`(`id`) VALUES (?);"
+	dmls := &preparedDMLs{sqls: []string{insertSQL}, values: [][]interface{}{{1}}, rowCount: 1}
+	rows := []*model.RowChangedEvent{newQuarantineTestRow(2, 10)}
+
+	// the first failure pauses the transaction, and the retries are not used up while paused
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec(insertSQL).WithArgs(1).WillReturnError(errors.New("duplicate entry"))
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(insertSQL).WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	done := make(chan error, 1)
+	go func() {
+		done <- ms.execDMLWithMaxRetries(ctx, rows, dmls, 1, 0)
+	}()
+	for i := 0; i < 2; i++ {
+		paused := waitPausedSinkError(c, "pause-exec-test")
+		c.Assert(paused.Operation, check.Equals, pausedOperationExecDMLs)
+		c.Assert(paused.Rows, check.DeepEquals, rows)
+		c.Assert(paused.Error, check.Matches, ".*duplicate entry.*")
+		assertNotReturned(c, done)
+		c.Assert(ResumePausedSink("pause-exec-test"), check.IsTrue)
+	}
+	c.Assert(<-done, check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// the downstream refusing the writes is returned to pause the changefeed
+	mock.ExpectBegin()
+	mock.ExpectExec(insertSQL).WithArgs(1).WillReturnError(errReadOnly)
+	err = ms.execDMLWithMaxRetries(ctx, rows, dmls, 1, 0)
+	c.Assert(cerror.ErrDownstreamUnwritable.Equal(err), check.IsTrue)
+	c.Assert(GetPausedSinkError("pause-exec-test"), check.IsNil)
+
+	// the paused write quits when the sink is closed
+	mock.ExpectBegin()
+	mock.ExpectExec(insertSQL).WithArgs(1).WillReturnError(errors.New("duplicate entry"))
+	go func() {
+		done <- ms.execDMLWithMaxRetries(ctx, rows, dmls, 1, 0)
+	}()
+	waitPausedSinkError(c, "pause-exec-test")
+	cancel()
+	c.Assert(errors.Cause(<-done), check.Equals, context.Canceled)
+	ms.pauser.close()
+	c.Assert(GetPausedSinkError("pause-exec-test"), check.IsNil)
+}
+
+func (s *pauseOnErrorSuite) TestPauseOnAsyncError(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink := newPauseOnErrorSink("pause-async-test", &failingSink{})
+	defer sink.Close() //nolint:errcheck
+	sinkErrCh, errCh := make(chan error, 1), make(chan error, 1)
+	go sink.pauser.forwardErrors(ctx, sinkErrCh, errCh)
+
+	// the error of a producer pauses the sink, and fails the changefeed once resumed
+	sinkErrCh <- errors.New("injected producer error")
+	paused := waitPausedSinkError(c, "pause-async-test")
+	c.Assert(paused.Operation, check.Equals, pausedOperationAsync)
+	c.Assert(paused.Error, check.Equals, "injected producer error")
+	done := make(chan error, 1)
+	go func() {
+		done <- sink.EmitRowChangedEvents(ctx, newQuarantineTestRow(1, 10))
+	}()
+	assertNotReturned(c, done)
+	c.Assert(errCh, check.HasLen, 0)
+	c.Assert(ResumePausedSink("pause-async-test"), check.IsTrue)
+	c.Assert(<-errCh, check.ErrorMatches, "injected producer error")
+	c.Assert(<-done, check.IsNil)
+
+	// the downstream refusing the writes is forwarded without pausing
+	sinkErrCh <- cerror.ErrDownstreamUnwritable.GenWithStackByArgs("read-only")
+	c.Assert(cerror.ErrDownstreamUnwritable.Equal(<-errCh), check.IsTrue)
+	c.Assert(GetPausedSinkError("pause-async-test"), check.IsNil)
+}
+
+func (s *pauseOnErrorSuite) TestPauseSeveralSinksOfChangefeed(c *check.C) {
+	ctx := context.Background()
+	first := newPauseOnErrorSink("pause-several-test", &failingSink{emitFailures: 1})
+	defer first.Close() //nolint:errcheck
+	second := newPauseOnErrorSink("pause-several-test", &failingSink{emitFailures: 1})
+	defer second.Close() //nolint:errcheck
+
+	done := make(chan error, 2)
+	go func() {
+		done <- first.EmitRowChangedEvents(ctx, newQuarantineTestRow(1, 10))
+	}()
+	paused := waitPausedSinkError(c, "pause-several-test")
+	go func() {
+		done <- second.EmitRowChangedEvents(ctx, newQuarantineTestRow(2, 20))
+	}()
+	for i := 0; i < 100; i++ {
+		second.pauser.mu.Lock()
+		secondPaused := second.pauser.paused != nil
+		second.pauser.mu.Unlock()
+		if secondPaused {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the earliest error is reported, and the pause is reported as the warning of every sink
+	c.Assert(GetPausedSinkError("pause-several-test"), check.Equals, paused)
+	c.Assert(paused.Rows[0].Table.TableID, check.Equals, int64(1))
+	for _, sink := range []*pauseOnErrorSink{first, second} {
+		c.Assert(cerror.ErrSinkPausedOnError.Equal(Warning(sink)), check.IsTrue)
+	}
+	assertNotReturned(c, done)
+
+	// all the paused sinks are resumed together
+	c.Assert(ResumePausedSink("pause-several-test"), check.IsTrue)
+	c.Assert(<-done, check.IsNil)
+	c.Assert(<-done, check.IsNil)
+	c.Assert(GetPausedSinkError("pause-several-test"), check.IsNil)
+	c.Assert(Warning(first), check.IsNil)
+	c.Assert(ResumePausedSink("pause-several-test"), check.IsFalse)
+}
+
+func (s *pauseOnErrorSuite) TestPausedSinkWarning(c *check.C) {
+	inner := &failingSink{}
+	sink := newPauseOnErrorSink("pause-warning-test", inner)
+	defer sink.Close() //nolint:errcheck
+	c.Assert(Warning(sink), check.IsNil)
+	inner.warning = cerror.ErrKafkaProbeFailed.GenWithStack("injected probe error")
+	c.Assert(Warning(sink), check.Equals, inner.warning)
+	c.Assert(Warning(&blackHoleSink{}), check.IsNil)
+}
+
+func (s *pauseOnErrorSuite) TestNewPauseOnErrorSink(c *check.C) {
+	ctx := context.Background()
+	cfg := config.GetDefaultReplicaConfig()
+	sink, err := NewSink(ctx, "pause-test", "blackhole://", nil, cfg, map[string]string{}, make(chan error))
+	c.Assert(err, check.IsNil)
+	c.Assert(sink, check.FitsTypeOf, &blackHoleSink{})
+
+	cfg.Debug.PauseOnFirstError = true
+	sink, err = NewSink(ctx, "pause-test", "blackhole://", nil, cfg, map[string]string{}, make(chan error))
+	c.Assert(err, check.IsNil)
+	c.Assert(sink, check.FitsTypeOf, &pauseOnErrorSink{})
+	c.Assert(sink.Close(), check.IsNil)
+
+	// the quarantine never returns the errors of the tables to pause on
+	cfg.Sink.TableErrorPolicy = config.TableErrorPolicyQuarantine
+	_, err = NewSink(ctx, "pause-test", "blackhole://", nil, cfg, map[string]string{}, make(chan error))
+	c.Assert(cerror.ErrDebugConfigInvalid.Equal(err), check.IsTrue)
+}
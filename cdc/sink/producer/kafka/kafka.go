@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/notify"
 	"github.com/pingcap/ticdc/pkg/security"
@@ -80,7 +81,7 @@ type kafkaSaramaProducer struct {
 	closed  int32
 }
 
-func (k *kafkaSaramaProducer) SendMessage(ctx context.Context, key []byte, value []byte, partition int32) error {
+func (k *kafkaSaramaProducer) SendMessage(ctx context.Context, key []byte, value []byte, headers []producer.MessageHeader, partition int32) error {
 	k.clientLock.RLock()
 	defer k.clientLock.RUnlock()
 	msg := &sarama.ProducerMessage{
@@ -89,6 +90,12 @@ func (k *kafkaSaramaProducer) SendMessage(ctx context.Context, key []byte, value
 		Value:     sarama.ByteEncoder(value),
 		Partition: partition,
 	}
+	if len(headers) != 0 {
+		msg.Headers = make([]sarama.RecordHeader, 0, len(headers))
+		for _, header := range headers {
+			msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(header.Key), Value: header.Value})
+		}
+	}
 	msg.Metadata = atomic.AddUint64(&k.partitionOffset[partition].sent, 1)
 
 	failpoint.Inject("KafkaSinkAsyncSendError", func() {
@@ -14,9 +14,13 @@
 package kafka
 
 import (
+	"context"
 	"testing"
 
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
 	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
 )
 
 type kafkaSuite struct{}
@@ -50,3 +54,41 @@ func (s *kafkaSuite) TestClientID(c *check.C) {
 		}
 	}
 }
+
+func (s *kafkaSuite) TestSendMessageWithHeaders(c *check.C) {
+	cfg := sarama.NewConfig()
+	cfg.Producer.Return.Successes = true
+	asyncClient := mocks.NewAsyncProducer(c, cfg)
+	defer asyncClient.Close() //nolint:errcheck
+	k := &kafkaSaramaProducer{
+		asyncClient:  asyncClient,
+		topic:        "test-topic",
+		partitionNum: 2,
+		partitionOffset: make([]struct {
+			flushed uint64
+			sent    uint64
+		}, 2),
+		closeCh: make(chan struct{}),
+	}
+
+	ctx := context.Background()
+	asyncClient.ExpectInputAndSucceed()
+	err := k.SendMessage(ctx, []byte("key"), []byte("value"), []producer.MessageHeader{
+		{Key: "schema", Value: []byte("test")},
+		{Key: "commit-ts", Value: []byte("101")},
+	}, 1)
+	c.Assert(err, check.IsNil)
+	msg := <-asyncClient.Successes()
+	c.Assert(msg.Topic, check.Equals, "test-topic")
+	c.Assert(msg.Partition, check.Equals, int32(1))
+	c.Assert(msg.Headers, check.DeepEquals, []sarama.RecordHeader{
+		{Key: []byte("schema"), Value: []byte("test")},
+		{Key: []byte("commit-ts"), Value: []byte("101")},
+	})
+
+	asyncClient.ExpectInputAndSucceed()
+	err = k.SendMessage(ctx, []byte("key"), []byte("value"), nil, 0)
+	c.Assert(err, check.IsNil)
+	msg = <-asyncClient.Successes()
+	c.Assert(msg.Headers, check.HasLen, 0)
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	probeRoundTripHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "ticdc",
+			Subsystem: "sink",
+			Name:      "kafka_probe_round_trip_duration",
+			Help:      "Bucketed histogram of the round trip time (s) of the Kafka delivery probes.",
+			Buckets:   prometheus.ExponentialBuckets(0.002 /* 2 ms */, 2, 16),
+		}, []string{"capture", "changefeed"})
+	probeFailureCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "sink",
+			Name:      "kafka_probe_failure_count",
+			Help:      "total count of the failed Kafka delivery probes",
+		}, []string{"capture", "changefeed"})
+)
+
+// InitMetrics registers all metrics in this file
+func InitMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(probeRoundTripHistogram)
+	registry.MustRegister(probeFailureCounter)
+}
@@ -0,0 +1,277 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	probePartition      = int32(0)
+	probeKey            = "ticdc-probe"
+	defaultProbeTimeout = 10 * time.Second
+)
+
+// Prober periodically produces a sentinel message to the probe topic and consumes it back
+// to check the end-to-end delivery of the Kafka cluster. It's a health probe and never
+// blocks or fails the replication, the error of the last probe is reported by Err.
+type Prober struct {
+	topic        string
+	interval     time.Duration
+	timeout      time.Duration
+	changefeedID string
+	seq          uint64
+
+	client   sarama.Client
+	producer sarama.SyncProducer
+	consumer sarama.Consumer
+
+	mu sync.Mutex
+	// partitionConsumer consumes the sentinels of all the probes, it's created at the offset of
+	// the first sentinel and created again if it's closed
+	partitionConsumer sarama.PartitionConsumer
+	lastErr           error
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+
+	metricRoundTrip prometheus.Observer
+	metricFailures  prometheus.Counter
+}
+
+// NewProber creates a Prober sending the sentinel messages to the first partition of the probe topic,
+// the topic is created if it doesn't exist.
+func NewProber(ctx context.Context, address string, topic string, config Config, interval time.Duration) (*Prober, error) {
+	cfg, err := newSaramaConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	addrs := strings.Split(address, ",")
+	admin, err := sarama.NewClusterAdmin(addrs, cfg)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+	}
+	topics, err := admin.ListTopics()
+	if err != nil {
+		_ = admin.Close()
+		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+	}
+	if _, exist := topics[topic]; !exist {
+		log.Info("create the probe topic", zap.String("topic", topic), zap.Int16("replication_factor", config.ReplicationFactor))
+		err := admin.CreateTopic(topic, &sarama.TopicDetail{
+			NumPartitions:     1,
+			ReplicationFactor: config.ReplicationFactor,
+		}, false)
+		if err != nil {
+			_ = admin.Close()
+			return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+		}
+	}
+	if err := admin.Close(); err != nil {
+		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+	}
+	return newProber(ctx, addrs, topic, cfg, interval)
+}
+
+func newProber(ctx context.Context, addrs []string, topic string, cfg *sarama.Config, interval time.Duration) (*Prober, error) {
+	client, err := sarama.NewClient(addrs, cfg)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		_ = producer.Close()
+		_ = client.Close()
+		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+	}
+	timeout := defaultProbeTimeout
+	if timeout > interval {
+		timeout = interval
+	}
+	captureAddr := util.CaptureAddrFromCtx(ctx)
+	changefeedID := util.ChangefeedIDFromCtx(ctx)
+	return &Prober{
+		topic:           topic,
+		interval:        interval,
+		timeout:         timeout,
+		changefeedID:    changefeedID,
+		client:          client,
+		producer:        producer,
+		consumer:        consumer,
+		closeCh:         make(chan struct{}),
+		metricRoundTrip: probeRoundTripHistogram.WithLabelValues(captureAddr, changefeedID),
+		metricFailures:  probeFailureCounter.WithLabelValues(captureAddr, changefeedID),
+	}, nil
+}
+
+// Run probes the delivery every interval until the context is canceled or the Prober is closed,
+// the failed probes are logged, counted and reported by Err.
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.closeCh:
+			return
+		case <-ticker.C:
+		}
+		err := p.probe(ctx)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.closeCh:
+				return
+			default:
+			}
+		}
+		p.report(err)
+	}
+}
+
+// report records the result of a probe
+func (p *Prober) report(err error) {
+	p.mu.Lock()
+	p.lastErr = err
+	p.mu.Unlock()
+	if err != nil {
+		p.metricFailures.Inc()
+		log.Warn("the end-to-end delivery of the kafka sink may be broken",
+			zap.String("changefeed", p.changefeedID), zap.String("topic", p.topic), zap.Error(err))
+	}
+}
+
+// Err returns the error of the last probe, it's nil if the last probe succeeded
+func (p *Prober) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastErr
+}
+
+// messages returns the messages of the partition consumer, which starts from the offset if it
+// doesn't exist
+func (p *Prober) messages(offset int64) (<-chan *sarama.ConsumerMessage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.partitionConsumer == nil {
+		pc, err := p.consumer.ConsumePartition(p.topic, probePartition, offset)
+		if err != nil {
+			return nil, err
+		}
+		p.partitionConsumer = pc
+	}
+	return p.partitionConsumer.Messages(), nil
+}
+
+// resetPartitionConsumer closes the partition consumer, it's created again by the next probe
+func (p *Prober) resetPartitionConsumer() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.partitionConsumer != nil {
+		p.partitionConsumer.AsyncClose()
+		p.partitionConsumer = nil
+	}
+}
+
+// probe produces a sentinel message and waits until it's consumed back
+func (p *Prober) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	p.seq++
+	value := fmt.Sprintf("%s/%d", p.changefeedID, p.seq)
+	start := time.Now()
+	_, offset, err := p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:     p.topic,
+		Key:       sarama.StringEncoder(probeKey),
+		Value:     sarama.StringEncoder(value),
+		Partition: probePartition,
+	})
+	if err != nil {
+		return cerror.WrapError(cerror.ErrKafkaProbeFailed, err)
+	}
+	messages, err := p.messages(offset)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrKafkaProbeFailed, err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return cerror.ErrKafkaProbeFailed.GenWithStack(
+					"the sentinel message at offset %d isn't consumed back in %s", offset, p.timeout)
+			}
+			return errors.Trace(ctx.Err())
+		case msg, ok := <-messages:
+			if !ok {
+				p.resetPartitionConsumer()
+				return cerror.ErrKafkaProbeFailed.GenWithStack("the probe consumer is closed")
+			}
+			// the sentinels of the timed out probes may arrive late
+			if msg.Offset < offset {
+				continue
+			}
+			if msg.Offset > offset || string(msg.Value) != value {
+				// the consumer skips the sentinel, consume from the offset of the next sentinel
+				p.resetPartitionConsumer()
+				return cerror.ErrKafkaProbeFailed.GenWithStack(
+					"unexpected message at offset %d, value %q", msg.Offset, msg.Value)
+			}
+			p.metricRoundTrip.Observe(time.Since(start).Seconds())
+			return nil
+		}
+	}
+}
+
+// Close stops the Prober and releases its clients
+func (p *Prober) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.closeCh)
+		p.mu.Lock()
+		if p.partitionConsumer != nil {
+			err = p.partitionConsumer.Close()
+			p.partitionConsumer = nil
+		}
+		p.mu.Unlock()
+		if err1 := p.consumer.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+		if err1 := p.producer.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+		if err1 := p.client.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+	})
+	return errors.Trace(err)
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pingcap/check"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newProbeTestBroker creates a mock broker which stores no message, the fetch responses carry the sentinel
+// at offset 0 if it's not empty.
+func newProbeTestBroker(c *check.C, topic string, sentinel string) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(c, 1)
+	fetch := sarama.NewMockFetchResponse(c, 1)
+	if sentinel != "" {
+		fetch.SetMessage(topic, probePartition, 0, sarama.StringEncoder(sentinel))
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(c).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, probePartition, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(c),
+		"OffsetRequest": sarama.NewMockOffsetResponse(c).
+			SetOffset(topic, probePartition, sarama.OffsetOldest, 0).
+			SetOffset(topic, probePartition, sarama.OffsetNewest, 1),
+		"FetchRequest": fetch,
+	})
+	return broker
+}
+
+func newProbeTestConfig() *sarama.Config {
+	cfg := sarama.NewConfig()
+	cfg.Producer.Partitioner = sarama.NewManualPartitioner
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Retry.Max = 0
+	cfg.Metadata.Retry.Max = 0
+	return cfg
+}
+
+func (s *kafkaSuite) TestProbeRoundTrip(c *check.C) {
+	const topic = "probe-topic"
+	ctx := util.PutChangefeedIDInCtx(context.Background(), "probe-round-trip")
+	broker := newProbeTestBroker(c, topic, "probe-round-trip/1")
+	defer broker.Close()
+
+	p, err := newProber(ctx, []string{broker.Addr()}, topic, newProbeTestConfig(), time.Second)
+	c.Assert(err, check.IsNil)
+	defer p.Close() //nolint:errcheck
+
+	err = p.probe(ctx)
+	c.Assert(err, check.IsNil)
+	p.report(err)
+	c.Assert(p.Err(), check.IsNil)
+	c.Assert(testutil.ToFloat64(p.metricFailures), check.Equals, float64(0))
+	pc := p.partitionConsumer
+	c.Assert(pc, check.NotNil)
+
+	// the probes share the partition consumer, which has consumed the first sentinel, the second
+	// sentinel isn't consumed back since the mock broker stores no message
+	p.timeout = 500 * time.Millisecond
+	err = p.probe(ctx)
+	c.Assert(err, check.ErrorMatches, ".*isn't consumed back in 500ms.*")
+	c.Assert(p.partitionConsumer, check.Equals, pc)
+	p.report(err)
+	c.Assert(p.Err(), check.Equals, err)
+}
+
+func (s *kafkaSuite) TestProbeTimeout(c *check.C) {
+	const topic = "probe-topic"
+	ctx := util.PutChangefeedIDInCtx(context.Background(), "probe-timeout")
+	// the sentinel is lost
+	broker := newProbeTestBroker(c, topic, "")
+	defer broker.Close()
+
+	p, err := newProber(ctx, []string{broker.Addr()}, topic, newProbeTestConfig(), 200*time.Millisecond)
+	c.Assert(err, check.IsNil)
+	c.Assert(p.probe(ctx), check.ErrorMatches, ".*isn't consumed back in 200ms.*")
+
+	// the failed probes are counted and reported by Run and never stop it
+	cctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		p.Run(cctx)
+		close(done)
+	}()
+	for testutil.ToFloat64(p.metricFailures) < 2 {
+		time.Sleep(50 * time.Millisecond)
+	}
+	c.Assert(p.Err(), check.ErrorMatches, ".*isn't consumed back in 200ms.*")
+	c.Assert(p.Close(), check.IsNil)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("the prober isn't stopped by Close")
+	}
+	cancel()
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/pingcap/ticdc/cdc/sink/producer"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+const (
+	defaultPartitionNum = 1
+	defaultCapacity     = 4096
+)
+
+// Producer sends the messages to an embedded Queue, it's used by the tests
+// to consume the output of a changefeed without any external MQ.
+type Producer struct {
+	queue        *Queue
+	partitionNum int32
+	closed       int32
+}
+
+// NewProducer creates a producer of the queue named by the host of the sink uri,
+// e.g. memory://test-queue?partition-num=4&capacity=1024
+func NewProducer(sinkURI *url.URL) (*Producer, error) {
+	name := sinkURI.Host
+	if name == "" {
+		return nil, cerror.ErrMemoryQueueInvalidConfig.GenWithStack("the name of the memory queue is required")
+	}
+	partitionNum := defaultPartitionNum
+	capacity := defaultCapacity
+	var err error
+	if s := sinkURI.Query().Get("partition-num"); s != "" {
+		partitionNum, err = strconv.Atoi(s)
+		if err != nil || partitionNum <= 0 {
+			return nil, cerror.ErrMemoryQueueInvalidConfig.GenWithStack("invalid partition-num %s", s)
+		}
+	}
+	if s := sinkURI.Query().Get("capacity"); s != "" {
+		capacity, err = strconv.Atoi(s)
+		if err != nil || capacity <= 0 {
+			return nil, cerror.ErrMemoryQueueInvalidConfig.GenWithStack("invalid capacity %s", s)
+		}
+	}
+	return &Producer{
+		queue:        acquireQueue(name, capacity),
+		partitionNum: int32(partitionNum),
+	}, nil
+}
+
+// SendMessage sends the message to the queue, it's dropped if the queue is full
+func (p *Producer) SendMessage(ctx context.Context, key []byte, value []byte, headers []producer.MessageHeader, partition int32) error {
+	if atomic.LoadInt32(&p.closed) == 1 {
+		return nil
+	}
+	p.queue.push(&Message{Key: key, Value: value, Headers: headers, Partition: partition})
+	return nil
+}
+
+// SyncBroadcastMessage sends the message to every partition of the queue
+func (p *Producer) SyncBroadcastMessage(ctx context.Context, key []byte, value []byte) error {
+	for i := int32(0); i < p.partitionNum; i++ {
+		if err := p.SendMessage(ctx, key, value, nil, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush returns immediately since the messages are sent synchronously
+func (p *Producer) Flush(ctx context.Context) error {
+	return nil
+}
+
+// GetPartitionNum returns the partition number of the queue
+func (p *Producer) GetPartitionNum() int32 {
+	return p.partitionNum
+}
+
+// Close closes the producer, the queue is removed once all the producers sending to it are closed,
+// so the consumers should look up the queue before the sink is closed
+func (p *Producer) Close() error {
+	if atomic.CompareAndSwapInt32(&p.closed, 0, 1) {
+		releaseQueue(p.queue)
+	}
+	return nil
+}
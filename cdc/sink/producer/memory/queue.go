@@ -0,0 +1,144 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
+	"go.uber.org/zap"
+)
+
+// Message is an encoded message sent to the queue
+type Message struct {
+	Key       []byte                   `json:"key"`
+	Value     []byte                   `json:"value"`
+	Headers   []producer.MessageHeader `json:"headers,omitempty"`
+	Partition int32                    `json:"partition"`
+}
+
+// Queue is a bounded in-memory queue of the messages, the messages are dropped when it's full
+type Queue struct {
+	name     string
+	capacity int
+	dropped  uint64
+
+	mu   sync.Mutex
+	msgs []*Message
+	// refs is the number of the producers sending to the queue, guarded by queuesMu
+	refs int
+}
+
+var (
+	queuesMu sync.Mutex
+	queues   = make(map[string]*Queue)
+)
+
+// GetQueue returns the queue with the name, the queue is created with the capacity if it doesn't exist
+func GetQueue(name string, capacity int) *Queue {
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+	return getQueueLocked(name, capacity)
+}
+
+func getQueueLocked(name string, capacity int) *Queue {
+	if q, ok := queues[name]; ok {
+		return q
+	}
+	q := &Queue{name: name, capacity: capacity}
+	queues[name] = q
+	return q
+}
+
+// acquireQueue returns the queue with the name for a producer, the queue is removed
+// once all the producers sending to it are closed
+func acquireQueue(name string, capacity int) *Queue {
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+	q := getQueueLocked(name, capacity)
+	q.refs++
+	return q
+}
+
+func releaseQueue(q *Queue) {
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+	q.refs--
+	if q.refs <= 0 && queues[q.name] == q {
+		delete(queues, q.name)
+	}
+}
+
+// LookupQueue returns the queue with the name if it exists
+func LookupQueue(name string) (*Queue, bool) {
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+	q, ok := queues[name]
+	return q, ok
+}
+
+// RemoveQueue removes the queue with the name, the messages in the queue are discarded
+func RemoveQueue(name string) {
+	queuesMu.Lock()
+	defer queuesMu.Unlock()
+	delete(queues, name)
+}
+
+// Poll returns and removes at most limit messages in the queue without waiting
+func (q *Queue) Poll(limit int) []*Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := q.peekLocked(limit)
+	q.msgs = q.msgs[len(msgs):]
+	return msgs
+}
+
+// Peek returns at most limit messages in the queue without removing them
+func (q *Queue) Peek(limit int) []*Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.peekLocked(limit)
+}
+
+func (q *Queue) peekLocked(limit int) []*Message {
+	if limit > len(q.msgs) {
+		limit = len(q.msgs)
+	}
+	msgs := make([]*Message, limit)
+	copy(msgs, q.msgs)
+	return msgs
+}
+
+// Dropped returns the number of the messages dropped because the queue was full
+func (q *Queue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+func (q *Queue) push(msg *Message) {
+	q.mu.Lock()
+	if len(q.msgs) < q.capacity {
+		q.msgs = append(q.msgs, msg)
+		q.mu.Unlock()
+		return
+	}
+	q.mu.Unlock()
+	dropped := atomic.AddUint64(&q.dropped, 1)
+	// only the first drop of every 1024 is logged to avoid flooding the log
+	if dropped%1024 == 1 {
+		log.Warn("memory queue is full, drop the message",
+			zap.String("queue", q.name), zap.Int("capacity", q.capacity), zap.Uint64("dropped", dropped))
+	}
+}
@@ -17,9 +17,15 @@ import (
 	"context"
 )
 
+// MessageHeader is a key-value pair attached to a message
+type MessageHeader struct {
+	Key   string
+	Value []byte
+}
+
 // Producer is a interface of mq producer
 type Producer interface {
-	SendMessage(ctx context.Context, key []byte, value []byte, partition int32) error
+	SendMessage(ctx context.Context, key []byte, value []byte, headers []MessageHeader, partition int32) error
 	SyncBroadcastMessage(ctx context.Context, key []byte, value []byte) error
 	Flush(ctx context.Context) error
 	GetPartitionNum() int32
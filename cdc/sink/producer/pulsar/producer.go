@@ -19,6 +19,7 @@ import (
 	"strconv"
 
 	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/pingcap/ticdc/cdc/sink/producer"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 )
 
@@ -60,8 +61,8 @@ type Producer struct {
 	partitions int
 }
 
-// SendMessage send key-value msg to target partition.
-func (p *Producer) SendMessage(ctx context.Context, key []byte, value []byte, partition int32) error {
+// SendMessage send key-value msg to target partition, the headers are ignored.
+func (p *Producer) SendMessage(ctx context.Context, key []byte, value []byte, headers []producer.MessageHeader, partition int32) error {
 	p.producer.SendAsync(ctx, &pulsar.ProducerMessage{
 		Payload:    value,
 		Key:        string(key),
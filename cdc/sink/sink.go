@@ -29,6 +29,7 @@ import (
 const (
 	OptChangefeedID = "_changefeed_id"
 	OptCaptureAddr  = "_capture_addr"
+	OptCaptureID    = "_capture_id"
 )
 
 // Sink is an abstraction for anything that a changefeed may emit into.
@@ -55,8 +56,45 @@ type Sink interface {
 	Close() error
 }
 
+// warner is implemented by the sinks detecting the problems which don't fail the changefeed
+type warner interface {
+	// Warning returns the current problem of the sink, nil if there is none
+	Warning() error
+}
+
+// Warning returns the current problem of the sink which doesn't fail the changefeed, such as
+// a failed delivery probe of the Kafka sink, nil if there is none
+func Warning(s Sink) error {
+	if w, ok := s.(warner); ok {
+		return w.Warning()
+	}
+	return nil
+}
+
 // NewSink creates a new sink with the sink-uri
 func NewSink(ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr string, filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string, errCh chan error) (Sink, error) {
+	if config.Debug == nil || !config.Debug.PauseOnFirstError {
+		return newSink(ctx, changefeedID, sinkURIStr, filter, config, opts, errCh)
+	}
+	if err := config.Debug.Validate(config.Sink); err != nil {
+		return nil, err
+	}
+	sinkErrCh := make(chan error, 1)
+	s, err := newSink(ctx, changefeedID, sinkURIStr, filter, config, opts, sinkErrCh)
+	if err != nil {
+		return nil, err
+	}
+	// the mysql sink pauses in the workers, which retry the failing transactions after resumed
+	if _, ok := s.(*mysqlSink); ok {
+		return s, nil
+	}
+	sink := newPauseOnErrorSink(changefeedID, s)
+	// the asynchronous errors such as the errors of the MQ producers pause the sink too
+	go sink.pauser.forwardErrors(ctx, sinkErrCh, errCh)
+	return sink, nil
+}
+
+func newSink(ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr string, filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string, errCh chan error) (Sink, error) {
 	// parse sinkURI as a URI
 	sinkURI, err := url.Parse(sinkURIStr)
 	if err != nil {
@@ -71,6 +109,8 @@ func NewSink(ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr st
 		return newKafkaSaramaSink(ctx, sinkURI, filter, config, opts, errCh)
 	case "pulsar", "pulsar+ssl":
 		return newPulsarSink(ctx, sinkURI, filter, config, opts, errCh)
+	case "memory":
+		return newMemorySink(ctx, sinkURI, filter, config, opts, errCh)
 	case "local":
 		return cdclog.NewLocalFileSink(ctx, sinkURI, errCh)
 	case "s3":
@@ -38,6 +38,8 @@ func NewStatistics(ctx context.Context, name string, opts map[string]string) *St
 	statistics.metricExecTxnHis = execTxnHistogram.WithLabelValues(statistics.captureAddr, statistics.changefeedID)
 	statistics.metricExecBatchHis = execBatchHistogram.WithLabelValues(statistics.captureAddr, statistics.changefeedID)
 	statistics.metricExecErrCnt = executionErrorCounter.WithLabelValues(statistics.captureAddr, statistics.changefeedID)
+	statistics.metricFilteredRowsCnt = filteredRowsCounter.WithLabelValues(statistics.captureAddr, statistics.changefeedID)
+	statistics.metricFallbackRowsCnt = fallbackEncodedRowsCounter.WithLabelValues(statistics.captureAddr, statistics.changefeedID)
 
 	// Flush metrics in background for better accuracy and efficiency.
 	ticker := time.NewTicker(flushMetricsInterval)
@@ -73,6 +75,9 @@ type Statistics struct {
 	metricExecTxnHis   prometheus.Observer
 	metricExecBatchHis prometheus.Observer
 	metricExecErrCnt   prometheus.Counter
+
+	metricFilteredRowsCnt prometheus.Counter
+	metricFallbackRowsCnt prometheus.Counter
 }
 
 // AddRowsCount records total number of rows needs to flush
@@ -85,6 +90,16 @@ func (b *Statistics) SubRowsCount(count int) {
 	atomic.AddUint64(&b.totalRows, ^uint64(count-1))
 }
 
+// AddFilteredRowsCount records total number of rows dropped by the filter
+func (b *Statistics) AddFilteredRowsCount(count int) {
+	b.metricFilteredRowsCnt.Add(float64(count))
+}
+
+// AddFallbackRowsCount records total number of rows encoded by the fallback protocol
+func (b *Statistics) AddFallbackRowsCount(count int) {
+	b.metricFallbackRowsCnt.Add(float64(count))
+}
+
 // RecordBatchExecution records the cost time of batch execution and batch size
 func (b *Statistics) RecordBatchExecution(executer func() (int, error)) error {
 	startTime := time.Now()
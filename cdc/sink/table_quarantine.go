@@ -0,0 +1,186 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/ticdc/cdc/model"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// TableQuarantiner is implemented by the sinks which quarantine the tables whose rows
+// still fail to be written after the retries, so that the other tables keep replicating.
+type TableQuarantiner interface {
+	// QuarantinedTables returns the tables quarantined by the sink
+	QuarantinedTables() []*model.QuarantinedTable
+	// QuarantineTable quarantines a table, e.g. a table quarantined before the sink is created
+	QuarantineTable(table *model.QuarantinedTable)
+	// ReleaseTable replicates the rows of a quarantined table again. The rows discarded while
+	// the table is quarantined are not kept, they're replicated only if the table is re-scanned
+	ReleaseTable(tableID model.TableID)
+}
+
+// tableQuarantine discards the rows of the quarantined tables before they are written
+type tableQuarantine struct {
+	captureID string
+
+	mu     sync.Mutex
+	tables map[model.TableID]*model.QuarantinedTable
+	// discarded counts the rows discarded of every quarantined table, only the first one is logged
+	discarded map[model.TableID]uint64
+
+	metricQuarantinedRows prometheus.Counter
+}
+
+func newTableQuarantine(captureID string, metricQuarantinedRows prometheus.Counter) *tableQuarantine {
+	return &tableQuarantine{
+		captureID:             captureID,
+		tables:                make(map[model.TableID]*model.QuarantinedTable),
+		discarded:             make(map[model.TableID]uint64),
+		metricQuarantinedRows: metricQuarantinedRows,
+	}
+}
+
+// wrap returns an execDMLs which skips the rows of the quarantined tables, and quarantines
+// the tables whose rows execDMLs fails to write instead of returning the error.
+func (q *tableQuarantine) wrap(
+	execDMLs func(context.Context, []*model.RowChangedEvent, uint64, int) error,
+) func(context.Context, []*model.RowChangedEvent, uint64, int) error {
+	return func(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+		rows = q.discard(rows)
+		if len(rows) == 0 {
+			return nil
+		}
+		err := execDMLs(ctx, rows, replicaID, bucket)
+		if err == nil || errors.Cause(err) == context.Canceled || cerror.ErrDownstreamUnwritable.Equal(err) {
+			// the downstream refusing the writes is not the fault of the tables
+			return err
+		}
+		groups := groupRowsByTable(rows)
+		if len(groups) == 1 {
+			q.quarantine(rows[0], err)
+			return nil
+		}
+		// the batch mixes the rows of several tables, write the tables one by one to find the failing ones
+		for _, tableRows := range groups {
+			err := execDMLs(ctx, tableRows, replicaID, bucket)
+			if err == nil {
+				continue
+			}
+			if errors.Cause(err) == context.Canceled || cerror.ErrDownstreamUnwritable.Equal(err) {
+				return err
+			}
+			q.quarantine(tableRows[0], err)
+		}
+		return nil
+	}
+}
+
+// discard removes the rows of the quarantined tables. The first row discarded of every table is
+// logged, the others are counted, so that a hot table doesn't flood the log.
+func (q *tableQuarantine) discard(rows []*model.RowChangedEvent) []*model.RowChangedEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.tables) == 0 {
+		return rows
+	}
+	kept := make([]*model.RowChangedEvent, 0, len(rows))
+	for _, row := range rows {
+		if _, ok := q.tables[row.Table.TableID]; !ok {
+			kept = append(kept, row)
+			continue
+		}
+		if q.discarded[row.Table.TableID] == 0 {
+			// the row values may be sensitive, only the position of the row is logged
+			log.Warn("discard the rows of the quarantined table, the later rows are counted only",
+				zap.Stringer("table", row.Table), zap.Int64("tableID", row.Table.TableID),
+				zap.Int64("handle", row.RowID), zap.Uint64("commitTs", row.CommitTs))
+		}
+		q.discarded[row.Table.TableID]++
+		q.metricQuarantinedRows.Inc()
+	}
+	return kept
+}
+
+func (q *tableQuarantine) quarantine(row *model.RowChangedEvent, err error) {
+	table := &model.QuarantinedTable{
+		TableID:        row.Table.TableID,
+		Schema:         row.Table.Schema,
+		Table:          row.Table.Table,
+		CommitTs:       row.CommitTs,
+		Error:          err.Error(),
+		CaptureID:      q.captureID,
+		QuarantineTime: time.Now(),
+	}
+	log.Warn("quarantine the table whose rows fail to be written",
+		zap.Stringer("table", row.Table), zap.Int64("tableID", table.TableID),
+		zap.Int64("handle", row.RowID), zap.Uint64("commitTs", table.CommitTs),
+		zap.String("captureID", table.CaptureID), zap.Error(err))
+	q.QuarantineTable(table)
+}
+
+// QuarantinedTables implements the TableQuarantiner interface
+func (q *tableQuarantine) QuarantinedTables() []*model.QuarantinedTable {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tables := make([]*model.QuarantinedTable, 0, len(q.tables))
+	for _, table := range q.tables {
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// QuarantineTable implements the TableQuarantiner interface
+func (q *tableQuarantine) QuarantineTable(table *model.QuarantinedTable) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.tables[table.TableID]; !ok {
+		q.tables[table.TableID] = table
+	}
+}
+
+// ReleaseTable implements the TableQuarantiner interface
+func (q *tableQuarantine) ReleaseTable(tableID model.TableID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.tables[tableID]; ok {
+		log.Info("release the quarantined table", zap.Int64("table-id", tableID),
+			zap.Uint64("discarded-rows", q.discarded[tableID]))
+		delete(q.tables, tableID)
+		delete(q.discarded, tableID)
+	}
+}
+
+// groupRowsByTable splits the rows by table and keeps the order of the rows of every table
+func groupRowsByTable(rows []*model.RowChangedEvent) [][]*model.RowChangedEvent {
+	var groups [][]*model.RowChangedEvent
+	index := make(map[model.TableID]int)
+	for _, row := range rows {
+		i, ok := index[row.Table.TableID]
+		if !ok {
+			i = len(groups)
+			index[row.Table.TableID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+	return groups
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/pkg/notify"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type tableQuarantineSuite struct{}
+
+var _ = check.Suite(&tableQuarantineSuite{})
+
+func newQuarantineTestRow(tableID model.TableID, commitTs uint64) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		CommitTs: commitTs,
+		Table:    &model.TableName{Schema: "test", Table: "t", TableID: tableID},
+	}
+}
+
+func (s *tableQuarantineSuite) TestQuarantineFailingTable(c *check.C) {
+	ctx := context.Background()
+	counter := quarantinedRowsCounter.WithLabelValues("capture", "quarantine-test")
+	q := newTableQuarantine("capture-id", counter)
+
+	// the downstream rejects every batch containing the rows of table 2
+	var written []model.TableID
+	execDMLs := q.wrap(func(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+		for _, row := range rows {
+			if row.Table.TableID == 2 {
+				return errors.New("duplicate entry")
+			}
+		}
+		for _, row := range rows {
+			written = append(written, row.Table.TableID)
+		}
+		return nil
+	})
+
+	err := execDMLs(ctx, []*model.RowChangedEvent{
+		newQuarantineTestRow(1, 10), newQuarantineTestRow(2, 10), newQuarantineTestRow(3, 11), newQuarantineTestRow(1, 12),
+	}, 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(written, check.DeepEquals, []model.TableID{1, 1, 3})
+	tables := q.QuarantinedTables()
+	c.Assert(tables, check.HasLen, 1)
+	c.Assert(tables[0].TableID, check.Equals, model.TableID(2))
+	c.Assert(tables[0].CommitTs, check.Equals, uint64(10))
+	c.Assert(tables[0].CaptureID, check.Equals, "capture-id")
+	c.Assert(tables[0].Error, check.Matches, ".*duplicate entry.*")
+
+	// the rows of the quarantined table are discarded, the others keep flowing
+	written = nil
+	err = execDMLs(ctx, []*model.RowChangedEvent{
+		newQuarantineTestRow(2, 20), newQuarantineTestRow(3, 20), newQuarantineTestRow(2, 21),
+	}, 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(written, check.DeepEquals, []model.TableID{3})
+	c.Assert(testutil.ToFloat64(counter), check.Equals, float64(2))
+	// only the first discarded row of the table is logged, the others are counted
+	c.Assert(q.discarded, check.DeepEquals, map[model.TableID]uint64{2: 2})
+
+	// the released table is written again, and quarantined again since it still fails
+	q.ReleaseTable(2)
+	c.Assert(q.QuarantinedTables(), check.HasLen, 0)
+	c.Assert(q.discarded, check.HasLen, 0)
+	written = nil
+	err = execDMLs(ctx, []*model.RowChangedEvent{newQuarantineTestRow(2, 30)}, 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(written, check.HasLen, 0)
+	c.Assert(q.QuarantinedTables()[0].CommitTs, check.Equals, uint64(30))
+}
+
+func (s *tableQuarantineSuite) TestCanceledIsNotQuarantined(c *check.C) {
+	q := newTableQuarantine("capture-id", quarantinedRowsCounter.WithLabelValues("capture", "quarantine-test"))
+	execDMLs := q.wrap(func(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+		return errors.Trace(context.Canceled)
+	})
+	err := execDMLs(context.Background(), []*model.RowChangedEvent{newQuarantineTestRow(1, 10)}, 0, 0)
+	c.Assert(errors.Cause(err), check.Equals, context.Canceled)
+	c.Assert(q.QuarantinedTables(), check.HasLen, 0)
+}
+
+func (s *tableQuarantineSuite) TestWorkerKeepsFlowing(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := newTableQuarantine("capture-id", quarantinedRowsCounter.WithLabelValues("capture", "quarantine-test"))
+	var writtenRows int64
+	execDMLs := q.wrap(func(ctx context.Context, rows []*model.RowChangedEvent, replicaID uint64, bucket int) error {
+		for _, row := range rows {
+			if row.Table.TableID == 2 {
+				return errors.New("duplicate entry")
+			}
+		}
+		atomic.AddInt64(&writtenRows, int64(len(rows)))
+		return nil
+	})
+
+	notifier := new(notify.Notifier)
+	w := newMySQLSinkWorker(256, 0,
+		bucketSizeCounter.WithLabelValues("capture", "quarantine-test", "0"), notifier.NewReceiver(10*time.Millisecond), execDMLs)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- w.run(ctx)
+	}()
+	for ts := uint64(1); ts <= 4; ts++ {
+		tableID := model.TableID(ts%2 + 1)
+		w.appendTxn(ctx, &model.SingleTableTxn{
+			CommitTs: ts, Table: &model.TableName{TableID: tableID},
+			Rows: []*model.RowChangedEvent{newQuarantineTestRow(tableID, ts)},
+		})
+		w.waitAllTxnsExecuted()
+	}
+	c.Assert(atomic.LoadUint64(&w.checkpointTs), check.Equals, uint64(4))
+	c.Assert(atomic.LoadInt64(&writtenRows), check.Equals, int64(2))
+	c.Assert(q.QuarantinedTables(), check.HasLen, 1)
+	cancel()
+	c.Assert(<-errCh, check.IsNil)
+}
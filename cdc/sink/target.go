@@ -0,0 +1,86 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// NormalizeSinkTarget returns the downstream that a sink URI writes to in a canonical form,
+// two sink URIs with the same target replicate to the same downstream. The credentials and
+// the options in the query are ignored. An empty target is returned for the blackhole sink,
+// which never conflicts with others.
+func NormalizeSinkTarget(sinkURIStr string) (string, error) {
+	sinkURI, err := url.Parse(sinkURIStr)
+	if err != nil {
+		return "", cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	scheme := strings.ToLower(sinkURI.Scheme)
+	switch scheme {
+	case "blackhole":
+		return "", nil
+	case "mysql", "tidb", "mysql+ssl", "tidb+ssl":
+		return "mysql://" + normalizeHosts(sinkURI.Host, "4000"), nil
+	case "kafka", "kafka+ssl":
+		return "kafka://" + normalizeHosts(sinkURI.Host, "9092") + "/" + strings.Trim(sinkURI.Path, "/"), nil
+	case "pulsar":
+		return "pulsar://" + normalizeHosts(sinkURI.Host, "6650") + "/" + strings.Trim(sinkURI.Path, "/"), nil
+	case "pulsar+ssl":
+		return "pulsar://" + normalizeHosts(sinkURI.Host, "6651") + "/" + strings.Trim(sinkURI.Path, "/"), nil
+	case "local":
+		return "local://" + path.Clean("/"+sinkURI.Path), nil
+	case "s3":
+		return "s3://" + strings.ToLower(sinkURI.Host) + path.Clean("/"+sinkURI.Path), nil
+	case "memory":
+		return "memory://" + sinkURI.Host, nil
+	default:
+		return "", cerror.ErrSinkURIInvalid.GenWithStack("the sink scheme (%s) is not supported", sinkURI.Scheme)
+	}
+}
+
+// IsMQSinkTarget returns whether the normalized sink target is a topic of Kafka or Pulsar
+func IsMQSinkTarget(target string) bool {
+	return strings.HasPrefix(target, "kafka://") || strings.HasPrefix(target, "pulsar://")
+}
+
+// normalizeHosts lowercases the hosts, fills the default port and sorts the host list
+func normalizeHosts(hosts string, defaultPort string) string {
+	var normalized []string
+	seen := make(map[string]struct{})
+	for _, host := range strings.Split(hosts, ",") {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" {
+			continue
+		}
+		hostname, port, err := net.SplitHostPort(host)
+		if err != nil {
+			// the port is missing
+			hostname, port = strings.Trim(host, "[]"), defaultPort
+		}
+		host = net.JoinHostPort(hostname, port)
+		if _, ok := seen[host]; ok {
+			continue
+		}
+		seen[host] = struct{}{}
+		normalized = append(normalized, host)
+	}
+	sort.Strings(normalized)
+	return strings.Join(normalized, ",")
+}
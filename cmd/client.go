@@ -70,6 +70,8 @@ var (
 	cliLogLevel       string
 	changefeedListAll bool
 
+	changefeedListIncludeRemoved bool
+
 	changefeedID string
 	captureID    string
 	interval     uint
@@ -77,7 +79,14 @@ var (
 	syncPointEnabled  bool
 	syncPointInterval time.Duration
 
+	checkpointInterval time.Duration
+	maxLagBeforePause  time.Duration
+
 	optForceRemove bool
+	// forceSharedTopic creates the changefeed even if another changefeed writes the same tables to the same topic
+	forceSharedTopic bool
+
+	dryRun bool
 
 	defaultContext context.Context
 )
@@ -86,6 +95,8 @@ var (
 type changefeedCommonInfo struct {
 	ID      string              `json:"id"`
 	Summary *cdc.ChangefeedResp `json:"summary"`
+	// Tombstone is the record of a changefeed whose metadata is garbage collected
+	Tombstone *model.ChangeFeedTombstone `json:"tombstone,omitempty"`
 }
 
 // capture holds capture information
@@ -17,7 +17,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
 	"strings"
 	"time"
 
@@ -27,11 +26,9 @@ import (
 	"github.com/pingcap/ticdc/cdc"
 	"github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/config"
-	"github.com/pingcap/ticdc/pkg/cyclic"
 	"github.com/pingcap/ticdc/pkg/cyclic/mark"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/ticdc/pkg/security"
-	"github.com/pingcap/ticdc/pkg/util"
 	"github.com/pingcap/tidb/store/tikv/oracle"
 	"github.com/r3labs/diff"
 	"github.com/spf13/cobra"
@@ -50,6 +47,8 @@ func newChangefeedCommand() *cobra.Command {
 		newUpdateChangefeedCommand(),
 		newStatisticsChangefeedCommand(),
 		newCreateChangefeedCyclicCommand(),
+		newExportChangefeedCommand(),
+		newImportChangefeedCommand(),
 	)
 	// Add pause, resume, remove changefeed
 	for _, cmd := range newAdminChangefeedCommand() {
@@ -151,10 +150,24 @@ func newListChangefeedCommand() *cobra.Command {
 				}
 				cfs = append(cfs, cfci)
 			}
+			if changefeedListIncludeRemoved {
+				history, _, err := cdcEtcdCli.GetChangeFeedHistory(ctx)
+				if err != nil {
+					return err
+				}
+				for _, tombstone := range history.Tombstones {
+					// a changefeed may be created again with the same ID
+					if _, ok := changefeedIDs[tombstone.ID]; ok {
+						continue
+					}
+					cfs = append(cfs, &changefeedCommonInfo{ID: tombstone.ID, Tombstone: tombstone})
+				}
+			}
 			return jsonPrint(cmd, cfs)
 		},
 	}
 	command.PersistentFlags().BoolVarP(&changefeedListAll, "all", "a", false, "List all replication tasks(including removed and finished)")
+	command.PersistentFlags().BoolVar(&changefeedListIncludeRemoved, "include-removed", false, "List the tombstones of the changefeeds whose metadata is garbage collected")
 	return command
 }
 
@@ -198,6 +211,9 @@ func newQueryChangefeedCommand() *cobra.Command {
 			for captureID, status := range processorInfos {
 				taskStatus = append(taskStatus, captureTaskStatus{CaptureID: captureID, TaskStatus: status})
 			}
+			if info != nil {
+				info = info.WithRedactedConfig()
+			}
 			meta := &cfMeta{Info: info, Status: status, Count: count, TaskStatus: taskStatus}
 			if info == nil {
 				log.Warn("this changefeed has been deleted, the residual meta data will be completely deleted within 24 hours.")
@@ -211,137 +227,27 @@ func newQueryChangefeedCommand() *cobra.Command {
 	return command
 }
 
-func verifyChangefeedParamers(ctx context.Context, cmd *cobra.Command, isCreate bool, credential *security.Credential) (*model.ChangeFeedInfo, error) {
-	if isCreate {
-		if startTs == 0 {
-			ts, logical, err := pdCli.GetTS(ctx)
-			if err != nil {
-				return nil, err
-			}
-			startTs = oracle.ComposeTS(ts, logical)
-		}
-		if err := verifyStartTs(ctx, startTs, cdcEtcdCli); err != nil {
-			return nil, err
-		}
-		if err := verifyTargetTs(ctx, startTs, targetTs); err != nil {
-			return nil, err
-		}
-	}
-
-	cfg := config.GetDefaultReplicaConfig()
-	if len(configFile) > 0 {
-		if err := strictDecodeFile(configFile, "cdc", cfg); err != nil {
-			return nil, err
-		}
-	}
-	if cyclicReplicaID != 0 || len(cyclicFilterReplicaIDs) != 0 {
-		if !(cyclicReplicaID != 0 && len(cyclicFilterReplicaIDs) != 0) {
-			return nil, errors.New("invaild cyclic config, please make sure using " +
-				"nonzero replica ID and specify filter replica IDs")
-		}
-		filter := make([]uint64, 0, len(cyclicFilterReplicaIDs))
-		for _, id := range cyclicFilterReplicaIDs {
-			filter = append(filter, uint64(id))
-		}
-		cfg.Cyclic = &config.CyclicConfig{
-			Enable:          true,
-			ReplicaID:       cyclicReplicaID,
-			FilterReplicaID: filter,
-			SyncDDL:         cyclicSyncDDL,
-			// TODO(neil) enable ID bucket.
-		}
-	}
-
-	if !cfg.EnableOldValue {
-		sinkURIParsed, err := url.Parse(sinkURI)
-		if err != nil {
-			return nil, cerror.WrapError(cerror.ErrSinkURIInvalid, err)
-		}
-
-		if strings.ToLower(sinkURIParsed.Scheme) == "kafka" && sinkURIParsed.Query().Get("protocol") == "canal" {
-			log.Warn("Attempting to use Canal without old value. CDC will enable old value and continue.")
-			cfg.EnableOldValue = true
-		}
-	}
-
-	for _, rules := range cfg.Sink.DispatchRules {
-		switch strings.ToLower(rules.Dispatcher) {
-		case "rowid", "index-value":
-			if cfg.EnableOldValue {
-				cmd.Printf("[WARN] This index-value distribution mode "+
-					"does not guarantee row-level orderliness when "+
-					"switching on the old value, so please use caution! dispatch-rules: %#v", rules)
-			}
-		}
-	}
-	info := &model.ChangeFeedInfo{
-		SinkURI:           sinkURI,
-		Opts:              make(map[string]string),
-		CreateTime:        time.Now(),
-		StartTs:           startTs,
-		TargetTs:          targetTs,
-		Config:            cfg,
-		Engine:            model.SortEngine(sortEngine),
-		SortDir:           sortDir,
-		State:             model.StateNormal,
-		SyncPointEnabled:  syncPointEnabled,
-		SyncPointInterval: syncPointInterval,
+func verifyChangefeedParamers(ctx context.Context, cmd *cobra.Command, id string, isCreate bool, credential *security.Credential) (*model.ChangeFeedInfo, error) {
+	report := newChangefeedChecker(credential).check(ctx, id, isCreate)
+	if err := report.err(); err != nil {
+		return nil, err
 	}
-
-	tz, err := util.GetTimezone(timezone)
-	if err != nil {
-		return nil, errors.Annotate(err, "can not load timezone, Please specify the time zone through environment variable `TZ` or command line parameters `--tz`")
+	for _, warning := range report.Warnings {
+		cmd.Printf("[WARN] %s\n", warning)
 	}
-
-	if isCreate {
-		ctx = util.PutTimezoneInCtx(ctx, tz)
-		ineligibleTables, eligibleTables, err := verifyTables(ctx, credential, cfg, startTs)
+	if isCreate && len(report.IneligibleTables) != 0 && !noConfirm {
+		cmd.Printf("Could you agree to ignore those tables, and continue to replicate [Y/N]\n")
+		var yOrN string
+		_, err := fmt.Scan(&yOrN)
 		if err != nil {
 			return nil, err
 		}
-		if len(ineligibleTables) != 0 {
-			cmd.Printf("[WARN] some tables are not eligible to replicate, %#v\n", ineligibleTables)
-			if !noConfirm {
-				cmd.Printf("Could you agree to ignore those tables, and continue to replicate [Y/N]\n")
-				var yOrN string
-				_, err := fmt.Scan(&yOrN)
-				if err != nil {
-					return nil, err
-				}
-				if strings.ToLower(strings.TrimSpace(yOrN)) != "y" {
-					cmd.Printf("No changefeed is created because you don't want to ignore some tables.\n")
-					return nil, nil
-				}
-			}
-		}
-		if cfg.Cyclic.IsEnabled() && !cyclic.IsTablesPaired(eligibleTables) {
-			return nil, errors.New("normal tables and mark tables are not paired, " +
-				"please run `cdc cli changefeed cyclic create-marktables`")
-		}
-	}
-
-	for _, opt := range opts {
-		s := strings.SplitN(opt, "=", 2)
-		if len(s) <= 0 {
-			cmd.Printf("omit opt: %s", opt)
-			continue
-		}
-
-		var key string
-		var value string
-
-		key = s[0]
-		if len(s) > 1 {
-			value = s[1]
+		if strings.ToLower(strings.TrimSpace(yOrN)) != "y" {
+			cmd.Printf("No changefeed is created because you don't want to ignore some tables.\n")
+			return nil, nil
 		}
-		info.Opts[key] = value
-	}
-
-	err = verifySink(ctx, info.SinkURI, info.Config, info.Opts)
-	if err != nil {
-		return nil, err
 	}
-	return info, nil
+	return report.Info, nil
 }
 
 func changefeedConfigVariables(command *cobra.Command) {
@@ -358,6 +264,8 @@ func changefeedConfigVariables(command *cobra.Command) {
 	command.PersistentFlags().BoolVar(&cyclicSyncDDL, "cyclic-sync-ddl", true, "(Expremental) Cyclic replication sync DDL of changefeed")
 	command.PersistentFlags().BoolVar(&syncPointEnabled, "sync-point", false, "(Expremental) Set and Record syncpoint in replication(default off)")
 	command.PersistentFlags().DurationVar(&syncPointInterval, "sync-interval", 10*time.Minute, "(Expremental) Set the interval for syncpoint in replication(default 10min)")
+	command.PersistentFlags().DurationVar(&checkpointInterval, "checkpoint-interval", 0, "Min interval to flush the sink and the checkpoint, a larger interval trades latency for less downstream and etcd writes (default 0, flush as soon as possible)")
+	command.PersistentFlags().DurationVar(&maxLagBeforePause, "max-lag-before-pause", 0, "Pause the changefeed automatically if its checkpoint lags behind this duration for a sustained period (default 0, never pause)")
 }
 
 func newCreateChangefeedCommand() *cobra.Command {
@@ -372,7 +280,21 @@ func newCreateChangefeedCommand() *cobra.Command {
 				id = uuid.New().String()
 			}
 
-			info, err := verifyChangefeedParamers(ctx, cmd, true /* isCreate */, getCredential())
+			if dryRun {
+				checker := newChangefeedChecker(getCredential())
+				checker.estimateRegions = true
+				report := checker.check(ctx, id, true /* isCreate */)
+				err := jsonPrint(cmd, report.redacted())
+				if err != nil {
+					return err
+				}
+				if len(report.Errors) != 0 {
+					return errors.Errorf("changefeed %s fails %d preflight checks, nothing is created", id, len(report.Errors))
+				}
+				return nil
+			}
+
+			info, err := verifyChangefeedParamers(ctx, cmd, id, true /* isCreate */, getCredential())
 			if err != nil {
 				return err
 			}
@@ -380,7 +302,7 @@ func newCreateChangefeedCommand() *cobra.Command {
 				return nil
 			}
 
-			infoStr, err := info.Marshal()
+			infoStr, err := info.WithRedactedConfig().Marshal()
 			if err != nil {
 				return err
 			}
@@ -394,6 +316,8 @@ func newCreateChangefeedCommand() *cobra.Command {
 	}
 	changefeedConfigVariables(command)
 	command.PersistentFlags().BoolVar(&noConfirm, "no-confirm", false, "Don't ask user whether to ignore ineligible table")
+	command.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Run all the checks and print a report without creating the changefeed")
+	command.PersistentFlags().BoolVar(&forceSharedTopic, "force", false, "Create the changefeed even if another changefeed writes the same tables to the same MQ topic")
 	command.PersistentFlags().StringVarP(&changefeedID, "changefeed-id", "c", "", "Replication task (changefeed) ID")
 
 	return command
@@ -412,7 +336,7 @@ func newUpdateChangefeedCommand() *cobra.Command {
 				return err
 			}
 
-			info, err := verifyChangefeedParamers(ctx, cmd, false /* isCreate */, getCredential())
+			info, err := verifyChangefeedParamers(ctx, cmd, changefeedID, false /* isCreate */, getCredential())
 			if err != nil {
 				return err
 			}
@@ -464,7 +388,7 @@ func newUpdateChangefeedCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			infoStr, err := info.Marshal()
+			infoStr, err := info.WithRedactedConfig().Marshal()
 			if err != nil {
 				return err
 			}
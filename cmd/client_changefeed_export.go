@@ -0,0 +1,273 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFile         string
+	importFile         string
+	preserveCheckpoint bool
+)
+
+func newExportChangefeedCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Export the definitions of all replication tasks (changefeeds) to a portable json document",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := defaultContext
+			doc, err := exportChangefeeds(ctx, cdcEtcdCli, pdCli.GetClusterID(ctx))
+			if err != nil {
+				return err
+			}
+			data, err := doc.Marshal()
+			if err != nil {
+				return err
+			}
+			if exportFile == "" {
+				cmd.Println(string(data))
+				return nil
+			}
+			err = ioutil.WriteFile(exportFile, data, 0600)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Export %d changefeeds to %s successfully!\n"+
+				"The credentials of sink uris are redacted, please fill them in before importing.\n",
+				len(doc.Changefeeds), exportFile)
+			return nil
+		},
+	}
+	command.PersistentFlags().StringVar(&exportFile, "output", "", "The file to write the exported document to, print to stdout if not specified")
+	return command
+}
+
+func newImportChangefeedCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "import",
+		Short: "Create replication tasks (changefeeds) from an exported json document",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := defaultContext
+			data, err := ioutil.ReadFile(importFile)
+			if err != nil {
+				return err
+			}
+			doc := &model.ChangefeedsExport{}
+			err = doc.Unmarshal(data)
+			if err != nil {
+				return err
+			}
+			ts, logical, err := pdCli.GetTS(ctx)
+			if err != nil {
+				return err
+			}
+			err = importChangefeeds(ctx, cdcEtcdCli, doc, pdCli.GetClusterID(ctx), oracle.ComposeTS(ts, logical), preserveCheckpoint)
+			if err != nil {
+				return err
+			}
+			for _, cf := range doc.Changefeeds {
+				cmd.Printf("Import changefeed successfully!\nID: %s\n", cf.ID)
+			}
+			return nil
+		},
+	}
+	command.PersistentFlags().StringVar(&importFile, "file", "", "The exported document to import")
+	command.PersistentFlags().BoolVar(&preserveCheckpoint, "preserve-checkpoint", false,
+		"Start the changefeeds from the exported checkpoints, only allowed if the upstream is the cluster they are exported from")
+	_ = command.MarkPersistentFlagRequired("file")
+	return command
+}
+
+// exportChangefeeds exports the definitions of all changefeeds except the removed and finished ones
+func exportChangefeeds(ctx context.Context, cli kv.CDCEtcdClient, clusterID uint64) (*model.ChangefeedsExport, error) {
+	_, raw, err := cli.GetChangeFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc := &model.ChangefeedsExport{
+		Version:     model.ChangefeedsExportVersion,
+		ClusterID:   clusterID,
+		ExportTime:  time.Now(),
+		Changefeeds: make([]*model.ExportedChangefeed, 0, len(raw)),
+	}
+	for id, rawInfo := range raw {
+		info := &model.ChangeFeedInfo{}
+		err := info.Unmarshal(rawInfo.Value)
+		if err != nil {
+			return nil, err
+		}
+		if info.State == model.StateRemoved || info.State == model.StateFinished {
+			continue
+		}
+		status, _, err := cli.GetChangeFeedStatus(ctx, id)
+		if err != nil && cerror.ErrChangeFeedNotExists.NotEqual(err) {
+			return nil, err
+		}
+		cf, err := model.NewExportedChangefeed(id, info, status)
+		if err != nil {
+			return nil, err
+		}
+		doc.Changefeeds = append(doc.Changefeeds, cf)
+	}
+	sort.Slice(doc.Changefeeds, func(i, j int) bool {
+		return doc.Changefeeds[i].ID < doc.Changefeeds[j].ID
+	})
+	return doc, nil
+}
+
+// importChangefeeds verifies all changefeeds in the export document and then creates them.
+// The changefeeds start from startTs, or from the exported checkpoints if preserveCheckpoint
+// is true, which requires the upstream to be the cluster they are exported from.
+func importChangefeeds(
+	ctx context.Context, cli kv.CDCEtcdClient, doc *model.ChangefeedsExport,
+	clusterID uint64, startTs uint64, preserveCheckpoint bool,
+) error {
+	if preserveCheckpoint && doc.ClusterID != clusterID {
+		return cerror.ErrChangefeedImportInvalid.GenWithStackByArgs("", fmt.Sprintf(
+			"checkpoints can only be preserved in the upstream cluster %d, but the current cluster is %d",
+			doc.ClusterID, clusterID))
+	}
+	infos := make([]*model.ChangeFeedInfo, 0, len(doc.Changefeeds))
+	ids := make(map[model.ChangeFeedID]struct{}, len(doc.Changefeeds))
+	for _, cf := range doc.Changefeeds {
+		if _, ok := ids[cf.ID]; ok {
+			return cerror.ErrChangefeedImportInvalid.GenWithStackByArgs(cf.ID, "duplicated changefeed id")
+		}
+		ids[cf.ID] = struct{}{}
+		cfStartTs := startTs
+		if preserveCheckpoint {
+			cfStartTs = cf.CheckpointTs
+		}
+		info, err := verifyImportedChangefeed(ctx, cli, cf, cfStartTs)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, info)
+	}
+	for i, cf := range doc.Changefeeds {
+		err := cli.CreateChangefeedInfo(ctx, infos[i], cf.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyImportedChangefeed(
+	ctx context.Context, cli kv.CDCEtcdClient, cf *model.ExportedChangefeed, startTs uint64,
+) (*model.ChangeFeedInfo, error) {
+	invalid := func(reason string) error {
+		return cerror.ErrChangefeedImportInvalid.GenWithStackByArgs(cf.ID, reason)
+	}
+	err := model.ValidateChangefeedID(cf.ID)
+	if err != nil {
+		return nil, err
+	}
+	if cf.Info == nil {
+		return nil, invalid("the changefeed info is missing")
+	}
+	redacted, err := model.IsSinkURIRedacted(cf.Info.SinkURI)
+	if err != nil {
+		return nil, err
+	}
+	if redacted {
+		return nil, invalid("the credentials of the sink uri are redacted, please fill them in")
+	}
+	if cf.Info.Config != nil && cf.Info.Config.IntegrityCheck != nil &&
+		config.IsDSNRedacted(cf.Info.Config.IntegrityCheck.UpstreamDSN) {
+		return nil, invalid("the password of the upstream dsn is redacted, please fill it in")
+	}
+	_, err = sink.NormalizeSinkTarget(cf.Info.SinkURI)
+	if err != nil {
+		return nil, err
+	}
+	_, err = cli.GetChangeFeedInfo(ctx, cf.ID)
+	if err == nil {
+		return nil, invalid("the changefeed already exists")
+	}
+	if cerror.ErrChangeFeedNotExists.NotEqual(err) {
+		return nil, err
+	}
+
+	info := *cf.Info
+	info.CreateTime = time.Now()
+	info.StartTs = startTs
+	info.AdminJobType = model.AdminNone
+	info.State = model.StateNormal
+	info.ErrorHis = nil
+	info.Error = nil
+	if info.Config == nil {
+		info.Config = config.GetDefaultReplicaConfig()
+	}
+	err = info.VerifyAndFix()
+	if err != nil {
+		return nil, err
+	}
+	err = info.Config.Mounter.Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = config.ValidatePriorityClass(info.Config.PriorityClass)
+	if err != nil {
+		return nil, err
+	}
+	err = config.ValidateGCSpanningTxn(info.Config.GCSpanningTxn)
+	if err != nil {
+		return nil, err
+	}
+	err = config.ValidateDDLOrder(info.Config.DDLOrder)
+	if err != nil {
+		return nil, err
+	}
+	err = config.ValidateStartTsInDDL(info.Config.StartTsInDDL)
+	if err != nil {
+		return nil, err
+	}
+	err = config.ValidateFinishVerification(info.Config.FinishVerification)
+	if err != nil {
+		return nil, err
+	}
+	err = info.Config.Scheduler.Validate()
+	if err != nil {
+		return nil, err
+	}
+	_, err = filter.NewFilter(info.Config)
+	if err != nil {
+		return nil, err
+	}
+	err = verifyStartTs(ctx, info.StartTs, cli)
+	if err != nil {
+		return nil, err
+	}
+	err = verifyTargetTs(ctx, info.StartTs, info.TargetTs)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
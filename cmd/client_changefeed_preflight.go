@@ -0,0 +1,359 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/ticdc/cdc/kv"
+	"github.com/pingcap/ticdc/cdc/model"
+	"github.com/pingcap/ticdc/cdc/sink"
+	"github.com/pingcap/ticdc/pkg/config"
+	"github.com/pingcap/ticdc/pkg/cyclic"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	"github.com/pingcap/ticdc/pkg/filter"
+	"github.com/pingcap/ticdc/pkg/regionspan"
+	"github.com/pingcap/ticdc/pkg/security"
+	"github.com/pingcap/ticdc/pkg/util"
+	"github.com/pingcap/tidb/store/tikv/oracle"
+	pd "github.com/tikv/pd/client"
+)
+
+const preflightScanRegionLimit = 1024
+
+// preflightReport is the result of the checks before creating or updating a changefeed.
+// Errors block the changefeed from being created, while warnings don't.
+type preflightReport struct {
+	ID               string                `json:"id"`
+	Info             *model.ChangeFeedInfo `json:"info,omitempty"`
+	EligibleTables   []model.TableName     `json:"eligible-tables"`
+	IneligibleTables []model.TableName     `json:"ineligible-tables"`
+	EstimatedRegions int                   `json:"estimated-regions"`
+	Errors           []string              `json:"errors"`
+	Warnings         []string              `json:"warnings"`
+
+	errs []error
+}
+
+func (r *preflightReport) addError(err error) {
+	r.errs = append(r.errs, err)
+	r.Errors = append(r.Errors, err.Error())
+}
+
+func (r *preflightReport) addWarning(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// err returns the first error found by the checks
+func (r *preflightReport) err() error {
+	if len(r.errs) == 0 {
+		return nil
+	}
+	return r.errs[0]
+}
+
+// redacted returns a copy of the report without the credentials of the sink uri and the upstream dsn
+func (r *preflightReport) redacted() *preflightReport {
+	report := *r
+	if r.Info != nil {
+		info := r.Info.WithRedactedConfig()
+		if uri, err := model.RedactSinkURI(info.SinkURI); err == nil {
+			info.SinkURI = uri
+		}
+		report.Info = info
+	}
+	return &report
+}
+
+// changefeedChecker checks the parameters of a changefeed from the command line flags,
+// it's shared by the dry run and the real creation of changefeeds.
+type changefeedChecker struct {
+	etcdCli kv.CDCEtcdClient
+	pdCli   pd.Client
+	// estimateRegions tells whether to count the regions of the eligible tables
+	estimateRegions bool
+
+	verifyTables func(ctx context.Context, cfg *config.ReplicaConfig, startTs uint64) (ineligibleTables, eligibleTables []model.TableName, err error)
+	verifySink   func(ctx context.Context, sinkURI string, cfg *config.ReplicaConfig, opts map[string]string) error
+}
+
+func newChangefeedChecker(credential *security.Credential) *changefeedChecker {
+	return &changefeedChecker{
+		etcdCli: cdcEtcdCli,
+		pdCli:   pdCli,
+		verifyTables: func(ctx context.Context, cfg *config.ReplicaConfig, startTs uint64) ([]model.TableName, []model.TableName, error) {
+			return verifyTables(ctx, credential, cfg, startTs)
+		},
+		verifySink: verifySink,
+	}
+}
+
+// check runs all the checks, it stops early only if the later checks depend on a failed one.
+func (c *changefeedChecker) check(ctx context.Context, id string, isCreate bool) *preflightReport {
+	report := &preflightReport{ID: id}
+	if isCreate {
+		if err := model.ValidateChangefeedID(id); err != nil {
+			report.addError(err)
+		} else if _, err := c.etcdCli.GetChangeFeedInfo(ctx, id); err == nil {
+			report.addError(cerror.ErrChangeFeedAlreadyExists.GenWithStackByArgs(id))
+		} else if cerror.ErrChangeFeedNotExists.NotEqual(err) {
+			report.addError(err)
+		}
+		if startTs == 0 {
+			ts, logical, err := c.pdCli.GetTS(ctx)
+			if err != nil {
+				report.addError(err)
+				return report
+			}
+			startTs = oracle.ComposeTS(ts, logical)
+		}
+		if err := verifyStartTs(ctx, startTs, c.etcdCli); err != nil {
+			report.addError(err)
+		}
+		if err := verifyTargetTs(ctx, startTs, targetTs); err != nil {
+			report.addError(err)
+		}
+	}
+
+	cfg := config.GetDefaultReplicaConfig()
+	if len(configFile) > 0 {
+		if err := strictDecodeFile(configFile, "cdc", cfg); err != nil {
+			report.addError(err)
+			return report
+		}
+	}
+	if err := cfg.Mounter.Validate(); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Sink.ValidateValueFormat(); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Sink.ValidateDispatchers(); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Sink.ValidateCommitTimeZone(); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Sink.ValidateTableErrorPolicy(); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Sink.ValidateConflictResolution(); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Sink.ValidateDownstreamUnwritable(); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Debug.Validate(cfg.Sink); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Sink.Dedup.Validate(); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Sink.SoftDelete.Validate(); err != nil {
+		report.addError(err)
+	}
+	if err := config.ValidatePriorityClass(cfg.PriorityClass); err != nil {
+		report.addError(err)
+	}
+	if err := config.ValidateGCSpanningTxn(cfg.GCSpanningTxn); err != nil {
+		report.addError(err)
+	}
+	if err := config.ValidateDDLOrder(cfg.DDLOrder); err != nil {
+		report.addError(err)
+	}
+	if err := config.ValidateStartTsInDDL(cfg.StartTsInDDL); err != nil {
+		report.addError(err)
+	}
+	if err := config.ValidateFinishVerification(cfg.FinishVerification); err != nil {
+		report.addError(err)
+	}
+	if checkpointInterval < 0 {
+		report.addError(errors.Errorf("invalid checkpoint interval %s, it must not be negative", checkpointInterval))
+	}
+	if maxLagBeforePause < 0 {
+		report.addError(errors.Errorf("invalid max lag before pause %s, it must not be negative", maxLagBeforePause))
+	}
+	if cyclicReplicaID != 0 || len(cyclicFilterReplicaIDs) != 0 {
+		if !(cyclicReplicaID != 0 && len(cyclicFilterReplicaIDs) != 0) {
+			report.addError(errors.New("invaild cyclic config, please make sure using " +
+				"nonzero replica ID and specify filter replica IDs"))
+		}
+		filter := make([]uint64, 0, len(cyclicFilterReplicaIDs))
+		for _, id := range cyclicFilterReplicaIDs {
+			filter = append(filter, uint64(id))
+		}
+		cfg.Cyclic = &config.CyclicConfig{
+			Enable:          true,
+			ReplicaID:       cyclicReplicaID,
+			FilterReplicaID: filter,
+			SyncDDL:         cyclicSyncDDL,
+			// TODO(neil) enable ID bucket.
+		}
+	}
+	_, err := filter.NewFilter(cfg)
+	if err != nil {
+		report.addError(err)
+		return report
+	}
+
+	sinkURIParsed, err := url.Parse(sinkURI)
+	if err != nil {
+		report.addError(cerror.WrapError(cerror.ErrSinkURIInvalid, err))
+		return report
+	}
+	if err := cfg.Scheduler.Validate(); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.IntegrityCheck.Validate(sinkURIParsed.Scheme, syncPointEnabled); err != nil {
+		report.addError(err)
+	}
+	if err := cfg.Sink.ValidateReconcileSchema(sinkURIParsed.Scheme); err != nil {
+		report.addError(err)
+	}
+	if !cfg.EnableOldValue {
+		if strings.ToLower(sinkURIParsed.Scheme) == "kafka" && sinkURIParsed.Query().Get("protocol") == "canal" {
+			report.addWarning("Attempting to use Canal without old value. CDC will enable old value and continue.")
+			cfg.EnableOldValue = true
+		}
+	}
+
+	for _, rules := range cfg.Sink.DispatchRules {
+		switch strings.ToLower(rules.Dispatcher) {
+		case "rowid", "index-value":
+			if cfg.EnableOldValue {
+				report.addWarning("This index-value distribution mode "+
+					"does not guarantee row-level orderliness when "+
+					"switching on the old value, so please use caution! dispatch-rules: %#v", rules)
+			}
+		}
+	}
+	info := &model.ChangeFeedInfo{
+		SinkURI:           sinkURI,
+		Opts:              make(map[string]string),
+		CreateTime:        time.Now(),
+		StartTs:           startTs,
+		TargetTs:          targetTs,
+		Config:            cfg,
+		Engine:            model.SortEngine(sortEngine),
+		SortDir:           sortDir,
+		State:             model.StateNormal,
+		SyncPointEnabled:  syncPointEnabled,
+		SyncPointInterval: syncPointInterval,
+
+		CheckpointInterval: checkpointInterval,
+		MaxLagBeforePause:  maxLagBeforePause,
+	}
+	report.Info = info
+
+	for _, opt := range opts {
+		s := strings.SplitN(opt, "=", 2)
+		if len(s) <= 0 {
+			report.addWarning("omit opt: %s", opt)
+			continue
+		}
+
+		var key string
+		var value string
+
+		key = s[0]
+		if len(s) > 1 {
+			value = s[1]
+		}
+		info.Opts[key] = value
+	}
+
+	tz, err := util.GetTimezone(timezone)
+	if err != nil {
+		report.addError(errors.Annotate(err, "can not load timezone, Please specify the time zone through environment variable `TZ` or command line parameters `--tz`"))
+		return report
+	}
+	ctx = util.PutTimezoneInCtx(ctx, tz)
+
+	tablesTs := startTs
+	if !isCreate {
+		ts, logical, err := c.pdCli.GetTS(ctx)
+		if err != nil {
+			report.addError(err)
+			return report
+		}
+		tablesTs = oracle.ComposeTS(ts, logical)
+	}
+	report.IneligibleTables, report.EligibleTables, err = c.verifyTables(ctx, cfg, tablesTs)
+	if err != nil {
+		report.addError(err)
+	} else {
+		if len(report.IneligibleTables) != 0 {
+			report.addWarning("some tables are not eligible to replicate, %#v", report.IneligibleTables)
+		}
+		if isCreate && cfg.Cyclic.IsEnabled() && !cyclic.IsTablesPaired(report.EligibleTables) {
+			report.addError(errors.New("normal tables and mark tables are not paired, " +
+				"please run `cdc cli changefeed cyclic create-marktables`"))
+		}
+		overlapping, err := verifyOverlappingChangefeeds(ctx, c.etcdCli, id, sinkURI, report.EligibleTables)
+		if err != nil {
+			report.addError(err)
+		}
+		for _, other := range overlapping {
+			// the messages of the changefeeds sharing a topic are interleaved, which breaks the
+			// order of the rows of the same key expected by the consumers
+			if isCreate && sink.IsMQSinkTarget(other.Target) && !forceSharedTopic {
+				report.addError(cerror.ErrChangefeedSharedTopic.GenWithStackByArgs(other.ID, other.Target, other.Tables))
+				continue
+			}
+			report.addWarning("changefeed %s replicates the same tables to the same sink %s, tables: %v", other.ID, other.Target, other.Tables)
+		}
+		if c.estimateRegions {
+			report.EstimatedRegions, err = c.countRegions(ctx, report.EligibleTables)
+			if err != nil {
+				report.addWarning("failed to estimate the regions to scan: %s", err)
+			}
+		}
+	}
+
+	err = c.verifySink(ctx, info.SinkURI, info.Config, info.Opts)
+	if err != nil {
+		report.addError(err)
+	}
+	return report
+}
+
+// countRegions returns the number of regions of the tables, which is the estimated size of
+// the initial scan of a changefeed.
+func (c *changefeedChecker) countRegions(ctx context.Context, tables []model.TableName) (int, error) {
+	count := 0
+	for _, table := range tables {
+		span := regionspan.ToComparableSpan(regionspan.GetTableSpan(table.TableID, true))
+		start := span.Start
+		for {
+			regions, _, err := c.pdCli.ScanRegions(ctx, start, span.End, preflightScanRegionLimit)
+			if err != nil {
+				return 0, errors.Trace(err)
+			}
+			count += len(regions)
+			if len(regions) < preflightScanRegionLimit {
+				break
+			}
+			start = regions[len(regions)-1].EndKey
+			if len(start) == 0 {
+				break
+			}
+		}
+	}
+	return count, nil
+}
@@ -35,21 +35,59 @@ func (s *decodeFileSuite) TestCanDecodeTOML(c *check.C) {
 	path := filepath.Join(dir, "config.toml")
 	content := `
 case-sensitive = false
+gc-spanning-txn = "fail"
+max-inflight-txns = 1024
+ddl-order = "ddl-first"
+start-ts-in-ddl = "reject"
+finish-verification = "acked"
 
 [filter]
 ignore-txn-start-ts = [1, 2]
 ddl-allow-list = [1, 2]
 rules = ['*.*', '!test.*']
+partial-drop-database = "drop-tables"
+drop-tables-batch-size = 16
+extra-system-schemas = ['dm_meta', 'tmp_*']
 
 [mounter]
 worker-num = 64
+delete-image = "fetch"
+unknown-column-type = "raw-bytes"
+missing-column-default = "current-default"
+check-schema-at-start = true
+transient-error-retry = 5
+decode-error = "skip"
 
 [sink]
 dispatchers = [
 	{matcher = ['test1.*', 'test2.*'], dispatcher = "ts"},
 	{matcher = ['test3.*', 'test4.*'], dispatcher = "rowid"},
 ]
+dispatcher = "table"
 protocol = "default"
+commit-time = true
+commit-time-zone = "Asia/Shanghai"
+table-error-policy = "quarantine"
+compact-insert = true
+reconcile-schema = true
+conflict-resolution = "last-writer-wins"
+fallback-protocol = "canal"
+delete-key-only = true
+downstream-unwritable = "retry"
+downstream-probe-interval = 10
+
+[sink.dedup]
+enable = true
+window = 30
+max-rows = 1000
+
+[sink.soft-delete]
+enable = true
+marker-column = "is_deleted"
+time-column = "deleted_at"
+
+[sorter]
+concurrency = 8
 
 [cyclic-replication]
 enable = true
@@ -61,6 +99,11 @@ sync-ddl = true
 [scheduler]
 type = "manual"
 polling-time = 5
+max-table-moves = 10
+table-move-interval = 30
+
+[debug]
+pause-on-first-error = true
 `
 	err := ioutil.WriteFile(path, []byte(content), 0644)
 	c.Assert(err, check.IsNil)
@@ -70,21 +113,49 @@ polling-time = 5
 	c.Assert(err, check.IsNil)
 
 	c.Assert(cfg.CaseSensitive, check.IsFalse)
+	c.Assert(cfg.GCSpanningTxn, check.Equals, config.GCSpanningTxnFail)
+	c.Assert(cfg.MaxInflightTxns, check.Equals, 1024)
+	c.Assert(cfg.DDLOrder, check.Equals, config.DDLOrderDDLFirst)
+	c.Assert(cfg.StartTsInDDL, check.Equals, config.StartTsInDDLReject)
+	c.Assert(cfg.FinishVerification, check.Equals, config.FinishVerificationAcked)
 	c.Assert(cfg.Filter, check.DeepEquals, &config.FilterConfig{
-		IgnoreTxnStartTs: []uint64{1, 2},
-		DDLAllowlist:     []model.ActionType{1, 2},
-		Rules:            []string{"*.*", "!test.*"},
+		IgnoreTxnStartTs:    []uint64{1, 2},
+		DDLAllowlist:        []model.ActionType{1, 2},
+		Rules:               []string{"*.*", "!test.*"},
+		PartialDropDatabase: config.PartialDropDatabaseDropTables,
+		DropTablesBatchSize: 16,
+		ExtraSystemSchemas:  []string{"dm_meta", "tmp_*"},
 	})
 	c.Assert(cfg.Mounter, check.DeepEquals, &config.MounterConfig{
-		WorkerNum: 64,
+		WorkerNum:            64,
+		DeleteImage:          config.DeleteImageFetch,
+		UnknownColumnType:    config.UnknownColumnTypeRawBytes,
+		MissingColumnDefault: config.MissingColumnCurrentDefault,
+		CheckSchemaAtStart:   true,
+		TransientErrorRetry:  5,
+		DecodeError:          config.DecodeErrorSkip,
 	})
 	c.Assert(cfg.Sink, check.DeepEquals, &config.SinkConfig{
 		DispatchRules: []*config.DispatchRule{
 			{Dispatcher: "ts", Matcher: []string{"test1.*", "test2.*"}},
 			{Dispatcher: "rowid", Matcher: []string{"test3.*", "test4.*"}},
 		},
-		Protocol: "default",
+		Protocol:                "default",
+		FallbackProtocol:        "canal",
+		Dispatcher:              "table",
+		CommitTime:              true,
+		CommitTimeZone:          "Asia/Shanghai",
+		TableErrorPolicy:        config.TableErrorPolicyQuarantine,
+		CompactInsert:           true,
+		ReconcileSchema:         true,
+		ConflictResolution:      config.ConflictResolutionLastWriterWins,
+		Dedup:                   &config.DedupConfig{Enable: true, Window: 30, MaxRows: 1000},
+		SoftDelete:              &config.SoftDeleteConfig{Enable: true, MarkerColumn: "is_deleted", TimeColumn: "deleted_at"},
+		DeleteKeyOnly:           true,
+		DownstreamUnwritable:    config.DownstreamUnwritableRetry,
+		DownstreamProbeInterval: 10,
 	})
+	c.Assert(cfg.Sorter, check.DeepEquals, &config.SorterConfig{Concurrency: 8})
 	c.Assert(cfg.Cyclic, check.DeepEquals, &config.CyclicConfig{
 		Enable:          true,
 		ReplicaID:       1,
@@ -93,9 +164,12 @@ polling-time = 5
 		SyncDDL:         true,
 	})
 	c.Assert(cfg.Scheduler, check.DeepEquals, &config.SchedulerConfig{
-		Tp:          "manual",
-		PollingTime: 5,
+		Tp:                "manual",
+		PollingTime:       5,
+		MaxTableMoves:     10,
+		TableMoveInterval: 30,
 	})
+	c.Assert(cfg.Debug, check.DeepEquals, &config.DebugConfig{PauseOnFirstError: true})
 }
 
 func (s *decodeFileSuite) TestAndWriteExampleTOML(c *check.C) {
@@ -107,6 +181,65 @@ func (s *decodeFileSuite) TestAndWriteExampleTOML(c *check.C) {
 # This configuration will affect both filter and sink related configurations, the default is true
 case-sensitive = true
 
+# 同步任务的优先级，可选值为 critical、normal、best-effort，默认为 normal
+# 资源紧张时（例如同步延迟阻塞上游 GC），owner 会优先暂停低优先级的同步任务，critical 的同步任务不会被自动暂停
+
+# The priority class of the changefeed, which is one of critical, normal and best-effort, the default is normal
+# The owner pauses the changefeeds of lower priority first under pressure (e.g. the lag blocks the GC of upstream),
+# the critical changefeeds are never paused automatically
+priority-class = "normal"
+
+# 是否在收到 commit ts 小于表 checkpoint 的事件时使同步任务失败，默认为 false，即丢弃该事件
+
+# Whether to fail the changefeed on an event whose commit ts is behind the checkpoint of its table,
+# the default is false, which drops the event
+strict-consistency = false
+
+# 如何处理开始于表开始同步时的 GC safepoint 之前、提交于其之后的上游事务，这类事务的部分行可能丢失
+# 支持 fail, warn-and-continue 两种，fail 报错停止同步，warn-and-continue 打印警告后继续同步，默认为 warn-and-continue
+
+# How to handle an upstream transaction which starts before the GC safepoint the table starts at and commits after it,
+# some rows of such a transaction may be missing. Supports fail and warn-and-continue, fail fails the changefeed
+# and warn-and-continue logs a warning and continues, the default is warn-and-continue
+gc-spanning-txn = "warn-and-continue"
+
+# 同步任务在一个 capture 中从排序输出到 sink flush 之间缓存的事务数量上限（按 commit ts 计数），0 表示不限制
+# 超过上限时表的排序输出会等待，但表的 resolved ts 之后提交的事务不会被阻塞，以免阻塞 resolved ts 的推进
+
+# The maximum number of the transactions, counted by the distinct commit ts, buffered between the sorter outputs
+# and the sink flush of the changefeed in a capture, 0 means unlimited. The sorter output of a table waits when
+# the limit is reached, unless some transaction committed after the resolved ts of the table is in flight,
+# so that the resolved ts is never blocked
+max-inflight-txns = 0
+
+# 与 DDL 的 commit ts 相同的 DML 在下游的写入顺序，支持 dml-first, ddl-first 两种
+# dml-first 先写入 DML 再执行 DDL，ddl-first 先执行 DDL 再写入 DML，默认为 dml-first
+# 这些 DML 按 DDL 之前的表结构写入，dml-first 是安全的顺序
+
+# The order of a DDL and the DMLs sharing its commit ts in the downstream, supports dml-first and ddl-first,
+# dml-first writes the DMLs before executing the DDL and ddl-first executes the DDL first, the default is dml-first.
+# Such DMLs are written in the schema before the DDL, so dml-first is the safe order
+ddl-order = "dml-first"
+
+# start-ts 落在某个 DDL 执行过程中时的处理方式，支持 wait, reject 两种，默认为 reject
+# 此时 start-ts 的表结构处于 DDL 的中间状态，reject 拒绝该 changefeed，wait 等待 DDL 执行完成后再启动 changefeed，
+# 但表结构仍然从 start-ts 读取，仅推迟启动
+
+# How to handle the start-ts falling in the execution of a DDL, supports wait and reject, the default is reject.
+# The schema at start-ts is in an intermediate state of the DDL, reject rejects the changefeed, and wait starts the
+# changefeed after the DDL finishes, which only delays the start, the schema is still read at start-ts.
+# The ADD INDEX jobs are ignored, the indexes being added don't affect the rows
+start-ts-in-ddl = "reject"
+
+# 指定了 target-ts 的同步任务在结束前如何确认下游已同步到 target-ts，支持 none, flushed, acked 三种，默认为 flushed
+# none 在 checkpoint-ts 到达 target-ts 后即结束，flushed 还需等待所有 processor 的 Sink 已写入到 target-ts，
+# acked 对于 MQ 类的 Sink 还需等待 target-ts 的 resolved ts 消息被所有分区确认
+# How to verify the downstream is caught up before a changefeed with target-ts is finished, supports none, flushed
+# and acked, the default is flushed. none finishes the changefeed once the checkpoint-ts reaches target-ts, flushed
+# also waits for the sinks of all the processors to flush to target-ts, and acked also waits for the resolved ts
+# message of target-ts to be acked by all the partitions for MQ sinks
+finish-verification = "flushed"
+
 [filter]
 # 忽略哪些 StartTs 的事务
 # Transactions with the following StartTs will be ignored
@@ -118,25 +251,171 @@ ignore-txn-start-ts = [1, 2]
 # Filter rules syntax: https://docs.pingcap.com/tidb/stable/table-filter#syntax
 rules = ['*.*', '!test.*']
 
+# 过滤器排除了部分表时如何同步 DROP DATABASE
+# 支持 drop-database, drop-tables 两种，drop-database 同步 DROP DATABASE 并删除下游被排除的表，drop-tables 只删除同步的表
+# How to replicate a DROP DATABASE if the rules exclude some tables of the database
+# Supports drop-database and drop-tables. drop-database emits the DROP DATABASE, which drops the excluded tables downstream,
+# and drop-tables emits the DROP TABLEs of the replicated tables only
+partial-drop-database = "drop-database"
+# drop-tables 模式下每条 DROP TABLE 最多删除的表数量，默认为 64
+# The maximum number of the tables a DROP TABLE drops in the drop-tables mode, the default is 64
+drop-tables-batch-size = 64
+# 额外视为系统库的库名，支持通配符。mysql, information_schema, performance_schema, metrics_schema 等系统库
+# 以及 BR 的临时库 __TiDB_BR_Temporary_* 总是会在过滤器规则之前被排除
+# The patterns of the extra schemas treated as the system schemas, the wildcards are supported. The system schemas such as
+# mysql, information_schema, performance_schema, metrics_schema and the temporary schemas __TiDB_BR_Temporary_* of BR
+# are always excluded before the rules are applied
+# extra-system-schemas = ['dm_meta']
+
 [mounter]
 # mounter 线程数
 # the thread number of the the mounter
 worker-num = 16
+# 开启 old value 时，如何处理缺少旧值的删除事件
+# 支持 best-effort, require, fetch 三种，best-effort 输出能获取到的列，require 报错停止同步，fetch 从 TiKV 读取删除前的行
+# How to handle a delete event without the old value when old value is enabled
+# Supports best-effort, require and fetch. best-effort emits the available columns, require fails the changefeed
+# and fetch reads the row from TiKV at the ts before the delete
+delete-image = "best-effort"
+# 如何处理 mounter 无法识别类型的列
+# 支持 fail, skip-column, raw-bytes 三种，fail 报错停止同步，skip-column 输出不包含该列的行，raw-bytes 输出该列编码后的原始字节
+# How to handle a column whose type is unknown to the mounter
+# Supports fail, skip-column and raw-bytes. fail fails the changefeed, skip-column emits the row without the column
+# and raw-bytes emits the raw encoded bytes of the column
+unknown-column-type = "fail"
+# 行中缺少的列的取值，行写入时该列尚未被 ADD COLUMN 添加，或该列值为 null 且没有默认值
+# 支持 origin-default, current-default 两种，origin-default 使用添加该列时的默认值，与 TiDB 的读取结果一致，
+# current-default 使用该列当前的默认值，可为 null 的列取 null
+# The value of a column missing in a row, the row is written before the column is added by ADD COLUMN,
+# or the column is null and has no default value. Supports origin-default and current-default. origin-default uses
+# the default value when the column is added, which is how TiDB reads the row, and current-default uses the current
+# default value of the column, or null if the column is nullable
+missing-column-default = "origin-default"
+# 是否在同步任务启动时检查所有同步表的表结构都能被解析，检查会增加启动耗时，检查失败时暂停同步任务
+# Whether to check the schemas of all the replicated tables can be reconstructed when the changefeed starts,
+# the check costs some startup time, the changefeed is paused if the check fails
+check-schema-at-start = false
+# 行因依赖暂时不可用（如 schema storage 尚未追上）而解析失败时的最大重试次数，重试间隔指数退避
+# The max retries of a row failed to mount because a dependency is momentarily unavailable,
+# e.g. the schema storage catching up, the retries back off exponentially
+transient-error-retry = 3
+# 如何处理解析失败的行，包括重试后仍失败的行
+# 支持 fail, skip 两种，fail 报错停止同步，skip 丢弃该行并输出错误日志
+# How to handle a row failed to mount, including the row still failing after the retries
+# Supports fail and skip. fail fails the changefeed, and skip drops the row with an error log
+decode-error = "fail"
 
 [sink]
 # 对于 MQ 类的 Sink，可以通过 dispatchers 配置 event 分发器
-# 分发器支持 default, ts, rowid, table 四种
+# 分发器支持 default, ts, rowid, table, index-value 五种，表使用第一个匹配的规则的分发器
 # For MQ Sinks, you can configure event distribution rules through dispatchers
-# Dispatchers support default, ts, rowid and table
+# Dispatchers support default, ts, rowid, table and index-value, a table uses the dispatcher of the first rule it matches
 dispatchers = [
 	{matcher = ['test1.*', 'test2.*'], dispatcher = "ts"},
 	{matcher = ['test3.*', 'test4.*'], dispatcher = "rowid"},
 ]
+# 没有匹配任何规则的表使用的分发器，默认为 default
+# The dispatcher of the tables no rule matches, the default is default
+dispatcher = "default"
 # 对于 MQ 类的 Sink，可以指定消息的协议格式
-# 协议目前支持 default, canal 两种，default 为 ticdc-open-protocol
+# 协议目前支持 default, canal, protobuf 三种，default 为 ticdc-open-protocol，protobuf 需要配置 sink.protobuf.descriptor-file
 # For MQ Sinks, you can configure the protocol of the messages sending to MQ
-# Currently the protocol support default and canal
+# Currently the protocol support default, canal and protobuf, protobuf requires sink.protobuf.descriptor-file
 protocol = "default"
+# 对于 MQ 类的 Sink，是否在消息中附带由 commit ts 计算出的提交时间，默认为 false
+# commit-time-zone 为提交时间的时区，默认为 UTC
+# For MQ Sinks, whether to add the commit time derived from the commit ts to the messages, the default is false
+# commit-time-zone is the time zone of the commit time, the default is UTC
+commit-time = false
+commit-time-zone = ""
+# 对于 MySQL Sink，如何处理重试后仍然写入失败的表，支持 fail, quarantine 两种，默认为 fail
+# fail 报错停止同步，quarantine 隔离该表，该表的行被丢弃直到通过 API 解除隔离，其他表继续同步
+# 被丢弃的行不会被保存（没有死信队列），解除隔离时该表从第一个写入失败的行重新扫描，重新扫描不可行时只能放弃这些行
+# For MySQL Sinks, how to handle a table whose rows still fail to be written after the retries, supports fail
+# and quarantine, the default is fail. fail fails the changefeed, quarantine quarantines the table, the rows of the
+# table are discarded until it is released by the API, the other tables keep replicating. The discarded rows are
+# not kept, there is no dead-letter queue, the released table is re-scanned from the first row failed to be written,
+# the discarded rows are lost if the table can't be re-scanned and is released with discard-rows
+table-error-policy = "fail"
+# 对于 default 协议，是否只输出插入事件的列值和列类型，不带除二进制以外的列标志和操作包装，更新和删除事件保持完整格式，默认为 false，需要开启 enable-old-value
+# For the default protocol, whether to encode the insert events as the column values and types only, without the column
+# flags other than binary and the operation wrapper, the update and delete events keep the full form, the default is false.
+# It requires enable-old-value, without which the updates can't be told from the inserts
+compact-insert = false
+# 对于 MySQL Sink，是否在同步任务启动或恢复时为下游表补齐缺少的列，会修改下游表结构，默认为 false
+# For MySQL Sinks, whether to add the columns the downstream tables lack when the changefeed starts or resumes,
+# it modifies the downstream schemas, the default is false
+reconcile-schema = false
+# 对于 MySQL Sink，多个同步任务写入同一下游时如何解决同一行的写入冲突，支持 none, last-writer-wins 两种，默认为 none
+# last-writer-wins 在下游表中添加 _cdc_commit_ts 列记录最后一次写入的 commit ts，跳过 commit ts 更小的写入，会修改下游表结构
+# For MySQL Sinks, how to resolve the conflicting writes of several changefeeds to the same downstream rows,
+# supports none and last-writer-wins, the default is none. last-writer-wins adds the column _cdc_commit_ts
+# storing the commit ts of the last write to the downstream tables, and skips the writes of smaller commit ts
+conflict-resolution = "none"
+# 是否在 etcd 中记录已输出的 DDL，避免 owner 切换后重复输出 DDL，默认为 false
+# Whether to record the DDLs emitted to the sink in etcd, so that the DDLs are not emitted
+# again after the owner fails over, the default is false
+deduplicate-ddl = false
+# 对于 MQ 类的 Sink，可以指定 ENUM、SET、BIT 类型的值在消息中的表示方式
+# enum-format 支持 index, name 两种，set-format 支持 bitmask, string 两种，bit-format 支持 integer, base64 两种
+# For MQ Sinks, you can configure the representations of the ENUM, SET and BIT values in the messages
+# enum-format supports index and name, set-format supports bitmask and string (the comma separated names),
+# bit-format supports integer and base64 (the base64 of the big-endian bytes)
+enum-format = "index"
+set-format = "bitmask"
+bit-format = "integer"
+# 对于 MQ 类的 Sink，可以指定备用协议，协议无法编码的行（如 Avro 不支持的值）使用备用协议编码，
+# 消息带有 fallback-protocol 头；备用协议支持 default, canal, maxwell，默认为空，即编码失败时同步出错
+# 其他编码错误（如 Schema Registry 的错误）不使用备用协议
+# For MQ Sinks, you can configure a fallback protocol to encode the rows whose values the protocol can't encode,
+# e.g. the values Avro doesn't support, the messages carry the header fallback-protocol.
+# The fallback protocol supports default, canal and maxwell, the replication fails if it's empty.
+# The other encoding errors, e.g. of the Schema Registry, are not sent with the fallback protocol
+fallback-protocol = ""
+# 对于 MQ 类的 Sink，删除事件是否只输出主键（handle key）列的旧值，没有主键的表输出所有列，默认为 false
+# For MQ Sinks, whether to keep only the old values of the handle key columns in the delete events,
+# the deletes of the tables without a handle key keep all the columns, the default is false
+delete-key-only = false
+# 对于 MySQL Sink，如何处理下游暂时拒绝写入的错误（如只读、磁盘已满），支持 pause, retry 两种，默认为 pause
+# pause 不再重试并将同步任务置为 paused-by-downstream 状态，每隔 downstream-probe-interval 秒探测下游，下游恢复写入后自动恢复同步任务
+# retry 与其他错误一样重试；downstream-probe-interval 默认为 30
+# For MySQL Sinks, how to handle the downstream refusing the writes for a while, e.g. read-only or disk full,
+# supports pause and retry, the default is pause. pause stops retrying and pauses the changefeed in the state
+# paused-by-downstream, the downstream is probed every downstream-probe-interval seconds and the changefeed is
+# resumed once the downstream accepts the writes again. retry retries the writes as the other errors.
+# downstream-probe-interval is 30 by default
+downstream-unwritable = "pause"
+downstream-probe-interval = 30
+
+# 对于 MQ 类的 Sink，可以丢弃最近发送过的重复行，行由表、handle 和 commit ts 标识，只在窗口内去重，
+# 发送过的行只由每个 Sink 记在内存中，只会去掉同一进程内重试产生的重复行，重启后或由其他 capture 重放的行不会被去重
+# For MQ Sinks, you can drop the duplicate rows sent recently, the rows are identified by the table,
+# the handle and the commit ts, the rows are only deduplicated within the window. The sent rows are
+# remembered in memory by each sink, only the duplicates from the retries within one process are dropped,
+# the rows replayed after a restart or by another capture aren't deduplicated
+[sink.dedup]
+enable = false
+# 行在发送后被记住的秒数，默认为 60
+# The seconds a row is remembered after it's sent, the default is 60
+window = 60
+# 最多记住的行数，默认为 100000
+# The max number of the remembered rows, the default is 100000
+max-rows = 100000
+
+# 对于 MySQL Sink，可以将删除转换为软删除，被删除的行保留在下游，标记列被设置为 1，之后写入的行将标记列设置为 0
+# 下游表需要有标记列，时间列记录删除的提交时间，为空则不记录
+# For MySQL Sinks, you can translate the deletes to the soft deletes, the deleted rows are kept in the downstream
+# with the marker column set to 1, and the rows written later set the marker column to 0. The downstream tables
+# must have the marker column, the time column records the commit time of the delete unless it's empty
+[sink.soft-delete]
+enable = false
+marker-column = "is_deleted"
+time-column = ""
+
+[sorter]
+# 同步任务在一个 capture 中所有表同时进行的排序操作数量上限，0 表示不限制
+# The maximum number of the concurrent sort operations of all the tables of the changefeed in a capture, 0 means unlimited
+concurrency = 0
 
 [cyclic-replication]
 # 是否开启环形复制
@@ -151,6 +430,34 @@ filter-replica-ids = [2,3]
 # 是否同步 DDL
 # Whether to replicate DDL
 sync-ddl = true
+
+[integrity-check]
+# 是否定期比较上下游的表数据，仅支持开启了 sync point 的 MySQL/TiDB Sink
+# Whether to compare the data of tables between upstream and downstream periodically,
+# only the MySQL/TiDB sinks with the sync point enabled are supported
+enable = false
+# 两次检查之间的间隔，单位为秒
+# The interval in seconds between two checks
+interval = 600
+# 上游 TiDB 的 DSN
+# The DSN of the upstream TiDB
+upstream-dsn = "root@tcp(127.0.0.1:4000)/"
+# 需要检查的表，为空时每次检查随机抽取 sample-size 张行数不超过 max-rows 的表
+# The tables to check, sample-size tables whose row counts are not larger than max-rows
+# are sampled in every check if it's empty
+tables = []
+sample-size = 3
+max-rows = 100000
+
+[debug]
+# 是否在 Sink 第一次写入失败时暂停写入而不是让同步任务出错，失败的行可以通过 /debug/paused-sink 查看，恢复后重试写入，
+# 暂停会显示在同步任务的 warnings 中
+# 不能与 sink.table-error-policy = "quarantine" 同时开启
+# Whether to pause the writes of the sink on the first error instead of failing the changefeed,
+# the failing rows can be inspected by /debug/paused-sink, and the writes are retried after resumed.
+# The pause is shown in the warnings of the changefeed.
+# It can't be enabled with sink.table-error-policy = "quarantine"
+pause-on-first-error = false
 `
 	err := ioutil.WriteFile("changefeed.toml", []byte(content), 0644)
 	c.Assert(err, check.IsNil)
@@ -160,26 +467,60 @@ sync-ddl = true
 	c.Assert(err, check.IsNil)
 
 	c.Assert(cfg.CaseSensitive, check.IsTrue)
+	c.Assert(cfg.PriorityClass, check.Equals, config.PriorityClassNormal)
+	c.Assert(cfg.StrictConsistency, check.IsFalse)
+	c.Assert(cfg.GCSpanningTxn, check.Equals, config.GCSpanningTxnWarnAndContinue)
+	c.Assert(cfg.MaxInflightTxns, check.Equals, 0)
+	c.Assert(cfg.DDLOrder, check.Equals, config.DDLOrderDMLFirst)
+	c.Assert(cfg.StartTsInDDL, check.Equals, config.StartTsInDDLReject)
+	c.Assert(cfg.FinishVerification, check.Equals, config.FinishVerificationFlushed)
 	c.Assert(cfg.Filter, check.DeepEquals, &config.FilterConfig{
-		IgnoreTxnStartTs: []uint64{1, 2},
-		Rules:            []string{"*.*", "!test.*"},
+		IgnoreTxnStartTs:    []uint64{1, 2},
+		Rules:               []string{"*.*", "!test.*"},
+		PartialDropDatabase: config.PartialDropDatabaseDropDatabase,
+		DropTablesBatchSize: 64,
 	})
 	c.Assert(cfg.Mounter, check.DeepEquals, &config.MounterConfig{
-		WorkerNum: 16,
+		WorkerNum:            16,
+		DeleteImage:          config.DeleteImageBestEffort,
+		UnknownColumnType:    config.UnknownColumnTypeFail,
+		MissingColumnDefault: config.MissingColumnOriginDefault,
+		TransientErrorRetry:  3,
+		DecodeError:          config.DecodeErrorFail,
 	})
 	c.Assert(cfg.Sink, check.DeepEquals, &config.SinkConfig{
 		DispatchRules: []*config.DispatchRule{
 			{Dispatcher: "ts", Matcher: []string{"test1.*", "test2.*"}},
 			{Dispatcher: "rowid", Matcher: []string{"test3.*", "test4.*"}},
 		},
-		Protocol: "default",
+		Protocol:                "default",
+		EnumFormat:              config.EnumFormatIndex,
+		SetFormat:               config.SetFormatBitmask,
+		BitFormat:               config.BitFormatInteger,
+		Dispatcher:              "default",
+		TableErrorPolicy:        config.TableErrorPolicyFail,
+		ConflictResolution:      config.ConflictResolutionNone,
+		Dedup:                   &config.DedupConfig{Enable: false, Window: 60, MaxRows: 100000},
+		SoftDelete:              &config.SoftDeleteConfig{Enable: false, MarkerColumn: "is_deleted"},
+		DownstreamUnwritable:    config.DownstreamUnwritablePause,
+		DownstreamProbeInterval: 30,
 	})
+	c.Assert(cfg.Sorter, check.DeepEquals, &config.SorterConfig{Concurrency: 0})
 	c.Assert(cfg.Cyclic, check.DeepEquals, &config.CyclicConfig{
 		Enable:          false,
 		ReplicaID:       1,
 		FilterReplicaID: []uint64{2, 3},
 		SyncDDL:         true,
 	})
+	c.Assert(cfg.IntegrityCheck, check.DeepEquals, &config.IntegrityCheckConfig{
+		Enable:      false,
+		Interval:    600,
+		UpstreamDSN: "root@tcp(127.0.0.1:4000)/",
+		Tables:      []string{},
+		SampleSize:  3,
+		MaxRows:     100000,
+	})
+	c.Assert(cfg.Debug, check.DeepEquals, &config.DebugConfig{PauseOnFirstError: false})
 }
 
 func (s *decodeFileSuite) TestShouldReturnErrForUnknownCfgs(c *check.C) {
@@ -39,6 +39,10 @@ var (
 	ownerFlushInterval     time.Duration
 	processorFlushInterval time.Duration
 
+	rejectOverlappingChangefeeds bool
+	gcGuardLag                   time.Duration
+	changefeedMetaRetention      time.Duration
+
 	serverCmd = &cobra.Command{
 		Use:   "server",
 		Short: "Start a TiCDC capture server",
@@ -58,6 +62,9 @@ func init() {
 	serverCmd.Flags().StringVar(&logLevel, "log-level", "info", "log level (etc: debug|info|warn|error)")
 	serverCmd.Flags().DurationVar(&ownerFlushInterval, "owner-flush-interval", time.Millisecond*200, "owner flushes changefeed status interval")
 	serverCmd.Flags().DurationVar(&processorFlushInterval, "processor-flush-interval", time.Millisecond*100, "processor flushes task status interval")
+	serverCmd.Flags().BoolVar(&rejectOverlappingChangefeeds, "reject-overlapping-changefeeds", false, "Mark a changefeed as failed if it replicates the same tables to the same sink as a running changefeed")
+	serverCmd.Flags().DurationVar(&gcGuardLag, "gc-guard-lag", 0, "Pause the changefeeds blocking the GC of upstream one by one, the lowest priority class first, while the GC safepoint lags behind the duration (default 0, never pause)")
+	serverCmd.Flags().DurationVar(&changefeedMetaRetention, "changefeed-meta-retention", cdc.DefaultChangefeedMetaRetention, "The owner deletes the metadata of the removed, finished and failed changefeeds after the duration, a tombstone of each is kept (0 means never delete)")
 	addSecurityFlags(serverCmd.Flags(), true /* isServer */)
 }
 
@@ -82,6 +89,9 @@ func runEServer(cmd *cobra.Command, args []string) error {
 		cdc.Credential(getCredential()),
 		cdc.OwnerFlushInterval(ownerFlushInterval),
 		cdc.ProcessorFlushInterval(processorFlushInterval),
+		cdc.RejectOverlappingChangefeeds(rejectOverlappingChangefeeds),
+		cdc.GCGuardLag(gcGuardLag),
+		cdc.ChangefeedMetaRetention(changefeedMetaRetention),
 	}
 	server, err := cdc.NewServer(opts...)
 	if err != nil {
@@ -22,7 +22,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
-	"strconv"
+	"sort"
 	"strings"
 	"syscall"
 
@@ -39,7 +39,6 @@ import (
 	"github.com/pingcap/ticdc/pkg/httputil"
 	"github.com/pingcap/ticdc/pkg/logutil"
 	"github.com/pingcap/ticdc/pkg/security"
-	"github.com/pingcap/tidb/store/tikv"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"go.etcd.io/etcd/clientv3/concurrency"
@@ -216,17 +215,13 @@ func jsonPrint(cmd *cobra.Command, v interface{}) error {
 }
 
 func verifyStartTs(ctx context.Context, startTs uint64, cli kv.CDCEtcdClient) error {
-	resp, err := cli.Client.Get(ctx, tikv.GcSavedSafePoint)
+	safePoint, err := cli.GetGCSafepoint(ctx)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	if resp.Count == 0 {
+	if safePoint == 0 {
 		return nil
 	}
-	safePoint, err := strconv.ParseUint(string(resp.Kvs[0].Value), 10, 64)
-	if err != nil {
-		return errors.Trace(err)
-	}
 	if startTs < safePoint {
 		return errors.Errorf("startTs %d less than gcSafePoint %d", startTs, safePoint)
 	}
@@ -277,6 +272,62 @@ func verifyTables(ctx context.Context, credential *security.Credential, cfg *con
 	return
 }
 
+// overlappingChangefeed is another changefeed replicating some of the tables to the same sink target
+type overlappingChangefeed struct {
+	ID     model.ChangeFeedID
+	Target string
+	Tables []string
+}
+
+// verifyOverlappingChangefeeds returns the other active changefeeds which replicate any of the tables to the same sink target
+func verifyOverlappingChangefeeds(ctx context.Context, cli kv.CDCEtcdClient, id string, sinkURI string, tables []model.TableName) ([]overlappingChangefeed, error) {
+	target, err := sink.NormalizeSinkTarget(sinkURI)
+	if err != nil || target == "" {
+		return nil, err
+	}
+	_, raw, err := cli.GetChangeFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var overlapping []overlappingChangefeed
+	for otherID, rawInfo := range raw {
+		if otherID == id {
+			continue
+		}
+		info := &model.ChangeFeedInfo{}
+		err := info.Unmarshal(rawInfo.Value)
+		if err != nil {
+			return nil, err
+		}
+		if info.State == model.StateRemoved || info.State == model.StateFinished {
+			continue
+		}
+		otherTarget, err := sink.NormalizeSinkTarget(info.SinkURI)
+		if err != nil || otherTarget != target {
+			continue
+		}
+		err = info.VerifyAndFix()
+		if err != nil {
+			return nil, err
+		}
+		filter, err := filter.NewFilter(info.Config)
+		if err != nil {
+			return nil, err
+		}
+		var overlapped []string
+		for _, table := range tables {
+			if !filter.ShouldIgnoreTable(table.Schema, table.Table) {
+				overlapped = append(overlapped, table.String())
+			}
+		}
+		if len(overlapped) != 0 {
+			overlapping = append(overlapping, overlappingChangefeed{ID: otherID, Target: target, Tables: overlapped})
+		}
+	}
+	sort.Slice(overlapping, func(i, j int) bool { return overlapping[i].ID < overlapping[j].ID })
+	return overlapping, nil
+}
+
 func verifySink(
 	ctx context.Context, sinkURI string, cfg *config.ReplicaConfig, opts map[string]string,
 ) error {
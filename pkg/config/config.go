@@ -25,23 +25,45 @@ import (
 )
 
 var defaultReplicaConfig = &ReplicaConfig{
-	CaseSensitive:  true,
-	EnableOldValue: false,
+	CaseSensitive:      true,
+	EnableOldValue:     false,
+	PriorityClass:      PriorityClassNormal,
+	DDLOrder:           DDLOrderDMLFirst,
+	StartTsInDDL:       StartTsInDDLReject,
+	FinishVerification: FinishVerificationFlushed,
 	Filter: &FilterConfig{
 		Rules: []string{"*.*"},
 	},
 	Mounter: &MounterConfig{
-		WorkerNum: 16,
+		WorkerNum:            16,
+		DeleteImage:          DeleteImageBestEffort,
+		UnknownColumnType:    UnknownColumnTypeFail,
+		MissingColumnDefault: MissingColumnOriginDefault,
+		TransientErrorRetry:  3,
+		DecodeError:          DecodeErrorFail,
 	},
 	Sink: &SinkConfig{
 		Protocol: "default",
 	},
+	Sorter: &SorterConfig{
+		Concurrency: 0,
+	},
 	Cyclic: &CyclicConfig{
 		Enable: false,
 	},
 	Scheduler: &SchedulerConfig{
-		Tp:          "table-number",
-		PollingTime: -1,
+		Tp:                "table-number",
+		PollingTime:       -1,
+		TableMoveInterval: 60,
+	},
+	IntegrityCheck: &IntegrityCheckConfig{
+		Enable:     false,
+		Interval:   600,
+		SampleSize: 3,
+		MaxRows:    100000,
+	},
+	Debug: &DebugConfig{
+		PauseOnFirstError: false,
 	},
 }
 
@@ -49,13 +71,23 @@ var defaultReplicaConfig = &ReplicaConfig{
 type ReplicaConfig replicaConfig
 
 type replicaConfig struct {
-	CaseSensitive  bool             `toml:"case-sensitive" json:"case-sensitive"`
-	EnableOldValue bool             `toml:"enable-old-value" json:"enable-old-value"`
-	Filter         *FilterConfig    `toml:"filter" json:"filter"`
-	Mounter        *MounterConfig   `toml:"mounter" json:"mounter"`
-	Sink           *SinkConfig      `toml:"sink" json:"sink"`
-	Cyclic         *CyclicConfig    `toml:"cyclic-replication" json:"cyclic-replication"`
-	Scheduler      *SchedulerConfig `toml:"scheduler" json:"scheduler"`
+	CaseSensitive      bool                  `toml:"case-sensitive" json:"case-sensitive"`
+	EnableOldValue     bool                  `toml:"enable-old-value" json:"enable-old-value"`
+	PriorityClass      string                `toml:"priority-class" json:"priority-class"`
+	StrictConsistency  bool                  `toml:"strict-consistency" json:"strict-consistency"`
+	GCSpanningTxn      string                `toml:"gc-spanning-txn" json:"gc-spanning-txn"`
+	MaxInflightTxns    int                   `toml:"max-inflight-txns" json:"max-inflight-txns"`
+	DDLOrder           string                `toml:"ddl-order" json:"ddl-order"`
+	StartTsInDDL       string                `toml:"start-ts-in-ddl" json:"start-ts-in-ddl"`
+	FinishVerification string                `toml:"finish-verification" json:"finish-verification"`
+	Filter             *FilterConfig         `toml:"filter" json:"filter"`
+	Mounter            *MounterConfig        `toml:"mounter" json:"mounter"`
+	Sink               *SinkConfig           `toml:"sink" json:"sink"`
+	Sorter             *SorterConfig         `toml:"sorter" json:"sorter"`
+	Cyclic             *CyclicConfig         `toml:"cyclic-replication" json:"cyclic-replication"`
+	Scheduler          *SchedulerConfig      `toml:"scheduler" json:"scheduler"`
+	IntegrityCheck     *IntegrityCheckConfig `toml:"integrity-check" json:"integrity-check"`
+	Debug              *DebugConfig          `toml:"debug" json:"debug"`
 }
 
 // Marshal returns the json marshal format of a ReplicationConfig
@@ -90,6 +122,15 @@ func (c *ReplicaConfig) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Redacted returns a copy of the config without the password of the upstream dsn
+func (c *ReplicaConfig) Redacted() *ReplicaConfig {
+	clone := c.Clone()
+	if clone.IntegrityCheck != nil {
+		clone.IntegrityCheck.UpstreamDSN = RedactDSN(clone.IntegrityCheck.UpstreamDSN)
+	}
+	return clone
+}
+
 // Clone clones a replication
 func (c *ReplicaConfig) Clone() *ReplicaConfig {
 	str, err := c.Marshal()
@@ -0,0 +1,34 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import cerror "github.com/pingcap/ticdc/pkg/errors"
+
+// The orders of a DDL and the DMLs sharing its commit ts in the downstream
+const (
+	// DDLOrderDMLFirst replicates the DMLs before the DDL, it is the default. The DMLs committed
+	// with the DDL are written in the schema before the DDL, so it's the safe order.
+	DDLOrderDMLFirst = "dml-first"
+	// DDLOrderDDLFirst executes the DDL before replicating the DMLs
+	DDLOrderDDLFirst = "ddl-first"
+)
+
+// ValidateDDLOrder checks whether the order of a DDL and the DMLs sharing its commit ts is supported
+func ValidateDDLOrder(order string) error {
+	switch order {
+	case "", DDLOrderDMLFirst, DDLOrderDDLFirst:
+		return nil
+	}
+	return cerror.ErrDDLOrderInvalid.GenWithStackByArgs(order)
+}
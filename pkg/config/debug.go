@@ -0,0 +1,37 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import cerror "github.com/pingcap/ticdc/pkg/errors"
+
+// DebugConfig represents the config for debugging a changefeed
+type DebugConfig struct {
+	// PauseOnFirstError pauses the writes of the sink on the first error instead of failing
+	// the changefeed, the failing rows are kept until the writes are resumed by the HTTP API.
+	// The pause is reported in the warnings of the changefeed.
+	PauseOnFirstError bool `toml:"pause-on-first-error" json:"pause-on-first-error"`
+}
+
+// Validate checks whether the debug config conflicts with the sink config
+func (c *DebugConfig) Validate(sink *SinkConfig) error {
+	if c == nil || !c.PauseOnFirstError {
+		return nil
+	}
+	if sink != nil && sink.TableErrorPolicy == TableErrorPolicyQuarantine {
+		// the quarantine never returns the errors of the tables, so nothing would pause
+		return cerror.ErrDebugConfigInvalid.GenWithStackByArgs(
+			"pause-on-first-error can't be enabled with the table-error-policy " + TableErrorPolicyQuarantine)
+	}
+	return nil
+}
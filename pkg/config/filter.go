@@ -24,4 +24,28 @@ type FilterConfig struct {
 	*filter.MySQLReplicationRules
 	IgnoreTxnStartTs []uint64           `toml:"ignore-txn-start-ts" json:"ignore-txn-start-ts"`
 	DDLAllowlist     []model.ActionType `toml:"ddl-allow-list" json:"ddl-allow-list"`
+	EventFilters     []*EventFilterRule `toml:"event-filters" json:"event-filters"`
+	// PartialDropDatabase chooses how a DROP DATABASE is replicated if the rules exclude some tables of the database
+	PartialDropDatabase string `toml:"partial-drop-database" json:"partial-drop-database"`
+	// DropTablesBatchSize is the maximum number of the tables a translated DROP TABLE drops,
+	// the default is used if it's 0
+	DropTablesBatchSize int `toml:"drop-tables-batch-size" json:"drop-tables-batch-size"`
+	// ExtraSystemSchemas are the patterns of the schemas ignored like the built-in system schemas,
+	// which are always ignored before the rules are applied
+	ExtraSystemSchemas []string `toml:"extra-system-schemas" json:"extra-system-schemas"`
+}
+
+// The ways a DROP DATABASE is replicated if the rules exclude some tables of the database
+const (
+	// PartialDropDatabaseDropDatabase emits the DROP DATABASE, the excluded tables are dropped downstream, it is the default
+	PartialDropDatabaseDropDatabase = "drop-database"
+	// PartialDropDatabaseDropTables emits the DROP TABLEs of the replicated tables, the excluded tables are preserved
+	PartialDropDatabaseDropTables = "drop-tables"
+)
+
+// EventFilterRule represents which types of row changed events are ignored for the matched tables
+type EventFilterRule struct {
+	Matcher []string `toml:"matcher" json:"matcher"`
+	// IgnoreEvent supports "insert", "update" and "delete"
+	IgnoreEvent []string `toml:"ignore-event" json:"ignore-event"`
 }
@@ -0,0 +1,37 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import cerror "github.com/pingcap/ticdc/pkg/errors"
+
+// The ways to verify the downstream is caught up before a changefeed with a target ts is finished
+const (
+	// FinishVerificationNone finishes the changefeed once its checkpoint ts reaches the target ts
+	FinishVerificationNone = "none"
+	// FinishVerificationFlushed also waits for the sinks of all the processors to report the flushed
+	// ts reaching the target ts, it is the default
+	FinishVerificationFlushed = "flushed"
+	// FinishVerificationAcked also waits for a resolved ts message at the target ts to be acked by the
+	// MQ sinks, it's the same as FinishVerificationFlushed for the other sinks
+	FinishVerificationAcked = "acked"
+)
+
+// ValidateFinishVerification checks whether the verification of finishing a changefeed is supported
+func ValidateFinishVerification(verification string) error {
+	switch verification {
+	case "", FinishVerificationNone, FinishVerificationFlushed, FinishVerificationAcked:
+		return nil
+	}
+	return cerror.ErrFinishVerificationInvalid.GenWithStackByArgs(verification)
+}
@@ -0,0 +1,34 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import cerror "github.com/pingcap/ticdc/pkg/errors"
+
+// The ways to handle a transaction which starts before the GC safepoint and commits after it,
+// some rows of such a transaction may be missing
+const (
+	// GCSpanningTxnFail fails the changefeed
+	GCSpanningTxnFail = "fail"
+	// GCSpanningTxnWarnAndContinue logs a warning and replicates the rows received, it is the default
+	GCSpanningTxnWarnAndContinue = "warn-and-continue"
+)
+
+// ValidateGCSpanningTxn checks whether the policy of the transactions spanning the GC safepoint is supported
+func ValidateGCSpanningTxn(policy string) error {
+	switch policy {
+	case "", GCSpanningTxnFail, GCSpanningTxnWarnAndContinue:
+		return nil
+	}
+	return cerror.ErrGCSpanningTxnPolicyInvalid.GenWithStackByArgs(policy)
+}
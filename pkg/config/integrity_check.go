@@ -0,0 +1,108 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// RedactedPassword replaces the password of the upstream dsn wherever the config leaves the owner
+const RedactedPassword = "redacted"
+
+// IntegrityCheckConfig represents the config of the integrity check of a changefeed, which
+// periodically compares the row counts and checksums of tables between the upstream at a
+// syncpoint and the downstream at the corresponding ts.
+type IntegrityCheckConfig struct {
+	Enable bool `toml:"enable" json:"enable"`
+	// Interval is the interval in seconds between two checks
+	Interval int `toml:"interval" json:"interval"`
+	// UpstreamDSN is the DSN of the upstream TiDB in the form of [user[:password]@][net[(addr)]]/
+	UpstreamDSN string `toml:"upstream-dsn" json:"upstream-dsn"`
+	// Tables are the tables to check, such as "test.t1". If it's empty, SampleSize tables
+	// whose estimated row counts are not larger than MaxRows are sampled in every check.
+	Tables     []string `toml:"tables" json:"tables"`
+	SampleSize int      `toml:"sample-size" json:"sample-size"`
+	MaxRows    int64    `toml:"max-rows" json:"max-rows"`
+}
+
+// Validate checks whether the integrity check can be enabled for the changefeed, it requires
+// a mysql or tidb sink with the syncpoint enabled.
+func (c *IntegrityCheckConfig) Validate(sinkScheme string, syncPointEnabled bool) error {
+	if c == nil || !c.Enable {
+		return nil
+	}
+	switch strings.ToLower(sinkScheme) {
+	case "mysql", "tidb", "mysql+ssl", "tidb+ssl":
+	default:
+		return cerror.ErrIntegrityCheckInvalid.GenWithStackByArgs(
+			"the sink scheme " + sinkScheme + " is not supported, only mysql and tidb sinks are supported")
+	}
+	if !syncPointEnabled {
+		return cerror.ErrIntegrityCheckInvalid.GenWithStackByArgs("the sync point must be enabled")
+	}
+	if c.UpstreamDSN == "" {
+		return cerror.ErrIntegrityCheckInvalid.GenWithStackByArgs("the upstream dsn must be specified")
+	}
+	if c.Interval <= 0 {
+		return cerror.ErrIntegrityCheckInvalid.GenWithStackByArgs("the interval must be positive")
+	}
+	if len(c.Tables) == 0 && c.SampleSize <= 0 {
+		return cerror.ErrIntegrityCheckInvalid.GenWithStackByArgs("either the tables or a positive sample size must be specified")
+	}
+	for _, table := range c.Tables {
+		parts := strings.Split(table, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return cerror.ErrIntegrityCheckInvalid.GenWithStackByArgs("the table " + table + " is not in the form of schema.table")
+		}
+	}
+	return nil
+}
+
+// RedactDSN replaces the password of a dsn in the form of [user[:password]@][net[(addr)]]/ with RedactedPassword
+func RedactDSN(dsn string) string {
+	user, rest, ok := splitDSNUser(dsn)
+	if !ok {
+		return dsn
+	}
+	if i := strings.IndexByte(user, ':'); i >= 0 {
+		return user[:i+1] + RedactedPassword + rest
+	}
+	return dsn
+}
+
+// IsDSNRedacted returns true if the password of the dsn is redacted
+func IsDSNRedacted(dsn string) bool {
+	user, _, ok := splitDSNUser(dsn)
+	if !ok {
+		return false
+	}
+	i := strings.IndexByte(user, ':')
+	return i >= 0 && user[i+1:] == RedactedPassword
+}
+
+// splitDSNUser splits the user info from the dsn like the mysql driver, the password
+// may contain '@' and '/' so the last '@' before the last '/' ends the user info.
+func splitDSNUser(dsn string) (user string, rest string, ok bool) {
+	slash := strings.LastIndexByte(dsn, '/')
+	if slash < 0 {
+		return "", "", false
+	}
+	at := strings.LastIndexByte(dsn[:slash], '@')
+	if at < 0 {
+		return "", "", false
+	}
+	return dsn[:at], dsn[at:], true
+}
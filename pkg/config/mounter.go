@@ -13,7 +13,93 @@
 
 package config
 
+import cerror "github.com/pingcap/ticdc/pkg/errors"
+
+// The ways to handle a deleted row whose old value is unavailable when old value is enabled
+const (
+	// DeleteImageBestEffort emits the deleted row with the columns available, the handle only in most cases
+	DeleteImageBestEffort = "best-effort"
+	// DeleteImageRequire fails the changefeed if the old value of a deleted row is missing
+	DeleteImageRequire = "require"
+	// DeleteImageFetch reads the old value of a deleted row from TiKV at the ts before the delete
+	DeleteImageFetch = "fetch"
+)
+
+// The ways to handle a column whose type is unknown to the mounter
+const (
+	// UnknownColumnTypeFail fails the changefeed
+	UnknownColumnTypeFail = "fail"
+	// UnknownColumnTypeSkipColumn emits the row without the column
+	UnknownColumnTypeSkipColumn = "skip-column"
+	// UnknownColumnTypeRawBytes emits the raw encoded bytes of the column
+	UnknownColumnTypeRawBytes = "raw-bytes"
+)
+
+// The values of a column missing in a row, the row is written before the column is added by
+// `ALTER TABLE ... ADD COLUMN`, or the column is null and has no default value
+const (
+	// MissingColumnOriginDefault uses the default value of the column when it's added, which is how TiDB reads the row
+	MissingColumnOriginDefault = "origin-default"
+	// MissingColumnCurrentDefault uses the current default value of the column, or null if the column is nullable
+	MissingColumnCurrentDefault = "current-default"
+)
+
+// The ways to handle a row failed to mount, after the retries of the transient errors
+const (
+	// DecodeErrorFail fails the changefeed
+	DecodeErrorFail = "fail"
+	// DecodeErrorSkip drops the row with an error log
+	DecodeErrorSkip = "skip"
+)
+
 // MounterConfig represents mounter config for a changefeed
 type MounterConfig struct {
-	WorkerNum int `toml:"worker-num" json:"worker-num"`
+	WorkerNum         int    `toml:"worker-num" json:"worker-num"`
+	DeleteImage       string `toml:"delete-image" json:"delete-image"`
+	UnknownColumnType string `toml:"unknown-column-type" json:"unknown-column-type"`
+	// MissingColumnDefault is the value of a column missing in a row
+	MissingColumnDefault string `toml:"missing-column-default" json:"missing-column-default"`
+	// CheckSchemaAtStart checks whether the schemas of all the replicated tables can be reconstructed
+	// when the changefeed starts, which costs some startup time. The changefeed is paused if any
+	// can't, and can be resumed after the config is updated, e.g. the unknown-column-type
+	CheckSchemaAtStart bool `toml:"check-schema-at-start" json:"check-schema-at-start"`
+	// TransientErrorRetry is the max retries of a row failed by a dependency momentarily unavailable,
+	// e.g. the schema storage catching up, the retries back off exponentially
+	TransientErrorRetry int `toml:"transient-error-retry" json:"transient-error-retry"`
+	// DecodeError is how to handle a row failed to mount
+	DecodeError string `toml:"decode-error" json:"decode-error"`
+}
+
+// Validate checks whether the mounter config is valid
+func (c *MounterConfig) Validate() error {
+	if err := c.ValidateDeleteImage(); err != nil {
+		return err
+	}
+	switch c.MissingColumnDefault {
+	case "", MissingColumnOriginDefault, MissingColumnCurrentDefault:
+	default:
+		return cerror.ErrMissingColumnDefaultInvalid.GenWithStackByArgs(c.MissingColumnDefault)
+	}
+	if c.TransientErrorRetry < 0 {
+		return cerror.ErrDecodeErrorPolicyInvalid.GenWithStack("negative transient-error-retry %d", c.TransientErrorRetry)
+	}
+	switch c.DecodeError {
+	case "", DecodeErrorFail, DecodeErrorSkip:
+	default:
+		return cerror.ErrDecodeErrorPolicyInvalid.GenWithStackByArgs(c.DecodeError)
+	}
+	switch c.UnknownColumnType {
+	case "", UnknownColumnTypeFail, UnknownColumnTypeSkipColumn, UnknownColumnTypeRawBytes:
+		return nil
+	}
+	return cerror.ErrUnknownColumnTypePolicyInvalid.GenWithStackByArgs(c.UnknownColumnType)
+}
+
+// ValidateDeleteImage checks whether the delete image mode is supported
+func (c *MounterConfig) ValidateDeleteImage() error {
+	switch c.DeleteImage {
+	case "", DeleteImageBestEffort, DeleteImageRequire, DeleteImageFetch:
+		return nil
+	}
+	return cerror.ErrDeleteImageInvalid.GenWithStackByArgs(c.DeleteImage)
 }
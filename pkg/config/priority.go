@@ -0,0 +1,49 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import cerror "github.com/pingcap/ticdc/pkg/errors"
+
+// The priority classes of changefeeds, the owner sheds the changefeeds of lower priority first
+// when the cluster is under pressure
+const (
+	// PriorityClassCritical changefeeds are never paused by the owner automatically
+	PriorityClassCritical = "critical"
+	// PriorityClassNormal is the default priority class
+	PriorityClassNormal = "normal"
+	// PriorityClassBestEffort changefeeds are the first to be paused under pressure
+	PriorityClassBestEffort = "best-effort"
+)
+
+// ValidatePriorityClass checks whether the priority class is supported
+func ValidatePriorityClass(class string) error {
+	switch class {
+	case "", PriorityClassCritical, PriorityClassNormal, PriorityClassBestEffort:
+		return nil
+	}
+	return cerror.ErrPriorityClassInvalid.GenWithStackByArgs(class)
+}
+
+// PriorityRank returns the rank of the priority class, a higher rank means a higher priority.
+// An empty priority class is treated as normal.
+func PriorityRank(class string) int {
+	switch class {
+	case PriorityClassCritical:
+		return 2
+	case PriorityClassBestEffort:
+		return 0
+	default:
+		return 1
+	}
+}
@@ -13,9 +13,31 @@
 
 package config
 
+import cerror "github.com/pingcap/ticdc/pkg/errors"
+
 // SchedulerConfig represents scheduler config for a changefeed
 type SchedulerConfig struct {
 	Tp string `toml:"type" json:"type"`
 	// PollingTime represents the polling cycle of checking the skewness of workload and try to do schedule if needed
 	PollingTime int `toml:"polling-time" json:"polling-time"`
+	// MaxTableMoves bounds the rebalance moves initiated in every TableMoveInterval seconds, so that the
+	// incremental scans of the moved tables are spread out, 0 means unlimited. The manual moves and the
+	// rescans are not limited. The interval of the configs created before it is added is 0, which is
+	// taken as 60 seconds.
+	MaxTableMoves     int `toml:"max-table-moves" json:"max-table-moves"`
+	TableMoveInterval int `toml:"table-move-interval" json:"table-move-interval"`
+}
+
+// Validate checks whether the scheduler config is valid
+func (c *SchedulerConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.MaxTableMoves < 0 {
+		return cerror.ErrSchedulerConfigInvalid.GenWithStackByArgs("max-table-moves must not be negative")
+	}
+	if c.TableMoveInterval < 0 {
+		return cerror.ErrSchedulerConfigInvalid.GenWithStackByArgs("table-move-interval must not be negative")
+	}
+	return nil
 }
@@ -13,10 +13,163 @@
 
 package config
 
+import (
+	"strings"
+	"time"
+
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// The representations of the ENUM, SET and BIT values in the messages of the MQ sinks
+const (
+	// EnumFormatIndex encodes an ENUM value as the 1-based index of the element, it is the default
+	EnumFormatIndex = "index"
+	// EnumFormatName encodes an ENUM value as the name of the element
+	EnumFormatName = "name"
+	// SetFormatBitmask encodes a SET value as the bitmask of the elements, it is the default
+	SetFormatBitmask = "bitmask"
+	// SetFormatString encodes a SET value as the comma separated names of the elements
+	SetFormatString = "string"
+	// BitFormatInteger encodes a BIT value as an unsigned integer, it is the default
+	BitFormatInteger = "integer"
+	// BitFormatBase64 encodes a BIT value as the base64 of its big-endian bytes
+	BitFormatBase64 = "base64"
+)
+
+// The ways the MySQL sink handles a table whose rows still fail to be written after the retries
+const (
+	// TableErrorPolicyFail fails the changefeed, it is the default
+	TableErrorPolicyFail = "fail"
+	// TableErrorPolicyQuarantine quarantines the table, the rows of the table are discarded until the
+	// table is released, the other tables keep replicating. There is no dead-letter queue, the discarded
+	// rows are not kept, the released table is re-scanned from the first row failed to be written instead
+	TableErrorPolicyQuarantine = "quarantine"
+)
+
+// The ways the MySQL sink handles the downstream refusing the writes for a while, e.g. read-only or disk full
+const (
+	// DownstreamUnwritablePause pauses the changefeed without retrying, the changefeed is resumed
+	// once a probe finds the downstream accepts the writes again, it is the default
+	DownstreamUnwritablePause = "pause"
+	// DownstreamUnwritableRetry retries the writes as the other errors
+	DownstreamUnwritableRetry = "retry"
+)
+
+// The ways the MySQL sink resolves the conflicting writes of several changefeeds to the same downstream rows
+const (
+	// ConflictResolutionNone writes the rows as they arrive, it is the default
+	ConflictResolutionNone = "none"
+	// ConflictResolutionLastWriterWins keeps the row of the largest commit ts, which is stored
+	// in the column _cdc_commit_ts the sink adds to the downstream tables
+	ConflictResolutionLastWriterWins = "last-writer-wins"
+)
+
 // SinkConfig represents sink config for a changefeed
 type SinkConfig struct {
 	DispatchRules []*DispatchRule `toml:"dispatchers" json:"dispatchers"`
 	Protocol      string          `toml:"protocol" json:"protocol"`
+	Protobuf      *ProtobufConfig `toml:"protobuf" json:"protobuf,omitempty"`
+	// DeduplicateDDL records the DDLs emitted to the sink in etcd, so that they are
+	// not emitted again after the owner fails over
+	DeduplicateDDL bool `toml:"deduplicate-ddl" json:"deduplicate-ddl"`
+	// EnumFormat, SetFormat and BitFormat choose the representations of the ENUM, SET and BIT
+	// values in the messages, the MySQL sink always writes the values the columns store
+	EnumFormat string `toml:"enum-format" json:"enum-format"`
+	SetFormat  string `toml:"set-format" json:"set-format"`
+	BitFormat  string `toml:"bit-format" json:"bit-format"`
+	// FallbackProtocol encodes the rows whose values the protocol can't encode, e.g. the rows with
+	// the column types Avro doesn't support, the rows are not replicated if it's empty,
+	// the other encoding errors, e.g. of the schema registry, fail the changefeed even if it's set
+	FallbackProtocol string `toml:"fallback-protocol" json:"fallback-protocol"`
+	// Dispatcher dispatches the rows of the tables no dispatch rule matches, the default is used if it's empty
+	Dispatcher string `toml:"dispatcher" json:"dispatcher"`
+	// CommitTime adds the wall-clock commit time derived from the commit ts to the messages,
+	// the commit time is in UTC if CommitTimeZone is empty
+	CommitTime     bool   `toml:"commit-time" json:"commit-time"`
+	CommitTimeZone string `toml:"commit-time-zone" json:"commit-time-zone"`
+	// TableErrorPolicy chooses how the MySQL sink handles a table whose rows still fail after the retries
+	TableErrorPolicy string `toml:"table-error-policy" json:"table-error-policy"`
+	// Dedup drops the duplicate rows before the MQ sinks encode them
+	Dedup *DedupConfig `toml:"dedup" json:"dedup,omitempty"`
+	// CompactInsert encodes the insert events of the default protocol as the column values and types only,
+	// without the column flags other than binary and the operation wrapper. It requires EnableOldValue,
+	// without which the inserts can't be told from the updates
+	CompactInsert bool `toml:"compact-insert" json:"compact-insert"`
+	// ReconcileSchema adds the columns the downstream tables lack before the changefeed starts or
+	// resumes replicating, it modifies the downstream schemas and is supported by the MySQL sink only
+	ReconcileSchema bool `toml:"reconcile-schema" json:"reconcile-schema"`
+	// ConflictResolution chooses how the MySQL sink resolves the conflicting writes of several
+	// changefeeds to the same downstream rows, the last writer wins modifies the downstream schemas
+	ConflictResolution string `toml:"conflict-resolution" json:"conflict-resolution"`
+	// SoftDelete keeps the deleted rows in the downstream with a marker, it's supported by the MySQL sink only
+	SoftDelete *SoftDeleteConfig `toml:"soft-delete" json:"soft-delete,omitempty"`
+	// DeleteKeyOnly trims the old values of the deletes of the MQ sinks to the handle key columns,
+	// the deletes of the tables without a handle key keep all the columns
+	DeleteKeyOnly bool `toml:"delete-key-only" json:"delete-key-only"`
+	// DownstreamUnwritable chooses how the MySQL sink handles the downstream refusing the writes for a while,
+	// DownstreamProbeInterval is the seconds between the probes of the downstream of a paused changefeed
+	DownstreamUnwritable    string `toml:"downstream-unwritable" json:"downstream-unwritable"`
+	DownstreamProbeInterval int    `toml:"downstream-probe-interval" json:"downstream-probe-interval"`
+}
+
+// ValidateValueFormat checks whether the representations of the ENUM, SET and BIT values are supported
+func (c *SinkConfig) ValidateValueFormat() error {
+	switch c.EnumFormat {
+	case "", EnumFormatIndex, EnumFormatName:
+	default:
+		return cerror.ErrValueFormatInvalid.GenWithStackByArgs("enum", c.EnumFormat)
+	}
+	switch c.SetFormat {
+	case "", SetFormatBitmask, SetFormatString:
+	default:
+		return cerror.ErrValueFormatInvalid.GenWithStackByArgs("set", c.SetFormat)
+	}
+	switch c.BitFormat {
+	case "", BitFormatInteger, BitFormatBase64:
+	default:
+		return cerror.ErrValueFormatInvalid.GenWithStackByArgs("bit", c.BitFormat)
+	}
+	return nil
+}
+
+// ValidateFallbackProtocol checks whether the fallback protocol is supported, the fallback
+// protocol must not rely on any external schema
+func (c *SinkConfig) ValidateFallbackProtocol() error {
+	switch strings.ToLower(c.FallbackProtocol) {
+	case "":
+		return nil
+	case "default", "canal", "maxwell":
+	default:
+		return cerror.ErrFallbackProtocolInvalid.GenWithStackByArgs(c.FallbackProtocol, "unsupported fallback protocol")
+	}
+	if strings.EqualFold(c.FallbackProtocol, c.Protocol) {
+		return cerror.ErrFallbackProtocolInvalid.GenWithStackByArgs(c.FallbackProtocol, "the fallback protocol is the same as the protocol")
+	}
+	return nil
+}
+
+// ValidateDispatchers checks whether the dispatchers of the dispatch rules and the changefeed are supported
+func (c *SinkConfig) ValidateDispatchers() error {
+	if c.Dispatcher != "" && !isValidDispatcher(c.Dispatcher) {
+		return cerror.ErrDispatcherInvalid.GenWithStackByArgs(c.Dispatcher, "unsupported dispatcher")
+	}
+	for _, rule := range c.DispatchRules {
+		if !isValidDispatcher(rule.Dispatcher) {
+			return cerror.ErrDispatcherInvalid.GenWithStackByArgs(rule.Dispatcher, "unsupported dispatcher")
+		}
+		if len(rule.Matcher) == 0 {
+			return cerror.ErrDispatcherInvalid.GenWithStackByArgs(rule.Dispatcher, "the matcher is empty")
+		}
+	}
+	return nil
+}
+
+func isValidDispatcher(dispatcher string) bool {
+	switch strings.ToLower(dispatcher) {
+	case "default", "rowid", "ts", "table", "index-value":
+		return true
+	}
+	return false
 }
 
 // DispatchRule represents partition rule for a table
@@ -24,3 +177,100 @@ type DispatchRule struct {
 	Matcher    []string `toml:"matcher" json:"matcher"`
 	Dispatcher string   `toml:"dispatcher" json:"dispatcher"`
 }
+
+// ProtobufConfig represents the config of the protobuf protocol
+type ProtobufConfig struct {
+	// DescriptorFile is the path of a serialized FileDescriptorSet, which can be
+	// generated by `protoc --include_imports --descriptor_set_out`.
+	// The file must be readable on every capture.
+	DescriptorFile string                 `toml:"descriptor-file" json:"descriptor-file"`
+	MessageRules   []*ProtobufMessageRule `toml:"messages" json:"messages"`
+}
+
+// DedupConfig represents the config of the deduplication of the rows of the MQ sinks. The rows are
+// identified by the table, the handle and the commit ts, a row is dropped if the same row is sent
+// within the window, so the deduplication is best-effort. The sent rows are remembered in memory by
+// each sink, it only suppresses the duplicates from the retries within one process, the rows replayed
+// after a restart or by another capture are sent again.
+type DedupConfig struct {
+	Enable bool `toml:"enable" json:"enable"`
+	// Window is the seconds a row is remembered after it's sent
+	Window int `toml:"window" json:"window"`
+	// MaxRows is the max number of the remembered rows, the oldest rows are forgotten first
+	MaxRows int `toml:"max-rows" json:"max-rows"`
+}
+
+// Validate checks the dedup config
+func (c *DedupConfig) Validate() error {
+	if c == nil || !c.Enable {
+		return nil
+	}
+	if c.Window < 0 {
+		return cerror.ErrDedupInvalidConfig.GenWithStack("window must not be negative, got %d", c.Window)
+	}
+	if c.MaxRows < 0 {
+		return cerror.ErrDedupInvalidConfig.GenWithStack("max-rows must not be negative, got %d", c.MaxRows)
+	}
+	return nil
+}
+
+// ProtobufMessageRule represents which protobuf message a table is encoded into
+type ProtobufMessageRule struct {
+	Matcher []string `toml:"matcher" json:"matcher"`
+	// Message is the full name of the message, e.g. "pkg.Order"
+	Message string `toml:"message" json:"message"`
+}
+
+// ValidateCommitTimeZone checks the time zone of the commit time
+func (c *SinkConfig) ValidateCommitTimeZone() error {
+	if c.CommitTimeZone == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(c.CommitTimeZone); err != nil {
+		return cerror.WrapError(cerror.ErrCommitTimeZoneInvalid, err)
+	}
+	return nil
+}
+
+// ValidateTableErrorPolicy checks whether the policy of the tables failing to be written is supported
+func (c *SinkConfig) ValidateTableErrorPolicy() error {
+	switch c.TableErrorPolicy {
+	case "", TableErrorPolicyFail, TableErrorPolicyQuarantine:
+		return nil
+	}
+	return cerror.ErrTableErrorPolicyInvalid.GenWithStackByArgs(c.TableErrorPolicy)
+}
+
+// ValidateReconcileSchema checks whether the sink of the scheme can reconcile the downstream schemas
+func (c *SinkConfig) ValidateReconcileSchema(sinkScheme string) error {
+	if !c.ReconcileSchema {
+		return nil
+	}
+	switch strings.ToLower(sinkScheme) {
+	case "mysql", "tidb", "mysql+ssl", "tidb+ssl":
+		return nil
+	}
+	return cerror.ErrReconcileUnsupported.GenWithStackByArgs(sinkScheme)
+}
+
+// ValidateConflictResolution checks whether the resolution of the conflicting writes is supported
+func (c *SinkConfig) ValidateConflictResolution() error {
+	switch c.ConflictResolution {
+	case "", ConflictResolutionNone, ConflictResolutionLastWriterWins:
+		return nil
+	}
+	return cerror.ErrConflictResolutionInvalid.GenWithStackByArgs(c.ConflictResolution)
+}
+
+// ValidateDownstreamUnwritable checks how the downstream refusing the writes is handled
+func (c *SinkConfig) ValidateDownstreamUnwritable() error {
+	switch c.DownstreamUnwritable {
+	case "", DownstreamUnwritablePause, DownstreamUnwritableRetry:
+	default:
+		return cerror.ErrDownstreamUnwritableInvalid.GenWithStackByArgs("unsupported downstream-unwritable " + c.DownstreamUnwritable)
+	}
+	if c.DownstreamProbeInterval < 0 {
+		return cerror.ErrDownstreamUnwritableInvalid.GenWithStackByArgs("negative downstream-probe-interval")
+	}
+	return nil
+}
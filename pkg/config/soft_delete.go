@@ -0,0 +1,43 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+)
+
+// SoftDeleteConfig represents the config of the soft deletes of the MySQL sink. A deleted row is kept in
+// the downstream with the marker column set to 1, and the time column set to the commit time of the delete
+// if it's not empty. The rows written later set the marker column to 0 and the time column to NULL.
+type SoftDeleteConfig struct {
+	Enable       bool   `toml:"enable" json:"enable"`
+	MarkerColumn string `toml:"marker-column" json:"marker-column"`
+	TimeColumn   string `toml:"time-column" json:"time-column"`
+}
+
+// Validate checks the soft delete config
+func (c *SoftDeleteConfig) Validate() error {
+	if c == nil || !c.Enable {
+		return nil
+	}
+	if c.MarkerColumn == "" {
+		return cerror.ErrSoftDeleteInvalidConfig.GenWithStack("marker-column must not be empty")
+	}
+	if strings.EqualFold(c.MarkerColumn, c.TimeColumn) {
+		return cerror.ErrSoftDeleteInvalidConfig.GenWithStack("time-column must differ from marker-column %s", c.MarkerColumn)
+	}
+	return nil
+}
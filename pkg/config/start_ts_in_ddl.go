@@ -0,0 +1,35 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import cerror "github.com/pingcap/ticdc/pkg/errors"
+
+// The ways to handle a changefeed whose start ts falls in the execution of a DDL job, the schemas
+// of the tables of the job at the start ts are in the intermediate states of the job
+const (
+	// StartTsInDDLWait starts the changefeed after the DDL jobs finish, the schemas are still bootstrapped
+	// at the start ts and the finished jobs are replicated on top of them, so it only delays the start
+	StartTsInDDLWait = "wait"
+	// StartTsInDDLReject fails the changefeed, it should be created again with another start ts, it is the default
+	StartTsInDDLReject = "reject"
+)
+
+// ValidateStartTsInDDL checks whether the policy of the start ts falling in a DDL job is supported
+func ValidateStartTsInDDL(policy string) error {
+	switch policy {
+	case "", StartTsInDDLWait, StartTsInDDLReject:
+		return nil
+	}
+	return cerror.ErrStartTsInDDLInvalid.GenWithStackByArgs(policy)
+}
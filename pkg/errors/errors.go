@@ -26,6 +26,8 @@ var (
 	ErrTaskStatusNotExists     = errors.Normalize("task status not exists, key: %s", errors.RFCCodeText("CDC:ErrTaskStatusNotExists"))
 	ErrTaskPositionNotExists   = errors.Normalize("task position not exists, key: %s", errors.RFCCodeText("CDC:ErrTaskPositionNotExists"))
 	ErrCaptureNotExist         = errors.Normalize("capture not exists, key: %s", errors.RFCCodeText("CDC:ErrCaptureNotExist"))
+	ErrQuarantineNotExists     = errors.Normalize("quarantined item not exists, key: %s", errors.RFCCodeText("CDC:ErrQuarantineNotExists"))
+	ErrTableNotQuarantined     = errors.Normalize("table %d of changefeed %s is not quarantined", errors.RFCCodeText("CDC:ErrTableNotQuarantined"))
 	ErrGetAllStoresFailed      = errors.Normalize("get stores from pd failed", errors.RFCCodeText("CDC:ErrGetAllStoresFailed"))
 	ErrMetaListDatabases       = errors.Normalize("meta store list databases", errors.RFCCodeText("CDC:ErrMetaListDatabases"))
 	ErrGRPCDialFailed          = errors.Normalize("grpc dial failed", errors.RFCCodeText("CDC:ErrGRPCDialFailed"))
@@ -55,9 +57,33 @@ var (
 	ErrNewStore               = errors.Normalize("new store faile", errors.RFCCodeText("CDC:ErrNewStore"))
 
 	// rule related errors
-	ErrEncodeFailed      = errors.Normalize("encode failed: %s", errors.RFCCodeText("CDC:ErrEncodeFailed"))
-	ErrDecodeFailed      = errors.Normalize("decode failed: %s", errors.RFCCodeText("CDC:ErrDecodeFailed"))
-	ErrFilterRuleInvalid = errors.Normalize("filter rule is invalid", errors.RFCCodeText("CDC:ErrFilterRuleInvalid"))
+	ErrEncodeFailed       = errors.Normalize("encode failed: %s", errors.RFCCodeText("CDC:ErrEncodeFailed"))
+	ErrDecodeFailed       = errors.Normalize("decode failed: %s", errors.RFCCodeText("CDC:ErrDecodeFailed"))
+	ErrFilterRuleInvalid  = errors.Normalize("filter rule is invalid", errors.RFCCodeText("CDC:ErrFilterRuleInvalid"))
+	ErrDeleteImageInvalid = errors.Normalize("invalid delete image mode: %s", errors.RFCCodeText("CDC:ErrDeleteImageInvalid"))
+
+	ErrUnknownColumnTypePolicyInvalid = errors.Normalize("invalid unknown column type policy: %s", errors.RFCCodeText("CDC:ErrUnknownColumnTypePolicyInvalid"))
+	ErrMissingColumnDefaultInvalid    = errors.Normalize("invalid missing-column-default: %s", errors.RFCCodeText("CDC:ErrMissingColumnDefaultInvalid"))
+	ErrDecodeErrorPolicyInvalid       = errors.Normalize("invalid decode-error policy: %s", errors.RFCCodeText("CDC:ErrDecodeErrorPolicyInvalid"))
+	ErrPriorityClassInvalid           = errors.Normalize("invalid priority class: %s", errors.RFCCodeText("CDC:ErrPriorityClassInvalid"))
+	ErrGCSpanningTxnPolicyInvalid     = errors.Normalize("invalid gc-spanning-txn policy: %s", errors.RFCCodeText("CDC:ErrGCSpanningTxnPolicyInvalid"))
+	ErrDDLOrderInvalid                = errors.Normalize("invalid ddl-order: %s", errors.RFCCodeText("CDC:ErrDDLOrderInvalid"))
+	ErrStartTsInDDLInvalid            = errors.Normalize("invalid start-ts-in-ddl policy: %s", errors.RFCCodeText("CDC:ErrStartTsInDDLInvalid"))
+	ErrFinishVerificationInvalid      = errors.Normalize("invalid finish-verification: %s", errors.RFCCodeText("CDC:ErrFinishVerificationInvalid"))
+	ErrIntegrityCheckInvalid          = errors.Normalize("invalid integrity check config: %s", errors.RFCCodeText("CDC:ErrIntegrityCheckInvalid"))
+	ErrSchedulerConfigInvalid         = errors.Normalize("invalid scheduler config: %s", errors.RFCCodeText("CDC:ErrSchedulerConfigInvalid"))
+	ErrValueFormatInvalid             = errors.Normalize("invalid %s format: %s", errors.RFCCodeText("CDC:ErrValueFormatInvalid"))
+	ErrFallbackProtocolInvalid        = errors.Normalize("invalid fallback protocol %s: %s", errors.RFCCodeText("CDC:ErrFallbackProtocolInvalid"))
+	ErrDispatcherInvalid              = errors.Normalize("invalid dispatcher %s: %s", errors.RFCCodeText("CDC:ErrDispatcherInvalid"))
+	ErrCommitTimeZoneInvalid          = errors.Normalize("invalid commit time zone", errors.RFCCodeText("CDC:ErrCommitTimeZoneInvalid"))
+	ErrTableErrorPolicyInvalid        = errors.Normalize("invalid table-error-policy: %s", errors.RFCCodeText("CDC:ErrTableErrorPolicyInvalid"))
+	ErrConflictResolutionInvalid      = errors.Normalize("invalid conflict-resolution: %s", errors.RFCCodeText("CDC:ErrConflictResolutionInvalid"))
+	ErrDownstreamUnwritableInvalid    = errors.Normalize("invalid downstream-unwritable config: %s", errors.RFCCodeText("CDC:ErrDownstreamUnwritableInvalid"))
+	ErrDebugConfigInvalid             = errors.Normalize("invalid debug config: %s", errors.RFCCodeText("CDC:ErrDebugConfigInvalid"))
+	ErrSinkPausedOnError              = errors.Normalize("the writes of the sink are paused on the %s error until resumed: %s", errors.RFCCodeText("CDC:ErrSinkPausedOnError"))
+	ErrDedupInvalidConfig             = errors.Normalize("dedup config invalid", errors.RFCCodeText("CDC:ErrDedupInvalidConfig"))
+	ErrSoftDeleteInvalidConfig        = errors.Normalize("soft delete config invalid", errors.RFCCodeText("CDC:ErrSoftDeleteInvalidConfig"))
+	ErrValueFormatFailed              = errors.Normalize("can not format the value of column %s: %v", errors.RFCCodeText("CDC:ErrValueFormatFailed"))
 
 	// internal errors
 	ErrAdminStopProcessor = errors.Normalize("stop processor by admin command", errors.RFCCodeText("CDC:ErrAdminStopProcessor"))
@@ -66,7 +92,7 @@ var (
 	ErrCreateMarkTableFailed = errors.Normalize("create mark table failed", errors.RFCCodeText("CDC:ErrCreateMarkTableFailed"))
 
 	// sink related errors
-	ErrExecDDLFailed             = errors.Normalize("exec DDL failed", errors.RFCCodeText("CDC:ErrExecDDLFailed"))
+	ErrExecDDLFailed             = errors.Normalize("exec DDL failed after %s, query: %s, error: %s", errors.RFCCodeText("CDC:ErrExecDDLFailed"))
 	ErrDDLEventIgnored           = errors.Normalize("ddl event is ignored", errors.RFCCodeText("CDC:ErrDDLEventIgnored"))
 	ErrKafkaSendMessage          = errors.Normalize("kafka send message failed", errors.RFCCodeText("CDC:ErrKafkaSendMessage"))
 	ErrKafkaAsyncSendMessage     = errors.Normalize("kafka async send message failed", errors.RFCCodeText("CDC:ErrKafkaAsyncSendMessage"))
@@ -75,8 +101,10 @@ var (
 	ErrKafkaNewSaramaProducer    = errors.Normalize("new sarama producer", errors.RFCCodeText("CDC:ErrKafkaNewSaramaProducer"))
 	ErrKafkaInvalidClientID      = errors.Normalize("invalid kafka client ID '%s'", errors.RFCCodeText("CDC:ErrKafkaInvalidClientID"))
 	ErrKafkaInvalidVersion       = errors.Normalize("invalid kafka version", errors.RFCCodeText("CDC:ErrKafkaInvalidVersion"))
+	ErrKafkaProbeFailed          = errors.Normalize("kafka delivery probe failed", errors.RFCCodeText("CDC:ErrKafkaProbeFailed"))
 	ErrPulsarNewProducer         = errors.Normalize("new pulsar producer", errors.RFCCodeText("CDC:ErrPulsarNewProducer"))
 	ErrPulsarSendMessage         = errors.Normalize("pulsar send message failed", errors.RFCCodeText("CDC:ErrPulsarSendMessage"))
+	ErrMemoryQueueInvalidConfig  = errors.Normalize("memory queue config invalid", errors.RFCCodeText("CDC:ErrMemoryQueueInvalidConfig"))
 	ErrFileSinkCreateDir         = errors.Normalize("file sink create dir", errors.RFCCodeText("CDC:ErrFileSinkCreateDir"))
 	ErrFileSinkFileOp            = errors.Normalize("file sink file operation", errors.RFCCodeText("CDC:ErrFileSinkFileOp"))
 	ErrFileSinkMetaAlreadyExists = errors.Normalize("file sink meta file already exists", errors.RFCCodeText("CDC:ErrFileSinkMetaAlreadyExists"))
@@ -89,9 +117,13 @@ var (
 	ErrSinkURIInvalid            = errors.Normalize("sink uri invalid", errors.RFCCodeText("CDC:ErrSinkURIInvalid"))
 	ErrMySQLTxnError             = errors.Normalize("MySQL txn error", errors.RFCCodeText("CDC:ErrMySQLTxnError"))
 	ErrMySQLQueryError           = errors.Normalize("MySQL query error", errors.RFCCodeText("CDC:ErrMySQLQueryError"))
+	ErrDownstreamUnwritable      = errors.Normalize("the downstream refuses to write for a while, %s", errors.RFCCodeText("CDC:ErrDownstreamUnwritable"))
+	ErrReconcileUnsupported      = errors.Normalize("the sink %s doesn't support reconciling the downstream schemas", errors.RFCCodeText("CDC:ErrReconcileUnsupported"))
 	ErrMySQLConnectionError      = errors.Normalize("MySQL connection error", errors.RFCCodeText("CDC:ErrMySQLConnectionError"))
 	ErrMySQLInvalidConfig        = errors.Normalize("MySQL config invaldi", errors.RFCCodeText("CDC:ErrMySQLInvalidConfig"))
 	ErrMySQLWorkerPanic          = errors.Normalize("MySQL worker panic", errors.RFCCodeText("CDC:ErrMySQLWorkerPanic"))
+	ErrSoftDeleteColumnNotFound  = errors.Normalize("the soft delete column %s doesn't exist in the downstream table %s", errors.RFCCodeText("CDC:ErrSoftDeleteColumnNotFound"))
+	ErrMySQLHeartbeatPrivilege   = errors.Normalize("the downstream user has no privilege to write heartbeats into %s, grant the CREATE, INSERT and UPDATE privileges or disable enable-heartbeat: %s", errors.RFCCodeText("CDC:ErrMySQLHeartbeatPrivilege"))
 	ErrAvroToEnvelopeError       = errors.Normalize("to envelope failed", errors.RFCCodeText("CDC:ErrAvroToEnvelopeError"))
 	ErrAvroUnknownType           = errors.Normalize("unknown type for Avro: %v", errors.RFCCodeText("CDC:ErrAvroUnknownType"))
 	ErrAvroMarshalFailed         = errors.Normalize("json marshal failed", errors.RFCCodeText("CDC:ErrAvroMarshalFailed"))
@@ -104,6 +136,9 @@ var (
 	ErrJSONCodecInvalidData      = errors.Normalize("json codec invalid data", errors.RFCCodeText("CDC:ErrJSONCodecInvalidData"))
 	ErrCanalDecodeFailed         = errors.Normalize("canal decode failed", errors.RFCCodeText("CDC:ErrCanalDecodeFailed"))
 	ErrCanalEncodeFailed         = errors.Normalize("canal encode failed", errors.RFCCodeText("CDC:ErrCanalEncodeFailed"))
+	ErrProtobufInvalidDescriptor = errors.Normalize("invalid protobuf descriptor: %s", errors.RFCCodeText("CDC:ErrProtobufInvalidDescriptor"))
+	ErrProtobufSchemaMismatch    = errors.Normalize("protobuf schema mismatch, table %s, message %s: %s", errors.RFCCodeText("CDC:ErrProtobufSchemaMismatch"))
+	ErrProtobufDecodeFailed      = errors.Normalize("protobuf decode failed", errors.RFCCodeText("CDC:ErrProtobufDecodeFailed"))
 
 	// utilities related errors
 	ErrToTLSConfigFailed         = errors.Normalize("generate tls config failed", errors.RFCCodeText("CDC:ErrToTLSConfigFailed"))
@@ -115,18 +150,24 @@ var (
 	ErrIntersectNoOverlap        = errors.Normalize("span doesn't overlap: %+v vs %+v", errors.RFCCodeText("CDC:ErrIntersectNoOverlap"))
 
 	// encode/decode, data format and data integrity errors
-	ErrInvalidRecordKey      = errors.Normalize("invalid record key - %q", errors.RFCCodeText("CDC:ErrInvalidRecordKey"))
-	ErrCodecDecode           = errors.Normalize("codec decode error", errors.RFCCodeText("CDC:ErrCodecDecode"))
-	ErrUnknownMetaType       = errors.Normalize("unknown meta type %v", errors.RFCCodeText("CDC:ErrUnknownMetaType"))
-	ErrFetchHandleValue      = errors.Normalize("can't find handle column, please check if the pk is handle", errors.RFCCodeText("CDC:ErrFetchHandleValue"))
-	ErrDatumUnflatten        = errors.Normalize("unflatten datume data", errors.RFCCodeText("CDC:ErrDatumUnflatten"))
-	ErrWrongTableInfo        = errors.Normalize("wrong table info in unflatten, table id %d, index table id: %d", errors.RFCCodeText("CDC:ErrWrongTableInfo"))
-	ErrIndexKeyTableNotFound = errors.Normalize("table not found with index ID %d in index kv", errors.RFCCodeText("CDC:ErrIndexKeyTableNotFound"))
-	ErrDecodeRowToDatum      = errors.Normalize("decode row data to datum failed", errors.RFCCodeText("CDC:ErrDecodeRowToDatum"))
-	ErrMarshalFailed         = errors.Normalize("marshal failed", errors.RFCCodeText("CDC:ErrMarshalFailed"))
-	ErrUnmarshalFailed       = errors.Normalize("unmarshal failed", errors.RFCCodeText("CDC:ErrUnmarshalFailed"))
-	ErrInvalidChangefeedID   = errors.Normalize(`bad changefeed id, please match the pattern "^[a-zA-Z0-9]+(\-[a-zA-Z0-9]+)*$", eg, "simple-changefeed-task"`, errors.RFCCodeText("CDC:ErrInvalidChangefeedID"))
-	ErrInvalidEtcdKey        = errors.Normalize("invalid key: %s", errors.RFCCodeText("CDC:ErrInvalidEtcdKey"))
+	ErrInvalidRecordKey        = errors.Normalize("invalid record key - %q", errors.RFCCodeText("CDC:ErrInvalidRecordKey"))
+	ErrCodecDecode             = errors.Normalize("codec decode error", errors.RFCCodeText("CDC:ErrCodecDecode"))
+	ErrUnknownMetaType         = errors.Normalize("unknown meta type %v", errors.RFCCodeText("CDC:ErrUnknownMetaType"))
+	ErrFetchHandleValue        = errors.Normalize("can't find handle column, please check if the pk is handle", errors.RFCCodeText("CDC:ErrFetchHandleValue"))
+	ErrDatumUnflatten          = errors.Normalize("unflatten datume data", errors.RFCCodeText("CDC:ErrDatumUnflatten"))
+	ErrWrongTableInfo          = errors.Normalize("wrong table info in unflatten, table id %d, index table id: %d", errors.RFCCodeText("CDC:ErrWrongTableInfo"))
+	ErrIndexKeyTableNotFound   = errors.Normalize("table not found with index ID %d in index kv", errors.RFCCodeText("CDC:ErrIndexKeyTableNotFound"))
+	ErrDecodeRowToDatum        = errors.Normalize("decode row data to datum failed", errors.RFCCodeText("CDC:ErrDecodeRowToDatum"))
+	ErrMarshalFailed           = errors.Normalize("marshal failed", errors.RFCCodeText("CDC:ErrMarshalFailed"))
+	ErrUnmarshalFailed         = errors.Normalize("unmarshal failed", errors.RFCCodeText("CDC:ErrUnmarshalFailed"))
+	ErrInvalidChangefeedID     = errors.Normalize(`bad changefeed id, please match the pattern "^[a-zA-Z0-9]+(\-[a-zA-Z0-9]+)*$", eg, "simple-changefeed-task"`, errors.RFCCodeText("CDC:ErrInvalidChangefeedID"))
+	ErrChangefeedImportInvalid = errors.Normalize("invalid changefeed %s in the import document: %s", errors.RFCCodeText("CDC:ErrChangefeedImportInvalid"))
+	ErrChangefeedSharedTopic   = errors.Normalize("changefeed %s writes the same tables to the same topic %s, tables: %v, the messages of both changefeeds are interleaved in the topic, use --force to create the changefeed anyway", errors.RFCCodeText("CDC:ErrChangefeedSharedTopic"))
+	ErrInvalidEtcdKey          = errors.Normalize("invalid key: %s", errors.RFCCodeText("CDC:ErrInvalidEtcdKey"))
+	ErrDeleteImageMissing      = errors.Normalize("the old value of the deleted row is unavailable, table: %s, handle: %d", errors.RFCCodeText("CDC:ErrDeleteImageMissing"))
+	ErrFetchDeleteImage        = errors.Normalize("fetch the old value of the deleted row failed", errors.RFCCodeText("CDC:ErrFetchDeleteImage"))
+	ErrUnknownColumnType       = errors.Normalize("column %s of table %s has an unknown type %d, set unknown-column-type to skip-column or raw-bytes to replicate the table", errors.RFCCodeText("CDC:ErrUnknownColumnType"))
+	ErrSchemaCheckFailed       = errors.Normalize("the schemas of the tables can't be reconstructed at ts %d: %s", errors.RFCCodeText("CDC:ErrSchemaCheckFailed"))
 
 	// schema storage errors
 	ErrSchemaStorageUnresolved = errors.Normalize("can not found schema snapshot, the specified ts(%d) is more than resolvedTs(%d)", errors.RFCCodeText("CDC:ErrSchemaStorageUnresolved"))
@@ -156,6 +197,8 @@ var (
 	ErrProcessorTableNotFound     = errors.Normalize("table not found in processor cache", errors.RFCCodeText("CDC:ErrProcessorTableNotFound"))
 	ErrProcessorEtcdWatch         = errors.Normalize("etcd watch returns error", errors.RFCCodeText("CDC:ErrProcessorEtcdWatch"))
 	ErrProcessorSortDir           = errors.Normalize("sort dir error", errors.RFCCodeText("CDC:ErrProcessorSortDir"))
+	ErrProcessorLateEvent         = errors.Normalize("event of table %d with commit ts %d arrives after the table checkpoint %d, region: %d", errors.RFCCodeText("CDC:ErrProcessorLateEvent"))
+	ErrTxnSpansGCSafepoint        = errors.Normalize("transaction of table %d with start ts %d and commit ts %d starts before the GC safepoint %d, some of its rows may be missing", errors.RFCCodeText("CDC:ErrTxnSpansGCSafepoint"))
 	ErrUnknownSortEngine          = errors.Normalize("unknown sort engine %s", errors.RFCCodeText("CDC:ErrUnknownSortEngine"))
 	ErrInvalidTaskKey             = errors.Normalize("invalid task key: %s", errors.RFCCodeText("CDC:ErrInvalidTaskKey"))
 	ErrInvalidServerOption        = errors.Normalize("invalid server option", errors.RFCCodeText("CDC:ErrInvalidServerOption"))
@@ -169,6 +212,11 @@ var (
 	ErrInternalServerError        = errors.Normalize("internal server error", errors.RFCCodeText("CDC:ErrInternalServerError"))
 	ErrOwnerSortDir               = errors.Normalize("owner sort dir", errors.RFCCodeText("CDC:ErrOwnerSortDir"))
 	ErrOwnerChangefeedNotFound    = errors.Normalize("changefeed %s not found in owner cache", errors.RFCCodeText("CDC:ErrOwnerChangefeedNotFound"))
+	ErrOwnerChangefeedOverlapped  = errors.Normalize("changefeed %s replicates the same tables to the same sink target as changefeed %v", errors.RFCCodeText("CDC:ErrOwnerChangefeedOverlapped"))
+	ErrTableRescanInvalid         = errors.Normalize("can not rescan table %d of changefeed %s, %s", errors.RFCCodeText("CDC:ErrTableRescanInvalid"))
+	ErrChangefeedShedByGCGuard    = errors.Normalize("changefeed %s is paused since its checkpoint lags behind %s and blocks the GC of upstream, the data before the checkpoint may be GC-ed", errors.RFCCodeText("CDC:ErrChangefeedShedByGCGuard"))
+	ErrStartTsInRunningDDL        = errors.Normalize("the start ts %d of changefeed %s falls in the execution of DDL job %d (%s) started at %d, create the changefeed with a start ts before the job starts or after it finishes", errors.RFCCodeText("CDC:ErrStartTsInRunningDDL"))
+	ErrChangefeedLagTooLong       = errors.Normalize("changefeed %s is paused since its checkpoint lags behind %s for more than %s, the data before the checkpoint may be GC-ed", errors.RFCCodeText("CDC:ErrChangefeedLagTooLong"))
 	ErrChangefeedAbnormalState    = errors.Normalize("changefeed in abnormal state: %s, replication status: %+v", errors.RFCCodeText("CDC:ErrChangefeedAbnormalState"))
 	ErrInvalidAdminJobType        = errors.Normalize("invalid admin job type: %d", errors.RFCCodeText("CDC:ErrInvalidAdminJobType"))
 	ErrOwnerEtcdWatch             = errors.Normalize("etcd watch returns error", errors.RFCCodeText("CDC:ErrOwnerEtcdWatch"))
@@ -15,11 +15,19 @@ package filter
 
 import (
 	"github.com/pingcap/parser/terror"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
 	"github.com/pingcap/tidb/store/tikv"
 )
 
 // ChangefeedFastFailError checks the error, returns true if it is meaningless
 // to retry on this error
 func ChangefeedFastFailError(err error) bool {
-	return terror.ErrorEqual(err, tikv.ErrGCTooEarly)
+	return terror.ErrorEqual(err, tikv.ErrGCTooEarly) ||
+		cerror.ErrStartTsInRunningDDL.Equal(err) || cerror.ErrReconcileUnsupported.Equal(err)
+}
+
+// ChangefeedPauseError checks the error, returns true if the changefeed should be paused on
+// this error, it can be resumed after the error is fixed, e.g. by updating the changefeed config
+func ChangefeedPauseError(err error) bool {
+	return cerror.ErrSchemaCheckFailed.Equal(err)
 }
@@ -14,22 +14,42 @@
 package filter
 
 import (
+	"strings"
+
+	"github.com/pingcap/errors"
 	"github.com/pingcap/parser/model"
+	cdcmodel "github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/config"
 	"github.com/pingcap/ticdc/pkg/cyclic/mark"
 	cerror "github.com/pingcap/ticdc/pkg/errors"
-	filterV1 "github.com/pingcap/tidb-tools/pkg/filter"
 	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
 )
 
 // Filter is a event filter implementation
 type Filter struct {
 	filter           filterV2.Filter
+	sysSchemaFilter  filterV2.Filter
 	ignoreTxnStartTs []uint64
 	ddlAllowlist     []model.ActionType
+	eventFilters     []*eventFilterRule
 	isCyclicEnabled  bool
 }
 
+// EventType is the type of a row changed event
+type EventType string
+
+// All EventTypes which can be ignored by event filters
+const (
+	InsertEvent EventType = "insert"
+	UpdateEvent EventType = "update"
+	DeleteEvent EventType = "delete"
+)
+
+type eventFilterRule struct {
+	filterV2.Filter
+	ignoreEvent map[EventType]struct{}
+}
+
 // NewFilter creates a filter
 func NewFilter(cfg *config.ReplicaConfig) (*Filter, error) {
 	var f filterV2.Filter
@@ -49,14 +69,66 @@ func NewFilter(cfg *config.ReplicaConfig) (*Filter, error) {
 	if !cfg.CaseSensitive {
 		f = filterV2.CaseInsensitive(f)
 	}
+	sysSchemaFilter, err := newSysSchemaFilter(cfg.Filter.ExtraSystemSchemas)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	eventFilters, err := newEventFilterRules(cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch cfg.Filter.PartialDropDatabase {
+	case "", config.PartialDropDatabaseDropDatabase, config.PartialDropDatabaseDropTables:
+	default:
+		return nil, cerror.ErrFilterRuleInvalid.GenWithStack("unknown partial-drop-database %s", cfg.Filter.PartialDropDatabase)
+	}
+	if cfg.Filter.DropTablesBatchSize < 0 {
+		return nil, cerror.ErrFilterRuleInvalid.GenWithStack(
+			"drop-tables-batch-size must not be negative, got %d", cfg.Filter.DropTablesBatchSize)
+	}
 	return &Filter{
 		filter:           f,
+		sysSchemaFilter:  sysSchemaFilter,
 		ignoreTxnStartTs: cfg.Filter.IgnoreTxnStartTs,
 		ddlAllowlist:     cfg.Filter.DDLAllowlist,
+		eventFilters:     eventFilters,
 		isCyclicEnabled:  cfg.Cyclic.IsEnabled(),
 	}, nil
 }
 
+func newEventFilterRules(cfg *config.ReplicaConfig) ([]*eventFilterRule, error) {
+	rules := make([]*eventFilterRule, 0, len(cfg.Filter.EventFilters))
+	for _, ruleConfig := range cfg.Filter.EventFilters {
+		f, err := filterV2.Parse(ruleConfig.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			f = filterV2.CaseInsensitive(f)
+		}
+		rule := &eventFilterRule{Filter: f, ignoreEvent: make(map[EventType]struct{})}
+		for _, tp := range ruleConfig.IgnoreEvent {
+			eventType := EventType(strings.ToLower(tp))
+			switch eventType {
+			case InsertEvent, UpdateEvent, DeleteEvent:
+				rule.ignoreEvent[eventType] = struct{}{}
+			default:
+				return nil, cerror.ErrFilterRuleInvalid.GenWithStack("unknown event type %s in event filter", tp)
+			}
+		}
+		// Without the old value, an update event carries no pre columns and
+		// can not be told apart from an insert event.
+		_, ignoreInsert := rule.ignoreEvent[InsertEvent]
+		_, ignoreUpdate := rule.ignoreEvent[UpdateEvent]
+		if !cfg.EnableOldValue && ignoreInsert != ignoreUpdate {
+			return nil, cerror.ErrFilterRuleInvalid.GenWithStack(
+				"ignoring only one of insert and update events requires enable-old-value, matcher: %v", ruleConfig.Matcher)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
 func (f *Filter) shouldIgnoreStartTs(ts uint64) bool {
 	for _, ignoreTs := range f.ignoreTxnStartTs {
 		if ignoreTs == ts {
@@ -69,7 +141,7 @@ func (f *Filter) shouldIgnoreStartTs(ts uint64) bool {
 // ShouldIgnoreTable returns true if the specified table should be ignored by this change feed.
 // Set `tbl` to an empty string to test against the whole database.
 func (f *Filter) ShouldIgnoreTable(db, tbl string) bool {
-	if IsSysSchema(db) {
+	if f.isSysSchema(db) {
 		return true
 	}
 	if f.isCyclicEnabled && mark.IsMarkTable(db, tbl) {
@@ -79,12 +151,44 @@ func (f *Filter) ShouldIgnoreTable(db, tbl string) bool {
 	return !f.filter.MatchTable(db, tbl)
 }
 
+// isSysSchema returns true if the schema is a system schema, which is ignored whatever the user rules are
+func (f *Filter) isSysSchema(db string) bool {
+	return f.sysSchemaFilter.MatchSchema(db)
+}
+
 // ShouldIgnoreDMLEvent removes DMLs that's not wanted by this change feed.
 // CDC only supports filtering by database/table now.
 func (f *Filter) ShouldIgnoreDMLEvent(ts uint64, schema, table string) bool {
 	return f.shouldIgnoreStartTs(ts) || f.ShouldIgnoreTable(schema, table)
 }
 
+// ShouldIgnoreRowChangedEvent removes row changed events that's not wanted by this change feed,
+// the event is filtered by the start ts, the table and the event type.
+// If an update event is ignored, the row is simply not updated downstream.
+func (f *Filter) ShouldIgnoreRowChangedEvent(row *cdcmodel.RowChangedEvent) bool {
+	if f.ShouldIgnoreDMLEvent(row.StartTs, row.Table.Schema, row.Table.Table) {
+		return true
+	}
+	if len(f.eventFilters) == 0 {
+		return false
+	}
+	var eventType EventType
+	switch {
+	case row.IsDelete():
+		eventType = DeleteEvent
+	case len(row.PreColumns) == 0:
+		eventType = InsertEvent
+	default:
+		eventType = UpdateEvent
+	}
+	for _, rule := range f.eventFilters {
+		if _, ok := rule.ignoreEvent[eventType]; ok && rule.MatchTable(row.Table.Schema, row.Table.Table) {
+			return true
+		}
+	}
+	return false
+}
+
 // ShouldIgnoreDDLEvent removes DDLs that's not wanted by this change feed.
 // CDC only supports filtering by database/table now.
 func (f *Filter) ShouldIgnoreDDLEvent(ts uint64, ddlType model.ActionType, schema, table string) bool {
@@ -92,7 +196,7 @@ func (f *Filter) ShouldIgnoreDDLEvent(ts uint64, ddlType model.ActionType, schem
 	switch ddlType {
 	case model.ActionCreateSchema, model.ActionDropSchema,
 		model.ActionModifySchemaCharsetAndCollate:
-		shouldIgnoreTableOrSchema = !f.filter.MatchSchema(schema)
+		shouldIgnoreTableOrSchema = f.isSysSchema(schema) || !f.filter.MatchSchema(schema)
 	default:
 		shouldIgnoreTableOrSchema = f.ShouldIgnoreTable(schema, table)
 	}
@@ -158,8 +262,3 @@ func (f *Filter) shouldDiscardByBuiltInDDLAllowlist(ddlType model.ActionType) bo
 	}
 	return true
 }
-
-// IsSysSchema returns true if the given schema is a system schema
-func IsSysSchema(db string) bool {
-	return filterV1.IsSystemSchema(db)
-}
@@ -16,7 +16,9 @@ package filter
 import (
 	"testing"
 
+	cdcmodel "github.com/pingcap/ticdc/cdc/model"
 	"github.com/pingcap/ticdc/pkg/config"
+	cerror "github.com/pingcap/ticdc/pkg/errors"
 
 	"github.com/pingcap/check"
 	"github.com/pingcap/parser/model"
@@ -177,3 +179,141 @@ func (s *filterSuite) TestShouldIgnoreDDL(c *check.C) {
 		}
 	}
 }
+
+func (s *filterSuite) TestShouldIgnoreRowChangedEvent(c *check.C) {
+	cols := []*cdcmodel.Column{{Name: "id", Value: 1}}
+	insert := &cdcmodel.RowChangedEvent{Table: &cdcmodel.TableName{Schema: "db1", Table: "audit_log"}, Columns: cols}
+	update := &cdcmodel.RowChangedEvent{Table: &cdcmodel.TableName{Schema: "db1", Table: "audit_log"}, Columns: cols, PreColumns: cols}
+	del := &cdcmodel.RowChangedEvent{Table: &cdcmodel.TableName{Schema: "db1", Table: "audit_log"}, PreColumns: cols}
+	other := &cdcmodel.RowChangedEvent{Table: &cdcmodel.TableName{Schema: "db1", Table: "orders"}, Columns: cols, PreColumns: cols}
+
+	testCases := []struct {
+		ignoreEvent []string
+		ignored     []bool // insert, update, delete, update of an unmatched table
+	}{
+		{[]string{}, []bool{false, false, false, false}},
+		{[]string{"insert"}, []bool{true, false, false, false}},
+		{[]string{"update"}, []bool{false, true, false, false}},
+		{[]string{"delete"}, []bool{false, false, true, false}},
+		{[]string{"insert", "update"}, []bool{true, true, false, false}},
+		{[]string{"insert", "delete"}, []bool{true, false, true, false}},
+		{[]string{"UPDATE", "Delete"}, []bool{false, true, true, false}},
+		{[]string{"insert", "update", "delete"}, []bool{true, true, true, false}},
+	}
+	for _, tc := range testCases {
+		cfg := config.GetDefaultReplicaConfig()
+		cfg.EnableOldValue = true
+		cfg.Filter.EventFilters = []*config.EventFilterRule{
+			{Matcher: []string{"db1.audit_*"}, IgnoreEvent: tc.ignoreEvent},
+		}
+		filter, err := NewFilter(cfg)
+		c.Assert(err, check.IsNil)
+		for i, row := range []*cdcmodel.RowChangedEvent{insert, update, del, other} {
+			c.Assert(filter.ShouldIgnoreRowChangedEvent(row), check.Equals, tc.ignored[i], check.Commentf("%v %d", tc.ignoreEvent, i))
+		}
+	}
+
+	// rules of multiple matchers are combined
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.EnableOldValue = true
+	cfg.Filter.EventFilters = []*config.EventFilterRule{
+		{Matcher: []string{"db1.audit_*"}, IgnoreEvent: []string{"update"}},
+		{Matcher: []string{"db1.*"}, IgnoreEvent: []string{"delete"}},
+	}
+	filter, err := NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(filter.ShouldIgnoreRowChangedEvent(update), check.IsTrue)
+	c.Assert(filter.ShouldIgnoreRowChangedEvent(del), check.IsTrue)
+	c.Assert(filter.ShouldIgnoreRowChangedEvent(insert), check.IsFalse)
+	c.Assert(filter.ShouldIgnoreRowChangedEvent(other), check.IsFalse)
+
+	// the table filter and the start ts are still respected
+	cfg.Filter.Rules = []string{"db2.*"}
+	cfg.Filter.IgnoreTxnStartTs = []uint64{10}
+	filter, err = NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+	c.Assert(filter.ShouldIgnoreRowChangedEvent(insert), check.IsTrue)
+	c.Assert(filter.ShouldIgnoreRowChangedEvent(&cdcmodel.RowChangedEvent{
+		StartTs: 10, Table: &cdcmodel.TableName{Schema: "db2", Table: "t"}, Columns: cols,
+	}), check.IsTrue)
+}
+
+func (s *filterSuite) TestInvalidEventFilter(c *check.C) {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.EnableOldValue = true
+	cfg.Filter.EventFilters = []*config.EventFilterRule{
+		{Matcher: []string{"db1.*"}, IgnoreEvent: []string{"truncate"}},
+	}
+	_, err := NewFilter(cfg)
+	c.Assert(cerror.ErrFilterRuleInvalid.Equal(err), check.IsTrue)
+
+	// updates can not be told apart from inserts without the old value
+	cfg.EnableOldValue = false
+	for _, ignoreEvent := range [][]string{{"insert"}, {"update", "delete"}} {
+		cfg.Filter.EventFilters = []*config.EventFilterRule{
+			{Matcher: []string{"db1.*"}, IgnoreEvent: ignoreEvent},
+		}
+		_, err = NewFilter(cfg)
+		c.Assert(cerror.ErrFilterRuleInvalid.Equal(err), check.IsTrue)
+	}
+	for _, ignoreEvent := range [][]string{{"delete"}, {"insert", "update"}} {
+		cfg.Filter.EventFilters = []*config.EventFilterRule{
+			{Matcher: []string{"db1.*"}, IgnoreEvent: ignoreEvent},
+		}
+		_, err = NewFilter(cfg)
+		c.Assert(err, check.IsNil)
+	}
+}
+
+func (s *filterSuite) TestInvalidPartialDropDatabase(c *check.C) {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.PartialDropDatabase = "drop-nothing"
+	_, err := NewFilter(cfg)
+	c.Assert(cerror.ErrFilterRuleInvalid.Equal(err), check.IsTrue)
+
+	cfg.Filter.PartialDropDatabase = config.PartialDropDatabaseDropTables
+	cfg.Filter.DropTablesBatchSize = -1
+	_, err = NewFilter(cfg)
+	c.Assert(cerror.ErrFilterRuleInvalid.Equal(err), check.IsTrue)
+
+	cfg.Filter.DropTablesBatchSize = 16
+	_, err = NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *filterSuite) TestShouldIgnoreSysSchemas(c *check.C) {
+	cfg := config.GetDefaultReplicaConfig()
+	// the broad rules match the system schemas explicitly
+	cfg.Filter.Rules = []string{"*.*", "mysql.*", "__TiDB_BR_Temporary_*.*"}
+	cfg.Filter.ExtraSystemSchemas = []string{"dm_meta", "tmp_*"}
+	filter, err := NewFilter(cfg)
+	c.Assert(err, check.IsNil)
+
+	for _, schema := range []string{
+		"mysql", "MySQL", "sys", "INFORMATION_SCHEMA", "performance_schema", "metrics_schema",
+		"__TiDB_BR_Temporary_mysql", "__tidb_br_temporary_mysql", "dm_meta", "tmp_restore",
+	} {
+		c.Assert(filter.ShouldIgnoreTable(schema, "t"), check.IsTrue, check.Commentf("%s", schema))
+		c.Assert(filter.ShouldIgnoreRowChangedEvent(&cdcmodel.RowChangedEvent{
+			Table:   &cdcmodel.TableName{Schema: schema, Table: "t"},
+			Columns: []*cdcmodel.Column{{Name: "id", Value: 1}},
+		}), check.IsTrue, check.Commentf("%s", schema))
+		for _, tp := range []model.ActionType{model.ActionCreateSchema, model.ActionDropSchema, model.ActionModifySchemaCharsetAndCollate} {
+			c.Assert(filter.ShouldIgnoreDDLEvent(1, tp, schema, ""), check.IsTrue, check.Commentf("%s %s", schema, tp))
+		}
+		c.Assert(filter.ShouldIgnoreDDLEvent(1, model.ActionCreateTable, schema, "t"), check.IsTrue, check.Commentf("%s", schema))
+	}
+	for _, schema := range []string{"test", "mysql_app", "metric_schema", "dm_meta2", "tidb_cdc"} {
+		c.Assert(filter.ShouldIgnoreTable(schema, "t"), check.IsFalse, check.Commentf("%s", schema))
+		c.Assert(filter.ShouldIgnoreDDLEvent(1, model.ActionCreateSchema, schema, ""), check.IsFalse, check.Commentf("%s", schema))
+	}
+	// the extra system schemas don't apply to the other changefeeds
+	c.Assert(IsSysSchema("__TiDB_BR_Temporary_mysql"), check.IsTrue)
+	c.Assert(IsSysSchema("dm_meta"), check.IsFalse)
+
+	for _, schema := range []string{"", "db.t", "!db", "@db"} {
+		cfg.Filter.ExtraSystemSchemas = []string{schema}
+		_, err = NewFilter(cfg)
+		c.Assert(cerror.ErrFilterRuleInvalid.Equal(err), check.IsTrue, check.Commentf("%q", schema))
+	}
+}
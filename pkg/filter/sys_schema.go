@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"strings"
+
+	cerror "github.com/pingcap/ticdc/pkg/errors"
+	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
+)
+
+// defaultSysSchemas are the patterns of the internal schemas which are never replicated,
+// they're matched case-insensitively before the user rules.
+var defaultSysSchemas = []string{
+	"mysql",
+	"sys",
+	"information_schema",
+	"inspection_schema",
+	"performance_schema",
+	"metrics_schema",
+	"dm_heartbeat",
+	// the temporary schemas BR restores the system tables into
+	"__TiDB_BR_Temporary_*",
+}
+
+var defaultSysSchemaFilter = mustNewSysSchemaFilter(nil)
+
+// newSysSchemaFilter creates a filter matching the default system schemas and the extra schema patterns,
+// the patterns use the wildcards of the table filter rules.
+func newSysSchemaFilter(extraSysSchemas []string) (filterV2.Filter, error) {
+	rules := make([]string, 0, len(defaultSysSchemas)+len(extraSysSchemas))
+	for _, schema := range defaultSysSchemas {
+		rules = append(rules, schema+".*")
+	}
+	for _, schema := range extraSysSchemas {
+		if schema == "" || strings.ContainsAny(schema, ".!@") {
+			return nil, cerror.ErrFilterRuleInvalid.GenWithStack("invalid extra system schema %q", schema)
+		}
+		rules = append(rules, schema+".*")
+	}
+	f, err := filterV2.Parse(rules)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+	}
+	return filterV2.CaseInsensitive(f), nil
+}
+
+func mustNewSysSchemaFilter(extraSysSchemas []string) filterV2.Filter {
+	f, err := newSysSchemaFilter(extraSysSchemas)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// IsSysSchema returns true if the given schema is one of the default system schemas
+func IsSysSchema(db string) bool {
+	return defaultSysSchemaFilter.MatchSchema(db)
+}